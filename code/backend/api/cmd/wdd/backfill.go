@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+	"wdd/api/internal/backfill"
+)
+
+func runBackfill(ctx context.Context, args []string) error {
+	var request backfill.Request
+	var from, to string
+
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	flags.StringVar(&request.FactoryID, "factory", "", "factory ID to backfill")
+	flags.StringVar(&request.AssetID, "asset", "", "asset ID to backfill")
+	flags.StringVar(&request.PropertyID, "property", "", "property ID to backfill")
+	flags.StringVar(&from, "from", "", "start of the window (RFC3339)")
+	flags.StringVar(&to, "to", "", "end of the window (RFC3339), defaults to now")
+	flags.Int64Var(&request.Seed, "seed", 1, "seed for the random generators")
+	flags.BoolVar(&request.IgnoreCalendar, "ignore-calendar", false, "simulate around the clock instead of following factory shifts")
+	flags.IntVar(&request.Concurrency, "concurrency", backfill.DEFAULTCONCURRENCY, "number of concurrent batch writers")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if request.Start, err = time.Parse(time.RFC3339, from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	request.End = time.Now()
+	if to != "" {
+		if request.End, err = time.Parse(time.RFC3339, to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	stats, err := backfill.New(db).Run(ctx, request)
+	fmt.Printf("series:     %d (%d skipped)\n", stats.Series, stats.Skipped)
+	fmt.Printf("readings:   %d\n", stats.Readings)
+	fmt.Printf("batches:    %d\n", stats.Batches)
+	fmt.Printf("duration:   %.2fs\n", stats.DurationSeconds)
+	fmt.Printf("throughput: %.0f readings/s\n", stats.ReadingsPerSecond)
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+	"wdd/api/internal/retention"
+)
+
+func runCompact(ctx context.Context, args []string) error {
+	var request retention.Request
+	var at string
+
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	flags.StringVar(&request.FactoryID, "factory", "", "factory ID to compact, defaults to every factory")
+	flags.BoolVar(&request.Rebuild, "rebuild", false, "recompute rollups from all retained data, e.g. after backfilling the past")
+	flags.StringVar(&at, "at", "", "evaluate retention at this instant (RFC3339), defaults to now")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if at != "" {
+		var err error
+		if request.At, err = time.Parse(time.RFC3339, at); err != nil {
+			return fmt.Errorf("invalid -at: %w", err)
+		}
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	stats, err := retention.New(db).Run(ctx, request)
+	fmt.Printf("factories: %d\n", stats.Factories)
+	fmt.Printf("series:    %d\n", stats.Series)
+	fmt.Printf("rollups:   %d written\n", stats.Rollups)
+	fmt.Printf("readings:  %d deleted\n", stats.Deleted)
+	fmt.Printf("duration:  %.2fs\n", stats.DurationSeconds)
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"wdd/api/internal/export"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	var request export.Request
+	var from, to, output string
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.StringVar(&request.FactoryID, "factory", "", "factory ID to export")
+	flags.StringVar(&request.AssetID, "asset", "", "asset ID to export")
+	flags.StringVar(&request.PropertyID, "property", "", "property ID to export")
+	flags.StringVar(&from, "from", "", "start of the window (RFC3339)")
+	flags.StringVar(&to, "to", "", "end of the window (RFC3339), defaults to now")
+	flags.StringVar(&request.Format, "format", export.CSV, "csv, jsonl, parquet or lineprotocol")
+	flags.StringVar(&request.Precision, "precision", "", "timestamp precision of lineprotocol exports: ns, us, ms, s, m or h")
+	flags.StringVar(&request.Layout, "layout", export.LONG, "long or wide (csv only)")
+	flags.StringVar(&request.Quality, "quality", "", "only export readings of these qualities, such as good,uncertain")
+	flags.StringVar(&output, "o", "-", "output file, - for stdout")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if request.Start, err = time.Parse(time.RFC3339, from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	request.End = time.Now()
+	if to != "" {
+		if request.End, err = time.Parse(time.RFC3339, to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	if err = request.Validate(); err != nil {
+		return err
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+
+	stats, err := export.New(db).Export(ctx, request, buffered)
+	if err != nil {
+		return err
+	}
+	if err = buffered.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d rows from %d series\n", stats.Rows, stats.Series)
+	return nil
+}
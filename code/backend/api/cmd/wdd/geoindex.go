@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// runGeoIndex sets the geohash of factories created before spatial search,
+// or whose stored geohash no longer matches their location, and the
+// timezone of factories created before timezones.
+func runGeoIndex(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("geoindex", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the factories to reindex without writing")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	factories, err := catalog.New(db).Factories(ctx)
+	if err != nil {
+		return err
+	}
+
+	var updated int
+	for _, f := range factories {
+		stored, located := f.Geohash, f.Timezone != nil
+		geo.IndexFactory(&f)
+		timezone.Locate(&f)
+		if f.Geohash == stored && (located || f.Timezone == nil) {
+			continue
+		}
+		fmt.Printf("%s: %q -> %q\n", f.FactoryID, stored, f.Geohash)
+		updated++
+		if *dryRun {
+			continue
+		}
+
+		var update expression.UpdateBuilder
+		if f.Geohash != "" {
+			update = update.Set(expression.Name("geohash"), expression.Value(f.Geohash)).
+				Set(expression.Name("geohashKey"), expression.Value(f.GeohashKey))
+		} else {
+			update = update.Remove(expression.Name("geohash")).Remove(expression.Name("geohashKey"))
+		}
+		if !located && f.Timezone != nil {
+			fmt.Printf("%s: timezone %s\n", f.FactoryID, *f.Timezone)
+			update = update.Set(expression.Name("timezone"), expression.Value(*f.Timezone))
+		}
+		expr, err := wrappers.UpdateExpressionBuilder(update)
+		if err != nil {
+			return err
+		}
+		if _, err = db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(catalog.FACTORYTABLE),
+			Key:                       map[string]ddbtypes.AttributeValue{"factoryId": &ddbtypes.AttributeValueMemberS{Value: f.FactoryID}},
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			UpdateExpression:          expr.Update(),
+		}); err != nil {
+			return fmt.Errorf("error indexing factory %s: %w", f.FactoryID, err)
+		}
+	}
+	fmt.Printf("factories: %d, reindexed: %d\n", len(factories), updated)
+	return nil
+}
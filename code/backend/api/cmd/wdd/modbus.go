@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/modbus"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/types"
+)
+
+func runModbus(ctx context.Context, args []string) error {
+	var factoryID, assetID, propertyID string
+
+	flags := flag.NewFlagSet("modbus", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:5020", "address to serve Modbus TCP on")
+	flags.StringVar(&factoryID, "factory", "", "factory ID to serve")
+	flags.StringVar(&assetID, "asset", "", "asset ID to serve")
+	flags.StringVar(&propertyID, "property", "", "property ID to serve")
+	table := flags.String("table", modbus.HOLDING, "register table: holding or input")
+	mapPath := flags.String("map", "", "write the register map as CSV to this file (- for stdout)")
+	seed := flags.Int64("seed", 1, "seed for the random generators")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	targets := 0
+	for _, id := range []string{factoryID, assetID, propertyID} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of -factory, -asset or -property is required")
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+	c := catalog.New(db)
+	series, err := c.Series(ctx, factoryID, assetID, propertyID)
+	if err != nil {
+		return err
+	}
+	registers, err := modbus.BuildRegisterMap(series, *table)
+	if err != nil {
+		return err
+	}
+
+	if *mapPath != "" {
+		out := os.Stdout
+		if *mapPath != "-" {
+			if out, err = os.Create(*mapPath); err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		if err = registers.WriteCSV(out); err != nil {
+			return err
+		}
+	}
+
+	server := modbus.NewServer(registers)
+	for _, s := range series {
+		if s.Property.Value != nil {
+			server.Update(types.Reading{PropertyID: s.Property.PropertyID, Value: *s.Property.Value})
+		}
+	}
+	listening, err := server.ListenAndServe(*addr)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	fmt.Fprintf(os.Stderr, "serving %d registers on %s\n", len(registers.Registers), listening)
+
+	err = simulator.New(c, *seed).Run(ctx, series, func(_ catalog.Series, reading types.Reading) {
+		server.Update(reading)
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
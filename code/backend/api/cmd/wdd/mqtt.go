@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mqtt"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/sparkplug"
+	"wdd/api/internal/telemetry"
+)
+
+const (
+	FORMATJSON      = "json"
+	FORMATSPARKPLUG = "sparkplug"
+)
+
+func runMQTT(ctx context.Context, args []string) error {
+	var opts telemetry.Options
+	var spOpts sparkplug.Options
+	var clientOpts mqtt.Options
+	var factoryID, assetID, propertyID string
+	var qos uint
+
+	flags := flag.NewFlagSet("mqtt", flag.ContinueOnError)
+	flags.StringVar(&clientOpts.Broker, "broker", "localhost:1883", "MQTT broker address")
+	flags.StringVar(&clientOpts.ClientID, "client-id", "wdd-simulator", "MQTT client ID")
+	flags.StringVar(&clientOpts.Username, "username", "", "MQTT user name")
+	flags.StringVar(&clientOpts.Password, "password", "", "MQTT password")
+	embedded := flags.String("embedded", "", "start an embedded broker on this address and publish to it")
+	flags.StringVar(&factoryID, "factory", "", "factory ID to publish")
+	flags.StringVar(&assetID, "asset", "", "asset ID to publish")
+	flags.StringVar(&propertyID, "property", "", "property ID to publish")
+	flags.StringVar(&opts.TopicPrefix, "prefix", telemetry.DEFAULTPREFIX, "first level of every topic")
+	flags.StringVar(&opts.Topics, "topics", telemetry.TOPICSNAMES, "name topic levels after names or ids")
+	flags.UintVar(&qos, "qos", 0, "QoS of published messages (0, 1 or 2)")
+	flags.BoolVar(&opts.Retain, "retain", false, "retain the last value of every topic")
+	format := flags.String("format", FORMATJSON, "payload format: json or sparkplug")
+	flags.StringVar(&spOpts.GroupID, "group", "", "sparkplug group ID, defaults to the factory")
+	flags.StringVar(&spOpts.EdgeNodeID, "edge-node", sparkplug.DEFAULTEDGENODE, "sparkplug edge node ID when assets are devices")
+	flags.StringVar(&spOpts.AssetsAs, "assets", sparkplug.ASSETSASDEVICES, "publish assets as sparkplug devices or nodes")
+	seed := flags.Int64("seed", 1, "seed for the random generators")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if qos > 2 {
+		return mqtt.ErrInvalidQoS
+	}
+	opts.QoS = byte(qos)
+
+	targets := 0
+	for _, id := range []string{factoryID, assetID, propertyID} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of -factory, -asset or -property is required")
+	}
+	if *format != FORMATJSON && *format != FORMATSPARKPLUG {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if *embedded != "" {
+		broker := mqtt.NewBroker()
+		addr, err := broker.ListenAndServe(*embedded)
+		if err != nil {
+			return err
+		}
+		defer broker.Close()
+		clientOpts.Broker = addr.String()
+		fmt.Printf("embedded broker listening on %s\n", addr)
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+	c := catalog.New(db)
+	sim := simulator.New(c, *seed)
+
+	if *format == FORMATSPARKPLUG {
+		spOpts.IDs = opts.Topics
+		publisher, err := sparkplug.New(c, sim, clientOpts, spOpts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("publishing sparkplug b to %s under %s/\n", clientOpts.Broker, sparkplug.NAMESPACE)
+		return ignoreCanceled(publisher.Run(ctx, factoryID, assetID, propertyID))
+	}
+
+	client, err := mqtt.Dial(ctx, clientOpts)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+
+	publisher, err := telemetry.New(c, sim, client, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Stop publishing if the broker drops the connection.
+		select {
+		case <-client.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("publishing to %s under %s/\n", clientOpts.Broker, opts.TopicPrefix)
+	err = publisher.Run(ctx, factoryID, assetID, propertyID)
+	select {
+	case <-client.Done():
+		return client.Err()
+	default:
+	}
+	return ignoreCanceled(err)
+}
+
+func ignoreCanceled(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/opcua"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/types"
+)
+
+func runOPCUA(ctx context.Context, args []string) error {
+	var factoryID, assetID, propertyID string
+
+	flags := flag.NewFlagSet("opcua", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:4840", "address to serve OPC UA on")
+	endpoint := flags.String("endpoint", "", "endpoint URL to advertise (default opc.tcp://<addr>)")
+	flags.StringVar(&factoryID, "factory", "", "factory ID to serve")
+	flags.StringVar(&assetID, "asset", "", "asset ID to serve")
+	flags.StringVar(&propertyID, "property", "", "property ID to serve")
+	seed := flags.Int64("seed", 1, "seed for the random generators")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	targets := 0
+	for _, id := range []string{factoryID, assetID, propertyID} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of -factory, -asset or -property is required")
+	}
+
+	db, err := newDynamoDBClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+	c := catalog.New(db)
+	space, series, err := opcua.Load(ctx, c, factoryID, assetID, propertyID)
+	if err != nil {
+		return err
+	}
+
+	server := opcua.NewServer(space)
+	server.EndpointURL = *endpoint
+	if _, err = server.ListenAndServe(*addr); err != nil {
+		return err
+	}
+	defer server.Close()
+	fmt.Fprintf(os.Stderr, "serving %d properties on %s\n", len(series), server.EndpointURL)
+
+	err = simulator.New(c, *seed).Run(ctx, series, func(_ catalog.Series, reading types.Reading) {
+		server.Update(reading)
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
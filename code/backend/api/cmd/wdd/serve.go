@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	"wdd/api/internal/server"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func runServe(ctx context.Context, args []string) error {
+	var cfg server.Config
+
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Int64Var(&cfg.Seed, "seed", 1, "seed for the random generators of the live simulation")
+	flags.DurationVar(&cfg.Heartbeat, "heartbeat", server.DEFAULTHEARTBEAT, "default interval between stream heartbeats")
+	region := flags.String("region", AWSREGION, "AWS region")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	awsCfg, err := loadConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	s3Client := s3.NewFromConfig(awsCfg)
+	cfg.DynamoDB = dynamodb.NewFromConfig(awsCfg)
+	cfg.S3Uploader = manager.NewUploader(s3Client)
+	cfg.S3Presigner = s3.NewPresignClient(s3Client)
+	cfg.Cognito = cognitoidentityprovider.NewFromConfig(awsCfg)
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(ctx, cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Cancel streams along with the command so shutdown is not held up by them.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		fmt.Printf("listening on http://%s\n", *addr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
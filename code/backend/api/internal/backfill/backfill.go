@@ -0,0 +1,250 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+)
+
+const (
+	DEFAULTCONCURRENCY = 4
+	MAXCONCURRENCY     = 32
+	CHUNKSIZE          = timeseries.BATCHSIZE * 20
+)
+
+var (
+	ErrInvalidRequest  = errors.New("invalid backfill request")
+	ErrTooManyReadings = errors.New("backfill window produces too many readings")
+	ErrNoSeries        = errors.New("no series with an offline generator")
+)
+
+type Request struct {
+	FactoryID   string    `json:"factoryId,omitempty"`
+	AssetID     string    `json:"assetId,omitempty"`
+	PropertyID  string    `json:"propertyId,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Seed        int64     `json:"seed"`
+	Concurrency int       `json:"concurrency,omitempty"`
+	// IgnoreCalendar generates every series around the clock at full amplitude.
+	IgnoreCalendar bool `json:"ignoreCalendar,omitempty"`
+}
+
+type Stats struct {
+	Series            int     `json:"series"`
+	Skipped           int     `json:"skipped"`
+	Readings          int64   `json:"readings"`
+	Batches           int64   `json:"batches"`
+	DurationSeconds   float64 `json:"durationSeconds"`
+	ReadingsPerSecond float64 `json:"readingsPerSecond"`
+}
+
+type Writer interface {
+	Write(ctx context.Context, readings []types.Reading) error
+}
+
+type Backfiller struct {
+	Catalog *catalog.Catalog
+	Store   Writer
+	// Limit caps the number of readings a single run may produce; zero means no limit.
+	Limit int64
+}
+
+type job struct {
+	readings []types.Reading
+}
+
+type plannedSeries struct {
+	series    catalog.Series
+	generator generators.Generator
+	interval  time.Duration
+	start     time.Time
+}
+
+func New(db types.DynamoDBClient) *Backfiller {
+	return &Backfiller{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+	}
+}
+
+func (r Request) Validate() error {
+	targets := 0
+	for _, id := range []string{r.FactoryID, r.AssetID, r.PropertyID} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("%w: exactly one of factoryId, assetId or propertyId is required", ErrInvalidRequest)
+	}
+	if r.Start.IsZero() || r.End.IsZero() || !r.End.After(r.Start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidRequest)
+	}
+	if r.Concurrency < 0 || r.Concurrency > MAXCONCURRENCY {
+		return fmt.Errorf("%w: concurrency must be at most %d", ErrInvalidRequest, MAXCONCURRENCY)
+	}
+	return nil
+}
+
+func (b Backfiller) Run(ctx context.Context, request Request) (Stats, error) {
+	if err := request.Validate(); err != nil {
+		return Stats{}, err
+	}
+
+	plan, stats, err := b.plan(ctx, request)
+	if err != nil {
+		return stats, err
+	}
+
+	concurrency := request.Concurrency
+	if concurrency == 0 {
+		concurrency = DEFAULTCONCURRENCY
+	}
+
+	started := time.Now()
+	err = b.write(ctx, plan, request, concurrency, &stats)
+	stats.DurationSeconds = time.Since(started).Seconds()
+	if stats.DurationSeconds > 0 {
+		stats.ReadingsPerSecond = float64(stats.Readings) / stats.DurationSeconds
+	}
+	return stats, err
+}
+
+func (b Backfiller) plan(ctx context.Context, request Request) ([]plannedSeries, Stats, error) {
+	var stats Stats
+
+	series, err := b.Catalog.Series(ctx, request.FactoryID, request.AssetID, request.PropertyID)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	sim := simulator.New(b.Catalog, request.Seed)
+	sim.IgnoreCalendar = request.IgnoreCalendar
+
+	plan := make([]plannedSeries, 0, len(series))
+	planned := make(map[string]bool, len(series))
+	var expected int64
+	for _, s := range series {
+		// Properties belong to models, so assets sharing a model share their
+		// series; each is generated once, on the calendar of its first asset.
+		if planned[s.Property.PropertyID] {
+			continue
+		}
+		planned[s.Property.PropertyID] = true
+
+		generator, err := sim.Generator(ctx, s)
+		if errors.Is(err, generators.ErrUnsupportedGenerator) {
+			stats.Skipped++
+			continue
+		}
+		if err != nil {
+			return nil, stats, err
+		}
+
+		// Start on the first aligned instant so backfilled readings line up
+		// with live ones.
+		interval := generators.Interval(s.Measurement)
+		start := simulator.Align(request.Start, interval)
+		if start.Before(request.Start) {
+			start = start.Add(interval)
+		}
+		expected += int64(request.End.Sub(start) / interval)
+		plan = append(plan, plannedSeries{series: s, generator: generator, interval: interval, start: start})
+	}
+
+	stats.Series = len(plan)
+	if len(plan) == 0 {
+		return nil, stats, ErrNoSeries
+	}
+	if b.Limit > 0 && expected > b.Limit {
+		return nil, stats, fmt.Errorf("%w: %d readings requested, limit is %d", ErrTooManyReadings, expected, b.Limit)
+	}
+	return plan, stats, nil
+}
+
+func (b Backfiller) write(ctx context.Context, plan []plannedSeries, request Request, concurrency int, stats *Stats) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan job)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		written  int64
+		batches  int64
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := b.Store.Write(ctx, j.readings); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				atomic.AddInt64(&written, int64(len(j.readings)))
+				atomic.AddInt64(&batches, int64((len(j.readings)+timeseries.BATCHSIZE-1)/timeseries.BATCHSIZE))
+			}
+		}()
+	}
+
+	produceErr := produce(ctx, plan, request, jobs)
+	close(jobs)
+	wg.Wait()
+
+	stats.Readings = written
+	stats.Batches = batches
+	if firstErr != nil {
+		return firstErr
+	}
+	return produceErr
+}
+
+func produce(ctx context.Context, plan []plannedSeries, request Request, jobs chan<- job) error {
+	for _, p := range plan {
+		chunk := make([]types.Reading, 0, CHUNKSIZE)
+		for t := p.start; t.Before(request.End); t = t.Add(p.interval) {
+			chunk = append(chunk, types.Reading{
+				PropertyID: p.series.Property.PropertyID,
+				Timestamp:  t.UnixMilli(),
+				Value:      p.generator.Value(t),
+			})
+			if len(chunk) < CHUNKSIZE {
+				continue
+			}
+			if err := send(ctx, jobs, chunk); err != nil {
+				return err
+			}
+			chunk = make([]types.Reading, 0, CHUNKSIZE)
+		}
+		if len(chunk) > 0 {
+			if err := send(ctx, jobs, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func send(ctx context.Context, jobs chan<- job, chunk []types.Reading) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case jobs <- job{readings: chunk}:
+		return nil
+	}
+}
@@ -0,0 +1,214 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wdd/api/internal/types"
+
+	// Lambda images do not ship a zoneinfo database, so embed one.
+	_ "time/tzdata"
+)
+
+const (
+	RUNNING = "running"
+	BREAK   = "break"
+	OFF     = "off"
+	HOLIDAY = "holiday"
+
+	minutesPerDay = 24 * 60
+)
+
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+type shift struct {
+	name   string
+	days   map[time.Weekday]bool
+	span   span
+	breaks []span
+}
+
+// Schedule is a compiled calendar that answers what a factory is doing at a
+// given instant. Shifts whose end is not after their start run past midnight.
+type Schedule struct {
+	location  *time.Location
+	shifts    []shift
+	holidays  map[string]bool
+	idleLevel float64
+}
+
+type State struct {
+	State string `json:"state"`
+	Shift string `json:"shift,omitempty"`
+}
+
+func Compile(cal types.Calendar) (*Schedule, error) {
+	schedule := &Schedule{
+		location: time.UTC,
+		holidays: map[string]bool{},
+	}
+
+	if cal.Timezone != nil && *cal.Timezone != "" {
+		location, err := time.LoadLocation(*cal.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidCalendar, *cal.Timezone)
+		}
+		schedule.location = location
+	}
+
+	if cal.IdleLevel != nil {
+		if *cal.IdleLevel < 0 || *cal.IdleLevel > 1 {
+			return nil, fmt.Errorf("%w: idleLevel must be between 0 and 1", ErrInvalidCalendar)
+		}
+		schedule.idleLevel = *cal.IdleLevel
+	}
+
+	for _, s := range cal.Shifts {
+		compiled, err := compileShift(s)
+		if err != nil {
+			return nil, err
+		}
+		schedule.shifts = append(schedule.shifts, compiled)
+	}
+
+	for _, holiday := range cal.Holidays {
+		date, err := time.Parse("2006-01-02", holiday.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: holiday date %q must be YYYY-MM-DD", ErrInvalidCalendar, holiday.Date)
+		}
+		schedule.holidays[date.Format("2006-01-02")] = true
+	}
+
+	return schedule, nil
+}
+
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// State reports what the factory is doing at t. A calendar without shifts is
+// treated as running around the clock, apart from its holidays.
+func (s *Schedule) State(t time.Time) State {
+	local := t.In(s.location)
+	if len(s.shifts) == 0 {
+		if s.holidays[local.Format("2006-01-02")] {
+			return State{State: HOLIDAY}
+		}
+		return State{State: RUNNING}
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	yesterday := local.AddDate(0, 0, -1)
+	for _, sh := range s.shifts {
+		if sh.days[local.Weekday()] && sh.span.contains(minute) {
+			return s.shiftState(sh, local, minute)
+		}
+		if sh.days[yesterday.Weekday()] && sh.span.contains(minute+minutesPerDay) {
+			return s.shiftState(sh, yesterday, minute+minutesPerDay)
+		}
+	}
+	return State{State: OFF}
+}
+
+// Activity scales simulated output: 1 while running and the idle level during
+// breaks, holidays and off hours.
+func (s *Schedule) Activity(t time.Time) float64 {
+	if s.State(t).State == RUNNING {
+		return 1
+	}
+	return s.idleLevel
+}
+
+func (s *Schedule) shiftState(sh shift, startDay time.Time, minute int) State {
+	if s.holidays[startDay.Format("2006-01-02")] {
+		return State{State: HOLIDAY, Shift: sh.name}
+	}
+	for _, b := range sh.breaks {
+		if b.contains(minute) {
+			return State{State: BREAK, Shift: sh.name}
+		}
+	}
+	return State{State: RUNNING, Shift: sh.name}
+}
+
+func compileShift(s types.Shift) (shift, error) {
+	compiled := shift{name: s.Name, days: map[time.Weekday]bool{}}
+
+	if len(s.Days) == 0 {
+		return compiled, fmt.Errorf("%w: shift %q has no days", ErrInvalidCalendar, s.Name)
+	}
+	for _, day := range s.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return compiled, fmt.Errorf("%w: unknown day %q in shift %q", ErrInvalidCalendar, day, s.Name)
+		}
+		compiled.days[weekday] = true
+	}
+
+	var err error
+	if compiled.span, err = parseSpan(s.Start, s.End, 0); err != nil {
+		return compiled, fmt.Errorf("%w in shift %q", err, s.Name)
+	}
+
+	for _, b := range s.Breaks {
+		breakSpan, err := parseSpan(b.Start, b.End, compiled.span.start)
+		if err != nil {
+			return compiled, fmt.Errorf("%w in break %q of shift %q", err, b.Name, s.Name)
+		}
+		if breakSpan.start < compiled.span.start || breakSpan.end > compiled.span.end {
+			return compiled, fmt.Errorf("%w: break %q falls outside shift %q", ErrInvalidCalendar, b.Name, s.Name)
+		}
+		compiled.breaks = append(compiled.breaks, breakSpan)
+	}
+
+	return compiled, nil
+}
+
+// parseSpan turns "HH:MM" bounds into minutes, moving times before notBefore
+// and ends not after their start onto the following day.
+func parseSpan(start, end string, notBefore int) (span, error) {
+	startMinute, err := parseClock(start)
+	if err != nil {
+		return span{}, err
+	}
+	endMinute, err := parseClock(end)
+	if err != nil {
+		return span{}, err
+	}
+
+	if startMinute < notBefore {
+		startMinute += minutesPerDay
+	}
+	for endMinute <= startMinute {
+		endMinute += minutesPerDay
+	}
+	return span{start: startMinute, end: endMinute}, nil
+}
+
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q must be HH:MM", ErrInvalidCalendar, clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func (s span) contains(minute int) bool {
+	return minute >= s.start && minute < s.end
+}
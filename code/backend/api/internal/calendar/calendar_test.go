@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wdd/api/internal/types"
+)
+
+func mustCompile(t *testing.T, cal types.Calendar) *Schedule {
+	t.Helper()
+	schedule, err := Compile(cal)
+	if err != nil {
+		t.Fatalf("Expected calendar to compile, got %v", err)
+	}
+	return schedule
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tz := "Nowhere/Special"
+	cases := map[string]types.Calendar{
+		"timezone": {Timezone: &tz},
+		"day":      {Shifts: []types.Shift{{Days: []string{"funday"}, Start: "06:00", End: "14:00"}}},
+		"clock":    {Shifts: []types.Shift{{Days: []string{"monday"}, Start: "6am", End: "14:00"}}},
+		"break":    {Shifts: []types.Shift{{Days: []string{"monday"}, Start: "06:00", End: "14:00", Breaks: []types.ShiftBreak{{Start: "15:00", End: "15:30"}}}}},
+		"holiday":  {Holidays: []types.Holiday{{Date: "25/12/2024"}}},
+	}
+
+	for name, cal := range cases {
+		if _, err := Compile(cal); !errors.Is(err, ErrInvalidCalendar) {
+			t.Errorf("Expected ErrInvalidCalendar for invalid %s, got %v", name, err)
+		}
+	}
+}
+
+func TestSchedule_State(t *testing.T) {
+	tz := "America/Chicago"
+	idle := 0.2
+	schedule := mustCompile(t, types.Calendar{
+		Timezone:  &tz,
+		IdleLevel: &idle,
+		Shifts: []types.Shift{
+			{Name: "Day", Days: []string{"Monday"}, Start: "06:00", End: "14:00", Breaks: []types.ShiftBreak{{Start: "10:00", End: "10:30"}}},
+			{Name: "Night", Days: []string{"monday"}, Start: "22:00", End: "06:00", Breaks: []types.ShiftBreak{{Start: "02:00", End: "02:30"}}},
+		},
+		Holidays: []types.Holiday{{Date: "2024-01-08"}},
+	})
+
+	location, _ := time.LoadLocation(tz)
+	cases := []struct {
+		at       time.Time
+		state    string
+		activity float64
+	}{
+		{time.Date(2024, 1, 1, 7, 0, 0, 0, location), RUNNING, 1},
+		{time.Date(2024, 1, 1, 10, 15, 0, 0, location), BREAK, idle},
+		{time.Date(2024, 1, 1, 18, 0, 0, 0, location), OFF, idle},
+		{time.Date(2024, 1, 2, 1, 0, 0, 0, location), RUNNING, 1},
+		{time.Date(2024, 1, 2, 2, 10, 0, 0, location), BREAK, idle},
+		{time.Date(2024, 1, 2, 7, 0, 0, 0, location), OFF, idle},
+		{time.Date(2024, 1, 8, 7, 0, 0, 0, location), HOLIDAY, idle},
+	}
+
+	for _, c := range cases {
+		if state := schedule.State(c.at); state.State != c.state {
+			t.Errorf("Expected %s at %s, got %s", c.state, c.at, state.State)
+		}
+		if activity := schedule.Activity(c.at); activity != c.activity {
+			t.Errorf("Expected activity %f at %s, got %f", c.activity, c.at, activity)
+		}
+	}
+}
+
+func TestSchedule_NoShiftsRunsAroundTheClock(t *testing.T) {
+	schedule := mustCompile(t, types.Calendar{Holidays: []types.Holiday{{Date: "2024-12-25"}}})
+
+	if state := schedule.State(time.Date(2024, 12, 24, 3, 0, 0, 0, time.UTC)); state.State != RUNNING {
+		t.Errorf("Expected running without shifts, got %s", state.State)
+	}
+	if state := schedule.State(time.Date(2024, 12, 25, 3, 0, 0, 0, time.UTC)); state.State != HOLIDAY {
+		t.Errorf("Expected holiday, got %s", state.State)
+	}
+}
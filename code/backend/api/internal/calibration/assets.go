@@ -0,0 +1,49 @@
+package calibration
+
+import (
+	"context"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+// Locate sets the PositionMeters of every asset placed on a calibrated
+// floorplan, reading each floorplan once. Assets on uncalibrated or missing
+// floorplans are left without one.
+func Locate(ctx context.Context, c *catalog.Catalog, assets []types.Asset) error {
+	var ids []string
+	for _, asset := range assets {
+		if _, ok := Pixel(asset.FloorplanCoords); ok && asset.FloorplanID != nil {
+			ids = append(ids, *asset.FloorplanID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	floorplans, err := c.Floorplans(ctx, ids)
+	if err != nil {
+		return err
+	}
+	transforms := map[string]*Transform{}
+	for id, floorplan := range floorplans {
+		if floorplan.Calibration == nil {
+			continue
+		}
+		// A stored calibration was checked when it was set.
+		if t, err := Compile(*floorplan.Calibration); err == nil {
+			transforms[id] = t
+		}
+	}
+
+	for i := range assets {
+		pixel, ok := Pixel(assets[i].FloorplanCoords)
+		if !ok || assets[i].FloorplanID == nil {
+			continue
+		}
+		if t, ok := transforms[*assets[i].FloorplanID]; ok {
+			meters := t.ToMeters(pixel)
+			assets[i].PositionMeters = &meters
+		}
+	}
+	return nil
+}
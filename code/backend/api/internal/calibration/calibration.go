@@ -0,0 +1,162 @@
+package calibration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+)
+
+// DEFAULTBEARING points the x axis of the shop floor east, and so its y axis
+// north, when a calibration places the floor on the globe without a bearing.
+const DEFAULTBEARING = 90
+
+var ErrInvalidCalibration = errors.New("invalid calibration")
+
+// Transform converts between the pixels of a floorplan image, meters on its
+// shop floor and, when the floor is placed on the globe, geographic
+// coordinates. Pixels have y growing down and meters y growing up; with
+// both as complex numbers, a pixel q with its y negated is a meter position
+// a*q + b, a similarity that scales by |a| and rotates by arg(a).
+type Transform struct {
+	a, b    complex128
+	origin  *geo.Point
+	bearing float64
+}
+
+// Compile checks a calibration and builds its transform.
+func Compile(c types.Calibration) (*Transform, error) {
+	t := &Transform{}
+	switch {
+	case len(c.References) > 0 && c.MetersPerPixel != nil:
+		return nil, fmt.Errorf("%w: give either references or metersPerPixel", ErrInvalidCalibration)
+	case len(c.References) > 0:
+		if len(c.References) != 2 {
+			return nil, fmt.Errorf("%w: expected 2 references, got %d", ErrInvalidCalibration, len(c.References))
+		}
+		q1, q2 := flip(c.References[0].Pixel), flip(c.References[1].Pixel)
+		m1, m2 := point(c.References[0].Meters), point(c.References[1].Meters)
+		if !finite(q1, q2, m1, m2) {
+			return nil, fmt.Errorf("%w: references must be finite", ErrInvalidCalibration)
+		}
+		if q1 == q2 || m1 == m2 {
+			return nil, fmt.Errorf("%w: references must be distinct pixels and positions", ErrInvalidCalibration)
+		}
+		t.a = (m2 - m1) / (q2 - q1)
+		t.b = m1 - t.a*q1
+	case c.MetersPerPixel != nil:
+		scale, rotation := *c.MetersPerPixel, 0.0
+		if c.RotationDegrees != nil {
+			rotation = *c.RotationDegrees
+		}
+		if math.IsNaN(scale) || math.IsInf(scale, 0) || scale <= 0 || math.IsNaN(rotation) || math.IsInf(rotation, 0) {
+			return nil, fmt.Errorf("%w: metersPerPixel must be positive and rotationDegrees finite", ErrInvalidCalibration)
+		}
+		var origin complex128
+		if c.Origin != nil {
+			if origin = flip(*c.Origin); !finite(origin) {
+				return nil, fmt.Errorf("%w: origin must be finite", ErrInvalidCalibration)
+			}
+		}
+		t.a = cmplx.Rect(scale, rotation*math.Pi/180)
+		t.b = -t.a * origin
+	default:
+		return nil, fmt.Errorf("%w: give references or metersPerPixel", ErrInvalidCalibration)
+	}
+
+	if c.GeoOrigin != nil {
+		origin, ok := geo.LocationPoint(c.GeoOrigin)
+		if !ok {
+			return nil, fmt.Errorf("%w: geoOrigin must have a valid latitude and longitude", ErrInvalidCalibration)
+		}
+		t.origin, t.bearing = &origin, DEFAULTBEARING
+		if c.BearingDegrees != nil {
+			if math.IsNaN(*c.BearingDegrees) || math.IsInf(*c.BearingDegrees, 0) {
+				return nil, fmt.Errorf("%w: bearingDegrees must be finite", ErrInvalidCalibration)
+			}
+			t.bearing = *c.BearingDegrees
+		}
+	}
+	return t, nil
+}
+
+// MetersPerPixel is the scale of the image.
+func (t *Transform) MetersPerPixel() float64 {
+	return cmplx.Abs(t.a)
+}
+
+// RotationDegrees is how far the image is turned counterclockwise onto the
+// meter axes, in (-180, 180].
+func (t *Transform) RotationDegrees() float64 {
+	return cmplx.Phase(t.a) * 180 / math.Pi
+}
+
+func (t *Transform) ToMeters(pixel types.Position) types.Position {
+	return position(t.a*flip(pixel) + t.b)
+}
+
+func (t *Transform) ToPixels(meters types.Position) types.Position {
+	q := (point(meters) - t.b) / t.a
+	return types.Position{X: real(q), Y: -imag(q)}
+}
+
+// ToGeo places a floor position on the globe, in the plane tangent to the
+// geographic origin, which is exact enough over the extent of a factory.
+// It returns false when the calibration has no geographic origin.
+func (t *Transform) ToGeo(meters types.Position) (geo.Point, bool) {
+	if t.origin == nil {
+		return geo.Point{}, false
+	}
+	sin, cos := math.Sincos(t.bearing * math.Pi / 180)
+	east := meters.X*sin - meters.Y*cos
+	north := meters.X*cos + meters.Y*sin
+	radius := geo.EARTHRADIUSKM * 1000
+	return geo.Point{
+		Lat: t.origin.Lat + north/radius*180/math.Pi,
+		Lon: t.origin.Lon + east/(radius*math.Cos(t.origin.Lat*math.Pi/180))*180/math.Pi,
+	}, true
+}
+
+// FromGeo is the inverse of ToGeo.
+func (t *Transform) FromGeo(p geo.Point) (types.Position, bool) {
+	if t.origin == nil {
+		return types.Position{}, false
+	}
+	radius := geo.EARTHRADIUSKM * 1000
+	north := (p.Lat - t.origin.Lat) * math.Pi / 180 * radius
+	east := (p.Lon - t.origin.Lon) * math.Pi / 180 * radius * math.Cos(t.origin.Lat*math.Pi/180)
+	sin, cos := math.Sincos(t.bearing * math.Pi / 180)
+	return types.Position{X: east*sin + north*cos, Y: -east*cos + north*sin}, true
+}
+
+// Pixel returns the pixel position of floorplan coordinates, or false when
+// either is missing.
+func Pixel(coords *types.FloorplanCoords) (types.Position, bool) {
+	if coords == nil || coords.Longitude == nil || coords.Latitude == nil {
+		return types.Position{}, false
+	}
+	return types.Position{X: *coords.Longitude, Y: *coords.Latitude}, true
+}
+
+func flip(p types.Position) complex128 {
+	return complex(p.X, -p.Y)
+}
+
+func point(p types.Position) complex128 {
+	return complex(p.X, p.Y)
+}
+
+func position(z complex128) types.Position {
+	return types.Position{X: real(z), Y: imag(z)}
+}
+
+func finite(values ...complex128) bool {
+	for _, z := range values {
+		if cmplx.IsNaN(z) || cmplx.IsInf(z) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package calibration
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+)
+
+func near(a, b types.Position) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestCompile_References(t *testing.T) {
+	// 100 pixels across the top of the image are 5 meters along a wall 10
+	// meters north of the origin.
+	transform, err := Compile(types.Calibration{References: []types.CalibrationReference{
+		{Pixel: types.Position{X: 0, Y: 0}, Meters: types.Position{X: 0, Y: 10}},
+		{Pixel: types.Position{X: 100, Y: 0}, Meters: types.Position{X: 5, Y: 10}},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if math.Abs(transform.MetersPerPixel()-0.05) > 1e-12 || transform.RotationDegrees() != 0 {
+		t.Errorf("Expected 0.05 m/px unrotated, got %g %g", transform.MetersPerPixel(), transform.RotationDegrees())
+	}
+	if m := transform.ToMeters(types.Position{X: 0, Y: 200}); !near(m, types.Position{X: 0, Y: 0}) {
+		t.Errorf("Expected the pixel 200 below the top to be the origin, got %+v", m)
+	}
+	pixel := types.Position{X: 37, Y: 81}
+	if back := transform.ToPixels(transform.ToMeters(pixel)); !near(back, pixel) {
+		t.Errorf("Expected %+v back, got %+v", pixel, back)
+	}
+}
+
+func TestCompile_Scale(t *testing.T) {
+	scale, rotation := 0.1, 90.0
+	transform, err := Compile(types.Calibration{MetersPerPixel: &scale, RotationDegrees: &rotation, Origin: &types.Position{X: 50, Y: 50}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Rotated a quarter turn, right in the image is up on the floor.
+	if m := transform.ToMeters(types.Position{X: 60, Y: 50}); !near(m, types.Position{X: 0, Y: 1}) {
+		t.Errorf("Expected (0, 1), got %+v", m)
+	}
+	if math.Abs(transform.RotationDegrees()-90) > 1e-9 {
+		t.Errorf("Expected a rotation of 90, got %g", transform.RotationDegrees())
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	zero, scale := 0.0, 1.0
+	same := types.CalibrationReference{Pixel: types.Position{X: 1, Y: 1}, Meters: types.Position{X: 1, Y: 1}}
+	cases := []types.Calibration{
+		{},
+		{MetersPerPixel: &zero},
+		{References: []types.CalibrationReference{same}},
+		{References: []types.CalibrationReference{same, same}},
+		{References: []types.CalibrationReference{same, {}}, MetersPerPixel: &scale},
+		{MetersPerPixel: &scale, GeoOrigin: &types.Location{}},
+	}
+	for _, c := range cases {
+		if _, err := Compile(c); !errors.Is(err, ErrInvalidCalibration) {
+			t.Errorf("Expected ErrInvalidCalibration for %+v, got %v", c, err)
+		}
+	}
+}
+
+func TestGeo(t *testing.T) {
+	scale, lat, lon := 1.0, 48.78, 9.18
+	calibration := types.Calibration{MetersPerPixel: &scale, GeoOrigin: &types.Location{Latitude: &lat, Longitude: &lon}}
+	transform, err := Compile(calibration)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	east, _ := transform.ToGeo(types.Position{X: 100})
+	if math.Abs(east.Lat-lat) > 1e-9 || east.Lon <= lon || math.Abs(geo.DistanceKm(geo.Point{Lat: lat, Lon: lon}, east)-0.1) > 1e-4 {
+		t.Errorf("Expected a point 100m east, got %+v", east)
+	}
+	if back, _ := transform.FromGeo(east); !near(back, types.Position{X: 100}) {
+		t.Errorf("Expected (100, 0) back, got %+v", back)
+	}
+
+	bearing := 0.0
+	calibration.BearingDegrees = &bearing
+	transform, _ = Compile(calibration)
+	if north, _ := transform.ToGeo(types.Position{X: 100}); north.Lat <= lat || math.Abs(north.Lon-lon) > 1e-9 {
+		t.Errorf("Expected x to point north with a bearing of 0, got %+v", north)
+	}
+
+	calibration.GeoOrigin = nil
+	transform, _ = Compile(calibration)
+	if _, ok := transform.ToGeo(types.Position{}); ok {
+		t.Errorf("Expected no geographic conversion without a geoOrigin")
+	}
+}
@@ -0,0 +1,44 @@
+package calibration
+
+import (
+	"math"
+	"wdd/api/internal/types"
+)
+
+// Remap returns the function moving the pixels of one version of a
+// floorplan onto the image of another. When both versions are calibrated a
+// pixel keeps its spot on the shop floor; otherwise the image is assumed to
+// show the same area, so pixels scale with its dimensions, or stay put when
+// either image's dimensions are unknown.
+func Remap(from, to types.Floorplan) func(types.Position) types.Position {
+	if from.Calibration != nil && to.Calibration != nil {
+		source, sourceErr := Compile(*from.Calibration)
+		target, targetErr := Compile(*to.Calibration)
+		if sourceErr == nil && targetErr == nil {
+			return func(p types.Position) types.Position {
+				return target.ToPixels(source.ToMeters(p))
+			}
+		}
+	}
+
+	if from.Width == nil || from.Height == nil || to.Width == nil || to.Height == nil || *from.Width == 0 || *from.Height == 0 {
+		return func(p types.Position) types.Position { return p }
+	}
+	sx := float64(*to.Width) / float64(*from.Width)
+	sy := float64(*to.Height) / float64(*from.Height)
+	return func(p types.Position) types.Position {
+		return types.Position{X: p.X * sx, Y: p.Y * sy}
+	}
+}
+
+// Clamp moves a pixel onto the image of a floorplan, reporting whether it
+// had to. Floorplans of unknown dimensions only clamp to non-negative
+// pixels.
+func Clamp(p types.Position, floorplan types.Floorplan) (types.Position, bool) {
+	width, height := math.Inf(1), math.Inf(1)
+	if floorplan.Width != nil && floorplan.Height != nil {
+		width, height = float64(*floorplan.Width), float64(*floorplan.Height)
+	}
+	clamped := types.Position{X: math.Max(0, math.Min(width, p.X)), Y: math.Max(0, math.Min(height, p.Y))}
+	return clamped, clamped != p
+}
@@ -0,0 +1,48 @@
+package calibration
+
+import (
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func TestRemap(t *testing.T) {
+	size := func(width, height int) types.Floorplan {
+		return types.Floorplan{Width: &width, Height: &height}
+	}
+	p := types.Position{X: 10, Y: 20}
+
+	if got := Remap(size(100, 50), size(200, 100))(p); got != (types.Position{X: 20, Y: 40}) {
+		t.Errorf("Expected pixels to scale with the image, got %v", got)
+	}
+	if got := Remap(types.Floorplan{}, size(200, 100))(p); got != p {
+		t.Errorf("Expected pixels to stay put without dimensions, got %v", got)
+	}
+
+	// The new image shows the same floor at twice the resolution, shifted
+	// by a 10m margin.
+	from, to := size(100, 50), size(240, 140)
+	metersPerPixel := 1.0
+	from.Calibration = &types.Calibration{MetersPerPixel: &metersPerPixel}
+	to.Calibration = &types.Calibration{References: []types.CalibrationReference{
+		{Pixel: types.Position{X: 20, Y: 20}, Meters: types.Position{X: 0, Y: 0}},
+		{Pixel: types.Position{X: 220, Y: 20}, Meters: types.Position{X: 100, Y: 0}},
+	}}
+	if got := Remap(from, to)(p); !near(got, types.Position{X: 40, Y: 60}) {
+		t.Errorf("Expected pixels to keep their spot on the floor, got %v", got)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	width, height := 100, 50
+	floorplan := types.Floorplan{Width: &width, Height: &height}
+
+	if got, clamped := Clamp(types.Position{X: 10, Y: 20}, floorplan); clamped || got != (types.Position{X: 10, Y: 20}) {
+		t.Errorf("Expected a pixel on the image to stay put, got %v", got)
+	}
+	if got, clamped := Clamp(types.Position{X: 120, Y: -5}, floorplan); !clamped || got != (types.Position{X: 100, Y: 0}) {
+		t.Errorf("Expected a pixel off the image clamped to its edge, got %v", got)
+	}
+	if got, clamped := Clamp(types.Position{X: 1e6, Y: 1e6}, types.Floorplan{}); clamped || got.X != 1e6 {
+		t.Errorf("Expected no upper bound without dimensions, got %v", got)
+	}
+}
@@ -0,0 +1,599 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	ASSETTABLE       = "Asset"
+	MODELTABLE       = "Model"
+	PROPERTYTABLE    = "Property"
+	MEASUREMENTTABLE = "Measurement"
+	CALENDARTABLE    = "Calendar"
+	FACTORYTABLE     = "Factory"
+	RETENTIONTABLE   = "RetentionPolicy"
+	LINEMAPPINGTABLE = "LineProtocolMapping"
+	FLOORPLANTABLE   = "Floorplan"
+	ZONETABLE        = "Zone"
+	// FLOORPLANVERSIONTABLE keeps every published version of a floorplan,
+	// keyed by floorplanId and version.
+	FLOORPLANVERSIONTABLE = "FloorplanVersion"
+)
+
+const (
+	// MAXBATCHGET is the most keys DynamoDB accepts in one BatchGetItem call.
+	MAXBATCHGET = 100
+	MAXRETRIES  = 5
+	// GEOHASHINDEX is the Factory index partitioned by geohash prefix and
+	// sorted by geohash.
+	GEOHASHINDEX = "geohash"
+	// MAXGEOHASHCELLS caps the index queries of one spatial search; larger
+	// areas are scanned instead.
+	MAXGEOHASHCELLS = 32
+	// FLOORPLANINDEX is the Asset and Zone index partitioned by floorplanId.
+	FLOORPLANINDEX = "floorplanId"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Series is a single simulated signal: one property of one asset together with
+// the measurement that drives its generator.
+type Series struct {
+	FactoryID   string            `json:"factoryId,omitempty"`
+	AssetID     string            `json:"assetId,omitempty"`
+	AssetName   string            `json:"assetName,omitempty"`
+	Property    types.Property    `json:"property"`
+	Measurement types.Measurement `json:"measurement"`
+}
+
+type Catalog struct {
+	DynamoDB types.DynamoDBClient
+}
+
+func New(db types.DynamoDBClient) *Catalog {
+	return &Catalog{
+		DynamoDB: db,
+	}
+}
+
+// Series resolves the series behind exactly one of a factory, asset or property ID.
+func (c Catalog) Series(ctx context.Context, factoryID, assetID, propertyID string) ([]Series, error) {
+	switch {
+	case factoryID != "":
+		return c.FactorySeries(ctx, factoryID)
+	case assetID != "":
+		return c.AssetSeries(ctx, assetID)
+	default:
+		return c.PropertySeries(ctx, propertyID)
+	}
+}
+
+func (c Catalog) FactorySeries(ctx context.Context, factoryID string) ([]Series, error) {
+	assets, err := c.FactoryAssets(ctx, factoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []Series
+	for _, asset := range assets {
+		assetSeries, err := c.assetSeries(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, assetSeries...)
+	}
+	return series, nil
+}
+
+func (c Catalog) AssetSeries(ctx context.Context, assetID string) ([]Series, error) {
+	asset, err := c.Asset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return c.assetSeries(ctx, asset)
+}
+
+// PropertySeries resolves the series of a property on its own. Properties
+// belong to models and models to factories, so the series carries the
+// factory of the model listing the property, whose calendar then applies;
+// the assets sharing the model are not told apart.
+func (c Catalog) PropertySeries(ctx context.Context, propertyID string) ([]Series, error) {
+	series, err := c.propertySeries(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	model, err := c.PropertyModel(ctx, propertyID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	series.FactoryID = model.FactoryID
+	return []Series{series}, nil
+}
+
+// PropertyMeasurement returns the measurement of a property.
+func (c Catalog) PropertyMeasurement(ctx context.Context, propertyID string) (types.Measurement, error) {
+	series, err := c.propertySeries(ctx, propertyID)
+	return series.Measurement, err
+}
+
+// PropertyModel returns the model listing a property. Nothing indexes
+// models by property, so the models are scanned.
+func (c Catalog) PropertyModel(ctx context.Context, propertyID string) (types.Model, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(MODELTABLE),
+		FilterExpression: aws.String("contains(properties, :propertyId)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":propertyId": &ddbtypes.AttributeValueMemberS{Value: propertyID},
+		},
+	}
+	for {
+		result, err := c.DynamoDB.Scan(ctx, input)
+		if err != nil {
+			return types.Model{}, fmt.Errorf("error scanning models: %w", err)
+		}
+		if len(result.Items) > 0 {
+			var model types.Model
+			if err = wrappers.UnmarshalMap(result.Items[0], &model); err != nil {
+				return types.Model{}, fmt.Errorf("failed to unmarshal model: %w", err)
+			}
+			return model, nil
+		}
+		if len(result.LastEvaluatedKey) == 0 {
+			return types.Model{}, fmt.Errorf("model of property %s: %w", propertyID, ErrNotFound)
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (c Catalog) propertySeries(ctx context.Context, propertyID string) (Series, error) {
+	property, err := c.Property(ctx, propertyID)
+	if err != nil {
+		return Series{}, err
+	}
+	measurement, err := c.Measurement(ctx, property.MeasurementID)
+	if err != nil {
+		return Series{}, err
+	}
+	return Series{Property: property, Measurement: measurement}, nil
+}
+
+// FactoryAssets queries every asset of a factory, following pagination.
+func (c Catalog) FactoryAssets(ctx context.Context, factoryID string) ([]types.Asset, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(ASSETTABLE),
+		IndexName:              aws.String("factoryId"),
+		KeyConditionExpression: aws.String("factoryId = :factoryId"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":factoryId": &ddbtypes.AttributeValueMemberS{Value: factoryID},
+		},
+	}
+
+	var assets []types.Asset
+	for {
+		result, err := c.DynamoDB.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error querying assets: %w", err)
+		}
+
+		var page []types.Asset
+		if err = wrappers.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal assets: %w", err)
+		}
+		assets = append(assets, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return assets, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (c Catalog) Factory(ctx context.Context, factoryID string) (types.Factory, error) {
+	var factory types.Factory
+	err := c.getItem(ctx, FACTORYTABLE, "factoryId", factoryID, &factory)
+	return factory, err
+}
+
+func (c Catalog) Asset(ctx context.Context, assetID string) (types.Asset, error) {
+	var asset types.Asset
+	err := c.getItem(ctx, ASSETTABLE, "assetId", assetID, &asset)
+	return asset, err
+}
+
+func (c Catalog) Floorplan(ctx context.Context, floorplanID string) (types.Floorplan, error) {
+	var floorplan types.Floorplan
+	err := c.getItem(ctx, FLOORPLANTABLE, "floorplanId", floorplanID, &floorplan)
+	return floorplan, err
+}
+
+func (c Catalog) Zone(ctx context.Context, zoneID string) (types.Zone, error) {
+	var zone types.Zone
+	err := c.getItem(ctx, ZONETABLE, "zoneId", zoneID, &zone)
+	return zone, err
+}
+
+// FloorplanVersion returns a published version of a floorplan.
+func (c Catalog) FloorplanVersion(ctx context.Context, floorplanID string, version int) (types.Floorplan, error) {
+	result, err := c.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(FLOORPLANVERSIONTABLE),
+		Key: map[string]ddbtypes.AttributeValue{
+			"floorplanId": &ddbtypes.AttributeValueMemberS{Value: floorplanID},
+			"version":     &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		},
+	})
+	if err != nil {
+		return types.Floorplan{}, fmt.Errorf("error fetching %s %s version %d: %w", FLOORPLANVERSIONTABLE, floorplanID, version, err)
+	}
+	if result.Item == nil {
+		return types.Floorplan{}, fmt.Errorf("%s %s version %d: %w", FLOORPLANVERSIONTABLE, floorplanID, version, ErrNotFound)
+	}
+	var floorplan types.Floorplan
+	if err = wrappers.UnmarshalMap(result.Item, &floorplan); err != nil {
+		return types.Floorplan{}, fmt.Errorf("failed to unmarshal %s: %w", FLOORPLANVERSIONTABLE, err)
+	}
+	return floorplan, nil
+}
+
+// FloorplanVersions returns every published version of a floorplan, oldest
+// first.
+func (c Catalog) FloorplanVersions(ctx context.Context, floorplanID string) ([]types.Floorplan, error) {
+	return queryAll[types.Floorplan](ctx, c.DynamoDB, FLOORPLANVERSIONTABLE, "", "floorplanId", floorplanID)
+}
+
+// FactoryFloorplans returns the current version of every floorplan of a
+// factory.
+func (c Catalog) FactoryFloorplans(ctx context.Context, factoryID string) ([]types.Floorplan, error) {
+	return queryAll[types.Floorplan](ctx, c.DynamoDB, FLOORPLANTABLE, "factoryId", "factoryId", factoryID)
+}
+
+// FloorplanAssets returns the assets placed on a floorplan.
+func (c Catalog) FloorplanAssets(ctx context.Context, floorplanID string) ([]types.Asset, error) {
+	return queryAll[types.Asset](ctx, c.DynamoDB, ASSETTABLE, FLOORPLANINDEX, "floorplanId", floorplanID)
+}
+
+// FloorplanZones returns the zones of a floorplan.
+func (c Catalog) FloorplanZones(ctx context.Context, floorplanID string) ([]types.Zone, error) {
+	return queryAll[types.Zone](ctx, c.DynamoDB, ZONETABLE, FLOORPLANINDEX, "floorplanId", floorplanID)
+}
+
+func (c Catalog) Model(ctx context.Context, modelID string) (types.Model, error) {
+	result, err := c.DynamoDB.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(MODELTABLE),
+		KeyConditionExpression: aws.String("modelId = :modelId"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":modelId": &ddbtypes.AttributeValueMemberS{Value: modelID},
+		},
+	})
+	if err != nil {
+		return types.Model{}, fmt.Errorf("error querying model: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return types.Model{}, fmt.Errorf("model %s: %w", modelID, ErrNotFound)
+	}
+
+	var model types.Model
+	if err = wrappers.UnmarshalMap(result.Items[0], &model); err != nil {
+		return types.Model{}, fmt.Errorf("failed to unmarshal model: %w", err)
+	}
+	return model, nil
+}
+
+func (c Catalog) Property(ctx context.Context, propertyID string) (types.Property, error) {
+	var property types.Property
+	err := c.getItem(ctx, PROPERTYTABLE, "propertyId", propertyID, &property)
+	return property, err
+}
+
+func (c Catalog) Measurement(ctx context.Context, measurementID string) (types.Measurement, error) {
+	var measurement types.Measurement
+	err := c.getItem(ctx, MEASUREMENTTABLE, "measurementId", measurementID, &measurement)
+	return measurement, err
+}
+
+// Properties fetches properties by ID in batches, keyed by ID. Unknown IDs
+// are left out.
+func (c Catalog) Properties(ctx context.Context, propertyIDs []string) (map[string]types.Property, error) {
+	properties, err := batchGet[types.Property](ctx, c.DynamoDB, PROPERTYTABLE, "propertyId", propertyIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]types.Property, len(properties))
+	for _, p := range properties {
+		byID[p.PropertyID] = p
+	}
+	return byID, nil
+}
+
+// Measurements fetches measurements by ID in batches, keyed by ID. Unknown
+// IDs are left out.
+func (c Catalog) Measurements(ctx context.Context, measurementIDs []string) (map[string]types.Measurement, error) {
+	measurements, err := batchGet[types.Measurement](ctx, c.DynamoDB, MEASUREMENTTABLE, "measurementId", measurementIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]types.Measurement, len(measurements))
+	for _, m := range measurements {
+		byID[m.MeasurementID] = m
+	}
+	return byID, nil
+}
+
+// Floorplans fetches floorplans by ID in batches, keyed by ID. Unknown IDs
+// are left out.
+func (c Catalog) Floorplans(ctx context.Context, floorplanIDs []string) (map[string]types.Floorplan, error) {
+	floorplans, err := batchGet[types.Floorplan](ctx, c.DynamoDB, FLOORPLANTABLE, "floorplanId", floorplanIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]types.Floorplan, len(floorplans))
+	for _, f := range floorplans {
+		byID[f.FloorplanID] = f
+	}
+	return byID, nil
+}
+
+func (c Catalog) Calendar(ctx context.Context, factoryID string) (types.Calendar, error) {
+	var cal types.Calendar
+	err := c.getItem(ctx, CALENDARTABLE, "factoryId", factoryID, &cal)
+	return cal, err
+}
+
+func (c Catalog) RetentionPolicy(ctx context.Context, factoryID string) (types.RetentionPolicy, error) {
+	var policy types.RetentionPolicy
+	err := c.getItem(ctx, RETENTIONTABLE, "factoryId", factoryID, &policy)
+	return policy, err
+}
+
+func (c Catalog) LineProtocolMapping(ctx context.Context, factoryID string) (types.LineProtocolMapping, error) {
+	var mapping types.LineProtocolMapping
+	err := c.getItem(ctx, LINEMAPPINGTABLE, "factoryId", factoryID, &mapping)
+	return mapping, err
+}
+
+// Factories scans every factory, following pagination.
+func (c Catalog) Factories(ctx context.Context) ([]types.Factory, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(FACTORYTABLE),
+	}
+
+	var factories []types.Factory
+	for {
+		result, err := c.DynamoDB.Scan(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning factories: %w", err)
+		}
+
+		var page []types.Factory
+		if err = wrappers.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal factories: %w", err)
+		}
+		factories = append(factories, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return factories, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// FactoriesWithin returns the factories located in a box. The box is covered
+// with the finest geohash cells that fit in MAXGEOHASHCELLS and each cell is
+// a prefix query on the geohash index; when even the coarsest cells are too
+// many, every factory is scanned instead.
+func (c Catalog) FactoriesWithin(ctx context.Context, box geo.BBox) ([]types.Factory, error) {
+	var cells []string
+	for precision := geo.GEOHASHPRECISION; precision >= geo.HASHKEYLENGTH; precision-- {
+		if cover, ok := geo.Cover(box, precision, MAXGEOHASHCELLS); ok {
+			cells = cover
+			break
+		}
+	}
+
+	var candidates []types.Factory
+	if cells == nil {
+		factories, err := c.Factories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		candidates = factories
+	}
+	for _, cell := range cells {
+		factories, err := c.factoriesInCell(ctx, cell)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, factories...)
+	}
+
+	seen := map[string]bool{}
+	var factories []types.Factory
+	for _, f := range candidates {
+		if p, ok := geo.LocationPoint(f.Location); ok && box.Contains(p) && !seen[f.FactoryID] {
+			seen[f.FactoryID] = true
+			factories = append(factories, f)
+		}
+	}
+	return factories, nil
+}
+
+func (c Catalog) factoriesInCell(ctx context.Context, cell string) ([]types.Factory, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(FACTORYTABLE),
+		IndexName:              aws.String(GEOHASHINDEX),
+		KeyConditionExpression: aws.String("geohashKey = :key AND begins_with(geohash, :cell)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":key":  &ddbtypes.AttributeValueMemberS{Value: cell[:geo.HASHKEYLENGTH]},
+			":cell": &ddbtypes.AttributeValueMemberS{Value: cell},
+		},
+	}
+
+	var factories []types.Factory
+	for {
+		result, err := c.DynamoDB.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error querying factories by geohash: %w", err)
+		}
+
+		var page []types.Factory
+		if err = wrappers.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal factories: %w", err)
+		}
+		factories = append(factories, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return factories, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (c Catalog) assetSeries(ctx context.Context, asset types.Asset) ([]Series, error) {
+	if asset.ModelID == nil || *asset.ModelID == "" {
+		return nil, nil
+	}
+
+	model, err := c.Model(ctx, *asset.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	if model.Properties == nil {
+		return nil, nil
+	}
+
+	series := make([]Series, 0, len(*model.Properties))
+	for _, propertyID := range *model.Properties {
+		s, err := c.propertySeries(ctx, propertyID)
+		if err != nil {
+			return nil, err
+		}
+		s.AssetID = asset.AssetID
+		if asset.Name != nil {
+			s.AssetName = *asset.Name
+		}
+		if asset.FactoryID != nil {
+			s.FactoryID = *asset.FactoryID
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+func (c Catalog) getItem(ctx context.Context, table, keyName, keyValue string, out interface{}) error {
+	result, err := c.DynamoDB.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]ddbtypes.AttributeValue{
+			keyName: &ddbtypes.AttributeValueMemberS{Value: keyValue},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error fetching %s %s: %w", table, keyValue, err)
+	}
+	if result.Item == nil {
+		return fmt.Errorf("%s %s: %w", table, keyValue, ErrNotFound)
+	}
+	if err = wrappers.UnmarshalMap(result.Item, out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", table, err)
+	}
+	return nil
+}
+
+// queryAll returns every item of a partition of a table, or of one of its
+// indexes when index is set, following pages.
+func queryAll[T any](ctx context.Context, db types.DynamoDBClient, table, index, keyName, keyValue string) ([]T, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(table),
+		KeyConditionExpression: aws.String("#key = :key"),
+		ExpressionAttributeNames: map[string]string{
+			"#key": keyName,
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":key": &ddbtypes.AttributeValueMemberS{Value: keyValue},
+		},
+	}
+
+	if index != "" {
+		input.IndexName = aws.String(index)
+	}
+
+	var items []T
+	for {
+		result, err := db.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error querying %s by %s: %w", table, keyName, err)
+		}
+
+		var page []T
+		if err = wrappers.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", table, err)
+		}
+		items = append(items, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// batchGet fetches the items with the given keys, MAXBATCHGET at a time,
+// retrying the keys DynamoDB leaves unprocessed with exponential backoff. Duplicate and empty keys
+// are skipped.
+func batchGet[T any](ctx context.Context, db types.DynamoDBClient, table, keyName string, keyValues []string) ([]T, error) {
+	seen := make(map[string]bool, len(keyValues))
+	keys := make([]map[string]ddbtypes.AttributeValue, 0, len(keyValues))
+	for _, value := range keyValues {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		keys = append(keys, map[string]ddbtypes.AttributeValue{
+			keyName: &ddbtypes.AttributeValueMemberS{Value: value},
+		})
+	}
+
+	var items []T
+	for start := 0; start < len(keys); start += MAXBATCHGET {
+		end := start + MAXBATCHGET
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		request := map[string]ddbtypes.KeysAndAttributes{table: {Keys: keys[start:end]}}
+		for attempt := 0; len(request[table].Keys) > 0; attempt++ {
+			if attempt > MAXRETRIES {
+				return nil, fmt.Errorf("%d %s keys left unprocessed after %d retries", len(request[table].Keys), table, MAXRETRIES)
+			}
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Duration(1<<attempt) * 25 * time.Millisecond):
+				}
+			}
+
+			result, err := db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, fmt.Errorf("error batch fetching %s: %w", table, err)
+			}
+
+			var page []T
+			if err = wrappers.UnmarshalListOfMaps(result.Responses[table], &page); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %w", table, err)
+			}
+			items = append(items, page...)
+			request = result.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,136 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+)
+
+type longCSVEncoder struct {
+	w        *csv.Writer
+	location *time.Location
+}
+
+// wideCSVEncoder writes one line per timestamp with a column per series,
+// leaving cells empty where a series has no reading at that instant.
+type wideCSVEncoder struct {
+	w         *csv.Writer
+	location  *time.Location
+	columns   map[*catalog.Series]int
+	record    []string
+	timestamp int64
+	pending   bool
+}
+
+func newLongCSVEncoder(w io.Writer, location *time.Location) (*longCSVEncoder, error) {
+	enc := &longCSVEncoder{w: csv.NewWriter(w), location: location}
+	header := []string{"timestamp", "factoryId", "assetId", "assetName", "propertyId", "property", "unit", "value", "quality"}
+	if err := enc.w.Write(header); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (e *longCSVEncoder) Write(row Row) error {
+	return e.w.Write([]string{
+		formatTimestamp(row.Reading.Timestamp, e.location),
+		row.Series.FactoryID,
+		row.Series.AssetID,
+		row.Series.AssetName,
+		row.Series.Property.PropertyID,
+		row.Series.Property.Name,
+		row.Series.Property.Unit,
+		formatValue(row.Reading.Value),
+		quality.Of(row.Reading),
+	})
+}
+
+func (e *longCSVEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func newWideCSVEncoder(w io.Writer, series []catalog.Series, location *time.Location) (*wideCSVEncoder, error) {
+	enc := &wideCSVEncoder{
+		w:        csv.NewWriter(w),
+		location: location,
+		columns:  make(map[*catalog.Series]int, len(series)),
+		record:   make([]string, len(series)+1),
+	}
+
+	header := make([]string, 0, len(series)+1)
+	header = append(header, "timestamp")
+	seen := map[string]bool{}
+	for i := range series {
+		label := columnLabel(series[i])
+		if seen[label] {
+			label = fmt.Sprintf("%s (%s)", label, series[i].Property.PropertyID)
+		}
+		seen[label] = true
+		header = append(header, label)
+		enc.columns[&series[i]] = i + 1
+	}
+
+	if err := enc.w.Write(header); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (e *wideCSVEncoder) Write(row Row) error {
+	if e.pending && row.Reading.Timestamp != e.timestamp {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	e.timestamp = row.Reading.Timestamp
+	e.pending = true
+	e.record[e.columns[row.Series]] = formatValue(row.Reading.Value)
+	return nil
+}
+
+func (e *wideCSVEncoder) Close() error {
+	if e.pending {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *wideCSVEncoder) flush() error {
+	e.record[0] = formatTimestamp(e.timestamp, e.location)
+	if err := e.w.Write(e.record); err != nil {
+		return err
+	}
+	for i := range e.record {
+		e.record[i] = ""
+	}
+	e.pending = false
+	return nil
+}
+
+func columnLabel(series catalog.Series) string {
+	label := series.Property.Name
+	if label == "" {
+		label = series.Property.PropertyID
+	}
+	if series.AssetName != "" {
+		label = series.AssetName + "/" + label
+	} else if series.AssetID != "" {
+		label = series.AssetID + "/" + label
+	}
+	if series.Property.Unit != "" {
+		label = fmt.Sprintf("%s [%s]", label, series.Property.Unit)
+	}
+	return label
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,305 @@
+package export
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/lineprotocol"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+)
+
+const (
+	CSV     = "csv"
+	JSONL   = "jsonl"
+	PARQUET = "parquet"
+	// LINEPROTOCOL is InfluxDB line protocol.
+	LINEPROTOCOL = "lineprotocol"
+
+	LONG = "long"
+	WIDE = "wide"
+
+	TIMEFORMAT = "2006-01-02T15:04:05.000Z07:00"
+)
+
+var ErrInvalidRequest = errors.New("invalid export request")
+
+type Request struct {
+	FactoryID  string    `json:"factoryId,omitempty"`
+	AssetID    string    `json:"assetId,omitempty"`
+	PropertyID string    `json:"propertyId,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Format     string    `json:"format,omitempty"`
+	Layout     string    `json:"layout,omitempty"`
+	// Precision is the timestamp unit of line protocol exports, defaulting
+	// to nanoseconds as in InfluxDB.
+	Precision string `json:"precision,omitempty"`
+	// Quality keeps only readings matching a comma-separated list of
+	// severities or codes, such as "good,uncertain".
+	Quality string `json:"quality,omitempty"`
+	// Timezone gives the times of csv and jsonl exports in an IANA zone, or
+	// in the zone of the exported factory with timezone.FACTORY, instead of
+	// UTC.
+	Timezone string `json:"tz,omitempty"`
+}
+
+type Stats struct {
+	Series int   `json:"series"`
+	Rows   int64 `json:"rows"`
+}
+
+type Row struct {
+	Series  *catalog.Series
+	Reading types.Reading
+}
+
+type encoder interface {
+	Write(row Row) error
+	Close() error
+}
+
+type Exporter struct {
+	Catalog *catalog.Catalog
+	Store   *timeseries.Store
+}
+
+func New(db types.DynamoDBClient) *Exporter {
+	return &Exporter{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+	}
+}
+
+func (r *Request) Validate() error {
+	targets := 0
+	for _, id := range []string{r.FactoryID, r.AssetID, r.PropertyID} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("%w: exactly one of factoryId, assetId or propertyId is required", ErrInvalidRequest)
+	}
+	if r.Start.IsZero() || r.End.IsZero() || !r.End.After(r.Start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidRequest)
+	}
+
+	if r.Format == "" {
+		r.Format = CSV
+	}
+	if r.Layout == "" {
+		r.Layout = LONG
+	}
+	switch r.Format {
+	case CSV, JSONL, PARQUET, LINEPROTOCOL:
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidRequest, r.Format)
+	}
+	if r.Layout != LONG && r.Layout != WIDE {
+		return fmt.Errorf("%w: unknown layout %q", ErrInvalidRequest, r.Layout)
+	}
+	if r.Layout == WIDE && r.Format != CSV {
+		return fmt.Errorf("%w: the wide layout is only available for csv", ErrInvalidRequest)
+	}
+	if r.Precision != "" && r.Format != LINEPROTOCOL {
+		return fmt.Errorf("%w: precision is only available for lineprotocol", ErrInvalidRequest)
+	}
+	if r.Format == LINEPROTOCOL {
+		if _, err := lineprotocol.ParsePrecision(r.Precision); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+		}
+	}
+	if _, err := quality.ParseFilter(r.Quality); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	}
+	if r.Timezone != "" && r.Format != CSV && r.Format != JSONL {
+		return fmt.Errorf("%w: tz is only available for csv and jsonl", ErrInvalidRequest)
+	}
+	if r.Timezone != "" && r.Timezone != timezone.FACTORY {
+		if err := timezone.Validate(r.Timezone); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+		}
+	}
+	return nil
+}
+
+func ContentType(format string) string {
+	switch format {
+	case JSONL:
+		return "application/x-ndjson"
+	case PARQUET:
+		return "application/vnd.apache.parquet"
+	case LINEPROTOCOL:
+		return "text/plain; charset=utf-8"
+	default:
+		return "text/csv"
+	}
+}
+
+func Extension(format string) string {
+	switch format {
+	case JSONL:
+		return "jsonl"
+	case LINEPROTOCOL:
+		return "lp"
+	}
+	return format
+}
+
+// Export streams the readings selected by the request to w, merged across
+// series in timestamp order. Readings are assessed against their
+// measurement's bounds before they are filtered and written.
+func (e Exporter) Export(ctx context.Context, request Request, w io.Writer) (Stats, error) {
+	var stats Stats
+	if err := request.Validate(); err != nil {
+		return stats, err
+	}
+
+	series, err := e.Catalog.Series(ctx, request.FactoryID, request.AssetID, request.PropertyID)
+	if err != nil {
+		return stats, err
+	}
+	stats.Series = len(series)
+
+	location, err := e.location(ctx, request, series)
+	if err != nil {
+		return stats, err
+	}
+	enc, err := newEncoder(request, series, location, w)
+	if err != nil {
+		return stats, err
+	}
+	filter, _ := quality.ParseFilter(request.Quality)
+
+	merged := &merger{}
+	for i := range series {
+		merged.add(ctx, &series[i], e.Store.Iterate(series[i].Property.PropertyID, request.Start, request.End))
+	}
+
+	for merged.Len() > 0 {
+		row, err := merged.next(ctx)
+		if err != nil {
+			return stats, err
+		}
+		row.Reading = quality.Assess(row.Reading, row.Series.Measurement)
+		if !filter.Match(row.Reading) {
+			continue
+		}
+		if err = enc.Write(row); err != nil {
+			return stats, err
+		}
+		stats.Rows++
+	}
+	if merged.err != nil {
+		return stats, merged.err
+	}
+
+	return stats, enc.Close()
+}
+
+// location returns the zone a request asks times to be given in, or nil
+// for UTC. The factory's zone is that of the factory the series belong to.
+func (e Exporter) location(ctx context.Context, request Request, series []catalog.Series) (*time.Location, error) {
+	factoryID := request.FactoryID
+	if factoryID == "" && len(series) > 0 {
+		factoryID = series[0].FactoryID
+	}
+	location, err := timezone.Load(ctx, e.Catalog, request.Timezone, factoryID)
+	if errors.Is(err, timezone.ErrInvalidTimezone) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	}
+	return location, err
+}
+
+func newEncoder(request Request, series []catalog.Series, location *time.Location, w io.Writer) (encoder, error) {
+	switch {
+	case request.Format == JSONL:
+		return newJSONLEncoder(w, location), nil
+	case request.Format == PARQUET:
+		return newParquetEncoder(w), nil
+	case request.Format == LINEPROTOCOL:
+		precision, err := lineprotocol.ParsePrecision(request.Precision)
+		if err != nil {
+			return nil, err
+		}
+		return newLineProtocolEncoder(w, precision), nil
+	case request.Layout == WIDE:
+		return newWideCSVEncoder(w, series, location)
+	default:
+		return newLongCSVEncoder(w, location)
+	}
+}
+
+type cursor struct {
+	series *catalog.Series
+	it     *timeseries.Iterator
+}
+
+// merger is a min-heap of per-series cursors ordered by their current reading.
+type merger struct {
+	cursors []cursor
+	err     error
+}
+
+func (m *merger) add(ctx context.Context, series *catalog.Series, it *timeseries.Iterator) {
+	if it.Next(ctx) {
+		heap.Push(m, cursor{series: series, it: it})
+		return
+	}
+	if it.Err() != nil && m.err == nil {
+		m.err = it.Err()
+	}
+}
+
+func (m *merger) next(ctx context.Context) (Row, error) {
+	if m.err != nil {
+		return Row{}, m.err
+	}
+
+	c := heap.Pop(m).(cursor)
+	row := Row{Series: c.series, Reading: c.it.Reading()}
+	m.add(ctx, c.series, c.it)
+	return row, nil
+}
+
+func (m *merger) Len() int {
+	return len(m.cursors)
+}
+
+func (m *merger) Less(i, j int) bool {
+	a, b := m.cursors[i].it.Reading(), m.cursors[j].it.Reading()
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.PropertyID < b.PropertyID
+}
+
+func (m *merger) Swap(i, j int) {
+	m.cursors[i], m.cursors[j] = m.cursors[j], m.cursors[i]
+}
+
+func (m *merger) Push(x interface{}) {
+	m.cursors = append(m.cursors, x.(cursor))
+}
+
+func (m *merger) Pop() interface{} {
+	last := m.cursors[len(m.cursors)-1]
+	m.cursors = m.cursors[:len(m.cursors)-1]
+	return last
+}
+
+// formatTimestamp gives a Unix millisecond timestamp in a location, or in
+// UTC when it is nil.
+func formatTimestamp(ms int64, location *time.Location) string {
+	if location == nil {
+		location = time.UTC
+	}
+	return time.UnixMilli(ms).In(location).Format(TIMEFORMAT)
+}
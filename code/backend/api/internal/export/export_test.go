@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var (
+	exportStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exportEnd   = exportStart.Add(time.Hour)
+)
+
+func mockExportClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch *params.TableName {
+			case "Asset":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"assetId":   &types.AttributeValueMemberS{Value: "a1"},
+					"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+					"name":      &types.AttributeValueMemberS{Value: "Press"},
+					"modelId":   &types.AttributeValueMemberS{Value: "m1"},
+				}}, nil
+			case "Property":
+				id := params.Key["propertyId"].(*types.AttributeValueMemberS).Value
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"propertyId":    &types.AttributeValueMemberS{Value: id},
+					"measurementId": &types.AttributeValueMemberS{Value: "meas"},
+					"name":          &types.AttributeValueMemberS{Value: strings.ToUpper(id)},
+					"unit":          &types.AttributeValueMemberS{Value: "C"},
+				}}, nil
+			}
+			// Readings of 2 are above the upper bound.
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"measurementId": &types.AttributeValueMemberS{Value: "meas"},
+				"upperBound":    &types.AttributeValueMemberN{Value: "1.8"},
+			}}, nil
+		},
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{{
+				"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+				"factoryId":  &types.AttributeValueMemberS{Value: "f1"},
+				"properties": &types.AttributeValueMemberSS{Value: []string{"p1", "p2"}},
+			}}}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if *params.TableName == "Model" {
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+					"properties": &types.AttributeValueMemberSS{Value: []string{"p1", "p2"}},
+				}}}, nil
+			}
+
+			id := params.ExpressionAttributeValues[":propertyId"].(*types.AttributeValueMemberS).Value
+			timestamps := map[string][]string{"p1": {"1704067200000", "1704067201000"}, "p2": {"1704067200000", "1704067202000"}}[id]
+			if params.ExclusiveStartKey == nil {
+				return &dynamodb.QueryOutput{
+					Items:            []map[string]types.AttributeValue{reading(id, timestamps[0], "1.5")},
+					LastEvaluatedKey: map[string]types.AttributeValue{"propertyId": &types.AttributeValueMemberS{Value: id}},
+				}, nil
+			}
+			last := reading(id, timestamps[1], "2")
+			if id == "p2" {
+				last["quality"] = &types.AttributeValueMemberS{Value: "bad/sensorFailure"}
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{last}}, nil
+		},
+	}
+}
+
+func reading(propertyID, timestamp, value string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"propertyId": &types.AttributeValueMemberS{Value: propertyID},
+		"timestamp":  &types.AttributeValueMemberN{Value: timestamp},
+		"value":      &types.AttributeValueMemberN{Value: value},
+	}
+}
+
+func TestExport_InvalidRequest(t *testing.T) {
+	cases := []Request{
+		{Start: exportStart, End: exportEnd},
+		{AssetID: "a1", Start: exportEnd, End: exportStart},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Format: "xlsx"},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Format: JSONL, Layout: WIDE},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Format: LINEPROTOCOL, Precision: "d"},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Precision: "s"},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Quality: "fine"},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Timezone: "Mars/Olympus"},
+		{AssetID: "a1", Start: exportStart, End: exportEnd, Format: PARQUET, Timezone: "UTC"},
+	}
+
+	for _, request := range cases {
+		if _, err := New(&mocks.DynamoDBClient{}).Export(context.Background(), request, &bytes.Buffer{}); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("Expected ErrInvalidRequest for %+v, got %v", request, err)
+		}
+	}
+}
+
+func TestExport_LongCSV(t *testing.T) {
+	var out bytes.Buffer
+	stats, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"timestamp,factoryId,assetId,assetName,propertyId,property,unit,value,quality",
+		"2024-01-01T00:00:00.000Z,f1,a1,Press,p1,P1,C,1.5,good",
+		"2024-01-01T00:00:00.000Z,f1,a1,Press,p2,P2,C,1.5,good",
+		"2024-01-01T00:00:01.000Z,f1,a1,Press,p1,P1,C,2,uncertain/engineeringUnitsExceeded",
+		"2024-01-01T00:00:02.000Z,f1,a1,Press,p2,P2,C,2,bad/sensorFailure",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("Expected long CSV\n%s\ngot\n%s", expected, out.String())
+	}
+	if stats.Rows != 4 || stats.Series != 2 {
+		t.Errorf("Expected 4 rows from 2 series, got %+v", stats)
+	}
+}
+
+func TestExport_WideCSV(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Layout: WIDE}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"timestamp,Press/P1 [C],Press/P2 [C]",
+		"2024-01-01T00:00:00.000Z,1.5,1.5",
+		"2024-01-01T00:00:01.000Z,2,",
+		"2024-01-01T00:00:02.000Z,,2",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("Expected wide CSV\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestExport_QualityFilter(t *testing.T) {
+	var out bytes.Buffer
+	stats, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Quality: "bad"}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], ",p2,P2,C,2,bad/sensorFailure") || stats.Rows != 1 {
+		t.Errorf("Expected only the bad reading, got %s", out.String())
+	}
+
+	// The reading above the upper bound is flagged before it is filtered.
+	out.Reset()
+	if _, err = New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Quality: "uncertain"}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], ",p1,P1,C,2,uncertain/engineeringUnitsExceeded") {
+		t.Errorf("Expected only the flagged reading, got %s", out.String())
+	}
+}
+
+func TestExport_Timezone(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Layout: WIDE, Timezone: "Asia/Kolkata"}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lines := strings.Split(out.String(), "\n"); len(lines) < 2 || !strings.HasPrefix(lines[1], "2024-01-01T05:30:00.000+05:30,") {
+		t.Errorf("Expected times in India Standard Time, got\n%s", out.String())
+	}
+
+	// The factory of a1 has no location, so no zone.
+	_, err = New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Timezone: "factory"}, &bytes.Buffer{})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for a factory without a zone, got %v", err)
+	}
+}
+
+func TestExport_JSONL(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Format: JSONL}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"propertyId":"p1"`) || !strings.Contains(lines[0], `"value":1.5`) || !strings.Contains(lines[0], `"quality":"good"`) {
+		t.Errorf("Expected 4 JSON lines, got %s", out.String())
+	}
+
+	// A property on its own belongs to the factory of its model.
+	out.Reset()
+	if _, err = New(mockExportClient()).Export(context.Background(), Request{PropertyID: "p1", Start: exportStart, End: exportEnd, Format: JSONL}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), `"factoryId":"f1"`) {
+		t.Errorf("Expected the readings of p1 in factory f1, got %s", out.String())
+	}
+}
+
+func TestExport_Parquet(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Format: PARQUET}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data := out.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Errorf("Expected Parquet magic bytes around the file")
+	}
+	if !bytes.Contains(data, []byte("property_id")) || !bytes.Contains(data, []byte("Press")) {
+		t.Errorf("Expected schema and values in the Parquet file")
+	}
+}
+
+func TestExport_LineProtocol(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(mockExportClient()).Export(context.Background(), Request{AssetID: "a1", Start: exportStart, End: exportEnd, Format: LINEPROTOCOL, Precision: "s"}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"wdd,asset=a1,asset_name=Press,factory=f1,property_id=p1,unit=C P1=1.5 1704067200",
+		"wdd,asset=a1,asset_name=Press,factory=f1,property_id=p2,unit=C P2=1.5 1704067200",
+		"wdd,asset=a1,asset_name=Press,factory=f1,property_id=p1,quality=uncertain/engineeringUnitsExceeded,unit=C P1=2 1704067201",
+		"wdd,asset=a1,asset_name=Press,factory=f1,property_id=p2,quality=bad/sensorFailure,unit=C P2=2 1704067202",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("Expected line protocol\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestExport_QueryError(t *testing.T) {
+	client := mockExportClient()
+	client.QueryFunc = func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+		return nil, errors.New("mock dynamodb error")
+	}
+
+	_, err := New(client).Export(context.Background(), Request{PropertyID: "p1", Start: exportStart, End: exportEnd}, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected query error to be returned")
+	}
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+	"wdd/api/internal/quality"
+)
+
+type jsonlRecord struct {
+	Timestamp  int64   `json:"timestamp"`
+	Time       string  `json:"time"`
+	FactoryID  string  `json:"factoryId,omitempty"`
+	AssetID    string  `json:"assetId,omitempty"`
+	AssetName  string  `json:"assetName,omitempty"`
+	PropertyID string  `json:"propertyId"`
+	Property   string  `json:"property,omitempty"`
+	Unit       string  `json:"unit,omitempty"`
+	Value      float64 `json:"value"`
+	Quality    string  `json:"quality"`
+}
+
+type jsonlEncoder struct {
+	buf      *bufio.Writer
+	enc      *json.Encoder
+	location *time.Location
+}
+
+func newJSONLEncoder(w io.Writer, location *time.Location) *jsonlEncoder {
+	buf := bufio.NewWriter(w)
+	return &jsonlEncoder{buf: buf, enc: json.NewEncoder(buf), location: location}
+}
+
+func (e *jsonlEncoder) Write(row Row) error {
+	return e.enc.Encode(jsonlRecord{
+		Timestamp:  row.Reading.Timestamp,
+		Time:       formatTimestamp(row.Reading.Timestamp, e.location),
+		FactoryID:  row.Series.FactoryID,
+		AssetID:    row.Series.AssetID,
+		AssetName:  row.Series.AssetName,
+		PropertyID: row.Series.Property.PropertyID,
+		Property:   row.Series.Property.Name,
+		Unit:       row.Series.Property.Unit,
+		Value:      row.Reading.Value,
+		Quality:    quality.Of(row.Reading),
+	})
+}
+
+func (e *jsonlEncoder) Close() error {
+	return e.buf.Flush()
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"time"
+	"wdd/api/internal/lineprotocol"
+)
+
+// MEASUREMENT is the measurement every exported reading is written under.
+const MEASUREMENT = "wdd"
+
+// lineProtocolEncoder writes one line per reading, with the property as the
+// field, the asset ID in the "asset" tag and any recorded quality in the
+// "quality" tag, so that an export can be written straight back through the
+// line protocol endpoint.
+type lineProtocolEncoder struct {
+	buf       *bufio.Writer
+	line      []byte
+	precision time.Duration
+}
+
+func newLineProtocolEncoder(w io.Writer, precision time.Duration) *lineProtocolEncoder {
+	return &lineProtocolEncoder{buf: bufio.NewWriter(w), precision: precision}
+}
+
+func (e *lineProtocolEncoder) Write(row Row) error {
+	// Line protocol has no way to express NaN or infinities.
+	if math.IsNaN(row.Reading.Value) || math.IsInf(row.Reading.Value, 0) {
+		return nil
+	}
+
+	field := row.Series.Property.Name
+	if field == "" {
+		field = row.Series.Property.PropertyID
+	}
+	e.line = lineprotocol.Append(e.line[:0], lineprotocol.Point{
+		Measurement: MEASUREMENT,
+		Tags: []lineprotocol.Tag{
+			{Key: lineprotocol.DEFAULTASSETTAG, Value: row.Series.AssetID},
+			{Key: "asset_name", Value: row.Series.AssetName},
+			{Key: "factory", Value: row.Series.FactoryID},
+			{Key: "property_id", Value: row.Series.Property.PropertyID},
+			{Key: lineprotocol.QUALITYTAG, Value: row.Reading.Quality},
+			{Key: "unit", Value: row.Series.Property.Unit},
+		},
+		Fields: []lineprotocol.Field{{Key: field, Value: row.Reading.Value}},
+		Time:   time.UnixMilli(row.Reading.Timestamp),
+	}, e.precision)
+	_, err := e.buf.Write(e.line)
+	return err
+}
+
+func (e *lineProtocolEncoder) Close() error {
+	return e.buf.Flush()
+}
@@ -0,0 +1,223 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"wdd/api/internal/quality"
+)
+
+const (
+	ROWGROUPSIZE = 100000
+
+	parquetMagic = "PAR1"
+
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+	parquetRequired     = 0
+)
+
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	data      bytes.Buffer
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+type parquetRowGroup struct {
+	rows   int64
+	chunks []parquetChunk
+}
+
+// parquetEncoder writes readings in the long layout as an uncompressed,
+// PLAIN-encoded Parquet file with one data page per column per row group.
+type parquetEncoder struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetEncoder(w io.Writer) *parquetEncoder {
+	enc := &parquetEncoder{
+		w: w,
+		columns: []*parquetColumn{
+			{name: "timestamp", physical: parquetInt64, converted: parquetTimestampMillis},
+			{name: "factory_id", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "asset_id", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "asset_name", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "property_id", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "property", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "unit", physical: parquetByteArray, converted: parquetUTF8},
+			{name: "value", physical: parquetDouble, converted: -1},
+			{name: "quality", physical: parquetByteArray, converted: parquetUTF8},
+		},
+	}
+	enc.write([]byte(parquetMagic))
+	return enc
+}
+
+func (e *parquetEncoder) Write(row Row) error {
+	e.columns[0].int64(row.Reading.Timestamp)
+	e.columns[1].byteArray(row.Series.FactoryID)
+	e.columns[2].byteArray(row.Series.AssetID)
+	e.columns[3].byteArray(row.Series.AssetName)
+	e.columns[4].byteArray(row.Series.Property.PropertyID)
+	e.columns[5].byteArray(row.Series.Property.Name)
+	e.columns[6].byteArray(row.Series.Property.Unit)
+	e.columns[7].double(row.Reading.Value)
+	e.columns[8].byteArray(quality.Of(row.Reading))
+	e.rows++
+
+	if e.rows >= ROWGROUPSIZE {
+		e.flushRowGroup()
+	}
+	return e.err
+}
+
+func (e *parquetEncoder) Close() error {
+	if e.rows > 0 {
+		e.flushRowGroup()
+	}
+
+	footer := e.footer()
+	e.write(footer)
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(footer)))
+	e.write(length)
+	e.write([]byte(parquetMagic))
+	return e.err
+}
+
+func (e *parquetEncoder) flushRowGroup() {
+	group := parquetRowGroup{rows: e.rows}
+	for _, column := range e.columns {
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(column.data.Len()))
+		header.i32(3, int32(column.data.Len()))
+		header.structBegin(5)
+		header.i32(1, int32(e.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.structEnd()
+		pageHeader := header.end()
+
+		chunk := parquetChunk{offset: e.offset, size: int64(len(pageHeader) + column.data.Len())}
+		e.write(pageHeader)
+		e.write(column.data.Bytes())
+		column.data.Reset()
+		group.chunks = append(group.chunks, chunk)
+	}
+	e.rowGroups = append(e.rowGroups, group)
+	e.rows = 0
+}
+
+func (e *parquetEncoder) footer() []byte {
+	var totalRows int64
+	for _, group := range e.rowGroups {
+		totalRows += group.rows
+	}
+
+	var t thriftWriter
+	t.i32(1, 1)
+
+	t.listBegin(2, thriftStruct, len(e.columns)+1)
+	t.listStruct()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(e.columns)))
+	t.structEnd()
+	for _, column := range e.columns {
+		t.listStruct()
+		t.i32(1, column.physical)
+		t.i32(3, parquetRequired)
+		t.binary(4, column.name)
+		if column.converted >= 0 {
+			t.i32(6, column.converted)
+		}
+		t.structEnd()
+	}
+
+	t.i64(3, totalRows)
+
+	t.listBegin(4, thriftStruct, len(e.rowGroups))
+	for _, group := range e.rowGroups {
+		t.listStruct()
+		t.listBegin(1, thriftStruct, len(group.chunks))
+		var groupSize int64
+		for i, chunk := range group.chunks {
+			e.columnChunk(&t, e.columns[i], chunk, group.rows)
+			groupSize += chunk.size
+		}
+		t.i64(2, groupSize)
+		t.i64(3, group.rows)
+		t.structEnd()
+	}
+
+	t.binary(6, "wdd api")
+	return t.end()
+}
+
+func (e *parquetEncoder) columnChunk(t *thriftWriter, column *parquetColumn, chunk parquetChunk, rows int64) {
+	t.listStruct()
+	t.i64(2, chunk.offset)
+	t.structBegin(3)
+	t.i32(1, column.physical)
+	t.listBegin(2, thriftI32, 1)
+	t.listI32(parquetPlain)
+	t.listBegin(3, thriftBinary, 1)
+	t.rawBinary(column.name)
+	t.i32(4, parquetUncompressed)
+	t.i64(5, rows)
+	t.i64(6, chunk.size)
+	t.i64(7, chunk.size)
+	t.i64(9, chunk.offset)
+	t.structEnd()
+	t.structEnd()
+}
+
+func (e *parquetEncoder) write(p []byte) {
+	if e.err != nil {
+		return
+	}
+	n, err := e.w.Write(p)
+	e.offset += int64(n)
+	e.err = err
+}
+
+func (c *parquetColumn) int64(v int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	c.data.Write(b[:])
+}
+
+func (c *parquetColumn) double(v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	c.data.Write(b[:])
+}
+
+func (c *parquetColumn) byteArray(v string) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+	c.data.Write(b[:])
+	c.data.WriteString(v)
+}
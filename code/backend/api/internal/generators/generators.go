@@ -0,0 +1,185 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+	"wdd/api/internal/types"
+)
+
+const (
+	SINEWAVE = "sinewave"
+	SAWTOOTH = "sawtooth"
+	RANDOM   = "random"
+)
+
+var ErrUnsupportedGenerator = errors.New("unsupported generator function")
+
+type Generator interface {
+	Value(t time.Time) float64
+}
+
+// Schedule reports how active a factory is at an instant, from 0 (idle) to 1.
+type Schedule interface {
+	Activity(t time.Time) float64
+}
+
+type bounds struct {
+	lower     float64
+	upper     float64
+	precision float64
+}
+
+type sineWave struct {
+	bounds
+	center           float64
+	amplitude        float64
+	angularFrequency float64
+	phase            float64
+}
+
+type sawtooth struct {
+	bounds
+	center           float64
+	amplitude        float64
+	angularFrequency float64
+	phase            float64
+}
+
+type random struct {
+	bounds
+	seed uint64
+}
+
+type scheduled struct {
+	generator Generator
+	schedule  Schedule
+	idle      float64
+}
+
+// New builds a deterministic generator for a measurement. The key (usually the
+// property ID) and seed together pick the random stream, so the same inputs
+// always evaluate to the same series regardless of how a window is chunked.
+func New(measurement types.Measurement, key string, seed int64) (Generator, error) {
+	b := newBounds(measurement)
+
+	switch measurement.GeneratorFunction {
+	case SINEWAVE:
+		return sineWave{
+			bounds:           b,
+			center:           b.center(),
+			amplitude:        valueOr(measurement.Amplitude, (b.upper-b.lower)/2),
+			angularFrequency: valueOr(measurement.AngularFrequency, 1),
+			phase:            valueOr(measurement.Phase, 0),
+		}, nil
+	case SAWTOOTH:
+		return sawtooth{
+			bounds:           b,
+			center:           b.center(),
+			amplitude:        valueOr(measurement.Amplitude, (b.upper-b.lower)/2),
+			angularFrequency: valueOr(measurement.AngularFrequency, 1),
+			phase:            valueOr(measurement.Phase, 0),
+		}, nil
+	case RANDOM:
+		return random{
+			bounds: b,
+			seed:   mix(uint64(seed) ^ hashKey(key)),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedGenerator, measurement.GeneratorFunction)
+	}
+}
+
+// WithSchedule pulls a generator toward the measurement's lower bound whenever
+// the schedule reports reduced activity, so machines idle outside their shifts.
+func WithSchedule(generator Generator, measurement types.Measurement, schedule Schedule) Generator {
+	return scheduled{
+		generator: generator,
+		schedule:  schedule,
+		idle:      newBounds(measurement).lower,
+	}
+}
+
+// Interval returns the sampling period of a measurement, whose frequency is in hertz.
+func Interval(measurement types.Measurement) time.Duration {
+	if measurement.Frequency == nil || *measurement.Frequency <= 0 {
+		return time.Second
+	}
+	interval := time.Duration(float64(time.Second) / *measurement.Frequency)
+	if interval < time.Millisecond {
+		return time.Millisecond
+	}
+	return interval
+}
+
+func (g sineWave) Value(t time.Time) float64 {
+	return g.finish(g.center + g.amplitude*math.Sin(g.angularFrequency*seconds(t)+g.phase))
+}
+
+func (g sawtooth) Value(t time.Time) float64 {
+	cycle := (g.angularFrequency*seconds(t) + g.phase) / (2 * math.Pi)
+	return g.finish(g.center + g.amplitude*(2*(cycle-math.Floor(cycle))-1))
+}
+
+func (g random) Value(t time.Time) float64 {
+	unit := float64(mix(g.seed^uint64(t.UnixMilli()))>>11) / (1 << 53)
+	return g.finish(g.lower + unit*(g.upper-g.lower))
+}
+
+func (g scheduled) Value(t time.Time) float64 {
+	activity := g.schedule.Activity(t)
+	if activity >= 1 {
+		return g.generator.Value(t)
+	}
+	return g.idle + activity*(g.generator.Value(t)-g.idle)
+}
+
+func newBounds(measurement types.Measurement) bounds {
+	b := bounds{
+		lower:     valueOr(measurement.LowerBound, 0),
+		upper:     valueOr(measurement.UpperBound, 1),
+		precision: valueOr(measurement.Precision, 0),
+	}
+	if b.upper < b.lower {
+		b.lower, b.upper = b.upper, b.lower
+	}
+	return b
+}
+
+func (b bounds) center() float64 {
+	return (b.lower + b.upper) / 2
+}
+
+func (b bounds) finish(value float64) float64 {
+	value = math.Max(b.lower, math.Min(b.upper, value))
+	if b.precision > 0 {
+		value = math.Round(value/b.precision) * b.precision
+	}
+	return value
+}
+
+func seconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+func valueOr(value *float64, fallback float64) float64 {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
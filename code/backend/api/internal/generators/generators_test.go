@@ -0,0 +1,76 @@
+package generators
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wdd/api/internal/types"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestNew_UnsupportedGenerator(t *testing.T) {
+	_, err := New(types.Measurement{GeneratorFunction: "square"}, "p1", 1)
+	if !errors.Is(err, ErrUnsupportedGenerator) {
+		t.Errorf("Expected ErrUnsupportedGenerator for square, got %v", err)
+	}
+}
+
+func TestRandom_DeterministicAndBounded(t *testing.T) {
+	measurement := types.Measurement{
+		GeneratorFunction: RANDOM,
+		LowerBound:        float(-5),
+		UpperBound:        float(5),
+		Precision:         float(0.5),
+	}
+	first, _ := New(measurement, "p1", 7)
+	second, _ := New(measurement, "p1", 7)
+	other, _ := New(measurement, "p2", 7)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	differs := false
+	for i := 0; i < 1000; i++ {
+		ts := start.Add(time.Duration(i) * time.Second)
+		value := first.Value(ts)
+		if value != second.Value(ts) {
+			t.Fatalf("Expected identical values for the same seed and key at %s", ts)
+		}
+		if value < -5 || value > 5 {
+			t.Fatalf("Expected value within bounds, got %f", value)
+		}
+		if value*2 != float64(int(value*2)) {
+			t.Fatalf("Expected value rounded to precision 0.5, got %f", value)
+		}
+		if value != other.Value(ts) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Errorf("Expected different keys to produce different series")
+	}
+}
+
+func TestSineWave_Value(t *testing.T) {
+	generator, _ := New(types.Measurement{
+		GeneratorFunction: SINEWAVE,
+		LowerBound:        float(0),
+		UpperBound:        float(10),
+		Amplitude:         float(5),
+		AngularFrequency:  float(1),
+	}, "p1", 1)
+
+	if value := generator.Value(time.Unix(0, 0)); value != 5 {
+		t.Errorf("Expected the wave to start at its center, got %f", value)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	if interval := Interval(types.Measurement{Frequency: float(4)}); interval != 250*time.Millisecond {
+		t.Errorf("Expected 250ms for 4Hz, got %s", interval)
+	}
+	if interval := Interval(types.Measurement{}); interval != time.Second {
+		t.Errorf("Expected 1s when frequency is unset, got %s", interval)
+	}
+}
@@ -0,0 +1,142 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// TILESIZE is the width in pixels of the world at zoom 0.
+	TILESIZE = 256
+	MAXZOOM  = 22
+	// DEFAULTCLUSTERRADIUS is the radius in pixels a cluster gathers points
+	// within, the default of common map clustering libraries.
+	DEFAULTCLUSTERRADIUS = 60
+	// MAXMERCATORLAT is the latitude Web Mercator maps clip at.
+	MAXMERCATORLAT = 85.05112878
+)
+
+// Cluster is a group of points drawn as one marker at a zoom level.
+type Cluster struct {
+	// Center is the centroid of the members in map space.
+	Center Point
+	// Bounds holds every member, for zooming in on the cluster.
+	Bounds BBox
+	// Members are the indexes of the clustered points.
+	Members []int
+}
+
+// Clusters groups points that would be drawn within radius pixels of each
+// other at a zoom level. Like supercluster, each cluster is seeded by the
+// first unclustered point, in north-west to south-east order, and takes
+// every unclustered point within radius of it; candidates are looked up in
+// a grid of radius-sized cells.
+func Clusters(points []Point, zoom int, radius float64) []Cluster {
+	type pixel struct{ x, y float64 }
+	pixels := make([]pixel, len(points))
+	order := make([]int, len(points))
+	grid := map[[2]int][]int{}
+	for i, p := range points {
+		x, y := project(p, zoom)
+		pixels[i] = pixel{x, y}
+		order[i] = i
+		cell := [2]int{int(math.Floor(x / radius)), int(math.Floor(y / radius))}
+		grid[cell] = append(grid[cell], i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := pixels[order[i]], pixels[order[j]]
+		if a.y != b.y {
+			return a.y < b.y
+		}
+		return a.x < b.x
+	})
+
+	clustered := make([]bool, len(points))
+	var clusters []Cluster
+	for _, seed := range order {
+		if clustered[seed] {
+			continue
+		}
+
+		origin := pixels[seed]
+		cx, cy := int(math.Floor(origin.x/radius)), int(math.Floor(origin.y/radius))
+		var members []int
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				for _, i := range grid[[2]int{cx + dx, cy + dy}] {
+					if clustered[i] || math.Hypot(pixels[i].x-origin.x, pixels[i].y-origin.y) > radius {
+						continue
+					}
+					clustered[i] = true
+					members = append(members, i)
+				}
+			}
+		}
+		sort.Ints(members)
+
+		var sumX, sumY float64
+		bounds := BBox{MinLon: 180, MinLat: 90, MaxLon: -180, MaxLat: -90}
+		for _, i := range members {
+			sumX += pixels[i].x
+			sumY += pixels[i].y
+			bounds.MinLon = math.Min(bounds.MinLon, points[i].Lon)
+			bounds.MaxLon = math.Max(bounds.MaxLon, points[i].Lon)
+			bounds.MinLat = math.Min(bounds.MinLat, points[i].Lat)
+			bounds.MaxLat = math.Max(bounds.MaxLat, points[i].Lat)
+		}
+		n := float64(len(members))
+		clusters = append(clusters, Cluster{
+			Center:  unproject(sumX/n, sumY/n, zoom),
+			Bounds:  bounds,
+			Members: members,
+		})
+	}
+	return clusters
+}
+
+// Pad grows a box by a margin in pixels at a zoom level, so that points
+// just outside a viewport still pull clusters at its edges toward them.
+func Pad(b BBox, zoom int, margin float64) BBox {
+	size := worldSize(zoom)
+	dLon := margin / size * 360
+	if b.MinLon <= b.MaxLon && b.MaxLon-b.MinLon+2*dLon >= 360 || b.MinLon > b.MaxLon && b.MaxLon+360-b.MinLon+2*dLon >= 360 {
+		b.MinLon, b.MaxLon = -180, 180
+	} else {
+		b.MinLon, b.MaxLon = wrapLon(b.MinLon-dLon), wrapLon(b.MaxLon+dLon)
+	}
+
+	_, top := project(Point{Lat: b.MaxLat}, zoom)
+	_, bottom := project(Point{Lat: b.MinLat}, zoom)
+	// Past the edge of the map, every latitude up to the pole is in reach.
+	b.MaxLat, b.MinLat = 90, -90
+	if top-margin > 0 {
+		b.MaxLat = unproject(0, top-margin, zoom).Lat
+	}
+	if bottom+margin < size {
+		b.MinLat = unproject(0, bottom+margin, zoom).Lat
+	}
+	return b
+}
+
+func worldSize(zoom int) float64 {
+	return TILESIZE * math.Exp2(float64(zoom))
+}
+
+// project returns the Web Mercator pixel coordinates of a point, with y
+// growing southward.
+func project(p Point, zoom int) (float64, float64) {
+	size := worldSize(zoom)
+	lat := math.Max(-MAXMERCATORLAT, math.Min(MAXMERCATORLAT, p.Lat))
+	sin := math.Sin(radians(lat))
+	x := (p.Lon + 180) / 360 * size
+	y := (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * size
+	return x, y
+}
+
+func unproject(x, y float64, zoom int) Point {
+	size := worldSize(zoom)
+	lon := x/size*360 - 180
+	y = math.Max(0, math.Min(size, y))
+	lat := degrees(math.Atan(math.Sinh(math.Pi * (1 - 2*y/size))))
+	return Point{Lat: lat, Lon: wrapLon(lon)}
+}
@@ -0,0 +1,69 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	points := []Point{
+		{Lat: 48.85, Lon: 2.35},
+		{Lat: 48.86, Lon: 2.36},
+		{Lat: 51.51, Lon: -0.13},
+		{Lat: 48.84, Lon: 2.34},
+	}
+
+	// Paris and London are far apart at zoom 8 but not at zoom 2.
+	clusters := Clusters(points, 8, DEFAULTCLUSTERRADIUS)
+	if len(clusters) != 2 {
+		t.Fatalf("Expected Paris and London clusters, got %+v", clusters)
+	}
+	london, paris := clusters[0], clusters[1]
+	if len(london.Members) != 1 || london.Members[0] != 2 || london.Center.Lat-51.51 > 1e-9 {
+		t.Errorf("Expected London on its own, got %+v", london)
+	}
+	if len(paris.Members) != 3 || math.Abs(paris.Center.Lat-48.85) > 0.01 || math.Abs(paris.Center.Lon-2.35) > 0.01 {
+		t.Errorf("Expected the three Paris points around their centroid, got %+v", paris)
+	}
+	if paris.Bounds != (BBox{MinLon: 2.34, MinLat: 48.84, MaxLon: 2.36, MaxLat: 48.86}) {
+		t.Errorf("Expected the bounds of the Paris points, got %+v", paris.Bounds)
+	}
+
+	if clusters = Clusters(points, 2, DEFAULTCLUSTERRADIUS); len(clusters) != 1 || len(clusters[0].Members) != 4 {
+		t.Errorf("Expected a single cluster at zoom 2, got %+v", clusters)
+	}
+	if clusters = Clusters(points, 18, DEFAULTCLUSTERRADIUS); len(clusters) != 4 {
+		t.Errorf("Expected every point on its own at zoom 18, got %+v", clusters)
+	}
+	if clusters = Clusters(nil, 5, DEFAULTCLUSTERRADIUS); len(clusters) != 0 {
+		t.Errorf("Expected no clusters, got %+v", clusters)
+	}
+}
+
+func TestProject(t *testing.T) {
+	for _, p := range []Point{{0, 0}, {48.85, 2.35}, {-33.9, 151.2}} {
+		x, y := project(p, 10)
+		back := unproject(x, y, 10)
+		if math.Abs(back.Lat-p.Lat) > 1e-9 || math.Abs(back.Lon-p.Lon) > 1e-9 {
+			t.Errorf("Expected %+v back, got %+v", p, back)
+		}
+	}
+	if x, y := project(Point{}, 0); x != 128 || y != 128 {
+		t.Errorf("Expected the center of the zoom 0 tile, got %g,%g", x, y)
+	}
+}
+
+func TestPad(t *testing.T) {
+	box := BBox{MinLon: 2, MinLat: 48, MaxLon: 3, MaxLat: 49}
+	padded := Pad(box, 8, 64)
+	// 64 pixels at zoom 8 are 0.35 degrees of longitude.
+	if math.Abs(padded.MinLon-1.65) > 0.01 || math.Abs(padded.MaxLon-3.35) > 0.01 || padded.MinLat >= 48 || padded.MaxLat <= 49 {
+		t.Errorf("Expected the box padded on every side, got %+v", padded)
+	}
+	if padded = Pad(World, 0, 60); padded != World {
+		t.Errorf("Expected the world to stay the world, got %+v", padded)
+	}
+	if padded = Pad(BBox{MinLon: 170, MinLat: 0, MaxLon: 179, MaxLat: 1}, 4, 60); padded.MinLon <= padded.MaxLon {
+		t.Errorf("Expected padding to wrap across the antimeridian, got %+v", padded)
+	}
+}
@@ -0,0 +1,178 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"wdd/api/internal/types"
+)
+
+// EARTHRADIUSKM is the mean Earth radius.
+const EARTHRADIUSKM = 6371.0088
+
+var ErrInvalidCoordinates = errors.New("invalid coordinates")
+
+type Point struct {
+	Lat float64 `json:"latitude"`
+	Lon float64 `json:"longitude"`
+}
+
+// BBox is a bounding box in degrees. A box whose MinLon is greater than its
+// MaxLon crosses the antimeridian.
+type BBox struct {
+	MinLon float64 `json:"minLongitude"`
+	MinLat float64 `json:"minLatitude"`
+	MaxLon float64 `json:"maxLongitude"`
+	MaxLat float64 `json:"maxLatitude"`
+}
+
+// World covers every point.
+var World = BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}
+
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("%w: latitude %g must be between -90 and 90", ErrInvalidCoordinates, p.Lat)
+	}
+	if math.IsNaN(p.Lon) || p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("%w: longitude %g must be between -180 and 180", ErrInvalidCoordinates, p.Lon)
+	}
+	return nil
+}
+
+// ParsePoint parses "lat,lon".
+func ParsePoint(value string) (Point, error) {
+	values, err := parseFloats(value, 2)
+	if err != nil {
+		return Point{}, err
+	}
+	p := Point{Lat: values[0], Lon: values[1]}
+	return p, p.Validate()
+}
+
+// ParseBBox parses "minLon,minLat,maxLon,maxLat", the order GeoJSON and
+// most map libraries use.
+func ParseBBox(value string) (BBox, error) {
+	values, err := parseFloats(value, 4)
+	if err != nil {
+		return BBox{}, err
+	}
+	b := BBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	for _, corner := range []Point{{b.MinLat, b.MinLon}, {b.MaxLat, b.MaxLon}} {
+		if err := corner.Validate(); err != nil {
+			return BBox{}, err
+		}
+	}
+	if b.MinLat > b.MaxLat {
+		return BBox{}, fmt.Errorf("%w: minimum latitude %g is above maximum latitude %g", ErrInvalidCoordinates, b.MinLat, b.MaxLat)
+	}
+	return b, nil
+}
+
+func parseFloats(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("%w: expected %d comma-separated numbers, got %q", ErrInvalidCoordinates, count, value)
+	}
+	values := make([]float64, count)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%w: %q is not a number", ErrInvalidCoordinates, part)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// DistanceKm is the great-circle distance between two points.
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTHRADIUSKM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Around returns the smallest box holding every point within radiusKm of
+// center. Circles over a pole span every longitude.
+func Around(center Point, radiusKm float64) BBox {
+	angular := radiusKm / EARTHRADIUSKM
+	minLat, maxLat := center.Lat-degrees(angular), center.Lat+degrees(angular)
+	if minLat <= -90 || maxLat >= 90 || angular >= math.Pi {
+		return BBox{MinLon: -180, MinLat: math.Max(minLat, -90), MaxLon: 180, MaxLat: math.Min(maxLat, 90)}
+	}
+
+	dLon := degrees(math.Asin(math.Sin(angular) / math.Cos(radians(center.Lat))))
+	if dLon >= 180 {
+		return BBox{MinLon: -180, MinLat: minLat, MaxLon: 180, MaxLat: maxLat}
+	}
+	return BBox{MinLon: wrapLon(center.Lon - dLon), MinLat: minLat, MaxLon: wrapLon(center.Lon + dLon), MaxLat: maxLat}
+}
+
+func (b BBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// Center is the middle of the box, across the antimeridian if it crosses it.
+func (b BBox) Center() Point {
+	maxLon := b.MaxLon
+	if b.MinLon > maxLon {
+		maxLon += 360
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: wrapLon((b.MinLon + maxLon) / 2)}
+}
+
+// split cuts a box crossing the antimeridian in two.
+func (b BBox) split() []BBox {
+	if b.MinLon <= b.MaxLon {
+		return []BBox{b}
+	}
+	return []BBox{
+		{MinLon: b.MinLon, MinLat: b.MinLat, MaxLon: 180, MaxLat: b.MaxLat},
+		{MinLon: -180, MinLat: b.MinLat, MaxLon: b.MaxLon, MaxLat: b.MaxLat},
+	}
+}
+
+func wrapLon(lon float64) float64 {
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// LocationPoint returns the point of a location, or false when either
+// coordinate is missing or out of range.
+func LocationPoint(location *types.Location) (Point, bool) {
+	if location == nil || location.Latitude == nil || location.Longitude == nil {
+		return Point{}, false
+	}
+	p := Point{Lat: *location.Latitude, Lon: *location.Longitude}
+	return p, p.Validate() == nil
+}
+
+// IndexFactory sets a factory's geohash fields from its location, clearing
+// them when the location is incomplete.
+func IndexFactory(factory *types.Factory) {
+	factory.Geohash, factory.GeohashKey = "", ""
+	if p, ok := LocationPoint(factory.Location); ok {
+		factory.Geohash, factory.GeohashKey = Index(p)
+	}
+}
@@ -0,0 +1,125 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func TestEncode(t *testing.T) {
+	cases := map[string]Point{
+		"u4pruydqq": {Lat: 57.64911, Lon: 10.40744},
+		"9q8yyk8yt": {Lat: 37.7749, Lon: -122.4194},
+		"s00000000": {Lat: 0, Lon: 0},
+	}
+	for expected, p := range cases {
+		if got := Encode(p, 9); got != expected {
+			t.Errorf("Expected %s for %+v, got %s", expected, p, got)
+		}
+		if cell := Cell(expected); !cell.Contains(p) {
+			t.Errorf("Expected cell %+v of %s to contain %+v", cell, expected, p)
+		}
+	}
+	if hash, key := Index(Point{Lat: 57.64911, Lon: 10.40744}); hash != "u4pruydqq" || key != "u4p" {
+		t.Errorf("Expected u4pruydqq keyed by u4p, got %s %s", hash, key)
+	}
+}
+
+func TestCover(t *testing.T) {
+	box := BBox{MinLon: 10.3, MinLat: 57.6, MaxLon: 10.5, MaxLat: 57.7}
+	cells, ok := Cover(box, 5, 100)
+	if !ok || len(cells) == 0 {
+		t.Fatalf("Expected a cover, got %v %v", cells, ok)
+	}
+	for _, p := range []Point{{57.6, 10.3}, {57.7, 10.5}, {57.65, 10.4}} {
+		covered := false
+		for _, cell := range cells {
+			covered = covered || Cell(cell).Contains(p)
+		}
+		if !covered {
+			t.Errorf("Expected %+v to be covered by %v", p, cells)
+		}
+	}
+
+	if _, ok := Cover(box, 9, 100); ok {
+		t.Errorf("Expected too many precision 9 cells")
+	}
+
+	// A box across the antimeridian is covered on both sides.
+	cells, ok = Cover(BBox{MinLon: 179, MinLat: 0, MaxLon: -179, MaxLat: 1}, 2, 100)
+	if !ok || len(cells) != 2 {
+		t.Errorf("Expected one cell on each side of the antimeridian, got %v", cells)
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	paris, london := Point{Lat: 48.8566, Lon: 2.3522}, Point{Lat: 51.5074, Lon: -0.1278}
+	if d := DistanceKm(paris, london); math.Abs(d-343.5) > 1 {
+		t.Errorf("Expected about 343.5km from Paris to London, got %g", d)
+	}
+	if d := DistanceKm(paris, paris); d != 0 {
+		t.Errorf("Expected 0km, got %g", d)
+	}
+}
+
+func TestAround(t *testing.T) {
+	center := Point{Lat: 45, Lon: 179.9}
+	box := Around(center, 100)
+	if box.MinLon < box.MaxLon {
+		t.Errorf("Expected the box to cross the antimeridian, got %+v", box)
+	}
+	for _, bearing := range []float64{0, 90, 180, 270} {
+		// A point just inside the radius in each direction is in the box.
+		p := destination(center, 99, bearing)
+		if !box.Contains(p) {
+			t.Errorf("Expected %+v to be in %+v", p, box)
+		}
+	}
+
+	if polar := Around(Point{Lat: 89, Lon: 0}, 500); polar.MinLon != -180 || polar.MaxLon != 180 || polar.MaxLat != 90 {
+		t.Errorf("Expected a circle over the pole to span every longitude, got %+v", polar)
+	}
+}
+
+func destination(p Point, km, bearing float64) Point {
+	d, b := km/EARTHRADIUSKM, radians(bearing)
+	lat1, lon1 := radians(p.Lat), radians(p.Lon)
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return Point{Lat: degrees(lat2), Lon: wrapLon(degrees(lon2))}
+}
+
+func TestParse(t *testing.T) {
+	if p, err := ParsePoint("57.6, 10.4"); err != nil || p != (Point{Lat: 57.6, Lon: 10.4}) {
+		t.Errorf("Expected 57.6,10.4, got %+v %v", p, err)
+	}
+	if b, err := ParseBBox("170,-10,-170,10"); err != nil || b.Center() != (Point{Lat: 0, Lon: 180}) {
+		t.Errorf("Expected a box centered on the antimeridian, got %+v %v", b, err)
+	}
+	for _, value := range []string{"", "1", "1,2,3", "91,0", "0,181", "a,b", "NaN,0"} {
+		if _, err := ParsePoint(value); !errors.Is(err, ErrInvalidCoordinates) {
+			t.Errorf("Expected ErrInvalidCoordinates for %q, got %v", value, err)
+		}
+	}
+	for _, value := range []string{"0,0,1", "0,10,1,5", "0,0,200,1"} {
+		if _, err := ParseBBox(value); !errors.Is(err, ErrInvalidCoordinates) {
+			t.Errorf("Expected ErrInvalidCoordinates for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestIndexFactory(t *testing.T) {
+	lat, lon := 57.64911, 10.40744
+	factory := types.Factory{Location: &types.Location{Latitude: &lat, Longitude: &lon}}
+	IndexFactory(&factory)
+	if factory.Geohash != "u4pruydqq" || factory.GeohashKey != "u4p" {
+		t.Errorf("Expected the factory to be indexed, got %+v", factory)
+	}
+
+	factory.Location.Longitude = nil
+	IndexFactory(&factory)
+	if factory.Geohash != "" || factory.GeohashKey != "" {
+		t.Errorf("Expected an incomplete location to clear the index, got %+v", factory)
+	}
+}
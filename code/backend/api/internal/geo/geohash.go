@@ -0,0 +1,156 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	// GEOHASHPRECISION is the length of stored geohashes, cells of about
+	// 5m by 5m.
+	GEOHASHPRECISION = 9
+	// HASHKEYLENGTH is the geohash prefix that partitions the index, cells
+	// of about 156km by 156km.
+	HASHKEYLENGTH = 3
+)
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of a point with precision characters.
+func Encode(p Point, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	var b strings.Builder
+	b.Grow(precision)
+	bits, ch, even := 0, 0, true
+	for b.Len() < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if p.Lon >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bits++; bits == 5 {
+			b.WriteByte(base32[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return b.String()
+}
+
+// Cell returns the box a geohash stands for. Invalid characters are
+// ignored.
+func Cell(hash string) BBox {
+	box := World
+	even := true
+	for i := 0; i < len(hash); i++ {
+		value := strings.IndexByte(base32, hash[i])
+		if value < 0 {
+			continue
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := value>>bit&1 == 1
+			if even {
+				mid := (box.MinLon + box.MaxLon) / 2
+				if set {
+					box.MinLon = mid
+				} else {
+					box.MaxLon = mid
+				}
+			} else {
+				mid := (box.MinLat + box.MaxLat) / 2
+				if set {
+					box.MinLat = mid
+				} else {
+					box.MaxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return box
+}
+
+// cellSize returns the width and height in degrees of the cells of a
+// precision.
+func cellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 360 / math.Exp2(float64(lonBits)), 180 / math.Exp2(float64(latBits))
+}
+
+// Cover returns the geohashes of a precision whose cells together hold the
+// box. It gives up and returns false when more than limit cells would be
+// needed.
+func Cover(b BBox, precision, limit int) ([]string, bool) {
+	width, height := cellSize(precision)
+
+	type span struct{ lonFrom, lonTo, latFrom, latTo int }
+	var spans []span
+	count := 0
+	for _, part := range b.split() {
+		s := span{
+			lonFrom: cellIndex(part.MinLon+180, width, 360),
+			lonTo:   cellIndex(part.MaxLon+180, width, 360),
+			latFrom: cellIndex(part.MinLat+90, height, 180),
+			latTo:   cellIndex(part.MaxLat+90, height, 180),
+		}
+		count += (s.lonTo - s.lonFrom + 1) * (s.latTo - s.latFrom + 1)
+		if count > limit {
+			return nil, false
+		}
+		spans = append(spans, s)
+	}
+
+	seen := map[string]bool{}
+	hashes := make([]string, 0, count)
+	for _, s := range spans {
+		for y := s.latFrom; y <= s.latTo; y++ {
+			for x := s.lonFrom; x <= s.lonTo; x++ {
+				center := Point{Lat: -90 + (float64(y)+0.5)*height, Lon: -180 + (float64(x)+0.5)*width}
+				if hash := Encode(center, precision); !seen[hash] {
+					seen[hash] = true
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+	}
+	return hashes, true
+}
+
+// cellIndex returns the index of the cell holding offset, clamping the far
+// edge into the last cell.
+func cellIndex(offset, size, span float64) int {
+	last := int(math.Round(span/size)) - 1
+	i := int(math.Floor(offset / size))
+	if i > last {
+		return last
+	}
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// Index returns the geohash stored for a point and the prefix its index
+// partition is keyed by.
+func Index(p Point) (string, string) {
+	hash := Encode(p, GEOHASHPRECISION)
+	return hash, hash[:HASHKEYLENGTH]
+}
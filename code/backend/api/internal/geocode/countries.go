@@ -0,0 +1,106 @@
+package geocode
+
+import "strings"
+
+// countries names the ISO 3166-1 alpha-2 codes of the bundled gazetteer and
+// of the other countries factories are commonly in.
+var countries = map[string]string{
+	"AE": "United Arab Emirates",
+	"AR": "Argentina",
+	"AT": "Austria",
+	"AU": "Australia",
+	"BE": "Belgium",
+	"BR": "Brazil",
+	"CA": "Canada",
+	"CH": "Switzerland",
+	"CL": "Chile",
+	"CN": "China",
+	"CZ": "Czechia",
+	"DE": "Germany",
+	"DK": "Denmark",
+	"EG": "Egypt",
+	"ES": "Spain",
+	"FI": "Finland",
+	"FR": "France",
+	"GB": "United Kingdom",
+	"HU": "Hungary",
+	"ID": "Indonesia",
+	"IE": "Ireland",
+	"IN": "India",
+	"IT": "Italy",
+	"JP": "Japan",
+	"KR": "South Korea",
+	"MA": "Morocco",
+	"MX": "Mexico",
+	"MY": "Malaysia",
+	"NG": "Nigeria",
+	"NL": "Netherlands",
+	"NO": "Norway",
+	"NZ": "New Zealand",
+	"PH": "Philippines",
+	"PL": "Poland",
+	"PT": "Portugal",
+	"RO": "Romania",
+	"RU": "Russia",
+	"SE": "Sweden",
+	"SG": "Singapore",
+	"SK": "Slovakia",
+	"TH": "Thailand",
+	"TR": "Turkey",
+	"TW": "Taiwan",
+	"UA": "Ukraine",
+	"US": "United States",
+	"VN": "Vietnam",
+	"ZA": "South Africa",
+}
+
+// countryAliases are other common names of countries, folded.
+var countryAliases = map[string]string{
+	"deutschland":              "DE",
+	"england":                  "GB",
+	"great britain":            "GB",
+	"uk":                       "GB",
+	"usa":                      "US",
+	"united states of america": "US",
+	"espana":                   "ES",
+	"italia":                   "IT",
+	"nederland":                "NL",
+	"osterreich":               "AT",
+	"schweiz":                  "CH",
+	"suisse":                   "CH",
+	"polska":                   "PL",
+	"cesko":                    "CZ",
+	"czech republic":           "CZ",
+	"korea":                    "KR",
+	"nippon":                   "JP",
+}
+
+var countriesByName = func() map[string]string {
+	byName := map[string]string{}
+	for code, name := range countries {
+		byName[fold(name)] = code
+	}
+	for alias, code := range countryAliases {
+		byName[alias] = code
+	}
+	return byName
+}()
+
+// CountryName returns the English name of a country code, or the code itself
+// when it is unknown.
+func CountryName(code string) string {
+	if name, ok := countries[code]; ok {
+		return name
+	}
+	return code
+}
+
+// CountryCode returns the code of a country named or coded by an address
+// part, or "" when it names none.
+func CountryCode(part string) string {
+	part = strings.TrimSpace(part)
+	if _, ok := countries[part]; ok && strings.ToUpper(part) == part {
+		return part
+	}
+	return countriesByName[fold(part)]
+}
@@ -0,0 +1,180 @@
+package geocode
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"wdd/api/internal/geo"
+)
+
+// GEONAMESCOLUMNS is the column count of the GeoNames geoname table.
+const GEONAMESCOLUMNS = 19
+
+var ErrInvalidGazetteer = errors.New("invalid gazetteer")
+
+// Gazetteer geocodes offline against a list of places in the GeoNames
+// geoname format: tab-separated geonameid, name, asciiname, alternatenames,
+// latitude, longitude, feature class, feature code, country code, cc2, four
+// admin codes, population, elevation, dem, timezone and modification date.
+type Gazetteer struct {
+	places []Place
+	// byName maps every folded name and alternate name to its places, most
+	// populous first.
+	byName map[string][]int
+}
+
+func Load(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a gazetteer. Only populated places (feature class P) are kept.
+func Parse(r io.Reader) (*Gazetteer, error) {
+	g := &Gazetteer{byName: map[string][]int{}}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		columns := strings.Split(text, "\t")
+		if len(columns) != GEONAMESCOLUMNS {
+			return nil, fmt.Errorf("%w: line %d has %d columns, expected %d", ErrInvalidGazetteer, line, len(columns), GEONAMESCOLUMNS)
+		}
+		if columns[6] != "P" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(columns[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid geonameid %q", ErrInvalidGazetteer, line, columns[0])
+		}
+		lat, latErr := strconv.ParseFloat(columns[4], 64)
+		lon, lonErr := strconv.ParseFloat(columns[5], 64)
+		point := geo.Point{Lat: lat, Lon: lon}
+		if latErr != nil || lonErr != nil || point.Validate() != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid coordinates %q,%q", ErrInvalidGazetteer, line, columns[4], columns[5])
+		}
+		population, _ := strconv.ParseInt(columns[14], 10, 64)
+
+		g.places = append(g.places, Place{
+			GeonameID:   id,
+			Name:        columns[1],
+			CountryCode: columns[8],
+			Point:       point,
+			Population:  population,
+			Timezone:    columns[17],
+		})
+		index := len(g.places) - 1
+		names := append([]string{columns[1], columns[2]}, strings.Split(columns[3], ",")...)
+		seen := map[string]bool{}
+		for _, name := range names {
+			if key := fold(name); key != "" && !seen[key] {
+				seen[key] = true
+				g.byName[key] = append(g.byName[key], index)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, indexes := range g.byName {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return g.places[indexes[i]].Population > g.places[indexes[j]].Population
+		})
+	}
+	return g, nil
+}
+
+// Geocode reads an address as comma-separated parts, such as "Hauptstraße 1,
+// 70173 Stuttgart, Germany". A last part naming a country restricts the
+// search to it; the other parts are tried from last to first, as they are
+// and without their numbers, and the most populous place named by the first
+// part that names any wins.
+func (g *Gazetteer) Geocode(ctx context.Context, address string) (Place, error) {
+	parts := strings.Split(address, ",")
+	var country string
+	// Only a last part names a country, as "CA" in "San Jose, CA" is a state.
+	if last := len(parts) - 1; last > 0 {
+		if country = CountryCode(parts[last]); country != "" {
+			parts = parts[:last]
+		}
+	}
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		for _, key := range []string{fold(parts[i]), fold(withoutNumbers(parts[i]))} {
+			for _, index := range g.byName[key] {
+				if place := g.places[index]; country == "" || place.CountryCode == country {
+					return place, nil
+				}
+			}
+		}
+	}
+	return Place{}, fmt.Errorf("%w for %q", ErrNotFound, address)
+}
+
+// Reverse returns the nearest place within MAXREVERSEKM.
+func (g *Gazetteer) Reverse(ctx context.Context, p geo.Point) (Place, error) {
+	place, ok := g.Nearest(p, MAXREVERSEKM)
+	if !ok {
+		return Place{}, fmt.Errorf("%w within %dkm of %g,%g", ErrNotFound, MAXREVERSEKM, p.Lat, p.Lon)
+	}
+	return place, nil
+}
+
+// Nearest returns the place nearest a point, or false when none is within
+// maxKm.
+func (g *Gazetteer) Nearest(p geo.Point, maxKm float64) (Place, bool) {
+	nearest, best := -1, maxKm
+	for i, place := range g.places {
+		if d := geo.DistanceKm(p, place.Point); d <= best {
+			nearest, best = i, d
+		}
+	}
+	if nearest < 0 {
+		return Place{}, false
+	}
+	return g.places[nearest], true
+}
+
+func withoutNumbers(part string) string {
+	var words []string
+	for _, word := range strings.Fields(part) {
+		if strings.IndexFunc(word, unicode.IsDigit) < 0 {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+var foldReplacer = strings.NewReplacer(
+	"ä", "a", "á", "a", "à", "a", "â", "a", "ã", "a", "å", "a", "ā", "a",
+	"ç", "c", "č", "c", "é", "e", "è", "e", "ê", "e", "ë", "e", "ē", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ł", "l", "ñ", "n", "ń", "n",
+	"ö", "o", "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ø", "o", "ō", "o",
+	"ß", "ss", "š", "s", "ś", "s", "ü", "u", "ú", "u", "ù", "u", "û", "u", "ū", "u",
+	"ý", "y", "ž", "z", "ź", "z", "ż", "z",
+)
+
+// fold lowercases a name, strips common diacritics and punctuation and
+// collapses spaces, so that "São  Paulo" and "sao paulo" match.
+func fold(name string) string {
+	name = foldReplacer.Replace(strings.ToLower(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
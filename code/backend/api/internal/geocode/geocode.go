@@ -0,0 +1,88 @@
+package geocode
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"wdd/api/internal/geo"
+)
+
+// MAXREVERSEKM is how far from a point the nearest place may be for it to
+// name the point's address.
+const MAXREVERSEKM = 50
+
+var ErrNotFound = errors.New("no matching place")
+
+// Place is a populated place of a gazetteer.
+type Place struct {
+	GeonameID   int64     `json:"geonameId"`
+	Name        string    `json:"name"`
+	CountryCode string    `json:"countryCode"`
+	Point       geo.Point `json:"point"`
+	Population  int64     `json:"population"`
+	Timezone    string    `json:"timezone,omitempty"`
+}
+
+// Address formats a place as "<name>, <country>".
+func (p Place) Address() string {
+	if country := CountryName(p.CountryCode); country != "" {
+		return p.Name + ", " + country
+	}
+	return p.Name
+}
+
+type Geocoder interface {
+	// Geocode resolves a free-form address to the place it names.
+	Geocode(ctx context.Context, address string) (Place, error)
+	// Reverse returns the place nearest a point.
+	Reverse(ctx context.Context, p geo.Point) (Place, error)
+}
+
+//go:embed cities.txt
+var bundled string
+
+var (
+	defaultOnce      sync.Once
+	defaultGazetteer *Gazetteer
+	defaultErr       error
+)
+
+// DefaultGazetteer loads the gazetteer at the path in the GAZETTEER
+// environment variable, such as a GeoNames cities15000.txt dump, or else the
+// bundled gazetteer of major cities.
+func DefaultGazetteer() (*Gazetteer, error) {
+	defaultOnce.Do(func() {
+		if path := os.Getenv("GAZETTEER"); path != "" {
+			defaultGazetteer, defaultErr = Load(path)
+		} else {
+			defaultGazetteer, defaultErr = Parse(strings.NewReader(bundled))
+		}
+	})
+	return defaultGazetteer, defaultErr
+}
+
+// Default returns the default gazetteer as a geocoder.
+func Default() Geocoder {
+	g, err := DefaultGazetteer()
+	if err != nil {
+		return unavailable{err}
+	}
+	return g
+}
+
+// unavailable fails every lookup with the error that kept the gazetteer
+// from loading.
+type unavailable struct {
+	err error
+}
+
+func (u unavailable) Geocode(ctx context.Context, address string) (Place, error) {
+	return Place{}, u.err
+}
+
+func (u unavailable) Reverse(ctx context.Context, p geo.Point) (Place, error) {
+	return Place{}, u.err
+}
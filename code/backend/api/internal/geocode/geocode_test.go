@@ -0,0 +1,76 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"wdd/api/internal/geo"
+)
+
+const gazetteer = "4049979\tBirmingham\tBirmingham\t\t33.52066\t-86.80249\tP\tPPLA2\tUS\t\tAL\t073\t\t\t200733\t\t183\tAmerica/Chicago\t2024-01-01\n" +
+	"2655603\tBirmingham\tBirmingham\t\t52.48142\t-1.89983\tP\tPPLA2\tGB\t\tENG\t\t\t\t984333\t\t149\tEurope/London\t2024-01-01\n" +
+	"# Not a populated place.\n" +
+	"2960316\tAlps\tAlps\t\t46.5\t10\tT\tMTS\tCH\t\t\t\t\t\t0\t\t\tEurope/Zurich\t2024-01-01\n"
+
+func TestParse(t *testing.T) {
+	g, err := Parse(strings.NewReader(gazetteer))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(g.places) != 2 {
+		t.Errorf("Expected only the 2 populated places, got %+v", g.places)
+	}
+	if place, _ := g.Geocode(context.Background(), "Birmingham"); place.CountryCode != "GB" {
+		t.Errorf("Expected the most populous Birmingham, got %+v", place)
+	}
+	if place, _ := g.Geocode(context.Background(), "Birmingham, United States"); place.CountryCode != "US" || place.Timezone != "America/Chicago" {
+		t.Errorf("Expected Birmingham in the US, got %+v", place)
+	}
+
+	for _, invalid := range []string{"1\tToo few columns\n", strings.Replace(gazetteer, "33.52066", "north", 1)} {
+		if _, err := Parse(strings.NewReader(invalid)); !errors.Is(err, ErrInvalidGazetteer) {
+			t.Errorf("Expected ErrInvalidGazetteer, got %v", err)
+		}
+	}
+}
+
+func TestGeocode(t *testing.T) {
+	cases := map[string]string{
+		"Hauptstraße 1, 70173 Stuttgart, Germany": "Stuttgart",
+		"Leopoldstraße 10, 80802 München":         "Munich",
+		"MUENCHEN":                                "Munich",
+		"1 Infinite Loop, Sao  Paulo":             "São Paulo",
+		"Woodward Ave, Detroit, MI 48201, USA":    "Detroit",
+		"Ciudad de México, Mexico":                "Mexico City",
+		"Köln, Deutschland":                       "Köln",
+		"Toronto, ON, CA":                         "Toronto",
+	}
+	for address, expected := range cases {
+		place, err := Default().Geocode(context.Background(), address)
+		if err != nil || place.Name != expected {
+			t.Errorf("Expected %s for %q, got %+v %v", expected, address, place, err)
+		}
+	}
+
+	for _, address := range []string{"", "Atlantis", "Paris, Japan"} {
+		if _, err := Default().Geocode(context.Background(), address); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q, got %v", address, err)
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	place, err := Default().Reverse(context.Background(), geo.Point{Lat: 48.8, Lon: 9.2})
+	if err != nil || place.Address() != "Stuttgart, Germany" {
+		t.Errorf("Expected Stuttgart, Germany, got %+v %v", place, err)
+	}
+	if math.Abs(place.Point.Lat-48.78232) > 1e-9 {
+		t.Errorf("Expected the coordinates of Stuttgart, got %+v", place.Point)
+	}
+
+	if _, err := Default().Reverse(context.Background(), geo.Point{Lat: 0, Lon: -30}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound in the middle of the Atlantic, got %v", err)
+	}
+}
@@ -3,10 +3,12 @@ package assets
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -31,8 +33,17 @@ func (h Handler) HandleCreateAssetRequest(ctx context.Context, request events.AP
 		return apiResponse(http.StatusBadRequest, "Error parsing JSON body: "+err.Error(), headers), nil
 	}
 
+	if err := h.validatePosition(ctx, asset, false); err != nil {
+		if errors.Is(err, validation.ErrInvalid) {
+			return apiResponse(http.StatusBadRequest, validation.Body(err), headers), nil
+		}
+		return apiResponse(http.StatusInternalServerError, "Error reading floorplan: "+err.Error(), headers), nil
+	}
+
 	asset.AssetID = uuid.NewString()
 	asset.DateCreated = time.Now().Format(time.RFC3339)
+	// A new position is on the floorplan's current image.
+	asset.FloorplanVersion = nil
 
 	if err := processAssetFiles(ctx, &asset, h.S3Uploader); err != nil {
 		return apiResponse(http.StatusInternalServerError, err.Error(), headers), nil
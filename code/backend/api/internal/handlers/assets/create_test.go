@@ -37,7 +37,7 @@ func TestHandleCreateAssetRequest_BadJSON(t *testing.T) {
 }
 
 func TestHandleCreateAssetRequest_Base64DecodeStringError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{}
 
 	originalBase64DecodeString := wrappers.Base64DecodeString
@@ -68,7 +68,7 @@ func TestHandleCreateAssetRequest_Base64DecodeStringError(t *testing.T) {
 
 //nolint:dupl
 func TestHandleCreateAssetRequest_UploadImageError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return nil, errors.New("upload error")
@@ -101,7 +101,7 @@ func TestHandleCreateAssetRequest_UploadImageError(t *testing.T) {
 
 //nolint:dupl
 func TestHandleCreateAssetRequest_UploadModelError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return nil, errors.New("upload error")
@@ -133,7 +133,7 @@ func TestHandleCreateAssetRequest_UploadModelError(t *testing.T) {
 }
 
 func TestHandleCreateAssetRequest_MarshalMapError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return &manager.UploadOutput{}, nil
@@ -172,6 +172,7 @@ func TestHandleCreateAssetRequest_MarshalMapError(t *testing.T) {
 
 func TestHandleCreateAssetRequest_PutItemError(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
 			return nil, errors.New("mock dynamodb error")
 		},
@@ -208,6 +209,7 @@ func TestHandleCreateAssetRequest_PutItemError(t *testing.T) {
 
 func TestHandleCreateAssetRequest_JSONMarshalError(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
 			return &dynamodb.PutItemOutput{}, nil
 		},
@@ -250,6 +252,7 @@ func TestHandleCreateAssetRequest_JSONMarshalError(t *testing.T) {
 
 func TestHandleCreateAssetRequest_Success(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
 			return &dynamodb.PutItemOutput{}, nil
 		},
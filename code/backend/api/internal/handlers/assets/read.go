@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
 	"wdd/api/internal/types"
 	"wdd/api/internal/wrappers"
+	"wdd/api/internal/zone"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -73,6 +76,22 @@ func (h Handler) HandleReadFactoryAssetsRequest(ctx context.Context, request eve
 		}, nil
 	}
 
+	c := catalog.New(h.DynamoDB)
+	if err = calibration.Locate(ctx, c, assets); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading floorplans: %s", err),
+		}, nil
+	}
+	if err = zone.Locate(ctx, c, assets); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading zones: %s", err),
+		}, nil
+	}
+
 	assetsJSON, err := wrappers.JSONMarshal(assets)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
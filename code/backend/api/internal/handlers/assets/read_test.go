@@ -2,6 +2,7 @@ package assets
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"testing"
 	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
 	"wdd/api/internal/wrappers"
 )
 
@@ -207,3 +209,74 @@ func TestHandleReadFactoryAssetsRequest_WithAssetId_Success(t *testing.T) {
 		t.Errorf("Expected status code %d for successful read, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleReadFactoryAssetsRequest_Placement(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if *params.TableName == "Zone" {
+				vertex := func(x, y string) ddbtypes.AttributeValue {
+					return &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+						"x": &ddbtypes.AttributeValueMemberN{Value: x},
+						"y": &ddbtypes.AttributeValueMemberN{Value: y},
+					}}
+				}
+				return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{{
+					"zoneId":      &ddbtypes.AttributeValueMemberS{Value: "z1"},
+					"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "test"},
+					"polygon":     &ddbtypes.AttributeValueMemberL{Value: []ddbtypes.AttributeValue{vertex("0", "0"), vertex("50", "0"), vertex("50", "50")}},
+				}}}, nil
+			}
+			items := []map[string]ddbtypes.AttributeValue{
+				{
+					"assetId":     &ddbtypes.AttributeValueMemberS{Value: "1"},
+					"factoryId":   &ddbtypes.AttributeValueMemberS{Value: "Factory 1"},
+					"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "test"},
+					"floorplanCoords": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+						"longitude": &ddbtypes.AttributeValueMemberN{Value: "20"},
+						"latitude":  &ddbtypes.AttributeValueMemberN{Value: "10"},
+					}},
+				},
+				{
+					"assetId":   &ddbtypes.AttributeValueMemberS{Value: "2"},
+					"factoryId": &ddbtypes.AttributeValueMemberS{Value: "Factory 1"},
+				},
+			}
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+		BatchGetItemFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			return &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]ddbtypes.AttributeValue{
+				"Floorplan": {{
+					"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "test"},
+					"calibration": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+						"metersPerPixel": &ddbtypes.AttributeValueMemberN{Value: "0.5"},
+					}},
+				}},
+			}}, nil
+		},
+	}
+
+	handler := NewReadFactoryAssetsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "Factory 1"},
+	}
+
+	response, err := handler.HandleReadFactoryAssetsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var assets []types.Asset
+	if err := json.Unmarshal([]byte(response.Body), &assets); err != nil || len(assets) != 2 {
+		t.Fatalf("Expected 2 assets, got %s", response.Body)
+	}
+	if p := assets[0].PositionMeters; p == nil || p.X != 10 || p.Y != -5 {
+		t.Errorf("Expected asset 1 at 10,-5 meters, got %+v", p)
+	}
+	if len(assets[0].Zones) != 1 || assets[0].Zones[0] != "z1" {
+		t.Errorf("Expected asset 1 in zone z1, got %v", assets[0].Zones)
+	}
+	if assets[1].PositionMeters != nil || assets[1].Zones != nil {
+		t.Errorf("Expected asset 2 without a position or zones, got %+v", assets[1])
+	}
+}
@@ -0,0 +1,85 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/snapshot"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func NewReadAssetSnapshotHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadAssetSnapshotRequest serves GET /assets/{id}/snapshot: the asset
+// with its model, properties, measurements and latest readings.
+func (h Handler) HandleReadAssetSnapshotRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	assetID := request.PathParameters["id"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if assetID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing asset ID",
+		}, nil
+	}
+
+	asset, err := snapshot.New(h.DynamoDB).Asset(ctx, assetID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading asset snapshot: %s", err.Error()),
+		}, nil
+	}
+
+	location, err := timezone.Load(ctx, catalog.New(h.DynamoDB), request.QueryStringParameters["tz"], aws.ToString(asset.FactoryID))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error loading timezone: %s", err.Error()),
+		}, nil
+	}
+
+	assetJSON, err := wrappers.JSONMarshal(asset)
+	if err == nil {
+		assetJSON, err = timezone.Render(assetJSON, location)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(assetJSON),
+	}, nil
+}
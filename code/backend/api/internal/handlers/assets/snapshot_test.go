@@ -0,0 +1,80 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/snapshot"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockSnapshotClient knows asset a1, whose model m1 has property p1 with
+// one reading.
+func mockSnapshotClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if params.Key["assetId"].(*types.AttributeValueMemberS).Value != "a1" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"assetId": &types.AttributeValueMemberS{Value: "a1"},
+				"modelId": &types.AttributeValueMemberS{Value: "m1"},
+			}}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if *params.TableName == "Model" {
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+					"properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "p1"}}},
+				}}}, nil
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+				"propertyId": &types.AttributeValueMemberS{Value: "p1"},
+				"timestamp":  &types.AttributeValueMemberN{Value: "1700000000000"},
+				"value":      &types.AttributeValueMemberN{Value: "21.5"},
+			}}}, nil
+		},
+		BatchGetItemFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			item := map[string]types.AttributeValue{"measurementId": &types.AttributeValueMemberS{Value: "m1"}}
+			if _, ok := params.RequestItems["Property"]; ok {
+				return &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{"Property": {{
+					"propertyId":    &types.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+				}}}}, nil
+			}
+			return &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{"Measurement": {item}}}, nil
+		},
+	}
+}
+
+func TestHandleReadAssetSnapshotRequest(t *testing.T) {
+	handler := NewReadAssetSnapshotHandler(mockSnapshotClient())
+
+	cases := map[string]int{"": http.StatusBadRequest, "missing": http.StatusNotFound, "a1": http.StatusOK}
+	for id, status := range cases {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}}
+		response, err := handler.HandleReadAssetSnapshotRequest(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %q, got %d", status, id, response.StatusCode)
+		}
+		if status != http.StatusOK {
+			continue
+		}
+
+		var asset snapshot.Asset
+		if err := json.Unmarshal([]byte(response.Body), &asset); err != nil {
+			t.Fatalf("Expected a JSON snapshot, got %s", response.Body)
+		}
+		if asset.Model == nil || len(asset.Properties) != 1 || asset.Properties[0].Measurement == nil || asset.Properties[0].Latest.Value != 21.5 {
+			t.Errorf("Expected the model, property, measurement and latest reading, got %s", response.Body)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -37,6 +39,17 @@ func (h Handler) HandleUpdateAssetRequest(ctx context.Context, request events.AP
 		return h.responseBadRequest(headers, err)
 	}
 
+	if err := h.validatePosition(ctx, asset, true); err != nil {
+		if errors.Is(err, validation.ErrInvalid) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       validation.Body(err),
+			}, nil
+		}
+		return h.responseInternalServerError(headers, err)
+	}
+
 	if err := h.updateAsset(ctx, &asset); err != nil {
 		return h.responseInternalServerError(headers, err)
 	}
@@ -189,6 +202,10 @@ func (h Handler) createUpdateBuilder(asset *types.Asset) expression.UpdateBuilde
 		setIfNotNil("floorplanCoords.longitude", asset.FloorplanCoords.Longitude)
 		setIfNotNil("floorplanCoords.latitude", asset.FloorplanCoords.Latitude)
 	}
+	// A new position is on the current image of its floorplan.
+	if asset.FloorplanCoords != nil || asset.FloorplanID != nil {
+		updateBuilder = updateBuilder.Remove(expression.Name("floorplanVersion"))
+	}
 
 	setIfNotNil("modelUrl", asset.ModelURL)
 	setIfNotNil("type", asset.Type)
@@ -38,7 +38,7 @@ func TestHandleUpdateAssetRequest_BadJSON(t *testing.T) {
 }
 
 func TestHandleUpdateAssetRequest_WithImage_Base64DecodeStringError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{}
 
 	originalBase64DecodeString := wrappers.Base64DecodeString
@@ -68,7 +68,7 @@ func TestHandleUpdateAssetRequest_WithImage_Base64DecodeStringError(t *testing.T
 }
 
 func TestHandleUpdateAssetRequest_WithModel_Base64DecodeStringError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{}
 
 	originalBase64DecodeString := wrappers.Base64DecodeString
@@ -99,7 +99,7 @@ func TestHandleUpdateAssetRequest_WithModel_Base64DecodeStringError(t *testing.T
 
 //nolint:dupl
 func TestHandleUpdateAssetRequest_UploadImageError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return nil, errors.New("upload error")
@@ -132,7 +132,7 @@ func TestHandleUpdateAssetRequest_UploadImageError(t *testing.T) {
 
 //nolint:dupl
 func TestHandleUpdateAssetRequest_UploadModelError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return nil, errors.New("upload error")
@@ -164,7 +164,7 @@ func TestHandleUpdateAssetRequest_UploadModelError(t *testing.T) {
 }
 
 func TestHandleUpdateAssetRequest_UpdateExpressionBuilderError(t *testing.T) {
-	mockDDBClient := &mocks.DynamoDBClient{}
+	mockDDBClient := &mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}
 	mockS3Uploader := &mocks.S3Uploader{
 		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 			return &manager.UploadOutput{}, nil
@@ -203,6 +203,7 @@ func TestHandleUpdateAssetRequest_UpdateExpressionBuilderError(t *testing.T) {
 
 func TestHandleUpdateAssetRequest_UpdateItemError(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			return nil, errors.New("mock dynamodb error")
 		},
@@ -239,6 +240,7 @@ func TestHandleUpdateAssetRequest_UpdateItemError(t *testing.T) {
 
 func TestHandleUpdateAssetRequest_JSONMarshalError(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			return &dynamodb.UpdateItemOutput{}, nil
 		},
@@ -302,6 +304,7 @@ func TestHandleUpdateAssetRequest_JSONMarshalError(t *testing.T) {
 //nolint:dupl
 func TestHandleUpdateAssetRequest_WithImagePrefix_Success(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			return &dynamodb.UpdateItemOutput{}, nil
 		},
@@ -359,6 +362,7 @@ func TestHandleUpdateAssetRequest_WithImagePrefix_Success(t *testing.T) {
 //nolint:dupl
 func TestHandleUpdateAssetRequest_Success(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
 		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			return &dynamodb.UpdateItemOutput{}, nil
 		},
@@ -0,0 +1,56 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
+)
+
+// validatePosition checks the floorplan coordinates of an asset against the
+// bounds of its floorplan. An update may move the asset or change its
+// floorplan without giving the rest of its position, which is then read
+// from the stored asset.
+func (h Handler) validatePosition(ctx context.Context, asset types.Asset, update bool) error {
+	if asset.FloorplanCoords == nil && (!update || asset.FloorplanID == nil) {
+		return nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	coords := types.FloorplanCoords{}
+	if asset.FloorplanCoords != nil {
+		coords = *asset.FloorplanCoords
+	}
+	floorplanID := asset.FloorplanID
+	if update && (floorplanID == nil || coords.Longitude == nil || coords.Latitude == nil) {
+		stored, err := c.Asset(ctx, asset.AssetID)
+		if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+			return err
+		}
+		if floorplanID == nil {
+			floorplanID = stored.FloorplanID
+		}
+		if stored.FloorplanCoords != nil {
+			if coords.Longitude == nil {
+				coords.Longitude = stored.FloorplanCoords.Longitude
+			}
+			if coords.Latitude == nil {
+				coords.Latitude = stored.FloorplanCoords.Latitude
+			}
+		}
+	}
+
+	if floorplanID == nil || *floorplanID == "" {
+		return validation.FloorplanCoords(&coords, nil)
+	}
+	floorplan, err := c.Floorplan(ctx, *floorplanID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return validation.Invalid("floorplanId", fmt.Sprintf("floorplan %s does not exist", *floorplanID))
+	}
+	if err != nil {
+		return err
+	}
+	return validation.FloorplanCoords(&coords, &floorplan)
+}
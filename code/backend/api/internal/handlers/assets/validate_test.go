@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockGetFloorplan finds floorplan "test", 100 by 50 pixels, and asset "1"
+// on it at (10, 10).
+func mockGetFloorplan(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	switch aws.ToString(params.TableName) {
+	case "Floorplan":
+		if params.Key["floorplanId"].(*ddbtypes.AttributeValueMemberS).Value == "test" {
+			return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+				"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "test"},
+				"width":       &ddbtypes.AttributeValueMemberN{Value: "100"},
+				"height":      &ddbtypes.AttributeValueMemberN{Value: "50"},
+			}}, nil
+		}
+	case "Asset":
+		if params.Key["assetId"].(*ddbtypes.AttributeValueMemberS).Value == "1" {
+			return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+				"assetId":     &ddbtypes.AttributeValueMemberS{Value: "1"},
+				"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "test"},
+				"floorplanCoords": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+					"longitude": &ddbtypes.AttributeValueMemberN{Value: "10"},
+					"latitude":  &ddbtypes.AttributeValueMemberN{Value: "10"},
+				}},
+			}}, nil
+		}
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func TestHandleCreateAssetRequest_InvalidPosition(t *testing.T) {
+	handler := NewCreateAssetHandler(&mocks.DynamoDBClient{GetItemFunc: mockGetFloorplan}, nil)
+
+	cases := map[string]string{
+		`{"floorplanId": "test", "floorplanCoords": {"longitude": 101, "latitude": 60}}`: "floorplanCoords.longitude",
+		`{"floorplanId": "test", "floorplanCoords": {"longitude": 1, "latitude": -1}}`:   "floorplanCoords.latitude",
+		`{"floorplanCoords": {"longitude": -5}}`:                                         "floorplanCoords.longitude",
+		`{"floorplanId": "gone", "floorplanCoords": {"longitude": 1, "latitude": 1}}`:    "floorplanId",
+	}
+	for body, field := range cases {
+		response, err := handler.HandleCreateAssetRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var rejection struct {
+			Violations []struct {
+				Field string `json:"field"`
+			} `json:"violations"`
+		}
+		if err := json.Unmarshal([]byte(response.Body), &rejection); err != nil || response.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected a structured %d for %s, got %d %s", http.StatusBadRequest, body, response.StatusCode, response.Body)
+		}
+		if len(rejection.Violations) == 0 || rejection.Violations[0].Field != field {
+			t.Errorf("Expected a violation of %s for %s, got %s", field, body, response.Body)
+		}
+	}
+}
+
+func TestHandleUpdateAssetRequest_InvalidPosition(t *testing.T) {
+	handler := NewUpdateAssetHandler(&mocks.DynamoDBClient{
+		GetItemFunc: mockGetFloorplan,
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}, nil)
+
+	// The stored y of 10 is within the floorplan, the new x of 150 is not.
+	response, _ := handler.HandleUpdateAssetRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"assetId": "1", "floorplanCoords": {"longitude": 150}}`})
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a position off the floorplan, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	response, _ = handler.HandleUpdateAssetRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"assetId": "1", "floorplanCoords": {"latitude": 49.5}}`})
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for a position on the floorplan, got %d %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+}
@@ -0,0 +1,93 @@
+package calendars
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"wdd/api/internal/calendar"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func NewCreateCalendarHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+func (h Handler) HandleCreateCalendarRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var cal types.Calendar
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &cal); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	if cal.FactoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factoryId in request body",
+		}, nil
+	}
+
+	if _, err := calendar.Compile(cal); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	cal.DateCreated = time.Now().Format(time.RFC3339)
+
+	av, err := wrappers.MarshalMap(cal)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling calendar to DynamoDB format: %s", err.Error()),
+		}, nil
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(TABLENAME),
+	}
+
+	if _, err = h.DynamoDB.PutItem(ctx, input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error putting item into DynamoDB: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(cal)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response body: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
@@ -0,0 +1,135 @@
+package calendars
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/wrappers"
+)
+
+const validCalendarBody = `{"factoryId":"1","timezone":"America/Chicago","shifts":[{"name":"Day","days":["monday","tuesday"],"start":"06:00","end":"14:00","breaks":[{"name":"Lunch","start":"10:00","end":"10:30"}]}],"holidays":[{"date":"2024-12-25","name":"Christmas"}]}`
+
+func TestHandleCreateCalendarRequest_BadJSON(t *testing.T) {
+	handler := NewCreateCalendarHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","shifts":"Invalid"}`,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for bad JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateCalendarRequest_MissingFactoryID(t *testing.T) {
+	handler := NewCreateCalendarHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"timezone":"UTC"}`,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateCalendarRequest_InvalidCalendar(t *testing.T) {
+	handler := NewCreateCalendarHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","timezone":"Mars/Olympus_Mons"}`,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for unknown timezone, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateCalendarRequest_MarshalMapError(t *testing.T) {
+	handler := NewCreateCalendarHandler(&mocks.DynamoDBClient{})
+
+	originalMarshalMap := wrappers.MarshalMap
+
+	defer func() { wrappers.MarshalMap = originalMarshalMap }()
+
+	wrappers.MarshalMap = func(interface{}) (map[string]types.AttributeValue, error) {
+		return nil, errors.New("mock error")
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: validCalendarBody,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for marshalling calendar to DynamoDB format, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleCreateCalendarRequest_PutItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewCreateCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: validCalendarBody,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB put item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleCreateCalendarRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: validCalendarBody,
+	}
+
+	response, err := handler.HandleCreateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for successful creation, got %d", http.StatusOK, response.StatusCode)
+	}
+}
@@ -0,0 +1,145 @@
+package calendars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wdd/api/internal/calendar"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewReadCalendarHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadCalendarRequest returns a factory's calendar, or with an "at"
+// query parameter (RFC3339 or "now") the calendar state at that instant. tz
+// (an IANA zone, or "factory") gives dateCreated in local time.
+func (h Handler) HandleReadCalendarRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	factoryID := request.QueryStringParameters["factoryId"]
+	at := request.QueryStringParameters["at"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if factoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factoryId query parameter",
+		}, nil
+	}
+
+	location, err := timezone.Load(ctx, catalog.New(h.DynamoDB), request.QueryStringParameters["tz"], factoryID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error loading timezone: %s", err.Error()),
+		}, nil
+	}
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(TABLENAME),
+		Key: map[string]ddbtypes.AttributeValue{
+			"factoryId": &ddbtypes.AttributeValueMemberS{Value: factoryID},
+		},
+	}
+
+	result, err := h.DynamoDB.GetItem(ctx, input)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding calendar: %s", err),
+		}, nil
+	}
+
+	if result.Item == nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Calendar for factory %s not found", factoryID),
+		}, nil
+	}
+
+	var cal types.Calendar
+	if err = wrappers.UnmarshalMap(result.Item, &cal); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Failed to unmarshal calendar, %v", err),
+		}, nil
+	}
+
+	var body interface{} = cal
+	if at != "" {
+		state, status, err := h.calendarState(ctx, cal, at)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: status,
+				Headers:    headers,
+				Body:       err.Error(),
+			}, nil
+		}
+		body = state
+	}
+
+	responseBody, err := wrappers.JSONMarshal(body)
+	if err == nil {
+		responseBody, err = timezone.Render(responseBody, location)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+// calendarState evaluates a calendar at an instant in the zone the simulator
+// runs it in, its factory's when it has none of its own.
+func (h Handler) calendarState(ctx context.Context, cal types.Calendar, at string) (calendar.State, int, error) {
+	instant := time.Now()
+	if at != "now" {
+		parsed, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return calendar.State{}, http.StatusBadRequest, fmt.Errorf("invalid at parameter: %s", err.Error())
+		}
+		instant = parsed
+	}
+
+	if err := timezone.LocateCalendar(ctx, catalog.New(h.DynamoDB), &cal); err != nil {
+		return calendar.State{}, http.StatusInternalServerError, fmt.Errorf("error finding factory: %s", err.Error())
+	}
+	schedule, err := calendar.Compile(cal)
+	if err != nil {
+		return calendar.State{}, http.StatusInternalServerError, fmt.Errorf("stored calendar is invalid: %s", err.Error())
+	}
+	return schedule.State(instant), http.StatusOK, nil
+}
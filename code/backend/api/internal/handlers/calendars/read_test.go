@@ -0,0 +1,170 @@
+package calendars
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+)
+
+func mockCalendarGetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		"factoryId": &types.AttributeValueMemberS{Value: "1"},
+		"timezone":  &types.AttributeValueMemberS{Value: "UTC"},
+		"shifts": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"name":  &types.AttributeValueMemberS{Value: "Day"},
+				"days":  &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "monday"}}},
+				"start": &types.AttributeValueMemberS{Value: "06:00"},
+				"end":   &types.AttributeValueMemberS{Value: "14:00"},
+			}},
+		}},
+	}}, nil
+}
+
+func TestHandleReadCalendarRequest_MissingFactoryID(t *testing.T) {
+	handler := NewReadCalendarHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleReadCalendarRequest_GetItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewReadCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB get item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleReadCalendarRequest_NotFound(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: nil}, nil
+		},
+	}
+	handler := NewReadCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for missing calendar, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleReadCalendarRequest_InvalidAt(t *testing.T) {
+	handler := NewReadCalendarHandler(&mocks.DynamoDBClient{GetItemFunc: mockCalendarGetItem})
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1", "at": "yesterday"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid at parameter, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleReadCalendarRequest_State(t *testing.T) {
+	handler := NewReadCalendarHandler(&mocks.DynamoDBClient{GetItemFunc: mockCalendarGetItem})
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1", "at": "2024-01-01T08:00:00Z"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"running"`) {
+		t.Errorf("Expected running state during the Monday day shift, got %d %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleReadCalendarRequest_FactoryTimezone(t *testing.T) {
+	// Without a zone of its own the calendar runs in its factory's, Detroit,
+	// where 06:30 UTC is before the day shift.
+	handler := NewReadCalendarHandler(&mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if *params.TableName == "Factory" {
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"factoryId": &types.AttributeValueMemberS{Value: "1"},
+					"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+						"longitude": &types.AttributeValueMemberN{Value: "-83.0458"},
+						"latitude":  &types.AttributeValueMemberN{Value: "42.3314"},
+					}},
+				}}, nil
+			}
+			output, err := mockCalendarGetItem(ctx, params, optFns...)
+			delete(output.Item, "timezone")
+			return output, err
+		},
+	})
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1", "at": "2024-01-01T06:30:00Z"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || strings.Contains(response.Body, `"running"`) {
+		t.Errorf("Expected no shift at 01:30 in Detroit, got %d %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleReadCalendarRequest_Success(t *testing.T) {
+	handler := NewReadCalendarHandler(&mocks.DynamoDBClient{GetItemFunc: mockCalendarGetItem})
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1"},
+	}
+
+	response, err := handler.HandleReadCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for successful read, got %d", http.StatusOK, response.StatusCode)
+	}
+}
@@ -0,0 +1,95 @@
+package calendars
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/calendar"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewUpdateCalendarHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+func (h Handler) HandleUpdateCalendarRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var cal types.Calendar
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &cal); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	if _, err := calendar.Compile(cal); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	key := map[string]ddbtypes.AttributeValue{
+		"factoryId": &ddbtypes.AttributeValueMemberS{Value: cal.FactoryID},
+	}
+
+	var updateBuilder expression.UpdateBuilder
+	if cal.Timezone != nil {
+		updateBuilder = updateBuilder.Set(expression.Name("timezone"), expression.Value(*cal.Timezone))
+	}
+	if cal.Shifts != nil {
+		updateBuilder = updateBuilder.Set(expression.Name("shifts"), expression.Value(cal.Shifts))
+	}
+	if cal.Holidays != nil {
+		updateBuilder = updateBuilder.Set(expression.Name("holidays"), expression.Value(cal.Holidays))
+	}
+	if cal.IdleLevel != nil {
+		updateBuilder = updateBuilder.Set(expression.Name("idleLevel"), expression.Value(*cal.IdleLevel))
+	}
+
+	expr, err := wrappers.UpdateExpressionBuilder(updateBuilder)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Failed to build update expression: %s", err.Error()),
+		}, nil
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key:                       key,
+		TableName:                 aws.String(TABLENAME),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	}
+
+	if _, err = h.DynamoDB.UpdateItem(ctx, input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error updating item into DynamoDB: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       fmt.Sprintf("calendar for factoryId %s updated successfully", cal.FactoryID),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package calendars
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/wrappers"
+)
+
+func TestHandleUpdateCalendarRequest_BadJSON(t *testing.T) {
+	handler := NewUpdateCalendarHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","holidays":"Invalid"}`,
+	}
+
+	response, err := handler.HandleUpdateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected StatusCode %d for bad JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleUpdateCalendarRequest_InvalidShift(t *testing.T) {
+	handler := NewUpdateCalendarHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","shifts":[{"name":"Day","days":["someday"],"start":"06:00","end":"14:00"}]}`,
+	}
+
+	response, err := handler.HandleUpdateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected StatusCode %d for invalid shift, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleUpdateCalendarRequest_UpdateExpressionBuilderError(t *testing.T) {
+	handler := NewUpdateCalendarHandler(&mocks.DynamoDBClient{})
+
+	originalUpdateExpressionBuilder := wrappers.UpdateExpressionBuilder
+
+	defer func() { wrappers.UpdateExpressionBuilder = originalUpdateExpressionBuilder }()
+
+	wrappers.UpdateExpressionBuilder = func(expression.UpdateBuilder) (expression.Expression, error) {
+		return expression.Expression{}, errors.New("update expression error")
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","timezone":"Europe/Berlin"}`,
+	}
+
+	response, err := handler.HandleUpdateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected StatusCode %d for update expression error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleUpdateCalendarRequest_UpdateItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewUpdateCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","timezone":"Europe/Berlin"}`,
+	}
+
+	response, err := handler.HandleUpdateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected StatusCode %d for DynamoDB update item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleUpdateCalendarRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateCalendarHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"1","timezone":"Europe/Berlin","idleLevel":0.1,"holidays":[{"date":"2024-05-01"}]}`,
+	}
+
+	response, err := handler.HandleUpdateCalendarRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected StatusCode %d for successful update, got %d", http.StatusOK, response.StatusCode)
+	}
+}
@@ -0,0 +1,11 @@
+package calendars
+
+import (
+	"wdd/api/internal/types"
+)
+
+const TABLENAME = "Calendar"
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
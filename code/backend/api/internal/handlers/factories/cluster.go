@@ -0,0 +1,121 @@
+package factories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// factoryCluster is one map marker: a single factory, or the count and
+// centroid of several.
+type factoryCluster struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Count     int      `json:"count"`
+	Bounds    geo.BBox `json:"bounds"`
+	// Factory is set when the cluster holds a single factory.
+	Factory *types.Factory `json:"factory,omitempty"`
+}
+
+type clustersResponse struct {
+	Zoom     int              `json:"zoom"`
+	Clusters []factoryCluster `json:"clusters"`
+}
+
+func NewReadFactoryClustersHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadFactoryClustersRequest clusters the factories in a viewport
+// (bbox, the whole world by default) at a map zoom level. Factories up to a
+// cluster radius beyond the viewport are included so that clusters on its
+// edges do not change as the map pans.
+func (h Handler) HandleReadFactoryClustersRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	zoom, viewport, radius, err := parseClustersRequest(request.QueryStringParameters)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid cluster request: %s", err.Error()),
+		}, nil
+	}
+
+	factories, err := catalog.New(h.DynamoDB).FactoriesWithin(ctx, geo.Pad(viewport, zoom, radius))
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error searching factories: %s", err.Error()),
+		}, nil
+	}
+
+	points := make([]geo.Point, len(factories))
+	for i, f := range factories {
+		points[i], _ = geo.LocationPoint(f.Location)
+	}
+
+	response := clustersResponse{Zoom: zoom, Clusters: []factoryCluster{}}
+	for _, c := range geo.Clusters(points, zoom, radius) {
+		if !viewport.Contains(c.Center) {
+			continue
+		}
+		cluster := factoryCluster{Latitude: c.Center.Lat, Longitude: c.Center.Lon, Count: len(c.Members), Bounds: c.Bounds}
+		if len(c.Members) == 1 {
+			cluster.Factory = &factories[c.Members[0]]
+		}
+		response.Clusters = append(response.Clusters, cluster)
+	}
+
+	responseJSON, err := wrappers.JSONMarshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseJSON),
+	}, nil
+}
+
+func parseClustersRequest(params map[string]string) (int, geo.BBox, float64, error) {
+	zoom, err := strconv.Atoi(params["zoom"])
+	if err != nil || zoom < 0 || zoom > geo.MAXZOOM {
+		return 0, geo.BBox{}, 0, fmt.Errorf("zoom must be an integer between 0 and %d", geo.MAXZOOM)
+	}
+
+	viewport := geo.World
+	if params["bbox"] != "" {
+		if viewport, err = geo.ParseBBox(params["bbox"]); err != nil {
+			return 0, geo.BBox{}, 0, err
+		}
+	}
+
+	radius := float64(geo.DEFAULTCLUSTERRADIUS)
+	if params["radius"] != "" {
+		radius, err = strconv.ParseFloat(params["radius"], 64)
+		if err != nil || !(radius >= 1 && radius <= geo.TILESIZE) {
+			return 0, geo.BBox{}, 0, errors.New("radius must be between 1 and 256 pixels")
+		}
+	}
+	return zoom, viewport, radius, nil
+}
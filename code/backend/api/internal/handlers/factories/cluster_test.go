@@ -0,0 +1,81 @@
+package factories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleReadFactoryClustersRequest(t *testing.T) {
+	factory := func(id, lat, lon string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"factoryId": &types.AttributeValueMemberS{Value: id},
+			"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"latitude":  &types.AttributeValueMemberN{Value: lat},
+				"longitude": &types.AttributeValueMemberN{Value: lon},
+			}},
+		}
+	}
+	items := []map[string]types.AttributeValue{
+		factory("paris-1", "48.85", "2.35"),
+		factory("paris-2", "48.86", "2.36"),
+		factory("london", "51.51", "-0.13"),
+		factory("sydney", "-33.87", "151.21"),
+		{"factoryId": &types.AttributeValueMemberS{Value: "nowhere"}},
+	}
+	mockDDBClient := &mocks.DynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: items}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+	}
+	handler := NewReadFactoryClustersHandler(mockDDBClient)
+
+	clusters := func(params map[string]string) (int, clustersResponse) {
+		response, err := handler.HandleReadFactoryClustersRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var body clustersResponse
+		json.Unmarshal([]byte(response.Body), &body)
+		return response.StatusCode, body
+	}
+
+	status, body := clusters(map[string]string{"zoom": "2"})
+	if status != http.StatusOK || body.Zoom != 2 || len(body.Clusters) != 2 {
+		t.Fatalf("Expected a European cluster and Sydney, got %d %+v", status, body)
+	}
+	europe, sydney := body.Clusters[0], body.Clusters[1]
+	if europe.Count != 3 || europe.Factory != nil || europe.Latitude < 48.85 || europe.Latitude > 51.51 {
+		t.Errorf("Expected 3 European factories around their centroid, got %+v", europe)
+	}
+	if sydney.Count != 1 || sydney.Factory == nil || sydney.Factory.FactoryID != "sydney" {
+		t.Errorf("Expected Sydney as a single factory, got %+v", sydney)
+	}
+
+	// Zoomed in on Paris, London is out of the viewport.
+	status, body = clusters(map[string]string{"zoom": "18", "bbox": "2.3,48.8,2.4,48.9", "radius": "40"})
+	if status != http.StatusOK || len(body.Clusters) != 2 || body.Clusters[0].Count != 1 || body.Clusters[1].Count != 1 {
+		t.Errorf("Expected the two Paris factories apart, got %+v", body)
+	}
+
+	for _, params := range []map[string]string{
+		{},
+		{"zoom": "23"},
+		{"zoom": "x"},
+		{"zoom": "3", "bbox": "1,2"},
+		{"zoom": "3", "radius": "0"},
+	} {
+		if status, _ := clusters(params); status != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %v, got %d", http.StatusBadRequest, params, status)
+		}
+	}
+}
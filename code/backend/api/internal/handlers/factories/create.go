@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/geocode"
+	"wdd/api/internal/timezone"
 	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -17,6 +21,7 @@ import (
 func NewCreateFactoryHandler(db types.DynamoDBClient) *Handler {
 	return &Handler{
 		DynamoDB: db,
+		Geocoder: geocode.Default(),
 	}
 }
 
@@ -36,8 +41,35 @@ func (h Handler) HandleCreateFactoryRequest(ctx context.Context, request events.
 		}, nil
 	}
 
+	if err := validation.Location(factory.Location, true); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       validation.Body(err),
+		}, nil
+	}
+
+	if factory.Timezone != nil {
+		if err := timezone.Validate(*factory.Timezone); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error validating factory: %s", err.Error()),
+			}, nil
+		}
+	}
+
 	factory.FactoryID = uuid.NewString()
 	factory.DateCreated = time.Now().Format(time.RFC3339)
+	if _, ok := geo.LocationPoint(factory.Location); !ok {
+		if p, ok := h.geocode(ctx, factory.Address); ok {
+			factory.Location = &types.Location{Latitude: &p.Lat, Longitude: &p.Lon}
+		}
+	} else if factory.Address == nil {
+		factory.Address = h.reverse(ctx, *factory.Location)
+	}
+	geo.IndexFactory(&factory)
+	timezone.Locate(&factory)
 
 	av, err := wrappers.MarshalMap(factory)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"net/http"
+	"strings"
 	"testing"
 	"wdd/api/internal/mocks"
 	"wdd/api/internal/wrappers"
@@ -144,3 +145,101 @@ func TestHandleCreateFactoryRequest_Success(t *testing.T) {
 		t.Errorf("Expected status code %d for successful creation, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleCreateFactoryRequest_Geohash(t *testing.T) {
+	var item map[string]types.AttributeValue
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			item = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"name":"Test Factory","location":{"longitude":10.40744,"latitude":57.64911},"geohash":"bogus"}`,
+	}
+	if _, err := handler.HandleCreateFactoryRequest(context.Background(), request); err != nil {
+		t.Fatalf("Did not expect an error, got %v", err)
+	}
+
+	geohash, _ := item["geohash"].(*types.AttributeValueMemberS)
+	geohashKey, _ := item["geohashKey"].(*types.AttributeValueMemberS)
+	if geohash == nil || geohash.Value != "u4pruydqq" || geohashKey == nil || geohashKey.Value != "u4p" {
+		t.Errorf("Expected the factory to be stored with its geohash, got %v", item)
+	}
+}
+
+func TestHandleCreateFactoryRequest_Geocode(t *testing.T) {
+	var item map[string]types.AttributeValue
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			item = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{Body: `{"name":"Test Factory","address":"Hauptstraße 1, 70173 Stuttgart, Germany"}`}
+	if _, err := handler.HandleCreateFactoryRequest(context.Background(), request); err != nil {
+		t.Fatalf("Did not expect an error, got %v", err)
+	}
+	location, _ := item["location"].(*types.AttributeValueMemberM)
+	if location == nil || location.Value["latitude"].(*types.AttributeValueMemberN).Value != "48.78232" {
+		t.Errorf("Expected the address to be geocoded to Stuttgart, got %v", item)
+	}
+	if geohash, _ := item["geohash"].(*types.AttributeValueMemberS); geohash == nil {
+		t.Errorf("Expected the geocoded location to be indexed, got %v", item)
+	}
+	if zone, _ := item["timezone"].(*types.AttributeValueMemberS); zone == nil || zone.Value != "Europe/Berlin" {
+		t.Errorf("Expected the timezone of the geocoded location, got %v", item)
+	}
+
+	request = events.APIGatewayProxyRequest{Body: `{"name":"Test Factory","location":{"latitude":48.8,"longitude":9.2}}`}
+	if _, err := handler.HandleCreateFactoryRequest(context.Background(), request); err != nil {
+		t.Fatalf("Did not expect an error, got %v", err)
+	}
+	if address, _ := item["address"].(*types.AttributeValueMemberS); address == nil || address.Value != "Stuttgart, Germany" {
+		t.Errorf("Expected the location to be reverse geocoded, got %v", item)
+	}
+
+	// An unknown address is kept without a location.
+	request = events.APIGatewayProxyRequest{Body: `{"name":"Test Factory","address":"Atlantis"}`}
+	if response, _ := handler.HandleCreateFactoryRequest(context.Background(), request); response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for an unknown address, got %d", http.StatusOK, response.StatusCode)
+	}
+	if _, ok := item["location"].(*types.AttributeValueMemberNULL); !ok {
+		t.Errorf("Expected no location for an unknown address, got %v", item["location"])
+	}
+}
+
+func TestHandleCreateFactoryRequest_InvalidTimezone(t *testing.T) {
+	handler := NewCreateFactoryHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{Body: `{"name":"Test Factory","timezone":"Mars/Olympus_Mons"}`}
+	response, err := handler.HandleCreateFactoryRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Did not expect an error, got %v", err)
+	}
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown timezone, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateFactoryRequest_InvalidLocation(t *testing.T) {
+	handler := NewCreateFactoryHandler(&mocks.DynamoDBClient{})
+
+	for _, body := range []string{
+		`{"name":"Test Factory","location":{"latitude":91,"longitude":10}}`,
+		`{"name":"Test Factory","location":{"latitude":45,"longitude":-180.5}}`,
+		`{"name":"Test Factory","location":{"latitude":45}}`,
+	} {
+		response, err := handler.HandleCreateFactoryRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if err != nil {
+			t.Fatalf("Did not expect an error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest || !strings.Contains(response.Body, `"violations":[{"field":"location`) {
+			t.Errorf("Expected a structured %d for %s, got %d %s", http.StatusBadRequest, body, response.StatusCode, response.Body)
+		}
+	}
+}
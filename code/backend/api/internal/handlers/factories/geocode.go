@@ -0,0 +1,36 @@
+package factories
+
+import (
+	"context"
+	"strings"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+)
+
+// geocode looks up the location of an address. Geocoding is best effort: an
+// address the gazetteer does not know leaves the location to the client.
+func (h Handler) geocode(ctx context.Context, address *string) (geo.Point, bool) {
+	if h.Geocoder == nil || address == nil || strings.TrimSpace(*address) == "" {
+		return geo.Point{}, false
+	}
+	place, err := h.Geocoder.Geocode(ctx, *address)
+	if err != nil {
+		return geo.Point{}, false
+	}
+	return place.Point, true
+}
+
+// reverse names the place nearest a location, or returns nil when there is
+// none.
+func (h Handler) reverse(ctx context.Context, location types.Location) *string {
+	p, ok := geo.LocationPoint(&location)
+	if h.Geocoder == nil || !ok {
+		return nil
+	}
+	place, err := h.Geocoder.Reverse(ctx, p)
+	if err != nil {
+		return nil
+	}
+	address := place.Address()
+	return &address
+}
@@ -2,8 +2,16 @@ package factories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/timezone"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -27,6 +35,20 @@ func (h Handler) HandleReadFactoryRequest(ctx context.Context, request events.AP
 		"Content-Type":                "application/json",
 	}
 
+	params := request.QueryStringParameters
+	if zone := params["tz"]; zone != "" && zone != timezone.FACTORY {
+		if err := timezone.Validate(zone); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       err.Error(),
+			}, nil
+		}
+	}
+	if params["near"] != "" || params["bbox"] != "" {
+		return h.handleSpatialSearch(ctx, params, headers)
+	}
+
 	if factoryID == "" {
 		input := &dynamodb.ScanInput{
 			TableName: aws.String(TABLENAME),
@@ -49,7 +71,7 @@ func (h Handler) HandleReadFactoryRequest(ctx context.Context, request events.AP
 			}, nil
 		}
 
-		factoriesJSON, err := wrappers.JSONMarshal(factories)
+		factoriesJSON, err := renderFactories(factories, func(f *types.Factory) *types.Factory { return f }, params["tz"])
 		if err != nil {
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusInternalServerError,
@@ -100,7 +122,21 @@ func (h Handler) HandleReadFactoryRequest(ctx context.Context, request events.AP
 		}, nil
 	}
 
+	// Factories created before timezones are given the zone of their location.
+	timezone.Locate(&factory)
+	location, err := timezone.ForFactory(params["tz"], factory)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
 	factoryJSON, err := wrappers.JSONMarshal(factory)
+	if err == nil {
+		factoryJSON, err = timezone.Render(factoryJSON, location)
+	}
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -115,3 +151,116 @@ func (h Handler) HandleReadFactoryRequest(ctx context.Context, request events.AP
 		Body:       string(factoryJSON),
 	}, nil
 }
+
+// MAXRADIUSKM is half the Earth's circumference; any larger radius covers
+// the whole planet anyway.
+const MAXRADIUSKM = 20038
+
+// nearbyFactory is a factory found by a spatial search with its distance
+// from the search point, or from the center of the searched box.
+type nearbyFactory struct {
+	types.Factory
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// handleSpatialSearch serves near=lat,lon&radiusKm= and
+// bbox=minLon,minLat,maxLon,maxLat, nearest first.
+func (h Handler) handleSpatialSearch(ctx context.Context, params map[string]string, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	origin, box, radiusKm, err := parseSpatialSearch(params)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid spatial search: %s", err.Error()),
+		}, nil
+	}
+
+	factories, err := catalog.New(h.DynamoDB).FactoriesWithin(ctx, box)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error searching factories: %s", err.Error()),
+		}, nil
+	}
+
+	results := []nearbyFactory{}
+	for _, f := range factories {
+		p, _ := geo.LocationPoint(f.Location)
+		distance := geo.DistanceKm(origin, p)
+		if radiusKm > 0 && distance > radiusKm {
+			continue
+		}
+		results = append(results, nearbyFactory{Factory: f, DistanceKm: distance})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DistanceKm < results[j].DistanceKm
+	})
+
+	resultsJSON, err := renderFactories(results, func(r *nearbyFactory) *types.Factory { return &r.Factory }, params["tz"])
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(resultsJSON),
+	}, nil
+}
+
+// parseSpatialSearch returns the point distances are measured from, the box
+// to search and, for near searches, the radius.
+func parseSpatialSearch(params map[string]string) (geo.Point, geo.BBox, float64, error) {
+	if params["near"] != "" && params["bbox"] != "" {
+		return geo.Point{}, geo.BBox{}, 0, errors.New("near and bbox cannot be combined")
+	}
+
+	if params["bbox"] != "" {
+		box, err := geo.ParseBBox(params["bbox"])
+		return box.Center(), box, 0, err
+	}
+
+	origin, err := geo.ParsePoint(params["near"])
+	if err != nil {
+		return geo.Point{}, geo.BBox{}, 0, err
+	}
+	radiusKm, err := strconv.ParseFloat(params["radiusKm"], 64)
+	if err != nil || !(radiusKm > 0 && radiusKm <= MAXRADIUSKM) {
+		return geo.Point{}, geo.BBox{}, 0, fmt.Errorf("radiusKm must be a number above 0 and at most %d", MAXRADIUSKM)
+	}
+	return origin, geo.Around(origin, radiusKm), radiusKm, nil
+}
+
+// renderFactories marshals a list of factories for the zone tz asks for,
+// already validated. With timezone.FACTORY each factory is rendered in its
+// own zone, and those without a location are left in UTC. Factories from
+// before timezones are given the zone of their location, as when read
+// alone.
+func renderFactories[T any](items []T, factory func(*T) *types.Factory, zone string) ([]byte, error) {
+	rendered := make([]json.RawMessage, len(items))
+	for i := range items {
+		f := factory(&items[i])
+		timezone.Locate(f)
+		var location *time.Location
+		if zone != timezone.FACTORY || f.Timezone != nil {
+			var err error
+			if location, err = timezone.ForFactory(zone, *f); err != nil {
+				return nil, err
+			}
+		}
+		body, err := wrappers.JSONMarshal(items[i])
+		if err == nil {
+			body, err = timezone.Render(body, location)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rendered[i] = body
+	}
+	return wrappers.JSONMarshal(rendered)
+}
@@ -2,6 +2,7 @@ package factories
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -133,6 +134,50 @@ func TestHandleReadFactoryRequest_WithoutId_Success(t *testing.T) {
 	}
 }
 
+func TestHandleReadFactoryRequest_WithoutId_Timezone(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			items := []map[string]types.AttributeValue{
+				{
+					"factoryId":    &types.AttributeValueMemberS{Value: "detroit"},
+					"Date Created": &types.AttributeValueMemberS{Value: "2024-01-01T12:00:00Z"},
+					"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+						"longitude": &types.AttributeValueMemberN{Value: "-83.0458"},
+						"latitude":  &types.AttributeValueMemberN{Value: "42.3314"},
+					}},
+				},
+				{
+					"factoryId":    &types.AttributeValueMemberS{Value: "nowhere"},
+					"Date Created": &types.AttributeValueMemberS{Value: "2024-01-01T12:00:00Z"},
+				},
+			}
+			return &dynamodb.ScanOutput{Items: items}, nil
+		},
+	}
+	handler := NewReadFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tz": "factory"}}
+	response, err := handler.HandleReadFactoryRequest(context.Background(), request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful read, got %d %v %s", response.StatusCode, err, response.Body)
+	}
+	var factories []struct {
+		DateCreated string `json:"dateCreated"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &factories); err != nil || len(factories) != 2 {
+		t.Fatalf("Expected 2 factories, got %s", response.Body)
+	}
+	// Each factory is in its own zone, and the one without a location in UTC.
+	if factories[0].DateCreated != "2024-01-01T07:00:00-05:00" || factories[1].DateCreated != "2024-01-01T12:00:00Z" {
+		t.Errorf("Expected each factory's local time, got %+v", factories)
+	}
+
+	request.QueryStringParameters["tz"] = "Mars/Olympus"
+	if response, _ := handler.HandleReadFactoryRequest(context.Background(), request); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown zone, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
 func TestHandleReadFactoryRequest_WithId_GetItemError(t *testing.T) {
 	mockDDBClient := &mocks.DynamoDBClient{
 		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
@@ -282,3 +327,80 @@ func TestHandleReadFactoryRequest_WithId_Success(t *testing.T) {
 		t.Errorf("Expected status code %d for successful read with id, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleReadFactoryRequest_SpatialSearch(t *testing.T) {
+	factory := func(id, lat, lon, geohash string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"factoryId": &types.AttributeValueMemberS{Value: id},
+			"geohash":   &types.AttributeValueMemberS{Value: geohash},
+			"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"latitude":  &types.AttributeValueMemberN{Value: lat},
+				"longitude": &types.AttributeValueMemberN{Value: lon},
+			}},
+		}
+	}
+	var queries int
+	mockDDBClient := &mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			queries++
+			if *params.IndexName != "geohash" {
+				t.Errorf("Expected a geohash index query, got %s", *params.IndexName)
+			}
+			// Every cell returns the same candidates; only those in range are kept.
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+				factory("far", "57.7", "10.6", "u4pv"),
+				factory("near", "57.649", "10.407", "u4pr"),
+				factory("out", "40", "10", "sp0"),
+			}}, nil
+		},
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{factory("near", "57.649", "10.407", "u4pr")}}, nil
+		},
+	}
+	handler := NewReadFactoryHandler(mockDDBClient)
+
+	search := func(params map[string]string) (int, []nearbyFactory) {
+		response, err := handler.HandleReadFactoryRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var results []nearbyFactory
+		json.Unmarshal([]byte(response.Body), &results)
+		return response.StatusCode, results
+	}
+
+	status, results := search(map[string]string{"near": "57.64911,10.40744", "radiusKm": "20"})
+	if status != http.StatusOK || len(results) != 2 || results[0].FactoryID != "near" || results[1].FactoryID != "far" {
+		t.Fatalf("Expected near then far, got %d %+v", status, results)
+	}
+	if results[0].DistanceKm > 0.1 || results[1].DistanceKm < 10 {
+		t.Errorf("Expected distances from the search point, got %+v", results)
+	}
+	if queries == 0 {
+		t.Errorf("Expected the geohash index to be queried")
+	}
+
+	if status, results = search(map[string]string{"near": "57.64911,10.40744", "radiusKm": "1"}); status != http.StatusOK || len(results) != 1 {
+		t.Errorf("Expected only the near factory within 1km, got %+v", results)
+	}
+	if status, results = search(map[string]string{"bbox": "10.3,57.6,10.7,57.8"}); status != http.StatusOK || len(results) != 2 || results[0].FactoryID != "far" {
+		t.Errorf("Expected the factories in the box nearest its center first, got %+v", results)
+	}
+	// A continent-sized box is scanned rather than queried cell by cell.
+	if status, results = search(map[string]string{"bbox": "-30,30,60,70"}); status != http.StatusOK || len(results) != 1 {
+		t.Errorf("Expected the scanned factory, got %+v", results)
+	}
+
+	for _, params := range []map[string]string{
+		{"near": "57,10"},
+		{"near": "57,10", "radiusKm": "0"},
+		{"near": "57,10", "radiusKm": "30000"},
+		{"near": "100,10", "radiusKm": "5"},
+		{"bbox": "1,2,3"},
+		{"near": "57,10", "radiusKm": "5", "bbox": "10,57,11,58"},
+	} {
+		if status, _ := search(params); status != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %v, got %d", http.StatusBadRequest, params, status)
+		}
+	}
+}
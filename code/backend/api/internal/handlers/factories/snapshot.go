@@ -0,0 +1,82 @@
+package factories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/snapshot"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadFactorySnapshotHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadFactorySnapshotRequest serves GET /factories/{id}/snapshot: the
+// factory with every asset and its model, properties, measurements and
+// latest readings.
+func (h Handler) HandleReadFactorySnapshotRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	factoryID := request.PathParameters["id"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if factoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factory ID",
+		}, nil
+	}
+
+	factory, err := snapshot.New(h.DynamoDB).Factory(ctx, factoryID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading factory snapshot: %s", err.Error()),
+		}, nil
+	}
+
+	timezone.Locate(&factory.Factory)
+	location, err := timezone.ForFactory(request.QueryStringParameters["tz"], factory.Factory)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	factoryJSON, err := wrappers.JSONMarshal(factory)
+	if err == nil {
+		factoryJSON, err = timezone.Render(factoryJSON, location)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(factoryJSON),
+	}, nil
+}
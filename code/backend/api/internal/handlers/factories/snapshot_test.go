@@ -0,0 +1,53 @@
+package factories
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleReadFactorySnapshotRequest(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch params.Key["factoryId"].(*types.AttributeValueMemberS).Value {
+			case "f1", "broken":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"factoryId": params.Key["factoryId"],
+				}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if params.ExpressionAttributeValues[":factoryId"].(*types.AttributeValueMemberS).Value == "broken" {
+				return nil, errors.New("mock dynamodb error")
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+				"assetId":   &types.AttributeValueMemberS{Value: "a1"},
+				"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+			}}}, nil
+		},
+	}
+	handler := NewReadFactorySnapshotHandler(mockDDBClient)
+
+	cases := map[string]int{"": http.StatusBadRequest, "missing": http.StatusNotFound, "broken": http.StatusInternalServerError, "f1": http.StatusOK}
+	for id, status := range cases {
+		request := events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": id}}
+		response, err := handler.HandleReadFactorySnapshotRequest(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %q, got %d", status, id, response.StatusCode)
+		}
+		if status == http.StatusOK && !strings.Contains(response.Body, `"assets":[{"assetId":"a1"`) {
+			t.Errorf("Expected the factory's assets, got %s", response.Body)
+		}
+	}
+}
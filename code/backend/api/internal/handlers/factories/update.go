@@ -2,9 +2,15 @@ package factories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/geocode"
+	"wdd/api/internal/timezone"
 	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -17,6 +23,7 @@ import (
 func NewUpdateFactoryHandler(db types.DynamoDBClient) *Handler {
 	return &Handler{
 		DynamoDB: db,
+		Geocoder: geocode.Default(),
 	}
 }
 
@@ -35,6 +42,14 @@ func (h Handler) HandleUpdateFactoryRequest(ctx context.Context, request events.
 		}, nil
 	}
 
+	if err := validation.Location(factory.Location, false); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       validation.Body(err),
+		}, nil
+	}
+
 	key := map[string]ddbtypes.AttributeValue{
 		"factoryId": &ddbtypes.AttributeValueMemberS{Value: factory.FactoryID},
 	}
@@ -46,12 +61,80 @@ func (h Handler) HandleUpdateFactoryRequest(ctx context.Context, request events.
 	if factory.Description != nil {
 		updateBuilder = updateBuilder.Set(expression.Name("description"), expression.Value(*factory.Description))
 	}
+	if factory.Timezone != nil {
+		if err := timezone.Validate(*factory.Timezone); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error validating factory: %s", err.Error()),
+			}, nil
+		}
+		updateBuilder = updateBuilder.Set(expression.Name("timezone"), expression.Value(*factory.Timezone))
+	}
+	if factory.Address != nil {
+		updateBuilder = updateBuilder.Set(expression.Name("address"), expression.Value(*factory.Address))
+		if factory.Location == nil {
+			if p, ok := h.geocode(ctx, factory.Address); ok {
+				factory.Location = &types.Location{Latitude: &p.Lat, Longitude: &p.Lon}
+			} else {
+				moved, err := h.addressChanged(ctx, factory.FactoryID, *factory.Address)
+				if err != nil {
+					status := http.StatusInternalServerError
+					if errors.Is(err, catalog.ErrNotFound) {
+						status = http.StatusNotFound
+					}
+					return events.APIGatewayProxyResponse{
+						StatusCode: status,
+						Headers:    headers,
+						Body:       fmt.Sprintf("Error reading factory address: %s", err.Error()),
+					}, nil
+				}
+				if moved {
+					// The old location is not that of an address which could not
+					// be geocoded, so it goes until the client sets a new one.
+					updateBuilder = updateBuilder.Remove(expression.Name("location"))
+					updateBuilder = updateBuilder.Remove(expression.Name("geohash"))
+					updateBuilder = updateBuilder.Remove(expression.Name("geohashKey"))
+				}
+			}
+		}
+	}
 	if factory.Location != nil {
-		if factory.Location.Longitude != nil {
-			updateBuilder = updateBuilder.Set(expression.Name("location.longitude"), expression.Value(*factory.Location.Longitude))
+		location, err := h.mergeLocation(ctx, factory.FactoryID, *factory.Location)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, catalog.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: status,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error reading factory location: %s", err.Error()),
+			}, nil
 		}
-		if factory.Location.Latitude != nil {
-			updateBuilder = updateBuilder.Set(expression.Name("location.latitude"), expression.Value(*factory.Location.Latitude))
+		// The whole location is set, as the stored one may be missing or null.
+		updateBuilder = updateBuilder.Set(expression.Name("location"), expression.Value(location))
+		if factory.Address == nil {
+			// A new location only names the factory's address when it has none.
+			if address := h.reverse(ctx, location); address != nil {
+				updateBuilder = updateBuilder.Set(expression.Name("address"), expression.IfNotExists(expression.Name("address"), expression.Value(*address)))
+			}
+		}
+		indexed := types.Factory{Location: &location}
+		geo.IndexFactory(&indexed)
+		if factory.Timezone == nil {
+			// A factory that moves is in the zone of its new location.
+			timezone.Locate(&indexed)
+			if indexed.Timezone != nil {
+				updateBuilder = updateBuilder.Set(expression.Name("timezone"), expression.Value(*indexed.Timezone))
+			}
+		}
+		if indexed.Geohash != "" {
+			updateBuilder = updateBuilder.Set(expression.Name("geohash"), expression.Value(indexed.Geohash))
+			updateBuilder = updateBuilder.Set(expression.Name("geohashKey"), expression.Value(indexed.GeohashKey))
+		} else {
+			updateBuilder = updateBuilder.Remove(expression.Name("geohash"))
+			updateBuilder = updateBuilder.Remove(expression.Name("geohashKey"))
 		}
 	}
 
@@ -86,3 +169,35 @@ func (h Handler) HandleUpdateFactoryRequest(ctx context.Context, request events.
 		Body:       fmt.Sprintf("factoryId %s updated successfully", factory.FactoryID),
 	}, nil
 }
+
+// mergeLocation completes a partial location update with the coordinate
+// already stored, so the geohash is derived from the whole new location.
+func (h Handler) mergeLocation(ctx context.Context, factoryID string, update types.Location) (types.Location, error) {
+	if update.Latitude != nil && update.Longitude != nil {
+		return update, nil
+	}
+
+	factory, err := catalog.New(h.DynamoDB).Factory(ctx, factoryID)
+	if err != nil {
+		return update, err
+	}
+	if factory.Location != nil {
+		if update.Latitude == nil {
+			update.Latitude = factory.Location.Latitude
+		}
+		if update.Longitude == nil {
+			update.Longitude = factory.Location.Longitude
+		}
+	}
+	return update, nil
+}
+
+// addressChanged reports whether an address differs from the one stored for
+// a factory.
+func (h Handler) addressChanged(ctx context.Context, factoryID string, address string) (bool, error) {
+	factory, err := catalog.New(h.DynamoDB).Factory(ctx, factoryID)
+	if err != nil {
+		return false, err
+	}
+	return factory.Address == nil || *factory.Address != address, nil
+}
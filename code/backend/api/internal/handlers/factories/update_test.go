@@ -3,11 +3,16 @@ package factories
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"net/http"
+	"strings"
 	"testing"
+	"wdd/api/internal/geo"
 	"wdd/api/internal/mocks"
 	"wdd/api/internal/wrappers"
 )
@@ -111,3 +116,185 @@ func TestHandleUpdateFactoryRequest_Success(t *testing.T) {
 		t.Errorf("Expected StatusCode %d for successful update, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleUpdateFactoryRequest_PartialLocation(t *testing.T) {
+	var update *dynamodb.UpdateItemInput
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if params.Key["factoryId"].(*types.AttributeValueMemberS).Value != "1" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "1"},
+				"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"latitude":  &types.AttributeValueMemberN{Value: "57.64911"},
+					"longitude": &types.AttributeValueMemberN{Value: "0"},
+				}},
+			}}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			update = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "location":{"longitude":10.40744}}`}
+	response, err := handler.HandleUpdateFactoryRequest(context.Background(), request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+
+	found := false
+	for _, value := range update.ExpressionAttributeValues {
+		if s, ok := value.(*types.AttributeValueMemberS); ok && s.Value == "u4pruydqq" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the geohash of the merged location to be set, got %v", update.ExpressionAttributeValues)
+	}
+
+	request = events.APIGatewayProxyRequest{Body: `{"factoryId": "missing", "location":{"longitude":10}}`}
+	if response, _ = handler.HandleUpdateFactoryRequest(context.Background(), request); response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a missing factory, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+// expressionValues collects the scalar values of an update, including those
+// inside maps such as the location.
+func expressionValues(update *dynamodb.UpdateItemInput) map[string]bool {
+	values := map[string]bool{}
+	var collect func(types.AttributeValue)
+	collect = func(value types.AttributeValue) {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			values[v.Value] = true
+		case *types.AttributeValueMemberN:
+			values[v.Value] = true
+		case *types.AttributeValueMemberM:
+			for _, item := range v.Value {
+				collect(item)
+			}
+		}
+	}
+	for _, value := range update.ExpressionAttributeValues {
+		collect(value)
+	}
+	return values
+}
+
+func TestHandleUpdateFactoryRequest_Geocode(t *testing.T) {
+	var update *dynamodb.UpdateItemInput
+	mockDDBClient := &mocks.DynamoDBClient{
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			update = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "address":"Leopoldstraße 10, 80802 München"}`}
+	if response, err := handler.HandleUpdateFactoryRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+	values := expressionValues(update)
+	geohash, _ := geo.Index(geo.Point{Lat: 48.13743, Lon: 11.57549})
+	if !values["48.13743"] || !values["11.57549"] || !values[geohash] {
+		t.Errorf("Expected the address to be geocoded to Munich, got %v", update.ExpressionAttributeValues)
+	}
+
+	request = events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "location":{"latitude":48.8,"longitude":9.2}}`}
+	if response, err := handler.HandleUpdateFactoryRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+	if !strings.Contains(aws.ToString(update.UpdateExpression), "if_not_exists") {
+		t.Errorf("Expected the address to be set only when missing, got %s", aws.ToString(update.UpdateExpression))
+	}
+}
+
+func TestHandleUpdateFactoryRequest_InvalidLocation(t *testing.T) {
+	handler := NewUpdateFactoryHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "location":{"longitude":200}}`}
+	response, err := handler.HandleUpdateFactoryRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusBadRequest || !strings.Contains(response.Body, `"field":"location.longitude","value":200`) {
+		t.Errorf("Expected a structured %d, got %d %s", http.StatusBadRequest, response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleUpdateFactoryRequest_WholeLocation(t *testing.T) {
+	var update *dynamodb.UpdateItemInput
+	mockDDBClient := &mocks.DynamoDBClient{
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			update = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateFactoryHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "location":{"latitude":48.8,"longitude":9.2}}`}
+	if response, err := handler.HandleUpdateFactoryRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+	if updateExpression := aws.ToString(update.UpdateExpression); strings.Contains(updateExpression, ".") {
+		t.Errorf("Expected no nested location paths, which fail on a null location, got %s", updateExpression)
+	}
+	whole := false
+	for _, value := range update.ExpressionAttributeValues {
+		if location, ok := value.(*types.AttributeValueMemberM); ok && len(location.Value) == 2 {
+			whole = true
+		}
+	}
+	if !whole {
+		t.Errorf("Expected the whole location to be set, got %v", update.ExpressionAttributeValues)
+	}
+	if values := expressionValues(update); !values["48.8"] || !values["9.2"] {
+		t.Errorf("Expected the location to be set, got %v", update.ExpressionAttributeValues)
+	}
+}
+
+func TestHandleUpdateFactoryRequest_UnknownAddress(t *testing.T) {
+	var update *dynamodb.UpdateItemInput
+	stored := "Leopoldstraße 10, 80802 München"
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "1"},
+				"address":   &types.AttributeValueMemberS{Value: stored},
+			}}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			update = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateFactoryHandler(mockDDBClient)
+	handler.Geocoder = nil
+
+	request := events.APIGatewayProxyRequest{Body: `{"factoryId": "1", "address":"1 Nowhere Lane, Atlantis"}`}
+	if response, err := handler.HandleUpdateFactoryRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+	updateExpression := aws.ToString(update.UpdateExpression)
+	removed := map[string]bool{}
+	if _, list, ok := strings.Cut(updateExpression, "REMOVE "); ok {
+		for _, placeholder := range strings.Split(list, ",") {
+			removed[update.ExpressionAttributeNames[strings.Fields(placeholder)[0]]] = true
+		}
+	}
+	if !removed["location"] || !removed["geohash"] || !removed["geohashKey"] {
+		t.Errorf("Expected the old location to be cleared for an address that does not geocode, got %s", updateExpression)
+	}
+
+	request = events.APIGatewayProxyRequest{Body: fmt.Sprintf(`{"factoryId": "1", "address":%q}`, stored)}
+	if response, err := handler.HandleUpdateFactoryRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v", response.StatusCode, err)
+	}
+	if updateExpression := aws.ToString(update.UpdateExpression); strings.Contains(updateExpression, "REMOVE") {
+		t.Errorf("Expected the location to be kept when the address is unchanged, got %s", updateExpression)
+	}
+}
@@ -1,6 +1,7 @@
 package factories
 
 import (
+	"wdd/api/internal/geocode"
 	"wdd/api/internal/types"
 )
 
@@ -8,4 +9,7 @@ const TABLENAME = "Factory"
 
 type Handler struct {
 	DynamoDB types.DynamoDBClient
+	// Geocoder fills in a missing address or location on create and update;
+	// without one neither is filled in.
+	Geocoder geocode.Geocoder
 }
@@ -0,0 +1,264 @@
+package floorplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/spatial"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+	"wdd/api/internal/zone"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	METERS = "meters"
+	PIXELS = "pixels"
+)
+
+// neighbour is the asset closest to another on the same floorplan.
+type neighbour struct {
+	AssetID  string  `json:"assetId"`
+	Distance float64 `json:"distance"`
+}
+
+// floorplanAsset is an asset found by a floorplan search. Distance is from
+// the search point, or from the center of the searched box.
+type floorplanAsset struct {
+	types.Asset
+	Distance *float64   `json:"distance,omitempty"`
+	Nearest  *neighbour `json:"nearest,omitempty"`
+}
+
+// assetSearch gives every distance and position of a search in Units.
+type assetSearch struct {
+	FloorplanID string           `json:"floorplanId"`
+	Units       string           `json:"units"`
+	Assets      []floorplanAsset `json:"assets"`
+}
+
+func NewReadFloorPlanAssetsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadFloorPlanAssetsRequest serves GET /floorplan/assets?id=, the
+// positioned assets of a floorplan with their nearest neighbours, narrowed
+// to near=x,y&radius= or bbox=minX,minY,maxX,maxY. Searches are in meters
+// on calibrated floorplans and in pixels otherwise, unless units says
+// which.
+func (h Handler) HandleReadFloorPlanAssetsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if params["id"] == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing id query parameter",
+		}, nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	floorplan, err := c.Floorplan(ctx, params["id"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding floorplan: %s", err.Error()),
+		}, nil
+	}
+	var transform *calibration.Transform
+	if floorplan.Calibration != nil {
+		if transform, err = calibration.Compile(*floorplan.Calibration); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Stored calibration is invalid: %s", err.Error()),
+			}, nil
+		}
+	}
+
+	search := assetSearch{FloorplanID: floorplan.FloorplanID, Units: params["units"]}
+	switch {
+	case search.Units == "" && transform != nil:
+		search.Units = METERS
+	case search.Units == "":
+		search.Units = PIXELS
+	case search.Units == METERS && transform == nil:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Floorplan %s is not calibrated", floorplan.FloorplanID),
+		}, nil
+	case search.Units != METERS && search.Units != PIXELS:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid units %q, expected %s or %s", search.Units, METERS, PIXELS),
+		}, nil
+	}
+
+	query, err := parseAssetQuery(params)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid floorplan search: %s", err.Error()),
+		}, nil
+	}
+
+	candidates, err := c.FloorplanAssets(ctx, floorplan.FloorplanID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error querying assets: %s", err.Error()),
+		}, nil
+	}
+
+	// Indexed in id order, equally near neighbours resolve to the lowest id.
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].AssetID < candidates[j].AssetID })
+	var assets []types.Asset
+	var positions []types.Position
+	for _, asset := range candidates {
+		pixel, ok := calibration.Pixel(asset.FloorplanCoords)
+		if !ok {
+			continue
+		}
+		position := pixel
+		if transform != nil {
+			meters := transform.ToMeters(pixel)
+			asset.PositionMeters = &meters
+			if search.Units == METERS {
+				position = meters
+			}
+		}
+		assets = append(assets, asset)
+		positions = append(positions, position)
+	}
+	if err = zone.Locate(ctx, c, assets); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading zones: %s", err.Error()),
+		}, nil
+	}
+
+	search.Assets = query.run(assets, positions)
+
+	responseBody, err := wrappers.JSONMarshal(search)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+// assetQuery is a search around a point, within a box, or of the whole
+// floorplan when neither is set.
+type assetQuery struct {
+	center   *types.Position
+	radius   float64
+	min, max *types.Position
+}
+
+// run indexes the assets at their positions and returns those matching the
+// query, nearest the point or box center first, or ordered by id for whole
+// floorplans.
+func (q assetQuery) run(assets []types.Asset, positions []types.Position) []floorplanAsset {
+	ix := spatial.New(positions)
+	var found []int
+	var origin *types.Position
+	switch {
+	case q.center != nil:
+		found, origin = ix.Within(*q.center, q.radius), q.center
+	case q.min != nil:
+		found = ix.InRect(*q.min, *q.max)
+		origin = &types.Position{X: (q.min.X + q.max.X) / 2, Y: (q.min.Y + q.max.Y) / 2}
+	default:
+		for i := range positions {
+			found = append(found, i)
+		}
+	}
+
+	results := make([]floorplanAsset, 0, len(found))
+	for _, i := range found {
+		result := floorplanAsset{Asset: assets[i]}
+		if origin != nil {
+			distance := spatial.Distance(positions[i], *origin)
+			result.Distance = &distance
+		}
+		if j, distance, ok := ix.Nearest(positions[i], i); ok {
+			result.Nearest = &neighbour{AssetID: assets[j].AssetID, Distance: distance}
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if origin != nil && *results[i].Distance != *results[j].Distance {
+			return *results[i].Distance < *results[j].Distance
+		}
+		return results[i].AssetID < results[j].AssetID
+	})
+	return results
+}
+
+func parseAssetQuery(params map[string]string) (assetQuery, error) {
+	var q assetQuery
+	switch {
+	case params["near"] != "" && params["bbox"] != "":
+		return q, errors.New("near and bbox cannot be combined")
+	case params["near"] != "":
+		center, err := parsePosition("near", params["near"])
+		if err != nil {
+			return q, err
+		}
+		radius, err := strconv.ParseFloat(params["radius"], 64)
+		if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+			return q, errors.New("radius must be a number above 0")
+		}
+		q.center, q.radius = &center, radius
+	case params["bbox"] != "":
+		parts := strings.Split(params["bbox"], ",")
+		var values []float64
+		for _, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+				break
+			}
+			values = append(values, v)
+		}
+		if len(parts) != 4 || len(values) != 4 {
+			return q, fmt.Errorf("invalid bbox parameter %q, expected minX,minY,maxX,maxY", params["bbox"])
+		}
+		if values[0] > values[2] || values[1] > values[3] {
+			return q, errors.New("bbox minimums must not be above its maximums")
+		}
+		q.min, q.max = &types.Position{X: values[0], Y: values[1]}, &types.Position{X: values[2], Y: values[3]}
+	}
+	return q, nil
+}
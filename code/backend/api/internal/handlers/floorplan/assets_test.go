@@ -0,0 +1,129 @@
+package floorplan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockQueryAssets places a1 at pixel (0,0), a2 at (100,0) and a3 at
+// (100,100) on every floorplan, and a4 nowhere on it.
+func mockQueryAssets(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if aws.ToString(params.TableName) != "Asset" {
+		return &dynamodb.QueryOutput{}, nil
+	}
+	floorplanID := params.ExpressionAttributeValues[":key"]
+	asset := func(id string, coords ...string) map[string]types.AttributeValue {
+		item := map[string]types.AttributeValue{"assetId": &types.AttributeValueMemberS{Value: id}, "floorplanId": floorplanID}
+		if len(coords) == 2 {
+			item["floorplanCoords"] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"longitude": &types.AttributeValueMemberN{Value: coords[0]},
+				"latitude":  &types.AttributeValueMemberN{Value: coords[1]},
+			}}
+		}
+		return item
+	}
+	return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+		asset("a3", "100", "100"), asset("a1", "0", "0"), asset("a4"), asset("a2", "100", "0"),
+	}}, nil
+}
+
+type searchResult struct {
+	Units  string `json:"units"`
+	Assets []struct {
+		AssetID        string `json:"assetId"`
+		PositionMeters *struct {
+			X, Y float64
+		} `json:"positionMeters"`
+		Distance *float64  `json:"distance"`
+		Nearest  neighbour `json:"nearest"`
+	} `json:"assets"`
+}
+
+func search(t *testing.T, params map[string]string) searchResult {
+	handler := NewReadFloorPlanAssetsHandler(&mocks.DynamoDBClient{GetItemFunc: mockGetCalibrated, QueryFunc: mockQueryAssets})
+	response, err := handler.HandleReadFloorPlanAssetsRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var result searchResult
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a search result for %v, got %d %s", params, response.StatusCode, response.Body)
+	}
+	return result
+}
+
+func ids(result searchResult) []string {
+	ids := []string{}
+	for _, asset := range result.Assets {
+		ids = append(ids, asset.AssetID)
+	}
+	return ids
+}
+
+func TestHandleReadFloorPlanAssetsRequest(t *testing.T) {
+	// At 0.05 m/px the assets are 5m apart.
+	all := search(t, map[string]string{"id": "f1"})
+	if all.Units != METERS || len(all.Assets) != 3 {
+		t.Fatalf("Expected the 3 positioned assets in meters, got %+v", all)
+	}
+	for i, expected := range []neighbour{{"a2", 5}, {"a1", 5}, {"a2", 5}} {
+		asset := all.Assets[i]
+		if asset.Nearest != expected || asset.PositionMeters == nil || asset.Distance != nil {
+			t.Errorf("Expected %s nearest to %+v, got %+v", asset.AssetID, expected, asset)
+		}
+	}
+
+	near := search(t, map[string]string{"id": "f1", "near": "0,0", "radius": "6"})
+	if got := ids(near); len(got) != 2 || got[0] != "a1" || got[1] != "a2" || *near.Assets[1].Distance != 5 {
+		t.Errorf("Expected a1 and a2 within 6m, nearest first, got %v", got)
+	}
+
+	// Meters have y growing up, so a3 is 5m below the x axis.
+	box := search(t, map[string]string{"id": "f1", "bbox": "4,-6,6,1"})
+	if got := ids(box); len(got) != 2 || got[0] != "a2" || got[1] != "a3" {
+		t.Errorf("Expected a2 and a3 in the box, got %v", got)
+	}
+
+	pixels := search(t, map[string]string{"id": "f1", "units": "pixels", "near": "100,100", "radius": "1"})
+	if got := ids(pixels); pixels.Units != PIXELS || len(got) != 1 || got[0] != "a3" || pixels.Assets[0].Nearest.Distance != 100 {
+		t.Errorf("Expected a3 in pixels, got %+v", pixels)
+	}
+
+	if uncalibrated := search(t, map[string]string{"id": "f2"}); uncalibrated.Units != PIXELS || uncalibrated.Assets[0].PositionMeters != nil {
+		t.Errorf("Expected an uncalibrated floorplan searched in pixels, got %+v", uncalibrated)
+	}
+}
+
+func TestHandleReadFloorPlanAssetsRequest_Invalid(t *testing.T) {
+	handler := NewReadFloorPlanAssetsHandler(&mocks.DynamoDBClient{GetItemFunc: mockGetCalibrated, QueryFunc: mockQueryAssets})
+
+	cases := map[string]map[string]string{
+		"missing id":                 {},
+		"unknown floorplan":          {"id": "gone"},
+		"meters without calibration": {"id": "f2", "units": "meters"},
+		"unknown units":              {"id": "f1", "units": "feet"},
+		"missing radius":             {"id": "f1", "near": "0,0"},
+		"near and bbox":              {"id": "f1", "near": "0,0", "radius": "1", "bbox": "0,0,1,1"},
+		"short bbox":                 {"id": "f1", "bbox": "0,0,1"},
+		"inverted bbox":              {"id": "f1", "bbox": "1,0,0,1"},
+	}
+	expected := map[string]int{"unknown floorplan": http.StatusNotFound, "meters without calibration": http.StatusConflict}
+	for name, params := range cases {
+		status := http.StatusBadRequest
+		if s, ok := expected[name]; ok {
+			status = s
+		}
+		response, _ := handler.HandleReadFloorPlanAssetsRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %s, got %d", status, name, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package floorplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// conversion is one spot of a calibrated floorplan in every coordinate
+// system; Geo is only set when the floorplan is placed on the globe.
+type conversion struct {
+	Pixel          types.Position `json:"pixel"`
+	Meters         types.Position `json:"meters"`
+	Geo            *geo.Point     `json:"geo,omitempty"`
+	MetersPerPixel float64        `json:"metersPerPixel"`
+}
+
+func NewConvertFloorPlanHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleConvertFloorPlanRequest serves GET /floorplan/convert?id=, converting
+// exactly one of pixel=x,y, meters=x,y or geo=lat,lon on a calibrated
+// floorplan to the others.
+func (h Handler) HandleConvertFloorPlanRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	given := 0
+	for _, name := range []string{"pixel", "meters", "geo"} {
+		if params[name] != "" {
+			given++
+		}
+	}
+	if params["id"] == "" || given != 1 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Expected an id and one of pixel, meters or geo",
+		}, nil
+	}
+
+	floorplan, err := catalog.New(h.DynamoDB).Floorplan(ctx, params["id"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding floorplan: %s", err.Error()),
+		}, nil
+	}
+	if floorplan.Calibration == nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Floorplan %s is not calibrated", floorplan.FloorplanID),
+		}, nil
+	}
+	transform, err := calibration.Compile(*floorplan.Calibration)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Stored calibration is invalid: %s", err.Error()),
+		}, nil
+	}
+
+	result, err := convert(transform, params)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(result)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+func convert(t *calibration.Transform, params map[string]string) (conversion, error) {
+	result := conversion{MetersPerPixel: t.MetersPerPixel()}
+	switch {
+	case params["pixel"] != "":
+		pixel, err := parsePosition("pixel", params["pixel"])
+		if err != nil {
+			return result, err
+		}
+		result.Pixel, result.Meters = pixel, t.ToMeters(pixel)
+	case params["meters"] != "":
+		meters, err := parsePosition("meters", params["meters"])
+		if err != nil {
+			return result, err
+		}
+		result.Pixel, result.Meters = t.ToPixels(meters), meters
+	default:
+		p, err := geo.ParsePoint(params["geo"])
+		if err != nil {
+			return result, err
+		}
+		meters, ok := t.FromGeo(p)
+		if !ok {
+			return result, errors.New("floorplan calibration has no geoOrigin")
+		}
+		result.Pixel, result.Meters = t.ToPixels(meters), meters
+	}
+	if p, ok := t.ToGeo(result.Meters); ok {
+		result.Geo = &p
+	}
+	return result, nil
+}
+
+// parsePosition parses "x,y".
+func parsePosition(name, value string) (types.Position, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) == 2 {
+		x, xErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		y, yErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if xErr == nil && yErr == nil && !math.IsInf(x, 0) && !math.IsInf(y, 0) && !math.IsNaN(x) && !math.IsNaN(y) {
+			return types.Position{X: x, Y: y}, nil
+		}
+	}
+	return types.Position{}, fmt.Errorf("invalid %s parameter %q, expected x,y", name, value)
+}
@@ -0,0 +1,73 @@
+package floorplan
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockGetCalibrated finds floorplan "f1" at 0.05 m/px, placed on the globe,
+// and the uncalibrated "f2".
+func mockGetCalibrated(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	switch params.Key["floorplanId"].(*types.AttributeValueMemberS).Value {
+	case "f1":
+		return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+			"floorplanId": &types.AttributeValueMemberS{Value: "f1"},
+			"calibration": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"metersPerPixel": &types.AttributeValueMemberN{Value: "0.05"},
+				"geoOrigin": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"latitude":  &types.AttributeValueMemberN{Value: "48.78"},
+					"longitude": &types.AttributeValueMemberN{Value: "9.18"},
+				}},
+			}},
+		}}, nil
+	case "f2":
+		return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"floorplanId": &types.AttributeValueMemberS{Value: "f2"}}}, nil
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func TestHandleConvertFloorPlanRequest(t *testing.T) {
+	handler := NewConvertFloorPlanHandler(&mocks.DynamoDBClient{GetItemFunc: mockGetCalibrated})
+
+	response, err := handler.HandleConvertFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"id": "f1", "pixel": "200,-100"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var result conversion
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a conversion, got %d %s", response.StatusCode, response.Body)
+	}
+	if math.Abs(result.Meters.X-10) > 1e-9 || math.Abs(result.Meters.Y-5) > 1e-9 || result.Geo == nil || result.Geo.Lat <= 48.78 {
+		t.Errorf("Expected 10m east and 5m north of the origin, got %+v", result)
+	}
+
+	cases := map[string]map[string]string{
+		"missing position":   {"id": "f1"},
+		"two positions":      {"id": "f1", "pixel": "1,1", "meters": "1,1"},
+		"invalid position":   {"id": "f1", "meters": "1"},
+		"unknown floorplan":  {"id": "gone", "pixel": "1,1"},
+		"uncalibrated":       {"id": "f2", "pixel": "1,1"},
+		"invalid geographic": {"id": "f1", "geo": "91,0"},
+	}
+	expected := map[string]int{"unknown floorplan": http.StatusNotFound, "uncalibrated": http.StatusConflict}
+	for name, params := range cases {
+		status := http.StatusBadRequest
+		if s, ok := expected[name]; ok {
+			status = s
+		}
+		response, _ := handler.HandleConvertFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %s, got %d", status, name, response.StatusCode)
+		}
+	}
+}
@@ -1,18 +1,19 @@
 package floorplan
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"time"
+	"wdd/api/internal/calibration"
 	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
 	"wdd/api/internal/wrappers"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 )
 
 func NewCreateFloorPlanHandler(db types.DynamoDBClient, s3Uploader types.S3Uploader) *Handler {
@@ -38,34 +39,42 @@ func (h Handler) HandleCreateFloorPlanRequest(ctx context.Context, request event
 		}, nil
 	}
 
-	floorplan.DateCreated = time.Now().Format(time.RFC3339)
+	if floorplan.Calibration != nil {
+		if _, err := calibration.Compile(*floorplan.Calibration); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       validation.Body(validation.Invalid("calibration", err.Error())),
+			}, nil
+		}
+	}
 
-	decodedImageData, err := wrappers.Base64DecodeString(floorplan.ImageData)
-	if err != nil {
+	if floorplan.FactoryID == "" {
 		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
+			StatusCode: http.StatusBadRequest,
 			Headers:    headers,
-			Body:       fmt.Sprintf("Error decoding image data: %s", err.Error()),
+			Body:       "Missing factoryId in request body",
 		}, nil
 	}
 
-	imageFileName := fmt.Sprintf("floorplans/%s.jpg", floorplan.FloorplanID)
-	_, err = h.S3Uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String("wingstopdrivenbucket"),
-		Key:         aws.String(imageFileName),
-		Body:        bytes.NewReader(decodedImageData),
-		ContentType: aws.String("image/jpeg"),
-	})
-	if err != nil {
+	floorplan.FloorplanID = uuid.NewString()
+	floorplan.Version = 1
+	// Without a level a floorplan is on the ground floor, so that a
+	// factory's floorplans always list in the same order.
+	if floorplan.Level == nil {
+		level := 0
+		floorplan.Level = &level
+	}
+	floorplan.DateCreated = time.Now().Format(time.RFC3339)
+
+	if err := h.storeImage(ctx, &floorplan); err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
 			Headers:    headers,
-			Body:       fmt.Sprintf("Error uploading image to S3: %s", err.Error()),
+			Body:       err.Error(),
 		}, nil
 	}
 
-	floorplan.ImageData = fmt.Sprintf("https://%s.s3.amazonaws.com/%s", "wingstopdrivenbucket", imageFileName)
-
 	av, err := wrappers.MarshalMap(floorplan)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
@@ -80,6 +89,13 @@ func (h Handler) HandleCreateFloorPlanRequest(ctx context.Context, request event
 		Item:      av,
 	})
 
+	if err == nil {
+		_, err = h.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(VERSIONTABLENAME),
+			Item:      av,
+		})
+	}
+
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -89,8 +105,10 @@ func (h Handler) HandleCreateFloorPlanRequest(ctx context.Context, request event
 	}
 
 	responseBody, err := wrappers.JSONMarshal(map[string]interface{}{
-		"message":   fmt.Sprintf("floorplanId %s created successfully", floorplan.FloorplanID),
-		"factoryId": floorplan.FloorplanID,
+		"message":     fmt.Sprintf("floorplanId %s created successfully", floorplan.FloorplanID),
+		"floorplanId": floorplan.FloorplanID,
+		"factoryId":   floorplan.FactoryID,
+		"version":     floorplan.Version,
 	})
 
 	if err != nil {
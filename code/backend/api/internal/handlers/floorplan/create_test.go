@@ -1,14 +1,20 @@
 package floorplan
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"image"
+	"image/png"
 	"net/http"
+	"strings"
 	"testing"
 	"wdd/api/internal/mocks"
 	"wdd/api/internal/wrappers"
@@ -249,3 +255,89 @@ func TestHandleCreateFloorPlanRequest_Success(t *testing.T) {
 		t.Errorf("Expected status code %d for successful creation, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleCreateFloorPlanRequest_Dimensions(t *testing.T) {
+	var item map[string]ddbtypes.AttributeValue
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			item = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	mockS3Uploader := &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			return &manager.UploadOutput{}, nil
+		},
+	}
+	handler := NewCreateFloorPlanHandler(mockDDBClient, mockS3Uploader)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 640, 480))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	request := events.APIGatewayProxyRequest{
+		Body: fmt.Sprintf(`{"floorplanId":"1", "factoryId": "1", "imageData":%q}`, base64.StdEncoding.EncodeToString(buf.Bytes())),
+	}
+	if response, err := handler.HandleCreateFloorPlanRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful create, got %d %v", response.StatusCode, err)
+	}
+
+	width, _ := item["width"].(*ddbtypes.AttributeValueMemberN)
+	height, _ := item["height"].(*ddbtypes.AttributeValueMemberN)
+	if width == nil || width.Value != "640" || height == nil || height.Value != "480" {
+		t.Errorf("Expected the floorplan to be stored as 640x480, got %v", item)
+	}
+}
+
+func TestHandleCreateFloorPlanRequest_FirstVersion(t *testing.T) {
+	puts := map[string]map[string]ddbtypes.AttributeValue{}
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			puts[*params.TableName] = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	var key string
+	mockS3Uploader := &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			key = *input.Key
+			return &manager.UploadOutput{}, nil
+		},
+	}
+	handler := NewCreateFloorPlanHandler(mockDDBClient, mockS3Uploader)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"floorplanId":"chosen", "factoryId": "1", "name": "Ground floor", "level": 0, "imageData":"aW1hZ2U="}`,
+	}
+	if response, err := handler.HandleCreateFloorPlanRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful create, got %d %v", response.StatusCode, err)
+	}
+
+	current, archived := puts["Floorplan"], puts["FloorplanVersion"]
+	if current == nil || archived == nil {
+		t.Fatalf("Expected the floorplan stored as current and as version 1, got %v", puts)
+	}
+	id := current["floorplanId"].(*ddbtypes.AttributeValueMemberS).Value
+	if id == "chosen" || id != archived["floorplanId"].(*ddbtypes.AttributeValueMemberS).Value {
+		t.Errorf("Expected a generated floorplanId, got %s", id)
+	}
+	if current["version"].(*ddbtypes.AttributeValueMemberN).Value != "1" || current["level"].(*ddbtypes.AttributeValueMemberN).Value != "0" {
+		t.Errorf("Expected version 1 on level 0, got %v", current)
+	}
+	if !strings.HasPrefix(key, fmt.Sprintf("floorplans/%s/v1-", id)) {
+		t.Errorf("Expected the image stored under its version, got %s", key)
+	}
+
+	request.Body = `{"factoryId": "1", "imageData":"aW1hZ2U="}`
+	if response, err := handler.HandleCreateFloorPlanRequest(context.Background(), request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful create, got %d %v", response.StatusCode, err)
+	}
+	if level, ok := puts["Floorplan"]["level"].(*ddbtypes.AttributeValueMemberN); !ok || level.Value != "0" {
+		t.Errorf("Expected a floorplan without a level on the ground floor, got %v", puts["Floorplan"])
+	}
+
+	request.Body = `{"imageData":"aW1hZ2U="}`
+	if response, _ := handler.HandleCreateFloorPlanRequest(context.Background(), request); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d without a factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
@@ -0,0 +1,490 @@
+package floorplan
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/heatmap"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	PNG     = "png"
+	GEOJSON = "geojson"
+	// LATEST interpolates the latest reading of every asset rather than an
+	// aggregate of a window.
+	LATEST = "latest"
+
+	DEFAULTCELLS  = 200
+	MAXCELLS      = 500
+	DEFAULTLEVELS = 10
+	OVERLAYALPHA  = 160
+)
+
+var errNoReadings = errors.New("no readings")
+
+type heatmapQuery struct {
+	property  string
+	aggregate string
+	start     time.Time
+	end       time.Time
+	format    string
+	units     string
+	cells     int
+	power     float64
+	min, max  *float64
+	levels    []float64
+}
+
+// heatmapSample is the value of the property of one asset, at its pixel.
+type heatmapSample struct {
+	assetID string
+	heatmap.Sample
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// contours is a GeoJSON feature collection of isolines and the samples
+// they were drawn from, in the pixels or meters of the floorplan.
+type contours struct {
+	Type        string    `json:"type"`
+	FloorplanID string    `json:"floorplanId"`
+	Property    string    `json:"property"`
+	Unit        string    `json:"unit"`
+	Units       string    `json:"units"`
+	Min         float64   `json:"min"`
+	Max         float64   `json:"max"`
+	Features    []feature `json:"features"`
+}
+
+func NewReadFloorPlanHeatmapHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadFloorPlanHeatmapRequest serves GET /floorplan/heatmap?id=&property=,
+// the readings of the named property across the assets of a floorplan,
+// interpolated by inverse distance weighting onto a grid over its image.
+// aggregate picks the latest readings (the default) or the avg, min, max or
+// last of those in [start, end). The grid is a PNG overlay, or with
+// format=geojson, the contour lines at levels.
+func (h Handler) HandleReadFloorPlanHeatmapRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if params["id"] == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing id query parameter",
+		}, nil
+	}
+	query, err := parseHeatmapQuery(params, time.Now())
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid heatmap: %s", err.Error()),
+		}, nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	floorplan, err := c.Floorplan(ctx, params["id"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding floorplan: %s", err.Error()),
+		}, nil
+	}
+	if floorplan.Width == nil || floorplan.Height == nil || *floorplan.Width <= 0 || *floorplan.Height <= 0 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Floorplan %s has no known image size", floorplan.FloorplanID),
+		}, nil
+	}
+	var transform *calibration.Transform
+	if query.units == METERS {
+		if floorplan.Calibration == nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusConflict,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Floorplan %s is not calibrated", floorplan.FloorplanID),
+			}, nil
+		}
+		if transform, err = calibration.Compile(*floorplan.Calibration); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Stored calibration is invalid: %s", err.Error()),
+			}, nil
+		}
+	}
+
+	samples, unit, err := h.heatmapSamples(ctx, c, floorplan.FloorplanID, query)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNoReadings) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error gathering readings: %s", err.Error()),
+		}, nil
+	}
+
+	points := make([]heatmap.Sample, len(samples))
+	for i, s := range samples {
+		points[i] = s.Sample
+	}
+	width, height := float64(*floorplan.Width), float64(*floorplan.Height)
+	cols, rows := heatmap.Size(width, height, query.cells)
+	grid := heatmap.Interpolate(points, width, height, cols, rows, query.power)
+	min, max := grid.Range()
+	if query.min != nil {
+		min = *query.min
+	}
+	if query.max != nil {
+		max = *query.max
+	}
+
+	if query.format == PNG {
+		var buf bytes.Buffer
+		if err := heatmap.PNG(&buf, grid, min, max, OVERLAYALPHA); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error rendering heatmap: %s", err.Error()),
+			}, nil
+		}
+		// The scale of the overlay travels in headers, for its legend.
+		headers["Content-Type"] = "image/png"
+		headers["Access-Control-Expose-Headers"] = "X-Heatmap-Min, X-Heatmap-Max, X-Heatmap-Unit"
+		headers["X-Heatmap-Min"] = strconv.FormatFloat(min, 'g', -1, 64)
+		headers["X-Heatmap-Max"] = strconv.FormatFloat(max, 'g', -1, 64)
+		headers["X-Heatmap-Unit"] = unit
+		return events.APIGatewayProxyResponse{
+			StatusCode:      http.StatusOK,
+			Headers:         headers,
+			Body:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+			IsBase64Encoded: true,
+		}, nil
+	}
+
+	project := func(p types.Position) []float64 {
+		if transform != nil {
+			p = transform.ToMeters(p)
+		}
+		return []float64{p.X, p.Y}
+	}
+	collection := contours{
+		Type:        "FeatureCollection",
+		FloorplanID: floorplan.FloorplanID,
+		Property:    query.property,
+		Unit:        unit,
+		Units:       query.units,
+		Min:         min,
+		Max:         max,
+		Features:    []feature{},
+	}
+	levels := query.levels
+	if levels == nil {
+		for i := 1; i <= DEFAULTLEVELS; i++ {
+			levels = append(levels, min+(max-min)*float64(i)/(DEFAULTLEVELS+1))
+		}
+	}
+	for _, level := range levels {
+		lines := heatmap.Contours(grid, level)
+		if len(lines) == 0 {
+			continue
+		}
+		coordinates := make([][][]float64, len(lines))
+		for i, line := range lines {
+			for _, p := range line {
+				coordinates[i] = append(coordinates[i], project(p))
+			}
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:       "Feature",
+			Geometry:   geometry{Type: "MultiLineString", Coordinates: coordinates},
+			Properties: map[string]interface{}{"level": level},
+		})
+	}
+	for _, s := range samples {
+		collection.Features = append(collection.Features, feature{
+			Type:       "Feature",
+			Geometry:   geometry{Type: "Point", Coordinates: project(s.Position)},
+			Properties: map[string]interface{}{"assetId": s.assetID, "value": s.Value},
+		})
+	}
+
+	responseBody, err := wrappers.JSONMarshal(collection)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	headers["Content-Type"] = "application/geo+json"
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+// heatmapSamples reads the value of the queried property of every
+// positioned asset on a floorplan whose model has it, leaving out bad
+// readings, and returns them in asset id order with the property's unit.
+func (h Handler) heatmapSamples(ctx context.Context, c *catalog.Catalog, floorplanID string, query heatmapQuery) ([]heatmapSample, string, error) {
+	assets, err := c.FloorplanAssets(ctx, floorplanID)
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].AssetID < assets[j].AssetID })
+
+	models := map[string]*types.Model{}
+	var propertyIDs []string
+	for _, asset := range assets {
+		if asset.ModelID == nil || *asset.ModelID == "" {
+			continue
+		}
+		if _, read := models[*asset.ModelID]; read {
+			continue
+		}
+		model, err := c.Model(ctx, *asset.ModelID)
+		if errors.Is(err, catalog.ErrNotFound) {
+			models[*asset.ModelID] = nil
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		models[*asset.ModelID] = &model
+		if model.Properties != nil {
+			propertyIDs = append(propertyIDs, *model.Properties...)
+		}
+	}
+	properties, err := c.Properties(ctx, propertyIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	var measurementIDs []string
+	for _, p := range properties {
+		if strings.EqualFold(p.Name, query.property) {
+			measurementIDs = append(measurementIDs, p.MeasurementID)
+		}
+	}
+	measurements, err := c.Measurements(ctx, measurementIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Assets sharing a model share its series, which is read once.
+	type value struct {
+		value float64
+		ok    bool
+	}
+	values := map[string]value{}
+	store := timeseries.NewStore(h.DynamoDB)
+	var samples []heatmapSample
+	unit := ""
+	for _, asset := range assets {
+		pixel, ok := calibration.Pixel(asset.FloorplanCoords)
+		if !ok || asset.ModelID == nil || models[*asset.ModelID] == nil || models[*asset.ModelID].Properties == nil {
+			continue
+		}
+		var property *types.Property
+		for _, id := range *models[*asset.ModelID].Properties {
+			if p, ok := properties[id]; ok && strings.EqualFold(p.Name, query.property) {
+				property = &p
+				break
+			}
+		}
+		if property == nil {
+			continue
+		}
+
+		v, read := values[property.PropertyID]
+		if !read {
+			if v.value, v.ok, err = propertyValue(ctx, store, *property, measurements[property.MeasurementID], query); err != nil {
+				return nil, "", err
+			}
+			values[property.PropertyID] = v
+		}
+		if !v.ok {
+			continue
+		}
+		if unit == "" {
+			unit = property.Unit
+		}
+		samples = append(samples, heatmapSample{assetID: asset.AssetID, Sample: heatmap.Sample{Position: pixel, Value: v.value}})
+	}
+	if len(samples) == 0 {
+		return nil, "", fmt.Errorf("%w of %q on floorplan %s", errNoReadings, query.property, floorplanID)
+	}
+	return samples, unit, nil
+}
+
+// propertyValue returns the latest good or uncertain reading of a property,
+// or the queried aggregate of those in the window, and false when there are
+// none.
+func propertyValue(ctx context.Context, store *timeseries.Store, property types.Property, measurement types.Measurement, query heatmapQuery) (float64, bool, error) {
+	if query.aggregate == LATEST {
+		reading, err := store.Latest(ctx, property.PropertyID)
+		if err != nil || reading == nil {
+			return 0, false, err
+		}
+		current := quality.Current(*reading, measurement, query.end)
+		return current.Value, quality.Severity(quality.Of(current)) != quality.BAD, nil
+	}
+
+	var count int
+	var value float64
+	it := store.Iterate(property.PropertyID, query.start, query.end)
+	for it.Next(ctx) {
+		reading := it.Reading()
+		if quality.Severity(quality.Of(reading)) == quality.BAD {
+			continue
+		}
+		count++
+		switch {
+		case count == 1 || query.aggregate == timeseries.LAST:
+			value = reading.Value
+		case query.aggregate == timeseries.MIN:
+			value = math.Min(value, reading.Value)
+		case query.aggregate == timeseries.MAX:
+			value = math.Max(value, reading.Value)
+		case query.aggregate == timeseries.AVG:
+			value += (reading.Value - value) / float64(count)
+		}
+	}
+	return value, count > 0, it.Err()
+}
+
+func parseHeatmapQuery(params map[string]string, now time.Time) (heatmapQuery, error) {
+	query := heatmapQuery{
+		property:  params["property"],
+		aggregate: strings.ToLower(params["aggregate"]),
+		end:       now,
+		format:    strings.ToLower(params["format"]),
+		units:     params["units"],
+		cells:     DEFAULTCELLS,
+		power:     2,
+	}
+	if query.property == "" {
+		return query, errors.New("missing property query parameter")
+	}
+
+	switch query.aggregate {
+	case "":
+		query.aggregate = LATEST
+	case LATEST, timeseries.AVG, timeseries.MIN, timeseries.MAX, timeseries.LAST:
+	default:
+		return query, fmt.Errorf("invalid aggregate %q, expected %s, %s, %s, %s or %s", query.aggregate, LATEST, timeseries.AVG, timeseries.MIN, timeseries.MAX, timeseries.LAST)
+	}
+	var err error
+	if params["end"] != "" {
+		if query.end, err = time.Parse(time.RFC3339, params["end"]); err != nil {
+			return query, fmt.Errorf("invalid end parameter: %w", err)
+		}
+	}
+	query.start = query.end.Add(-time.Hour)
+	if params["start"] != "" {
+		if query.start, err = time.Parse(time.RFC3339, params["start"]); err != nil {
+			return query, fmt.Errorf("invalid start parameter: %w", err)
+		}
+	}
+	if !query.end.After(query.start) {
+		return query, errors.New("end must be after start")
+	}
+
+	switch query.format {
+	case "":
+		query.format = PNG
+	case PNG, GEOJSON:
+	default:
+		return query, fmt.Errorf("invalid format %q, expected %s or %s", query.format, PNG, GEOJSON)
+	}
+	switch query.units {
+	case "":
+		query.units = PIXELS
+	case PIXELS, METERS:
+	default:
+		return query, fmt.Errorf("invalid units %q, expected %s or %s", query.units, METERS, PIXELS)
+	}
+
+	if params["cells"] != "" {
+		if query.cells, err = strconv.Atoi(params["cells"]); err != nil || query.cells < 2 || query.cells > MAXCELLS {
+			return query, fmt.Errorf("cells must be between 2 and %d", MAXCELLS)
+		}
+	}
+	if params["power"] != "" {
+		if query.power, err = strconv.ParseFloat(params["power"], 64); err != nil || !(query.power > 0 && query.power <= 10) {
+			return query, errors.New("power must be above 0 and at most 10")
+		}
+	}
+	for _, name := range []string{"min", "max"} {
+		if params[name] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(params[name], 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return query, fmt.Errorf("invalid %s parameter %q", name, params[name])
+		}
+		if name == "min" {
+			query.min = &v
+		} else {
+			query.max = &v
+		}
+	}
+	if query.min != nil && query.max != nil && *query.min > *query.max {
+		return query, errors.New("min must not be above max")
+	}
+	if params["levels"] != "" {
+		for _, part := range strings.Split(params["levels"], ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+				return query, fmt.Errorf("invalid levels parameter %q", params["levels"])
+			}
+			query.levels = append(query.levels, v)
+		}
+	}
+	return query, nil
+}
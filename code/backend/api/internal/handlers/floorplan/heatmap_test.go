@@ -0,0 +1,215 @@
+package floorplan
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockHeatmap serves floorplan "f1" of 100x50 pixels, and "f2" of unknown
+// size. On it a1 at (0,0) reads 10 degrees and a2 at (100,0) reads 30, each
+// with its own model; a3 at (50,50) only measures humidity. The readings of
+// a2 are bad before the latest.
+func mockHeatmap() *mocks.DynamoDBClient {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	s := func(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+	n := func(v string) types.AttributeValue { return &types.AttributeValueMemberN{Value: v} }
+	asset := func(id, model, x, y string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"assetId": s(id), "modelId": s(model), "floorplanId": s("f1"),
+			"floorplanCoords": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"longitude": n(x), "latitude": n(y)}},
+		}
+	}
+	reading := func(value, quality string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"timestamp": n(now), "value": n(value), "quality": s(quality)}
+	}
+	properties := map[string]string{"m1": "t1", "m2": "t2", "m3": "h3"}
+
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch params.Key["floorplanId"].(*types.AttributeValueMemberS).Value {
+			case "f1":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"floorplanId": s("f1"), "width": n("100"), "height": n("50")}}, nil
+			case "f2":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"floorplanId": s("f2")}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			switch aws.ToString(params.TableName) {
+			case "Asset":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{asset("a2", "m2", "100", "0"), asset("a1", "m1", "0", "0"), asset("a3", "m3", "50", "50")}}, nil
+			case "Model":
+				id := params.ExpressionAttributeValues[":modelId"].(*types.AttributeValueMemberS).Value
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId": s(id), "properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{s(properties[id])}},
+				}}}, nil
+			case "Reading":
+				switch params.ExpressionAttributeValues[":propertyId"].(*types.AttributeValueMemberS).Value {
+				case "t1":
+					return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{reading("10", ""), reading("12", "")}}, nil
+				case "t2":
+					if params.Limit != nil {
+						return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{reading("30", "")}}, nil
+					}
+					return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{reading("90", "bad"), reading("30", "")}}, nil
+				}
+			}
+			return &dynamodb.QueryOutput{}, nil
+		},
+		BatchGetItemFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{}}
+			for table, request := range params.RequestItems {
+				for _, key := range request.Keys {
+					if table == "Property" {
+						id := key["propertyId"].(*types.AttributeValueMemberS).Value
+						name := "Temperature"
+						if id == "h3" {
+							name = "Humidity"
+						}
+						output.Responses[table] = append(output.Responses[table], map[string]types.AttributeValue{"propertyId": s(id), "measurementId": s("m-" + id), "name": s(name), "unit": s("°C")})
+					} else {
+						output.Responses[table] = append(output.Responses[table], map[string]types.AttributeValue{"measurementId": key["measurementId"], "frequency": n("1")})
+					}
+				}
+			}
+			return output, nil
+		},
+	}
+}
+
+func heatmapRequest(params map[string]string) events.APIGatewayProxyResponse {
+	handler := NewReadFloorPlanHeatmapHandler(mockHeatmap())
+	response, _ := handler.HandleReadFloorPlanHeatmapRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+	return response
+}
+
+func TestHandleReadFloorPlanHeatmapRequest_PNG(t *testing.T) {
+	response := heatmapRequest(map[string]string{"id": "f1", "property": "temperature", "cells": "20"})
+	if response.StatusCode != http.StatusOK || !response.IsBase64Encoded || response.Headers["Content-Type"] != "image/png" {
+		t.Fatalf("Expected a PNG, got %d %v %s", response.StatusCode, response.Headers, response.Body)
+	}
+	body, err := base64.StdEncoding.DecodeString(response.Body)
+	if err != nil {
+		t.Fatalf("Expected a base64 body, got %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected a PNG, got %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 20 || size.Y != 10 {
+		t.Errorf("Expected 20x10 cells over the 100x50 floorplan, got %v", size)
+	}
+	// The interpolated range stays within the samples, nearly reaching them
+	// at the cells next to them.
+	min, _ := strconv.ParseFloat(response.Headers["X-Heatmap-Min"], 64)
+	max, _ := strconv.ParseFloat(response.Headers["X-Heatmap-Max"], 64)
+	if !(min >= 10 && min < 15 && max <= 30 && max > 25) || response.Headers["X-Heatmap-Unit"] != "°C" {
+		t.Errorf("Expected a scale inside 10 to 30°C, got %v", response.Headers)
+	}
+}
+
+type heatmapCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"features"`
+}
+
+func contoursOf(t *testing.T, params map[string]string) heatmapCollection {
+	response := heatmapRequest(params)
+	if response.StatusCode != http.StatusOK || response.Headers["Content-Type"] != "application/geo+json" {
+		t.Fatalf("Expected GeoJSON, got %d %s", response.StatusCode, response.Body)
+	}
+	var collection heatmapCollection
+	if err := json.Unmarshal([]byte(response.Body), &collection); err != nil || collection.Type != "FeatureCollection" {
+		t.Fatalf("Expected a feature collection, got %s", response.Body)
+	}
+	return collection
+}
+
+// sampled returns the values of the sample features of a collection.
+func sampled(collection heatmapCollection) map[string]float64 {
+	values := map[string]float64{}
+	for _, f := range collection.Features {
+		if f.Geometry.Type == "Point" {
+			values[f.Properties["assetId"].(string)] = f.Properties["value"].(float64)
+		}
+	}
+	return values
+}
+
+func TestHandleReadFloorPlanHeatmapRequest_GeoJSON(t *testing.T) {
+	collection := contoursOf(t, map[string]string{"id": "f1", "property": "Temperature", "format": "geojson", "levels": "20", "cells": "20"})
+	if len(collection.Features) != 3 {
+		t.Fatalf("Expected one contour and 2 samples, got %+v", collection)
+	}
+
+	// Halfway between the samples, the 20 degree line runs down the middle.
+	contour := collection.Features[0]
+	var lines [][][]float64
+	if err := json.Unmarshal(contour.Geometry.Coordinates, &lines); err != nil || contour.Geometry.Type != "MultiLineString" || len(lines) != 1 {
+		t.Fatalf("Expected one contour line, got %s", contour.Geometry.Coordinates)
+	}
+	for _, p := range lines[0] {
+		if math.Abs(p[0]-50) > 1e-9 {
+			t.Errorf("Expected the 20 degree line at x 50, got %v", lines[0])
+		}
+	}
+
+	// Humidity of a3 is left out.
+	if values := sampled(collection); len(values) != 2 || values["a1"] != 10 || values["a2"] != 30 {
+		t.Errorf("Expected a1 at 10 and a2 at 30, got %v", values)
+	}
+}
+
+func TestHandleReadFloorPlanHeatmapRequest_Aggregate(t *testing.T) {
+	// Bad readings are left out of the aggregate.
+	collection := contoursOf(t, map[string]string{"id": "f1", "property": "temperature", "format": "geojson", "aggregate": "max", "cells": "20"})
+	if values := sampled(collection); len(values) != 2 || values["a1"] != 12 || values["a2"] != 30 {
+		t.Errorf("Expected a1 at 12 and a2 at 30, got %v", values)
+	}
+	if len(collection.Features) != 2+DEFAULTLEVELS {
+		t.Errorf("Expected %d contours and 2 samples, got %d features", DEFAULTLEVELS, len(collection.Features))
+	}
+}
+
+func TestHandleReadFloorPlanHeatmapRequest_Errors(t *testing.T) {
+	cases := []struct {
+		params map[string]string
+		status int
+	}{
+		{map[string]string{"property": "temperature"}, http.StatusBadRequest},
+		{map[string]string{"id": "f1"}, http.StatusBadRequest},
+		{map[string]string{"id": "f1", "property": "temperature", "aggregate": "median"}, http.StatusBadRequest},
+		{map[string]string{"id": "f1", "property": "temperature", "format": "svg"}, http.StatusBadRequest},
+		{map[string]string{"id": "f1", "property": "temperature", "cells": "100000"}, http.StatusBadRequest},
+		{map[string]string{"id": "f1", "property": "temperature", "min": "30", "max": "10"}, http.StatusBadRequest},
+		{map[string]string{"id": "gone", "property": "temperature"}, http.StatusNotFound},
+		{map[string]string{"id": "f1", "property": "pressure"}, http.StatusNotFound},
+		{map[string]string{"id": "f2", "property": "temperature"}, http.StatusConflict},
+		{map[string]string{"id": "f1", "property": "temperature", "units": "meters", "format": "geojson"}, http.StatusConflict},
+	}
+	for _, c := range cases {
+		if response := heatmapRequest(c.params); response.StatusCode != c.status {
+			t.Errorf("Expected status code %d for %v, got %d %s", c.status, c.params, response.StatusCode, response.Body)
+		}
+	}
+}
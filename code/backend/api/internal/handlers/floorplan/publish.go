@@ -0,0 +1,365 @@
+package floorplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// publication is the new version of a floorplan and how many of the assets
+// and zones on it were moved onto its image.
+type publication struct {
+	Floorplan     types.Floorplan `json:"floorplan"`
+	AssetsMoved   int             `json:"assetsMoved"`
+	AssetsClamped int             `json:"assetsClamped"`
+	ZonesMoved    int             `json:"zonesMoved"`
+}
+
+// publish replaces the image of a floorplan with a new version, keeping the
+// current one in the version table, and moves the assets and zones on it
+// so they keep their spot on the new image. Each asset and zone records the
+// version its position refers to, so a publish cut short leaves the rest on
+// the version they were on and the next publish moves them from there.
+func (h Handler) publish(ctx context.Context, update types.Floorplan, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	c := catalog.New(h.DynamoDB)
+	current, err := c.Floorplan(ctx, update.FloorplanID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding floorplan: %s", err.Error()),
+		}, nil
+	}
+	// Floorplans from before versioning are their own first version.
+	previousVersion := current.Version
+	if current.Version == 0 {
+		current.Version = 1
+	}
+
+	next := current
+	next.Version = current.Version + 1
+	next.ImageData = update.ImageData
+	next.DateCreated = time.Now().Format(time.RFC3339)
+	if update.Name != nil {
+		next.Name = update.Name
+	}
+	if update.Level != nil {
+		next.Level = update.Level
+	}
+	if err := h.storeImage(ctx, &next); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+	// A calibration only carries over to an image of the same size.
+	next.Calibration = update.Calibration
+	if next.Calibration == nil && sameSize(current, next) {
+		next.Calibration = current.Calibration
+	}
+
+	assets, zones, err := h.pin(ctx, c, current)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading floorplan contents: %s", err.Error()),
+		}, nil
+	}
+
+	if err := h.putVersion(ctx, current, next, previousVersion); err != nil {
+		var conditionFailed *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusConflict,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Floorplan %s changed while publishing, retry", current.FloorplanID),
+			}, nil
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error publishing floorplan: %s", err.Error()),
+		}, nil
+	}
+
+	result := publication{Floorplan: next}
+	remap := remapper{
+		catalog: c,
+		next:    next,
+		remaps:  map[int]func(types.Position) types.Position{current.Version: calibration.Remap(current, next)},
+	}
+	if result.AssetsMoved, result.AssetsClamped, err = h.moveAssets(ctx, next, assets, remap); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error moving assets: %s", err.Error()),
+		}, nil
+	}
+	if result.ZonesMoved, err = h.moveZones(ctx, next, zones, remap); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error moving zones: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(result)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+// putVersion archives the current version, which floorplans from before
+// versioning lack, then makes next current unless another publish got there
+// first, and archives it too.
+func (h Handler) putVersion(ctx context.Context, current, next types.Floorplan, previousVersion int) error {
+	currentItem, err := wrappers.MarshalMap(current)
+	if err != nil {
+		return err
+	}
+	nextItem, err := wrappers.MarshalMap(next)
+	if err != nil {
+		return err
+	}
+
+	if _, err = h.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(VERSIONTABLENAME),
+		Item:      currentItem,
+	}); err != nil {
+		return err
+	}
+
+	condition := expression.Name("version").Equal(expression.Value(previousVersion))
+	if previousVersion == 0 {
+		condition = expression.AttributeNotExists(expression.Name("version")).Or(condition)
+	}
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+	if _, err = h.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(TABLENAME),
+		Item:                      nextItem,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}); err != nil {
+		return err
+	}
+
+	_, err = h.DynamoDB.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(VERSIONTABLENAME),
+		Item:      nextItem,
+	})
+	return err
+}
+
+// remapper moves the pixels of the versions of a floorplan onto the image
+// of its new version, reading earlier versions from the version table.
+type remapper struct {
+	catalog *catalog.Catalog
+	next    types.Floorplan
+	remaps  map[int]func(types.Position) types.Position
+}
+
+func (r remapper) from(ctx context.Context, version int) (func(types.Position) types.Position, error) {
+	if remap, ok := r.remaps[version]; ok {
+		return remap, nil
+	}
+	previous, err := r.catalog.FloorplanVersion(ctx, r.next.FloorplanID, version)
+	if err != nil {
+		return nil, err
+	}
+	r.remaps[version] = calibration.Remap(previous, r.next)
+	return r.remaps[version], nil
+}
+
+// pin returns the assets and zones on a floorplan, recording the current
+// version on those whose position does not refer to one yet, before a new
+// version replaces it.
+func (h Handler) pin(ctx context.Context, c *catalog.Catalog, current types.Floorplan) ([]types.Asset, []types.Zone, error) {
+	assets, err := c.FloorplanAssets(ctx, current.FloorplanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, asset := range assets {
+		if _, ok := calibration.Pixel(asset.FloorplanCoords); !ok || asset.FloorplanVersion != nil {
+			continue
+		}
+		if err := h.pinVersion(ctx, catalog.ASSETTABLE, "assetId", asset.AssetID, current); errors.Is(err, errMoved) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		assets[i].FloorplanVersion = &current.Version
+	}
+
+	zones, err := c.FloorplanZones(ctx, current.FloorplanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, z := range zones {
+		if len(z.Polygon) == 0 || z.FloorplanVersion != 0 {
+			continue
+		}
+		if err := h.pinVersion(ctx, catalog.ZONETABLE, "zoneId", z.ZoneID, current); errors.Is(err, errMoved) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		zones[i].FloorplanVersion = current.Version
+	}
+	return assets, zones, nil
+}
+
+// pinVersion records the version of a floorplan on an item of it that has
+// none, unless it was moved off the floorplan in the meantime.
+func (h Handler) pinVersion(ctx context.Context, table, keyName, key string, floorplan types.Floorplan) error {
+	update := expression.Set(expression.Name("floorplanVersion"), expression.Value(floorplan.Version))
+	condition := expression.AttributeNotExists(expression.Name("floorplanVersion")).
+		And(expression.Name("floorplanId").Equal(expression.Value(floorplan.FloorplanID)))
+	return h.updateOnFloorplan(ctx, table, keyName, key, update, condition)
+}
+
+// moveAssets remaps the positioned assets of a floorplan onto its new
+// image, clamping those that would fall off it, and records the new version
+// on each. Assets moved to another floorplan or to another spot in the
+// meantime are left alone.
+func (h Handler) moveAssets(ctx context.Context, floorplan types.Floorplan, assets []types.Asset, r remapper) (moved, clamped int, err error) {
+	for _, asset := range assets {
+		pixel, ok := calibration.Pixel(asset.FloorplanCoords)
+		if !ok || asset.FloorplanVersion == nil || *asset.FloorplanVersion >= floorplan.Version {
+			continue
+		}
+		remap, err := r.from(ctx, *asset.FloorplanVersion)
+		if err != nil {
+			return moved, clamped, err
+		}
+		position, wasClamped := calibration.Clamp(remap(pixel), floorplan)
+
+		update := expression.Set(expression.Name("floorplanVersion"), expression.Value(floorplan.Version))
+		if position != pixel {
+			coords, err := wrappers.MarshalMap(types.FloorplanCoords{Longitude: &position.X, Latitude: &position.Y})
+			if err != nil {
+				return moved, clamped, err
+			}
+			update = update.Set(expression.Name("floorplanCoords"), expression.Value(&ddbtypes.AttributeValueMemberM{Value: coords}))
+		}
+		condition := expression.Name("floorplanId").Equal(expression.Value(floorplan.FloorplanID)).
+			And(expression.Name("floorplanVersion").Equal(expression.Value(*asset.FloorplanVersion)))
+		if err := h.updateOnFloorplan(ctx, catalog.ASSETTABLE, "assetId", asset.AssetID, update, condition); errors.Is(err, errMoved) {
+			continue
+		} else if err != nil {
+			return moved, clamped, err
+		}
+		if position == pixel {
+			continue
+		}
+		moved++
+		if wasClamped {
+			clamped++
+		}
+	}
+	return moved, clamped, nil
+}
+
+// moveZones remaps the polygons of the zones on a floorplan onto its new
+// image, clamping vertices that would fall off it, and records the new
+// version on each.
+func (h Handler) moveZones(ctx context.Context, floorplan types.Floorplan, zones []types.Zone, r remapper) (int, error) {
+	moved := 0
+	for _, z := range zones {
+		if z.FloorplanVersion == 0 || z.FloorplanVersion >= floorplan.Version {
+			continue
+		}
+		remap, err := r.from(ctx, z.FloorplanVersion)
+		if err != nil {
+			return moved, err
+		}
+		changed := false
+		polygon := make([]types.Position, len(z.Polygon))
+		for i, vertex := range z.Polygon {
+			polygon[i], _ = calibration.Clamp(remap(vertex), floorplan)
+			changed = changed || polygon[i] != vertex
+		}
+
+		update := expression.Set(expression.Name("floorplanVersion"), expression.Value(floorplan.Version))
+		if changed {
+			av, err := wrappers.MarshalMap(types.Zone{Polygon: polygon})
+			if err != nil {
+				return moved, err
+			}
+			update = update.Set(expression.Name("polygon"), expression.Value(av["polygon"]))
+		}
+		condition := expression.Name("floorplanId").Equal(expression.Value(floorplan.FloorplanID)).
+			And(expression.Name("floorplanVersion").Equal(expression.Value(z.FloorplanVersion)))
+		if err := h.updateOnFloorplan(ctx, catalog.ZONETABLE, "zoneId", z.ZoneID, update, condition); errors.Is(err, errMoved) {
+			continue
+		} else if err != nil {
+			return moved, err
+		}
+		if changed {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// errMoved reports that an item changed under a publish: it left the
+// floorplan, or its position no longer refers to the expected version.
+var errMoved = errors.New("moved in the meantime")
+
+func (h Handler) updateOnFloorplan(ctx context.Context, table, keyName, key string, update expression.UpdateBuilder, condition expression.ConditionBuilder) error {
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+	_, err = h.DynamoDB.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       map[string]ddbtypes.AttributeValue{keyName: &ddbtypes.AttributeValueMemberS{Value: key}},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return errMoved
+	}
+	return err
+}
+
+func sameSize(a, b types.Floorplan) bool {
+	if a.Width == nil || a.Height == nil || b.Width == nil || b.Height == nil {
+		return a.Width == nil && a.Height == nil && b.Width == nil && b.Height == nil
+	}
+	return *a.Width == *b.Width && *a.Height == *b.Height
+}
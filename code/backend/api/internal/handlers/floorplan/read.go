@@ -2,8 +2,12 @@ package floorplan
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"wdd/api/internal/catalog"
 	"wdd/api/internal/types"
 	"wdd/api/internal/wrappers"
 
@@ -27,6 +31,13 @@ func (h Handler) HandleReadFloorPlanRequest(ctx context.Context, request events.
 		"Content-Type":                "application/json",
 	}
 
+	if factoryID := request.QueryStringParameters["factoryId"]; factoryID != "" && floorplanID == "" {
+		return h.readFactoryFloorplans(ctx, factoryID, headers)
+	}
+	if version := request.QueryStringParameters["version"]; version != "" && floorplanID != "" {
+		return h.readFloorplanVersion(ctx, floorplanID, version, headers)
+	}
+
 	if floorplanID == "" {
 		input := &dynamodb.ScanInput{
 			TableName: aws.String(TABLENAME),
@@ -115,3 +126,85 @@ func (h Handler) HandleReadFloorPlanRequest(ctx context.Context, request events.
 		Body:       string(floorplanJSON),
 	}, nil
 }
+
+// readFactoryFloorplans lists the current version of every floorplan of a
+// factory, from the lowest level up.
+func (h Handler) readFactoryFloorplans(ctx context.Context, factoryID string, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	floorplans, err := catalog.New(h.DynamoDB).FactoryFloorplans(ctx, factoryID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error fetching floorplans: %s", err),
+		}, nil
+	}
+
+	sort.Slice(floorplans, func(i, j int) bool {
+		a, b := floorplans[i], floorplans[j]
+		if (a.Level == nil) != (b.Level == nil) {
+			return b.Level == nil
+		}
+		if a.Level != nil && *a.Level != *b.Level {
+			return *a.Level < *b.Level
+		}
+		return a.FloorplanID < b.FloorplanID
+	})
+	if floorplans == nil {
+		floorplans = []types.Floorplan{}
+	}
+
+	floorplansJSON, err := wrappers.JSONMarshal(floorplans)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(floorplansJSON),
+	}, nil
+}
+
+// readFloorplanVersion returns one published version of a floorplan.
+func (h Handler) readFloorplanVersion(ctx context.Context, floorplanID, version string, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	number, err := strconv.Atoi(version)
+	if err != nil || number < 1 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Invalid version %q, expected a number from 1", version),
+		}, nil
+	}
+
+	floorplan, err := catalog.New(h.DynamoDB).FloorplanVersion(ctx, floorplanID, number)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding floorplan: %s", err),
+		}, nil
+	}
+
+	floorplanJSON, err := wrappers.JSONMarshal(floorplan)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(floorplanJSON),
+	}, nil
+}
@@ -2,6 +2,7 @@ package floorplan
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -270,3 +271,57 @@ func TestHandleReadFloorPlanRequest_WithId_Success(t *testing.T) {
 		t.Errorf("Expected status code %d for successful read with id, got %d", http.StatusOK, response.StatusCode)
 	}
 }
+
+func TestHandleReadFloorPlanRequest_ByFactory(t *testing.T) {
+	var query *dynamodb.QueryInput
+	level := func(id, level string) map[string]types.AttributeValue {
+		item := map[string]types.AttributeValue{"floorplanId": &types.AttributeValueMemberS{Value: id}}
+		if level != "" {
+			item["level"] = &types.AttributeValueMemberN{Value: level}
+		}
+		return item
+	}
+	handler := NewReadFloorPlanHandler(&mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			query = params
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{level("roof", ""), level("upper", "1"), level("ground", "0")}}, nil
+		},
+	})
+
+	response, err := handler.HandleReadFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "factory"}})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the floorplans of the factory, got %d %v", response.StatusCode, err)
+	}
+	if query.IndexName == nil || *query.IndexName != "factoryId" {
+		t.Errorf("Expected a query of the factoryId index, got %v", query.IndexName)
+	}
+	var floorplans []struct {
+		FloorplanID string `json:"floorplanId"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &floorplans); err != nil || len(floorplans) != 3 {
+		t.Fatalf("Expected 3 floorplans, got %s", response.Body)
+	}
+	if order := floorplans[0].FloorplanID + "," + floorplans[1].FloorplanID + "," + floorplans[2].FloorplanID; order != "ground,upper,roof" {
+		t.Errorf("Expected floorplans from the lowest level up, got %s", order)
+	}
+}
+
+func TestHandleReadFloorPlanRequest_Version(t *testing.T) {
+	handler := NewReadFloorPlanHandler(&mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if *params.TableName != "FloorplanVersion" || params.Key["version"].(*types.AttributeValueMemberN).Value != "1" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: params.Key}, nil
+		},
+	})
+
+	cases := map[string]int{"1": http.StatusOK, "2": http.StatusNotFound, "latest": http.StatusBadRequest}
+	for version, status := range cases {
+		request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": "f1", "version": version}}
+		response, _ := handler.HandleReadFloorPlanRequest(context.Background(), request)
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for version %s, got %d", status, version, response.StatusCode)
+		}
+	}
+}
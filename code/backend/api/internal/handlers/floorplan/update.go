@@ -0,0 +1,155 @@
+package floorplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewUpdateFloorPlanHandler(db types.DynamoDBClient, s3Uploader types.S3Uploader) *Handler {
+	return &Handler{
+		DynamoDB:   db,
+		S3Uploader: s3Uploader,
+	}
+}
+
+// HandleUpdateFloorPlanRequest renames an existing floorplan, moves it to
+// another level or recalibrates it. With imageData it publishes a new
+// version of the floorplan instead.
+func (h Handler) HandleUpdateFloorPlanRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	var floorplan types.Floorplan
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &floorplan); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+	if floorplan.FloorplanID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing floorplanId in request body",
+		}, nil
+	}
+	if floorplan.Calibration != nil {
+		if _, err := calibration.Compile(*floorplan.Calibration); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       validation.Body(validation.Invalid("calibration", err.Error())),
+			}, nil
+		}
+	}
+	if floorplan.ImageData != "" {
+		return h.publish(ctx, floorplan, headers)
+	}
+	if floorplan.Name == nil && floorplan.Level == nil && floorplan.Calibration == nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Nothing to update: give a name, level, calibration or imageData",
+		}, nil
+	}
+
+	av, err := wrappers.MarshalMap(floorplan)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling floorplan: %s", err.Error()),
+		}, nil
+	}
+	var update expression.UpdateBuilder
+	for _, name := range []string{"name", "level", "calibration"} {
+		if value, ok := av[name]; ok {
+			update = update.Set(expression.Name(name), expression.Value(value))
+		}
+	}
+	condition := expression.AttributeExists(expression.Name("floorplanId"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Failed to build update expression: %s", err.Error()),
+		}, nil
+	}
+
+	result, err := h.DynamoDB.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(TABLENAME),
+		Key:                       map[string]ddbtypes.AttributeValue{"floorplanId": &ddbtypes.AttributeValueMemberS{Value: floorplan.FloorplanID}},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+		ReturnValues:              ddbtypes.ReturnValueAllNew,
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Floorplan with ID %s not found", floorplan.FloorplanID),
+		}, nil
+	}
+	if err == nil {
+		err = h.updateVersion(ctx, result.Attributes, expr)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error updating floorplan: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       fmt.Sprintf("floorplanId %s updated successfully", floorplan.FloorplanID),
+	}, nil
+}
+
+// updateVersion applies an update of the floorplan table to the row of its
+// current version in the version table, so the two stay alike. Floorplans
+// from before versioning have no such row.
+func (h Handler) updateVersion(ctx context.Context, item map[string]ddbtypes.AttributeValue, expr expression.Expression) error {
+	var current types.Floorplan
+	if err := wrappers.UnmarshalMap(item, &current); err != nil || current.Version == 0 {
+		return err
+	}
+	_, err := h.DynamoDB.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(VERSIONTABLENAME),
+		Key: map[string]ddbtypes.AttributeValue{
+			"floorplanId": &ddbtypes.AttributeValueMemberS{Value: current.FloorplanID},
+			"version":     &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(current.Version)},
+		},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
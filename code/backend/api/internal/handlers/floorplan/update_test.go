@@ -0,0 +1,296 @@
+package floorplan
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestHandleUpdateFloorPlanRequest(t *testing.T) {
+	var update, versionUpdate *dynamodb.UpdateItemInput
+	handler := NewUpdateFloorPlanHandler(&mocks.DynamoDBClient{
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			if params.Key["floorplanId"].(*types.AttributeValueMemberS).Value == "gone" {
+				return nil, &types.ConditionalCheckFailedException{Message: aws.String("mock condition failed")}
+			}
+			if aws.ToString(params.TableName) == "FloorplanVersion" {
+				versionUpdate = params
+				return &dynamodb.UpdateItemOutput{}, nil
+			}
+			update = params
+			return &dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{
+				"floorplanId": params.Key["floorplanId"],
+				"version":     &types.AttributeValueMemberN{Value: "2"},
+			}}, nil
+		},
+	}, &mocks.S3Uploader{})
+
+	body := `{"floorplanId": "1", "calibration": {"metersPerPixel": 0.05, "rotationDegrees": 15}}`
+	response, err := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful update, got %d %v %s", response.StatusCode, err, response.Body)
+	}
+	calibration, ok := update.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberM)
+	if !ok || calibration.Value["metersPerPixel"].(*types.AttributeValueMemberN).Value != "0.05" {
+		t.Errorf("Expected the calibration to be set, got %v", update.ExpressionAttributeValues)
+	}
+
+	body = `{"floorplanId": "1", "name": "Mezzanine", "level": 1}`
+	if response, _ := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: body}); response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful rename, got %d %s", response.StatusCode, response.Body)
+	}
+	if _, ok := update.ExpressionAttributeNames["#0"]; !ok || len(update.ExpressionAttributeValues) != 2 {
+		t.Errorf("Expected the name and level to be set, got %v", update.ExpressionAttributeValues)
+	}
+	if versionUpdate == nil || versionUpdate.Key["version"].(*types.AttributeValueMemberN).Value != "2" || aws.ToString(versionUpdate.UpdateExpression) != aws.ToString(update.UpdateExpression) {
+		t.Errorf("Expected the row of version 2 updated alike, got %v", versionUpdate)
+	}
+
+	cases := map[string]int{
+		`{"floorplanId": "1"}`:  http.StatusBadRequest,
+		`{"name": "Mezzanine"}`: http.StatusBadRequest,
+		`{"floorplanId": "1", "calibration": {"metersPerPixel": -1}}`:   http.StatusBadRequest,
+		`{"floorplanId": "gone", "calibration": {"metersPerPixel": 1}}`: http.StatusNotFound,
+	}
+	for body, status := range cases {
+		response, _ := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %s, got %d", status, body, response.StatusCode)
+		}
+	}
+}
+
+// publishMocks serve floorplan "f1" as version 2 of 100x50 pixels, with the
+// assets of mockQueryAssets and zone "z1" over its left half.
+func publishMocks(puts *[]*dynamodb.PutItemInput, updates map[string]*dynamodb.UpdateItemInput) *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"floorplanId": &types.AttributeValueMemberS{Value: "f1"},
+				"factoryId":   &types.AttributeValueMemberS{Value: "factory"},
+				"version":     &types.AttributeValueMemberN{Value: "2"},
+				"width":       &types.AttributeValueMemberN{Value: "100"},
+				"height":      &types.AttributeValueMemberN{Value: "50"},
+			}}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if aws.ToString(params.TableName) != "Zone" {
+				return mockQueryAssets(ctx, params, optFns...)
+			}
+			vertex := func(x, y string) types.AttributeValue {
+				return &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"x": &types.AttributeValueMemberN{Value: x},
+					"y": &types.AttributeValueMemberN{Value: y},
+				}}
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+				"zoneId":      &types.AttributeValueMemberS{Value: "z1"},
+				"floorplanId": &types.AttributeValueMemberS{Value: "f1"},
+				"polygon":     &types.AttributeValueMemberL{Value: []types.AttributeValue{vertex("0", "0"), vertex("50", "0"), vertex("50", "50"), vertex("0", "50")}},
+			}}}, nil
+		},
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			*puts = append(*puts, params)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			for _, key := range params.Key {
+				updates[key.(*types.AttributeValueMemberS).Value] = params
+			}
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+}
+
+func TestHandleUpdateFloorPlanRequest_Publish(t *testing.T) {
+	var puts []*dynamodb.PutItemInput
+	updates := map[string]*dynamodb.UpdateItemInput{}
+	var uploaded string
+	handler := NewUpdateFloorPlanHandler(publishMocks(&puts, updates), &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			uploaded = aws.ToString(input.Key)
+			return &manager.UploadOutput{}, nil
+		},
+	})
+
+	// The new image is twice the size of the old one.
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body := fmt.Sprintf(`{"floorplanId": "f1", "imageData": %q}`, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	response, err := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful publish, got %d %v %s", response.StatusCode, err, response.Body)
+	}
+
+	var result publication
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil {
+		t.Fatalf("Expected a publication, got %s", response.Body)
+	}
+	if result.Floorplan.Version != 3 || *result.Floorplan.Width != 200 || !strings.HasPrefix(uploaded, "floorplans/f1/v3-") || !strings.HasSuffix(result.Floorplan.ImageData, uploaded) {
+		t.Errorf("Expected version 3 of 200px stored under floorplans/f1/v3-, got %+v at %s", result.Floorplan, uploaded)
+	}
+	if result.AssetsMoved != 2 || result.AssetsClamped != 1 || result.ZonesMoved != 1 {
+		t.Errorf("Expected 2 assets moved, 1 clamped and 1 zone moved, got %+v", result)
+	}
+
+	tables := []string{}
+	for _, put := range puts {
+		tables = append(tables, fmt.Sprintf("%s v%s", aws.ToString(put.TableName), put.Item["version"].(*types.AttributeValueMemberN).Value))
+	}
+	if fmt.Sprint(tables) != "[FloorplanVersion v2 Floorplan v3 FloorplanVersion v3]" {
+		t.Errorf("Expected version 2 archived before version 3 is published, got %v", tables)
+	}
+	if puts[1].ConditionExpression == nil {
+		t.Errorf("Expected the publish to be conditional on the current version")
+	}
+
+	if updated(updates["a1"], "floorplanCoords") != nil {
+		t.Errorf("Expected a1 at the origin to stay put")
+	}
+	if version, _ := updated(updates["a1"], "floorplanVersion").(*types.AttributeValueMemberN); version == nil || version.Value != "3" {
+		t.Errorf("Expected a1 on version 3, got %v", updates["a1"])
+	}
+	coords, _ := updated(updates["a3"], "floorplanCoords").(*types.AttributeValueMemberM)
+	if coords == nil || coords.Value["longitude"].(*types.AttributeValueMemberN).Value != "200" || coords.Value["latitude"].(*types.AttributeValueMemberN).Value != "100" {
+		t.Errorf("Expected a3 clamped to the corner of the new image, got %v", updates["a3"])
+	}
+}
+
+func TestHandleUpdateFloorPlanRequest_PublishResumes(t *testing.T) {
+	var puts []*dynamodb.PutItemInput
+	updates := map[string]*dynamodb.UpdateItemInput{}
+	db := publishMocks(&puts, updates)
+	// Publishing version 2 moved a3 but failed before a2, which is still on
+	// version 1, an image of 400x200 pixels.
+	getItem := db.GetItemFunc
+	db.GetItemFunc = func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+		if aws.ToString(params.TableName) != "FloorplanVersion" {
+			return getItem(ctx, params, optFns...)
+		}
+		return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+			"floorplanId": &types.AttributeValueMemberS{Value: "f1"},
+			"version":     &types.AttributeValueMemberN{Value: "1"},
+			"width":       &types.AttributeValueMemberN{Value: "400"},
+			"height":      &types.AttributeValueMemberN{Value: "200"},
+		}}, nil
+	}
+	query := db.QueryFunc
+	db.QueryFunc = func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+		output, err := query(ctx, params, optFns...)
+		for _, item := range output.Items {
+			version := "2"
+			if item["assetId"] != nil && item["assetId"].(*types.AttributeValueMemberS).Value == "a2" {
+				version = "1"
+			}
+			item["floorplanVersion"] = &types.AttributeValueMemberN{Value: version}
+		}
+		return output, err
+	}
+	handler := NewUpdateFloorPlanHandler(db, &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			return &manager.UploadOutput{}, nil
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body := fmt.Sprintf(`{"floorplanId": "f1", "imageData": %q}`, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	response, err := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful publish, got %d %v %s", response.StatusCode, err, response.Body)
+	}
+
+	// a2 at (100, 0) on version 1 is at (50, 0) on version 3, not doubled
+	// from version 2.
+	coords, _ := updated(updates["a2"], "floorplanCoords").(*types.AttributeValueMemberM)
+	if coords == nil || coords.Value["longitude"].(*types.AttributeValueMemberN).Value != "50" {
+		t.Errorf("Expected a2 moved from version 1, got %v", updates["a2"])
+	}
+	for id, update := range updates {
+		if update.ConditionExpression == nil || !strings.Contains(aws.ToString(update.ConditionExpression), "AND") || updated(update, "floorplanVersion") == nil {
+			t.Errorf("Expected %s moved on condition of its version, got %v", id, update)
+		}
+	}
+}
+
+// updated returns the value an update expression sets an attribute to.
+func updated(update *dynamodb.UpdateItemInput, attribute string) types.AttributeValue {
+	if update == nil {
+		return nil
+	}
+	for placeholder, name := range update.ExpressionAttributeNames {
+		if name != attribute {
+			continue
+		}
+		sets := strings.TrimPrefix(strings.TrimSpace(aws.ToString(update.UpdateExpression)), "SET ")
+		for _, set := range strings.Split(sets, ", ") {
+			if parts := strings.SplitN(set, " = ", 2); len(parts) == 2 && parts[0] == placeholder {
+				return update.ExpressionAttributeValues[parts[1]]
+			}
+		}
+	}
+	return nil
+}
+
+func TestHandleUpdateFloorPlanRequest_PublishConflict(t *testing.T) {
+	var puts []*dynamodb.PutItemInput
+	db := publishMocks(&puts, map[string]*dynamodb.UpdateItemInput{})
+	// The first publish of version 3 wins; the second finds it taken.
+	published := 0
+	db.PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+		if params.ConditionExpression != nil {
+			if published++; published > 1 {
+				return nil, &types.ConditionalCheckFailedException{Message: aws.String("mock condition failed")}
+			}
+		}
+		puts = append(puts, params)
+		return &dynamodb.PutItemOutput{}, nil
+	}
+	var uploaded []string
+	handler := NewUpdateFloorPlanHandler(db, &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			uploaded = append(uploaded, aws.ToString(input.Key))
+			return &manager.UploadOutput{}, nil
+		},
+	})
+
+	response, _ := handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"floorplanId": "f1", "imageData": "d2lubmVy"}`})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the first publish to succeed, got %d %s", response.StatusCode, response.Body)
+	}
+	response, _ = handler.HandleUpdateFloorPlanRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"floorplanId": "f1", "imageData": "bG9zZXI="}`})
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d for a concurrent publish, got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	if len(uploaded) != 2 || uploaded[0] == uploaded[1] {
+		t.Fatalf("Expected the two images of version 3 under their own keys, got %v", uploaded)
+	}
+	for _, put := range puts {
+		if put.Item["version"].(*types.AttributeValueMemberN).Value != "3" {
+			continue
+		}
+		if image := put.Item["imageData"].(*types.AttributeValueMemberS).Value; !strings.HasSuffix(image, uploaded[0]) {
+			t.Errorf("Expected version 3 to keep the image of the publish that won, got %s", image)
+		}
+	}
+}
@@ -1,12 +1,59 @@
 package floorplan
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-const TABLENAME = "Floorplan"
+const (
+	TABLENAME        = "Floorplan"
+	VERSIONTABLENAME = "FloorplanVersion"
+	BUCKETNAME       = "wingstopdrivenbucket"
+)
 
 type Handler struct {
 	DynamoDB   types.DynamoDBClient
 	S3Uploader types.S3Uploader
 }
+
+// storeImage uploads the base64 image of a floorplan version under a key of
+// its version and content, so that earlier versions keep theirs and
+// concurrent publishes of the same version cannot overwrite each other's,
+// and replaces ImageData with its URL.
+func (h Handler) storeImage(ctx context.Context, floorplan *types.Floorplan) error {
+	decodedImageData, err := wrappers.Base64DecodeString(floorplan.ImageData)
+	if err != nil {
+		return fmt.Errorf("error decoding image data: %w", err)
+	}
+
+	// The image's dimensions bound the positions of assets on it.
+	floorplan.Width, floorplan.Height = nil, nil
+	if config, _, err := image.DecodeConfig(bytes.NewReader(decodedImageData)); err == nil {
+		floorplan.Width, floorplan.Height = &config.Width, &config.Height
+	}
+
+	digest := sha256.Sum256(decodedImageData)
+	imageFileName := fmt.Sprintf("floorplans/%s/v%d-%x.jpg", floorplan.FloorplanID, floorplan.Version, digest[:8])
+	_, err = h.S3Uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(BUCKETNAME),
+		Key:         aws.String(imageFileName),
+		Body:        bytes.NewReader(decodedImageData),
+		ContentType: aws.String("image/jpeg"),
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading image to S3: %w", err)
+	}
+
+	floorplan.ImageData = fmt.Sprintf("https://%s.s3.amazonaws.com/%s", BUCKETNAME, imageFileName)
+	return nil
+}
@@ -0,0 +1,84 @@
+package floorplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadFloorPlanVersionsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadFloorPlanVersionsRequest serves GET /floorplan/versions?id=,
+// every published version of a floorplan, oldest first.
+func (h Handler) HandleReadFloorPlanVersionsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	floorplanID := request.QueryStringParameters["id"]
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if floorplanID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing id query parameter",
+		}, nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	versions, err := c.FloorplanVersions(ctx, floorplanID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error fetching floorplan versions: %s", err),
+		}, nil
+	}
+	// Floorplans from before versioning are only in the floorplan table,
+	// as their first version.
+	if len(versions) == 0 {
+		floorplan, err := c.Floorplan(ctx, floorplanID)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, catalog.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: status,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error finding floorplan: %s", err),
+			}, nil
+		}
+		if floorplan.Version == 0 {
+			floorplan.Version = 1
+		}
+		versions = []types.Floorplan{floorplan}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	versionsJSON, err := wrappers.JSONMarshal(versions)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(versionsJSON),
+	}, nil
+}
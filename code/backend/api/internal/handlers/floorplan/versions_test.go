@@ -0,0 +1,64 @@
+package floorplan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleReadFloorPlanVersionsRequest(t *testing.T) {
+	version := func(n string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"floorplanId": &types.AttributeValueMemberS{Value: "f1"},
+			"version":     &types.AttributeValueMemberN{Value: n},
+		}
+	}
+	handler := NewReadFloorPlanVersionsHandler(&mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if params.ExpressionAttributeValues[":key"].(*types.AttributeValueMemberS).Value != "f1" {
+				return &dynamodb.QueryOutput{}, nil
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{version("2"), version("1")}}, nil
+		},
+		// Floorplans from before versioning only have their current record.
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if params.Key["floorplanId"].(*types.AttributeValueMemberS).Value != "legacy" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: params.Key}, nil
+		},
+	})
+
+	expected := map[string][]int{"f1": {1, 2}, "legacy": {1}}
+	for id, versions := range expected {
+		response, err := handler.HandleReadFloorPlanVersionsRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": id}})
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected the versions of %s, got %d %v", id, response.StatusCode, err)
+		}
+		var floorplans []struct {
+			Version int `json:"version"`
+		}
+		if err := json.Unmarshal([]byte(response.Body), &floorplans); err != nil || len(floorplans) != len(versions) {
+			t.Fatalf("Expected %d versions of %s, got %s", len(versions), id, response.Body)
+		}
+		for i, v := range versions {
+			if floorplans[i].Version != v {
+				t.Errorf("Expected version %d of %s at %d, got %d", v, id, i, floorplans[i].Version)
+			}
+		}
+	}
+
+	cases := map[string]int{"": http.StatusBadRequest, "gone": http.StatusNotFound}
+	for id, status := range cases {
+		response, _ := handler.HandleReadFloorPlanVersionsRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": id}})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for id %q, got %d", status, id, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package lineprotocolmappings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"wdd/api/internal/lineprotocol"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func NewCreateLineProtocolMappingHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleCreateLineProtocolMappingRequest stores how line protocol written
+// for a factory maps to its assets and properties, replacing any previous
+// mapping.
+func (h Handler) HandleCreateLineProtocolMappingRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var mapping types.LineProtocolMapping
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &mapping); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	if mapping.FactoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factoryId in request body",
+		}, nil
+	}
+
+	if err := lineprotocol.Validate(mapping); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	if mapping.AssetTag == "" {
+		mapping.AssetTag = lineprotocol.DEFAULTASSETTAG
+	}
+	mapping.DateCreated = time.Now().Format(time.RFC3339)
+
+	av, err := wrappers.MarshalMap(mapping)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling line protocol mapping to DynamoDB format: %s", err.Error()),
+		}, nil
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(TABLENAME),
+	}
+
+	if _, err = h.DynamoDB.PutItem(ctx, input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error putting item into DynamoDB: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(mapping)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response body: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package lineprotocolmappings
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func TestHandleCreateLineProtocolMappingRequest_BadJSON(t *testing.T) {
+	handler := NewCreateLineProtocolMappingHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","assets":"boiler"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for bad JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateLineProtocolMappingRequest_MissingFactoryID(t *testing.T) {
+	handler := NewCreateLineProtocolMappingHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"assetTag":"host"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateLineProtocolMappingRequest_InvalidMapping(t *testing.T) {
+	handler := NewCreateLineProtocolMappingHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","fields":{"temp":""}}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an empty field mapping, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateLineProtocolMappingRequest_PutItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewCreateLineProtocolMappingHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB PutItem error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleCreateLineProtocolMappingRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateLineProtocolMappingHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","fields":{"temp":"Temperature"}}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	if !strings.Contains(response.Body, `"assetTag":"asset"`) || !strings.Contains(response.Body, `"temp":"Temperature"`) {
+		t.Errorf("Expected the stored mapping with the default asset tag, got %s", response.Body)
+	}
+}
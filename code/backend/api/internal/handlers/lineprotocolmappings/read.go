@@ -0,0 +1,83 @@
+package lineprotocolmappings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/lineprotocol"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewReadLineProtocolMappingHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadLineProtocolMappingRequest returns the mapping in effect for a
+// factory, which is the default mapping when none has been stored.
+func (h Handler) HandleReadLineProtocolMappingRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	factoryID := request.QueryStringParameters["factoryId"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if factoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factoryId query parameter",
+		}, nil
+	}
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(TABLENAME),
+		Key: map[string]ddbtypes.AttributeValue{
+			"factoryId": &ddbtypes.AttributeValueMemberS{Value: factoryID},
+		},
+	}
+
+	result, err := h.DynamoDB.GetItem(ctx, input)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding line protocol mapping: %s", err),
+		}, nil
+	}
+
+	mapping := lineprotocol.Default(factoryID)
+	if result.Item != nil {
+		mapping = types.LineProtocolMapping{}
+		if err = wrappers.UnmarshalMap(result.Item, &mapping); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Failed to unmarshal line protocol mapping, %v", err),
+			}, nil
+		}
+	}
+
+	responseBody, err := wrappers.JSONMarshal(mapping)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
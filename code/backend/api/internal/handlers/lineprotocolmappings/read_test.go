@@ -0,0 +1,87 @@
+package lineprotocolmappings
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleReadLineProtocolMappingRequest_MissingFactoryID(t *testing.T) {
+	handler := NewReadLineProtocolMappingHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleReadLineProtocolMappingRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleReadLineProtocolMappingRequest_GetItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewReadLineProtocolMappingHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadLineProtocolMappingRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB GetItem error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleReadLineProtocolMappingRequest_Default(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+	handler := NewReadLineProtocolMappingHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadLineProtocolMappingRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"assetTag":"asset"`) {
+		t.Errorf("Expected the default mapping, got %d %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleReadLineProtocolMappingRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "1"},
+				"assetTag":  &types.AttributeValueMemberS{Value: "host"},
+			}}, nil
+		},
+	}
+	handler := NewReadLineProtocolMappingHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadLineProtocolMappingRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"assetTag":"host"`) {
+		t.Errorf("Expected the stored mapping, got %d %s", response.StatusCode, response.Body)
+	}
+}
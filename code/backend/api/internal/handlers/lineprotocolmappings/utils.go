@@ -0,0 +1,11 @@
+package lineprotocolmappings
+
+import (
+	"wdd/api/internal/types"
+)
+
+const TABLENAME = "LineProtocolMapping"
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/metrics"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadMetricsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadMetricsRequest exposes the latest reading of every property as
+// Prometheus gauges. factoryId takes a comma-separated list of factories to
+// scrape (all of them by default) and limit caps the number of properties,
+// up to metrics.MAXSERIES.
+func (h Handler) HandleReadMetricsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                metrics.CONTENTTYPE,
+	}
+
+	var opts metrics.Options
+	for _, id := range strings.Split(params["factoryId"], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			opts.FactoryIDs = append(opts.FactoryIDs, id)
+		}
+	}
+	if raw := params["limit"]; raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > metrics.MAXSERIES {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       fmt.Sprintf("limit must be between 1 and %d", metrics.MAXSERIES),
+			}, nil
+		}
+		opts.Limit = limit
+	}
+
+	snapshot, err := metrics.NewCollector(h.DynamoDB).Collect(ctx, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error collecting metrics: %s", err),
+		}, nil
+	}
+
+	var body strings.Builder
+	if err = metrics.WriteText(&body, snapshot); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error writing metrics: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       body.String(),
+	}, nil
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func mockFactoryClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if params.Key["factoryId"].(*types.AttributeValueMemberS).Value != "f1" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+			}}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}
+}
+
+func TestHandleReadMetricsRequest_InvalidLimit(t *testing.T) {
+	handler := NewReadMetricsHandler(mockFactoryClient())
+
+	for _, limit := range []string{"0", "abc", "10001"} {
+		request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": limit}}
+		response, err := handler.HandleReadMetricsRequest(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for limit %s, got %d", http.StatusBadRequest, limit, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleReadMetricsRequest_NotFound(t *testing.T) {
+	handler := NewReadMetricsHandler(mockFactoryClient())
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "f1,missing"}}
+	response, err := handler.HandleReadMetricsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleReadMetricsRequest_Success(t *testing.T) {
+	handler := NewReadMetricsHandler(mockFactoryClient())
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "f1"}}
+	response, err := handler.HandleReadMetricsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+	if response.Headers["Content-Type"] != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", response.Headers["Content-Type"])
+	}
+	if !strings.Contains(response.Body, "wdd_metrics_series 0\n") {
+		t.Errorf("Expected an empty scrape, got:\n%s", response.Body)
+	}
+}
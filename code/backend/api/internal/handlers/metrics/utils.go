@@ -0,0 +1,9 @@
+package metrics
+
+import (
+	"wdd/api/internal/types"
+)
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
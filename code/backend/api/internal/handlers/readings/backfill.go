@@ -0,0 +1,76 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/backfill"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewBackfillReadingsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+func (h Handler) HandleBackfillReadingsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	var backfillRequest backfill.Request
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &backfillRequest); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	backfiller := backfill.New(h.DynamoDB)
+	backfiller.Limit = MAXBACKFILLREADINGS
+
+	stats, err := backfiller.Run(ctx, backfillRequest)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: backfillErrorStatus(err),
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error backfilling readings: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(stats)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+func backfillErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, catalog.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backfill.ErrInvalidRequest), errors.Is(err, backfill.ErrNoSeries):
+		return http.StatusBadRequest
+	case errors.Is(err, backfill.ErrTooManyReadings):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,334 @@
+package readings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"wdd/api/internal/backfill"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func mockPropertyGetItem(generatorFunction string) func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+		switch *params.TableName {
+		case "Property":
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"propertyId":    &types.AttributeValueMemberS{Value: "p1"},
+				"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+				"name":          &types.AttributeValueMemberS{Value: "Temperature"},
+			}}, nil
+		case "Measurement":
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"measurementId":     &types.AttributeValueMemberS{Value: "m1"},
+				"generatorFunction": &types.AttributeValueMemberS{Value: generatorFunction},
+				"frequency":         &types.AttributeValueMemberN{Value: "1"},
+				"lowerBound":        &types.AttributeValueMemberN{Value: "10"},
+				"upperBound":        &types.AttributeValueMemberN{Value: "20"},
+			}}, nil
+		}
+		return &dynamodb.GetItemOutput{}, nil
+	}
+}
+
+// mockModelScan finds p1 on a model of factory f1.
+func mockModelScan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{{
+		"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+		"factoryId":  &types.AttributeValueMemberS{Value: "f1"},
+		"properties": &types.AttributeValueMemberSS{Value: []string{"p1"}},
+	}}}, nil
+}
+
+func TestHandleBackfillReadingsRequest_JSONUnmarshalError(t *testing.T) {
+	handler := NewBackfillReadingsHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: "invalid json",
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_MissingTarget(t *testing.T) {
+	handler := NewBackfillReadingsHandler(&mocks.DynamoDBClient{})
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a request without a target, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_PropertyNotFound(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: nil}, nil
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a missing property, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_UnsupportedGenerator(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("square"),
+		ScanFunc:    mockModelScan,
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a property without an offline generator, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_TooManyReadings(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2023-01-01T00:00:00Z","end":"2024-01-01T00:00:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d for a window over the limit, got %d", http.StatusRequestEntityTooLarge, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_BatchWriteError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("sinewave"),
+		ScanFunc:    mockModelScan,
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB batch write error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_JSONMarshalError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("sawtooth"),
+		ScanFunc:    mockModelScan,
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	originalJSONMarshal := wrappers.JSONMarshal
+
+	defer func() { wrappers.JSONMarshal = originalJSONMarshal }()
+
+	wrappers.JSONMarshal = func(v interface{}) ([]byte, error) {
+		return nil, errors.New("mock marshal error")
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T00:01:00Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for marshalling stats in JSON format, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_Success(t *testing.T) {
+	var written int64
+	var calendar string
+	getItem := mockPropertyGetItem("random")
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if *params.TableName == "Calendar" {
+				calendar = params.Key["factoryId"].(*types.AttributeValueMemberS).Value
+			}
+			return getItem(ctx, params, optFns...)
+		},
+		ScanFunc: mockModelScan,
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			if len(params.RequestItems["Reading"]) > 25 {
+				t.Errorf("Expected at most 25 items per batch, got %d", len(params.RequestItems["Reading"]))
+			}
+			atomic.AddInt64(&written, int64(len(params.RequestItems["Reading"])))
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z","seed":42}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for successful backfill, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var stats backfill.Stats
+	if err := json.Unmarshal([]byte(response.Body), &stats); err != nil {
+		t.Fatalf("Expected stats in the response body, got %v", err)
+	}
+	if stats.Readings != 3600 || written != 3600 {
+		t.Errorf("Expected 3600 readings written, got %d reported and %d written", stats.Readings, written)
+	}
+	if stats.Batches != 144 {
+		t.Errorf("Expected 144 batches, got %d", stats.Batches)
+	}
+	if calendar != "f1" {
+		t.Errorf("Expected the calendar of f1, whose model lists p1, to apply, got %q", calendar)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_AlignsStart(t *testing.T) {
+	var timestamps []string
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			for _, request := range params.RequestItems["Reading"] {
+				timestamps = append(timestamps, request.PutRequest.Item["timestamp"].(*types.AttributeValueMemberN).Value)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"propertyId":"p1","start":"2024-01-01T00:00:00.300Z","end":"2024-01-01T00:00:04Z"}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for successful backfill, got %d", http.StatusOK, response.StatusCode)
+	}
+	if len(timestamps) != 3 || timestamps[0] != "1704067201000" || timestamps[2] != "1704067203000" {
+		t.Errorf("Expected readings on the whole seconds after the start, got %v", timestamps)
+	}
+}
+
+func TestHandleBackfillReadingsRequest_SharedModel(t *testing.T) {
+	var written int64
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		// The factory's assets come in two pages, the second holding two
+		// assets of model m1.
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if *params.TableName == "Model" {
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+					"properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "p1"}}},
+				}}}, nil
+			}
+			asset := func(id string) map[string]types.AttributeValue {
+				return map[string]types.AttributeValue{
+					"assetId": &types.AttributeValueMemberS{Value: id},
+					"modelId": &types.AttributeValueMemberS{Value: "m1"},
+				}
+			}
+			if params.ExclusiveStartKey == nil {
+				unmodelled := map[string]types.AttributeValue{"assetId": &types.AttributeValueMemberS{Value: "a0"}}
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{unmodelled}, LastEvaluatedKey: unmodelled}, nil
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{asset("a1"), asset("a2")}}, nil
+		},
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			atomic.AddInt64(&written, int64(len(params.RequestItems["Reading"])))
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+	handler := NewBackfillReadingsHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"factoryId":"f1","start":"2024-01-01T00:00:00Z","end":"2024-01-01T01:00:00Z","ignoreCalendar":true}`,
+	}
+
+	response, err := handler.HandleBackfillReadingsRequest(context.Background(), request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a successful backfill, got %d %v %s", response.StatusCode, err, response.Body)
+	}
+
+	var stats backfill.Stats
+	if err := json.Unmarshal([]byte(response.Body), &stats); err != nil {
+		t.Fatalf("Expected stats in the response body, got %v", err)
+	}
+	if stats.Series != 1 || stats.Readings != 3600 || written != 3600 {
+		t.Errorf("Expected the shared series written once, got %d series and %d readings written", stats.Series, written)
+	}
+}
@@ -0,0 +1,71 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/retention"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewCompactReadingsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleCompactReadingsRequest runs the retention job for one factory, or for
+// every factory when the body has no factoryId.
+func (h Handler) HandleCompactReadingsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	var compactRequest retention.Request
+	if request.Body != "" {
+		if err := wrappers.JSONUnmarshal([]byte(request.Body), &compactRequest); err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+			}, nil
+		}
+	}
+
+	stats, err := retention.New(h.DynamoDB).Run(ctx, compactRequest)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, catalog.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, retention.ErrInvalidPolicy):
+			status = http.StatusUnprocessableEntity
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error compacting readings: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(stats)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
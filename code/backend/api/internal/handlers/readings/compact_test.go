@@ -0,0 +1,93 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleCompactReadingsRequest_BadJSON(t *testing.T) {
+	handler := NewCompactReadingsHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCompactReadingsRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":1}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for bad JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCompactReadingsRequest_InvalidPolicy(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+				"rawDays":   &types.AttributeValueMemberN{Value: "0"},
+			}}, nil
+		},
+	}
+	handler := NewCompactReadingsHandler(mockDDBClient)
+
+	response, err := handler.HandleCompactReadingsRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"f1"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d for an invalid stored policy, got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+}
+
+func TestHandleCompactReadingsRequest_ScanError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewCompactReadingsHandler(mockDDBClient)
+
+	response, err := handler.HandleCompactReadingsRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB scan error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleCompactReadingsRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{
+				{"factoryId": &types.AttributeValueMemberS{Value: "f1"}},
+				{"factoryId": &types.AttributeValueMemberS{Value: "f2"}},
+			}}, nil
+		},
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}
+	handler := NewCompactReadingsHandler(mockDDBClient)
+
+	response, err := handler.HandleCompactReadingsRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"factories":2`) {
+		t.Errorf("Expected both factories to be compacted, got %d %s", response.StatusCode, response.Body)
+	}
+}
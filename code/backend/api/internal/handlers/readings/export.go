@@ -0,0 +1,181 @@
+package readings
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/export"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+)
+
+const EXPORTLINKEXPIRY = time.Hour
+
+type exportLink struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expiresAt"`
+	Format    string `json:"format"`
+	Bytes     int64  `json:"bytes"`
+	Series    int    `json:"series"`
+	Rows      int64  `json:"rows"`
+}
+
+func NewExportReadingsHandler(db types.DynamoDBClient, s3Uploader types.S3Uploader, s3Presigner types.S3Presigner) *Handler {
+	return &Handler{
+		DynamoDB:    db,
+		S3Uploader:  s3Uploader,
+		S3Presigner: s3Presigner,
+	}
+}
+
+// HandleExportReadingsRequest returns small exports inline and writes exports
+// whose response body would be larger than MAXINLINEBYTES (or any export with
+// delivery=link) to S3, responding with a presigned download link instead.
+func (h Handler) HandleExportReadingsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	exportRequest, err := parseExportRequest(request.QueryStringParameters)
+	if err == nil {
+		err = exportRequest.Validate()
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	out := &spillWriter{
+		ctx:         ctx,
+		uploader:    h.S3Uploader,
+		key:         fmt.Sprintf("exports/%s.%s", uuid.NewString(), export.Extension(exportRequest.Format)),
+		contentType: export.ContentType(exportRequest.Format),
+		limit:       inlineLimit(exportRequest.Format),
+	}
+
+	stats, err := export.New(h.DynamoDB).Export(ctx, exportRequest, out)
+	if err != nil {
+		out.abort(err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, catalog.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, export.ErrInvalidRequest):
+			status = http.StatusBadRequest
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error exporting readings: %s", err.Error()),
+		}, nil
+	}
+
+	uploaded, err := out.finish(request.QueryStringParameters["delivery"] == "link")
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error uploading export to S3: %s", err.Error()),
+		}, nil
+	}
+
+	if !uploaded {
+		return inlineExport(exportRequest.Format, out.buf.Bytes()), nil
+	}
+
+	presigned, err := h.S3Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(BUCKETNAME),
+		Key:    aws.String(out.key),
+	}, s3.WithPresignExpires(EXPORTLINKEXPIRY))
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error presigning export link: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(exportLink{
+		URL:       presigned.URL,
+		ExpiresAt: time.Now().Add(EXPORTLINKEXPIRY).Format(time.RFC3339),
+		Format:    exportRequest.Format,
+		Bytes:     out.size,
+		Series:    stats.Series,
+		Rows:      stats.Rows,
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+func parseExportRequest(params map[string]string) (export.Request, error) {
+	request := export.Request{
+		FactoryID:  params["factoryId"],
+		AssetID:    params["assetId"],
+		PropertyID: params["propertyId"],
+		Format:     params["format"],
+		Layout:     params["layout"],
+		Precision:  params["precision"],
+		Quality:    params["quality"],
+		Timezone:   params["tz"],
+	}
+
+	var err error
+	if request.Start, err = time.Parse(time.RFC3339, params["start"]); err != nil {
+		return request, fmt.Errorf("invalid start parameter: %w", err)
+	}
+	if request.End, err = time.Parse(time.RFC3339, params["end"]); err != nil {
+		return request, fmt.Errorf("invalid end parameter: %w", err)
+	}
+	return request, nil
+}
+
+// inlineLimit is the largest export of a format returned inline. Binary
+// formats go out base64 encoded, so their limit is the size whose encoding
+// still fits in MAXINLINEBYTES.
+func inlineLimit(format string) int {
+	if format == export.PARQUET {
+		return base64.StdEncoding.DecodedLen(MAXINLINEBYTES)
+	}
+	return MAXINLINEBYTES
+}
+
+func inlineExport(format string, body []byte) events.APIGatewayProxyResponse {
+	response := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Access-Control-Allow-Origin": "*",
+			"Content-Type":                export.ContentType(format),
+			"Content-Disposition":         fmt.Sprintf("attachment; filename=\"readings.%s\"", export.Extension(format)),
+		},
+		Body: string(body),
+	}
+	if format == export.PARQUET {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
+	}
+	return response
+}
@@ -0,0 +1,259 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func mockExportQuery(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+		{
+			"propertyId": &types.AttributeValueMemberS{Value: "p1"},
+			"timestamp":  &types.AttributeValueMemberN{Value: "1704067200000"},
+			"value":      &types.AttributeValueMemberN{Value: "12.5"},
+		},
+	}}, nil
+}
+
+func exportRequest(params map[string]string) events.APIGatewayProxyRequest {
+	query := map[string]string{
+		"propertyId": "p1",
+		"start":      "2024-01-01T00:00:00Z",
+		"end":        "2024-01-02T00:00:00Z",
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	return events.APIGatewayProxyRequest{QueryStringParameters: query}
+}
+
+func TestHandleExportReadingsRequest_InvalidWindow(t *testing.T) {
+	handler := NewExportReadingsHandler(&mocks.DynamoDBClient{}, &mocks.S3Uploader{}, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"start": "yesterday"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid start, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleExportReadingsRequest_InvalidFormat(t *testing.T) {
+	handler := NewExportReadingsHandler(&mocks.DynamoDBClient{}, &mocks.S3Uploader{}, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"format": "xlsx"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for unknown format, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleExportReadingsRequest_PropertyNotFound(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: nil}, nil
+		},
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, &mocks.S3Uploader{}, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a missing property, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleExportReadingsRequest_Inline(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		QueryFunc:   mockExportQuery,
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, &mocks.S3Uploader{}, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for inline export, got %d", http.StatusOK, response.StatusCode)
+	}
+	if response.Headers["Content-Type"] != "text/csv" || !strings.Contains(response.Body, "p1,Temperature,,12.5") {
+		t.Errorf("Expected inline CSV body, got %s", response.Body)
+	}
+}
+
+func TestHandleExportReadingsRequest_InlineParquet(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		QueryFunc:   mockExportQuery,
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, &mocks.S3Uploader{}, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"format": "parquet"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !response.IsBase64Encoded {
+		t.Errorf("Expected a base64 encoded Parquet body, got %d (base64 %t)", response.StatusCode, response.IsBase64Encoded)
+	}
+}
+
+func TestHandleExportReadingsRequest_UploadError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		QueryFunc:   mockExportQuery,
+	}
+	mockUploader := &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			return nil, errors.New("mock s3 error")
+		},
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, mockUploader, &mocks.S3Presigner{})
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"delivery": "link"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for S3 upload error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleExportReadingsRequest_PresignError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		QueryFunc:   mockExportQuery,
+	}
+	mockUploader := &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			_, err := io.Copy(io.Discard, input.Body)
+			return &manager.UploadOutput{}, err
+		},
+	}
+	mockPresigner := &mocks.S3Presigner{
+		PresignGetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+			return nil, errors.New("mock presign error")
+		},
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, mockUploader, mockPresigner)
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"delivery": "link"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for presign error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleExportReadingsRequest_Link(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockPropertyGetItem("random"),
+		ScanFunc:    mockModelScan,
+		QueryFunc:   mockExportQuery,
+	}
+	var uploaded string
+	mockUploader := &mocks.S3Uploader{
+		UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			body, err := io.ReadAll(input.Body)
+			uploaded = string(body)
+			return &manager.UploadOutput{}, err
+		},
+	}
+	mockPresigner := &mocks.S3Presigner{
+		PresignGetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+			return &v4.PresignedHTTPRequest{URL: "https://example.com/" + *params.Key}, nil
+		},
+	}
+	handler := NewExportReadingsHandler(mockDDBClient, mockUploader, mockPresigner)
+
+	response, err := handler.HandleExportReadingsRequest(context.Background(), exportRequest(map[string]string{"delivery": "link", "format": "jsonl"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for linked export, got %d", http.StatusOK, response.StatusCode)
+	}
+	if !strings.Contains(response.Body, `"url":"https://example.com/exports/`) || !strings.Contains(uploaded, `"value":12.5`) {
+		t.Errorf("Expected the export uploaded and linked, got %s", response.Body)
+	}
+}
+
+func TestInlineLimit_Parquet(t *testing.T) {
+	limit := inlineLimit("parquet")
+	if size := len(inlineExport("parquet", make([]byte, limit)).Body); size > MAXINLINEBYTES {
+		t.Errorf("Expected an encoded body of at most %d bytes at the limit, got %d", MAXINLINEBYTES, size)
+	}
+	if size := len(inlineExport("parquet", make([]byte, limit+3)).Body); size <= MAXINLINEBYTES {
+		t.Errorf("Expected an encoded body over %d bytes past the limit, got %d", MAXINLINEBYTES, size)
+	}
+	if limit := inlineLimit("csv"); limit != MAXINLINEBYTES {
+		t.Errorf("Expected the CSV limit to be %d, got %d", MAXINLINEBYTES, limit)
+	}
+}
+
+func TestSpillWriter_Boundary(t *testing.T) {
+	for _, tc := range []struct {
+		size  int
+		spill bool
+	}{
+		{size: inlineLimit("parquet"), spill: false},
+		{size: inlineLimit("parquet") + 1, spill: true},
+	} {
+		var uploaded int64
+		out := &spillWriter{
+			ctx: context.Background(),
+			uploader: &mocks.S3Uploader{
+				UploadFunc: func(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+					n, err := io.Copy(io.Discard, input.Body)
+					uploaded = n
+					return &manager.UploadOutput{}, err
+				},
+			},
+			key:   "exports/test.parquet",
+			limit: inlineLimit("parquet"),
+		}
+		if _, err := out.Write(make([]byte, tc.size)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		spilled, err := out.finish(false)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if spilled != tc.spill {
+			t.Errorf("Expected %d bytes to spill %t, got %t", tc.size, tc.spill, spilled)
+		}
+		if spilled && uploaded != int64(tc.size) {
+			t.Errorf("Expected %d bytes uploaded, got %d", tc.size, uploaded)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/ingest"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewIngestReadingsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleIngestReadingsRequest writes a batch of device readings. The
+// response lists every rejected point with its reason; it is a 400 only when
+// nothing could be written.
+func (h Handler) HandleIngestReadingsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	var ingestRequest ingest.Request
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &ingestRequest); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	result, err := ingest.New(h.DynamoDB).Ingest(ctx, ingestRequest)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: ingestErrorStatus(err),
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error ingesting readings: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(result)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	status := http.StatusOK
+	if result.Written == 0 {
+		status = http.StatusBadRequest
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+func ingestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, catalog.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ingest.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ingest.ErrTooManyPoints):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
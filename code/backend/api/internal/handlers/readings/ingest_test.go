@@ -0,0 +1,69 @@
+package readings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"wdd/api/internal/ingest"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandleIngestReadingsRequest_BadRequest(t *testing.T) {
+	var written int
+	handler := NewIngestReadingsHandler(mockFactoryCatalog(&written))
+
+	for _, body := range []string{"not json", `{"points":[]}`} {
+		response, err := handler.HandleIngestReadingsRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, body, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleIngestReadingsRequest_TooManyPoints(t *testing.T) {
+	var written int
+	handler := NewIngestReadingsHandler(mockFactoryCatalog(&written))
+
+	body := `{"points":[` + strings.Repeat(`{"propertyId":"p1"},`, ingest.MAXPOINTS) + `{"propertyId":"p1"}]}`
+	response, err := handler.HandleIngestReadingsRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.StatusCode)
+	}
+}
+
+func TestHandleIngestReadingsRequest_Success(t *testing.T) {
+	var written int
+	handler := NewIngestReadingsHandler(mockFactoryCatalog(&written))
+
+	ts := time.Now().UnixMilli()
+	body := fmt.Sprintf(`{"points":[
+		{"propertyId":"p1","timestamp":%d,"value":1.5,"quality":"good"},
+		{"propertyId":"p1","timestamp":%d,"value":2},
+		{"propertyId":"p1","timestamp":%d,"value":3}
+	]}`, ts, ts-1000, ts)
+	response, err := handler.HandleIngestReadingsRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+	if written != 2 {
+		t.Errorf("Expected 2 readings written, got %d", written)
+	}
+	for _, want := range []string{`"written":2`, `"duplicates":1`, `"index":2`, `duplicate of point 0`} {
+		if !strings.Contains(response.Body, want) {
+			t.Errorf("Expected %s in the response, got %s", want, response.Body)
+		}
+	}
+}
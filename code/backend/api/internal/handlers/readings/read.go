@@ -0,0 +1,342 @@
+package readings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/retention"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	MAXRAWREADINGS  = 100000
+	MAXSAMPLEPOINTS = 10000
+)
+
+var (
+	errTooManyReadings   = errors.New("too many readings")
+	errUnknownResolution = errors.New("unknown resolution")
+	errQualityOnRollups  = errors.New("rollups do not keep quality")
+)
+
+type readingsQuery struct {
+	propertyID string
+	factoryID  string
+	resolution string
+	now        time.Time
+	start      time.Time
+	end        time.Time
+	bucket     time.Duration
+	aggregates []string
+	points     int
+	quality    quality.Filter
+}
+
+type readingsResponse struct {
+	PropertyID string              `json:"propertyId"`
+	Start      int64               `json:"start"`
+	End        int64               `json:"end"`
+	Resolution string              `json:"resolution"`
+	Bucket     string              `json:"bucket,omitempty"`
+	Readings   []types.Reading     `json:"readings,omitempty"`
+	Buckets    []timeseries.Bucket `json:"buckets,omitempty"`
+}
+
+func NewReadReadingsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadReadingsRequest returns the readings of a property in a window.
+// With bucket (a duration such as "5m") and aggregates ("min,max,avg") the
+// readings are aggregated per bucket; with points=N they are visually
+// downsampled to at most N points. The resolution is picked from the
+// retention policy of factoryId (or the default policy): raw readings while
+// they are retained, rollups beyond that, unless resolution names a tier.
+// Raw readings are assessed against the property's measurement bounds, and
+// quality ("good,uncertain" or a code such as "bad/sensorFailure") keeps
+// only matching raw readings and cannot be combined with rollups. tz (an
+// IANA zone, or "factory" for the zone of factoryId) adds local times.
+func (h Handler) HandleReadReadingsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	query, err := parseReadingsQuery(request.QueryStringParameters, time.Now())
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	location, err := timezone.Load(ctx, catalog.New(h.DynamoDB), request.QueryStringParameters["tz"], query.factoryID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error loading timezone: %s", err.Error()),
+		}, nil
+	}
+
+	response, err := h.queryReadings(ctx, query)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errTooManyReadings):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, errUnknownResolution), errors.Is(err, errQualityOnRollups):
+			status = http.StatusBadRequest
+		case errors.Is(err, catalog.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error querying readings: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(response)
+	if err == nil {
+		responseBody, err = timezone.Render(responseBody, location)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+func (h Handler) queryReadings(ctx context.Context, query readingsQuery) (readingsResponse, error) {
+	store := timeseries.NewStore(h.DynamoDB)
+	response := readingsResponse{
+		PropertyID: query.propertyID,
+		Start:      query.start.UnixMilli(),
+		End:        query.end.UnixMilli(),
+	}
+
+	tier, err := h.pickTier(ctx, query)
+	if err != nil {
+		return response, err
+	}
+	response.Resolution = tier.Name
+	if tier.Resolution > 0 {
+		if len(query.quality) > 0 {
+			return response, fmt.Errorf("%w: use resolution=raw to filter by quality", errQualityOnRollups)
+		}
+		return queryRollups(ctx, store, query, tier, response)
+	}
+
+	measurement, err := h.measurement(ctx, query.propertyID)
+	if err != nil {
+		return response, err
+	}
+
+	if query.bucket > 0 {
+		agg, err := timeseries.NewAggregator(query.bucket, query.aggregates)
+		if err != nil {
+			return response, err
+		}
+		it := store.Iterate(query.propertyID, query.start, query.end)
+		for it.Next(ctx) {
+			if query.quality.Match(quality.Assess(it.Reading(), measurement)) {
+				agg.Add(it.Reading())
+			}
+		}
+		response.Bucket = query.bucket.String()
+		response.Buckets = agg.Buckets()
+		return response, it.Err()
+	}
+
+	readings, err := store.Query(ctx, query.propertyID, query.start, query.end)
+	if err != nil {
+		return response, err
+	}
+	matching := readings[:0]
+	for _, r := range readings {
+		if r = quality.Assess(r, measurement); query.quality.Match(r) {
+			matching = append(matching, r)
+		}
+	}
+	readings = matching
+	if query.points > 0 {
+		readings = timeseries.Downsample(readings, query.points)
+	} else if len(readings) > MAXRAWREADINGS {
+		return response, fmt.Errorf("%w: %d readings in window, use bucket or points", errTooManyReadings, len(readings))
+	}
+	response.Readings = readings
+	return response, nil
+}
+
+// measurement returns the measurement of a property. Readings of a property
+// that no longer exists are served as they were stored.
+func (h Handler) measurement(ctx context.Context, propertyID string) (types.Measurement, error) {
+	measurement, err := catalog.New(h.DynamoDB).PropertyMeasurement(ctx, propertyID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return types.Measurement{}, nil
+	}
+	return measurement, err
+}
+
+func (h Handler) pickTier(ctx context.Context, query readingsQuery) (retention.Tier, error) {
+	var policy *retention.Policy
+	var err error
+	if query.factoryID != "" {
+		policy, err = retention.Load(ctx, catalog.New(h.DynamoDB), query.factoryID)
+	} else {
+		policy, err = retention.Compile(retention.Default(""))
+	}
+	if err != nil {
+		return retention.Tier{}, err
+	}
+
+	if query.resolution == "" || query.resolution == "auto" {
+		return policy.Pick(query.start, query.now, query.bucket), nil
+	}
+	for _, tier := range policy.Tiers() {
+		if tier.Name == query.resolution {
+			return tier, nil
+		}
+	}
+	return retention.Tier{}, fmt.Errorf("%w %q", errUnknownResolution, query.resolution)
+}
+
+// queryRollups answers a query from a rollup tier. Buckets after the latest
+// rollup, which compaction has not reached yet, are filled from raw readings.
+// Buckets are never finer than the tier; the bucket used is reported.
+func queryRollups(ctx context.Context, store *timeseries.Store, query readingsQuery, tier retention.Tier, response readingsResponse) (readingsResponse, error) {
+	bucket, funcs := query.bucket, query.aggregates
+	if bucket < tier.Resolution {
+		bucket = tier.Resolution
+	}
+	if funcs == nil || query.points > 0 {
+		funcs = []string{timeseries.AVG}
+	}
+	agg, err := timeseries.NewAggregator(bucket, funcs)
+	if err != nil {
+		return response, err
+	}
+
+	compacted := query.start
+	latest, err := store.LatestRollup(ctx, query.propertyID, tier.Name)
+	if err != nil {
+		return response, err
+	}
+	if latest != nil {
+		if next := time.UnixMilli(latest.Timestamp).Add(tier.Resolution); next.After(compacted) {
+			compacted = next
+		}
+	}
+	if compacted.After(query.end) {
+		compacted = query.end
+	}
+
+	if compacted.After(query.start) {
+		rollups := store.IterateRollups(query.propertyID, tier.Name, query.start, compacted)
+		for rollups.Next(ctx) {
+			agg.AddRollup(rollups.Rollup())
+		}
+		if err = rollups.Err(); err != nil {
+			return response, err
+		}
+	}
+	if query.end.After(compacted) {
+		readings := store.Iterate(query.propertyID, compacted, query.end)
+		for readings.Next(ctx) {
+			agg.Add(readings.Reading())
+		}
+		if err = readings.Err(); err != nil {
+			return response, err
+		}
+	}
+
+	buckets := agg.Buckets()
+	if query.points > 0 {
+		readings := make([]types.Reading, len(buckets))
+		for i, b := range buckets {
+			readings[i] = types.Reading{PropertyID: query.propertyID, Timestamp: b.Timestamp, Value: *b.Avg}
+		}
+		response.Readings = timeseries.Downsample(readings, query.points)
+		return response, nil
+	}
+
+	response.Bucket = bucket.String()
+	response.Buckets = buckets
+	return response, nil
+}
+
+func parseReadingsQuery(params map[string]string, now time.Time) (readingsQuery, error) {
+	query := readingsQuery{
+		propertyID: params["propertyId"],
+		factoryID:  params["factoryId"],
+		resolution: params["resolution"],
+		now:        now,
+		end:        now,
+	}
+	if query.propertyID == "" {
+		return query, errors.New("missing propertyId query parameter")
+	}
+
+	var err error
+	if query.quality, err = quality.ParseFilter(params["quality"]); err != nil {
+		return query, err
+	}
+	if params["end"] != "" {
+		if query.end, err = time.Parse(time.RFC3339, params["end"]); err != nil {
+			return query, fmt.Errorf("invalid end parameter: %w", err)
+		}
+	}
+	query.start = query.end.Add(-time.Hour)
+	if params["start"] != "" {
+		if query.start, err = time.Parse(time.RFC3339, params["start"]); err != nil {
+			return query, fmt.Errorf("invalid start parameter: %w", err)
+		}
+	}
+	if !query.end.After(query.start) {
+		return query, errors.New("end must be after start")
+	}
+
+	if params["bucket"] != "" && params["points"] != "" {
+		return query, errors.New("bucket and points cannot be combined")
+	}
+	if params["bucket"] != "" {
+		if query.bucket, err = time.ParseDuration(params["bucket"]); err != nil || query.bucket < time.Millisecond {
+			return query, fmt.Errorf("invalid bucket parameter %q", params["bucket"])
+		}
+		if query.aggregates, err = timeseries.ParseAggregates(params["aggregates"]); err != nil {
+			return query, err
+		}
+	}
+	if params["points"] != "" {
+		if query.points, err = strconv.Atoi(params["points"]); err != nil || query.points < 2 || query.points > MAXSAMPLEPOINTS {
+			return query, fmt.Errorf("points must be between 2 and %d", MAXSAMPLEPOINTS)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,333 @@
+package readings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func mockReadingsQuery(count int) func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+		items := make([]map[string]types.AttributeValue, 0, count)
+		for i := 0; i < count; i++ {
+			item := map[string]types.AttributeValue{
+				"propertyId": &types.AttributeValueMemberS{Value: "p1"},
+				"timestamp":  &types.AttributeValueMemberN{Value: strconv.Itoa(1704067200000 + i*1000)},
+				"value":      &types.AttributeValueMemberN{Value: strconv.Itoa(i % 10)},
+			}
+			if i%10 == 9 {
+				item["quality"] = &types.AttributeValueMemberS{Value: "bad/sensorFailure"}
+			}
+			items = append(items, item)
+		}
+		return &dynamodb.QueryOutput{Items: items}, nil
+	}
+}
+
+// mockMeasurement serves property p1 with a measurement bounded to [0, 7].
+func mockMeasurement(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	switch aws.ToString(params.TableName) {
+	case "Property":
+		return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+			"propertyId":    &types.AttributeValueMemberS{Value: "p1"},
+			"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+		}}, nil
+	case "Measurement":
+		return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+			"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+			"lowerBound":    &types.AttributeValueMemberN{Value: "0"},
+			"upperBound":    &types.AttributeValueMemberN{Value: "7"},
+		}}, nil
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func readRequest(params map[string]string) events.APIGatewayProxyRequest {
+	query := map[string]string{
+		"propertyId": "p1",
+		"start":      "2024-01-01T00:00:00Z",
+		"end":        "2024-01-02T00:00:00Z",
+		"resolution": "raw",
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	return events.APIGatewayProxyRequest{QueryStringParameters: query}
+}
+
+func TestHandleReadReadingsRequest_InvalidParameters(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{})
+
+	cases := []map[string]string{
+		{"propertyId": ""},
+		{"start": "yesterday"},
+		{"start": "2024-01-03T00:00:00Z"},
+		{"bucket": "fortnight"},
+		{"bucket": "1m", "aggregates": "median"},
+		{"points": "1"},
+		{"bucket": "1m", "points": "100"},
+		{"resolution": "5m"},
+		{"quality": "fine"},
+		{"quality": "good", "resolution": "1h"},
+		{"tz": "Mars/Olympus_Mons"},
+		{"tz": "factory"},
+	}
+
+	for _, params := range cases {
+		response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(params))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %v, got %d", http.StatusBadRequest, params, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleReadReadingsRequest_QueryError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockMeasurement,
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewReadReadingsHandler(mockDDBClient)
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB query error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleReadReadingsRequest_JSONMarshalError(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{GetItemFunc: mockMeasurement, QueryFunc: mockReadingsQuery(10)})
+
+	originalJSONMarshal := wrappers.JSONMarshal
+
+	defer func() { wrappers.JSONMarshal = originalJSONMarshal }()
+
+	wrappers.JSONMarshal = func(v interface{}) ([]byte, error) {
+		return nil, errors.New("mock marshal error")
+	}
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for marshalling readings in JSON format, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleReadReadingsRequest_Raw(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{GetItemFunc: mockMeasurement, QueryFunc: mockReadingsQuery(120)})
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a readings response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Readings) != 120 {
+		t.Errorf("Expected 120 raw readings, got %d", len(body.Readings))
+	}
+}
+
+func TestHandleReadReadingsRequest_Timezone(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{
+		QueryFunc: mockReadingsQuery(2),
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if aws.ToString(params.TableName) != "Factory" {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+				"location": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"latitude":  &types.AttributeValueMemberN{Value: "35.7"},
+					"longitude": &types.AttributeValueMemberN{Value: "139.7"},
+				}},
+			}}, nil
+		},
+	})
+
+	for _, params := range []map[string]string{{"tz": "Asia/Tokyo"}, {"tz": "factory", "factoryId": "f1"}} {
+		response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(params))
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected a readings response for %v, got %d %s", params, response.StatusCode, response.Body)
+		}
+		if !strings.Contains(response.Body, `"timestampLocal":"2024-01-01T09:00:01.000+09:00"`) {
+			t.Errorf("Expected readings in Tokyo time for %v, got %s", params, response.Body)
+		}
+	}
+}
+
+func TestHandleReadReadingsRequest_QualityFilter(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{GetItemFunc: mockMeasurement, QueryFunc: mockReadingsQuery(120)})
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"quality": "bad"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a readings response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Readings) != 12 || body.Readings[0].Quality != "bad/sensorFailure" {
+		t.Errorf("Expected the 12 bad readings, got %s", response.Body)
+	}
+
+	response, err = handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"quality": "good", "bucket": "1m", "aggregates": "max,count"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body = readingsResponse{}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a bucketed response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Buckets) != 2 || *body.Buckets[0].Count != 48 || *body.Buckets[0].Max != 7 {
+		t.Errorf("Expected buckets of the good readings within bounds only, got %s", response.Body)
+	}
+
+	// Stored readings are flagged against the bounds on the way out.
+	response, err = handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"quality": "uncertain"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body = readingsResponse{}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a readings response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Readings) != 12 || body.Readings[0].Value != 8 || body.Readings[0].Quality != "uncertain/engineeringUnitsExceeded" {
+		t.Errorf("Expected the 12 readings above the upper bound flagged, got %s", response.Body)
+	}
+}
+
+func TestHandleReadReadingsRequest_Bucketed(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{GetItemFunc: mockMeasurement, QueryFunc: mockReadingsQuery(120)})
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"bucket": "1m", "aggregates": "min,max,count"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a bucketed response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Buckets) != 2 || *body.Buckets[0].Count != 60 || *body.Buckets[0].Max != 9 || body.Buckets[0].Avg != nil {
+		t.Errorf("Expected two one-minute buckets of 60 readings, got %s", response.Body)
+	}
+}
+
+func TestHandleReadReadingsRequest_Downsampled(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{GetItemFunc: mockMeasurement, QueryFunc: mockReadingsQuery(1000)})
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"points": "100"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a downsampled response, got %d %s", response.StatusCode, response.Body)
+	}
+	if len(body.Readings) != 100 {
+		t.Errorf("Expected 100 points, got %d", len(body.Readings))
+	}
+}
+
+// mockRollupsQuery serves two hour rollups from midnight, the second being
+// the latest, and a raw reading at 02:00.
+func mockRollupsQuery(t *testing.T) func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+		rollup := func(hour int) map[string]types.AttributeValue {
+			return map[string]types.AttributeValue{
+				"seriesId":   &types.AttributeValueMemberS{Value: "p1#1h"},
+				"propertyId": &types.AttributeValueMemberS{Value: "p1"},
+				"resolution": &types.AttributeValueMemberS{Value: "1h"},
+				"timestamp":  &types.AttributeValueMemberN{Value: strconv.Itoa(1704067200000 + hour*3600000)},
+				"count":      &types.AttributeValueMemberN{Value: "3600"},
+				"min":        &types.AttributeValueMemberN{Value: "1"},
+				"max":        &types.AttributeValueMemberN{Value: "3"},
+				"avg":        &types.AttributeValueMemberN{Value: "2"},
+			}
+		}
+		switch {
+		case *params.TableName == "Reading":
+			// Compaction has reached 02:00, so the rest comes from raw readings.
+			if params.ExpressionAttributeValues[":start"].(*types.AttributeValueMemberN).Value != "1704074400000" {
+				t.Errorf("Expected raw readings to be read from the latest rollup on")
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+				"propertyId": &types.AttributeValueMemberS{Value: "p1"},
+				"timestamp":  &types.AttributeValueMemberN{Value: "1704074400000"},
+				"value":      &types.AttributeValueMemberN{Value: "10"},
+			}}}, nil
+		case params.ScanIndexForward != nil:
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{rollup(1)}}, nil
+		default:
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{rollup(0), rollup(1)}}, nil
+		}
+	}
+}
+
+func TestHandleReadReadingsRequest_Rollups(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{QueryFunc: mockRollupsQuery(t)}
+	handler := NewReadReadingsHandler(mockDDBClient)
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"resolution": "", "bucket": "2h", "aggregates": "count,max"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a bucketed response, got %d %s", response.StatusCode, response.Body)
+	}
+	if body.Resolution != "1h" {
+		t.Errorf("Expected hour rollups for a query past raw retention, got %s", body.Resolution)
+	}
+	if len(body.Buckets) != 2 || *body.Buckets[0].Count != 7200 || *body.Buckets[1].Count != 1 || *body.Buckets[1].Max != 10 {
+		t.Errorf("Expected a bucket of two rollups and a bucket of raw readings, got %s", response.Body)
+	}
+}
+
+func TestHandleReadReadingsRequest_RollupsFinerBucket(t *testing.T) {
+	handler := NewReadReadingsHandler(&mocks.DynamoDBClient{QueryFunc: mockRollupsQuery(t)})
+
+	response, err := handler.HandleReadReadingsRequest(context.Background(), readRequest(map[string]string{"resolution": "1h", "bucket": "5m", "aggregates": "count"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var body readingsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a bucketed response, got %d %s", response.StatusCode, response.Body)
+	}
+	if body.Bucket != "1h0m0s" {
+		t.Errorf("Expected the bucket to be raised to the rollup resolution, got %s", body.Bucket)
+	}
+	if len(body.Buckets) != 3 || *body.Buckets[0].Count != 3600 || *body.Buckets[2].Count != 1 {
+		t.Errorf("Expected a bucket per rollup and one of raw readings, got %s", response.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package readings
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	BUCKETNAME          = "wingstopdrivenbucket"
+	MAXBACKFILLREADINGS = 500000
+	MAXINLINEBYTES      = 5 << 20
+)
+
+type Handler struct {
+	DynamoDB    types.DynamoDBClient
+	S3Uploader  types.S3Uploader
+	S3Presigner types.S3Presigner
+}
+
+// spillWriter keeps output in memory up to a limit and, once it grows past it,
+// streams everything written so far and afterwards into an S3 upload.
+type spillWriter struct {
+	ctx         context.Context
+	uploader    types.S3Uploader
+	key         string
+	contentType string
+	limit       int
+	size        int64
+	buf         bytes.Buffer
+	pipe        *io.PipeWriter
+	done        chan error
+}
+
+func (w *spillWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	if w.pipe == nil && w.buf.Len()+len(p) <= w.limit {
+		return w.buf.Write(p)
+	}
+	if err := w.spill(); err != nil {
+		return 0, err
+	}
+	return w.pipe.Write(p)
+}
+
+// finish completes the upload if one was started, or starts one when force is
+// set, and reports whether the output ended up in S3.
+func (w *spillWriter) finish(force bool) (bool, error) {
+	if w.pipe == nil && !force {
+		return false, nil
+	}
+	if err := w.spill(); err != nil {
+		return true, err
+	}
+	if err := w.pipe.Close(); err != nil {
+		return true, err
+	}
+	return true, <-w.done
+}
+
+func (w *spillWriter) abort(err error) {
+	if w.pipe != nil {
+		w.pipe.CloseWithError(err)
+		<-w.done
+	}
+}
+
+func (w *spillWriter) spill() error {
+	if w.pipe != nil {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	w.pipe = writer
+	w.done = make(chan error, 1)
+	go func() {
+		_, err := w.uploader.Upload(w.ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(BUCKETNAME),
+			Key:         aws.String(w.key),
+			Body:        reader,
+			ContentType: aws.String(w.contentType),
+		})
+		reader.CloseWithError(err)
+		w.done <- err
+	}()
+
+	_, err := w.pipe.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
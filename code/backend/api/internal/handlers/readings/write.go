@@ -0,0 +1,208 @@
+package readings
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/lineprotocol"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// MAXLINEPROTOCOLBYTES caps a write body once decompressed.
+	MAXLINEPROTOCOLBYTES = 25 << 20
+	// MAXREJECTIONS caps how many rejections a partial write reports.
+	MAXREJECTIONS = 100
+)
+
+type writeResponse struct {
+	Error      string                   `json:"error"`
+	Written    int                      `json:"written"`
+	Rejected   int                      `json:"rejected"`
+	Rejections []lineprotocol.Rejection `json:"rejections"`
+}
+
+func NewWriteLineProtocolHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleWriteLineProtocolRequest stores readings sent as InfluxDB line
+// protocol. The factory comes from factoryId, or from db or bucket so that
+// Telegraf and InfluxDB clients can write unchanged. Like InfluxDB, it
+// answers 204 when every point was written and 400 on a partial write, in
+// which case the points that could be mapped are still stored.
+func (h Handler) HandleWriteLineProtocolRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+	params := request.QueryStringParameters
+
+	factoryID := params["factoryId"]
+	for _, name := range []string{"db", "bucket"} {
+		if factoryID == "" {
+			factoryID = params[name]
+		}
+	}
+	if factoryID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing factoryId query parameter",
+		}, nil
+	}
+
+	precision, err := lineprotocol.ParsePrecision(params["precision"])
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	body, err := writeBody(request)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading body: %s", err.Error()),
+		}, nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	if _, err = c.Factory(ctx, factoryID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding factory: %s", err.Error()),
+		}, nil
+	}
+
+	mapping, err := c.LineProtocolMapping(ctx, factoryID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		mapping, err = lineprotocol.Default(factoryID), nil
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding line protocol mapping: %s", err.Error()),
+		}, nil
+	}
+
+	series, err := c.FactorySeries(ctx, factoryID)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding factory properties: %s", err.Error()),
+		}, nil
+	}
+
+	points, lineErrors := lineprotocol.Parse(body, precision, time.Now())
+	readings, rejections := lineprotocol.NewResolver(mapping, series).Resolve(points)
+	for _, e := range lineErrors {
+		rejections = append(rejections, lineprotocol.Rejection{Line: e.Line, Reason: e.Reason})
+	}
+
+	if err = timeseries.NewStore(h.DynamoDB).Write(ctx, readings); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error writing readings: %s", err.Error()),
+		}, nil
+	}
+
+	if len(rejections) == 0 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNoContent,
+			Headers:    headers,
+		}, nil
+	}
+
+	sort.SliceStable(rejections, func(i, j int) bool { return rejections[i].Line < rejections[j].Line })
+	response := writeResponse{
+		Error:      fmt.Sprintf("partial write: %d rejected, first at line %d: %s", len(rejections), rejections[0].Line, rejections[0].Reason),
+		Written:    len(readings),
+		Rejected:   len(rejections),
+		Rejections: rejections,
+	}
+	if len(response.Rejections) > MAXREJECTIONS {
+		response.Rejections = response.Rejections[:MAXREJECTIONS]
+	}
+
+	responseBody, err := wrappers.JSONMarshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusBadRequest,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+var errBodyTooLarge = fmt.Errorf("body exceeds %d bytes", MAXLINEPROTOCOLBYTES)
+
+// writeBody decodes the request body, which API Gateway may have base64
+// encoded and the client may have gzipped.
+func writeBody(request events.APIGatewayProxyRequest) ([]byte, error) {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+
+	var r io.Reader = bytes.NewReader(body)
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, "Content-Encoding") && strings.EqualFold(strings.TrimSpace(value), "gzip") {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			r = gz
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, MAXLINEPROTOCOLBYTES+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MAXLINEPROTOCOLBYTES {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
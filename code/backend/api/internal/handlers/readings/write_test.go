@@ -0,0 +1,150 @@
+package readings
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockFactoryCatalog serves factory f1 with asset a1 ("boiler") whose model
+// has property p1 ("Temperature"), and counts the readings written.
+func mockFactoryCatalog(written *int) *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch *params.TableName {
+			case "Factory":
+				if params.Key["factoryId"].(*types.AttributeValueMemberS).Value == "f1" {
+					return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+						"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+					}}, nil
+				}
+			case "Property":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"propertyId":    &types.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+					"name":          &types.AttributeValueMemberS{Value: "Temperature"},
+				}}, nil
+			case "Measurement":
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+				}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			switch *params.TableName {
+			case "Asset":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"assetId":   &types.AttributeValueMemberS{Value: "a1"},
+					"factoryId": &types.AttributeValueMemberS{Value: "f1"},
+					"name":      &types.AttributeValueMemberS{Value: "boiler"},
+					"modelId":   &types.AttributeValueMemberS{Value: "m1"},
+				}}}, nil
+			case "Model":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    &types.AttributeValueMemberS{Value: "m1"},
+					"properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "p1"}}},
+				}}}, nil
+			}
+			return &dynamodb.QueryOutput{}, nil
+		},
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			*written += len(params.RequestItems["Reading"])
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+}
+
+func TestHandleWriteLineProtocolRequest_BadRequest(t *testing.T) {
+	var written int
+	handler := NewWriteLineProtocolHandler(mockFactoryCatalog(&written))
+
+	for _, params := range []map[string]string{
+		{},
+		{"db": "f1", "precision": "d"},
+	} {
+		response, err := handler.HandleWriteLineProtocolRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %v, got %d", http.StatusBadRequest, params, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleWriteLineProtocolRequest_FactoryNotFound(t *testing.T) {
+	var written int
+	handler := NewWriteLineProtocolHandler(mockFactoryCatalog(&written))
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "missing"},
+		Body:                  "boiler Temperature=1",
+	}
+	response, err := handler.HandleWriteLineProtocolRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleWriteLineProtocolRequest_Success(t *testing.T) {
+	var written int
+	handler := NewWriteLineProtocolHandler(mockFactoryCatalog(&written))
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte("sensors,asset=a1 p1=20.5 1700000000\nboiler Temperature=21i 1700000001\n"))
+	gz.Close()
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"bucket": "f1", "precision": "s"},
+		Headers:               map[string]string{"content-encoding": "gzip"},
+		Body:                  body.String(),
+	}
+	response, err := handler.HandleWriteLineProtocolRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusNoContent, response.StatusCode, response.Body)
+	}
+	if written != 2 {
+		t.Errorf("Expected 2 readings written, got %d", written)
+	}
+}
+
+func TestHandleWriteLineProtocolRequest_PartialWrite(t *testing.T) {
+	var written int
+	handler := NewWriteLineProtocolHandler(mockFactoryCatalog(&written))
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "f1"},
+		Body:                  "boiler Temperature=20,Pressure=1\nboiler Temperature=\nchiller Temperature=3\n",
+	}
+	response, err := handler.HandleWriteLineProtocolRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+	if written != 1 {
+		t.Errorf("Expected the mapped reading to be written, got %d", written)
+	}
+	for _, want := range []string{`"written":1`, `"rejected":3`, `"unknown property \"Pressure\""`, `"unknown asset \"chiller\""`, `"line":2`} {
+		if !strings.Contains(response.Body, want) {
+			t.Errorf("Expected %s in the response, got %s", want, response.Body)
+		}
+	}
+}
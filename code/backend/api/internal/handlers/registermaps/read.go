@@ -0,0 +1,84 @@
+package registermaps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/modbus"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadRegisterMapHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadRegisterMapRequest returns the Modbus register map of a factory,
+// asset or property as CSV. The map is derived from the catalog, so it matches
+// what `wdd modbus` serves for the same target and table.
+func (h Handler) HandleReadRegisterMapRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "text/csv",
+		"Content-Disposition":         "attachment; filename=\"registers.csv\"",
+	}
+
+	targets := 0
+	for _, id := range []string{params["factoryId"], params["assetId"], params["propertyId"]} {
+		if id != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Exactly one of factoryId, assetId or propertyId is required",
+		}, nil
+	}
+
+	series, err := catalog.New(h.DynamoDB).Series(ctx, params["factoryId"], params["assetId"], params["propertyId"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, catalog.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error resolving properties: %s", err),
+		}, nil
+	}
+
+	registers, err := modbus.BuildRegisterMap(series, params["table"])
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       err.Error(),
+		}, nil
+	}
+
+	var body bytes.Buffer
+	if err = registers.WriteCSV(&body); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error writing register map: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       body.String(),
+	}, nil
+}
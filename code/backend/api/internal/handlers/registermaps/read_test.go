@@ -0,0 +1,93 @@
+package registermaps
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func mockPropertyClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if aws.ToString(params.TableName) == "Property" {
+				if params.Key["propertyId"].(*types.AttributeValueMemberS).Value != "p1" {
+					return &dynamodb.GetItemOutput{}, nil
+				}
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"propertyId":    &types.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+					"name":          &types.AttributeValueMemberS{Value: "Speed"},
+					"unit":          &types.AttributeValueMemberS{Value: "rpm"},
+				}}, nil
+			}
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"measurementId": &types.AttributeValueMemberS{Value: "m1"},
+				"precision":     &types.AttributeValueMemberN{Value: "0.5"},
+				"lowerBound":    &types.AttributeValueMemberN{Value: "0"},
+				"upperBound":    &types.AttributeValueMemberN{Value: "100"},
+			}}, nil
+		},
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{}, nil
+		},
+	}
+}
+
+func TestHandleReadRegisterMapRequest_InvalidParams(t *testing.T) {
+	handler := NewReadRegisterMapHandler(mockPropertyClient())
+
+	for _, params := range []map[string]string{
+		{},
+		{"factoryId": "f1", "assetId": "a1"},
+		{"propertyId": "p1", "table": "coils"},
+	} {
+		response, err := handler.HandleReadRegisterMapRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %v, got %d", http.StatusBadRequest, params, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleReadRegisterMapRequest_NotFound(t *testing.T) {
+	handler := NewReadRegisterMapHandler(mockPropertyClient())
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"propertyId": "missing"}}
+	response, err := handler.HandleReadRegisterMapRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestHandleReadRegisterMapRequest_Success(t *testing.T) {
+	handler := NewReadRegisterMapHandler(mockPropertyClient())
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"propertyId": "p1", "table": "input"}}
+	response, err := handler.HandleReadRegisterMapRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+	if response.Headers["Content-Type"] != "text/csv" {
+		t.Errorf("Expected a CSV response, got %q", response.Headers["Content-Type"])
+	}
+
+	lines := strings.Split(strings.TrimSpace(response.Body), "\n")
+	if len(lines) != 2 || lines[1] != "1,,,p1,Speed,input,0,1,int16,0.5,rpm" {
+		t.Errorf("Unexpected register map:\n%s", response.Body)
+	}
+}
@@ -0,0 +1,9 @@
+package registermaps
+
+import (
+	"wdd/api/internal/types"
+)
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
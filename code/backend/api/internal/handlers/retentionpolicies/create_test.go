@@ -0,0 +1,91 @@
+package retentionpolicies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func TestHandleCreateRetentionPolicyRequest_BadJSON(t *testing.T) {
+	handler := NewCreateRetentionPolicyHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","rawDays":"week"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for bad JSON, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateRetentionPolicyRequest_MissingFactoryID(t *testing.T) {
+	handler := NewCreateRetentionPolicyHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"rawDays":3}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateRetentionPolicyRequest_InvalidPolicy(t *testing.T) {
+	handler := NewCreateRetentionPolicyHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleCreateRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","rollups":[{"resolution":"1h"},{"resolution":"1m"}]}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for decreasing resolutions, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleCreateRetentionPolicyRequest_PutItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewCreateRetentionPolicyHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1"}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB PutItem error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleCreateRetentionPolicyRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateRetentionPolicyHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{Body: `{"factoryId":"1","rawDays":3}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	if !strings.Contains(response.Body, `"rawDays":3`) || !strings.Contains(response.Body, `"resolution":"1h"`) {
+		t.Errorf("Expected the effective policy with default rollups, got %s", response.Body)
+	}
+}
@@ -0,0 +1,68 @@
+package retentionpolicies
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+)
+
+func TestHandleDeleteRetentionPolicyRequest_MissingFactoryID(t *testing.T) {
+	handler := NewDeleteRetentionPolicyHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleDeleteRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleDeleteRetentionPolicyRequest_DeleteItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewDeleteRetentionPolicyHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1"},
+	}
+
+	response, err := handler.HandleDeleteRetentionPolicyRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB delete item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleDeleteRetentionPolicyRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+	handler := NewDeleteRetentionPolicyHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"factoryId": "1"},
+	}
+
+	response, err := handler.HandleDeleteRetentionPolicyRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for successful deletion, got %d", http.StatusOK, response.StatusCode)
+	}
+}
@@ -0,0 +1,88 @@
+package retentionpolicies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleReadRetentionPolicyRequest_MissingFactoryID(t *testing.T) {
+	handler := NewReadRetentionPolicyHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleReadRetentionPolicyRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing factoryId, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleReadRetentionPolicyRequest_GetItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewReadRetentionPolicyHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadRetentionPolicyRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB GetItem error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleReadRetentionPolicyRequest_Default(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+	handler := NewReadRetentionPolicyHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadRetentionPolicyRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"rawDays":7`) {
+		t.Errorf("Expected the default policy, got %d %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleReadRetentionPolicyRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"factoryId": &types.AttributeValueMemberS{Value: "1"},
+				"rawDays":   &types.AttributeValueMemberN{Value: "2"},
+				"rollups":   &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			}}, nil
+		},
+	}
+	handler := NewReadRetentionPolicyHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"factoryId": "1"}}
+	response, err := handler.HandleReadRetentionPolicyRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(response.Body, `"rawDays":2`) || strings.Contains(response.Body, "resolution") {
+		t.Errorf("Expected the stored policy without rollups, got %d %s", response.StatusCode, response.Body)
+	}
+}
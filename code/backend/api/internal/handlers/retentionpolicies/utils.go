@@ -0,0 +1,11 @@
+package retentionpolicies
+
+import (
+	"wdd/api/internal/types"
+)
+
+const TABLENAME = "RetentionPolicy"
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
@@ -0,0 +1,86 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+	"wdd/api/internal/zone"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadZoneAssetsHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadZoneAssetsRequest returns the assets sitting in a zone.
+func (h Handler) HandleReadZoneAssetsRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	zoneID := request.QueryStringParameters["id"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if zoneID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing id query parameter",
+		}, nil
+	}
+
+	c := catalog.New(h.DynamoDB)
+	z, err := c.Zone(ctx, zoneID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Zone with ID %s not found", zoneID),
+		}, nil
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error finding zone: %s", err),
+		}, nil
+	}
+
+	assets, err := zone.Assets(ctx, c, z)
+	if err == nil {
+		err = calibration.Locate(ctx, c, assets)
+	}
+	if err == nil {
+		err = zone.Locate(ctx, c, assets)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error reading zone assets: %s", err),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(assets)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
@@ -0,0 +1,65 @@
+package zones
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"net/http"
+	"reflect"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
+)
+
+func TestHandleReadZoneAssetsRequest(t *testing.T) {
+	asset := func(id, x string) map[string]ddbtypes.AttributeValue {
+		return map[string]ddbtypes.AttributeValue{
+			"assetId":     &ddbtypes.AttributeValueMemberS{Value: id},
+			"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+			"floorplanCoords": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+				"longitude": &ddbtypes.AttributeValueMemberN{Value: x},
+				"latitude":  &ddbtypes.AttributeValueMemberN{Value: "25"},
+			}},
+		}
+	}
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetItem,
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			if aws.ToString(params.TableName) == "Asset" {
+				return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{asset("a1", "20"), asset("a2", "80")}}, nil
+			}
+			zone, _ := mockGetItem(ctx, &dynamodb.GetItemInput{Key: map[string]ddbtypes.AttributeValue{"zoneId": &ddbtypes.AttributeValueMemberS{Value: "z1"}}})
+			return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{zone.Item}}, nil
+		},
+		BatchGetItemFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			return &dynamodb.BatchGetItemOutput{}, nil
+		},
+	}
+	handler := NewReadZoneAssetsHandler(mockDDBClient)
+
+	response, err := handler.HandleReadZoneAssetsRequest(context.Background(), events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"id": "z1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var assets []types.Asset
+	if err := json.Unmarshal([]byte(response.Body), &assets); err != nil || len(assets) != 1 || assets[0].AssetID != "a1" {
+		t.Fatalf("Expected only a1 in the zone, got %d %s", response.StatusCode, response.Body)
+	}
+	if !reflect.DeepEqual(assets[0].Zones, []string{"z1"}) {
+		t.Errorf("Expected a1 to report zone z1, got %v", assets[0].Zones)
+	}
+
+	for params, status := range map[string]int{"": http.StatusBadRequest, "z9": http.StatusNotFound} {
+		response, _ := handler.HandleReadZoneAssetsRequest(context.Background(), events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{"id": params},
+		})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for id %q, got %d", status, params, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/google/uuid"
+)
+
+func NewCreateZoneHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+func (h Handler) HandleCreateZoneRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var zone types.Zone
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &zone); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	if zone.FloorplanID == "" || zone.Name == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing floorplanId or name in request body",
+		}, nil
+	}
+
+	if err := h.validatePolygon(ctx, zone); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, validation.ErrInvalid) {
+			status = http.StatusBadRequest
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       validation.Body(err),
+		}, nil
+	}
+
+	zone.ZoneID = uuid.NewString()
+	// A new polygon is drawn on the floorplan's current image.
+	zone.FloorplanVersion = 0
+
+	av, err := wrappers.MarshalMap(zone)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling zone to DynamoDB format: %s", err.Error()),
+		}, nil
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(TABLENAME),
+	}
+
+	if _, err = h.DynamoDB.PutItem(ctx, input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error putting item into DynamoDB: %s", err.Error()),
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(zone)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response body: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
+
+// validatePolygon checks a zone outline against the bounds of its
+// floorplan, which must exist.
+func (h Handler) validatePolygon(ctx context.Context, zone types.Zone) error {
+	floorplan, err := catalog.New(h.DynamoDB).Floorplan(ctx, zone.FloorplanID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		return validation.Invalid("floorplanId", fmt.Sprintf("floorplan %s does not exist", zone.FloorplanID))
+	}
+	if err != nil {
+		return err
+	}
+	return validation.Polygon(zone.Polygon, &floorplan)
+}
@@ -0,0 +1,112 @@
+package zones
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
+)
+
+const validZoneBody = `{"floorplanId":"f1","name":"Packaging Line 2","type":"line","polygon":[{"x":10,"y":10},{"x":90,"y":10},{"x":90,"y":40},{"x":10,"y":40}]}`
+
+// mockGetItem finds floorplan f1 of 100x50 pixels and zone z1, its left
+// half.
+func mockGetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if key, ok := params.Key["floorplanId"].(*ddbtypes.AttributeValueMemberS); ok && key.Value == "f1" {
+		return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+			"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+			"width":       &ddbtypes.AttributeValueMemberN{Value: "100"},
+			"height":      &ddbtypes.AttributeValueMemberN{Value: "50"},
+		}}, nil
+	}
+	if key, ok := params.Key["zoneId"].(*ddbtypes.AttributeValueMemberS); ok && key.Value == "z1" {
+		vertex := func(x, y string) ddbtypes.AttributeValue {
+			return &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+				"x": &ddbtypes.AttributeValueMemberN{Value: x},
+				"y": &ddbtypes.AttributeValueMemberN{Value: y},
+			}}
+		}
+		return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+			"zoneId":      &ddbtypes.AttributeValueMemberS{Value: "z1"},
+			"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+			"name":        &ddbtypes.AttributeValueMemberS{Value: "Left"},
+			"polygon":     &ddbtypes.AttributeValueMemberL{Value: []ddbtypes.AttributeValue{vertex("0", "0"), vertex("50", "0"), vertex("50", "50"), vertex("0", "50")}},
+		}}, nil
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func TestHandleCreateZoneRequest_Success(t *testing.T) {
+	var put *dynamodb.PutItemInput
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetItem,
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			put = params
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+	handler := NewCreateZoneHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateZoneRequest(context.Background(), events.APIGatewayProxyRequest{Body: validZoneBody})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for successful create, got %d: %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+	var zone types.Zone
+	if err := json.Unmarshal([]byte(response.Body), &zone); err != nil || zone.ZoneID == "" || len(zone.Polygon) != 4 {
+		t.Errorf("Expected the zone with a new ID, got %s", response.Body)
+	}
+	if id, ok := put.Item["zoneId"].(*ddbtypes.AttributeValueMemberS); !ok || id.Value != zone.ZoneID {
+		t.Errorf("Expected the zone to be stored, got %v", put.Item)
+	}
+}
+
+func TestHandleCreateZoneRequest_Invalid(t *testing.T) {
+	handler := NewCreateZoneHandler(&mocks.DynamoDBClient{GetItemFunc: mockGetItem})
+
+	cases := map[string]string{
+		"bad JSON":          `{"polygon":"square"}`,
+		"missing name":      `{"floorplanId":"f1","polygon":[{"x":0,"y":0},{"x":1,"y":0},{"x":1,"y":1}]}`,
+		"unknown floorplan": `{"floorplanId":"f9","name":"A","polygon":[{"x":0,"y":0},{"x":1,"y":0},{"x":1,"y":1}]}`,
+		"outside the image": `{"floorplanId":"f1","name":"A","polygon":[{"x":0,"y":0},{"x":200,"y":0},{"x":1,"y":1}]}`,
+		"crossing itself":   `{"floorplanId":"f1","name":"A","polygon":[{"x":0,"y":0},{"x":10,"y":10},{"x":10,"y":0},{"x":0,"y":10}]}`,
+	}
+	for name, body := range cases {
+		response, err := handler.HandleCreateZoneRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, name, response.StatusCode)
+		}
+	}
+}
+
+func TestHandleCreateZoneRequest_PutItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetItem,
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewCreateZoneHandler(mockDDBClient)
+
+	response, err := handler.HandleCreateZoneRequest(context.Background(), events.APIGatewayProxyRequest{Body: validZoneBody})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB put item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
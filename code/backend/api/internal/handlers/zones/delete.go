@@ -0,0 +1,57 @@
+package zones
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewDeleteZoneHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+func (h Handler) HandleDeleteZoneRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	zoneID := request.QueryStringParameters["id"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if zoneID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing zone 'id' in query string parameters.",
+		}, nil
+	}
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(TABLENAME),
+		Key: map[string]ddbtypes.AttributeValue{
+			"zoneId": &ddbtypes.AttributeValueMemberS{Value: zoneID},
+		},
+	}
+
+	if _, err := h.DynamoDB.DeleteItem(ctx, input); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error deleting item in DynamoDB: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       fmt.Sprintf("zoneId %s deleted successfully", zoneID),
+	}, nil
+}
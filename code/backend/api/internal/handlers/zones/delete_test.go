@@ -0,0 +1,68 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+)
+
+func TestHandleDeleteZoneRequest_MissingID(t *testing.T) {
+	handler := NewDeleteZoneHandler(&mocks.DynamoDBClient{})
+
+	response, err := handler.HandleDeleteZoneRequest(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for missing id, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestHandleDeleteZoneRequest_DeleteItemError(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			return nil, errors.New("mock dynamodb error")
+		},
+	}
+	handler := NewDeleteZoneHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"id": "z1"},
+	}
+
+	response, err := handler.HandleDeleteZoneRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for DynamoDB delete item error, got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestHandleDeleteZoneRequest_Success(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+	handler := NewDeleteZoneHandler(mockDDBClient)
+
+	request := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"id": "z1"},
+	}
+
+	response, err := handler.HandleDeleteZoneRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d for successful delete, got %d", http.StatusOK, response.StatusCode)
+	}
+}
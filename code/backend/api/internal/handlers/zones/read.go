@@ -0,0 +1,86 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func NewReadZoneHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleReadZoneRequest returns a zone by id, or every zone of a floorplan.
+func (h Handler) HandleReadZoneRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	zoneID := request.QueryStringParameters["id"]
+	floorplanID := request.QueryStringParameters["floorplanId"]
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	c := catalog.New(h.DynamoDB)
+	var body interface{}
+	switch {
+	case zoneID != "":
+		zone, err := c.Zone(ctx, zoneID)
+		if errors.Is(err, catalog.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Zone with ID %s not found", zoneID),
+			}, nil
+		}
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error finding zone: %s", err),
+			}, nil
+		}
+		body = zone
+	case floorplanID != "":
+		zones, err := c.FloorplanZones(ctx, floorplanID)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Error querying zones: %s", err),
+			}, nil
+		}
+		if zones == nil {
+			zones = []types.Zone{}
+		}
+		body = zones
+	default:
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing id or floorplanId query parameter",
+		}, nil
+	}
+
+	responseBody, err := wrappers.JSONMarshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error marshalling response: %s", err),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(responseBody),
+	}, nil
+}
@@ -0,0 +1,52 @@
+package zones
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
+)
+
+func TestHandleReadZoneRequest(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetItem,
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}
+	handler := NewReadZoneHandler(mockDDBClient)
+
+	response, err := handler.HandleReadZoneRequest(context.Background(), events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"id": "z1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var zone types.Zone
+	if err := json.Unmarshal([]byte(response.Body), &zone); err != nil || zone.ZoneID != "z1" || len(zone.Polygon) != 4 {
+		t.Errorf("Expected zone z1, got %d %s", response.StatusCode, response.Body)
+	}
+
+	response, _ = handler.HandleReadZoneRequest(context.Background(), events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"floorplanId": "f1"},
+	})
+	if response.StatusCode != http.StatusOK || response.Body != "[]" {
+		t.Errorf("Expected an empty list of zones, got %d %s", response.StatusCode, response.Body)
+	}
+
+	cases := map[string]map[string]string{
+		"missing parameters": {},
+		"unknown zone":       {"id": "z9"},
+	}
+	expected := map[string]int{"missing parameters": http.StatusBadRequest, "unknown zone": http.StatusNotFound}
+	for name, params := range cases {
+		response, _ := handler.HandleReadZoneRequest(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: params})
+		if response.StatusCode != expected[name] {
+			t.Errorf("Expected status code %d for %s, got %d", expected[name], name, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package zones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+	"wdd/api/internal/validation"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func NewUpdateZoneHandler(db types.DynamoDBClient) *Handler {
+	return &Handler{
+		DynamoDB: db,
+	}
+}
+
+// HandleUpdateZoneRequest renames, retypes or reshapes a zone. A zone stays
+// on the floorplan it was created on.
+func (h Handler) HandleUpdateZoneRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var zone types.Zone
+
+	headers := map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"Content-Type":                "application/json",
+	}
+
+	if err := wrappers.JSONUnmarshal([]byte(request.Body), &zone); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error parsing JSON body: %s", err.Error()),
+		}, nil
+	}
+
+	if zone.ZoneID == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Missing zoneId in request body",
+		}, nil
+	}
+
+	var update expression.UpdateBuilder
+	changed := false
+	if zone.Name != "" {
+		update = update.Set(expression.Name("name"), expression.Value(zone.Name))
+		changed = true
+	}
+	if zone.Type != nil {
+		update = update.Set(expression.Name("type"), expression.Value(*zone.Type))
+		changed = true
+	}
+	if zone.Polygon != nil {
+		stored, err := catalog.New(h.DynamoDB).Zone(ctx, zone.ZoneID)
+		if errors.Is(err, catalog.ErrNotFound) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Headers:    headers,
+				Body:       fmt.Sprintf("Zone with ID %s not found", zone.ZoneID),
+			}, nil
+		}
+		if err == nil {
+			zone.FloorplanID = stored.FloorplanID
+			err = h.validatePolygon(ctx, zone)
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, validation.ErrInvalid) {
+				status = http.StatusBadRequest
+			}
+			return events.APIGatewayProxyResponse{
+				StatusCode: status,
+				Headers:    headers,
+				Body:       validation.Body(err),
+			}, nil
+		}
+		// The new polygon is drawn on the floorplan's current image.
+		update = update.Set(expression.Name("polygon"), expression.Value(zone.Polygon)).
+			Remove(expression.Name("floorplanVersion"))
+		changed = true
+	}
+	if !changed {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    headers,
+			Body:       "Nothing to update: give a name, type or polygon",
+		}, nil
+	}
+
+	condition := expression.AttributeExists(expression.Name("zoneId"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Failed to build update expression: %s", err.Error()),
+		}, nil
+	}
+
+	_, err = h.DynamoDB.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(TABLENAME),
+		Key:                       map[string]ddbtypes.AttributeValue{"zoneId": &ddbtypes.AttributeValueMemberS{Value: zone.ZoneID}},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+	})
+	var conditionFailed *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Zone with ID %s not found", zone.ZoneID),
+		}, nil
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    headers,
+			Body:       fmt.Sprintf("Error updating zone: %s", err.Error()),
+		}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       fmt.Sprintf("zoneId %s updated successfully", zone.ZoneID),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package zones
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"net/http"
+	"testing"
+	"wdd/api/internal/mocks"
+)
+
+func TestHandleUpdateZoneRequest(t *testing.T) {
+	var update *dynamodb.UpdateItemInput
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: mockGetItem,
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			if params.Key["zoneId"].(*ddbtypes.AttributeValueMemberS).Value != "z1" {
+				return nil, &ddbtypes.ConditionalCheckFailedException{Message: aws.String("mock condition failed")}
+			}
+			update = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	handler := NewUpdateZoneHandler(mockDDBClient)
+
+	body := `{"zoneId":"z1","name":"Hazard Zone","polygon":[{"x":0,"y":0},{"x":100,"y":0},{"x":100,"y":50}]}`
+	response, err := handler.HandleUpdateZoneRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d for successful update, got %d: %s", http.StatusOK, response.StatusCode, response.Body)
+	}
+	if len(update.ExpressionAttributeValues) != 2 {
+		t.Errorf("Expected the name and polygon to be set, got %v", update.ExpressionAttributeValues)
+	}
+
+	cases := map[string]int{
+		`{"name":"A"}`:               http.StatusBadRequest,
+		`{"zoneId":"z1"}`:            http.StatusBadRequest,
+		`{"zoneId":"z9","name":"A"}`: http.StatusNotFound,
+		`{"zoneId":"z9","polygon":[{"x":0,"y":0},{"x":1,"y":0},{"x":1,"y":1}]}`:  http.StatusNotFound,
+		`{"zoneId":"z1","polygon":[{"x":0,"y":0},{"x":1,"y":0},{"x":1,"y":99}]}`: http.StatusBadRequest,
+	}
+	for body, status := range cases {
+		response, _ := handler.HandleUpdateZoneRequest(context.Background(), events.APIGatewayProxyRequest{Body: body})
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %s, got %d", status, body, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package zones
+
+import (
+	"wdd/api/internal/types"
+)
+
+const TABLENAME = "Zone"
+
+type Handler struct {
+	DynamoDB types.DynamoDBClient
+}
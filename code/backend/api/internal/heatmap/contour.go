@@ -0,0 +1,114 @@
+package heatmap
+
+import (
+	"wdd/api/internal/types"
+)
+
+// edge is a side between two neighbouring cell centres, named by its top
+// or left centre and whether it runs down rather than right.
+type edge struct {
+	col, row int
+	down     bool
+}
+
+// Contours traces the isoline of a grid at level by marching squares over
+// its cell centres. Lines are polylines in the grid's coordinates; closed
+// ones end on their first point. Saddles are resolved by the mean of their
+// four corners.
+func Contours(g Grid, level float64) [][]types.Position {
+	links := map[edge][]edge{}
+	var order []edge
+	link := func(a, b edge) {
+		for _, e := range []edge{a, b} {
+			if _, ok := links[e]; !ok {
+				order = append(order, e)
+			}
+		}
+		links[a] = append(links[a], b)
+		links[b] = append(links[b], a)
+	}
+
+	for row := 0; row+1 < g.Rows; row++ {
+		for col := 0; col+1 < g.Cols; col++ {
+			corners := [4]float64{g.At(col, row), g.At(col+1, row), g.At(col+1, row+1), g.At(col, row+1)}
+			// The sides of the square, each following the corner it starts
+			// from clockwise: top, right, bottom and left.
+			sides := [4]edge{{col, row, false}, {col + 1, row, true}, {col, row + 1, false}, {col, row, true}}
+
+			var crossed []int
+			for i := range corners {
+				if (corners[i] >= level) != (corners[(i+1)%4] >= level) {
+					crossed = append(crossed, i)
+				}
+			}
+			switch len(crossed) {
+			case 2:
+				link(sides[crossed[0]], sides[crossed[1]])
+			case 4:
+				// Cut off the corners on the other side of the level from
+				// the square's centre, each between its two sides.
+				centre := (corners[0] + corners[1] + corners[2] + corners[3]) / 4
+				for i := range corners {
+					if (corners[i] >= level) != (centre >= level) {
+						link(sides[(i+3)%4], sides[i])
+					}
+				}
+			}
+		}
+	}
+
+	point := func(e edge) types.Position {
+		a, b := g.Centre(e.col, e.row), g.Centre(e.col+1, e.row)
+		va, vb := g.At(e.col, e.row), 0.0
+		if e.down {
+			b, vb = g.Centre(e.col, e.row+1), g.At(e.col, e.row+1)
+		} else {
+			vb = g.At(e.col+1, e.row)
+		}
+		t := (level - va) / (vb - va)
+		return types.Position{X: a.X + t*(b.X-a.X), Y: a.Y + t*(b.Y-a.Y)}
+	}
+
+	visited := map[edge]bool{}
+	trace := func(start edge) []types.Position {
+		line := []types.Position{point(start)}
+		visited[start] = true
+		previous, current := edge{col: -1, row: -1}, start
+		for {
+			next, found := edge{}, false
+			for _, e := range links[current] {
+				if e != previous && !visited[e] {
+					next, found = e, true
+					break
+				}
+			}
+			if !found {
+				// Back at the start of a closed line.
+				for _, e := range links[current] {
+					if e == start && e != previous && len(line) > 2 {
+						line = append(line, line[0])
+						break
+					}
+				}
+				return line
+			}
+			visited[next] = true
+			line = append(line, point(next))
+			previous, current = current, next
+		}
+	}
+
+	var lines [][]types.Position
+	// Open lines end at the border of the grid, where an edge has one link.
+	for _, e := range order {
+		if !visited[e] && len(links[e]) == 1 {
+			lines = append(lines, trace(e))
+		}
+	}
+	for _, e := range order {
+		if !visited[e] {
+			lines = append(lines, trace(e))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,74 @@
+package heatmap
+
+import (
+	"math"
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func grid(cols, rows int, values ...float64) Grid {
+	return Grid{Cols: cols, Rows: rows, Width: float64(cols), Height: float64(rows), Values: values}
+}
+
+func TestContours_Open(t *testing.T) {
+	// Values rise from left to right, so the isoline at 1.5 runs straight
+	// down between the second and third columns.
+	g := grid(4, 3,
+		0, 1, 2, 3,
+		0, 1, 2, 3,
+		0, 1, 2, 3,
+	)
+	lines := Contours(g, 1.5)
+	if len(lines) != 1 || len(lines[0]) != 3 {
+		t.Fatalf("Expected one line through 3 rows, got %v", lines)
+	}
+	for _, p := range lines[0] {
+		if p.X != 2 {
+			t.Errorf("Expected the line at x 2, got %v", lines[0])
+		}
+	}
+}
+
+func TestContours_Closed(t *testing.T) {
+	g := grid(3, 3,
+		0, 0, 0,
+		0, 4, 0,
+		0, 0, 0,
+	)
+	lines := Contours(g, 2)
+	if len(lines) != 1 || len(lines[0]) != 5 || lines[0][0] != lines[0][4] {
+		t.Fatalf("Expected one closed diamond, got %v", lines)
+	}
+	for _, p := range lines[0][:4] {
+		if d := math.Abs(p.X-1.5) + math.Abs(p.Y-1.5); math.Abs(d-0.5) > 1e-9 {
+			t.Errorf("Expected the diamond halfway to the peak, got %v", p)
+		}
+	}
+}
+
+func TestContours_Saddle(t *testing.T) {
+	// High corners on one diagonal; the low centre keeps them apart.
+	g := grid(2, 2,
+		2, 0,
+		0, 2,
+	)
+	lines := Contours(g, 1.5)
+	if len(lines) != 2 {
+		t.Fatalf("Expected two lines cutting off the high corners, got %v", lines)
+	}
+	for _, line := range lines {
+		corner := types.Position{X: 0.5, Y: 0.5}
+		if line[0].X > 1 || line[0].Y > 1 {
+			corner = types.Position{X: 1.5, Y: 1.5}
+		}
+		for _, p := range line {
+			if math.Abs(p.X-corner.X)+math.Abs(p.Y-corner.Y) > 0.25+1e-9 {
+				t.Errorf("Expected %v to stay near the corner %v", line, corner)
+			}
+		}
+	}
+
+	if lines := Contours(g, 3); len(lines) != 0 {
+		t.Errorf("Expected no lines above the grid, got %v", lines)
+	}
+}
@@ -0,0 +1,79 @@
+package heatmap
+
+import (
+	"math"
+	"wdd/api/internal/types"
+)
+
+// Sample is a value measured at a position of the floorplan.
+type Sample struct {
+	Position types.Position
+	Value    float64
+}
+
+// Grid holds interpolated values over a Width by Height area, one per cell
+// of a Cols by Rows lattice, row by row from the top left. Values stand for
+// the centre of their cell.
+type Grid struct {
+	Cols, Rows    int
+	Width, Height float64
+	Values        []float64
+}
+
+// At returns the value of a cell.
+func (g Grid) At(col, row int) float64 {
+	return g.Values[row*g.Cols+col]
+}
+
+// Centre returns the position of the centre of a cell.
+func (g Grid) Centre(col, row int) types.Position {
+	return types.Position{
+		X: (float64(col) + 0.5) * g.Width / float64(g.Cols),
+		Y: (float64(row) + 0.5) * g.Height / float64(g.Rows),
+	}
+}
+
+// Range returns the smallest and largest values of a grid.
+func (g Grid) Range() (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range g.Values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	return min, max
+}
+
+// Interpolate spreads samples over a grid by inverse distance weighting:
+// every cell takes the mean of the sample values weighted by 1/d^power,
+// and the value of a sample lying on its centre.
+func Interpolate(samples []Sample, width, height float64, cols, rows int, power float64) Grid {
+	g := Grid{Cols: cols, Rows: rows, Width: width, Height: height, Values: make([]float64, cols*rows)}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			g.Values[row*cols+col] = idw(samples, g.Centre(col, row), power)
+		}
+	}
+	return g
+}
+
+func idw(samples []Sample, p types.Position, power float64) float64 {
+	var sum, weights float64
+	for _, s := range samples {
+		d := math.Hypot(s.Position.X-p.X, s.Position.Y-p.Y)
+		if d < 1e-9 {
+			return s.Value
+		}
+		w := math.Pow(d, -power)
+		sum += w * s.Value
+		weights += w
+	}
+	return sum / weights
+}
+
+// Size picks the lattice of an area so that its longer side has cells
+// cells, keeping them close to square.
+func Size(width, height float64, cells int) (int, int) {
+	if width >= height {
+		return cells, int(math.Max(1, math.Round(float64(cells)*height/width)))
+	}
+	return int(math.Max(1, math.Round(float64(cells)*width/height))), cells
+}
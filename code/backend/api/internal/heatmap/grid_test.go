@@ -0,0 +1,46 @@
+package heatmap
+
+import (
+	"math"
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func TestInterpolate(t *testing.T) {
+	samples := []Sample{
+		{Position: types.Position{X: 5, Y: 5}, Value: 10},
+		{Position: types.Position{X: 35, Y: 5}, Value: 30},
+	}
+	g := Interpolate(samples, 40, 10, 4, 1, 2)
+
+	// Samples on a cell centre keep their value, and the cells between them
+	// lean toward the nearer one.
+	if g.At(0, 0) != 10 || g.At(3, 0) != 30 {
+		t.Errorf("Expected the sampled cells to keep 10 and 30, got %v", g.Values)
+	}
+	if !(g.At(1, 0) > 10 && g.At(1, 0) < 20 && g.At(2, 0) > 20 && g.At(2, 0) < 30) {
+		t.Errorf("Expected the values between the samples to lean toward the nearer one, got %v", g.Values)
+	}
+	if math.Abs(g.At(1, 0)+g.At(2, 0)-40) > 1e-9 {
+		t.Errorf("Expected the values to be symmetric about the midpoint, got %v", g.Values)
+	}
+	if min, max := g.Range(); min != 10 || max != 30 {
+		t.Errorf("Expected a range of 10 to 30, got %g to %g", min, max)
+	}
+}
+
+func TestSize(t *testing.T) {
+	cases := []struct {
+		width, height float64
+		cols, rows    int
+	}{
+		{1000, 500, 200, 100},
+		{500, 1000, 100, 200},
+		{1000, 1, 200, 1},
+	}
+	for _, c := range cases {
+		if cols, rows := Size(c.width, c.height, 200); cols != c.cols || rows != c.rows {
+			t.Errorf("Expected %gx%g to be %dx%d cells, got %dx%d", c.width, c.height, c.cols, c.rows, cols, rows)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package heatmap
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// ramp runs from cold to hot: blue, cyan, green, yellow and red.
+var ramp = []color.NRGBA{
+	{R: 0, G: 0, B: 255},
+	{R: 0, G: 255, B: 255},
+	{R: 0, G: 255, B: 0},
+	{R: 255, G: 255, B: 0},
+	{R: 255, G: 0, B: 0},
+}
+
+// Colour maps a value onto the ramp between min and max, clamping values
+// outside them. Values are mid-ramp when min and max are equal.
+func Colour(v, min, max float64, alpha uint8) color.NRGBA {
+	t := 0.5
+	if max > min {
+		t = math.Max(0, math.Min(1, (v-min)/(max-min)))
+	}
+	position := t * float64(len(ramp)-1)
+	i := int(math.Min(position, float64(len(ramp)-2)))
+	f := position - float64(i)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + f*(float64(b)-float64(a))))
+	}
+	a, b := ramp[i], ramp[i+1]
+	return color.NRGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: alpha}
+}
+
+// PNG renders a grid as an image of one pixel per cell, to be stretched
+// over the floorplan it covers.
+func PNG(w io.Writer, g Grid, min, max float64, alpha uint8) error {
+	img := image.NewNRGBA(image.Rect(0, 0, g.Cols, g.Rows))
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			img.SetNRGBA(col, row, Colour(g.At(col, row), min, max, alpha))
+		}
+	}
+	return png.Encode(w, img)
+}
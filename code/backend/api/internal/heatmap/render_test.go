@@ -0,0 +1,42 @@
+package heatmap
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestColour(t *testing.T) {
+	cases := map[float64]color.NRGBA{
+		0:   {R: 0, G: 0, B: 255, A: 128},
+		50:  {R: 0, G: 255, B: 0, A: 128},
+		100: {R: 255, G: 0, B: 0, A: 128},
+		200: {R: 255, G: 0, B: 0, A: 128},
+	}
+	for v, expected := range cases {
+		if got := Colour(v, 0, 100, 128); got != expected {
+			t.Errorf("Expected %g to be %v, got %v", v, expected, got)
+		}
+	}
+	if got := Colour(5, 5, 5, 255); got != (color.NRGBA{R: 0, G: 255, B: 0, A: 255}) {
+		t.Errorf("Expected a flat range to be mid-ramp, got %v", got)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PNG(&buf, grid(3, 2, 0, 50, 100, 0, 50, 100), 0, 100, 200); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Expected a PNG, got %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 3 || size.Y != 2 {
+		t.Errorf("Expected one pixel per cell, got %v", size)
+	}
+	if got := color.NRGBAModel.Convert(img.At(2, 1)).(color.NRGBA); got != (color.NRGBA{R: 255, G: 0, B: 0, A: 200}) {
+		t.Errorf("Expected the hottest cell red, got %v", got)
+	}
+}
@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+)
+
+const (
+	// MAXPOINTS caps the points of one request.
+	MAXPOINTS   = 10000
+	CONCURRENCY = 8
+	CHUNKSIZE   = timeseries.BATCHSIZE * 4
+	// MAXCLOCKSKEW is how far in the future a point may be stamped before it
+	// is taken for a device with a wrong clock.
+	MAXCLOCKSKEW = 5 * time.Minute
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid ingestion request")
+	ErrTooManyPoints  = errors.New("too many points")
+)
+
+// Point is one reading sent by a device. Timestamp is in Unix milliseconds
+// and Quality is a code such as "uncertain" or "bad/sensorFailure",
+// defaulting to good.
+type Point struct {
+	PropertyID string   `json:"propertyId"`
+	Timestamp  int64    `json:"timestamp"`
+	Value      *float64 `json:"value"`
+	Quality    string   `json:"quality,omitempty"`
+}
+
+type Request struct {
+	Points []Point `json:"points"`
+}
+
+// Rejection is a point that was not written, by its index in the request.
+type Rejection struct {
+	Index      int    `json:"index"`
+	PropertyID string `json:"propertyId,omitempty"`
+	Timestamp  int64  `json:"timestamp,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+type Result struct {
+	Received   int         `json:"received"`
+	Written    int         `json:"written"`
+	Duplicates int         `json:"duplicates"`
+	Batches    int         `json:"batches"`
+	Rejected   []Rejection `json:"rejected"`
+}
+
+type Writer interface {
+	Write(ctx context.Context, readings []types.Reading) error
+}
+
+type Ingester struct {
+	Catalog *catalog.Catalog
+	Store   Writer
+	Now     func() time.Time
+}
+
+func New(db types.DynamoDBClient) *Ingester {
+	return &Ingester{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+		Now:     time.Now,
+	}
+}
+
+func (r Request) Validate() error {
+	if len(r.Points) == 0 {
+		return fmt.Errorf("%w: no points", ErrInvalidRequest)
+	}
+	if len(r.Points) > MAXPOINTS {
+		return fmt.Errorf("%w: %d points sent, limit is %d", ErrTooManyPoints, len(r.Points), MAXPOINTS)
+	}
+	return nil
+}
+
+// Ingest validates every point, flags good points outside their property's
+// measurement bounds as line protocol writes do, drops all but the first
+// point for each (propertyId, timestamp) and writes the rest. Points that fail validation are reported rather than failing the
+// request; only a lookup or write error does.
+func (in Ingester) Ingest(ctx context.Context, request Request) (Result, error) {
+	result := Result{Received: len(request.Points), Rejected: []Rejection{}}
+	if err := request.Validate(); err != nil {
+		return result, err
+	}
+
+	measurements, err := in.measurements(ctx, request.Points)
+	if err != nil {
+		return result, err
+	}
+
+	type key struct {
+		propertyID string
+		timestamp  int64
+	}
+	seen := make(map[key]int, len(request.Points))
+	readings := make([]types.Reading, 0, len(request.Points))
+	latest := in.Now().Add(MAXCLOCKSKEW).UnixMilli()
+	for i, p := range request.Points {
+		reject := func(reason string, args ...any) {
+			result.Rejected = append(result.Rejected, Rejection{Index: i, PropertyID: p.PropertyID, Timestamp: p.Timestamp, Reason: fmt.Sprintf(reason, args...)})
+		}
+
+		measurement, known := measurements[p.PropertyID]
+		_, _, qualityErr := quality.Parse(p.Quality)
+		switch {
+		case p.PropertyID == "":
+			reject("missing propertyId")
+		case !known:
+			reject("unknown property %q", p.PropertyID)
+		case p.Timestamp <= 0:
+			reject("missing timestamp")
+		case p.Timestamp > latest:
+			reject("timestamp is more than %s in the future", MAXCLOCKSKEW)
+		case p.Value == nil:
+			reject("missing value")
+		case math.IsNaN(*p.Value) || math.IsInf(*p.Value, 0):
+			reject("value is not finite")
+		case qualityErr != nil:
+			reject("%s", qualityErr)
+		default:
+			k := key{p.PropertyID, p.Timestamp}
+			if first, ok := seen[k]; ok {
+				reject("duplicate of point %d", first)
+				result.Duplicates++
+				continue
+			}
+			seen[k] = i
+			reading := types.Reading{PropertyID: p.PropertyID, Timestamp: p.Timestamp, Value: *p.Value, Quality: p.Quality}
+			readings = append(readings, quality.Assess(reading, measurement))
+		}
+	}
+
+	written, batches, err := in.write(ctx, readings)
+	result.Written, result.Batches = written, batches
+	return result, err
+}
+
+// measurements looks up the measurement of every property the points
+// mention, once per property. Unknown properties are left out.
+func (in Ingester) measurements(ctx context.Context, points []Point) (map[string]types.Measurement, error) {
+	measurements := map[string]types.Measurement{}
+	checked := map[string]bool{}
+	for _, p := range points {
+		if p.PropertyID == "" || checked[p.PropertyID] {
+			continue
+		}
+		checked[p.PropertyID] = true
+
+		measurement, err := in.Catalog.PropertyMeasurement(ctx, p.PropertyID)
+		if errors.Is(err, catalog.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		measurements[p.PropertyID] = measurement
+	}
+	return measurements, nil
+}
+
+// write stores the readings in chunks with CONCURRENCY parallel writers,
+// sorted so that each chunk touches as few partitions as possible.
+func (in Ingester) write(ctx context.Context, readings []types.Reading) (int, int, error) {
+	sort.Slice(readings, func(i, j int) bool {
+		if readings[i].PropertyID != readings[j].PropertyID {
+			return readings[i].PropertyID < readings[j].PropertyID
+		}
+		return readings[i].Timestamp < readings[j].Timestamp
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan []types.Reading)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		once     sync.Once
+		firstErr error
+		written  int
+		batches  int
+	)
+	for i := 0; i < CONCURRENCY; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				if err := in.Store.Write(ctx, chunk); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				mu.Lock()
+				written += len(chunk)
+				batches += (len(chunk) + timeseries.BATCHSIZE - 1) / timeseries.BATCHSIZE
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for start := 0; start < len(readings) && ctx.Err() == nil; start += CHUNKSIZE {
+		end := start + CHUNKSIZE
+		if end > len(readings) {
+			end = len(readings)
+		}
+		select {
+		case jobs <- readings[start:end]:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return written, batches, firstErr
+	}
+	return written, batches, ctx.Err()
+}
@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	readings []types.Reading
+	err      error
+}
+
+func (s *memoryStore) Write(ctx context.Context, readings []types.Reading) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.readings = append(s.readings, readings...)
+	return nil
+}
+
+// mockCatalogClient knows property p1, measured between 10 and 20.
+func mockCatalogClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch *params.TableName {
+			case "Property":
+				if params.Key["propertyId"].(*ddbtypes.AttributeValueMemberS).Value != "p1" {
+					return &dynamodb.GetItemOutput{}, nil
+				}
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"propertyId":    &ddbtypes.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &ddbtypes.AttributeValueMemberS{Value: "m1"},
+				}}, nil
+			case "Measurement":
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"measurementId": &ddbtypes.AttributeValueMemberS{Value: "m1"},
+					"lowerBound":    &ddbtypes.AttributeValueMemberN{Value: "10"},
+					"upperBound":    &ddbtypes.AttributeValueMemberN{Value: "20"},
+				}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+}
+
+func newIngester(store *memoryStore) *Ingester {
+	return &Ingester{
+		Catalog: catalog.New(mockCatalogClient()),
+		Store:   store,
+		Now:     func() time.Time { return now },
+	}
+}
+
+func value(v float64) *float64 {
+	return &v
+}
+
+func TestIngest_InvalidRequest(t *testing.T) {
+	in := newIngester(&memoryStore{})
+
+	if _, err := in.Ingest(context.Background(), Request{}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for no points, got %v", err)
+	}
+	if _, err := in.Ingest(context.Background(), Request{Points: make([]Point, MAXPOINTS+1)}); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("Expected ErrTooManyPoints, got %v", err)
+	}
+}
+
+func TestIngest_Validation(t *testing.T) {
+	store := &memoryStore{}
+	ts := now.UnixMilli()
+	points := []Point{
+		{PropertyID: "p1", Timestamp: ts, Value: value(15)},
+		{PropertyID: "", Timestamp: ts, Value: value(15)},
+		{PropertyID: "p9", Timestamp: ts, Value: value(15)},
+		{PropertyID: "p1", Value: value(15)},
+		{PropertyID: "p1", Timestamp: now.Add(time.Hour).UnixMilli(), Value: value(15)},
+		{PropertyID: "p1", Timestamp: ts + 1},
+		{PropertyID: "p1", Timestamp: ts + 1, Value: value(math.Inf(1))},
+		{PropertyID: "p1", Timestamp: ts + 1, Value: value(15), Quality: "fine"},
+		{PropertyID: "p1", Timestamp: ts + 2, Value: value(5)},
+		{PropertyID: "p1", Timestamp: ts + 3, Value: value(25), Quality: "uncertain"},
+		{PropertyID: "p1", Timestamp: ts + 1, Value: value(25), Quality: "bad/sensorFailure"},
+		{PropertyID: "p1", Timestamp: ts, Value: value(16)},
+	}
+
+	result, err := newIngester(store).Ingest(context.Background(), Request{Points: points})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []Rejection{
+		{Index: 1, Timestamp: ts, Reason: "missing propertyId"},
+		{Index: 2, PropertyID: "p9", Timestamp: ts, Reason: `unknown property "p9"`},
+		{Index: 3, PropertyID: "p1", Reason: "missing timestamp"},
+		{Index: 4, PropertyID: "p1", Timestamp: now.Add(time.Hour).UnixMilli(), Reason: "timestamp is more than 5m0s in the future"},
+		{Index: 5, PropertyID: "p1", Timestamp: ts + 1, Reason: "missing value"},
+		{Index: 6, PropertyID: "p1", Timestamp: ts + 1, Reason: "value is not finite"},
+		{Index: 7, PropertyID: "p1", Timestamp: ts + 1, Reason: `invalid quality "fine": severity must be good, uncertain or bad`},
+		{Index: 11, PropertyID: "p1", Timestamp: ts, Reason: "duplicate of point 0"},
+	}
+	if !reflect.DeepEqual(result.Rejected, expected) {
+		t.Errorf("Expected rejections\n%+v\ngot\n%+v", expected, result.Rejected)
+	}
+	if result.Received != 12 || result.Written != 4 || result.Duplicates != 1 || result.Batches != 1 {
+		t.Errorf("Unexpected counts %+v", result)
+	}
+
+	written := []types.Reading{
+		{PropertyID: "p1", Timestamp: ts, Value: 15},
+		{PropertyID: "p1", Timestamp: ts + 1, Value: 25, Quality: "bad/sensorFailure"},
+		// Out of bounds, so flagged unless the device reported worse.
+		{PropertyID: "p1", Timestamp: ts + 2, Value: 5, Quality: quality.OUTOFBOUNDS},
+		{PropertyID: "p1", Timestamp: ts + 3, Value: 25, Quality: "uncertain"},
+	}
+	if !reflect.DeepEqual(store.readings, written) {
+		t.Errorf("Expected readings %+v, got %+v", written, store.readings)
+	}
+}
+
+func TestIngest_Batches(t *testing.T) {
+	store := &memoryStore{}
+	points := make([]Point, 1000)
+	for i := range points {
+		points[i] = Point{PropertyID: "p1", Timestamp: now.UnixMilli() - int64(i), Value: value(12)}
+	}
+
+	result, err := newIngester(store).Ingest(context.Background(), Request{Points: points})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Written != 1000 || result.Batches != 40 || len(store.readings) != 1000 {
+		t.Errorf("Expected 1000 readings in 40 batches, got %+v", result)
+	}
+}
+
+func TestIngest_WriteError(t *testing.T) {
+	store := &memoryStore{err: errors.New("mock write error")}
+	points := []Point{{PropertyID: "p1", Timestamp: now.UnixMilli(), Value: value(12)}}
+
+	if _, err := newIngester(store).Ingest(context.Background(), Request{Points: points}); err == nil {
+		t.Errorf("Expected the write error")
+	}
+}
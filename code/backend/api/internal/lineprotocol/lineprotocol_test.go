@@ -0,0 +1,235 @@
+package lineprotocol
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestParsePrecision(t *testing.T) {
+	cases := map[string]time.Duration{
+		"": time.Nanosecond, "n": time.Nanosecond, "ns": time.Nanosecond,
+		"u": time.Microsecond, "us": time.Microsecond, "µs": time.Microsecond,
+		"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour,
+	}
+	for precision, expected := range cases {
+		got, err := ParsePrecision(precision)
+		if err != nil || got != expected {
+			t.Errorf("Expected %v for %q, got %v, %v", expected, precision, got, err)
+		}
+	}
+	if _, err := ParsePrecision("d"); !errors.Is(err, ErrInvalidPrecision) {
+		t.Errorf("Expected ErrInvalidPrecision, got %v", err)
+	}
+}
+
+func TestParse_Timestamps(t *testing.T) {
+	expected := time.Unix(1700000000, 0).UTC()
+	cases := map[time.Duration]string{
+		time.Nanosecond:  "1700000000000000000",
+		time.Microsecond: "1700000000000000",
+		time.Millisecond: "1700000000000",
+		time.Second:      "1700000000",
+		time.Minute:      "28333333",
+		time.Hour:        "472222",
+	}
+	for precision, ts := range cases {
+		points, errs := Parse([]byte("m v=1 "+ts), precision, now)
+		if len(errs) != 0 || len(points) != 1 {
+			t.Fatalf("Expected one point at precision %v, got %v %v", precision, points, errs)
+		}
+		if got := points[0].Time; got.Sub(expected).Abs() >= precision {
+			t.Errorf("Expected %v at precision %v, got %v", expected, precision, got)
+		}
+	}
+
+	points, _ := Parse([]byte("m v=1"), time.Nanosecond, now)
+	if !points[0].Time.Equal(now) {
+		t.Errorf("Expected a point without a timestamp to get now, got %v", points[0].Time)
+	}
+	if _, errs := Parse([]byte("m v=1 9223372036854775"), time.Second, now); len(errs) != 1 {
+		t.Errorf("Expected an out of range timestamp to be rejected")
+	}
+}
+
+func TestParse_Syntax(t *testing.T) {
+	data := strings.Join([]string{
+		"# comment",
+		"",
+		`my\ meas\,ure,tag\=key=tag\ value\,x,t2=v2 f\ 1="say \"hi\" \\o/",i=-3i,u=4u,b=true,B=F,x=1.5e3 1000`,
+		"  ",
+		"cpu load=0.5\r",
+	}, "\n")
+
+	points, errs := Parse([]byte(data), time.Nanosecond, now)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(points))
+	}
+
+	expected := Point{
+		Line:        3,
+		Measurement: "my meas,ure",
+		Tags:        []Tag{{"tag=key", "tag value,x"}, {"t2", "v2"}},
+		Fields: []Field{
+			{"f 1", `say "hi" \o/`},
+			{"i", int64(-3)},
+			{"u", uint64(4)},
+			{"b", true},
+			{"B", false},
+			{"x", 1500.0},
+		},
+		Time: time.Unix(0, 1000).UTC(),
+	}
+	if !reflect.DeepEqual(points[0], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, points[0])
+	}
+	if points[1].Line != 5 || points[1].Fields[0].Value != 0.5 {
+		t.Errorf("Expected line 5 with load=0.5, got %+v", points[1])
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	lines := []string{
+		",tag=1 v=1",
+		"m",
+		"m,tag v=1",
+		"m,tag= v=1",
+		"m v=",
+		"m v=abc",
+		"m v=1i2",
+		`m v="open`,
+		"m v=1 soon",
+		"m v=1,",
+	}
+	points, errs := Parse([]byte(strings.Join(lines, "\n")), time.Nanosecond, now)
+	if len(points) != 0 {
+		t.Errorf("Expected no points, got %+v", points)
+	}
+	if len(errs) != len(lines) {
+		t.Fatalf("Expected %d errors, got %v", len(lines), errs)
+	}
+	for i, err := range errs {
+		if err.Line != i+1 {
+			t.Errorf("Expected an error on line %d, got %v", i+1, err)
+		}
+	}
+}
+
+func TestAppend_RoundTrip(t *testing.T) {
+	p := Point{
+		Line:        1,
+		Measurement: "my meas,ure",
+		Tags:        []Tag{{"z", "last"}, {"a=b", "c d,e"}, {"empty", ""}},
+		Fields:      []Field{{"f", 1.25}, {"s", `q"\`}, {"i", int64(-7)}, {"u", uint64(8)}, {"b", false}},
+		Time:        time.UnixMilli(1700000000123).UTC(),
+	}
+
+	line := string(Append(nil, p, time.Millisecond))
+	expected := `my\ meas\,ure,a\=b=c\ d\,e,z=last f=1.25,s="q\"\\",i=-7i,u=8u,b=false 1700000000123` + "\n"
+	if line != expected {
+		t.Errorf("Expected %s, got %s", expected, line)
+	}
+
+	points, errs := Parse([]byte(line), time.Millisecond, now)
+	if len(errs) != 0 || len(points) != 1 {
+		t.Fatalf("Expected the line to parse, got %v", errs)
+	}
+	p.Tags = []Tag{{"a=b", "c d,e"}, {"z", "last"}}
+	if !reflect.DeepEqual(points[0], p) {
+		t.Errorf("Expected %+v, got %+v", p, points[0])
+	}
+}
+
+func TestResolver(t *testing.T) {
+	upper := 1.0
+	series := []catalog.Series{
+		{AssetID: "a1", AssetName: "boiler", Property: types.Property{PropertyID: "p1", Name: "Temperature"}},
+		{AssetID: "a1", AssetName: "boiler", Property: types.Property{PropertyID: "p2", Name: "Pressure"}, Measurement: types.Measurement{UpperBound: &upper}},
+		{AssetID: "a2", AssetName: "chiller", Property: types.Property{PropertyID: "p3", Name: "Temperature"}},
+	}
+	mapping := types.LineProtocolMapping{
+		AssetTag: "host",
+		Assets:   map[string]string{"plc-7": "a2"},
+		Fields:   map[string]string{"temp": "Temperature", "sensors.psi": "p2"},
+	}
+
+	data := strings.Join([]string{
+		"sensors,host=boiler temp=80,psi=2i,running=true 1000",
+		"sensors,host=plc-7 temp=5 2000",
+		"chiller Temperature=6 3000",
+		"sensors,host=boiler Temperature=\"hot\",rpm=5 4000",
+		"sensors,host=unknown temp=1",
+		"sensors,host=plc-7,quality=bad/commFailure temp=0 5000",
+		"sensors,host=plc-7,quality=wrong temp=0 6000",
+	}, "\n")
+	points, _ := Parse([]byte(data), time.Millisecond, now)
+	readings, rejections := NewResolver(mapping, series).Resolve(points)
+
+	expected := []types.Reading{
+		{PropertyID: "p1", Timestamp: 1000, Value: 80},
+		{PropertyID: "p2", Timestamp: 1000, Value: 2, Quality: "uncertain/engineeringUnitsExceeded"},
+		{PropertyID: "p3", Timestamp: 2000, Value: 5},
+		{PropertyID: "p3", Timestamp: 3000, Value: 6},
+		{PropertyID: "p3", Timestamp: 5000, Value: 0, Quality: "bad/commFailure"},
+	}
+	if !reflect.DeepEqual(readings, expected) {
+		t.Errorf("Expected readings %+v, got %+v", expected, readings)
+	}
+
+	reasons := make([]string, len(rejections))
+	for i, r := range rejections {
+		reasons[i] = r.Field + ": " + r.Reason
+	}
+	expectedReasons := []string{
+		`running: unknown property "running"`,
+		`Temperature: value is not numeric`,
+		`rpm: unknown property "rpm"`,
+		`: unknown asset "unknown"`,
+		`: invalid quality "wrong": severity must be good, uncertain or bad`,
+	}
+	if !reflect.DeepEqual(reasons, expectedReasons) {
+		t.Errorf("Expected rejections %q, got %q", expectedReasons, reasons)
+	}
+}
+
+func TestResolver_Duplicates(t *testing.T) {
+	// Both presses are of one model, so they share its property.
+	series := []catalog.Series{
+		{AssetID: "a1", AssetName: "press-1", Property: types.Property{PropertyID: "p1", Name: "temp"}},
+		{AssetID: "a2", AssetName: "press-2", Property: types.Property{PropertyID: "p1", Name: "temp"}},
+	}
+	data := strings.Join([]string{
+		"sensors,host=press-1 temp=1 1000000001",
+		"sensors,host=press-2 temp=2 1000000001",
+		"sensors,host=press-1 temp=3 1000000002",
+		"sensors,host=press-1 temp=1 1000000001",
+		"sensors,host=press-1 temp=4 1001000000",
+	}, "\n")
+	points, _ := Parse([]byte(data), time.Nanosecond, now)
+	readings, rejections := NewResolver(types.LineProtocolMapping{AssetTag: "host"}, series).Resolve(points)
+
+	expected := []types.Reading{
+		{PropertyID: "p1", Timestamp: 1000, Value: 1},
+		{PropertyID: "p1", Timestamp: 1001, Value: 4},
+	}
+	if !reflect.DeepEqual(readings, expected) {
+		t.Errorf("Expected readings %+v, got %+v", expected, readings)
+	}
+	expectedRejections := []Rejection{
+		{Line: 2, Field: "temp", Reason: "duplicate of line 1"},
+		{Line: 3, Field: "temp", Reason: "duplicate of line 1"},
+		{Line: 4, Field: "temp", Reason: "duplicate of line 1"},
+	}
+	if !reflect.DeepEqual(rejections, expectedRejections) {
+		t.Errorf("Expected rejections %+v, got %+v", expectedRejections, rejections)
+	}
+}
@@ -0,0 +1,170 @@
+package lineprotocol
+
+import (
+	"errors"
+	"fmt"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/types"
+)
+
+const (
+	// DEFAULTASSETTAG is the tag naming a point's asset when the mapping
+	// does not set one.
+	DEFAULTASSETTAG = "asset"
+	// QUALITYTAG optionally carries the quality code of a point's fields.
+	QUALITYTAG = "quality"
+)
+
+var ErrInvalidMapping = errors.New("invalid line protocol mapping")
+
+// Default is the mapping used for factories without one: the asset tag
+// names the asset and field names name properties.
+func Default(factoryID string) types.LineProtocolMapping {
+	return types.LineProtocolMapping{FactoryID: factoryID, AssetTag: DEFAULTASSETTAG}
+}
+
+// Validate rejects mappings with empty keys or targets, which could never
+// match anything.
+func Validate(mapping types.LineProtocolMapping) error {
+	for name, target := range mapping.Assets {
+		if name == "" || target == "" {
+			return fmt.Errorf("%w: empty asset mapping %q -> %q", ErrInvalidMapping, name, target)
+		}
+	}
+	for name, target := range mapping.Fields {
+		if name == "" || target == "" {
+			return fmt.Errorf("%w: empty field mapping %q -> %q", ErrInvalidMapping, name, target)
+		}
+	}
+	return nil
+}
+
+// Rejection is a field that could not be turned into a reading.
+type Rejection struct {
+	Line   int    `json:"line"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Resolver maps the fields of points to the properties of one factory.
+type Resolver struct {
+	mapping types.LineProtocolMapping
+	// assets indexes the factory's assets by ID and by name; properties
+	// indexes each asset's properties by ID and by name.
+	assets     map[string]string
+	properties map[string]map[string]*catalog.Series
+}
+
+func NewResolver(mapping types.LineProtocolMapping, series []catalog.Series) *Resolver {
+	if mapping.AssetTag == "" {
+		mapping.AssetTag = DEFAULTASSETTAG
+	}
+	r := &Resolver{
+		mapping:    mapping,
+		assets:     map[string]string{},
+		properties: map[string]map[string]*catalog.Series{},
+	}
+	// Names are indexed first so that an ID always wins over a name that
+	// happens to look like another asset's or property's ID.
+	for i := range series {
+		s := &series[i]
+		if _, ok := r.properties[s.AssetID]; !ok {
+			r.properties[s.AssetID] = map[string]*catalog.Series{}
+		}
+		r.assets[s.AssetName] = s.AssetID
+		r.properties[s.AssetID][s.Property.Name] = s
+	}
+	for i := range series {
+		s := &series[i]
+		r.assets[s.AssetID] = s.AssetID
+		r.properties[s.AssetID][s.Property.PropertyID] = s
+	}
+	return r
+}
+
+// Resolve turns every numeric or boolean field into a reading of the
+// property it maps to, truncating timestamps to milliseconds. Booleans
+// become 1 or 0; strings are rejected. Readings take the quality of the
+// QUALITYTAG tag and are flagged when outside their measurement's bounds.
+// Readings are stored by property and millisecond, so a field that resolves
+// to the same property and millisecond as an earlier one is rejected as a
+// duplicate.
+func (r *Resolver) Resolve(points []Point) ([]types.Reading, []Rejection) {
+	type key struct {
+		propertyID string
+		timestamp  int64
+	}
+	seen := map[key]int{}
+	var readings []types.Reading
+	var rejections []Rejection
+	for _, p := range points {
+		name, ok := p.Tag(r.mapping.AssetTag)
+		if !ok {
+			name = p.Measurement
+		}
+		if mapped, ok := r.mapping.Assets[name]; ok {
+			name = mapped
+		}
+		assetID, ok := r.assets[name]
+		if !ok {
+			rejections = append(rejections, Rejection{Line: p.Line, Reason: fmt.Sprintf("unknown asset %q", name)})
+			continue
+		}
+		code, _ := p.Tag(QUALITYTAG)
+		if _, _, err := quality.Parse(code); err != nil {
+			rejections = append(rejections, Rejection{Line: p.Line, Reason: err.Error()})
+			continue
+		}
+
+		for _, f := range p.Fields {
+			property, ok := r.mapping.Fields[p.Measurement+"."+f.Key]
+			if !ok {
+				if property, ok = r.mapping.Fields[f.Key]; !ok {
+					property = f.Key
+				}
+			}
+			s, ok := r.properties[assetID][property]
+			if !ok {
+				rejections = append(rejections, Rejection{Line: p.Line, Field: f.Key, Reason: fmt.Sprintf("unknown property %q", property)})
+				continue
+			}
+			value, ok := number(f.Value)
+			if !ok {
+				rejections = append(rejections, Rejection{Line: p.Line, Field: f.Key, Reason: "value is not numeric"})
+				continue
+			}
+			k := key{s.Property.PropertyID, p.Time.UnixMilli()}
+			if first, ok := seen[k]; ok {
+				rejections = append(rejections, Rejection{Line: p.Line, Field: f.Key, Reason: fmt.Sprintf("duplicate of line %d", first)})
+				continue
+			}
+			seen[k] = p.Line
+			readings = append(readings, quality.Assess(types.Reading{
+				PropertyID: s.Property.PropertyID,
+				Timestamp:  k.timestamp,
+				Value:      value,
+				Quality:    code,
+			}, s.Measurement))
+		}
+	}
+	return readings, rejections
+}
+
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,268 @@
+package lineprotocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPrecision = errors.New("invalid precision")
+
+// Point is one line: a measurement, its tags and fields, and a time. Field
+// values are float64, int64, uint64, string or bool.
+type Point struct {
+	Line        int
+	Measurement string
+	Tags        []Tag
+	Fields      []Field
+	Time        time.Time
+}
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+type Field struct {
+	Key   string
+	Value any
+}
+
+// LineError is a line that could not be parsed.
+type LineError struct {
+	Line   int
+	Reason string
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Tag returns the value of a tag and whether the point has it.
+func (p Point) Tag(key string) (string, bool) {
+	for _, t := range p.Tags {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParsePrecision accepts the precisions of both InfluxDB APIs: n/ns, u/us/µs,
+// ms, s, and the 1.x-only m and h. Empty means nanoseconds.
+func ParsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("%w %q", ErrInvalidPrecision, precision)
+	}
+}
+
+// Parse reads every line of data. Timestamps are in units of precision;
+// points without one get now. Lines that fail to parse are reported and
+// skipped, so one bad line does not cost the whole batch.
+func Parse(data []byte, precision time.Duration, now time.Time) ([]Point, []*LineError) {
+	var points []Point
+	var errs []*LineError
+	for number := 1; len(data) > 0; number++ {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		line = bytes.TrimRight(line, "\r")
+		if trimmed := bytes.TrimLeft(line, " \t"); len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+
+		p, err := parseLine(string(line), precision, now)
+		if err != nil {
+			errs = append(errs, &LineError{Line: number, Reason: err.Error()})
+			continue
+		}
+		p.Line = number
+		points = append(points, p)
+	}
+	return points, errs
+}
+
+func parseLine(line string, precision time.Duration, now time.Time) (Point, error) {
+	var p Point
+	s := &scanner{line: line}
+
+	p.Measurement = s.until(", ", false)
+	if p.Measurement == "" {
+		return p, errors.New("missing measurement")
+	}
+	for s.peek() == ',' {
+		s.pos++
+		key := s.until("=", true)
+		if !s.consume('=') || key == "" {
+			return p, errors.New("invalid tag set")
+		}
+		value := s.until(", ", true)
+		if value == "" {
+			return p, fmt.Errorf("missing value for tag %q", key)
+		}
+		p.Tags = append(p.Tags, Tag{Key: key, Value: value})
+	}
+	if !s.consume(' ') {
+		return p, errors.New("missing fields")
+	}
+
+	for {
+		key := s.until("=", true)
+		if !s.consume('=') || key == "" {
+			return p, errors.New("invalid field set")
+		}
+		value, err := s.fieldValue()
+		if err != nil {
+			return p, fmt.Errorf("field %q: %w", key, err)
+		}
+		p.Fields = append(p.Fields, Field{Key: key, Value: value})
+		if !s.consume(',') {
+			break
+		}
+	}
+
+	rest := strings.TrimSpace(line[s.pos:])
+	if rest == "" {
+		p.Time = now
+		return p, nil
+	}
+	if s.peek() != ' ' {
+		return p, errors.New("invalid field set")
+	}
+	ts, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return p, fmt.Errorf("invalid timestamp %q", rest)
+	}
+	if p.Time, err = timestamp(ts, precision); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// timestamp converts a count of precision units to a time, refusing what
+// does not fit in int64 nanoseconds, as InfluxDB does.
+func timestamp(ts int64, precision time.Duration) (time.Time, error) {
+	unit := int64(precision)
+	if ts > math.MaxInt64/unit || ts < math.MinInt64/unit {
+		return time.Time{}, fmt.Errorf("timestamp %d out of range", ts)
+	}
+	return time.Unix(0, ts*unit).UTC(), nil
+}
+
+type scanner struct {
+	line string
+	pos  int
+}
+
+func (s *scanner) peek() byte {
+	if s.pos >= len(s.line) {
+		return 0
+	}
+	return s.line[s.pos]
+}
+
+func (s *scanner) consume(c byte) bool {
+	if s.peek() != c {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+// until reads up to the first unescaped stop byte, unescaping backslashed
+// commas, spaces and, in keys and tag values, equals signs.
+func (s *scanner) until(stops string, escapesEquals bool) string {
+	var b strings.Builder
+	for s.pos < len(s.line) {
+		c := s.line[s.pos]
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		if c == '\\' && s.pos+1 < len(s.line) {
+			next := s.line[s.pos+1]
+			if next == ',' || next == ' ' || next == '\\' || next == '=' && escapesEquals {
+				b.WriteByte(next)
+				s.pos += 2
+				continue
+			}
+		}
+		b.WriteByte(c)
+		s.pos++
+	}
+	return b.String()
+}
+
+func (s *scanner) fieldValue() (any, error) {
+	if s.consume('"') {
+		var b strings.Builder
+		for s.pos < len(s.line) {
+			c := s.line[s.pos]
+			switch {
+			case c == '"':
+				s.pos++
+				return b.String(), nil
+			case c == '\\' && s.pos+1 < len(s.line) && (s.line[s.pos+1] == '"' || s.line[s.pos+1] == '\\'):
+				b.WriteByte(s.line[s.pos+1])
+				s.pos += 2
+			default:
+				b.WriteByte(c)
+				s.pos++
+			}
+		}
+		return nil, errors.New("unterminated string")
+	}
+
+	start := s.pos
+	for s.pos < len(s.line) && s.line[s.pos] != ',' && s.line[s.pos] != ' ' {
+		s.pos++
+	}
+	raw := s.line[start:s.pos]
+	switch raw {
+	case "":
+		return nil, errors.New("missing value")
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		v, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", raw)
+		}
+		return v, nil
+	case 'u':
+		v, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("invalid float %q", raw)
+	}
+	return v, nil
+}
@@ -0,0 +1,71 @@
+package lineprotocol
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// Append appends p as one line, with its tags sorted by key as InfluxDB
+// recommends and its time in units of precision. Tags with empty values are
+// left out since line protocol cannot express them.
+func Append(buf []byte, p Point, precision time.Duration) []byte {
+	buf = append(buf, measurementEscaper.Replace(p.Measurement)...)
+
+	tags := make([]Tag, 0, len(p.Tags))
+	for _, t := range p.Tags {
+		if t.Key != "" && t.Value != "" {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	for _, t := range tags {
+		buf = append(buf, ',')
+		buf = append(buf, keyEscaper.Replace(t.Key)...)
+		buf = append(buf, '=')
+		buf = append(buf, keyEscaper.Replace(t.Value)...)
+	}
+
+	for i, f := range p.Fields {
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, keyEscaper.Replace(f.Key)...)
+		buf = append(buf, '=')
+		buf = appendValue(buf, f.Value)
+	}
+
+	if !p.Time.IsZero() {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, p.Time.UnixNano()/int64(precision), 10)
+	}
+	return append(buf, '\n')
+}
+
+func appendValue(buf []byte, value any) []byte {
+	switch v := value.(type) {
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case int64:
+		return append(strconv.AppendInt(buf, v, 10), 'i')
+	case uint64:
+		return append(strconv.AppendUint(buf, v, 10), 'u')
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case string:
+		buf = append(buf, '"')
+		buf = append(buf, stringEscaper.Replace(v)...)
+		return append(buf, '"')
+	default:
+		return append(buf, `""`...)
+	}
+}
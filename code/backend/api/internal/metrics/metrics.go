@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+)
+
+const (
+	// MAXSERIES caps how many properties one scrape exposes, so a growing
+	// catalog cannot blow up Prometheus' series count.
+	MAXSERIES   = 10000
+	CONCURRENCY = 16
+	CONTENTTYPE = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Labels every property gauge carries, in exposition order.
+var LABELS = []string{"factory_id", "asset_id", "asset_name", "property_id", "property_name", "unit"}
+
+type Options struct {
+	// FactoryIDs limits the scrape to these factories; empty means all.
+	FactoryIDs []string
+	// Limit is the most properties exposed, at most MAXSERIES.
+	Limit int
+}
+
+// Sample is the latest reading of one property, with its quality assessed
+// at collection time.
+type Sample struct {
+	Series  catalog.Series
+	Reading types.Reading
+}
+
+// Snapshot is what one scrape exposes.
+type Snapshot struct {
+	Samples []Sample
+	// Series counts the properties in scope, including those without readings.
+	Series int
+	// Dropped counts the properties left out because of the limit.
+	Dropped int
+}
+
+type Collector struct {
+	Catalog *catalog.Catalog
+	Store   *timeseries.Store
+	Now     func() time.Time
+}
+
+func NewCollector(db types.DynamoDBClient) *Collector {
+	return &Collector{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+		Now:     time.Now,
+	}
+}
+
+// Collect reads the latest reading of every property of the factories in
+// scope. Properties are ordered by factory, asset and property ID and those
+// past the limit are dropped before any reading is fetched, so the exposed
+// set is stable from one scrape to the next.
+func (c Collector) Collect(ctx context.Context, opts Options) (*Snapshot, error) {
+	limit := opts.Limit
+	if limit <= 0 || limit > MAXSERIES {
+		limit = MAXSERIES
+	}
+
+	factoryIDs := opts.FactoryIDs
+	if len(factoryIDs) == 0 {
+		factories, err := c.Catalog.Factories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range factories {
+			factoryIDs = append(factoryIDs, f.FactoryID)
+		}
+	} else {
+		for _, id := range factoryIDs {
+			if _, err := c.Catalog.Factory(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var series []catalog.Series
+	for _, id := range factoryIDs {
+		factorySeries, err := c.Catalog.FactorySeries(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, factorySeries...)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		if a.FactoryID != b.FactoryID {
+			return a.FactoryID < b.FactoryID
+		}
+		if a.AssetID != b.AssetID {
+			return a.AssetID < b.AssetID
+		}
+		return a.Property.PropertyID < b.Property.PropertyID
+	})
+
+	snapshot := &Snapshot{Series: len(series)}
+	if len(series) > limit {
+		snapshot.Dropped = len(series) - limit
+		series = series[:limit]
+	}
+
+	readings, err := c.latest(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	now := c.Now()
+	for i, r := range readings {
+		if r != nil {
+			snapshot.Samples = append(snapshot.Samples, Sample{Series: series[i], Reading: quality.Current(*r, series[i].Measurement, now)})
+		}
+	}
+	return snapshot, nil
+}
+
+// latest fetches the latest readings of the series with CONCURRENCY
+// parallel queries.
+func (c Collector) latest(ctx context.Context, series []catalog.Series) ([]*types.Reading, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	readings := make([]*types.Reading, len(series))
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < CONCURRENCY; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				reading, err := c.Store.Latest(ctx, series[j].Property.PropertyID)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				readings[j] = reading
+			}
+		}()
+	}
+
+	for i := range series {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return readings, ctx.Err()
+}
+
+// WriteText writes a snapshot in the Prometheus text exposition format.
+// Readings keep their own time in wdd_property_timestamp_seconds rather
+// than as sample timestamps, which Prometheus would treat as stale once the
+// simulation pauses. Their quality is an info-style gauge labelled with its
+// severity and subcode.
+func WriteText(w io.Writer, snapshot *Snapshot) error {
+	var b strings.Builder
+
+	family := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	family("wdd_property_value", "Latest reading of a property.")
+	for _, s := range snapshot.Samples {
+		fmt.Fprintf(&b, "wdd_property_value{%s} %s\n", labels(s.Series), formatFloat(s.Reading.Value))
+	}
+	family("wdd_property_timestamp_seconds", "Unix time of the latest reading of a property.")
+	for _, s := range snapshot.Samples {
+		fmt.Fprintf(&b, "wdd_property_timestamp_seconds{%s} %s\n", labels(s.Series), formatFloat(float64(s.Reading.Timestamp)/1000))
+	}
+	family("wdd_property_quality", "Quality of the latest reading of a property, always 1.")
+	for _, s := range snapshot.Samples {
+		severity, subcode, _ := quality.Parse(s.Reading.Quality)
+		fmt.Fprintf(&b, "wdd_property_quality{%s,severity=\"%s\",subcode=\"%s\"} 1\n", labels(s.Series), severity, subcode)
+	}
+	family("wdd_metrics_series", "Properties in scope of the scrape.")
+	fmt.Fprintf(&b, "wdd_metrics_series %d\n", snapshot.Series)
+	family("wdd_metrics_series_dropped", "Properties left out because of the series limit.")
+	fmt.Fprintf(&b, "wdd_metrics_series_dropped %d\n", snapshot.Dropped)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labels(s catalog.Series) string {
+	values := []string{s.FactoryID, s.AssetID, s.AssetName, s.Property.PropertyID, s.Property.Name, s.Property.Unit}
+	pairs := make([]string, len(LABELS))
+	for i, name := range LABELS {
+		pairs[i] = name + `="` + escape(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(value string) string {
+	return escaper.Replace(value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func s(v string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: v}
+}
+
+func n(v string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: v}
+}
+
+// mockClient has one factory with one asset whose model has two properties;
+// only p1 has readings.
+func mockClient() *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{{"factoryId": s("f1")}}}, nil
+		},
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch aws.ToString(params.TableName) {
+			case "Factory":
+				if params.Key["factoryId"].(*types.AttributeValueMemberS).Value != "f1" {
+					return &dynamodb.GetItemOutput{}, nil
+				}
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"factoryId": s("f1")}}, nil
+			case "Property":
+				id := params.Key["propertyId"].(*types.AttributeValueMemberS).Value
+				names := map[string]string{"p1": "Oil temp", "p2": "Speed"}
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+					"propertyId": s(id), "measurementId": s("m1"), "name": s(names[id]), "unit": s("°C"),
+				}}, nil
+			default:
+				return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"measurementId": s("m1")}}, nil
+			}
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			switch aws.ToString(params.TableName) {
+			case "Asset":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"assetId": s("a1"), "factoryId": s("f1"), "name": s(`Press "A"`), "modelId": s("m1"),
+				}}}, nil
+			case "Model":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    s("m1"),
+					"properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("p2"), s("p1")}},
+				}}}, nil
+			default:
+				if params.ExpressionAttributeValues[":propertyId"].(*types.AttributeValueMemberS).Value != "p1" {
+					return &dynamodb.QueryOutput{}, nil
+				}
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"propertyId": s("p1"), "timestamp": n("1700000000500"), "value": n("21.5"),
+				}}}, nil
+			}
+		},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	snapshot, err := NewCollector(mockClient()).Collect(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot.Series != 2 || snapshot.Dropped != 0 || len(snapshot.Samples) != 1 {
+		t.Fatalf("Expected 2 series with one sample, got %+v", snapshot)
+	}
+
+	var b strings.Builder
+	if err = WriteText(&b, snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE wdd_property_value gauge\n",
+		`wdd_property_value{factory_id="f1",asset_id="a1",asset_name="Press \"A\"",property_id="p1",property_name="Oil temp",unit="°C"} 21.5` + "\n",
+		`wdd_property_timestamp_seconds{factory_id="f1",asset_id="a1",asset_name="Press \"A\"",property_id="p1",property_name="Oil temp",unit="°C"} 1.7000000005e+09` + "\n",
+		`wdd_property_quality{factory_id="f1",asset_id="a1",asset_name="Press \"A\"",property_id="p1",property_name="Oil temp",unit="°C",severity="uncertain",subcode="lastUsableValue"} 1` + "\n",
+		"wdd_metrics_series 2\n",
+		"wdd_metrics_series_dropped 0\n",
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestCollect_Limit(t *testing.T) {
+	snapshot, err := NewCollector(mockClient()).Collect(context.Background(), Options{FactoryIDs: []string{"f1"}, Limit: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Series are kept in property ID order, so p2 is the one dropped.
+	if snapshot.Dropped != 1 || len(snapshot.Samples) != 1 || snapshot.Samples[0].Series.Property.PropertyID != "p1" {
+		t.Errorf("Expected p2 to be dropped, got %+v", snapshot)
+	}
+}
+
+func TestCollect_UnknownFactory(t *testing.T) {
+	if _, err := NewCollector(mockClient()).Collect(context.Background(), Options{FactoryIDs: []string{"nope"}}); err == nil {
+		t.Errorf("Expected an error for an unknown factory")
+	}
+}
@@ -0,0 +1,199 @@
+package modbus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func testSeries() []catalog.Series {
+	return []catalog.Series{
+		{AssetID: "b", AssetName: "Pump", Property: types.Property{PropertyID: "p3", Name: "Flow", Unit: "l/min"}},
+		{AssetID: "a", AssetName: "Press", Property: types.Property{PropertyID: "p1", Name: "Oil temp", Unit: "°C"},
+			Measurement: types.Measurement{LowerBound: float(-20), UpperBound: float(120), Precision: float(0.1)}},
+		{AssetID: "a", AssetName: "Press", Property: types.Property{PropertyID: "p2", Name: "Speed", Unit: "rpm"},
+			Measurement: types.Measurement{LowerBound: float(0), UpperBound: float(6000), Precision: float(0.01)}},
+	}
+}
+
+func TestBuildRegisterMap(t *testing.T) {
+	m, err := BuildRegisterMap(testSeries(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []struct {
+		propertyID string
+		unit       byte
+		address    uint16
+		dataType   string
+		scale      float64
+	}{
+		{"p1", 1, 0, INT16, 0.1},
+		// 6000 / 0.01 does not fit in 16 bits.
+		{"p2", 1, 1, INT32, 0.01},
+		// Without bounds the range is unknown.
+		{"p3", 2, 0, INT32, 1},
+	}
+	for _, w := range want {
+		registers := m.Lookup(w.propertyID)
+		if len(registers) != 1 {
+			t.Fatalf("Expected %s to be mapped once, got %+v", w.propertyID, registers)
+		}
+		if r := registers[0]; r.UnitID != w.unit || r.Address != w.address || r.DataType != w.dataType || r.Scale != w.scale || r.Table != HOLDING {
+			t.Errorf("Unexpected register for %s: %+v", w.propertyID, registers[0])
+		}
+	}
+	if units := m.Units(); units[1] != 3 || units[2] != 2 {
+		t.Errorf("Unexpected unit sizes %v", units)
+	}
+
+	var out bytes.Buffer
+	if err = m.WriteCSV(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || lines[1] != "1,a,Press,p1,Oil temp,holding,0,1,int16,0.1,°C" {
+		t.Errorf("Unexpected CSV:\n%s", out.String())
+	}
+
+	if _, err = BuildRegisterMap(testSeries(), "coils"); err != ErrInvalidTable {
+		t.Errorf("Expected ErrInvalidTable, got %v", err)
+	}
+}
+
+func TestBuildRegisterMap_SharedModel(t *testing.T) {
+	// Both presses are of one model, so they share its properties.
+	series := []catalog.Series{
+		{AssetID: "a", AssetName: "Press 1", Property: types.Property{PropertyID: "p1", Name: "Oil temp"}},
+		{AssetID: "a", AssetName: "Press 1", Property: types.Property{PropertyID: "p2", Name: "Speed"}},
+		{AssetID: "b", AssetName: "Press 2", Property: types.Property{PropertyID: "p1", Name: "Oil temp"}},
+		{AssetID: "b", AssetName: "Press 2", Property: types.Property{PropertyID: "p2", Name: "Speed"}},
+	}
+	m, err := BuildRegisterMap(series, HOLDING)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if units := m.Units(); len(units) != 2 || units[1] != 4 || units[2] != 4 {
+		t.Errorf("Expected both units to hold both properties, got %v", units)
+	}
+	registers := m.Lookup("p2")
+	if len(registers) != 2 || registers[0].UnitID != 1 || registers[1].UnitID != 2 || registers[1].Address != 2 {
+		t.Fatalf("Expected p2 at address 2 of units 1 and 2, got %+v", registers)
+	}
+
+	server := NewServer(m)
+	server.Update(types.Reading{PropertyID: "p2", Value: 1500})
+	for _, unit := range []byte{1, 2} {
+		got, ok := server.Registers(unit, 2, 2)
+		if !ok || registers[0].Decode(got) != 1500 {
+			t.Errorf("Expected 1500 in unit %d, got %v", unit, got)
+		}
+	}
+}
+
+func TestRegister_Encode(t *testing.T) {
+	tests := []struct {
+		register Register
+		value    float64
+		want     []uint16
+		decoded  float64
+	}{
+		{Register{DataType: INT16, Scale: 0.1}, 21.34, []uint16{213}, 21.3},
+		{Register{DataType: INT16, Scale: 0.1}, -1.5, []uint16{0xfff1}, -1.5},
+		{Register{DataType: INT16, Scale: 1}, 40000, []uint16{0x7fff}, 32767},
+		{Register{DataType: INT32, Scale: 0.01}, 5999.99, []uint16{0x0009, 0x27bf}, 5999.99},
+		{Register{DataType: INT32, Scale: 1}, -2, []uint16{0xffff, 0xfffe}, -2},
+	}
+	for _, tt := range tests {
+		got := tt.register.Encode(tt.value)
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || len(got) > 1 && got[1] != tt.want[1] {
+			t.Errorf("Encode(%v) with %+v = %#04x, want %#04x", tt.value, tt.register, got, tt.want)
+		}
+		if decoded := tt.register.Decode(got); decoded-tt.decoded > 1e-9 || tt.decoded-decoded > 1e-9 {
+			t.Errorf("Decode(%#04x) = %v, want %v", got, decoded, tt.decoded)
+		}
+	}
+}
+
+func request(t *testing.T, conn net.Conn, transaction uint16, unit byte, pdu ...byte) []byte {
+	t.Helper()
+	frame := binary.BigEndian.AppendUint16(nil, transaction)
+	frame = append(frame, 0, 0)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(pdu)+1))
+	frame = append(append(frame, unit), pdu...)
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Unexpected error writing request: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatalf("Unexpected error reading response: %v", err)
+	}
+	if binary.BigEndian.Uint16(header) != transaction || header[6] != unit {
+		t.Errorf("Expected transaction %d for unit %d, got header % x", transaction, unit, header)
+	}
+	response := make([]byte, binary.BigEndian.Uint16(header[4:6])-1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatalf("Unexpected error reading response: %v", err)
+	}
+	return response
+}
+
+func TestServer_ReadRegisters(t *testing.T) {
+	m, err := BuildRegisterMap(testSeries(), HOLDING)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	server := NewServer(m)
+	addr, err := server.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer server.Close()
+
+	server.Update(types.Reading{PropertyID: "p1", Value: 21.3})
+	server.Update(types.Reading{PropertyID: "p2", Value: 1500})
+	server.Update(types.Reading{PropertyID: "unmapped", Value: 1})
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	got := request(t, conn, 1, 1, READHOLDINGREGISTERS, 0, 0, 0, 3)
+	want := []byte{READHOLDINGREGISTERS, 6, 0x00, 0xd5, 0x00, 0x02, 0x49, 0xf0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % x, got % x", want, got)
+	}
+
+	exceptions := []struct {
+		name string
+		unit byte
+		pdu  []byte
+		want []byte
+	}{
+		{"input table", 1, []byte{READINPUTREGISTERS, 0, 0, 0, 1}, []byte{0x84, ILLEGALDATAADDRESS}},
+		{"past the end", 1, []byte{READHOLDINGREGISTERS, 0, 2, 0, 2}, []byte{0x83, ILLEGALDATAADDRESS}},
+		{"too many registers", 1, []byte{READHOLDINGREGISTERS, 0, 0, 0, 126}, []byte{0x83, ILLEGALDATAVALUE}},
+		{"unknown unit", 9, []byte{READHOLDINGREGISTERS, 0, 0, 0, 1}, []byte{0x83, GATEWAYTARGETFAILED}},
+		{"write", 1, []byte{0x06, 0, 0, 0, 1}, []byte{0x86, ILLEGALFUNCTION}},
+	}
+	for i, tt := range exceptions {
+		if got := request(t, conn, uint16(i+2), tt.unit, tt.pdu...); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: expected % x, got % x", tt.name, tt.want, got)
+		}
+	}
+}
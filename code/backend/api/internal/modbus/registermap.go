@@ -0,0 +1,200 @@
+package modbus
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+const (
+	HOLDING   = "holding"
+	INPUT     = "input"
+	INT16     = "int16"
+	INT32     = "int32"
+	MAXUNITID = 247
+)
+
+var (
+	ErrInvalidTable  = errors.New("table must be holding or input")
+	ErrTooManyAssets = fmt.Errorf("a modbus server addresses at most %d assets", MAXUNITID)
+)
+
+// Register describes where one property lives: its unit ID, the address of
+// its first register and how the raw value is scaled.
+type Register struct {
+	UnitID       byte
+	AssetID      string
+	AssetName    string
+	PropertyID   string
+	PropertyName string
+	Unit         string
+	Table        string
+	Address      uint16
+	DataType     string
+	// Scale is the measurement's precision: value = raw * Scale.
+	Scale float64
+}
+
+// RegisterMap assigns every asset a unit ID, in asset ID order, and every
+// property of an asset consecutive registers from address 0, in model order.
+// Assets of the same model share their properties, so a property may have
+// registers in several units.
+type RegisterMap struct {
+	Table      string
+	Registers  []Register
+	byProperty map[string][]int
+}
+
+func BuildRegisterMap(series []catalog.Series, table string) (*RegisterMap, error) {
+	if table == "" {
+		table = HOLDING
+	}
+	if table != HOLDING && table != INPUT {
+		return nil, ErrInvalidTable
+	}
+
+	// Properties published on their own have no asset and share a unit.
+	var assetIDs []string
+	byAsset := map[string][]catalog.Series{}
+	for _, s := range series {
+		if _, ok := byAsset[s.AssetID]; !ok {
+			assetIDs = append(assetIDs, s.AssetID)
+		}
+		byAsset[s.AssetID] = append(byAsset[s.AssetID], s)
+	}
+	if len(assetIDs) > MAXUNITID {
+		return nil, ErrTooManyAssets
+	}
+	sort.Strings(assetIDs)
+
+	m := &RegisterMap{Table: table, byProperty: map[string][]int{}}
+	for i, assetID := range assetIDs {
+		var address uint16
+		mapped := map[string]bool{}
+		for _, s := range byAsset[assetID] {
+			if mapped[s.Property.PropertyID] {
+				continue
+			}
+			mapped[s.Property.PropertyID] = true
+			r := Register{
+				UnitID:       byte(i + 1),
+				AssetID:      assetID,
+				AssetName:    s.AssetName,
+				PropertyID:   s.Property.PropertyID,
+				PropertyName: s.Property.Name,
+				Unit:         s.Property.Unit,
+				Table:        table,
+				Address:      address,
+				Scale:        1,
+				DataType:     INT32,
+			}
+			if s.Measurement.Precision != nil && *s.Measurement.Precision > 0 {
+				r.Scale = *s.Measurement.Precision
+			}
+			if fitsInt16(s.Measurement, r.Scale) {
+				r.DataType = INT16
+			}
+
+			m.byProperty[r.PropertyID] = append(m.byProperty[r.PropertyID], len(m.Registers))
+			m.Registers = append(m.Registers, r)
+			address += uint16(r.Count())
+		}
+	}
+	return m, nil
+}
+
+// Lookup returns the registers of a property, one per asset that has it.
+func (m *RegisterMap) Lookup(propertyID string) []Register {
+	registers := make([]Register, 0, len(m.byProperty[propertyID]))
+	for _, i := range m.byProperty[propertyID] {
+		registers = append(registers, m.Registers[i])
+	}
+	return registers
+}
+
+// Units returns the number of registers each unit ID needs.
+func (m *RegisterMap) Units() map[byte]int {
+	units := map[byte]int{}
+	for _, r := range m.Registers {
+		if end := int(r.Address) + r.Count(); end > units[r.UnitID] {
+			units[r.UnitID] = end
+		}
+	}
+	return units
+}
+
+func (m *RegisterMap) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{"unitId", "assetId", "assetName", "propertyId", "propertyName", "table", "address", "count", "dataType", "scale", "unit"})
+	if err != nil {
+		return err
+	}
+	for _, r := range m.Registers {
+		err = out.Write([]string{
+			strconv.Itoa(int(r.UnitID)),
+			r.AssetID,
+			r.AssetName,
+			r.PropertyID,
+			r.PropertyName,
+			r.Table,
+			strconv.Itoa(int(r.Address)),
+			strconv.Itoa(r.Count()),
+			r.DataType,
+			strconv.FormatFloat(r.Scale, 'g', -1, 64),
+			r.Unit,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Count is the number of 16-bit registers the property occupies.
+func (r Register) Count() int {
+	if r.DataType == INT16 {
+		return 1
+	}
+	return 2
+}
+
+// Encode scales a value and splits it into registers, high word first.
+// Values outside the data type's range saturate.
+func (r Register) Encode(value float64) []uint16 {
+	raw := math.Round(value / r.Scale)
+	if r.DataType == INT16 {
+		return []uint16{uint16(int16(clamp(raw, math.MinInt16, math.MaxInt16)))}
+	}
+	v := uint32(int32(clamp(raw, math.MinInt32, math.MaxInt32)))
+	return []uint16{uint16(v >> 16), uint16(v)}
+}
+
+// Decode reverses Encode.
+func (r Register) Decode(registers []uint16) float64 {
+	if r.DataType == INT16 {
+		return float64(int16(registers[0])) * r.Scale
+	}
+	return float64(int32(uint32(registers[0])<<16|uint32(registers[1]))) * r.Scale
+}
+
+func fitsInt16(measurement types.Measurement, scale float64) bool {
+	if measurement.LowerBound == nil || measurement.UpperBound == nil {
+		return false
+	}
+	lower, upper := math.Round(*measurement.LowerBound/scale), math.Round(*measurement.UpperBound/scale)
+	return lower >= math.MinInt16 && upper <= math.MaxInt16
+}
+
+func clamp(v, lower, upper float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Max(lower, math.Min(upper, v))
+}
@@ -0,0 +1,217 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"wdd/api/internal/types"
+)
+
+// Function codes.
+const (
+	READHOLDINGREGISTERS = 0x03
+	READINPUTREGISTERS   = 0x04
+	MAXREADREGISTERS     = 125
+	IDLETIMEOUT          = 5 * time.Minute
+)
+
+// Exception codes.
+const (
+	ILLEGALFUNCTION     = 0x01
+	ILLEGALDATAADDRESS  = 0x02
+	ILLEGALDATAVALUE    = 0x03
+	GATEWAYTARGETFAILED = 0x0B
+)
+
+const (
+	mbapHeaderLength     = 7
+	maxPDULength         = 253
+	exceptionFunctionBit = 0x80
+)
+
+var ErrClosed = errors.New("modbus server closed")
+
+// Server is a read-only Modbus TCP slave serving the latest value of every
+// property in a register map.
+type Server struct {
+	Map *RegisterMap
+
+	mu    sync.RWMutex
+	banks map[byte][]uint16
+
+	connMu    sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func NewServer(m *RegisterMap) *Server {
+	s := &Server{
+		Map:   m,
+		banks: map[byte][]uint16{},
+		conns: map[net.Conn]struct{}{},
+	}
+	for unit, size := range m.Units() {
+		s.banks[unit] = make([]uint16, size)
+	}
+	return s
+}
+
+// Update stores a reading in its property's registers of every unit.
+// Readings for properties outside the map are ignored.
+func (s *Server) Update(reading types.Reading) {
+	registers := s.Map.Lookup(reading.PropertyID)
+	s.mu.Lock()
+	for _, r := range registers {
+		copy(s.banks[r.UnitID][r.Address:], r.Encode(reading.Value))
+	}
+	s.mu.Unlock()
+}
+
+// Registers returns a copy of count registers of a unit starting at address.
+func (s *Server) Registers(unit byte, address uint16, count int) ([]uint16, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bank, ok := s.banks[unit]
+	if !ok || int(address)+count > len(bank) {
+		return nil, false
+	}
+	return append([]uint16(nil), bank[address:int(address)+count]...), true
+}
+
+// ListenAndServe listens on addr and serves until the server is closed. It
+// returns the listener's address once bound.
+func (s *Server) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go s.Serve(listener)
+	return listener.Addr(), nil
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		listener.Close()
+		return ErrClosed
+	}
+	s.listeners = append(s.listeners, listener)
+	s.connMu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.connMu.Lock()
+			delete(s.conns, conn)
+			s.connMu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	s.closed = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, mbapHeaderLength)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(IDLETIMEOUT))
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		// The length counts the unit ID and the PDU.
+		length := int(binary.BigEndian.Uint16(header[4:6]))
+		if binary.BigEndian.Uint16(header[2:4]) != 0 || length < 2 || length > maxPDULength+1 {
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := s.handle(header[6], pdu)
+		frame := make([]byte, mbapHeaderLength, mbapHeaderLength+len(response))
+		copy(frame, header[:4])
+		binary.BigEndian.PutUint16(frame[4:6], uint16(len(response)+1))
+		frame[6] = header[6]
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(unit byte, pdu []byte) []byte {
+	function := pdu[0]
+	exception := func(code byte) []byte {
+		return []byte{function | exceptionFunctionBit, code}
+	}
+
+	if function != READHOLDINGREGISTERS && function != READINPUTREGISTERS {
+		return exception(ILLEGALFUNCTION)
+	}
+	if len(pdu) != 5 {
+		return exception(ILLEGALDATAVALUE)
+	}
+	address := binary.BigEndian.Uint16(pdu[1:3])
+	count := int(binary.BigEndian.Uint16(pdu[3:5]))
+	if count < 1 || count > MAXREADREGISTERS {
+		return exception(ILLEGALDATAVALUE)
+	}
+
+	s.mu.RLock()
+	_, known := s.banks[unit]
+	s.mu.RUnlock()
+	if !known {
+		return exception(GATEWAYTARGETFAILED)
+	}
+	// Values live in one table only; the other reads as unmapped.
+	if function == READHOLDINGREGISTERS && s.Map.Table != HOLDING || function == READINPUTREGISTERS && s.Map.Table != INPUT {
+		return exception(ILLEGALDATAADDRESS)
+	}
+	registers, ok := s.Registers(unit, address, count)
+	if !ok {
+		return exception(ILLEGALDATAADDRESS)
+	}
+
+	response := []byte{function, byte(count * 2)}
+	for _, r := range registers {
+		response = binary.BigEndian.AppendUint16(response, r)
+	}
+	return response
+}
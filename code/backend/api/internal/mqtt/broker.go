@@ -0,0 +1,397 @@
+package mqtt
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Broker is a small embedded MQTT 3.1.1 broker for local runs and tests. It
+// keeps retained messages and wills but no persistent sessions, and delivers
+// at most QoS 1 to subscribers.
+type Broker struct {
+	// Authenticate, when set, accepts or rejects CONNECT credentials.
+	Authenticate func(clientID, username, password string) bool
+
+	mu        sync.Mutex
+	sessions  map[string]*session
+	retained  map[string]Message
+	listeners []net.Listener
+	closed    bool
+	generated int
+	wg        sync.WaitGroup
+}
+
+type session struct {
+	broker   *Broker
+	conn     net.Conn
+	clientID string
+	will     *Message
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	subs    map[string]byte
+	nextID  uint16
+	// inbound holds QoS 2 messages until the client releases them.
+	inbound map[uint16]Message
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		sessions: map[string]*session{},
+		retained: map[string]Message{},
+	}
+}
+
+// ListenAndServe listens on addr and serves until the broker is closed. It
+// returns the listener's address once bound, so an addr of ":0" can be used.
+func (b *Broker) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go b.Serve(listener)
+	return listener.Addr(), nil
+}
+
+func (b *Broker) Serve(listener net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		listener.Close()
+		return ErrClosed
+	}
+	b.listeners = append(b.listeners, listener)
+	b.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.serveConn(conn)
+		}()
+	}
+}
+
+// Close stops every listener and drops every connection.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	for _, listener := range b.listeners {
+		listener.Close()
+	}
+	for _, s := range b.sessions {
+		s.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// Retained returns the retained message on a topic, if any.
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.retained[topic]
+	return m, ok
+}
+
+func (b *Broker) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(DEFAULTTIMEOUT))
+	first, err := readPacket(r)
+	if err != nil || first.kind != CONNECT {
+		return
+	}
+	s, keepAlive, code := b.accept(conn, first)
+	if _, err = conn.Write(packet{kind: CONNACK, body: []byte{0, code}}.encode()); err != nil || code != 0 {
+		return
+	}
+	defer b.drop(s)
+
+	for {
+		if keepAlive > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		} else {
+			_ = conn.SetReadDeadline(time.Time{})
+		}
+
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		if p.kind == DISCONNECT {
+			b.mu.Lock()
+			s.will = nil
+			b.mu.Unlock()
+			return
+		}
+		if err = s.handle(p); err != nil {
+			return
+		}
+	}
+}
+
+func (b *Broker) accept(conn net.Conn, p packet) (*session, time.Duration, byte) {
+	r := &reader{buf: p.body}
+	protocol, level, flags := r.string(), r.byte(), r.byte()
+	keepAlive := time.Duration(r.uint16()) * time.Second
+	if r.err != nil || protocol != "MQTT" || level != 4 {
+		return nil, 0, 1
+	}
+
+	s := &session{broker: b, conn: conn, clientID: r.string(), subs: map[string]byte{}, inbound: map[uint16]Message{}}
+	if flags&0x04 != 0 {
+		s.will = &Message{Topic: r.string(), Payload: r.bytes(), QoS: (flags >> 3) & 0x03, Retain: flags&0x20 != 0}
+	}
+	var username, password string
+	if flags&0x80 != 0 {
+		username = r.string()
+	}
+	if flags&0x40 != 0 {
+		password = r.string()
+	}
+	if r.err != nil {
+		return nil, 0, 2
+	}
+	if b.Authenticate != nil && !b.Authenticate(s.clientID, username, password) {
+		return nil, 0, 4
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.clientID == "" {
+		b.generated++
+		s.clientID = fmt.Sprintf("wdd-%d", b.generated)
+	}
+	// A second connection with the same client ID takes over the session;
+	// the will is guarded by b.mu because the two sessions race here.
+	if previous, ok := b.sessions[s.clientID]; ok {
+		previous.will = nil
+		previous.conn.Close()
+	}
+	b.sessions[s.clientID] = s
+	return s, keepAlive, 0
+}
+
+func (b *Broker) drop(s *session) {
+	b.mu.Lock()
+	if b.sessions[s.clientID] == s {
+		delete(b.sessions, s.clientID)
+	}
+	will := s.will
+	b.mu.Unlock()
+
+	if will != nil {
+		b.route(*will)
+	}
+}
+
+// route stores retained messages and forwards the message to every matching
+// subscription.
+func (b *Broker) route(m Message) {
+	b.mu.Lock()
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m
+		}
+	}
+	sessions := make([]*session, 0, len(b.sessions))
+	for _, s := range b.sessions {
+		sessions = append(sessions, s)
+	}
+	b.mu.Unlock()
+
+	m.Retain = false
+	for _, s := range sessions {
+		if qos, ok := s.granted(m.Topic); ok {
+			s.send(m, qos)
+		}
+	}
+}
+
+func (s *session) handle(p packet) error {
+	switch p.kind {
+	case PUBLISH:
+		m, id, err := parsePublish(p)
+		if err != nil {
+			return err
+		}
+		switch m.QoS {
+		case 0:
+			s.broker.route(m)
+		case 1:
+			s.broker.route(m)
+			return s.write(ackPacket(PUBACK, id))
+		case 2:
+			s.mu.Lock()
+			s.inbound[id] = m
+			s.mu.Unlock()
+			return s.write(ackPacket(PUBREC, id))
+		}
+	case PUBREL:
+		id := packetID(p)
+		s.mu.Lock()
+		m, ok := s.inbound[id]
+		delete(s.inbound, id)
+		s.mu.Unlock()
+		if ok {
+			s.broker.route(m)
+		}
+		return s.write(ackPacket(PUBCOMP, id))
+	case PUBREC:
+		return s.write(ackPacket(PUBREL, packetID(p)))
+	case SUBSCRIBE:
+		return s.subscribe(p)
+	case UNSUBSCRIBE:
+		r := &reader{buf: p.body}
+		id := r.uint16()
+		s.mu.Lock()
+		for len(r.buf) > 0 && r.err == nil {
+			delete(s.subs, r.string())
+		}
+		s.mu.Unlock()
+		if r.err != nil {
+			return r.err
+		}
+		return s.write(ackPacket(UNSUBACK, id))
+	case PINGREQ:
+		return s.write(packet{kind: PINGRESP})
+	case PUBACK, PUBCOMP:
+	default:
+		return fmt.Errorf("%w: unexpected packet type %d", ErrMalformedPacket, p.kind)
+	}
+	return nil
+}
+
+func (s *session) subscribe(p packet) error {
+	r := &reader{buf: p.body}
+	id := r.uint16()
+
+	var filters []string
+	codes := []byte{byte(id >> 8), byte(id)}
+	for len(r.buf) > 0 && r.err == nil {
+		filter, qos := r.string(), r.byte()
+		if qos > 2 || !validFilter(filter) {
+			codes = append(codes, 0x80)
+			continue
+		}
+		// Outbound delivery is capped at QoS 1.
+		if qos > 1 {
+			qos = 1
+		}
+		s.mu.Lock()
+		s.subs[filter] = qos
+		s.mu.Unlock()
+		filters = append(filters, filter)
+		codes = append(codes, qos)
+	}
+	if r.err != nil || len(codes) == 2 {
+		return ErrMalformedPacket
+	}
+	if err := s.write(packet{kind: SUBACK, body: codes}); err != nil {
+		return err
+	}
+
+	s.broker.mu.Lock()
+	var retained []Message
+	for topic, m := range s.broker.retained {
+		for _, filter := range filters {
+			if Match(filter, topic) {
+				retained = append(retained, m)
+				break
+			}
+		}
+	}
+	s.broker.mu.Unlock()
+
+	for _, m := range retained {
+		if qos, ok := s.granted(m.Topic); ok {
+			s.send(m, qos)
+		}
+	}
+	return nil
+}
+
+// granted returns the highest QoS of the session's subscriptions matching topic.
+func (s *session) granted(topic string) (byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var qos byte
+	matched := false
+	for filter, q := range s.subs {
+		if Match(filter, topic) {
+			if q > qos {
+				qos = q
+			}
+			matched = true
+		}
+	}
+	return qos, matched
+}
+
+func (s *session) send(m Message, qos byte) {
+	if m.QoS > qos {
+		m.QoS = qos
+	}
+	var id uint16
+	if m.QoS > 0 {
+		s.mu.Lock()
+		if s.nextID++; s.nextID == 0 {
+			s.nextID = 1
+		}
+		id = s.nextID
+		s.mu.Unlock()
+	}
+	// A failed write closes the connection, which ends the session's read loop.
+	_ = s.write(publishPacket(m, id, false))
+}
+
+func (s *session) write(p packet) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(DEFAULTTIMEOUT))
+	if _, err := s.conn.Write(p.encode()); err != nil {
+		s.conn.Close()
+		return err
+	}
+	return nil
+}
+
+func validFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	for i := 0; i < len(filter); i++ {
+		switch filter[i] {
+		case '#':
+			if i != len(filter)-1 || i > 0 && filter[i-1] != '/' {
+				return false
+			}
+		case '+':
+			if i > 0 && filter[i-1] != '/' || i < len(filter)-1 && filter[i+1] != '/' {
+				return false
+			}
+		}
+	}
+	return true
+}
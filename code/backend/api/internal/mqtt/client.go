@@ -0,0 +1,397 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DEFAULTKEEPALIVE = 30 * time.Second
+	DEFAULTTIMEOUT   = 10 * time.Second
+)
+
+var (
+	ErrClosed     = errors.New("mqtt connection closed")
+	ErrInvalidQoS = errors.New("qos must be 0, 1 or 2")
+	// ErrPasswordWithoutUsername is returned by Dial: MQTT 3.1.1 only sends
+	// a password after a user name [MQTT-3.1.2-22].
+	ErrPasswordWithoutUsername = errors.New("mqtt password requires a username")
+)
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+type Options struct {
+	// Broker is host:port, optionally prefixed with tcp:// or mqtt://.
+	Broker    string
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	// Timeout bounds connecting and waiting for acknowledgements.
+	Timeout time.Duration
+	// Will is published by the broker if the connection drops without a
+	// DISCONNECT.
+	Will *Message
+}
+
+// Client is a minimal MQTT 3.1.1 client with a clean session: it publishes
+// and subscribes at QoS 0, 1 and 2 but does not resend in-flight messages
+// after a reconnect.
+type Client struct {
+	opts Options
+	conn net.Conn
+
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	nextID   uint16
+	pending  map[uint16]chan packet
+	handlers []handler
+	lastRead atomic.Int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+type handler struct {
+	filter string
+	fn     func(Message)
+}
+
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DEFAULTKEEPALIVE
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULTTIMEOUT
+	}
+	if opts.Will != nil && opts.Will.QoS > 2 {
+		return nil, ErrInvalidQoS
+	}
+	if opts.Password != "" && opts.Username == "" {
+		return nil, ErrPasswordWithoutUsername
+	}
+
+	addr := strings.TrimPrefix(strings.TrimPrefix(opts.Broker, "tcp://"), "mqtt://")
+	dialer := net.Dialer{Timeout: opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to mqtt broker: %w", err)
+	}
+
+	c := &Client{
+		opts:    opts,
+		conn:    conn,
+		pending: map[uint16]chan packet{},
+		done:    make(chan struct{}),
+	}
+	r := bufio.NewReader(conn)
+	if err = c.connect(r); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c.lastRead.Store(time.Now().UnixNano())
+	go c.readLoop(r)
+	go c.keepAlive()
+	return c, nil
+}
+
+func (c *Client) connect(r *bufio.Reader) error {
+	flags := byte(0x02)
+	if will := c.opts.Will; will != nil {
+		flags |= 0x04 | will.QoS<<3
+		if will.Retain {
+			flags |= 0x20
+		}
+	}
+	if c.opts.Password != "" {
+		flags |= 0x40
+	}
+	if c.opts.Username != "" {
+		flags |= 0x80
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(c.opts.KeepAlive/time.Second>>8), byte(c.opts.KeepAlive/time.Second))
+	body = appendString(body, c.opts.ClientID)
+	if will := c.opts.Will; will != nil {
+		body = appendString(body, will.Topic)
+		body = appendBytes(body, will.Payload)
+	}
+	if c.opts.Username != "" {
+		body = appendString(body, c.opts.Username)
+	}
+	if c.opts.Password != "" {
+		body = appendString(body, c.opts.Password)
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(c.opts.Timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if _, err := c.conn.Write(packet{kind: CONNECT, body: body}.encode()); err != nil {
+		return fmt.Errorf("error sending connect: %w", err)
+	}
+
+	ack, err := readPacket(r)
+	if err != nil {
+		return fmt.Errorf("error reading connack: %w", err)
+	}
+	if ack.kind != CONNACK || len(ack.body) != 2 {
+		return fmt.Errorf("%w: expected connack", ErrMalformedPacket)
+	}
+	if code := ack.body[1]; code != 0 {
+		return fmt.Errorf("mqtt broker refused connection: %s", connackErrors[code])
+	}
+	return nil
+}
+
+// Publish sends a message and, for QoS 1 and 2, waits for the broker to
+// acknowledge it.
+func (c *Client) Publish(ctx context.Context, m Message) error {
+	if m.QoS > 2 {
+		return ErrInvalidQoS
+	}
+	if m.QoS == 0 {
+		return c.write(publishPacket(m, 0, false))
+	}
+
+	id, acks := c.track()
+	defer c.untrack(id)
+	if err := c.write(publishPacket(m, id, false)); err != nil {
+		return err
+	}
+
+	expected := byte(PUBACK)
+	if m.QoS == 2 {
+		expected = PUBREC
+	}
+	if err := c.await(ctx, acks, expected); err != nil {
+		return err
+	}
+	if m.QoS == 2 {
+		if err := c.write(ackPacket(PUBREL, id)); err != nil {
+			return err
+		}
+		return c.await(ctx, acks, PUBCOMP)
+	}
+	return nil
+}
+
+// Subscribe registers fn for messages matching filter. Handlers run on the
+// connection's read loop and must not block.
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte, fn func(Message)) error {
+	if qos > 2 {
+		return ErrInvalidQoS
+	}
+	c.mu.Lock()
+	c.handlers = append(c.handlers, handler{filter: filter, fn: fn})
+	c.mu.Unlock()
+
+	id, acks := c.track()
+	defer c.untrack(id)
+	body := appendString([]byte{byte(id >> 8), byte(id)}, filter)
+	if err := c.write(packet{kind: SUBSCRIBE, flags: 0x02, body: append(body, qos)}); err != nil {
+		return err
+	}
+	return c.await(ctx, acks, SUBACK)
+}
+
+// Disconnect closes the connection cleanly, so the broker discards the will.
+func (c *Client) Disconnect() error {
+	err := c.write(packet{kind: DISCONNECT})
+	c.fail(ErrClosed)
+	return err
+}
+
+// Close drops the connection without a DISCONNECT, so the broker publishes
+// the will.
+func (c *Client) Close() error {
+	c.fail(ErrClosed)
+	return nil
+}
+
+// Done is closed once the connection is gone; Err then says why.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Err() error {
+	<-c.done
+	return c.err
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			c.fail(fmt.Errorf("error reading from mqtt broker: %w", err))
+			return
+		}
+		c.lastRead.Store(time.Now().UnixNano())
+
+		switch p.kind {
+		case PUBLISH:
+			m, id, err := parsePublish(p)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			c.deliver(m)
+			switch m.QoS {
+			case 1:
+				err = c.write(ackPacket(PUBACK, id))
+			case 2:
+				err = c.write(ackPacket(PUBREC, id))
+			}
+			if err != nil {
+				return
+			}
+		case PUBREL:
+			if c.write(ackPacket(PUBCOMP, packetID(p))) != nil {
+				return
+			}
+		case PUBACK, PUBREC, PUBCOMP, SUBACK, UNSUBACK:
+			c.mu.Lock()
+			acks, ok := c.pending[packetID(p)]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case acks <- p:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(c.opts.KeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, c.lastRead.Load())) > c.opts.KeepAlive*3/2 {
+				c.fail(errors.New("mqtt broker stopped responding"))
+				return
+			}
+			if c.write(packet{kind: PINGREQ}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) deliver(m Message) {
+	c.mu.Lock()
+	handlers := append([]handler(nil), c.handlers...)
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		if Match(h.filter, m.Topic) {
+			h.fn(m)
+		}
+	}
+}
+
+func (c *Client) write(p packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.opts.Timeout))
+	if _, err := c.conn.Write(p.encode()); err != nil {
+		c.fail(fmt.Errorf("error writing to mqtt broker: %w", err))
+		return c.err
+	}
+	return nil
+}
+
+func (c *Client) track() (uint16, chan packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		c.nextID++
+		if _, taken := c.pending[c.nextID]; c.nextID != 0 && !taken {
+			break
+		}
+	}
+	acks := make(chan packet, 2)
+	c.pending[c.nextID] = acks
+	return c.nextID, acks
+}
+
+func (c *Client) untrack(id uint16) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *Client) await(ctx context.Context, acks chan packet, kind byte) error {
+	timer := time.NewTimer(c.opts.Timeout)
+	defer timer.Stop()
+
+	select {
+	case p := <-acks:
+		if p.kind != kind {
+			return fmt.Errorf("%w: expected packet type %d, got %d", ErrMalformedPacket, kind, p.kind)
+		}
+		if kind == SUBACK && len(p.body) > 2 && p.body[2] == 0x80 {
+			return errors.New("mqtt broker rejected subscription")
+		}
+		return nil
+	case <-timer.C:
+		return errors.New("timed out waiting for mqtt acknowledgement")
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return c.err
+	}
+}
+
+func (c *Client) fail(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// Match reports whether a topic matches a subscription filter with the +
+// and # wildcards.
+func Match(filter, topic string) bool {
+	// Topics starting with $ are not matched by filters starting with a wildcard.
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
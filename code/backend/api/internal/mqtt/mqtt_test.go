@@ -0,0 +1,167 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func startBroker(t *testing.T) (*Broker, string) {
+	t.Helper()
+	broker := NewBroker()
+	addr, err := broker.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error starting broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker, addr.String()
+}
+
+func dial(t *testing.T, addr, clientID string, will *Message) *Client {
+	t.Helper()
+	client, err := Dial(context.Background(), Options{Broker: "tcp://" + addr, ClientID: clientID, Will: will, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func receive(t *testing.T, messages <-chan Message) Message {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return Message{}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b/c", "a/b/c", true},
+		{"a/+/c", "a/b/c", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"#", "$SYS/uptime", false},
+		{"a/b", "a/b/c", false},
+		{"a/b/c/d", "a/b/c", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	_, addr := startBroker(t)
+	subscriber := dial(t, addr, "subscriber", nil)
+	publisher := dial(t, addr, "publisher", nil)
+	ctx := context.Background()
+
+	messages := make(chan Message, 10)
+	if err := subscriber.Subscribe(ctx, "plant/+/temperature", 1, func(m Message) { messages <- m }); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	for qos := byte(0); qos <= 2; qos++ {
+		err := publisher.Publish(ctx, Message{Topic: "plant/press/temperature", Payload: []byte{'0' + qos}, QoS: qos})
+		if err != nil {
+			t.Fatalf("Unexpected error publishing at qos %d: %v", qos, err)
+		}
+		m := receive(t, messages)
+		if string(m.Payload) != string([]byte{'0' + qos}) {
+			t.Errorf("Expected payload %d, got %q", qos, m.Payload)
+		}
+		want := qos
+		if want > 1 {
+			want = 1
+		}
+		if m.QoS != want {
+			t.Errorf("Expected delivery at qos %d, got %d", want, m.QoS)
+		}
+	}
+
+	if err := publisher.Publish(ctx, Message{Topic: "plant/press/pressure", Payload: []byte("x")}); err != nil {
+		t.Fatalf("Unexpected error publishing: %v", err)
+	}
+	select {
+	case m := <-messages:
+		t.Errorf("Expected no message for an unmatched topic, got %+v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBroker_Retained(t *testing.T) {
+	broker, addr := startBroker(t)
+	publisher := dial(t, addr, "publisher", nil)
+	ctx := context.Background()
+
+	if err := publisher.Publish(ctx, Message{Topic: "plant/press/speed", Payload: []byte("42"), QoS: 1, Retain: true}); err != nil {
+		t.Fatalf("Unexpected error publishing: %v", err)
+	}
+	if _, ok := broker.Retained("plant/press/speed"); !ok {
+		t.Fatal("Expected the message to be retained")
+	}
+
+	late := dial(t, addr, "late", nil)
+	messages := make(chan Message, 1)
+	if err := late.Subscribe(ctx, "plant/#", 0, func(m Message) { messages <- m }); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	if m := receive(t, messages); string(m.Payload) != "42" || !m.Retain {
+		t.Errorf("Expected the retained value, got %+v", m)
+	}
+
+	if err := publisher.Publish(ctx, Message{Topic: "plant/press/speed", QoS: 1, Retain: true}); err != nil {
+		t.Fatalf("Unexpected error publishing: %v", err)
+	}
+	receive(t, messages)
+	if _, ok := broker.Retained("plant/press/speed"); ok {
+		t.Error("Expected an empty retained message to clear the topic")
+	}
+}
+
+func TestBroker_Will(t *testing.T) {
+	_, addr := startBroker(t)
+	watcher := dial(t, addr, "watcher", nil)
+	messages := make(chan Message, 1)
+	if err := watcher.Subscribe(context.Background(), "status/#", 1, func(m Message) { messages <- m }); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	clean := dial(t, addr, "clean", &Message{Topic: "status/clean", Payload: []byte("offline")})
+	if err := clean.Disconnect(); err != nil {
+		t.Fatalf("Unexpected error disconnecting: %v", err)
+	}
+	dropped := dial(t, addr, "dropped", &Message{Topic: "status/dropped", Payload: []byte("offline")})
+	dropped.Close()
+
+	if m := receive(t, messages); m.Topic != "status/dropped" {
+		t.Errorf("Expected only the dropped client's will, got %+v", m)
+	}
+}
+
+func TestDial_Rejected(t *testing.T) {
+	broker, addr := startBroker(t)
+	broker.Authenticate = func(_, username, password string) bool { return username == "user" && password == "secret" }
+
+	if _, err := Dial(context.Background(), Options{Broker: addr, Username: "user", Password: "wrong"}); err == nil {
+		t.Error("Expected bad credentials to be refused")
+	}
+	if _, err := Dial(context.Background(), Options{Broker: addr, Password: "secret"}); !errors.Is(err, ErrPasswordWithoutUsername) {
+		t.Errorf("Expected ErrPasswordWithoutUsername for a password without a username, got %v", err)
+	}
+	client, err := Dial(context.Background(), Options{Broker: addr, Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	client.Disconnect()
+}
@@ -0,0 +1,356 @@
+package opcua
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	NAMESPACEURI   = "urn:wdd:simulator"
+	APPLICATIONURI = "urn:wdd:simulator:server"
+	PRODUCTURI     = "urn:wdd:simulator"
+	PRODUCTNAME    = "wdd simulator"
+)
+
+// Access level bits.
+const accessCurrentRead = 0x01
+
+type Reference struct {
+	Type    NodeID
+	Forward bool
+	Target  NodeID
+}
+
+// Node is a node of the address space. Everything but the value of a
+// variable is fixed once the space is built.
+type Node struct {
+	ID          NodeID
+	Class       NodeClass
+	BrowseName  QualifiedName
+	DisplayName LocalizedText
+	Description LocalizedText
+	References  []Reference
+
+	// Variables and variable types.
+	DataType  NodeID
+	ValueRank int32
+	// MinimumSamplingInterval is in milliseconds.
+	MinimumSamplingInterval float64
+
+	// Types.
+	IsAbstract  bool
+	Symmetric   bool
+	InverseName LocalizedText
+
+	value DataValue
+	// dynamic computes the value on every read.
+	dynamic func() any
+}
+
+// AddressSpace holds the standard nodes a client expects (Root, Objects,
+// Types, Server) plus the simulated factories, assets and properties in
+// namespace 1.
+type AddressSpace struct {
+	mu         sync.RWMutex
+	nodes      map[NodeID]*Node
+	properties map[string][]NodeID
+	supertypes map[NodeID]NodeID
+	started    time.Time
+}
+
+func NewAddressSpace() *AddressSpace {
+	a := &AddressSpace{
+		nodes:      map[NodeID]*Node{},
+		properties: map[string][]NodeID{},
+		supertypes: map[NodeID]NodeID{},
+		started:    time.Now().UTC(),
+	}
+	a.addStandardNodes()
+	return a
+}
+
+// Node returns a node by ID.
+func (a *AddressSpace) Node(id NodeID) (*Node, bool) {
+	n, ok := a.nodes[id]
+	return n, ok
+}
+
+// PropertyNodes returns the variables of a simulated property, one per asset
+// that has it.
+func (a *AddressSpace) PropertyNodes(propertyID string) []NodeID {
+	return a.properties[propertyID]
+}
+
+// SetValue updates a variable's value and returns the stored data value.
+func (a *AddressSpace) SetValue(id NodeID, value any, source time.Time) (DataValue, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n, ok := a.nodes[id]
+	if !ok || n.Class != ClassVariable {
+		return DataValue{}, false
+	}
+	n.value = DataValue{Value: value, SourceTimestamp: source, ServerTimestamp: time.Now().UTC()}
+	return n.value, true
+}
+
+// Read returns one attribute of a node.
+func (a *AddressSpace) Read(id NodeID, attribute uint32) DataValue {
+	n, ok := a.nodes[id]
+	if !ok {
+		return DataValue{Status: BadNodeIDUnknown}
+	}
+
+	value := func(v any) DataValue { return DataValue{Value: v} }
+	switch attribute {
+	case AttributeNodeID:
+		return value(n.ID)
+	case AttributeNodeClass:
+		return value(int32(n.Class))
+	case AttributeBrowseName:
+		return value(n.BrowseName)
+	case AttributeDisplayName:
+		return value(n.DisplayName)
+	case AttributeDescription:
+		return value(n.Description)
+	case AttributeWriteMask, AttributeUserWriteMask:
+		return value(uint32(0))
+	}
+
+	switch n.Class {
+	case ClassObject:
+		if attribute == AttributeEventNotifier {
+			return value(byte(0))
+		}
+	case ClassVariable, ClassVariableType:
+		switch attribute {
+		case AttributeValue:
+			if n.dynamic != nil {
+				now := time.Now().UTC()
+				return DataValue{Value: n.dynamic(), SourceTimestamp: now, ServerTimestamp: now}
+			}
+			a.mu.RLock()
+			defer a.mu.RUnlock()
+			if n.value.Value == nil && n.Class == ClassVariable {
+				return DataValue{Status: BadWaitingForInitialData, ServerTimestamp: time.Now().UTC()}
+			}
+			return n.value
+		case AttributeDataType:
+			return value(n.DataType)
+		case AttributeValueRank:
+			return value(n.ValueRank)
+		case AttributeArrayDimensions:
+			return value([]uint32{})
+		}
+		if n.Class == ClassVariableType && attribute == AttributeIsAbstract {
+			return value(n.IsAbstract)
+		}
+		switch attribute {
+		case AttributeAccessLevel, AttributeUserAccessLevel:
+			return value(byte(accessCurrentRead))
+		case AttributeMinimumSamplingInterval:
+			return value(n.MinimumSamplingInterval)
+		case AttributeHistorizing:
+			return value(false)
+		}
+	case ClassObjectType, ClassDataType:
+		if attribute == AttributeIsAbstract {
+			return value(n.IsAbstract)
+		}
+	case ClassReferenceType:
+		switch attribute {
+		case AttributeIsAbstract:
+			return value(n.IsAbstract)
+		case AttributeSymmetric:
+			return value(n.Symmetric)
+		case AttributeInverseName:
+			return value(n.InverseName)
+		}
+	}
+	return DataValue{Status: BadAttributeIDInvalid}
+}
+
+// IsSubtype reports whether a type is base or one of its subtypes.
+func (a *AddressSpace) IsSubtype(id, base NodeID) bool {
+	for {
+		if id == base {
+			return true
+		}
+		parent, ok := a.supertypes[id]
+		if !ok {
+			return false
+		}
+		id = parent
+	}
+}
+
+// TypeDefinition returns the target of a node's HasTypeDefinition reference.
+func (a *AddressSpace) TypeDefinition(n *Node) NodeID {
+	for _, r := range n.References {
+		if r.Forward && r.Type == NewNumericNodeID(0, idHasTypeDefinition) {
+			return r.Target
+		}
+	}
+	return NodeID{}
+}
+
+// add inserts a node below parent, linking both directions, and gives it a
+// type definition unless typeDefinition is zero.
+func (a *AddressSpace) add(n *Node, parent NodeID, referenceType uint32, typeDefinition uint32) *Node {
+	if n.DisplayName.Text == "" {
+		n.DisplayName = Text(n.BrowseName.Name)
+	}
+	a.nodes[n.ID] = n
+	if typeDefinition != 0 {
+		n.References = append(n.References, Reference{Type: NewNumericNodeID(0, idHasTypeDefinition), Forward: true, Target: NewNumericNodeID(0, typeDefinition)})
+	}
+	if parent != (NodeID{}) {
+		a.link(parent, NewNumericNodeID(0, referenceType), n.ID)
+	}
+	return n
+}
+
+func (a *AddressSpace) link(source, referenceType, target NodeID) {
+	if n, ok := a.nodes[source]; ok {
+		n.References = append(n.References, Reference{Type: referenceType, Forward: true, Target: target})
+	}
+	if n, ok := a.nodes[target]; ok {
+		n.References = append(n.References, Reference{Type: referenceType, Forward: false, Target: source})
+	}
+	if referenceType == NewNumericNodeID(0, idHasSubtype) {
+		a.supertypes[target] = source
+	}
+}
+
+func (a *AddressSpace) addStandardNodes() {
+	ns0 := func(id uint32) NodeID { return NewNumericNodeID(0, id) }
+	object := func(id uint32, name string, parent, referenceType, typeDefinition uint32) {
+		a.add(&Node{ID: ns0(id), Class: ClassObject, BrowseName: QualifiedName{Name: name}}, ns0(parent), referenceType, typeDefinition)
+	}
+	typeNode := func(class NodeClass, id uint32, name string, supertype uint32, abstract bool) *Node {
+		parent := NodeID{}
+		if supertype != 0 {
+			parent = ns0(supertype)
+		}
+		return a.add(&Node{ID: ns0(id), Class: class, BrowseName: QualifiedName{Name: name}, IsAbstract: abstract}, parent, idHasSubtype, 0)
+	}
+
+	a.add(&Node{ID: ns0(idRootFolder), Class: ClassObject, BrowseName: QualifiedName{Name: "Root"}}, NodeID{}, 0, idFolderType)
+	object(idObjectsFolder, "Objects", idRootFolder, idOrganizes, idFolderType)
+	object(idTypesFolder, "Types", idRootFolder, idOrganizes, idFolderType)
+	object(idViewsFolder, "Views", idRootFolder, idOrganizes, idFolderType)
+	object(idObjectTypesFolder, "ObjectTypes", idTypesFolder, idOrganizes, idFolderType)
+	object(idVariableTypesFolder, "VariableTypes", idTypesFolder, idOrganizes, idFolderType)
+	object(idDataTypesFolder, "DataTypes", idTypesFolder, idOrganizes, idFolderType)
+	object(idReferenceTypesFolder, "ReferenceTypes", idTypesFolder, idOrganizes, idFolderType)
+
+	references := []struct {
+		id, supertype uint32
+		name, inverse string
+		abstract      bool
+	}{
+		{idReferences, 0, "References", "", true},
+		{idHierarchical, idReferences, "HierarchicalReferences", "", true},
+		{idNonHierarchical, idReferences, "NonHierarchicalReferences", "", true},
+		{idHasChild, idHierarchical, "HasChild", "", true},
+		{idOrganizes, idHierarchical, "Organizes", "OrganizedBy", false},
+		{idAggregates, idHasChild, "Aggregates", "", true},
+		{idHasSubtype, idHasChild, "HasSubtype", "HasSupertype", false},
+		{idHasProperty, idAggregates, "HasProperty", "PropertyOf", false},
+		{idHasComponent, idAggregates, "HasComponent", "ComponentOf", false},
+		{idHasTypeDefinition, idNonHierarchical, "HasTypeDefinition", "TypeDefinitionOf", false},
+	}
+	for _, r := range references {
+		n := typeNode(ClassReferenceType, r.id, r.name, r.supertype, r.abstract)
+		n.InverseName = Text(r.inverse)
+	}
+	a.link(ns0(idReferenceTypesFolder), ns0(idOrganizes), ns0(idReferences))
+
+	typeNode(ClassObjectType, idBaseObjectType, "BaseObjectType", 0, false)
+	typeNode(ClassObjectType, idFolderType, "FolderType", idBaseObjectType, false)
+	typeNode(ClassObjectType, idServerType, "ServerType", idBaseObjectType, false)
+	a.link(ns0(idObjectTypesFolder), ns0(idOrganizes), ns0(idBaseObjectType))
+
+	variableTypes := []struct {
+		id, supertype, dataType uint32
+		name                    string
+		abstract                bool
+	}{
+		{idBaseVariableType, 0, idBaseDataType, "BaseVariableType", true},
+		{idBaseDataVariableType, idBaseVariableType, idBaseDataType, "BaseDataVariableType", false},
+		{idPropertyType, idBaseVariableType, idBaseDataType, "PropertyType", false},
+		{idDataItemType, idBaseDataVariableType, idBaseDataType, "DataItemType", false},
+		{idAnalogItemType, idDataItemType, idNumber, "AnalogItemType", false},
+		{idServerStatusType, idBaseDataVariableType, idServerStatusDataType, "ServerStatusType", false},
+	}
+	for _, v := range variableTypes {
+		n := typeNode(ClassVariableType, v.id, v.name, v.supertype, v.abstract)
+		n.DataType, n.ValueRank = ns0(v.dataType), -2
+	}
+	a.link(ns0(idVariableTypesFolder), ns0(idOrganizes), ns0(idBaseVariableType))
+
+	dataTypes := []struct {
+		id, supertype uint32
+		name          string
+		abstract      bool
+	}{
+		{idBaseDataType, 0, "BaseDataType", true},
+		{idBoolean, idBaseDataType, "Boolean", false},
+		{idNumber, idBaseDataType, "Number", true},
+		{idDouble, idNumber, "Double", false},
+		{idInt32, idNumber, "Int32", false},
+		{idUInt32, idNumber, "UInt32", false},
+		{idString, idBaseDataType, "String", false},
+		{idDateTime, idBaseDataType, "DateTime", false},
+		{idLocalizedText, idBaseDataType, "LocalizedText", false},
+		{idStructure, idBaseDataType, "Structure", true},
+		{idEnumeration, idBaseDataType, "Enumeration", true},
+		{idRange, idStructure, "Range", false},
+		{idEUInformation, idStructure, "EUInformation", false},
+		{idServerStatusDataType, idStructure, "ServerStatusDataType", false},
+		{idServerState, idEnumeration, "ServerState", false},
+	}
+	for _, d := range dataTypes {
+		typeNode(ClassDataType, d.id, d.name, d.supertype, d.abstract)
+	}
+	a.link(ns0(idDataTypesFolder), ns0(idOrganizes), ns0(idBaseDataType))
+
+	object(idServer, "Server", idObjectsFolder, idOrganizes, idServerType)
+	variable := func(id uint32, name string, parent, referenceType, typeDefinition, dataType uint32, valueRank int32, fn func() any) {
+		a.add(&Node{
+			ID:         ns0(id),
+			Class:      ClassVariable,
+			BrowseName: QualifiedName{Name: name},
+			DataType:   ns0(dataType),
+			ValueRank:  valueRank,
+			dynamic:    fn,
+		}, ns0(parent), referenceType, typeDefinition)
+	}
+	variable(idServerArray, "ServerArray", idServer, idHasProperty, idPropertyType, idString, 1, func() any { return []string{APPLICATIONURI} })
+	variable(idNamespaceArray, "NamespaceArray", idServer, idHasProperty, idPropertyType, idString, 1, func() any {
+		return []string{"http://opcfoundation.org/UA/", NAMESPACEURI}
+	})
+	variable(idServerStatus, "ServerStatus", idServer, idHasComponent, idServerStatusType, idServerStatusDataType, -1, a.serverStatus)
+	variable(idStartTime, "StartTime", idServerStatus, idHasComponent, idBaseDataVariableType, idDateTime, -1, func() any { return a.started })
+	variable(idCurrentTime, "CurrentTime", idServerStatus, idHasComponent, idBaseDataVariableType, idDateTime, -1, func() any { return time.Now().UTC() })
+	variable(idState, "State", idServerStatus, idHasComponent, idBaseDataVariableType, idServerState, -1, func() any { return int32(0) })
+}
+
+func (a *AddressSpace) serverStatus() any {
+	return newExtensionObject(encServerStatusDataType, func(e *encoder) {
+		e.dateTime(a.started)
+		e.dateTime(time.Now().UTC())
+		// Running.
+		e.int32(0)
+		// BuildInfo.
+		e.string(PRODUCTURI)
+		e.string("wdd")
+		e.string(PRODUCTNAME)
+		e.string("1.0")
+		e.string("1")
+		e.dateTime(a.started)
+		// SecondsTillShutdown and ShutdownReason.
+		e.uint32(0)
+		e.localizedText(LocalizedText{})
+	})
+}
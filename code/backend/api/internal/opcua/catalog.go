@@ -0,0 +1,217 @@
+package opcua
+
+import (
+	"context"
+	"sort"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/types"
+)
+
+// unitIDs maps common units to their UNECE Recommendation 20 codes, which
+// EUInformation carries as UnitId. Other units are published by name only.
+var unitIDs = map[string]string{
+	"°C":  "CEL",
+	"°F":  "FAH",
+	"K":   "KEL",
+	"bar": "BAR",
+	"Pa":  "PAL",
+	"kPa": "KPA",
+	"V":   "VLT",
+	"A":   "AMP",
+	"W":   "WTT",
+	"kW":  "KWT",
+	"Hz":  "HTZ",
+	"m":   "MTR",
+	"mm":  "MMT",
+	"kg":  "KGM",
+	"l":   "LTR",
+	"s":   "SEC",
+	"%":   "P1",
+}
+
+// Load builds the address space for exactly one of a factory, asset or
+// property and returns the series behind its variables.
+func Load(ctx context.Context, c *catalog.Catalog, factoryID, assetID, propertyID string) (*AddressSpace, []catalog.Series, error) {
+	series, err := c.Series(ctx, factoryID, assetID, propertyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a := NewAddressSpace()
+	objects := NewNumericNodeID(0, idObjectsFolder)
+
+	var assets []types.Asset
+	switch {
+	case factoryID != "":
+		factory, err := c.Factory(ctx, factoryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		parent := a.AddFactory(objects, factory)
+		if assets, err = c.FactoryAssets(ctx, factoryID); err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(assets, func(i, j int) bool { return assets[i].AssetID < assets[j].AssetID })
+		for _, asset := range assets {
+			a.AddAsset(parent, asset)
+		}
+	case assetID != "":
+		asset, err := c.Asset(ctx, assetID)
+		if err != nil {
+			return nil, nil, err
+		}
+		a.AddAsset(objects, asset)
+	}
+
+	for _, s := range series {
+		parent := objects
+		if s.AssetID != "" {
+			parent = assetNodeID(s.AssetID)
+		}
+		a.AddProperty(parent, s)
+	}
+	return a, series, nil
+}
+
+func factoryNodeID(factoryID string) NodeID {
+	return NewStringNodeID(1, "factories/"+factoryID)
+}
+
+func assetNodeID(assetID string) NodeID {
+	return NewStringNodeID(1, "assets/"+assetID)
+}
+
+// propertyNodeID names a property's variable after its asset as well, since
+// assets of the same model share property IDs.
+func propertyNodeID(assetID, propertyID string) NodeID {
+	if assetID == "" {
+		return NewStringNodeID(1, "properties/"+propertyID)
+	}
+	return NewStringNodeID(1, "assets/"+assetID+"/properties/"+propertyID)
+}
+
+// AddFactory adds a factory object organized by parent.
+func (a *AddressSpace) AddFactory(parent NodeID, factory types.Factory) NodeID {
+	n := a.add(&Node{
+		ID:          factoryNodeID(factory.FactoryID),
+		Class:       ClassObject,
+		BrowseName:  QualifiedName{Namespace: 1, Name: or(factory.Name, factory.FactoryID)},
+		Description: Text(or(factory.Description, "")),
+	}, parent, idOrganizes, idBaseObjectType)
+
+	if location := factory.Location; location != nil {
+		if location.Latitude != nil {
+			a.addProperty(n.ID, "Latitude", idDouble, *location.Latitude)
+		}
+		if location.Longitude != nil {
+			a.addProperty(n.ID, "Longitude", idDouble, *location.Longitude)
+		}
+	}
+	return n.ID
+}
+
+// AddAsset adds an asset object as a component of parent, with its
+// attributes as properties.
+func (a *AddressSpace) AddAsset(parent NodeID, asset types.Asset) NodeID {
+	n := a.add(&Node{
+		ID:          assetNodeID(asset.AssetID),
+		Class:       ClassObject,
+		BrowseName:  QualifiedName{Namespace: 1, Name: or(asset.Name, asset.AssetID)},
+		Description: Text(or(asset.Description, "")),
+	}, parent, idHasComponent, idBaseObjectType)
+
+	if asset.Type != nil {
+		a.addProperty(n.ID, "Type", idString, *asset.Type)
+	}
+	names := make([]string, 0, len(asset.Attributes))
+	for name := range asset.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attr := asset.Attributes[name]
+		p := a.addProperty(n.ID, name, idString, attr.Value)
+		if attr.Unit != "" {
+			p.Description = Text(attr.Unit)
+		}
+	}
+	return n.ID
+}
+
+// AddProperty adds a simulated property as an AnalogItemType variable of
+// parent, with EngineeringUnits and, when the measurement is bounded,
+// EURange properties.
+func (a *AddressSpace) AddProperty(parent NodeID, s catalog.Series) NodeID {
+	id := propertyNodeID(s.AssetID, s.Property.PropertyID)
+	if _, ok := a.nodes[id]; ok {
+		return id
+	}
+
+	n := &Node{
+		ID:                      id,
+		Class:                   ClassVariable,
+		BrowseName:              QualifiedName{Namespace: 1, Name: s.Property.Name},
+		DataType:                NewNumericNodeID(0, idDouble),
+		ValueRank:               -1,
+		MinimumSamplingInterval: float64(generators.Interval(s.Measurement).Milliseconds()),
+	}
+	if n.BrowseName.Name == "" {
+		n.BrowseName.Name = s.Property.PropertyID
+	}
+	if s.Property.Value != nil {
+		n.value = DataValue{Value: *s.Property.Value}
+	}
+	a.add(n, parent, idHasComponent, idAnalogItemType)
+	a.properties[s.Property.PropertyID] = append(a.properties[s.Property.PropertyID], id)
+
+	a.addProperty(id, "EngineeringUnits", idEUInformation, newExtensionObject(encEUInformation, func(e *encoder) {
+		e.string("http://www.opcfoundation.org/UA/units/un/cefact")
+		e.int32(unitID(s.Property.Unit))
+		e.localizedText(Text(s.Property.Unit))
+		e.localizedText(Text(s.Property.Unit))
+	}))
+	if low, high := s.Measurement.LowerBound, s.Measurement.UpperBound; low != nil && high != nil {
+		a.addProperty(id, "EURange", idRange, newExtensionObject(encRange, func(e *encoder) {
+			e.double(*low)
+			e.double(*high)
+		}))
+	}
+	return id
+}
+
+func (a *AddressSpace) addProperty(parent NodeID, name string, dataType uint32, value any) *Node {
+	id := NewStringNodeID(1, parent.Text+"/"+name)
+	if n, ok := a.nodes[id]; ok {
+		return n
+	}
+	return a.add(&Node{
+		ID:         id,
+		Class:      ClassVariable,
+		BrowseName: QualifiedName{Namespace: 1, Name: name},
+		DataType:   NewNumericNodeID(0, dataType),
+		ValueRank:  -1,
+		value:      DataValue{Value: value},
+	}, parent, idHasProperty, idPropertyType)
+}
+
+// unitID packs a UNECE code into the integer form EUInformation uses, or
+// returns -1 when the unit has no known code.
+func unitID(unit string) int32 {
+	code, ok := unitIDs[unit]
+	if !ok {
+		return -1
+	}
+	var id int32
+	for _, c := range []byte(code) {
+		id = id<<8 | int32(c)
+	}
+	return id
+}
+
+func or(value *string, fallback string) string {
+	if value != nil && *value != "" {
+		return *value
+	}
+	return fallback
+}
@@ -0,0 +1,253 @@
+package opcua
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	SECURITYPOLICYNONE = "http://opcfoundation.org/UA/SecurityPolicy#None"
+	MAXMESSAGESIZE     = 16 << 20
+	BUFFERSIZE         = 65535
+	MINBUFFERSIZE      = 8192
+	// DEFAULTTOKENLIFETIME is granted when a client asks for none.
+	DEFAULTTOKENLIFETIME = time.Hour
+	handshakeTimeout     = 10 * time.Second
+	writeTimeout         = 10 * time.Second
+	headerSize           = 8
+	// symmetricOverhead is the chunk header, channel ID, token ID and
+	// sequence header of a MSG chunk.
+	symmetricOverhead = headerSize + 4 + 4 + 8
+)
+
+// channel is one TCP connection carrying a secure channel with security
+// policy None: messages are framed, chunked and sequenced but not signed.
+type channel struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	id       uint32
+	tokenID  uint32
+	sendSize int
+	// lifetime is the security token's revised lifetime; the channel is
+	// dropped when the client lets it lapse.
+	lifetime time.Duration
+
+	writeMu sync.Mutex
+	seq     uint32
+
+	// partial holds the chunks of messages still being received, by request
+	// ID; buffered is their total size, capped at MAXMESSAGESIZE so a client
+	// cannot spread a flood of chunks over many requests.
+	partial  map[uint32][]byte
+	buffered int
+}
+
+// message is a complete request received on a channel.
+type message struct {
+	kind      string
+	requestID uint32
+	body      []byte
+}
+
+func newChannel(conn net.Conn) *channel {
+	return &channel{
+		conn:     conn,
+		r:        bufio.NewReaderSize(conn, BUFFERSIZE),
+		seq:      1,
+		lifetime: DEFAULTTOKENLIFETIME,
+		partial:  map[uint32][]byte{},
+	}
+}
+
+// handshake answers the client's HEL with ACK, settling buffer sizes.
+func (ch *channel) handshake() error {
+	_ = ch.conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer ch.conn.SetReadDeadline(time.Time{})
+
+	kind, _, body, err := ch.readChunk()
+	if err != nil {
+		return err
+	}
+	if kind != "HEL" {
+		return ch.fail(BadTCPMessageTypeInvalid, "expected HEL")
+	}
+
+	d := &decoder{buf: body}
+	d.uint32()
+	receiveSize, sendSize := d.uint32(), d.uint32()
+	d.uint32()
+	d.uint32()
+	if d.string(); d.err != nil {
+		return ch.fail(BadDecodingError, d.err.Error())
+	}
+	if receiveSize < MINBUFFERSIZE || sendSize < MINBUFFERSIZE {
+		return ch.fail(BadTCPMessageTooLarge, "buffers must hold at least 8192 bytes")
+	}
+
+	ch.sendSize = int(min32(receiveSize, BUFFERSIZE))
+	e := &encoder{}
+	e.uint32(0)
+	e.uint32(min32(sendSize, BUFFERSIZE))
+	e.uint32(uint32(ch.sendSize))
+	e.uint32(MAXMESSAGESIZE)
+	e.uint32(0)
+	return ch.writeChunk("ACK", 'F', e.buf)
+}
+
+// read returns the next complete OPN, MSG or CLO message.
+func (ch *channel) read() (message, error) {
+	for {
+		kind, chunkType, body, err := ch.readChunk()
+		if err != nil {
+			return message{}, err
+		}
+
+		d := &decoder{buf: body}
+		channelID := d.uint32()
+		switch kind {
+		case "OPN":
+			policy := d.string()
+			d.bytes()
+			d.bytes()
+			if d.err == nil && policy != SECURITYPOLICYNONE {
+				return message{}, ch.fail(BadSecurityPolicyRejected, "only SecurityPolicy#None is supported")
+			}
+		case "MSG", "CLO":
+			if ch.id == 0 || channelID != ch.id {
+				return message{}, ch.fail(BadSecureChannelIDInvalid, "unknown secure channel")
+			}
+			// The token ID is not checked: without security there is nothing
+			// to verify it against.
+			d.uint32()
+		default:
+			return message{}, ch.fail(BadTCPMessageTypeInvalid, "unexpected message type "+kind)
+		}
+		d.uint32()
+		requestID := d.uint32()
+		if d.err != nil {
+			return message{}, ch.fail(BadDecodingError, d.err.Error())
+		}
+
+		switch chunkType {
+		case 'A':
+			ch.buffered -= len(ch.partial[requestID])
+			delete(ch.partial, requestID)
+			continue
+		case 'C':
+			if ch.buffered+len(d.buf) > MAXMESSAGESIZE {
+				return message{}, ch.fail(BadTCPMessageTooLarge, "message too large")
+			}
+			ch.partial[requestID] = append(ch.partial[requestID], d.buf...)
+			ch.buffered += len(d.buf)
+			continue
+		}
+
+		full := append(ch.partial[requestID], d.buf...)
+		ch.buffered -= len(ch.partial[requestID])
+		delete(ch.partial, requestID)
+		return message{kind: kind, requestID: requestID, body: full}, nil
+	}
+}
+
+func (ch *channel) readChunk() (string, byte, []byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(ch.r, header); err != nil {
+		return "", 0, nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < headerSize || size > BUFFERSIZE+headerSize {
+		return "", 0, nil, ch.fail(BadTCPMessageTooLarge, "chunk too large")
+	}
+	body := make([]byte, size-headerSize)
+	if _, err := io.ReadFull(ch.r, body); err != nil {
+		return "", 0, nil, err
+	}
+	return string(header[:3]), header[3], body, nil
+}
+
+// openResponse answers OpenSecureChannel in an OPN chunk, which carries the
+// asymmetric security header even for policy None.
+func (ch *channel) openResponse(requestID uint32, body []byte) error {
+	e := &encoder{}
+	e.uint32(ch.id)
+	e.string(SECURITYPOLICYNONE)
+	e.bytes(nil)
+	e.bytes(nil)
+
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	e.uint32(ch.nextSeq())
+	e.uint32(requestID)
+	return ch.writeChunk("OPN", 'F', append(e.buf, body...))
+}
+
+// send writes a service response as one or more MSG chunks.
+func (ch *channel) send(requestID uint32, body []byte) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+
+	max := ch.sendSize - symmetricOverhead
+	for {
+		chunk, chunkType := body, byte('F')
+		if len(body) > max {
+			chunk, chunkType = body[:max], 'C'
+		}
+		body = body[len(chunk):]
+
+		e := &encoder{}
+		e.uint32(ch.id)
+		e.uint32(ch.tokenID)
+		e.uint32(ch.nextSeq())
+		e.uint32(requestID)
+		if err := ch.writeChunk("MSG", chunkType, append(e.buf, chunk...)); err != nil {
+			return err
+		}
+		if chunkType == 'F' {
+			return nil
+		}
+	}
+}
+
+// fail sends an ERR message and returns the status as the error that ends
+// the connection.
+func (ch *channel) fail(status StatusCode, reason string) error {
+	e := &encoder{}
+	e.statusCode(status)
+	e.string(reason)
+	ch.writeMu.Lock()
+	_ = ch.writeChunk("ERR", 'F', e.buf)
+	ch.writeMu.Unlock()
+	return fmt.Errorf("%w: %s", status, reason)
+}
+
+func (ch *channel) writeChunk(kind string, chunkType byte, body []byte) error {
+	frame := make([]byte, headerSize, headerSize+len(body))
+	copy(frame, kind)
+	frame[3] = chunkType
+	binary.LittleEndian.PutUint32(frame[4:], uint32(headerSize+len(body)))
+	_ = ch.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := ch.conn.Write(append(frame, body...))
+	return err
+}
+
+// nextSeq must be called with writeMu held, so sequence numbers go out in
+// order.
+func (ch *channel) nextSeq() uint32 {
+	seq := ch.seq
+	if ch.seq++; ch.seq > 0xFFFFFBFF {
+		ch.seq = 1
+	}
+	return seq
+}
+
+func min32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
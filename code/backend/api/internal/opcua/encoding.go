@@ -0,0 +1,630 @@
+package opcua
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrDecoding = errors.New("opc ua decoding error")
+
+// Built-in type IDs, used as variant encoding bytes.
+const (
+	typeBoolean         = 1
+	typeSByte           = 2
+	typeByte            = 3
+	typeInt16           = 4
+	typeUInt16          = 5
+	typeInt32           = 6
+	typeUInt32          = 7
+	typeInt64           = 8
+	typeUInt64          = 9
+	typeFloat           = 10
+	typeDouble          = 11
+	typeString          = 12
+	typeDateTime        = 13
+	typeGUID            = 14
+	typeByteString      = 15
+	typeNodeID          = 17
+	typeExpandedNodeID  = 18
+	typeStatusCode      = 19
+	typeQualifiedName   = 20
+	typeLocalizedText   = 21
+	typeExtensionObject = 22
+)
+
+// The OPC UA epoch is 1601-01-01; DateTime counts 100ns ticks from it.
+const epochOffset = 116444736000000000
+
+type QualifiedName struct {
+	Namespace uint16
+	Name      string
+}
+
+type LocalizedText struct {
+	Locale string
+	Text   string
+}
+
+// ExtensionObject is a binary encoded structure tagged with its encoding ID.
+type ExtensionObject struct {
+	TypeID NodeID
+	Body   []byte
+}
+
+// DataValue is a value with its status and timestamps; a nil Value is omitted.
+type DataValue struct {
+	Value           any
+	Status          StatusCode
+	SourceTimestamp time.Time
+	ServerTimestamp time.Time
+}
+
+func Text(text string) LocalizedText {
+	return LocalizedText{Text: text}
+}
+
+// newExtensionObject encodes a structure with fn and tags it with its
+// binary encoding ID.
+func newExtensionObject(encodingID uint32, fn func(e *encoder)) *ExtensionObject {
+	e := &encoder{}
+	fn(e)
+	return &ExtensionObject{TypeID: NewNumericNodeID(0, encodingID), Body: e.buf}
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) byte(v byte) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *encoder) boolean(v bool) {
+	if v {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.buf = binary.LittleEndian.AppendUint16(e.buf, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) int32(v int32) {
+	e.uint32(uint32(v))
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, v)
+}
+
+func (e *encoder) int64(v int64) {
+	e.uint64(uint64(v))
+}
+
+func (e *encoder) double(v float64) {
+	e.uint64(math.Float64bits(v))
+}
+
+// string encodes "" as a null string.
+func (e *encoder) string(s string) {
+	if s == "" {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	if b == nil {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) dateTime(t time.Time) {
+	if t.IsZero() {
+		e.int64(0)
+		return
+	}
+	e.int64(t.UnixNano()/100 + epochOffset)
+}
+
+func (e *encoder) statusCode(s StatusCode) {
+	e.uint32(uint32(s))
+}
+
+func (e *encoder) nodeID(id NodeID) {
+	switch {
+	case id.Kind == numericID && id.Namespace == 0 && id.Numeric <= 0xff:
+		e.byte(0x00)
+		e.byte(byte(id.Numeric))
+	case id.Kind == numericID && id.Namespace <= 0xff && id.Numeric <= 0xffff:
+		e.byte(0x01)
+		e.byte(byte(id.Namespace))
+		e.uint16(uint16(id.Numeric))
+	case id.Kind == numericID:
+		e.byte(0x02)
+		e.uint16(id.Namespace)
+		e.uint32(id.Numeric)
+	case id.Kind == stringID:
+		e.byte(0x03)
+		e.uint16(id.Namespace)
+		e.string(id.Text)
+	case id.Kind == guidID:
+		e.byte(0x04)
+		e.uint16(id.Namespace)
+		e.buf = append(e.buf, id.Text...)
+	default:
+		e.byte(0x05)
+		e.uint16(id.Namespace)
+		e.bytes([]byte(id.Text))
+	}
+}
+
+// expandedNodeID encodes a local node; server indexes and namespace URIs are
+// never needed.
+func (e *encoder) expandedNodeID(id NodeID) {
+	e.nodeID(id)
+}
+
+func (e *encoder) qualifiedName(q QualifiedName) {
+	e.uint16(q.Namespace)
+	e.string(q.Name)
+}
+
+func (e *encoder) localizedText(t LocalizedText) {
+	var mask byte
+	if t.Locale != "" {
+		mask |= 0x01
+	}
+	if t.Text != "" {
+		mask |= 0x02
+	}
+	e.byte(mask)
+	if t.Locale != "" {
+		e.string(t.Locale)
+	}
+	if t.Text != "" {
+		e.string(t.Text)
+	}
+}
+
+func (e *encoder) extensionObject(x *ExtensionObject) {
+	if x == nil {
+		e.nodeID(NodeID{})
+		e.byte(0)
+		return
+	}
+	e.nodeID(x.TypeID)
+	e.byte(0x01)
+	e.bytes(x.Body)
+}
+
+func (e *encoder) variant(v any) {
+	switch v := v.(type) {
+	case nil:
+		e.byte(0)
+	case bool:
+		e.byte(typeBoolean)
+		e.boolean(v)
+	case byte:
+		e.byte(typeByte)
+		e.byte(v)
+	case uint16:
+		e.byte(typeUInt16)
+		e.uint16(v)
+	case int32:
+		e.byte(typeInt32)
+		e.int32(v)
+	case uint32:
+		e.byte(typeUInt32)
+		e.uint32(v)
+	case int64:
+		e.byte(typeInt64)
+		e.int64(v)
+	case float64:
+		e.byte(typeDouble)
+		e.double(v)
+	case string:
+		e.byte(typeString)
+		e.string(v)
+	case time.Time:
+		e.byte(typeDateTime)
+		e.dateTime(v)
+	case []byte:
+		e.byte(typeByteString)
+		e.bytes(v)
+	case NodeID:
+		e.byte(typeNodeID)
+		e.nodeID(v)
+	case StatusCode:
+		e.byte(typeStatusCode)
+		e.statusCode(v)
+	case QualifiedName:
+		e.byte(typeQualifiedName)
+		e.qualifiedName(v)
+	case LocalizedText:
+		e.byte(typeLocalizedText)
+		e.localizedText(v)
+	case *ExtensionObject:
+		e.byte(typeExtensionObject)
+		e.extensionObject(v)
+	case []uint32:
+		e.byte(typeUInt32 | 0x80)
+		e.int32(int32(len(v)))
+		for _, u := range v {
+			e.uint32(u)
+		}
+	case []string:
+		e.byte(typeString | 0x80)
+		e.int32(int32(len(v)))
+		for _, s := range v {
+			e.string(s)
+		}
+	default:
+		panic(fmt.Sprintf("opcua: unsupported variant type %T", v))
+	}
+}
+
+func (e *encoder) dataValue(v DataValue) {
+	var mask byte
+	if v.Value != nil {
+		mask |= 0x01
+	}
+	if v.Status != Good {
+		mask |= 0x02
+	}
+	if !v.SourceTimestamp.IsZero() {
+		mask |= 0x04
+	}
+	if !v.ServerTimestamp.IsZero() {
+		mask |= 0x08
+	}
+	e.byte(mask)
+	if v.Value != nil {
+		e.variant(v.Value)
+	}
+	if v.Status != Good {
+		e.statusCode(v.Status)
+	}
+	if !v.SourceTimestamp.IsZero() {
+		e.dateTime(v.SourceTimestamp)
+	}
+	if !v.ServerTimestamp.IsZero() {
+		e.dateTime(v.ServerTimestamp)
+	}
+}
+
+func (e *encoder) statusCodes(codes []StatusCode) {
+	e.int32(int32(len(codes)))
+	for _, c := range codes {
+		e.statusCode(c)
+	}
+}
+
+// diagnosticInfos writes an empty list; the server never returns diagnostics.
+func (e *encoder) diagnosticInfos() {
+	e.int32(0)
+}
+
+func (e *encoder) strings(values []string) {
+	e.int32(int32(len(values)))
+	for _, s := range values {
+		e.string(s)
+	}
+}
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil || n < 0 || len(d.buf) < n {
+		d.fail("unexpected end of message")
+		return make([]byte, n&0xffff)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) fail(reason string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrDecoding, reason)
+	}
+}
+
+func (d *decoder) byte() byte {
+	return d.take(1)[0]
+}
+
+func (d *decoder) boolean() bool {
+	return d.byte() != 0
+}
+
+func (d *decoder) uint16() uint16 {
+	return binary.LittleEndian.Uint16(d.take(2))
+}
+
+func (d *decoder) uint32() uint32 {
+	return binary.LittleEndian.Uint32(d.take(4))
+}
+
+func (d *decoder) int32() int32 {
+	return int32(d.uint32())
+}
+
+func (d *decoder) uint64() uint64 {
+	return binary.LittleEndian.Uint64(d.take(8))
+}
+
+func (d *decoder) int64() int64 {
+	return int64(d.uint64())
+}
+
+func (d *decoder) double() float64 {
+	return math.Float64frombits(d.uint64())
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return append([]byte(nil), d.take(int(n))...)
+}
+
+func (d *decoder) dateTime() time.Time {
+	ticks := d.int64()
+	if ticks <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, (ticks-epochOffset)*100).UTC()
+}
+
+func (d *decoder) statusCode() StatusCode {
+	return StatusCode(d.uint32())
+}
+
+// arrayLength reads an array length, treating null as empty and rejecting
+// lengths the remaining bytes cannot hold.
+func (d *decoder) arrayLength() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	if int(n) > len(d.buf) {
+		d.fail("array longer than message")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) nodeID() NodeID {
+	switch d.byte() & 0x0f {
+	case 0x00:
+		return NewNumericNodeID(0, uint32(d.byte()))
+	case 0x01:
+		ns := uint16(d.byte())
+		return NewNumericNodeID(ns, uint32(d.uint16()))
+	case 0x02:
+		ns := d.uint16()
+		return NewNumericNodeID(ns, d.uint32())
+	case 0x03:
+		ns := d.uint16()
+		return NewStringNodeID(ns, d.string())
+	case 0x04:
+		ns := d.uint16()
+		return NodeID{Namespace: ns, Kind: guidID, Text: string(d.take(16))}
+	case 0x05:
+		ns := d.uint16()
+		return NodeID{Namespace: ns, Kind: opaqueID, Text: string(d.bytes())}
+	default:
+		d.fail("unknown node id encoding")
+		return NodeID{}
+	}
+}
+
+func (d *decoder) expandedNodeID() NodeID {
+	flags := d.buf
+	id := d.nodeID()
+	if len(flags) > 0 {
+		if flags[0]&0x80 != 0 {
+			d.string()
+		}
+		if flags[0]&0x40 != 0 {
+			d.uint32()
+		}
+	}
+	return id
+}
+
+func (d *decoder) qualifiedName() QualifiedName {
+	ns := d.uint16()
+	return QualifiedName{Namespace: ns, Name: d.string()}
+}
+
+func (d *decoder) localizedText() LocalizedText {
+	var t LocalizedText
+	mask := d.byte()
+	if mask&0x01 != 0 {
+		t.Locale = d.string()
+	}
+	if mask&0x02 != 0 {
+		t.Text = d.string()
+	}
+	return t
+}
+
+func (d *decoder) extensionObject() *ExtensionObject {
+	id := d.nodeID()
+	switch d.byte() {
+	case 0x00:
+		if id == (NodeID{}) {
+			return nil
+		}
+		return &ExtensionObject{TypeID: id}
+	case 0x01, 0x02:
+		return &ExtensionObject{TypeID: id, Body: d.bytes()}
+	default:
+		d.fail("unknown extension object encoding")
+		return nil
+	}
+}
+
+func (d *decoder) variant() any {
+	mask := d.byte()
+	if mask&0x80 == 0 {
+		return d.scalar(mask & 0x3f)
+	}
+
+	n := d.arrayLength()
+	var value any
+	if mask&0x3f == typeString {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = d.string()
+		}
+		value = values
+	} else {
+		values := make([]any, n)
+		for i := range values {
+			values[i] = d.scalar(mask & 0x3f)
+		}
+		value = values
+	}
+	if mask&0x40 != 0 {
+		for i := d.arrayLength(); i > 0; i-- {
+			d.int32()
+		}
+	}
+	return value
+}
+
+func (d *decoder) scalar(kind byte) any {
+	switch kind {
+	case 0:
+		return nil
+	case typeBoolean:
+		return d.boolean()
+	case typeSByte:
+		return int8(d.byte())
+	case typeByte:
+		return d.byte()
+	case typeInt16:
+		return int16(d.uint16())
+	case typeUInt16:
+		return d.uint16()
+	case typeInt32:
+		return d.int32()
+	case typeUInt32:
+		return d.uint32()
+	case typeInt64:
+		return d.int64()
+	case typeUInt64:
+		return d.uint64()
+	case typeFloat:
+		return float64(math.Float32frombits(d.uint32()))
+	case typeDouble:
+		return d.double()
+	case typeString:
+		return d.string()
+	case typeDateTime:
+		return d.dateTime()
+	case typeGUID:
+		return d.take(16)
+	case typeByteString:
+		return d.bytes()
+	case typeNodeID:
+		return d.nodeID()
+	case typeExpandedNodeID:
+		return d.expandedNodeID()
+	case typeStatusCode:
+		return d.statusCode()
+	case typeQualifiedName:
+		return d.qualifiedName()
+	case typeLocalizedText:
+		return d.localizedText()
+	case typeExtensionObject:
+		return d.extensionObject()
+	default:
+		d.fail(fmt.Sprintf("unsupported variant type %d", kind))
+		return nil
+	}
+}
+
+func (d *decoder) dataValue() DataValue {
+	var v DataValue
+	mask := d.byte()
+	if mask&0x01 != 0 {
+		v.Value = d.variant()
+	}
+	if mask&0x02 != 0 {
+		v.Status = d.statusCode()
+	}
+	if mask&0x04 != 0 {
+		v.SourceTimestamp = d.dateTime()
+	}
+	if mask&0x10 != 0 {
+		d.uint16()
+	}
+	if mask&0x08 != 0 {
+		v.ServerTimestamp = d.dateTime()
+	}
+	if mask&0x20 != 0 {
+		d.uint16()
+	}
+	return v
+}
+
+// diagnosticInfo skips a diagnostic info; clients send none worth keeping.
+func (d *decoder) diagnosticInfo() {
+	mask := d.byte()
+	for _, bit := range []byte{0x01, 0x02, 0x04, 0x08} {
+		if mask&bit != 0 {
+			d.int32()
+		}
+	}
+	if mask&0x10 != 0 {
+		d.string()
+	}
+	if mask&0x20 != 0 {
+		d.uint32()
+	}
+	if mask&0x40 != 0 && d.err == nil {
+		d.diagnosticInfo()
+	}
+}
+
+func (d *decoder) strings() []string {
+	values := make([]string, d.arrayLength())
+	for i := range values {
+		values[i] = d.string()
+	}
+	return values
+}
+
+func (d *decoder) uint32s() []uint32 {
+	values := make([]uint32, d.arrayLength())
+	for i := range values {
+		values[i] = d.uint32()
+	}
+	return values
+}
@@ -0,0 +1,229 @@
+package opcua
+
+import (
+	"fmt"
+)
+
+type idKind byte
+
+const (
+	numericID idKind = iota
+	stringID
+	guidID
+	opaqueID
+)
+
+// NodeID identifies a node. It is comparable, so it can key maps.
+type NodeID struct {
+	Namespace uint16
+	Kind      idKind
+	Numeric   uint32
+	// Text holds string identifiers, and the raw bytes of GUID and opaque ones.
+	Text string
+}
+
+func NewNumericNodeID(ns uint16, id uint32) NodeID {
+	return NodeID{Namespace: ns, Kind: numericID, Numeric: id}
+}
+
+func NewStringNodeID(ns uint16, id string) NodeID {
+	return NodeID{Namespace: ns, Kind: stringID, Text: id}
+}
+
+func (id NodeID) String() string {
+	switch id.Kind {
+	case numericID:
+		return fmt.Sprintf("ns=%d;i=%d", id.Namespace, id.Numeric)
+	case stringID:
+		return fmt.Sprintf("ns=%d;s=%s", id.Namespace, id.Text)
+	case guidID:
+		return fmt.Sprintf("ns=%d;g=%x", id.Namespace, id.Text)
+	default:
+		return fmt.Sprintf("ns=%d;b=%x", id.Namespace, id.Text)
+	}
+}
+
+type StatusCode uint32
+
+const (
+	Good                          StatusCode = 0
+	BadUnexpectedError            StatusCode = 0x80010000
+	BadInternalError              StatusCode = 0x80020000
+	BadDecodingError              StatusCode = 0x80070000
+	BadTimeout                    StatusCode = 0x800A0000
+	BadServiceUnsupported         StatusCode = 0x800B0000
+	BadNothingToDo                StatusCode = 0x800F0000
+	BadTooManyOperations          StatusCode = 0x80100000
+	BadIdentityTokenInvalid       StatusCode = 0x80200000
+	BadSecureChannelIDInvalid     StatusCode = 0x80220000
+	BadSessionIDInvalid           StatusCode = 0x80250000
+	BadSessionClosed              StatusCode = 0x80260000
+	BadSessionNotActivated        StatusCode = 0x80270000
+	BadSubscriptionIDInvalid      StatusCode = 0x80280000
+	BadTimestampsToReturnInvalid  StatusCode = 0x802B0000
+	BadWaitingForInitialData      StatusCode = 0x80320000
+	BadNodeIDUnknown              StatusCode = 0x80340000
+	BadAttributeIDInvalid         StatusCode = 0x80350000
+	BadNotWritable                StatusCode = 0x803B0000
+	BadMonitoringModeInvalid      StatusCode = 0x80410000
+	BadMonitoredItemIDInvalid     StatusCode = 0x80420000
+	BadContinuationPointInvalid   StatusCode = 0x804A0000
+	BadNoContinuationPoints       StatusCode = 0x804B0000
+	BadBrowseDirectionInvalid     StatusCode = 0x804D0000
+	BadSecurityPolicyRejected     StatusCode = 0x80550000
+	BadNoMatch                    StatusCode = 0x806F0000
+	BadTooManyPublishRequests     StatusCode = 0x80780000
+	BadNoSubscription             StatusCode = 0x80790000
+	BadSequenceNumberUnknown      StatusCode = 0x807A0000
+	BadMessageNotAvailable        StatusCode = 0x807B0000
+	BadTCPMessageTypeInvalid      StatusCode = 0x807E0000
+	BadTCPSecureChannelUnknown    StatusCode = 0x807F0000
+	BadTCPMessageTooLarge         StatusCode = 0x80800000
+	BadTCPEndpointURLInvalid      StatusCode = 0x80830000
+	BadSecurityModeRejected       StatusCode = 0x80540000
+	BadProtocolVersionUnsupported StatusCode = 0x80BE0000
+)
+
+func (s StatusCode) Error() string {
+	return fmt.Sprintf("opc ua status 0x%08X", uint32(s))
+}
+
+// Attribute IDs.
+const (
+	AttributeNodeID                  = 1
+	AttributeNodeClass               = 2
+	AttributeBrowseName              = 3
+	AttributeDisplayName             = 4
+	AttributeDescription             = 5
+	AttributeWriteMask               = 6
+	AttributeUserWriteMask           = 7
+	AttributeIsAbstract              = 8
+	AttributeSymmetric               = 9
+	AttributeInverseName             = 10
+	AttributeEventNotifier           = 12
+	AttributeValue                   = 13
+	AttributeDataType                = 14
+	AttributeValueRank               = 15
+	AttributeArrayDimensions         = 16
+	AttributeAccessLevel             = 17
+	AttributeUserAccessLevel         = 18
+	AttributeMinimumSamplingInterval = 19
+	AttributeHistorizing             = 20
+)
+
+type NodeClass uint32
+
+const (
+	ClassObject        NodeClass = 1
+	ClassVariable      NodeClass = 2
+	ClassObjectType    NodeClass = 8
+	ClassVariableType  NodeClass = 16
+	ClassReferenceType NodeClass = 32
+	ClassDataType      NodeClass = 64
+)
+
+// Standard nodes in namespace 0.
+const (
+	idBoolean              = 1
+	idInt32                = 6
+	idUInt32               = 7
+	idDouble               = 11
+	idString               = 12
+	idDateTime             = 13
+	idLocalizedText        = 21
+	idStructure            = 22
+	idEnumeration          = 29
+	idBaseDataType         = 24
+	idNumber               = 26
+	idReferences           = 31
+	idNonHierarchical      = 32
+	idHierarchical         = 33
+	idHasChild             = 34
+	idOrganizes            = 35
+	idHasTypeDefinition    = 40
+	idAggregates           = 44
+	idHasSubtype           = 45
+	idHasProperty          = 46
+	idHasComponent         = 47
+	idBaseObjectType       = 58
+	idFolderType           = 61
+	idBaseVariableType     = 62
+	idBaseDataVariableType = 63
+	idPropertyType         = 68
+	idRootFolder           = 84
+	idObjectsFolder        = 85
+	idTypesFolder          = 86
+	idViewsFolder          = 87
+	idObjectTypesFolder    = 88
+	idVariableTypesFolder  = 89
+	idDataTypesFolder      = 90
+	idReferenceTypesFolder = 91
+	idServerStatusDataType = 862
+	idServerState          = 852
+	idRange                = 884
+	idEUInformation        = 887
+	idServerType           = 2004
+	idServerStatusType     = 2138
+	idServer               = 2253
+	idServerArray          = 2254
+	idNamespaceArray       = 2255
+	idServerStatus         = 2256
+	idStartTime            = 2257
+	idCurrentTime          = 2258
+	idState                = 2259
+	idDataItemType         = 2365
+	idAnalogItemType       = 2368
+)
+
+// Binary encoding IDs of the structures the server exchanges.
+const (
+	encServiceFault                 = 397
+	encFindServersRequest           = 422
+	encFindServersResponse          = 425
+	encGetEndpointsRequest          = 428
+	encGetEndpointsResponse         = 431
+	encOpenSecureChannelRequest     = 446
+	encOpenSecureChannelResponse    = 449
+	encCloseSecureChannelRequest    = 452
+	encCreateSessionRequest         = 461
+	encCreateSessionResponse        = 464
+	encActivateSessionRequest       = 467
+	encActivateSessionResponse      = 470
+	encCloseSessionRequest          = 473
+	encCloseSessionResponse         = 476
+	encBrowseRequest                = 527
+	encBrowseResponse               = 530
+	encBrowseNextRequest            = 533
+	encBrowseNextResponse           = 536
+	encTranslateBrowsePathsRequest  = 554
+	encTranslateBrowsePathsResponse = 557
+	encReadRequest                  = 631
+	encReadResponse                 = 634
+	encWriteRequest                 = 673
+	encWriteResponse                = 676
+	encCreateMonitoredItemsRequest  = 751
+	encCreateMonitoredItemsResponse = 754
+	encModifyMonitoredItemsRequest  = 763
+	encModifyMonitoredItemsResponse = 766
+	encSetMonitoringModeRequest     = 769
+	encSetMonitoringModeResponse    = 772
+	encDeleteMonitoredItemsRequest  = 781
+	encDeleteMonitoredItemsResponse = 784
+	encCreateSubscriptionRequest    = 787
+	encCreateSubscriptionResponse   = 790
+	encModifySubscriptionRequest    = 793
+	encModifySubscriptionResponse   = 796
+	encSetPublishingModeRequest     = 799
+	encSetPublishingModeResponse    = 802
+	encDataChangeNotification       = 811
+	encPublishRequest               = 826
+	encPublishResponse              = 829
+	encRepublishRequest             = 832
+	encRepublishResponse            = 835
+	encDeleteSubscriptionsRequest   = 847
+	encDeleteSubscriptionsResponse  = 850
+	encServerStatusDataType         = 864
+	encRange                        = 886
+	encEUInformation                = 889
+	encAnonymousIdentityToken       = 321
+)
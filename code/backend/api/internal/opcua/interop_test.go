@@ -0,0 +1,150 @@
+package opcua
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wdd/api/internal/types"
+
+	gopcua "github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+)
+
+// TestServer_Gopcua drives the server with an independent client
+// implementation, github.com/gopcua/opcua, to catch encodings the test
+// client shares with the server.
+func TestServer_Gopcua(t *testing.T) {
+	server := NewServer(testSpace())
+	if _, err := server.ListenAndServe("127.0.0.1:0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	endpoints, err := gopcua.GetEndpoints(ctx, server.EndpointURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	endpoint := gopcua.SelectEndpoint(endpoints, ua.SecurityPolicyURINone, ua.MessageSecurityModeNone)
+	if endpoint == nil {
+		t.Fatalf("Expected an endpoint without security, got %v", endpoints)
+	}
+	c, err := gopcua.NewClient(server.EndpointURL,
+		gopcua.AuthAnonymous(),
+		gopcua.SecurityFromEndpoint(endpoint, ua.UserTokenTypeAnonymous),
+		gopcua.AutoReconnect(false),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	defer c.Close(context.Background())
+
+	browse := func(node *gopcua.Node) map[string]*gopcua.Node {
+		t.Helper()
+		refs, err := node.References(ctx, id.HierarchicalReferences, ua.BrowseDirectionForward, ua.NodeClassAll, true)
+		if err != nil {
+			t.Fatalf("Unexpected error browsing %v: %v", node, err)
+		}
+		children := map[string]*gopcua.Node{}
+		for _, ref := range refs {
+			children[ref.BrowseName.Name] = c.NodeFromExpandedNodeID(ref.NodeID)
+		}
+		return children
+	}
+
+	objects := browse(c.Node(ua.NewNumericNodeID(0, id.ObjectsFolder)))
+	factory, ok := objects["Plant"]
+	if !ok {
+		t.Fatalf("Expected the factory under Objects, got %v", objects)
+	}
+	asset, ok := browse(factory)["Press"]
+	if !ok {
+		t.Fatalf("Expected the asset under the factory")
+	}
+	property, ok := browse(asset)["Oil temp"]
+	if !ok {
+		t.Fatalf("Expected the property under the asset")
+	}
+	if want := ua.NewStringNodeID(1, "assets/a1/properties/p1"); property.ID.String() != want.String() {
+		t.Errorf("Expected %v, got %v", want, property.ID)
+	}
+
+	properties := browse(property)
+	units, err := properties["EngineeringUnits"].Value(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error reading EngineeringUnits: %v", err)
+	}
+	eu, ok := units.Value().(*ua.ExtensionObject)
+	if !ok {
+		t.Fatalf("Expected an extension object, got %v", units.Value())
+	}
+	if info, ok := eu.Value.(*ua.EUInformation); !ok || info.UnitID != unitID("°C") || info.DisplayName.Text != "°C" {
+		t.Errorf("Expected °C engineering units, got %#v", eu.Value)
+	}
+	bounds, err := properties["EURange"].Value(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error reading EURange: %v", err)
+	}
+	eu, ok = bounds.Value().(*ua.ExtensionObject)
+	if !ok {
+		t.Fatalf("Expected an extension object, got %v", bounds.Value())
+	}
+	if r, ok := eu.Value.(*ua.Range); !ok || r.Low != -20 || r.High != 120 {
+		t.Errorf("Expected a range of -20 to 120, got %#v", eu.Value)
+	}
+
+	server.Update(types.Reading{PropertyID: "p1", Timestamp: 1700000000000, Value: 21.5})
+	response, err := c.Read(ctx, &ua.ReadRequest{
+		NodesToRead:        []*ua.ReadValueID{{NodeID: property.ID, AttributeID: ua.AttributeIDValue}},
+		TimestampsToReturn: ua.TimestampsToReturnBoth,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error reading: %v", err)
+	}
+	if v := response.Results[0]; v.Status != ua.StatusOK || v.Value.Value() != 21.5 || !v.SourceTimestamp.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Expected 21.5 at the reading's timestamp, got %v at %v (%v)", v.Value.Value(), v.SourceTimestamp, v.Status)
+	}
+
+	notifications := make(chan *gopcua.PublishNotificationData, 8)
+	sub, err := c.Subscribe(ctx, &gopcua.SubscriptionParameters{Interval: 50 * time.Millisecond}, notifications)
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer sub.Cancel(context.Background())
+	monitored, err := sub.Monitor(ctx, ua.TimestampsToReturnBoth, gopcua.NewMonitoredItemCreateRequestWithDefaults(property.ID, ua.AttributeIDValue, 7))
+	if err != nil || monitored.Results[0].StatusCode != ua.StatusOK {
+		t.Fatalf("Expected the monitored item to be created, got %v", err)
+	}
+
+	next := func() float64 {
+		t.Helper()
+		select {
+		case n := <-notifications:
+			if n.Error != nil {
+				t.Fatalf("Unexpected error publishing: %v", n.Error)
+			}
+			changes, ok := n.Value.(*ua.DataChangeNotification)
+			if !ok || len(changes.MonitoredItems) == 0 || changes.MonitoredItems[0].ClientHandle != 7 {
+				t.Fatalf("Expected a data change of item 7, got %#v", n.Value)
+			}
+			v, _ := changes.MonitoredItems[len(changes.MonitoredItems)-1].Value.Value.Value().(float64)
+			return v
+		case <-ctx.Done():
+			t.Fatalf("Expected a data change notification")
+		}
+		return 0
+	}
+	if v := next(); v != 21.5 {
+		t.Errorf("Expected the initial value 21.5, got %v", v)
+	}
+	server.Update(types.Reading{PropertyID: "p1", Timestamp: 1700000001000, Value: 22})
+	if v := next(); v != 22 {
+		t.Errorf("Expected 22, got %v", v)
+	}
+}
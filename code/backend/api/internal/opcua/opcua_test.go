@@ -0,0 +1,600 @@
+package opcua
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func str(v string) *string {
+	return &v
+}
+
+func testSpace() *AddressSpace {
+	a := NewAddressSpace()
+	factory := a.AddFactory(NewNumericNodeID(0, idObjectsFolder), types.Factory{
+		FactoryID: "f1",
+		Name:      str("Plant"),
+		Location:  &types.Location{Latitude: float(52.5), Longitude: float(13.4)},
+	})
+	asset := a.AddAsset(factory, types.Asset{
+		AssetID:    "a1",
+		Name:       str("Press"),
+		Type:       str("Hydraulic press"),
+		Attributes: map[string]types.Attribute{"Max force": {Value: "250", Unit: "kN"}},
+	})
+	a.AddProperty(asset, catalog.Series{
+		AssetID:     "a1",
+		Property:    types.Property{PropertyID: "p1", Name: "Oil temp", Unit: "°C"},
+		Measurement: types.Measurement{LowerBound: float(-20), UpperBound: float(120)},
+	})
+	return a
+}
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(e *encoder)
+		want []byte
+	}{
+		{"two byte node id", func(e *encoder) { e.nodeID(NewNumericNodeID(0, 85)) }, []byte{0x00, 0x55}},
+		{"four byte node id", func(e *encoder) { e.nodeID(NewNumericNodeID(1, 1000)) }, []byte{0x01, 0x01, 0xE8, 0x03}},
+		{"string node id", func(e *encoder) { e.nodeID(NewStringNodeID(1, "a")) }, []byte{0x03, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 'a'}},
+		{"null string", func(e *encoder) { e.string("") }, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+		{"unix epoch", func(e *encoder) { e.dateTime(time.Unix(0, 0)) }, []byte{0x00, 0x80, 0x3E, 0xD5, 0xDE, 0xB1, 0x9D, 0x01}},
+		{"double variant", func(e *encoder) { e.variant(1.0) }, []byte{typeDouble, 0, 0, 0, 0, 0, 0, 0xF0, 0x3F}},
+		{"localized text", func(e *encoder) { e.localizedText(Text("x")) }, []byte{0x02, 0x01, 0x00, 0x00, 0x00, 'x'}},
+		{"bad data value", func(e *encoder) { e.dataValue(DataValue{Status: BadNodeIDUnknown}) }, []byte{0x02, 0x00, 0x00, 0x34, 0x80}},
+	}
+	for _, tt := range tests {
+		e := &encoder{}
+		tt.fn(e)
+		if !bytes.Equal(e.buf, tt.want) {
+			t.Errorf("%s: expected % x, got % x", tt.name, tt.want, e.buf)
+		}
+	}
+
+	source := time.UnixMilli(1700000000123).UTC()
+	e := &encoder{}
+	e.dataValue(DataValue{Value: 21.5, SourceTimestamp: source})
+	d := &decoder{buf: e.buf}
+	v := d.dataValue()
+	if d.err != nil || v.Value != 21.5 || !v.SourceTimestamp.Equal(source) || len(d.buf) != 0 {
+		t.Errorf("Unexpected round trip %+v, err %v", v, d.err)
+	}
+
+	d = &decoder{buf: []byte{0x03, 0x01, 0x00, 0xFF, 0xFF, 0xFF, 0x7F}}
+	if d.nodeID(); d.err == nil {
+		t.Errorf("Expected an error decoding a string longer than the message")
+	}
+}
+
+func TestUnitID(t *testing.T) {
+	if id := unitID("°C"); id != 4408652 {
+		t.Errorf("Expected CEL to pack to 4408652, got %d", id)
+	}
+	if id := unitID("widgets"); id != -1 {
+		t.Errorf("Expected -1 for an unknown unit, got %d", id)
+	}
+}
+
+// testClient speaks just enough OPC UA to drive the server.
+type testClient struct {
+	t         *testing.T
+	ch        *channel
+	requestID uint32
+	token     NodeID
+}
+
+func dial(t *testing.T, addr net.Addr) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	c := &testClient{t: t, ch: newChannel(conn)}
+	c.ch.sendSize = BUFFERSIZE
+
+	e := &encoder{}
+	e.uint32(0)
+	e.uint32(BUFFERSIZE)
+	e.uint32(BUFFERSIZE)
+	e.uint32(0)
+	e.uint32(0)
+	e.string("opc.tcp://" + addr.String())
+	if err = c.ch.writeChunk("HEL", 'F', e.buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if kind, _, _, err := c.ch.readChunk(); err != nil || kind != "ACK" {
+		t.Fatalf("Expected ACK, got %q (%v)", kind, err)
+	}
+
+	e = &encoder{}
+	e.uint32(0)
+	e.string(SECURITYPOLICYNONE)
+	e.bytes(nil)
+	e.bytes(nil)
+	e.uint32(1)
+	e.uint32(1)
+	c.requestHeader(e, encOpenSecureChannelRequest)
+	e.uint32(0)
+	e.uint32(0)
+	e.uint32(1)
+	e.bytes(nil)
+	e.uint32(60000)
+	if err = c.ch.writeChunk("OPN", 'F', e.buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg, err := c.ch.read()
+	if err != nil || msg.kind != "OPN" {
+		t.Fatalf("Expected OPN, got %q (%v)", msg.kind, err)
+	}
+	d := c.responseHeader(msg.body, encOpenSecureChannelResponse)
+	d.uint32()
+	c.ch.id = d.uint32()
+	c.ch.tokenID = d.uint32()
+	if d.err != nil || c.ch.id == 0 {
+		t.Fatalf("Unexpected OpenSecureChannel response, channel %d, err %v", c.ch.id, d.err)
+	}
+	return c
+}
+
+func (c *testClient) requestHeader(e *encoder, encodingID uint32) {
+	e.nodeID(NewNumericNodeID(0, encodingID))
+	e.nodeID(c.token)
+	e.dateTime(time.Now())
+	e.uint32(c.requestID)
+	e.uint32(0)
+	e.string("")
+	e.uint32(10000)
+	e.extensionObject(nil)
+}
+
+// call sends a request and returns the decoder positioned after the
+// response header, failing the test unless the response has the expected
+// type.
+func (c *testClient) call(encodingID, responseID uint32, fn func(e *encoder)) *decoder {
+	c.t.Helper()
+	d, status := c.try(encodingID, responseID, fn)
+	if status != Good {
+		c.t.Fatalf("Request %d failed with %v", encodingID, status)
+	}
+	return d
+}
+
+func (c *testClient) try(encodingID, responseID uint32, fn func(e *encoder)) (*decoder, StatusCode) {
+	c.t.Helper()
+	c.requestID++
+	e := &encoder{}
+	c.requestHeader(e, encodingID)
+	fn(e)
+	if err := c.ch.send(c.requestID, e.buf); err != nil {
+		c.t.Fatalf("Unexpected error: %v", err)
+	}
+	msg, err := c.ch.read()
+	if err != nil {
+		c.t.Fatalf("Unexpected error: %v", err)
+	}
+
+	d := &decoder{buf: msg.body}
+	typeID := d.nodeID()
+	d.dateTime()
+	d.uint32()
+	status := d.statusCode()
+	d.diagnosticInfo()
+	d.strings()
+	d.extensionObject()
+	if d.err != nil {
+		c.t.Fatalf("Unexpected error decoding response header: %v", d.err)
+	}
+	if status == Good && typeID != NewNumericNodeID(0, responseID) {
+		c.t.Fatalf("Expected response %d, got %v", responseID, typeID)
+	}
+	return d, status
+}
+
+func (c *testClient) responseHeader(body []byte, responseID uint32) *decoder {
+	d := &decoder{buf: body}
+	if typeID := d.nodeID(); typeID != NewNumericNodeID(0, responseID) {
+		c.t.Fatalf("Expected response %d, got %v", responseID, typeID)
+	}
+	d.dateTime()
+	d.uint32()
+	if status := d.statusCode(); status != Good {
+		c.t.Fatalf("Unexpected status %v", status)
+	}
+	d.diagnosticInfo()
+	d.strings()
+	d.extensionObject()
+	return d
+}
+
+func (c *testClient) openSession() {
+	c.t.Helper()
+	d := c.call(encCreateSessionRequest, encCreateSessionResponse, func(e *encoder) {
+		e.string("urn:test")
+		e.string("")
+		e.localizedText(Text("test"))
+		e.uint32(1)
+		e.string("")
+		e.string("")
+		e.strings(nil)
+		e.string("")
+		e.string("")
+		e.string("test session")
+		e.bytes(nil)
+		e.bytes(nil)
+		e.double(30000)
+		e.uint32(0)
+	})
+	d.nodeID()
+	c.token = d.nodeID()
+
+	c.call(encActivateSessionRequest, encActivateSessionResponse, func(e *encoder) {
+		e.string("")
+		e.bytes(nil)
+		e.int32(0)
+		e.strings(nil)
+		e.extensionObject(newExtensionObject(encAnonymousIdentityToken, func(e *encoder) { e.string("anonymous") }))
+		e.string("")
+		e.bytes(nil)
+	})
+}
+
+// browse returns the browse names of a node's forward hierarchical
+// references.
+func (c *testClient) browse(id NodeID) map[string]NodeID {
+	c.t.Helper()
+	d := c.call(encBrowseRequest, encBrowseResponse, func(e *encoder) {
+		e.nodeID(NodeID{})
+		e.dateTime(time.Time{})
+		e.uint32(0)
+		e.uint32(0)
+		e.int32(1)
+		e.nodeID(id)
+		e.uint32(browseForward)
+		e.nodeID(NewNumericNodeID(0, idHierarchical))
+		e.boolean(true)
+		e.uint32(0)
+		e.uint32(0x3F)
+	})
+
+	names := map[string]NodeID{}
+	d.arrayLength()
+	if status := d.statusCode(); status != Good {
+		c.t.Fatalf("Browsing %v failed with %v", id, status)
+	}
+	d.bytes()
+	for i := d.arrayLength(); i > 0; i-- {
+		d.nodeID()
+		d.boolean()
+		target := d.expandedNodeID()
+		name := d.qualifiedName()
+		d.localizedText()
+		d.uint32()
+		d.expandedNodeID()
+		names[name.Name] = target
+	}
+	if d.err != nil {
+		c.t.Fatalf("Unexpected error: %v", d.err)
+	}
+	return names
+}
+
+func (c *testClient) read(id NodeID, attribute uint32) DataValue {
+	c.t.Helper()
+	d := c.call(encReadRequest, encReadResponse, func(e *encoder) {
+		e.double(0)
+		e.uint32(timestampsBoth)
+		e.int32(1)
+		e.nodeID(id)
+		e.uint32(attribute)
+		e.string("")
+		e.qualifiedName(QualifiedName{})
+	})
+	d.arrayLength()
+	v := d.dataValue()
+	if d.err != nil {
+		c.t.Fatalf("Unexpected error: %v", d.err)
+	}
+	return v
+}
+
+// publish sends Publish requests until one carries a data change, and
+// returns its sequence number and values by client handle.
+func (c *testClient) publish(subscription, ack uint32) (uint32, map[uint32]DataValue) {
+	c.t.Helper()
+	for attempt := 0; attempt < 5; attempt++ {
+		d := c.call(encPublishRequest, encPublishResponse, func(e *encoder) {
+			if ack == 0 {
+				e.int32(0)
+				return
+			}
+			e.int32(1)
+			e.uint32(subscription)
+			e.uint32(ack)
+		})
+		if id := d.uint32(); id != subscription {
+			c.t.Fatalf("Expected subscription %d, got %d", subscription, id)
+		}
+		d.uint32s()
+		d.boolean()
+		seq := d.uint32()
+		d.dateTime()
+		notifications := d.arrayLength()
+		ack = 0
+		if notifications == 0 {
+			continue
+		}
+
+		x := d.extensionObject()
+		if x.TypeID != NewNumericNodeID(0, encDataChangeNotification) {
+			c.t.Fatalf("Expected a DataChangeNotification, got %v", x.TypeID)
+		}
+		body := &decoder{buf: x.Body}
+		values := map[uint32]DataValue{}
+		for i := body.arrayLength(); i > 0; i-- {
+			handle := body.uint32()
+			values[handle] = body.dataValue()
+		}
+		if body.err != nil {
+			c.t.Fatalf("Unexpected error: %v", body.err)
+		}
+		return seq, values
+	}
+	c.t.Fatalf("Expected a data change")
+	return 0, nil
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(testSpace())
+	addr, err := server.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer server.Close()
+
+	c := dial(t, addr)
+	d := c.call(encGetEndpointsRequest, encGetEndpointsResponse, func(e *encoder) {
+		e.string("")
+		e.strings(nil)
+		e.strings(nil)
+	})
+	if n := d.arrayLength(); n != 1 {
+		t.Fatalf("Expected one endpoint, got %d", n)
+	}
+	if url := d.string(); url != server.EndpointURL {
+		t.Errorf("Expected endpoint %s, got %s", server.EndpointURL, url)
+	}
+
+	if _, status := c.try(encReadRequest, encReadResponse, func(e *encoder) {}); status != BadSessionIDInvalid {
+		t.Errorf("Expected a read without a session to fail with BadSessionIdInvalid, got %v", status)
+	}
+	c.openSession()
+
+	objects := c.browse(NewNumericNodeID(0, idObjectsFolder))
+	if _, ok := objects["Server"]; !ok {
+		t.Errorf("Expected the Server object under Objects, got %v", objects)
+	}
+	factory, ok := objects["Plant"]
+	if !ok {
+		t.Fatalf("Expected the factory under Objects, got %v", objects)
+	}
+	assets := c.browse(factory)
+	if _, ok := assets["Latitude"]; !ok {
+		t.Errorf("Expected a Latitude property on the factory, got %v", assets)
+	}
+	asset, ok := assets["Press"]
+	if !ok {
+		t.Fatalf("Expected the asset under the factory, got %v", assets)
+	}
+	children := c.browse(asset)
+	for _, name := range []string{"Type", "Max force", "Oil temp"} {
+		if _, ok := children[name]; !ok {
+			t.Errorf("Expected %s under the asset, got %v", name, children)
+		}
+	}
+	property := children["Oil temp"]
+	if property != propertyNodeID("a1", "p1") {
+		t.Errorf("Expected %v, got %v", propertyNodeID("a1", "p1"), property)
+	}
+
+	if v := c.read(children["Max force"], AttributeValue); v.Value != "250" {
+		t.Errorf("Expected the attribute value 250, got %v", v.Value)
+	}
+	if v := c.read(children["Max force"], AttributeDescription); v.Value != Text("kN") {
+		t.Errorf("Expected the attribute unit as its description, got %v", v.Value)
+	}
+
+	properties := c.browse(property)
+	units := c.read(properties["EngineeringUnits"], AttributeValue)
+	x, ok := units.Value.(*ExtensionObject)
+	if !ok || x.TypeID != NewNumericNodeID(0, encEUInformation) {
+		t.Fatalf("Expected EUInformation, got %v", units.Value)
+	}
+	eu := &decoder{buf: x.Body}
+	eu.string()
+	if id, name := eu.int32(), eu.localizedText(); id != unitID("°C") || name.Text != "°C" {
+		t.Errorf("Expected °C engineering units, got %d %q", id, name.Text)
+	}
+	if _, ok := properties["EURange"]; !ok {
+		t.Errorf("Expected an EURange property, got %v", properties)
+	}
+
+	if v := c.read(property, AttributeValue); v.Status != BadWaitingForInitialData {
+		t.Errorf("Expected BadWaitingForInitialData before the first reading, got %v", v.Status)
+	}
+	server.Update(types.Reading{PropertyID: "p1", Timestamp: 1700000000000, Value: 21.5})
+	v := c.read(property, AttributeValue)
+	if v.Value != 21.5 || !v.SourceTimestamp.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Expected 21.5 at the reading's timestamp, got %v at %v", v.Value, v.SourceTimestamp)
+	}
+	if v := c.read(NewStringNodeID(1, "nope"), AttributeValue); v.Status != BadNodeIDUnknown {
+		t.Errorf("Expected BadNodeIdUnknown, got %v", v.Status)
+	}
+
+	d = c.call(encTranslateBrowsePathsRequest, encTranslateBrowsePathsResponse, func(e *encoder) {
+		e.int32(1)
+		e.nodeID(NewNumericNodeID(0, idObjectsFolder))
+		e.int32(3)
+		for _, name := range []string{"Plant", "Press", "Oil temp"} {
+			e.nodeID(NewNumericNodeID(0, idHierarchical))
+			e.boolean(false)
+			e.boolean(true)
+			e.qualifiedName(QualifiedName{Namespace: 1, Name: name})
+		}
+	})
+	d.arrayLength()
+	if status, n := d.statusCode(), d.arrayLength(); status != Good || n != 1 || d.expandedNodeID() != property {
+		t.Errorf("Expected the path to resolve to %v, got %v with %d targets", property, status, n)
+	}
+
+	d = c.call(encWriteRequest, encWriteResponse, func(e *encoder) {
+		e.int32(1)
+		e.nodeID(property)
+		e.uint32(AttributeValue)
+		e.string("")
+		e.dataValue(DataValue{Value: 1.0})
+	})
+	if results := d.uint32s(); len(results) != 1 || StatusCode(results[0]) != BadNotWritable {
+		t.Errorf("Expected BadNotWritable, got %v", results)
+	}
+
+	d = c.call(encCreateSubscriptionRequest, encCreateSubscriptionResponse, func(e *encoder) {
+		e.double(50)
+		e.uint32(300)
+		e.uint32(100)
+		e.uint32(0)
+		e.boolean(true)
+		e.byte(0)
+	})
+	subscription := d.uint32()
+	if interval := d.double(); interval != 50 {
+		t.Errorf("Expected a 50ms publishing interval, got %v", interval)
+	}
+
+	d = c.call(encCreateMonitoredItemsRequest, encCreateMonitoredItemsResponse, func(e *encoder) {
+		e.uint32(subscription)
+		e.uint32(timestampsBoth)
+		e.int32(1)
+		e.nodeID(property)
+		e.uint32(AttributeValue)
+		e.string("")
+		e.qualifiedName(QualifiedName{})
+		e.uint32(monitoringReporting)
+		e.uint32(7)
+		e.double(-1)
+		e.extensionObject(nil)
+		e.uint32(1)
+		e.boolean(true)
+	})
+	d.arrayLength()
+	if status := d.statusCode(); status != Good {
+		t.Fatalf("Expected the monitored item to be created, got %v", status)
+	}
+
+	seq, values := c.publish(subscription, 0)
+	if values[7].Value != 21.5 {
+		t.Errorf("Expected the initial value 21.5, got %v", values)
+	}
+
+	server.Update(types.Reading{PropertyID: "p1", Timestamp: 1700000001000, Value: 22})
+	next, values := c.publish(subscription, seq)
+	if values[7].Value != 22.0 || next != seq+1 {
+		t.Errorf("Expected 22 in message %d, got %v in message %d", seq+1, values, next)
+	}
+
+	c.call(encCloseSessionRequest, encCloseSessionResponse, func(e *encoder) { e.boolean(true) })
+	if _, status := c.try(encReadRequest, encReadResponse, func(e *encoder) {}); status != BadSessionIDInvalid {
+		t.Errorf("Expected BadSessionIdInvalid after closing the session, got %v", status)
+	}
+}
+
+func TestChannel_BufferedLimit(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+
+	ch := newChannel(server)
+	ch.id = 1
+	peer := &channel{conn: client}
+	chunk := make([]byte, BUFFERSIZE-16)
+	go func() {
+		// Every chunk starts a message of its own, so none is too large by
+		// itself.
+		for requestID := uint32(1); requestID <= MAXMESSAGESIZE/uint32(len(chunk))+1; requestID++ {
+			e := &encoder{}
+			e.uint32(1)
+			e.uint32(0)
+			e.uint32(requestID)
+			e.uint32(requestID)
+			if peer.writeChunk("MSG", 'C', append(e.buf, chunk...)) != nil {
+				return
+			}
+		}
+	}()
+
+	if _, err := ch.read(); !errors.Is(err, BadTCPMessageTooLarge) {
+		t.Errorf("Expected BadTCPMessageTooLarge, got %v", err)
+	}
+	if ch.buffered > MAXMESSAGESIZE {
+		t.Errorf("Expected at most %d bytes buffered, got %d", MAXMESSAGESIZE, ch.buffered)
+	}
+}
+
+func TestServer_SharedModel(t *testing.T) {
+	// Both presses are of one model, so they share its property.
+	a := NewAddressSpace()
+	factory := a.AddFactory(NewNumericNodeID(0, idObjectsFolder), types.Factory{FactoryID: "f1", Name: str("Plant")})
+	for _, id := range []string{"a1", "a2"} {
+		asset := a.AddAsset(factory, types.Asset{AssetID: id, Name: str("Press " + id)})
+		a.AddProperty(asset, catalog.Series{AssetID: id, Property: types.Property{PropertyID: "p1", Name: "Oil temp", Unit: "°C"}})
+	}
+	server := NewServer(a)
+	addr, err := server.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer server.Close()
+
+	c := dial(t, addr)
+	c.openSession()
+	server.Update(types.Reading{PropertyID: "p1", Timestamp: 1700000000000, Value: 21.5})
+
+	assets := c.browse(factory)
+	seen := map[NodeID]bool{}
+	for _, name := range []string{"Press a1", "Press a2"} {
+		asset, ok := assets[name]
+		if !ok {
+			t.Fatalf("Expected %s under the factory, got %v", name, assets)
+		}
+		children := c.browse(asset)
+		property, ok := children["Oil temp"]
+		if !ok {
+			t.Fatalf("Expected Oil temp under %s, got %v", name, children)
+		}
+		if seen[property] {
+			t.Errorf("Expected a variable of its own for %s, got %v again", name, property)
+		}
+		seen[property] = true
+		if _, ok := c.browse(property)["EngineeringUnits"]; !ok {
+			t.Errorf("Expected EngineeringUnits on the property of %s", name)
+		}
+		if v := c.read(property, AttributeValue); v.Value != 21.5 {
+			t.Errorf("Expected 21.5 for %s, got %v (%v)", name, v.Value, v.Status)
+		}
+	}
+}
@@ -0,0 +1,606 @@
+package opcua
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"wdd/api/internal/types"
+)
+
+const (
+	DEFAULTSESSIONTIMEOUT = time.Minute
+	MAXSESSIONTIMEOUT     = time.Hour
+	MAXSESSIONS           = 100
+	// MAXPUBLISHREQUESTS is how many Publish requests a session may queue.
+	MAXPUBLISHREQUESTS = 10
+	// MAXCONTINUATIONPOINTS is how many unfinished browses a session may hold.
+	MAXCONTINUATIONPOINTS = 16
+	TRANSPORTPROFILE      = "http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary"
+	janitorInterval       = time.Second
+)
+
+var ErrClosed = errors.New("opc ua server closed")
+
+// Server serves an address space over OPC UA binary (opc.tcp) with security
+// policy None and anonymous sessions, which is what browse, read and
+// subscribe clients such as UaExpert and the OPC Foundation stacks need.
+type Server struct {
+	Space *AddressSpace
+	// EndpointURL is advertised by GetEndpoints; ListenAndServe sets it.
+	EndpointURL string
+
+	mu                 sync.Mutex
+	sessions           map[NodeID]*session
+	nextChannelID      uint32
+	nextSessionID      uint32
+	nextSubscriptionID uint32
+
+	connMu    sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// session is an activated client session. Its subscriptions outlive the
+// channel that created them, so a client can reconnect and carry on.
+type session struct {
+	id            NodeID
+	token         NodeID
+	name          string
+	channel       *channel
+	activated     bool
+	timeout       time.Duration
+	lastSeen      time.Time
+	subscriptions map[uint32]*subscription
+	publish       []*publishRequest
+	continuations map[string]*continuation
+	nextPoint     uint32
+}
+
+// request is a decoded service request waiting for its response.
+type request struct {
+	ch        *channel
+	requestID uint32
+	handle    uint32
+	authToken NodeID
+	d         *decoder
+	session   *session
+}
+
+func NewServer(space *AddressSpace) *Server {
+	s := &Server{
+		Space:    space,
+		sessions: map[NodeID]*session{},
+		conns:    map[net.Conn]struct{}{},
+		done:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.janitor()
+	return s
+}
+
+// Update stores a reading as the value of its property's variables, which
+// monitored items pick up on their next sample. Readings for properties
+// outside the address space are ignored.
+func (s *Server) Update(reading types.Reading) {
+	for _, id := range s.Space.PropertyNodes(reading.PropertyID) {
+		s.Space.SetValue(id, reading.Value, time.UnixMilli(reading.Timestamp).UTC())
+	}
+}
+
+// ListenAndServe listens on addr and serves until the server is closed. It
+// returns the listener's address once bound.
+func (s *Server) ListenAndServe(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.EndpointURL == "" {
+		// Clients connect to the advertised URL, so a wildcard address
+		// needs a name they can reach.
+		tcp := listener.Addr().(*net.TCPAddr)
+		host := tcp.IP.String()
+		if tcp.IP.IsUnspecified() {
+			host = "localhost"
+		}
+		s.EndpointURL = fmt.Sprintf("opc.tcp://%s", net.JoinHostPort(host, strconv.Itoa(tcp.Port)))
+	}
+	go s.Serve(listener)
+	return listener.Addr(), nil
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		listener.Close()
+		return ErrClosed
+	}
+	s.listeners = append(s.listeners, listener)
+	s.connMu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.connMu.Lock()
+			delete(s.conns, conn)
+			s.connMu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.mu.Lock()
+	for token, sess := range s.sessions {
+		s.closeSession(sess)
+		delete(s.sessions, token)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	ch := newChannel(conn)
+	if err := ch.handshake(); err != nil {
+		return
+	}
+	defer s.detach(ch)
+
+	for {
+		// A client renews its token well before it lapses; one that does
+		// not has gone away.
+		_ = conn.SetReadDeadline(time.Now().Add(ch.lifetime * 5 / 4))
+		msg, err := ch.read()
+		if err != nil {
+			return
+		}
+
+		switch msg.kind {
+		case "OPN":
+			if err = s.openSecureChannel(ch, msg); err != nil {
+				return
+			}
+		case "CLO":
+			return
+		default:
+			if err = s.dispatch(ch, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) openSecureChannel(ch *channel, msg message) error {
+	d := &decoder{buf: msg.body}
+	if typeID := d.nodeID(); typeID != NewNumericNodeID(0, encOpenSecureChannelRequest) && d.err == nil {
+		return ch.fail(BadTCPMessageTypeInvalid, "expected OpenSecureChannelRequest")
+	}
+	_, handle := decodeRequestHeader(d)
+	d.uint32()
+	requestType := d.uint32()
+	securityMode := d.uint32()
+	d.bytes()
+	lifetime := time.Duration(d.uint32()) * time.Millisecond
+	if d.err != nil {
+		return ch.fail(BadDecodingError, d.err.Error())
+	}
+	if securityMode != 1 {
+		return ch.fail(BadSecurityModeRejected, "only security mode None is supported")
+	}
+
+	switch {
+	case requestType == 0 && ch.id == 0:
+		s.mu.Lock()
+		s.nextChannelID++
+		ch.id = s.nextChannelID
+		s.mu.Unlock()
+		ch.tokenID = 1
+	case requestType == 1 && ch.id != 0:
+		ch.tokenID++
+	default:
+		return ch.fail(BadTCPSecureChannelUnknown, "cannot issue or renew this channel")
+	}
+	if lifetime <= 0 || lifetime > DEFAULTTOKENLIFETIME {
+		lifetime = DEFAULTTOKENLIFETIME
+	}
+	ch.lifetime = lifetime
+
+	body := response(encOpenSecureChannelResponse, handle, Good, func(e *encoder) {
+		e.uint32(0)
+		e.uint32(ch.id)
+		e.uint32(ch.tokenID)
+		e.dateTime(time.Now().UTC())
+		e.uint32(uint32(lifetime.Milliseconds()))
+		e.bytes([]byte{})
+	})
+	return ch.openResponse(msg.requestID, body)
+}
+
+// dispatch decodes a service request and answers it. Only transport errors
+// end the connection; everything else is answered with a ServiceFault.
+func (s *Server) dispatch(ch *channel, msg message) error {
+	d := &decoder{buf: msg.body}
+	typeID := d.nodeID()
+	authToken, handle := decodeRequestHeader(d)
+	r := &request{ch: ch, requestID: msg.requestID, handle: handle, authToken: authToken, d: d}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if typeID.Namespace != 0 || typeID.Kind != numericID {
+		return r.fault(BadServiceUnsupported)
+	}
+
+	switch typeID.Numeric {
+	case encCloseSecureChannelRequest:
+		return errors.New("secure channel closed")
+	case encFindServersRequest:
+		return s.findServers(r)
+	case encGetEndpointsRequest:
+		return s.getEndpoints(r)
+	case encCreateSessionRequest:
+		return s.createSession(r)
+	case encActivateSessionRequest:
+		return s.activateSession(r)
+	case encCloseSessionRequest:
+		return s.closeSessionRequest(r)
+	}
+
+	handlers := map[uint32]func(*request) error{
+		encBrowseRequest:               s.browse,
+		encBrowseNextRequest:           s.browseNext,
+		encTranslateBrowsePathsRequest: s.translateBrowsePaths,
+		encReadRequest:                 s.read,
+		encWriteRequest:                s.write,
+		encCreateSubscriptionRequest:   s.createSubscription,
+		encModifySubscriptionRequest:   s.modifySubscription,
+		encSetPublishingModeRequest:    s.setPublishingMode,
+		encDeleteSubscriptionsRequest:  s.deleteSubscriptions,
+		encCreateMonitoredItemsRequest: s.createMonitoredItems,
+		encModifyMonitoredItemsRequest: s.modifyMonitoredItems,
+		encSetMonitoringModeRequest:    s.setMonitoringMode,
+		encDeleteMonitoredItemsRequest: s.deleteMonitoredItems,
+		encPublishRequest:              s.publishRequest,
+		encRepublishRequest:            s.republish,
+	}
+	handler, ok := handlers[typeID.Numeric]
+	if !ok {
+		return r.fault(BadServiceUnsupported)
+	}
+	if status := s.attach(r); status != Good {
+		return r.fault(status)
+	}
+	return handler(r)
+}
+
+// attach finds the activated session a request belongs to.
+func (s *Server) attach(r *request) StatusCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[r.authToken]
+	switch {
+	case !ok:
+		return BadSessionIDInvalid
+	case !sess.activated || sess.channel != r.ch:
+		return BadSessionNotActivated
+	}
+	sess.lastSeen = time.Now()
+	r.session = sess
+	return Good
+}
+
+// detach unbinds the sessions of a closed channel and drops the Publish
+// requests that can no longer be answered on it.
+func (s *Server) detach(ch *channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sess := range s.sessions {
+		if sess.channel != ch {
+			continue
+		}
+		sess.channel = nil
+		sess.activated = false
+		sess.publish = nil
+	}
+}
+
+func (s *Server) findServers(r *request) error {
+	endpointURL := r.d.string()
+	r.d.strings()
+	r.d.strings()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	return r.respond(encFindServersResponse, func(e *encoder) {
+		e.int32(1)
+		s.applicationDescription(e, or(&endpointURL, s.EndpointURL))
+	})
+}
+
+func (s *Server) getEndpoints(r *request) error {
+	endpointURL := r.d.string()
+	r.d.strings()
+	r.d.strings()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	return r.respond(encGetEndpointsResponse, func(e *encoder) {
+		e.int32(1)
+		s.endpointDescription(e, or(&endpointURL, s.EndpointURL))
+	})
+}
+
+func (s *Server) createSession(r *request) error {
+	d := r.d
+	skipApplicationDescription(d)
+	d.string()
+	endpointURL := d.string()
+	name := d.string()
+	d.bytes()
+	d.bytes()
+	timeout := time.Duration(d.double() * float64(time.Millisecond))
+	d.uint32()
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if timeout <= 0 {
+		timeout = DEFAULTSESSIONTIMEOUT
+	}
+	if timeout > MAXSESSIONTIMEOUT {
+		timeout = MAXSESSIONTIMEOUT
+	}
+
+	s.mu.Lock()
+	if len(s.sessions) >= MAXSESSIONS {
+		s.mu.Unlock()
+		return r.fault(BadTooManyOperations)
+	}
+	s.nextSessionID++
+	sess := &session{
+		id:            NewNumericNodeID(1, s.nextSessionID),
+		token:         NodeID{Namespace: 1, Kind: opaqueID, Text: string(nonce())},
+		name:          name,
+		channel:       r.ch,
+		timeout:       timeout,
+		lastSeen:      time.Now(),
+		subscriptions: map[uint32]*subscription{},
+		continuations: map[string]*continuation{},
+	}
+	s.sessions[sess.token] = sess
+	s.mu.Unlock()
+
+	return r.respond(encCreateSessionResponse, func(e *encoder) {
+		e.nodeID(sess.id)
+		e.nodeID(sess.token)
+		e.double(float64(timeout.Milliseconds()))
+		e.bytes(nonce())
+		e.bytes(nil)
+		e.int32(1)
+		s.endpointDescription(e, or(&endpointURL, s.EndpointURL))
+		e.int32(0)
+		e.string("")
+		e.bytes(nil)
+		e.uint32(MAXMESSAGESIZE)
+	})
+}
+
+func (s *Server) activateSession(r *request) error {
+	d := r.d
+	d.string()
+	d.bytes()
+	for i := d.arrayLength(); i > 0; i-- {
+		d.bytes()
+		d.bytes()
+	}
+	d.strings()
+	token := d.extensionObject()
+	d.string()
+	d.bytes()
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	// Anonymous is the only identity on offer; a null token means the same.
+	if token != nil && token.TypeID != NewNumericNodeID(0, encAnonymousIdentityToken) {
+		return r.fault(BadIdentityTokenInvalid)
+	}
+
+	s.mu.Lock()
+	sess, ok := s.sessions[r.authToken]
+	if ok {
+		if sess.channel != r.ch {
+			sess.publish = nil
+		}
+		sess.channel = r.ch
+		sess.activated = true
+		sess.lastSeen = time.Now()
+	}
+	s.mu.Unlock()
+	if !ok {
+		return r.fault(BadSessionIDInvalid)
+	}
+
+	return r.respond(encActivateSessionResponse, func(e *encoder) {
+		e.bytes(nonce())
+		e.statusCodes(nil)
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) closeSessionRequest(r *request) error {
+	r.d.boolean()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+
+	s.mu.Lock()
+	sess, ok := s.sessions[r.authToken]
+	if ok {
+		s.closeSession(sess)
+		delete(s.sessions, r.authToken)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return r.fault(BadSessionIDInvalid)
+	}
+	return r.respond(encCloseSessionResponse, func(e *encoder) {})
+}
+
+// closeSession stops a session's subscriptions and answers its queued
+// Publish requests. It must be called with s.mu held.
+func (s *Server) closeSession(sess *session) {
+	for id, sub := range sess.subscriptions {
+		sub.stop()
+		delete(sess.subscriptions, id)
+	}
+	for _, p := range sess.publish {
+		go p.fault(BadSessionClosed)
+	}
+	sess.publish = nil
+}
+
+// janitor closes sessions whose clients stopped talking to them.
+func (s *Server) janitor() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for token, sess := range s.sessions {
+				if now.Sub(sess.lastSeen) > sess.timeout {
+					s.closeSession(sess)
+					delete(s.sessions, token)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) applicationDescription(e *encoder, endpointURL string) {
+	e.string(APPLICATIONURI)
+	e.string(PRODUCTURI)
+	e.localizedText(Text(PRODUCTNAME))
+	// Server.
+	e.int32(0)
+	e.string("")
+	e.string("")
+	e.strings([]string{endpointURL})
+}
+
+func (s *Server) endpointDescription(e *encoder, endpointURL string) {
+	e.string(endpointURL)
+	s.applicationDescription(e, endpointURL)
+	e.bytes(nil)
+	// MessageSecurityMode None.
+	e.int32(1)
+	e.string(SECURITYPOLICYNONE)
+	// One anonymous UserTokenPolicy.
+	e.int32(1)
+	e.string("anonymous")
+	e.int32(0)
+	e.string("")
+	e.string("")
+	e.string("")
+	e.string(TRANSPORTPROFILE)
+	e.byte(0)
+}
+
+// decodeRequestHeader reads a RequestHeader and returns the authentication
+// token and request handle, the only fields the server needs.
+func decodeRequestHeader(d *decoder) (NodeID, uint32) {
+	authToken := d.nodeID()
+	d.dateTime()
+	handle := d.uint32()
+	d.uint32()
+	d.string()
+	d.uint32()
+	d.extensionObject()
+	return authToken, handle
+}
+
+func skipApplicationDescription(d *decoder) {
+	d.string()
+	d.string()
+	d.localizedText()
+	d.uint32()
+	d.string()
+	d.string()
+	d.strings()
+}
+
+// response encodes a service response: its type ID, a ResponseHeader and
+// the body written by fn.
+func response(encodingID, handle uint32, status StatusCode, fn func(e *encoder)) []byte {
+	e := &encoder{}
+	e.nodeID(NewNumericNodeID(0, encodingID))
+	e.dateTime(time.Now().UTC())
+	e.uint32(handle)
+	e.statusCode(status)
+	// No diagnostics, an empty string table and no additional header.
+	e.byte(0)
+	e.int32(0)
+	e.extensionObject(nil)
+	fn(e)
+	return e.buf
+}
+
+func (r *request) respond(encodingID uint32, fn func(e *encoder)) error {
+	return r.ch.send(r.requestID, response(encodingID, r.handle, Good, fn))
+}
+
+func (r *request) fault(status StatusCode) error {
+	return r.ch.send(r.requestID, response(encServiceFault, r.handle, status, func(e *encoder) {}))
+}
+
+func nonce() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("opcua: reading random nonce: %v", err))
+	}
+	return b
+}
@@ -0,0 +1,393 @@
+package opcua
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Browse directions.
+const (
+	browseForward = 0
+	browseInverse = 1
+	browseBoth    = 2
+)
+
+// TimestampsToReturn.
+const (
+	timestampsSource  = 0
+	timestampsServer  = 1
+	timestampsBoth    = 2
+	timestampsNeither = 3
+)
+
+// Browse result mask bits.
+const (
+	resultReferenceType  = 0x01
+	resultIsForward      = 0x02
+	resultNodeClass      = 0x04
+	resultBrowseName     = 0x08
+	resultDisplayName    = 0x10
+	resultTypeDefinition = 0x20
+)
+
+// browseDescription is one node to browse and the references to follow.
+type browseDescription struct {
+	node            NodeID
+	direction       uint32
+	referenceType   NodeID
+	includeSubtypes bool
+	nodeClassMask   uint32
+	resultMask      uint32
+}
+
+// continuation holds the references of a browse that did not fit in one
+// response.
+type continuation struct {
+	references []Reference
+	resultMask uint32
+	max        int
+}
+
+type browseResult struct {
+	status     StatusCode
+	point      []byte
+	references []Reference
+	resultMask uint32
+}
+
+func (s *Server) browse(r *request) error {
+	d := r.d
+	// The view is ignored: the whole address space is the only view.
+	d.nodeID()
+	d.dateTime()
+	d.uint32()
+	max := int(d.uint32())
+	descriptions := make([]browseDescription, d.arrayLength())
+	for i := range descriptions {
+		descriptions[i] = browseDescription{
+			node:            d.nodeID(),
+			direction:       d.uint32(),
+			referenceType:   d.nodeID(),
+			includeSubtypes: d.boolean(),
+			nodeClassMask:   d.uint32(),
+			resultMask:      d.uint32(),
+		}
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(descriptions) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	results := make([]browseResult, len(descriptions))
+	for i, b := range descriptions {
+		references, status := s.references(b)
+		if status != Good {
+			results[i] = browseResult{status: status}
+			continue
+		}
+		results[i] = s.page(r.session, references, b.resultMask, max)
+	}
+	return r.respond(encBrowseResponse, func(e *encoder) {
+		s.encodeBrowseResults(e, results)
+	})
+}
+
+func (s *Server) browseNext(r *request) error {
+	d := r.d
+	release := d.boolean()
+	points := make([][]byte, d.arrayLength())
+	for i := range points {
+		points[i] = d.bytes()
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(points) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	results := make([]browseResult, len(points))
+	for i, point := range points {
+		s.mu.Lock()
+		c, ok := r.session.continuations[string(point)]
+		delete(r.session.continuations, string(point))
+		s.mu.Unlock()
+		switch {
+		case !ok:
+			results[i] = browseResult{status: BadContinuationPointInvalid}
+		case release:
+			results[i] = browseResult{}
+		default:
+			results[i] = s.page(r.session, c.references, c.resultMask, c.max)
+		}
+	}
+	return r.respond(encBrowseNextResponse, func(e *encoder) {
+		s.encodeBrowseResults(e, results)
+	})
+}
+
+// references returns the references of a node that match a browse
+// description.
+func (s *Server) references(b browseDescription) ([]Reference, StatusCode) {
+	n, ok := s.Space.Node(b.node)
+	if !ok {
+		return nil, BadNodeIDUnknown
+	}
+	if b.direction > browseBoth {
+		return nil, BadBrowseDirectionInvalid
+	}
+
+	var references []Reference
+	for _, ref := range n.References {
+		if ref.Forward && b.direction == browseInverse || !ref.Forward && b.direction == browseForward {
+			continue
+		}
+		if b.referenceType != (NodeID{}) && ref.Type != b.referenceType &&
+			!(b.includeSubtypes && s.Space.IsSubtype(ref.Type, b.referenceType)) {
+			continue
+		}
+		target, ok := s.Space.Node(ref.Target)
+		if !ok {
+			continue
+		}
+		if b.nodeClassMask != 0 && b.nodeClassMask&uint32(target.Class) == 0 {
+			continue
+		}
+		references = append(references, ref)
+	}
+	return references, Good
+}
+
+// page returns up to max references, keeping the rest behind a
+// continuation point.
+func (s *Server) page(sess *session, references []Reference, resultMask uint32, max int) browseResult {
+	result := browseResult{references: references, resultMask: resultMask}
+	if max <= 0 || len(references) <= max {
+		return result
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(sess.continuations) >= MAXCONTINUATIONPOINTS {
+		return browseResult{status: BadNoContinuationPoints}
+	}
+	sess.nextPoint++
+	result.point = binary.LittleEndian.AppendUint32(nil, sess.nextPoint)
+	result.references = references[:max]
+	sess.continuations[string(result.point)] = &continuation{references: references[max:], resultMask: resultMask, max: max}
+	return result
+}
+
+func (s *Server) encodeBrowseResults(e *encoder, results []browseResult) {
+	e.int32(int32(len(results)))
+	for _, result := range results {
+		e.statusCode(result.status)
+		e.bytes(result.point)
+		e.int32(int32(len(result.references)))
+		for _, ref := range result.references {
+			s.encodeReference(e, ref, result.resultMask)
+		}
+	}
+	e.diagnosticInfos()
+}
+
+// encodeReference writes a ReferenceDescription, leaving out the fields the
+// result mask does not ask for.
+func (s *Server) encodeReference(e *encoder, ref Reference, mask uint32) {
+	target, _ := s.Space.Node(ref.Target)
+
+	if mask&resultReferenceType != 0 {
+		e.nodeID(ref.Type)
+	} else {
+		e.nodeID(NodeID{})
+	}
+	e.boolean(mask&resultIsForward != 0 && ref.Forward)
+	e.expandedNodeID(target.ID)
+	if mask&resultBrowseName != 0 {
+		e.qualifiedName(target.BrowseName)
+	} else {
+		e.qualifiedName(QualifiedName{})
+	}
+	if mask&resultDisplayName != 0 {
+		e.localizedText(target.DisplayName)
+	} else {
+		e.localizedText(LocalizedText{})
+	}
+	if mask&resultNodeClass != 0 {
+		e.int32(int32(target.Class))
+	} else {
+		e.int32(0)
+	}
+	if mask&resultTypeDefinition != 0 && (target.Class == ClassObject || target.Class == ClassVariable) {
+		e.expandedNodeID(s.Space.TypeDefinition(target))
+	} else {
+		e.expandedNodeID(NodeID{})
+	}
+}
+
+// translateBrowsePaths resolves relative paths of browse names, such as
+// Objects/<factory>/<asset>/<property>, to node IDs.
+func (s *Server) translateBrowsePaths(r *request) error {
+	type element struct {
+		referenceType   NodeID
+		inverse         bool
+		includeSubtypes bool
+		name            QualifiedName
+	}
+	type path struct {
+		start    NodeID
+		elements []element
+	}
+
+	d := r.d
+	paths := make([]path, d.arrayLength())
+	for i := range paths {
+		paths[i].start = d.nodeID()
+		paths[i].elements = make([]element, d.arrayLength())
+		for j := range paths[i].elements {
+			paths[i].elements[j] = element{
+				referenceType:   d.nodeID(),
+				inverse:         d.boolean(),
+				includeSubtypes: d.boolean(),
+				name:            d.qualifiedName(),
+			}
+		}
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(paths) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	statuses := make([]StatusCode, len(paths))
+	targets := make([][]NodeID, len(paths))
+	for i, p := range paths {
+		if _, ok := s.Space.Node(p.start); !ok {
+			statuses[i] = BadNodeIDUnknown
+			continue
+		}
+		if len(p.elements) == 0 {
+			statuses[i] = BadNothingToDo
+			continue
+		}
+
+		current := []NodeID{p.start}
+		for _, el := range p.elements {
+			direction := uint32(browseForward)
+			if el.inverse {
+				direction = browseInverse
+			}
+			var next []NodeID
+			for _, id := range current {
+				references, _ := s.references(browseDescription{
+					node:            id,
+					direction:       direction,
+					referenceType:   el.referenceType,
+					includeSubtypes: el.includeSubtypes,
+				})
+				for _, ref := range references {
+					if target, _ := s.Space.Node(ref.Target); target.BrowseName == el.name {
+						next = append(next, ref.Target)
+					}
+				}
+			}
+			current = next
+		}
+		if len(current) == 0 {
+			statuses[i] = BadNoMatch
+		}
+		targets[i] = current
+	}
+
+	return r.respond(encTranslateBrowsePathsResponse, func(e *encoder) {
+		e.int32(int32(len(paths)))
+		for i := range paths {
+			e.statusCode(statuses[i])
+			e.int32(int32(len(targets[i])))
+			for _, id := range targets[i] {
+				e.expandedNodeID(id)
+				// The whole path was followed.
+				e.uint32(0xFFFFFFFF)
+			}
+		}
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) read(r *request) error {
+	type readValueID struct {
+		node      NodeID
+		attribute uint32
+	}
+
+	d := r.d
+	d.double()
+	timestamps := d.uint32()
+	nodes := make([]readValueID, d.arrayLength())
+	for i := range nodes {
+		nodes[i] = readValueID{node: d.nodeID(), attribute: d.uint32()}
+		d.string()
+		d.qualifiedName()
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(nodes) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+	if timestamps > timestampsNeither {
+		return r.fault(BadTimestampsToReturnInvalid)
+	}
+
+	return r.respond(encReadResponse, func(e *encoder) {
+		e.int32(int32(len(nodes)))
+		for _, n := range nodes {
+			e.dataValue(filterTimestamps(s.Space.Read(n.node, n.attribute), timestamps))
+		}
+		e.diagnosticInfos()
+	})
+}
+
+// write refuses every write: the values belong to the simulator.
+func (s *Server) write(r *request) error {
+	d := r.d
+	nodes := make([]NodeID, d.arrayLength())
+	for i := range nodes {
+		nodes[i] = d.nodeID()
+		d.uint32()
+		d.string()
+		d.dataValue()
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(nodes) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	results := make([]StatusCode, len(nodes))
+	for i, id := range nodes {
+		results[i] = BadNotWritable
+		if _, ok := s.Space.Node(id); !ok {
+			results[i] = BadNodeIDUnknown
+		}
+	}
+	return r.respond(encWriteResponse, func(e *encoder) {
+		e.statusCodes(results)
+		e.diagnosticInfos()
+	})
+}
+
+// filterTimestamps keeps the timestamps a client asked for.
+func filterTimestamps(v DataValue, timestamps uint32) DataValue {
+	if timestamps == timestampsServer || timestamps == timestampsNeither {
+		v.SourceTimestamp = time.Time{}
+	}
+	if timestamps == timestampsSource || timestamps == timestampsNeither {
+		v.ServerTimestamp = time.Time{}
+	}
+	return v
+}
@@ -0,0 +1,758 @@
+package opcua
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+const (
+	MINPUBLISHINGINTERVAL = 50 * time.Millisecond
+	MAXPUBLISHINGINTERVAL = time.Hour
+	DEFAULTKEEPALIVECOUNT = 10
+	MAXQUEUESIZE          = 100
+	// MAXRETRANSMIT is how many unacknowledged notification messages a
+	// subscription keeps for Republish.
+	MAXRETRANSMIT = 100
+)
+
+// Monitoring modes.
+const (
+	monitoringDisabled  = 0
+	monitoringSampling  = 1
+	monitoringReporting = 2
+)
+
+// subscription samples its monitored items every publishing interval and
+// answers the session's queued Publish requests with the changes, or with
+// a keep-alive when nothing changed for keepAliveCount intervals. All of
+// its state is guarded by Server.mu.
+type subscription struct {
+	id               uint32
+	session          *session
+	interval         time.Duration
+	lifetimeCount    uint32
+	keepAliveCount   uint32
+	maxNotifications uint32
+	enabled          bool
+
+	items      map[uint32]*monitoredItem
+	nextItemID uint32
+	seq        uint32
+	// retransmit holds sent notification messages until they are
+	// acknowledged, by sequence number.
+	retransmit map[uint32][]byte
+
+	keepAliveCounter uint32
+	lifetimeCounter  uint32
+	done             chan struct{}
+}
+
+type monitoredItem struct {
+	id            uint32
+	node          NodeID
+	attribute     uint32
+	clientHandle  uint32
+	mode          uint32
+	timestamps    uint32
+	queueSize     uint32
+	discardOldest bool
+
+	sampled bool
+	last    DataValue
+	queue   []DataValue
+}
+
+// publishRequest is a Publish request waiting for a notification.
+type publishRequest struct {
+	ch        *channel
+	requestID uint32
+	handle    uint32
+	results   []StatusCode
+}
+
+// notification is a queued value change of a monitored item.
+type notification struct {
+	clientHandle uint32
+	value        DataValue
+}
+
+func (p *publishRequest) fault(status StatusCode) {
+	_ = p.ch.send(p.requestID, response(encServiceFault, p.handle, status, func(e *encoder) {}))
+}
+
+func (sub *subscription) stop() {
+	close(sub.done)
+}
+
+// revise clamps the requested publishing parameters to what the server
+// supports.
+func (sub *subscription) revise(interval float64, lifetimeCount, keepAliveCount, maxNotifications uint32) {
+	sub.interval = time.Duration(interval * float64(time.Millisecond))
+	if sub.interval < MINPUBLISHINGINTERVAL {
+		sub.interval = MINPUBLISHINGINTERVAL
+	}
+	if sub.interval > MAXPUBLISHINGINTERVAL {
+		sub.interval = MAXPUBLISHINGINTERVAL
+	}
+	if keepAliveCount == 0 {
+		keepAliveCount = DEFAULTKEEPALIVECOUNT
+	}
+	if lifetimeCount < 3*keepAliveCount {
+		lifetimeCount = 3 * keepAliveCount
+	}
+	sub.keepAliveCount, sub.lifetimeCount, sub.maxNotifications = keepAliveCount, lifetimeCount, maxNotifications
+}
+
+func (s *Server) createSubscription(r *request) error {
+	d := r.d
+	interval := d.double()
+	lifetimeCount := d.uint32()
+	keepAliveCount := d.uint32()
+	maxNotifications := d.uint32()
+	enabled := d.boolean()
+	d.byte()
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+
+	s.mu.Lock()
+	s.nextSubscriptionID++
+	sub := &subscription{
+		id:         s.nextSubscriptionID,
+		session:    r.session,
+		enabled:    enabled,
+		items:      map[uint32]*monitoredItem{},
+		seq:        1,
+		retransmit: map[uint32][]byte{},
+		done:       make(chan struct{}),
+	}
+	sub.revise(interval, lifetimeCount, keepAliveCount, maxNotifications)
+	// The first keep-alive goes out after one interval, so the client knows
+	// the subscription is alive.
+	sub.keepAliveCounter = sub.keepAliveCount
+	r.session.subscriptions[sub.id] = sub
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.runSubscription(sub)
+
+	return r.respond(encCreateSubscriptionResponse, func(e *encoder) {
+		e.uint32(sub.id)
+		e.double(float64(sub.interval.Milliseconds()))
+		e.uint32(sub.lifetimeCount)
+		e.uint32(sub.keepAliveCount)
+	})
+}
+
+func (s *Server) modifySubscription(r *request) error {
+	d := r.d
+	id := d.uint32()
+	interval := d.double()
+	lifetimeCount := d.uint32()
+	keepAliveCount := d.uint32()
+	maxNotifications := d.uint32()
+	d.byte()
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	if ok {
+		sub.revise(interval, lifetimeCount, keepAliveCount, maxNotifications)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return r.fault(BadSubscriptionIDInvalid)
+	}
+
+	return r.respond(encModifySubscriptionResponse, func(e *encoder) {
+		e.double(float64(sub.interval.Milliseconds()))
+		e.uint32(sub.lifetimeCount)
+		e.uint32(sub.keepAliveCount)
+	})
+}
+
+func (s *Server) setPublishingMode(r *request) error {
+	enabled := r.d.boolean()
+	ids := r.d.uint32s()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(ids) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	results := make([]StatusCode, len(ids))
+	s.mu.Lock()
+	for i, id := range ids {
+		sub, ok := r.session.subscriptions[id]
+		if !ok {
+			results[i] = BadSubscriptionIDInvalid
+			continue
+		}
+		sub.enabled = enabled
+	}
+	s.mu.Unlock()
+
+	return r.respond(encSetPublishingModeResponse, func(e *encoder) {
+		e.statusCodes(results)
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) deleteSubscriptions(r *request) error {
+	ids := r.d.uint32s()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(ids) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	results := make([]StatusCode, len(ids))
+	var orphaned []*publishRequest
+	s.mu.Lock()
+	for i, id := range ids {
+		sub, ok := r.session.subscriptions[id]
+		if !ok {
+			results[i] = BadSubscriptionIDInvalid
+			continue
+		}
+		sub.stop()
+		delete(r.session.subscriptions, id)
+	}
+	// Publish requests are pointless once the last subscription is gone.
+	if len(r.session.subscriptions) == 0 {
+		orphaned, r.session.publish = r.session.publish, nil
+	}
+	s.mu.Unlock()
+
+	for _, p := range orphaned {
+		p.fault(BadNoSubscription)
+	}
+	return r.respond(encDeleteSubscriptionsResponse, func(e *encoder) {
+		e.statusCodes(results)
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) createMonitoredItems(r *request) error {
+	type createRequest struct {
+		node          NodeID
+		attribute     uint32
+		mode          uint32
+		clientHandle  uint32
+		queueSize     uint32
+		discardOldest bool
+	}
+
+	d := r.d
+	id := d.uint32()
+	timestamps := d.uint32()
+	requests := make([]createRequest, d.arrayLength())
+	for i := range requests {
+		c := &requests[i]
+		c.node = d.nodeID()
+		c.attribute = d.uint32()
+		d.string()
+		d.qualifiedName()
+		c.mode = d.uint32()
+		c.clientHandle = d.uint32()
+		d.double()
+		// Filters are accepted but every item reports status and value
+		// changes, the default trigger.
+		d.extensionObject()
+		c.queueSize = d.uint32()
+		c.discardOldest = d.boolean()
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(requests) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+	if timestamps > timestampsNeither {
+		return r.fault(BadTimestampsToReturnInvalid)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return r.fault(BadSubscriptionIDInvalid)
+	}
+	results := make([]StatusCode, len(requests))
+	items := make([]*monitoredItem, len(requests))
+	for i, c := range requests {
+		status := s.Space.Read(c.node, c.attribute).Status
+		switch {
+		case status == BadNodeIDUnknown || status == BadAttributeIDInvalid:
+			results[i] = status
+			continue
+		case c.mode > monitoringReporting:
+			results[i] = BadMonitoringModeInvalid
+			continue
+		}
+		sub.nextItemID++
+		item := &monitoredItem{
+			id:            sub.nextItemID,
+			node:          c.node,
+			attribute:     c.attribute,
+			clientHandle:  c.clientHandle,
+			mode:          c.mode,
+			timestamps:    timestamps,
+			queueSize:     reviseQueueSize(c.queueSize),
+			discardOldest: c.discardOldest,
+		}
+		sub.items[item.id] = item
+		items[i] = item
+	}
+	interval := sub.interval
+	s.mu.Unlock()
+
+	return r.respond(encCreateMonitoredItemsResponse, func(e *encoder) {
+		e.int32(int32(len(requests)))
+		for i, item := range items {
+			e.statusCode(results[i])
+			if item == nil {
+				e.uint32(0)
+				e.double(0)
+				e.uint32(0)
+			} else {
+				e.uint32(item.id)
+				// Items are sampled once per publishing interval.
+				e.double(float64(interval.Milliseconds()))
+				e.uint32(item.queueSize)
+			}
+			e.extensionObject(nil)
+		}
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) modifyMonitoredItems(r *request) error {
+	type modifyRequest struct {
+		id            uint32
+		clientHandle  uint32
+		queueSize     uint32
+		discardOldest bool
+	}
+
+	d := r.d
+	id := d.uint32()
+	timestamps := d.uint32()
+	requests := make([]modifyRequest, d.arrayLength())
+	for i := range requests {
+		m := &requests[i]
+		m.id = d.uint32()
+		m.clientHandle = d.uint32()
+		d.double()
+		d.extensionObject()
+		m.queueSize = d.uint32()
+		m.discardOldest = d.boolean()
+	}
+	if d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(requests) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+	if timestamps > timestampsNeither {
+		return r.fault(BadTimestampsToReturnInvalid)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return r.fault(BadSubscriptionIDInvalid)
+	}
+	results := make([]StatusCode, len(requests))
+	queueSizes := make([]uint32, len(requests))
+	for i, m := range requests {
+		item, ok := sub.items[m.id]
+		if !ok {
+			results[i] = BadMonitoredItemIDInvalid
+			continue
+		}
+		item.clientHandle = m.clientHandle
+		item.timestamps = timestamps
+		item.queueSize = reviseQueueSize(m.queueSize)
+		item.discardOldest = m.discardOldest
+		if over := len(item.queue) - int(item.queueSize); over > 0 {
+			item.queue = item.queue[over:]
+		}
+		queueSizes[i] = item.queueSize
+	}
+	interval := sub.interval
+	s.mu.Unlock()
+
+	return r.respond(encModifyMonitoredItemsResponse, func(e *encoder) {
+		e.int32(int32(len(requests)))
+		for i := range requests {
+			e.statusCode(results[i])
+			if results[i] == Good {
+				e.double(float64(interval.Milliseconds()))
+			} else {
+				e.double(0)
+			}
+			e.uint32(queueSizes[i])
+			e.extensionObject(nil)
+		}
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) setMonitoringMode(r *request) error {
+	id := r.d.uint32()
+	mode := r.d.uint32()
+	ids := r.d.uint32s()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(ids) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+	if mode > monitoringReporting {
+		return r.fault(BadMonitoringModeInvalid)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return r.fault(BadSubscriptionIDInvalid)
+	}
+	results := make([]StatusCode, len(ids))
+	for i, itemID := range ids {
+		item, ok := sub.items[itemID]
+		if !ok {
+			results[i] = BadMonitoredItemIDInvalid
+			continue
+		}
+		item.mode = mode
+		if mode == monitoringDisabled {
+			// Re-enabling reports the current value afresh.
+			item.sampled, item.queue = false, nil
+		}
+	}
+	s.mu.Unlock()
+
+	return r.respond(encSetMonitoringModeResponse, func(e *encoder) {
+		e.statusCodes(results)
+		e.diagnosticInfos()
+	})
+}
+
+func (s *Server) deleteMonitoredItems(r *request) error {
+	id := r.d.uint32()
+	ids := r.d.uint32s()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+	if len(ids) == 0 {
+		return r.fault(BadNothingToDo)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	if !ok {
+		s.mu.Unlock()
+		return r.fault(BadSubscriptionIDInvalid)
+	}
+	results := make([]StatusCode, len(ids))
+	for i, itemID := range ids {
+		if _, ok := sub.items[itemID]; !ok {
+			results[i] = BadMonitoredItemIDInvalid
+			continue
+		}
+		delete(sub.items, itemID)
+	}
+	s.mu.Unlock()
+
+	return r.respond(encDeleteMonitoredItemsResponse, func(e *encoder) {
+		e.statusCodes(results)
+		e.diagnosticInfos()
+	})
+}
+
+// publishRequest acknowledges delivered notifications and queues the
+// request until a subscription has something to send.
+func (s *Server) publishRequest(r *request) error {
+	type ack struct{ subscription, seq uint32 }
+
+	acks := make([]ack, r.d.arrayLength())
+	for i := range acks {
+		acks[i] = ack{r.d.uint32(), r.d.uint32()}
+	}
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+
+	s.mu.Lock()
+	sess := r.session
+	if len(sess.subscriptions) == 0 {
+		s.mu.Unlock()
+		return r.fault(BadNoSubscription)
+	}
+	results := make([]StatusCode, len(acks))
+	for i, a := range acks {
+		sub, ok := sess.subscriptions[a.subscription]
+		switch {
+		case !ok:
+			results[i] = BadSubscriptionIDInvalid
+		case sub.retransmit[a.seq] == nil:
+			results[i] = BadSequenceNumberUnknown
+		default:
+			delete(sub.retransmit, a.seq)
+		}
+	}
+	for _, sub := range sess.subscriptions {
+		sub.lifetimeCounter = 0
+	}
+	sess.publish = append(sess.publish, &publishRequest{ch: r.ch, requestID: r.requestID, handle: r.handle, results: results})
+	var dropped *publishRequest
+	if len(sess.publish) > MAXPUBLISHREQUESTS {
+		dropped, sess.publish = sess.publish[0], sess.publish[1:]
+	}
+	s.mu.Unlock()
+
+	if dropped != nil {
+		dropped.fault(BadTooManyPublishRequests)
+	}
+	return nil
+}
+
+func (s *Server) republish(r *request) error {
+	id := r.d.uint32()
+	seq := r.d.uint32()
+	if r.d.err != nil {
+		return r.fault(BadDecodingError)
+	}
+
+	s.mu.Lock()
+	sub, ok := r.session.subscriptions[id]
+	var message []byte
+	if ok {
+		message = sub.retransmit[seq]
+	}
+	s.mu.Unlock()
+	switch {
+	case !ok:
+		return r.fault(BadSubscriptionIDInvalid)
+	case message == nil:
+		return r.fault(BadMessageNotAvailable)
+	}
+
+	return r.respond(encRepublishResponse, func(e *encoder) {
+		e.buf = append(e.buf, message...)
+	})
+}
+
+func (s *Server) runSubscription(sub *subscription) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(sub.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-sub.done:
+			return
+		case <-timer.C:
+		}
+
+		p, body, next := s.tick(sub)
+		if body != nil {
+			_ = p.ch.send(p.requestID, body)
+		}
+		if next == 0 {
+			return
+		}
+		timer.Reset(next)
+	}
+}
+
+// tick runs one publishing cycle. It returns the Publish request to answer
+// and its response, if any, and the time until the next cycle, or zero once
+// the subscription has expired.
+func (s *Server) tick(sub *subscription) (*publishRequest, []byte, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-sub.done:
+		return nil, nil, 0
+	default:
+	}
+
+	pending := sub.sample(s.Space)
+	sess := sub.session
+
+	var p *publishRequest
+	var body []byte
+	switch {
+	case pending && sub.enabled && len(sess.publish) > 0:
+		p, sess.publish = sess.publish[0], sess.publish[1:]
+		notifications, more := sub.drain()
+		body = sub.publishResponse(p, notifications, more)
+		sub.keepAliveCounter, sub.lifetimeCounter = 0, 0
+	case pending && sub.enabled:
+		sub.lifetimeCounter++
+	case sub.keepAliveCounter+1 >= sub.keepAliveCount && len(sess.publish) > 0:
+		p, sess.publish = sess.publish[0], sess.publish[1:]
+		body = sub.publishResponse(p, nil, false)
+		sub.keepAliveCounter, sub.lifetimeCounter = 0, 0
+	default:
+		sub.keepAliveCounter++
+		if sub.keepAliveCounter >= sub.keepAliveCount {
+			sub.lifetimeCounter++
+		}
+	}
+
+	if sub.lifetimeCounter >= sub.lifetimeCount {
+		sub.stop()
+		delete(sess.subscriptions, sub.id)
+		return p, body, 0
+	}
+	return p, body, sub.interval
+}
+
+// sample reads every enabled item, queuing values whose status or value
+// changed, and reports whether any reporting item has something queued.
+func (sub *subscription) sample(space *AddressSpace) bool {
+	pending := false
+	for _, item := range sub.items {
+		if item.mode == monitoringDisabled {
+			continue
+		}
+		v := space.Read(item.node, item.attribute)
+		if !item.sampled || changed(item.last, v) {
+			item.sampled, item.last = true, v
+			item.push(filterTimestamps(v, item.timestamps))
+		}
+		if item.mode == monitoringReporting && len(item.queue) > 0 {
+			pending = true
+		}
+	}
+	return pending
+}
+
+// drain takes the queued values of the reporting items, up to the
+// subscription's notification limit, in item order.
+func (sub *subscription) drain() ([]notification, bool) {
+	ids := make([]uint32, 0, len(sub.items))
+	for id, item := range sub.items {
+		if item.mode == monitoringReporting && len(item.queue) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var notifications []notification
+	for _, id := range ids {
+		item := sub.items[id]
+		for len(item.queue) > 0 {
+			if sub.maxNotifications > 0 && len(notifications) == int(sub.maxNotifications) {
+				return notifications, true
+			}
+			notifications = append(notifications, notification{clientHandle: item.clientHandle, value: item.queue[0]})
+			item.queue = item.queue[1:]
+		}
+	}
+	return notifications, false
+}
+
+// publishResponse answers a Publish request with a notification message,
+// or a keep-alive when there are no notifications. Keep-alives carry the
+// next sequence number without using it up.
+func (sub *subscription) publishResponse(p *publishRequest, notifications []notification, more bool) []byte {
+	e := &encoder{}
+	e.uint32(sub.seq)
+	e.dateTime(time.Now().UTC())
+	if len(notifications) == 0 {
+		e.int32(0)
+	} else {
+		e.int32(1)
+		e.extensionObject(newExtensionObject(encDataChangeNotification, func(e *encoder) {
+			e.int32(int32(len(notifications)))
+			for _, n := range notifications {
+				e.uint32(n.clientHandle)
+				e.dataValue(n.value)
+			}
+			e.diagnosticInfos()
+		}))
+
+		sub.retransmit[sub.seq] = e.buf
+		if len(sub.retransmit) > MAXRETRANSMIT {
+			delete(sub.retransmit, sub.oldestRetransmit())
+		}
+		if sub.seq++; sub.seq == 0 {
+			sub.seq = 1
+		}
+	}
+	message := e.buf
+
+	available := make([]uint32, 0, len(sub.retransmit))
+	for seq := range sub.retransmit {
+		available = append(available, seq)
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i] < available[j] })
+
+	return response(encPublishResponse, p.handle, Good, func(e *encoder) {
+		e.uint32(sub.id)
+		e.int32(int32(len(available)))
+		for _, seq := range available {
+			e.uint32(seq)
+		}
+		e.boolean(more)
+		e.buf = append(e.buf, message...)
+		e.statusCodes(p.results)
+		e.diagnosticInfos()
+	})
+}
+
+func (sub *subscription) oldestRetransmit() uint32 {
+	oldest, first := uint32(0), true
+	for seq := range sub.retransmit {
+		if first || seq < oldest {
+			oldest, first = seq, false
+		}
+	}
+	return oldest
+}
+
+func (item *monitoredItem) push(v DataValue) {
+	if len(item.queue) < int(item.queueSize) {
+		item.queue = append(item.queue, v)
+		return
+	}
+	if item.discardOldest {
+		item.queue = append(item.queue[1:], v)
+		return
+	}
+	item.queue[len(item.queue)-1] = v
+}
+
+func reviseQueueSize(size uint32) uint32 {
+	if size == 0 {
+		return 1
+	}
+	if size > MAXQUEUESIZE {
+		return MAXQUEUESIZE
+	}
+	return size
+}
+
+// changed reports whether the status or value of a sample differs from the
+// last one, the default data change trigger.
+func changed(last, v DataValue) bool {
+	if last.Status != v.Status {
+		return true
+	}
+	a, b := &encoder{}, &encoder{}
+	a.variant(last.Value)
+	b.variant(v.Value)
+	return !bytes.Equal(a.buf, b.buf)
+}
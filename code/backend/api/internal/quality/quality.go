@@ -0,0 +1,162 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/types"
+)
+
+// Severities, from best to worst.
+const (
+	GOOD      = "good"
+	UNCERTAIN = "uncertain"
+	BAD       = "bad"
+)
+
+// Subcodes, after the OPC quality substatus they stand for.
+const (
+	LOCALOVERRIDE = "localOverride"
+
+	LASTUSABLEVALUE          = "lastUsableValue"
+	SENSORNOTACCURATE        = "sensorNotAccurate"
+	ENGINEERINGUNITSEXCEEDED = "engineeringUnitsExceeded"
+	SUBNORMAL                = "subNormal"
+
+	CONFIGURATIONERROR = "configurationError"
+	NOTCONNECTED       = "notConnected"
+	DEVICEFAILURE      = "deviceFailure"
+	SENSORFAILURE      = "sensorFailure"
+	LASTKNOWNVALUE     = "lastKnownValue"
+	COMMFAILURE        = "commFailure"
+	OUTOFSERVICE       = "outOfService"
+)
+
+// Codes set automatically.
+const (
+	// OUTOFBOUNDS marks a value outside its measurement's bounds.
+	OUTOFBOUNDS = UNCERTAIN + "/" + ENGINEERINGUNITSEXCEEDED
+	// STALE marks a latest value that has not been refreshed for
+	// STALEINTERVALS sampling intervals.
+	STALE = UNCERTAIN + "/" + LASTUSABLEVALUE
+)
+
+const STALEINTERVALS = 3
+
+var ErrInvalidQuality = errors.New("invalid quality")
+
+var subcodes = map[string][]string{
+	GOOD:      {LOCALOVERRIDE},
+	UNCERTAIN: {LASTUSABLEVALUE, SENSORNOTACCURATE, ENGINEERINGUNITSEXCEEDED, SUBNORMAL},
+	BAD:       {CONFIGURATIONERROR, NOTCONNECTED, DEVICEFAILURE, SENSORFAILURE, LASTKNOWNVALUE, COMMFAILURE, OUTOFSERVICE},
+}
+
+// Parse splits a code such as "bad/sensorFailure" into its severity and
+// subcode. A code may be a bare severity; the empty code is good.
+func Parse(code string) (string, string, error) {
+	if code == "" {
+		return GOOD, "", nil
+	}
+	severity, subcode, _ := strings.Cut(code, "/")
+	allowed, ok := subcodes[severity]
+	if !ok {
+		return "", "", fmt.Errorf("%w %q: severity must be good, uncertain or bad", ErrInvalidQuality, code)
+	}
+	if subcode == "" {
+		if strings.HasSuffix(code, "/") {
+			return "", "", fmt.Errorf("%w %q: empty subcode", ErrInvalidQuality, code)
+		}
+		return severity, "", nil
+	}
+	for _, s := range allowed {
+		if s == subcode {
+			return severity, subcode, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w %q: unknown subcode for %s", ErrInvalidQuality, code, severity)
+}
+
+// Of returns the code of a reading, which is good when none was recorded.
+func Of(r types.Reading) string {
+	if r.Quality == "" {
+		return GOOD
+	}
+	return r.Quality
+}
+
+// Severity returns the severity of a code, or bad for codes that do not
+// parse.
+func Severity(code string) string {
+	severity, _, err := Parse(code)
+	if err != nil {
+		return BAD
+	}
+	return severity
+}
+
+// Assess flags a reading whose value falls outside its measurement's bounds.
+// Only good readings are downgraded; a worse code reported by the device is
+// more specific and is kept.
+func Assess(r types.Reading, m types.Measurement) types.Reading {
+	if Severity(r.Quality) != GOOD {
+		return r
+	}
+	if m.LowerBound != nil && r.Value < *m.LowerBound || m.UpperBound != nil && r.Value > *m.UpperBound {
+		r.Quality = OUTOFBOUNDS
+	}
+	return r
+}
+
+// Stale reports whether the latest reading of a series is older than
+// STALEINTERVALS of its measurement's sampling interval.
+func Stale(r types.Reading, m types.Measurement, now time.Time) bool {
+	return now.Sub(time.UnixMilli(r.Timestamp)) > STALEINTERVALS*generators.Interval(m)
+}
+
+// Current assesses the latest reading of a series, also flagging it stale
+// when it has not been refreshed in time.
+func Current(r types.Reading, m types.Measurement, now time.Time) types.Reading {
+	r = Assess(r, m)
+	if Severity(r.Quality) == GOOD && Stale(r, m, now) {
+		r.Quality = STALE
+	}
+	return r
+}
+
+// Filter matches reading qualities against a list of severities and codes.
+// The empty filter matches everything.
+type Filter []string
+
+// ParseFilter parses a comma-separated list such as "good,uncertain" or
+// "bad/sensorFailure".
+func ParseFilter(list string) (Filter, error) {
+	var filter Filter
+	for _, code := range strings.Split(list, ",") {
+		if code = strings.TrimSpace(code); code == "" {
+			continue
+		}
+		if _, _, err := Parse(code); err != nil {
+			return nil, err
+		}
+		filter = append(filter, code)
+	}
+	return filter, nil
+}
+
+// Match reports whether a reading's quality is in the filter. A severity
+// matches every code of that severity.
+func (f Filter) Match(r types.Reading) bool {
+	if len(f) == 0 {
+		return true
+	}
+	code := Of(r)
+	severity := Severity(code)
+	for _, want := range f {
+		if want == code || want == severity {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package quality
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wdd/api/internal/types"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		code     string
+		severity string
+		subcode  string
+		invalid  bool
+	}{
+		{code: "", severity: GOOD},
+		{code: "good", severity: GOOD},
+		{code: "good/localOverride", severity: GOOD, subcode: LOCALOVERRIDE},
+		{code: "uncertain", severity: UNCERTAIN},
+		{code: "uncertain/engineeringUnitsExceeded", severity: UNCERTAIN, subcode: ENGINEERINGUNITSEXCEEDED},
+		{code: "bad/sensorFailure", severity: BAD, subcode: SENSORFAILURE},
+		{code: "bad/outOfService", severity: BAD, subcode: OUTOFSERVICE},
+		{code: "fine", invalid: true},
+		{code: "Good", invalid: true},
+		{code: "bad/", invalid: true},
+		{code: "/sensorFailure", invalid: true},
+		{code: "good/sensorFailure", invalid: true},
+		{code: "bad/lastUsableValue", invalid: true},
+		{code: "bad/sensorFailure/extra", invalid: true},
+	}
+	for _, tc := range tests {
+		severity, subcode, err := Parse(tc.code)
+		if tc.invalid {
+			if !errors.Is(err, ErrInvalidQuality) {
+				t.Errorf("Expected ErrInvalidQuality for %q, got %v", tc.code, err)
+			}
+			continue
+		}
+		if err != nil || severity != tc.severity || subcode != tc.subcode {
+			t.Errorf("Expected %q to parse as %s/%s, got %s/%s (%v)", tc.code, tc.severity, tc.subcode, severity, subcode, err)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	tests := map[string]string{
+		"":                                   GOOD,
+		"good/localOverride":                 GOOD,
+		"uncertain/lastUsableValue":          UNCERTAIN,
+		"bad/commFailure":                    BAD,
+		"unknown":                            BAD,
+		"uncertain/notARealSubcode":          BAD,
+		"uncertain/engineeringUnitsExceeded": UNCERTAIN,
+	}
+	for code, want := range tests {
+		if got := Severity(code); got != want {
+			t.Errorf("Expected severity %s for %q, got %s", want, code, got)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	if got := Of(types.Reading{}); got != GOOD {
+		t.Errorf("Expected a reading without quality to be good, got %s", got)
+	}
+	if got := Of(types.Reading{Quality: "bad/sensorFailure"}); got != "bad/sensorFailure" {
+		t.Errorf("Expected the recorded quality, got %s", got)
+	}
+}
+
+func TestAssess(t *testing.T) {
+	bounded := types.Measurement{LowerBound: float(0), UpperBound: float(100)}
+	tests := []struct {
+		name        string
+		reading     types.Reading
+		measurement types.Measurement
+		want        string
+	}{
+		{name: "within bounds", reading: types.Reading{Value: 50}, measurement: bounded, want: ""},
+		{name: "on lower bound", reading: types.Reading{Value: 0}, measurement: bounded, want: ""},
+		{name: "on upper bound", reading: types.Reading{Value: 100}, measurement: bounded, want: ""},
+		{name: "below lower bound", reading: types.Reading{Value: -0.1}, measurement: bounded, want: OUTOFBOUNDS},
+		{name: "above upper bound", reading: types.Reading{Value: 100.1}, measurement: bounded, want: OUTOFBOUNDS},
+		{name: "only lower bound", reading: types.Reading{Value: 1e9}, measurement: types.Measurement{LowerBound: float(0)}, want: ""},
+		{name: "only upper bound", reading: types.Reading{Value: 1e9}, measurement: types.Measurement{UpperBound: float(0)}, want: OUTOFBOUNDS},
+		{name: "unbounded", reading: types.Reading{Value: 1e9}, measurement: types.Measurement{}, want: ""},
+		{name: "good subcode downgraded", reading: types.Reading{Value: 200, Quality: "good/localOverride"}, measurement: bounded, want: OUTOFBOUNDS},
+		{name: "device code kept", reading: types.Reading{Value: 200, Quality: "bad/sensorFailure"}, measurement: bounded, want: "bad/sensorFailure"},
+		{name: "uncertain code kept", reading: types.Reading{Value: 200, Quality: "uncertain/subNormal"}, measurement: bounded, want: "uncertain/subNormal"},
+	}
+	for _, tc := range tests {
+		if got := Assess(tc.reading, tc.measurement).Quality; got != tc.want {
+			t.Errorf("%s: expected quality %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestStaleAndCurrent(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(age time.Duration) types.Reading {
+		return types.Reading{Value: 50, Timestamp: now.Add(-age).UnixMilli()}
+	}
+	// Sampled every 10 seconds, so stale after 30.
+	slow := types.Measurement{Frequency: float(0.1), LowerBound: float(0), UpperBound: float(100)}
+	// Sampled every second by default, so stale after 3.
+	unset := types.Measurement{}
+
+	tests := []struct {
+		name        string
+		reading     types.Reading
+		measurement types.Measurement
+		stale       bool
+		want        string
+	}{
+		{name: "fresh", reading: at(10 * time.Second), measurement: slow, stale: false, want: ""},
+		{name: "at threshold", reading: at(30 * time.Second), measurement: slow, stale: false, want: ""},
+		{name: "past threshold", reading: at(31 * time.Second), measurement: slow, stale: true, want: STALE},
+		{name: "default interval fresh", reading: at(3 * time.Second), measurement: unset, stale: false, want: ""},
+		{name: "default interval stale", reading: at(4 * time.Second), measurement: unset, stale: true, want: STALE},
+		{name: "out of bounds wins", reading: types.Reading{Value: 200, Timestamp: now.Add(-time.Hour).UnixMilli()}, measurement: slow, stale: true, want: OUTOFBOUNDS},
+		{name: "device code kept", reading: types.Reading{Value: 50, Quality: "bad/commFailure", Timestamp: now.Add(-time.Hour).UnixMilli()}, measurement: slow, stale: true, want: "bad/commFailure"},
+	}
+	for _, tc := range tests {
+		if got := Stale(tc.reading, tc.measurement, now); got != tc.stale {
+			t.Errorf("%s: expected stale %t, got %t", tc.name, tc.stale, got)
+		}
+		if got := Current(tc.reading, tc.measurement, now).Quality; got != tc.want {
+			t.Errorf("%s: expected quality %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter(" good, bad/sensorFailure ,,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(filter) != 2 || filter[0] != GOOD || filter[1] != "bad/sensorFailure" {
+		t.Errorf("Expected [good bad/sensorFailure], got %v", filter)
+	}
+
+	if filter, err := ParseFilter(""); err != nil || len(filter) != 0 {
+		t.Errorf("Expected an empty filter, got %v (%v)", filter, err)
+	}
+	if _, err := ParseFilter("good,broken"); !errors.Is(err, ErrInvalidQuality) {
+		t.Errorf("Expected ErrInvalidQuality, got %v", err)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		filter  string
+		quality string
+		match   bool
+	}{
+		{filter: "", quality: "bad/sensorFailure", match: true},
+		{filter: "good", quality: "", match: true},
+		{filter: "good", quality: "good/localOverride", match: true},
+		{filter: "good", quality: "uncertain/lastUsableValue", match: false},
+		{filter: "uncertain", quality: OUTOFBOUNDS, match: true},
+		{filter: "uncertain", quality: "uncertain", match: true},
+		{filter: "bad", quality: "bad/commFailure", match: true},
+		{filter: "bad/sensorFailure", quality: "bad/sensorFailure", match: true},
+		{filter: "bad/sensorFailure", quality: "bad/commFailure", match: false},
+		{filter: "bad/sensorFailure", quality: "bad", match: false},
+		{filter: "good/localOverride", quality: "", match: false},
+		{filter: "good,bad/sensorFailure", quality: "bad/sensorFailure", match: true},
+		{filter: "good,bad/sensorFailure", quality: "uncertain/subNormal", match: false},
+		// Codes that do not parse are treated as bad.
+		{filter: "bad", quality: "corrupted", match: true},
+	}
+	for _, tc := range tests {
+		filter, err := ParseFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("Expected filter %q to parse, got %v", tc.filter, err)
+		}
+		if got := filter.Match(types.Reading{Quality: tc.quality}); got != tc.match {
+			t.Errorf("Expected filter %q to match %q: %t, got %t", tc.filter, tc.quality, tc.match, got)
+		}
+	}
+}
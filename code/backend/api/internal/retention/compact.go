@@ -0,0 +1,241 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+)
+
+type Request struct {
+	// FactoryID limits compaction to one factory; empty compacts every factory.
+	FactoryID string `json:"factoryId,omitempty"`
+	// Rebuild recomputes rollups from whatever source data is still retained
+	// instead of continuing after the latest rollup.
+	Rebuild bool `json:"rebuild,omitempty"`
+	// At is the instant retention is evaluated at, defaulting to now.
+	At time.Time `json:"at,omitempty"`
+}
+
+type Stats struct {
+	Factories       int     `json:"factories"`
+	Series          int     `json:"series"`
+	Rollups         int64   `json:"rollups"`
+	Deleted         int64   `json:"deleted"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// Compactor rolls raw readings up into the tiers of each factory's policy and
+// deletes raw readings once they are older than the raw retention. Expired
+// rollups are left to DynamoDB's TTL on the expiresAt attribute.
+type Compactor struct {
+	Catalog *catalog.Catalog
+	Store   *timeseries.Store
+}
+
+func New(db types.DynamoDBClient) *Compactor {
+	return &Compactor{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+	}
+}
+
+// Load returns the compiled policy of a factory, or the default policy when
+// the factory has none.
+func Load(ctx context.Context, c *catalog.Catalog, factoryID string) (*Policy, error) {
+	policy, err := c.RetentionPolicy(ctx, factoryID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		policy = Default(factoryID)
+	} else if err != nil {
+		return nil, err
+	}
+	return Compile(policy)
+}
+
+func (c Compactor) Run(ctx context.Context, request Request) (Stats, error) {
+	started := time.Now()
+	if request.At.IsZero() {
+		request.At = started
+	}
+
+	var stats Stats
+	factoryIDs := []string{request.FactoryID}
+	if request.FactoryID == "" {
+		factories, err := c.Catalog.Factories(ctx)
+		if err != nil {
+			return stats, err
+		}
+		factoryIDs = factoryIDs[:0]
+		for _, factory := range factories {
+			factoryIDs = append(factoryIDs, factory.FactoryID)
+		}
+	}
+
+	var err error
+	for _, factoryID := range factoryIDs {
+		if err = c.compactFactory(ctx, factoryID, request, &stats); err != nil {
+			break
+		}
+		stats.Factories++
+	}
+	stats.DurationSeconds = time.Since(started).Seconds()
+	return stats, err
+}
+
+func (c Compactor) compactFactory(ctx context.Context, factoryID string, request Request, stats *Stats) error {
+	policy, err := Load(ctx, c.Catalog, factoryID)
+	if err != nil {
+		return err
+	}
+	series, err := c.Catalog.FactorySeries(ctx, factoryID)
+	if err != nil {
+		return err
+	}
+
+	// Assets sharing a model share their properties' readings.
+	seen := make(map[string]bool, len(series))
+	for _, s := range series {
+		propertyID := s.Property.PropertyID
+		if seen[propertyID] {
+			continue
+		}
+		seen[propertyID] = true
+
+		if err = c.compactSeries(ctx, propertyID, policy, request, stats); err != nil {
+			return err
+		}
+		stats.Series++
+	}
+	return nil
+}
+
+func (c Compactor) compactSeries(ctx context.Context, propertyID string, policy *Policy, request Request, stats *Stats) error {
+	now := request.At
+	// Only delete whole buckets of the finest rollup so a rebuild never
+	// overwrites a rollup from a partially deleted minute.
+	cutoff := now.Add(-policy.Raw.Retention)
+	if len(policy.Rollups) > 0 {
+		cutoff = align(cutoff, policy.Rollups[0].Resolution)
+	}
+
+	// Backfill, bulk ingest and line protocol writes can add readings behind
+	// the latest rollups, which continuing after them would never see. Every
+	// bucket from the oldest raw reading up to the cutoff is rolled up again
+	// before those readings are deleted.
+	var redo time.Time
+	if len(policy.Rollups) > 0 && !request.Rebuild {
+		oldest, err := c.Store.Earliest(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+		if oldest != nil && oldest.Timestamp < cutoff.UnixMilli() {
+			redo = time.UnixMilli(oldest.Timestamp)
+		}
+	}
+
+	source := policy.Raw
+	for _, tier := range policy.Rollups {
+		start, err := c.rollupStart(ctx, propertyID, tier, source, request)
+		if err != nil {
+			return err
+		}
+		if !redo.IsZero() {
+			from := align(redo, tier.Resolution)
+			if retained := retainedFrom(tier, source, now); from.Before(retained) {
+				from = retained
+			}
+			to := align(cutoff.Add(tier.Resolution-time.Millisecond), tier.Resolution)
+			if to.After(start) {
+				to = start
+			}
+			if err = c.compactRange(ctx, propertyID, tier, source, from, to, stats); err != nil {
+				return err
+			}
+		}
+		if err = c.compactRange(ctx, propertyID, tier, source, start, align(now, tier.Resolution), stats); err != nil {
+			return err
+		}
+		source = tier
+	}
+
+	deleted, err := c.Store.Delete(ctx, propertyID, time.UnixMilli(0), cutoff)
+	stats.Deleted += deleted
+	return err
+}
+
+// compactRange rolls the buckets of a tier in [start, end) up from its source.
+func (c Compactor) compactRange(ctx context.Context, propertyID string, tier, source Tier, start, end time.Time, stats *Stats) error {
+	if !end.After(start) {
+		return nil
+	}
+	rollups, err := c.rollup(ctx, propertyID, tier, source, start, end)
+	if err != nil {
+		return err
+	}
+	if err = c.Store.WriteRollups(ctx, rollups); err != nil {
+		return err
+	}
+	stats.Rollups += int64(len(rollups))
+	return nil
+}
+
+// rollupStart is where a tier continues from: after its latest rollup, or
+// from the oldest complete source data when rebuilding. Raw readings are only
+// ever removed by compaction, so they are always complete; rollup sources
+// expire through TTL and are only trusted inside their retention.
+func (c Compactor) rollupStart(ctx context.Context, propertyID string, tier, source Tier, request Request) (time.Time, error) {
+	start := retainedFrom(tier, source, request.At)
+	if request.Rebuild {
+		return start, nil
+	}
+
+	latest, err := c.Store.LatestRollup(ctx, propertyID, tier.Name)
+	if err != nil || latest == nil {
+		return start, err
+	}
+	if next := time.UnixMilli(latest.Timestamp).Add(tier.Resolution); next.After(start) {
+		start = next
+	}
+	return start, nil
+}
+
+// retainedFrom is the first bucket of a tier whose source data is complete at
+// now.
+func retainedFrom(tier, source Tier, now time.Time) time.Time {
+	if source.Resolution > 0 && source.Retention > 0 {
+		return align(now.Add(-source.Retention), tier.Resolution).Add(tier.Resolution)
+	}
+	return time.UnixMilli(0)
+}
+
+func (c Compactor) rollup(ctx context.Context, propertyID string, tier, source Tier, start, end time.Time) ([]types.Rollup, error) {
+	agg, err := timeseries.NewAggregator(tier.Resolution, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.Resolution == 0 {
+		it := c.Store.Iterate(propertyID, start, end)
+		for it.Next(ctx) {
+			agg.Add(it.Reading())
+		}
+		err = it.Err()
+	} else {
+		it := c.Store.IterateRollups(propertyID, source.Name, start, end)
+		for it.Next(ctx) {
+			agg.AddRollup(it.Rollup())
+		}
+		err = it.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rollups := agg.Rollups(propertyID, tier.Name)
+	for i := range rollups {
+		rollups[i].ExpiresAt = tier.ExpiresAt(rollups[i].Timestamp)
+	}
+	return rollups, nil
+}
@@ -0,0 +1,182 @@
+package retention
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+	"wdd/api/internal/mocks"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type item = map[string]ddbtypes.AttributeValue
+
+// memoryTables keeps the Reading and ReadingRollup tables in memory, keyed by
+// partition key and then timestamp, and answers range queries like DynamoDB.
+type memoryTables map[string]map[string]map[int64]item
+
+func (m memoryTables) put(table, key string, it item) {
+	partition := it[key].(*ddbtypes.AttributeValueMemberS).Value
+	ts, _ := strconv.ParseInt(it["timestamp"].(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+	if m[table] == nil {
+		m[table] = map[string]map[int64]item{}
+	}
+	if m[table][partition] == nil {
+		m[table][partition] = map[int64]item{}
+	}
+	m[table][partition][ts] = it
+}
+
+func (m memoryTables) client() *mocks.DynamoDBClient {
+	keys := map[string]string{"Reading": "propertyId", "ReadingRollup": "seriesId"}
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch *params.TableName {
+			case "Property":
+				return &dynamodb.GetItemOutput{Item: item{
+					"propertyId":    &ddbtypes.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &ddbtypes.AttributeValueMemberS{Value: "meas"},
+				}}, nil
+			case "Measurement":
+				return &dynamodb.GetItemOutput{Item: item{"measurementId": &ddbtypes.AttributeValueMemberS{Value: "meas"}}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			switch *params.TableName {
+			case "Asset":
+				return &dynamodb.QueryOutput{Items: []item{
+					{"assetId": &ddbtypes.AttributeValueMemberS{Value: "a1"}, "modelId": &ddbtypes.AttributeValueMemberS{Value: "m1"}},
+					{"assetId": &ddbtypes.AttributeValueMemberS{Value: "a2"}, "modelId": &ddbtypes.AttributeValueMemberS{Value: "m1"}},
+				}}, nil
+			case "Model":
+				return &dynamodb.QueryOutput{Items: []item{{
+					"modelId":    &ddbtypes.AttributeValueMemberS{Value: "m1"},
+					"properties": &ddbtypes.AttributeValueMemberSS{Value: []string{"p1"}},
+				}}}, nil
+			}
+
+			key := keys[*params.TableName]
+			partition := params.ExpressionAttributeValues[":"+key].(*ddbtypes.AttributeValueMemberS).Value
+			start, end := int64(-1<<62), int64(1<<62)
+			if v, ok := params.ExpressionAttributeValues[":start"]; ok {
+				start, _ = strconv.ParseInt(v.(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+				end, _ = strconv.ParseInt(params.ExpressionAttributeValues[":end"].(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+			}
+
+			var timestamps []int64
+			for ts := range m[*params.TableName][partition] {
+				if ts >= start && ts <= end {
+					timestamps = append(timestamps, ts)
+				}
+			}
+			sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+			if params.ScanIndexForward != nil && !*params.ScanIndexForward {
+				sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] > timestamps[j] })
+			}
+			if params.Limit != nil && len(timestamps) > int(*params.Limit) {
+				timestamps = timestamps[:*params.Limit]
+			}
+
+			items := make([]item, 0, len(timestamps))
+			for _, ts := range timestamps {
+				items = append(items, m[*params.TableName][partition][ts])
+			}
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			for table, requests := range params.RequestItems {
+				for _, request := range requests {
+					if request.PutRequest != nil {
+						m.put(table, keys[table], request.PutRequest.Item)
+						continue
+					}
+					partition := request.DeleteRequest.Key[keys[table]].(*ddbtypes.AttributeValueMemberS).Value
+					ts, _ := strconv.ParseInt(request.DeleteRequest.Key["timestamp"].(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+					delete(m[table][partition], ts)
+				}
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+}
+
+func TestCompactor_Run(t *testing.T) {
+	tables := memoryTables{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// One reading every 10 seconds for 10 days, valued by its minute of the day.
+	for ts := start; ts.Before(start.Add(10 * DAY)); ts = ts.Add(10 * time.Second) {
+		tables.put("Reading", "propertyId", item{
+			"propertyId": &ddbtypes.AttributeValueMemberS{Value: "p1"},
+			"timestamp":  &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(ts.UnixMilli(), 10)},
+			"value":      &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(ts.Hour()*60 + ts.Minute())},
+		})
+	}
+
+	at := start.Add(10*DAY + 30*time.Second)
+	stats, err := New(tables.client()).Run(context.Background(), Request{FactoryID: "f1", At: at})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stats.Factories != 1 || stats.Series != 1 {
+		t.Errorf("Expected the shared property to be compacted once, got %+v", stats)
+	}
+	if minutes, hours := len(tables["ReadingRollup"]["p1#1m"]), len(tables["ReadingRollup"]["p1#1h"]); minutes != 10*24*60 || hours != 10*24 {
+		t.Errorf("Expected %d minute and %d hour rollups, got %d and %d", 10*24*60, 10*24, minutes, hours)
+	}
+	if remaining := len(tables["Reading"]["p1"]); remaining != 7*24*60*6 {
+		t.Errorf("Expected 7 days of raw readings to remain, got %d", remaining)
+	}
+	if stats.Deleted != 3*24*60*6 {
+		t.Errorf("Expected 3 days of raw readings to be deleted, got %d", stats.Deleted)
+	}
+
+	hour := tables["ReadingRollup"]["p1#1h"][start.Add(time.Hour).UnixMilli()]
+	if hour["count"].(*ddbtypes.AttributeValueMemberN).Value != "360" || hour["min"].(*ddbtypes.AttributeValueMemberN).Value != "60" ||
+		hour["max"].(*ddbtypes.AttributeValueMemberN).Value != "119" {
+		t.Errorf("Expected the 01:00 rollup to cover 360 readings from 60 to 119, got %v", hour)
+	}
+	if _, ok := hour["expiresAt"]; ok {
+		t.Errorf("Expected hour rollups to have no TTL")
+	}
+	if _, ok := tables["ReadingRollup"]["p1#1m"][start.UnixMilli()]["expiresAt"]; !ok {
+		t.Errorf("Expected minute rollups to have a TTL")
+	}
+
+	// A second run continues after the latest rollups, and rolls the minute
+	// and hour it deletes raw readings from up again.
+	tables.put("Reading", "propertyId", item{
+		"propertyId": &ddbtypes.AttributeValueMemberS{Value: "p1"},
+		"timestamp":  &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(at.UnixMilli(), 10)},
+		"value":      &ddbtypes.AttributeValueMemberN{Value: "1"},
+	})
+	stats, err = New(tables.client()).Run(context.Background(), Request{FactoryID: "f1", At: at.Add(time.Minute)})
+	if err != nil || stats.Rollups != 3 || stats.Deleted != 6 {
+		t.Errorf("Expected one new and two recomputed rollups and one minute of raw readings deleted, got %+v %v", stats, err)
+	}
+
+	// A reading written late, behind the latest rollups, reaches them before
+	// it is deleted.
+	late := start.Add(3*DAY + 2*time.Minute + 5*time.Second)
+	tables.put("Reading", "propertyId", item{
+		"propertyId": &ddbtypes.AttributeValueMemberS{Value: "p1"},
+		"timestamp":  &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(late.UnixMilli(), 10)},
+		"value":      &ddbtypes.AttributeValueMemberN{Value: "5000"},
+	})
+	stats, err = New(tables.client()).Run(context.Background(), Request{FactoryID: "f1", At: at.Add(3 * time.Minute)})
+	if err != nil || stats.Deleted != 13 {
+		t.Fatalf("Expected two minutes of raw readings and the late one deleted, got %+v %v", stats, err)
+	}
+	minute := tables["ReadingRollup"]["p1#1m"][start.Add(3*DAY+2*time.Minute).UnixMilli()]
+	if minute["count"].(*ddbtypes.AttributeValueMemberN).Value != "7" || minute["max"].(*ddbtypes.AttributeValueMemberN).Value != "5000" {
+		t.Errorf("Expected the minute rollup to include the late reading, got %v", minute)
+	}
+	hour = tables["ReadingRollup"]["p1#1h"][start.Add(3*DAY).UnixMilli()]
+	if hour["count"].(*ddbtypes.AttributeValueMemberN).Value != "361" || hour["max"].(*ddbtypes.AttributeValueMemberN).Value != "5000" {
+		t.Errorf("Expected the hour rollup to include the late reading, got %v", hour)
+	}
+}
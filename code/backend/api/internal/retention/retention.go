@@ -0,0 +1,153 @@
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wdd/api/internal/types"
+)
+
+const (
+	RAW = "raw"
+
+	DEFAULTRAWDAYS = 7
+	DAY            = 24 * time.Hour
+)
+
+var ErrInvalidPolicy = errors.New("invalid retention policy")
+
+// Tier is one resolution readings are kept at. The raw tier has a zero
+// Resolution; a zero Retention keeps the tier forever.
+type Tier struct {
+	Name       string        `json:"name"`
+	Resolution time.Duration `json:"-"`
+	Retention  time.Duration `json:"-"`
+}
+
+// Policy is a validated retention policy. Each rollup tier is computed from
+// the tier before it, so resolutions increase and divide one another.
+type Policy struct {
+	FactoryID string
+	Raw       Tier
+	Rollups   []Tier
+}
+
+// Default keeps raw readings for 7 days, 1 minute rollups for 90 days and
+// 1 hour rollups forever.
+func Default(factoryID string) types.RetentionPolicy {
+	rawDays, minuteDays := DEFAULTRAWDAYS, 90
+	return types.RetentionPolicy{
+		FactoryID: factoryID,
+		RawDays:   &rawDays,
+		Rollups: []types.RollupPolicy{
+			{Resolution: "1m", RetentionDays: &minuteDays},
+			{Resolution: "1h"},
+		},
+	}
+}
+
+// Effective fills the unset fields of a policy with the defaults. An
+// explicitly empty rollup list disables rollups.
+func Effective(policy types.RetentionPolicy) types.RetentionPolicy {
+	defaults := Default(policy.FactoryID)
+	if policy.RawDays == nil {
+		policy.RawDays = defaults.RawDays
+	}
+	if policy.Rollups == nil {
+		policy.Rollups = defaults.Rollups
+	}
+	return policy
+}
+
+// Compile validates the effective policy.
+func Compile(policy types.RetentionPolicy) (*Policy, error) {
+	policy = Effective(policy)
+	if *policy.RawDays < 1 {
+		return nil, fmt.Errorf("%w: rawDays must be at least 1", ErrInvalidPolicy)
+	}
+	compiled := &Policy{
+		FactoryID: policy.FactoryID,
+		Raw:       Tier{Name: RAW, Retention: time.Duration(*policy.RawDays) * DAY},
+	}
+
+	previous := compiled.Raw
+	for _, rollup := range policy.Rollups {
+		name := strings.TrimSpace(rollup.Resolution)
+		resolution, err := time.ParseDuration(name)
+		if err != nil || resolution < time.Second || resolution%time.Second != 0 {
+			return nil, fmt.Errorf("%w: resolution %q must be a whole number of seconds", ErrInvalidPolicy, rollup.Resolution)
+		}
+		if previous.Resolution > 0 && (resolution <= previous.Resolution || resolution%previous.Resolution != 0) {
+			return nil, fmt.Errorf("%w: resolution %s must be a multiple of %s", ErrInvalidPolicy, name, previous.Name)
+		}
+
+		tier := Tier{Name: name, Resolution: resolution}
+		if rollup.RetentionDays != nil {
+			if *rollup.RetentionDays < 1 {
+				return nil, fmt.Errorf("%w: retentionDays of %s must be at least 1", ErrInvalidPolicy, name)
+			}
+			tier.Retention = time.Duration(*rollup.RetentionDays) * DAY
+		}
+		if previous.Retention == 0 || tier.Retention > 0 && tier.Retention < previous.Retention {
+			return nil, fmt.Errorf("%w: %s rollups must be kept at least as long as %s", ErrInvalidPolicy, name, previous.Name)
+		}
+
+		compiled.Rollups = append(compiled.Rollups, tier)
+		previous = tier
+	}
+	return compiled, nil
+}
+
+// Tiers returns the raw tier followed by the rollup tiers, finest first.
+func (p Policy) Tiers() []Tier {
+	return append([]Tier{p.Raw}, p.Rollups...)
+}
+
+// Pick chooses the tier to answer a query starting at start. It only
+// considers tiers that still hold data for start and, for bucketed queries,
+// prefers the coarsest tier whose resolution divides the bucket since that
+// reads the fewest items for an exact answer.
+func (p Policy) Pick(start, now time.Time, bucket time.Duration) Tier {
+	var picked *Tier
+	for _, tier := range p.Tiers() {
+		tier := tier
+		if !tier.Covers(start, now) {
+			continue
+		}
+		if picked == nil {
+			picked = &tier
+		}
+		if bucket > 0 && tier.Resolution > 0 && bucket%tier.Resolution == 0 {
+			picked = &tier
+		}
+	}
+	if picked == nil {
+		// Nothing reaches back far enough; the coarsest tier has the most history.
+		tiers := p.Tiers()
+		return tiers[len(tiers)-1]
+	}
+	return *picked
+}
+
+// Covers reports whether the tier still holds data for instants at or after start.
+func (t Tier) Covers(start, now time.Time) bool {
+	return t.Retention == 0 || !start.Before(now.Add(-t.Retention))
+}
+
+// ExpiresAt is the TTL, in Unix seconds, of a rollup of this tier starting at
+// timestamp (in milliseconds), or nil when the tier is kept forever.
+func (t Tier) ExpiresAt(timestamp int64) *int64 {
+	if t.Retention == 0 {
+		return nil
+	}
+	expires := (timestamp + (t.Resolution + t.Retention).Milliseconds()) / 1000
+	return &expires
+}
+
+// align rounds t down to a multiple of d since the Unix epoch.
+func align(t time.Time, d time.Duration) time.Time {
+	ms, width := t.UnixMilli(), d.Milliseconds()
+	ms -= ms % width
+	return time.UnixMilli(ms)
+}
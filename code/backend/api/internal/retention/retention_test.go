@@ -0,0 +1,87 @@
+package retention
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wdd/api/internal/types"
+)
+
+func days(n int) *int {
+	return &n
+}
+
+func TestCompile_Defaults(t *testing.T) {
+	policy, err := Compile(types.RetentionPolicy{FactoryID: "f1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if policy.Raw.Retention != 7*DAY {
+		t.Errorf("Expected raw readings to be kept 7 days, got %s", policy.Raw.Retention)
+	}
+	if len(policy.Rollups) != 2 || policy.Rollups[0].Resolution != time.Minute || policy.Rollups[0].Retention != 90*DAY ||
+		policy.Rollups[1].Resolution != time.Hour || policy.Rollups[1].Retention != 0 {
+		t.Errorf("Expected 1m rollups for 90 days and 1h rollups forever, got %+v", policy.Rollups)
+	}
+
+	policy, err = Compile(types.RetentionPolicy{FactoryID: "f1", Rollups: []types.RollupPolicy{}})
+	if err != nil || len(policy.Rollups) != 0 {
+		t.Errorf("Expected an empty rollup list to disable rollups, got %+v %v", policy, err)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	cases := []types.RetentionPolicy{
+		{RawDays: days(0)},
+		{Rollups: []types.RollupPolicy{{Resolution: "soon"}}},
+		{Rollups: []types.RollupPolicy{{Resolution: "500ms"}}},
+		{Rollups: []types.RollupPolicy{{Resolution: "1h"}, {Resolution: "1m"}}},
+		{Rollups: []types.RollupPolicy{{Resolution: "1m"}, {Resolution: "90s"}}},
+		{Rollups: []types.RollupPolicy{{Resolution: "1m"}, {Resolution: "1h", RetentionDays: days(30)}}},
+		{Rollups: []types.RollupPolicy{{Resolution: "1m", RetentionDays: days(90)}, {Resolution: "1h", RetentionDays: days(30)}}},
+		{RawDays: days(7), Rollups: []types.RollupPolicy{{Resolution: "1m", RetentionDays: days(1)}}},
+	}
+
+	for _, policy := range cases {
+		if _, err := Compile(policy); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("Expected ErrInvalidPolicy for %+v, got %v", policy, err)
+		}
+	}
+}
+
+func TestPolicy_Pick(t *testing.T) {
+	policy, _ := Compile(types.RetentionPolicy{})
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		start    time.Time
+		bucket   time.Duration
+		expected string
+	}{
+		{now.Add(-time.Hour), 0, RAW},
+		{now.Add(-time.Hour), 30 * time.Second, RAW},
+		{now.Add(-time.Hour), 5 * time.Minute, "1m"},
+		{now.Add(-24 * time.Hour), 2 * time.Hour, "1h"},
+		{now.Add(-30 * DAY), 0, "1m"},
+		{now.Add(-30 * DAY), 90 * time.Second, "1m"},
+		{now.Add(-365 * DAY), 0, "1h"},
+	}
+
+	for _, c := range cases {
+		if tier := policy.Pick(c.start, now, c.bucket); tier.Name != c.expected {
+			t.Errorf("Expected %s for a query from %s with bucket %s, got %s", c.expected, c.start, c.bucket, tier.Name)
+		}
+	}
+}
+
+func TestTier_ExpiresAt(t *testing.T) {
+	policy, _ := Compile(types.RetentionPolicy{})
+
+	if expires := policy.Rollups[0].ExpiresAt(60000); expires == nil || *expires != 120+90*86400 {
+		t.Errorf("Expected the 1m rollup to expire 90 days after it closes, got %v", expires)
+	}
+	if expires := policy.Rollups[1].ExpiresAt(0); expires != nil {
+		t.Errorf("Expected 1h rollups to be kept forever, got %d", *expires)
+	}
+}
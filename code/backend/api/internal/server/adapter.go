@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MAXBODYBYTES mirrors API Gateway's payload limit.
+const MAXBODYBYTES = 10 << 20
+
+// LambdaHandler is the signature of every API Gateway proxy handler.
+type LambdaHandler func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Adapt serves a Lambda proxy handler over net/http, translating the request
+// and response the way API Gateway does.
+func Adapt(handler LambdaHandler) http.HandlerFunc {
+	return adapt(handler, "", nil)
+}
+
+// AdaptPath serves a Lambda proxy handler under an API Gateway resource
+// template such as /assets/{id}/snapshot, passing the matched segments as
+// path parameters. Paths that do not match the template are not found.
+func AdaptPath(resource string, handler LambdaHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parameters, ok := matchResource(resource, r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		adapt(handler, resource, parameters)(w, r)
+	}
+}
+
+func adapt(handler LambdaHandler, resource string, parameters map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAXBODYBYTES))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Error reading request body", http.StatusBadRequest)
+			return
+		}
+
+		request := proxyRequest(r, body)
+		if resource != "" {
+			request.Resource = resource
+			request.PathParameters = parameters
+		}
+		response, err := handler(r.Context(), request)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusBadGateway)
+			return
+		}
+		writeProxyResponse(w, response)
+	}
+}
+
+func proxyRequest(r *http.Request, body []byte) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{
+		Resource:          r.URL.Path,
+		Path:              r.URL.Path,
+		HTTPMethod:        r.Method,
+		Headers:           map[string]string{},
+		MultiValueHeaders: map[string][]string{},
+		Body:              string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Path:             r.URL.Path,
+			HTTPMethod:       r.Method,
+			RequestTimeEpoch: time.Now().UnixMilli(),
+		},
+	}
+	for name, values := range r.Header {
+		request.Headers[name] = values[0]
+		request.MultiValueHeaders[name] = values
+	}
+
+	query := r.URL.Query()
+	if len(query) > 0 {
+		request.QueryStringParameters = make(map[string]string, len(query))
+		request.MultiValueQueryStringParameters = make(map[string][]string, len(query))
+		for name, values := range query {
+			request.QueryStringParameters[name] = values[0]
+			request.MultiValueQueryStringParameters[name] = values
+		}
+	}
+	return request
+}
+
+// matchResource matches a path against a resource template segment by
+// segment, collecting the segments matched by {name} placeholders.
+func matchResource(resource, path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(resource, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+
+	parameters := map[string]string{}
+	for i, segment := range want {
+		if name, ok := strings.CutPrefix(segment, "{"); ok && strings.HasSuffix(name, "}") {
+			if got[i] == "" {
+				return nil, false
+			}
+			parameters[strings.TrimSuffix(name, "}")] = got[i]
+			continue
+		}
+		if segment != got[i] {
+			return nil, false
+		}
+	}
+	return parameters, true
+}
+
+func writeProxyResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusBadGateway)
+			return
+		}
+		body = decoded
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// methods routes a path by HTTP method and answers CORS preflight requests.
+type methods map[string]http.Handler
+
+func (m methods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := m[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	allowed := make([]string, 0, len(m)+1)
+	for method := range m {
+		allowed = append(allowed, method)
+	}
+	allowed = append(allowed, http.MethodOptions)
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAdapt(t *testing.T) {
+	var received events.APIGatewayProxyRequest
+	handler := Adapt(func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		received = request
+		return events.APIGatewayProxyResponse{
+			StatusCode:      http.StatusCreated,
+			Headers:         map[string]string{"Content-Type": "application/octet-stream"},
+			Body:            base64.StdEncoding.EncodeToString([]byte("PAR1")),
+			IsBase64Encoded: true,
+		}, nil
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/assets?factoryId=f1&tag=a&tag=b", strings.NewReader(`{"name":"Press"}`))
+	request.Header.Set("Authorization", "Bearer token")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if received.HTTPMethod != http.MethodPost || received.Path != "/assets" || received.Body != `{"name":"Press"}` {
+		t.Errorf("Unexpected proxy request %+v", received)
+	}
+	if received.QueryStringParameters["factoryId"] != "f1" || len(received.MultiValueQueryStringParameters["tag"]) != 2 {
+		t.Errorf("Expected query string parameters to be passed through, got %v", received.MultiValueQueryStringParameters)
+	}
+	if received.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("Expected headers to be passed through, got %v", received.Headers)
+	}
+
+	body, _ := io.ReadAll(recorder.Result().Body)
+	if recorder.Code != http.StatusCreated || string(body) != "PAR1" || recorder.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("Expected the decoded binary response, got %d %q %v", recorder.Code, body, recorder.Header())
+	}
+}
+
+func TestAdaptPath(t *testing.T) {
+	var received events.APIGatewayProxyRequest
+	handler := AdaptPath("/assets/{id}/snapshot", func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		received = request
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/assets/a1/snapshot", nil))
+	if recorder.Code != http.StatusOK || received.PathParameters["id"] != "a1" || received.Resource != "/assets/{id}/snapshot" {
+		t.Errorf("Expected the asset ID as a path parameter, got %d %+v", recorder.Code, received)
+	}
+
+	for _, path := range []string{"/assets/a1", "/assets//snapshot", "/assets/a1/other", "/assets/a1/snapshot/x"} {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusNotFound, path, recorder.Code)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	routes := methods{http.MethodGet: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/factories", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/factories", nil))
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected a CORS preflight response, got %d %v", recorder.Code, recorder.Header())
+	}
+}
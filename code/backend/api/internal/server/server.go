@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/handlers/assets"
+	"wdd/api/internal/handlers/auth"
+	"wdd/api/internal/handlers/calendars"
+	"wdd/api/internal/handlers/factories"
+	"wdd/api/internal/handlers/floorplan"
+	"wdd/api/internal/handlers/lineprotocolmappings"
+	"wdd/api/internal/handlers/measurements"
+	"wdd/api/internal/handlers/metrics"
+	"wdd/api/internal/handlers/models"
+	"wdd/api/internal/handlers/properties"
+	"wdd/api/internal/handlers/readings"
+	"wdd/api/internal/handlers/registermaps"
+	"wdd/api/internal/handlers/retentionpolicies"
+	"wdd/api/internal/handlers/zones"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/stream"
+	"wdd/api/internal/types"
+)
+
+const DEFAULTHEARTBEAT = 15 * time.Second
+
+type Config struct {
+	DynamoDB    types.DynamoDBClient
+	S3Uploader  types.S3Uploader
+	S3Presigner types.S3Presigner
+	// Cognito is optional; the auth routes are only served when it is set.
+	Cognito types.Cognito
+	// Seed seeds the random generators of the live simulation.
+	Seed int64
+	// Heartbeat is the default interval between stream heartbeats.
+	Heartbeat time.Duration
+}
+
+// Server runs the whole API as one local HTTP server: every Lambda handler
+// under its API Gateway route, plus the live readings streams, which need
+// long-lived connections Lambda cannot hold.
+type Server struct {
+	Hub       *stream.Hub
+	Live      *stream.Live
+	Catalog   *catalog.Catalog
+	Heartbeat time.Duration
+
+	mux *http.ServeMux
+}
+
+// New builds the server. Simulations started for stream subscribers stop
+// when ctx is done.
+func New(ctx context.Context, cfg Config) *Server {
+	hub := stream.NewHub()
+	db := stream.Tap(cfg.DynamoDB, hub)
+	c := catalog.New(db)
+
+	s := &Server{
+		Hub:       hub,
+		Live:      stream.NewLive(ctx, hub, simulator.New(c, cfg.Seed)),
+		Catalog:   c,
+		Heartbeat: cfg.Heartbeat,
+		mux:       http.NewServeMux(),
+	}
+	if s.Heartbeat <= 0 {
+		s.Heartbeat = DEFAULTHEARTBEAT
+	}
+
+	s.mux.Handle("/factories", methods{
+		http.MethodGet:    Adapt(factories.NewReadFactoryHandler(db).HandleReadFactoryRequest),
+		http.MethodPost:   Adapt(factories.NewCreateFactoryHandler(db).HandleCreateFactoryRequest),
+		http.MethodPut:    Adapt(factories.NewUpdateFactoryHandler(db).HandleUpdateFactoryRequest),
+		http.MethodDelete: Adapt(factories.NewDeleteFactoryHandler(db).HandleDeleteFactoryRequest),
+	})
+	s.mux.Handle("/factories/clusters", methods{
+		http.MethodGet: Adapt(factories.NewReadFactoryClustersHandler(db).HandleReadFactoryClustersRequest),
+	})
+	s.mux.Handle("/factories/", methods{
+		http.MethodGet: AdaptPath("/factories/{id}/snapshot", factories.NewReadFactorySnapshotHandler(db).HandleReadFactorySnapshotRequest),
+	})
+	s.mux.Handle("/assets", methods{
+		http.MethodGet:    Adapt(assets.NewReadFactoryAssetsHandler(db).HandleReadFactoryAssetsRequest),
+		http.MethodPost:   Adapt(assets.NewCreateAssetHandler(db, cfg.S3Uploader).HandleCreateAssetRequest),
+		http.MethodPut:    Adapt(assets.NewUpdateAssetHandler(db, cfg.S3Uploader).HandleUpdateAssetRequest),
+		http.MethodDelete: Adapt(assets.NewDeleteAssetHandler(db).HandleDeleteAssetRequest),
+	})
+	s.mux.Handle("/assets/", methods{
+		http.MethodGet: AdaptPath("/assets/{id}/snapshot", assets.NewReadAssetSnapshotHandler(db).HandleReadAssetSnapshotRequest),
+	})
+	s.mux.Handle("/floorplan", methods{
+		http.MethodGet:  Adapt(floorplan.NewReadFloorPlanHandler(db).HandleReadFloorPlanRequest),
+		http.MethodPost: Adapt(floorplan.NewCreateFloorPlanHandler(db, cfg.S3Uploader).HandleCreateFloorPlanRequest),
+		http.MethodPut:  Adapt(floorplan.NewUpdateFloorPlanHandler(db, cfg.S3Uploader).HandleUpdateFloorPlanRequest),
+	})
+	s.mux.Handle("/floorplan/assets", methods{
+		http.MethodGet: Adapt(floorplan.NewReadFloorPlanAssetsHandler(db).HandleReadFloorPlanAssetsRequest),
+	})
+	s.mux.Handle("/floorplan/heatmap", methods{
+		http.MethodGet: Adapt(floorplan.NewReadFloorPlanHeatmapHandler(db).HandleReadFloorPlanHeatmapRequest),
+	})
+	s.mux.Handle("/floorplan/versions", methods{
+		http.MethodGet: Adapt(floorplan.NewReadFloorPlanVersionsHandler(db).HandleReadFloorPlanVersionsRequest),
+	})
+	s.mux.Handle("/floorplan/convert", methods{
+		http.MethodGet: Adapt(floorplan.NewConvertFloorPlanHandler(db).HandleConvertFloorPlanRequest),
+	})
+	s.mux.Handle("/zones", methods{
+		http.MethodGet:    Adapt(zones.NewReadZoneHandler(db).HandleReadZoneRequest),
+		http.MethodPost:   Adapt(zones.NewCreateZoneHandler(db).HandleCreateZoneRequest),
+		http.MethodPut:    Adapt(zones.NewUpdateZoneHandler(db).HandleUpdateZoneRequest),
+		http.MethodDelete: Adapt(zones.NewDeleteZoneHandler(db).HandleDeleteZoneRequest),
+	})
+	s.mux.Handle("/zones/assets", methods{
+		http.MethodGet: Adapt(zones.NewReadZoneAssetsHandler(db).HandleReadZoneAssetsRequest),
+	})
+	s.mux.Handle("/models", methods{
+		http.MethodGet:    Adapt(models.NewReadModelHandler(db).HandleReadModelRequest),
+		http.MethodPost:   Adapt(models.NewCreateModelHandler(db).HandleCreateModelRequest),
+		http.MethodPut:    Adapt(models.NewUpdateModelHandler(db).HandleUpdateModelRequest),
+		http.MethodDelete: Adapt(models.NewDeleteModelHandler(db).HandleDeleteModelRequest),
+	})
+	s.mux.Handle("/properties", methods{
+		http.MethodGet:    Adapt(properties.NewReadPropertyHandler(db).HandleReadPropertyRequest),
+		http.MethodPost:   Adapt(properties.NewCreatePropertyHandler(db).HandleCreatePropertyRequest),
+		http.MethodPut:    Adapt(properties.NewUpdatePropertyHandler(db).HandleUpdatePropertyRequest),
+		http.MethodDelete: Adapt(properties.NewDeletePropertyHandler(db).HandleDeletePropertyRequest),
+	})
+	s.mux.Handle("/measurements", methods{
+		http.MethodGet:    Adapt(measurements.NewReadMeasurementHandler(db).HandleReadMeasurementRequest),
+		http.MethodPost:   Adapt(measurements.NewCreateMeasurementHandler(db).HandleCreateMeasurementRequest),
+		http.MethodPut:    Adapt(measurements.NewUpdateMeasurementHandler(db).HandleUpdateMeasurementRequest),
+		http.MethodDelete: Adapt(measurements.NewDeleteMeasurementHandler(db).HandleDeleteMeasurementRequest),
+	})
+	s.mux.Handle("/calendars", methods{
+		http.MethodGet:    Adapt(calendars.NewReadCalendarHandler(db).HandleReadCalendarRequest),
+		http.MethodPost:   Adapt(calendars.NewCreateCalendarHandler(db).HandleCreateCalendarRequest),
+		http.MethodPut:    Adapt(calendars.NewUpdateCalendarHandler(db).HandleUpdateCalendarRequest),
+		http.MethodDelete: Adapt(calendars.NewDeleteCalendarHandler(db).HandleDeleteCalendarRequest),
+	})
+	s.mux.Handle("/retention-policies", methods{
+		http.MethodGet:    Adapt(retentionpolicies.NewReadRetentionPolicyHandler(db).HandleReadRetentionPolicyRequest),
+		http.MethodPost:   Adapt(retentionpolicies.NewCreateRetentionPolicyHandler(db).HandleCreateRetentionPolicyRequest),
+		http.MethodDelete: Adapt(retentionpolicies.NewDeleteRetentionPolicyHandler(db).HandleDeleteRetentionPolicyRequest),
+	})
+	s.mux.Handle("/line-protocol-mappings", methods{
+		http.MethodGet:    Adapt(lineprotocolmappings.NewReadLineProtocolMappingHandler(db).HandleReadLineProtocolMappingRequest),
+		http.MethodPost:   Adapt(lineprotocolmappings.NewCreateLineProtocolMappingHandler(db).HandleCreateLineProtocolMappingRequest),
+		http.MethodDelete: Adapt(lineprotocolmappings.NewDeleteLineProtocolMappingHandler(db).HandleDeleteLineProtocolMappingRequest),
+	})
+	s.mux.Handle("/register-maps", methods{
+		http.MethodGet: Adapt(registermaps.NewReadRegisterMapHandler(db).HandleReadRegisterMapRequest),
+	})
+	s.mux.Handle("/metrics", methods{
+		http.MethodGet: Adapt(metrics.NewReadMetricsHandler(db).HandleReadMetricsRequest),
+	})
+	s.mux.Handle("/readings", methods{
+		http.MethodGet:  Adapt(readings.NewReadReadingsHandler(db).HandleReadReadingsRequest),
+		http.MethodPost: Adapt(readings.NewIngestReadingsHandler(db).HandleIngestReadingsRequest),
+	})
+	// Backfilled readings are historical, so they bypass the tap and never
+	// reach live subscribers.
+	s.mux.Handle("/readings/backfill", methods{
+		http.MethodPost: Adapt(readings.NewBackfillReadingsHandler(cfg.DynamoDB).HandleBackfillReadingsRequest),
+	})
+	s.mux.Handle("/readings/compact", methods{
+		http.MethodPost: Adapt(readings.NewCompactReadingsHandler(db).HandleCompactReadingsRequest),
+	})
+	s.mux.Handle("/readings/export", methods{
+		http.MethodGet: Adapt(readings.NewExportReadingsHandler(db, cfg.S3Uploader, cfg.S3Presigner).HandleExportReadingsRequest),
+	})
+	// InfluxDB clients post to /write (1.x) or /api/v2/write (2.x) under
+	// whatever base URL they are given, so both are served as well.
+	write := methods{
+		http.MethodPost: Adapt(readings.NewWriteLineProtocolHandler(db).HandleWriteLineProtocolRequest),
+	}
+	s.mux.Handle("/readings/write", write)
+	s.mux.Handle("/write", write)
+	s.mux.Handle("/api/v2/write", write)
+	s.mux.Handle("/readings/stream", methods{
+		http.MethodGet: http.HandlerFunc(s.handleSSE),
+	})
+	s.mux.Handle("/readings/ws", methods{
+		http.MethodGet: http.HandlerFunc(s.handleWebSocket),
+	})
+
+	if cfg.Cognito != nil {
+		s.mux.Handle("/auth/login", methods{
+			http.MethodPost: Adapt(auth.NewLoginHandler(cfg.Cognito).HandleLoginRequest),
+		})
+		s.mux.Handle("/auth/register", methods{
+			http.MethodPost: Adapt(auth.NewRegisterHandler(cfg.Cognito).HandleRegisterRequest),
+		})
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/stream"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+)
+
+const (
+	HEARTBEAT = "heartbeat"
+	READING   = "reading"
+	SUBSCRIBE = "subscribed"
+	// STALE repeats the last reading of a property that stopped reporting,
+	// flagged quality.STALE.
+	STALE = "stale"
+)
+
+// message is the envelope of every frame sent on a stream.
+type message struct {
+	Type        string         `json:"type"`
+	Reading     *types.Reading `json:"reading,omitempty"`
+	PropertyIDs []string       `json:"propertyIds,omitempty"`
+	Timestamp   int64          `json:"timestamp,omitempty"`
+	// Dropped counts the readings dropped so far because the client fell behind.
+	Dropped *int64 `json:"dropped,omitempty"`
+}
+
+type subscription struct {
+	*stream.Subscription
+	propertyIDs  []string
+	measurements map[string]types.Measurement
+	quality      quality.Filter
+	heartbeat    time.Duration
+	// location adds local times to messages, as tz does on the API.
+	location *time.Location
+	release  func()
+}
+
+// subscribe resolves the propertyIds, assetId or factoryId query parameter
+// to a subscription and starts simulating its properties.
+func (s *Server) subscribe(ctx context.Context, r *http.Request) (*subscription, int, error) {
+	query := r.URL.Query()
+	propertyIDs, assetID, factoryID := splitList(query.Get("propertyIds")), query.Get("assetId"), query.Get("factoryId")
+
+	targets := 0
+	for _, set := range []bool{len(propertyIDs) > 0, assetID != "", factoryID != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, http.StatusBadRequest, errors.New("exactly one of propertyIds, assetId or factoryId is required")
+	}
+
+	buffer := stream.DEFAULTBUFFER
+	if value := query.Get("buffer"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > stream.MAXBUFFER {
+			return nil, http.StatusBadRequest, fmt.Errorf("buffer must be between 1 and %d", stream.MAXBUFFER)
+		}
+		buffer = parsed
+	}
+	heartbeat := s.Heartbeat
+	if value := query.Get("heartbeat"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < time.Second {
+			return nil, http.StatusBadRequest, errors.New("heartbeat must be a duration of at least 1s")
+		}
+		heartbeat = parsed
+	}
+	filter, err := quality.ParseFilter(query.Get("quality"))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var series []catalog.Series
+	if len(propertyIDs) > 0 {
+		for _, id := range propertyIDs {
+			var one []catalog.Series
+			if one, err = s.Catalog.PropertySeries(ctx, id); err != nil {
+				break
+			}
+			series = append(series, one...)
+		}
+	} else {
+		series, err = s.Catalog.Series(ctx, factoryID, assetID, "")
+	}
+	if errors.Is(err, catalog.ErrNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if factoryID == "" && len(series) > 0 {
+		factoryID = series[0].FactoryID
+	}
+	location, err := timezone.Load(ctx, s.Catalog, query.Get("tz"), factoryID)
+	if errors.Is(err, timezone.ErrInvalidTimezone) {
+		return nil, http.StatusBadRequest, err
+	}
+	if errors.Is(err, catalog.ErrNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	measurements := map[string]types.Measurement{}
+	ids := make([]string, 0, len(series))
+	for _, one := range series {
+		if _, seen := measurements[one.Property.PropertyID]; !seen {
+			id := one.Property.PropertyID
+			measurements[id] = one.Measurement
+			ids = append(ids, id)
+		}
+	}
+
+	release, err := s.Live.Acquire(ctx, series)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	sub := s.Hub.Subscribe(ids, buffer)
+	return &subscription{
+		Subscription: sub,
+		propertyIDs:  ids,
+		measurements: measurements,
+		quality:      filter,
+		heartbeat:    heartbeat,
+		location:     location,
+		release: func() {
+			s.Hub.Unsubscribe(sub)
+			release()
+		},
+	}, http.StatusOK, nil
+}
+
+// pump sends the subscription's readings and heartbeats until ctx is done or
+// send fails. Readings are assessed against their measurement's bounds and
+// those outside the quality filter are skipped. On each heartbeat, a
+// property whose last reading has gone stale is reported once with a stale
+// message.
+func (sub *subscription) pump(ctx context.Context, send func(message) error) error {
+	if err := send(message{Type: SUBSCRIBE, PropertyIDs: sub.propertyIDs, Timestamp: time.Now().UnixMilli()}); err != nil {
+		return err
+	}
+
+	last := map[string]types.Reading{}
+	reported := map[string]bool{}
+	heartbeat := time.NewTicker(sub.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-heartbeat.C:
+			for _, id := range sub.propertyIDs {
+				reading, ok := last[id]
+				if !ok || reported[id] || !quality.Stale(reading, sub.measurements[id], now) {
+					continue
+				}
+				reported[id] = true
+				reading.Quality = quality.STALE
+				if !sub.quality.Match(reading) {
+					continue
+				}
+				if err := send(message{Type: STALE, Reading: &reading, Timestamp: now.UnixMilli()}); err != nil {
+					return err
+				}
+			}
+			dropped := sub.Dropped()
+			if err := send(message{Type: HEARTBEAT, Timestamp: now.UnixMilli(), Dropped: &dropped}); err != nil {
+				return err
+			}
+		case <-sub.Ready():
+			for _, reading := range sub.Drain() {
+				reading := quality.Assess(reading, sub.measurements[reading.PropertyID])
+				last[reading.PropertyID] = reading
+				reported[reading.PropertyID] = false
+				if !sub.quality.Match(reading) {
+					continue
+				}
+				if err := send(message{Type: READING, Reading: &reading}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// encode marshals a message, with local times when the subscription asked
+// for a zone.
+func (sub *subscription) encode(m message) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return timezone.Render(data, sub.location)
+}
+
+// handleSSE streams readings as Server-Sent Events named after the message type.
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, status, err := s.subscribe(r.Context(), r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer sub.release()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	_ = sub.pump(r.Context(), func(m message) error {
+		data, err := sub.encode(m)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/stream"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch *params.TableName {
+			case "Property":
+				if params.Key["propertyId"].(*ddbtypes.AttributeValueMemberS).Value != "p1" {
+					return &dynamodb.GetItemOutput{}, nil
+				}
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"propertyId":    &ddbtypes.AttributeValueMemberS{Value: "p1"},
+					"measurementId": &ddbtypes.AttributeValueMemberS{Value: "m1"},
+				}}, nil
+			case "Measurement":
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"measurementId":     &ddbtypes.AttributeValueMemberS{Value: "m1"},
+					"generatorFunction": &ddbtypes.AttributeValueMemberS{Value: "sinewave"},
+					"frequency":         &ddbtypes.AttributeValueMemberN{Value: "20"},
+				}}, nil
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(ctx, Config{DynamoDB: mockDDBClient, Heartbeat: time.Second})
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.CloseClientConnections()
+		ts.Close()
+		cancel()
+	})
+	return s, ts
+}
+
+func TestStream_InvalidParameters(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	cases := map[string]int{
+		"":                                http.StatusBadRequest,
+		"?propertyIds=p1&assetId=a1":      http.StatusBadRequest,
+		"?propertyIds=p1&buffer=0":        http.StatusBadRequest,
+		"?propertyIds=p1&heartbeat=10ms":  http.StatusBadRequest,
+		"?propertyIds=p1&quality=fine":    http.StatusBadRequest,
+		"?propertyIds=missing":            http.StatusNotFound,
+		"?propertyIds=p1&tz=Mars/Olympus": http.StatusBadRequest,
+		"?propertyIds=p1&tz=factory":      http.StatusBadRequest,
+	}
+	for query, status := range cases {
+		response, err := http.Get(ts.URL + "/readings/stream" + query)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != status {
+			t.Errorf("Expected status code %d for %q, got %d", status, query, response.StatusCode)
+		}
+	}
+}
+
+func TestStream_SSE(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	response, err := http.Get(ts.URL + "/readings/stream?propertyIds=p1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", response.Header.Get("Content-Type"))
+	}
+
+	events := map[string]int{}
+	reader := bufio.NewReader(response.Body)
+	deadline := time.Now().Add(5 * time.Second)
+	for (events[READING] < 3 || events[HEARTBEAT] == 0) && time.Now().Before(deadline) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "event: "); ok {
+			events[name]++
+		}
+		if strings.HasPrefix(line, "data: ") && strings.Contains(line, `"type":"subscribed"`) {
+			// Readings written through the API reach the stream too.
+			s.Hub.Publish(types.Reading{PropertyID: "p1", Timestamp: 1})
+		}
+	}
+
+	if events[SUBSCRIBE] != 1 || events[READING] < 3 || events[HEARTBEAT] == 0 {
+		t.Errorf("Expected a subscription, live readings and a heartbeat, got %v", events)
+	}
+}
+
+func TestSubscriptionPump_Quality(t *testing.T) {
+	hub := stream.NewHub()
+	frequency, upper := 1000.0, 10.0
+	sub := &subscription{
+		Subscription: hub.Subscribe([]string{"p1"}, 10),
+		propertyIDs:  []string{"p1"},
+		measurements: map[string]types.Measurement{"p1": {Frequency: &frequency, UpperBound: &upper}},
+		quality:      quality.Filter{quality.UNCERTAIN},
+		heartbeat:    20 * time.Millisecond,
+	}
+	hub.Publish(
+		types.Reading{PropertyID: "p1", Timestamp: 1, Value: 5},
+		types.Reading{PropertyID: "p1", Timestamp: 2, Value: 50},
+		types.Reading{PropertyID: "p1", Timestamp: 3, Value: 6},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var messages []message
+	sub.pump(ctx, func(m message) error {
+		messages = append(messages, m)
+		if m.Type == STALE {
+			cancel()
+		}
+		return nil
+	})
+
+	var readings []types.Reading
+	for _, m := range messages {
+		if m.Reading != nil {
+			readings = append(readings, *m.Reading)
+		}
+	}
+	if len(readings) != 2 || readings[0].Quality != quality.OUTOFBOUNDS || readings[1].Timestamp != 3 || readings[1].Quality != quality.STALE {
+		t.Errorf("Expected the out of bounds reading then the last one flagged stale, got %+v", readings)
+	}
+}
+
+func TestSubscriptionEncode_Timezone(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Kolkata")
+	sub := &subscription{location: location}
+	data, err := sub.encode(message{Type: READING, Reading: &types.Reading{PropertyID: "p1", Timestamp: 1704067200000}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(string(data), `"timestampLocal":"2024-01-01T05:30:00.000+05:30"`) {
+		t.Errorf("Expected the reading's local time, got %s", data)
+	}
+}
+
+func TestStream_WebSocket(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, _ = io.WriteString(conn, "GET /readings/ws?propertyIds=p1&buffer=4 HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected a WebSocket handshake, got %d %v", response.StatusCode, response.Header)
+	}
+
+	readings := 0
+	for readings < 3 {
+		var header [2]byte
+		if _, err = io.ReadFull(reader, header[:]); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if header[0] != 0x80|opText || header[1]&0x80 != 0 {
+			t.Fatalf("Expected an unmasked text frame, got %x", header)
+		}
+		length := int(header[1])
+		if length == 126 {
+			var extended [2]byte
+			_, _ = io.ReadFull(reader, extended[:])
+			length = int(binary.BigEndian.Uint16(extended[:]))
+		}
+		payload := make([]byte, length)
+		_, _ = io.ReadFull(reader, payload)
+
+		var m message
+		if err = json.Unmarshal(payload, &m); err != nil {
+			t.Fatalf("Expected a JSON message, got %s", payload)
+		}
+		if m.Type == READING {
+			readings++
+		}
+	}
+
+	// A masked close frame from the client is echoed before the server hangs up.
+	_, _ = conn.Write([]byte{0x80 | opClose, 0x82, 1, 2, 3, 4, 0x03 ^ 1, 0xe8 ^ 2})
+	for {
+		var header [2]byte
+		if _, err = io.ReadFull(reader, header[:]); err != nil {
+			t.Fatalf("Expected a close frame, got %v", err)
+		}
+		payload := make([]byte, header[1]&0x7f)
+		_, _ = io.ReadFull(reader, payload)
+		if header[0] == 0x80|opClose {
+			break
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"wdd/api/internal/calendar"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/timezone"
+	"wdd/api/internal/types"
+)
+
+// Simulator builds the generator of a series, following its factory's shift
+// calendar, and drives generators in real time for the live outputs.
+type Simulator struct {
+	Catalog *catalog.Catalog
+	Seed    int64
+	// IgnoreCalendar generates every series around the clock at full amplitude.
+	IgnoreCalendar bool
+
+	mu        sync.Mutex
+	schedules map[string]*calendar.Schedule
+}
+
+// Emit receives each reading generated by Run.
+type Emit func(series catalog.Series, reading types.Reading)
+
+func New(c *catalog.Catalog, seed int64) *Simulator {
+	return &Simulator{
+		Catalog:   c,
+		Seed:      seed,
+		schedules: map[string]*calendar.Schedule{},
+	}
+}
+
+// Generator returns the generator of a series. It returns
+// generators.ErrUnsupportedGenerator for series that cannot be simulated.
+func (s *Simulator) Generator(ctx context.Context, series catalog.Series) (generators.Generator, error) {
+	generator, err := generators.New(series.Measurement, series.Property.PropertyID, s.Seed)
+	if err != nil {
+		return nil, err
+	}
+	if s.IgnoreCalendar || series.FactoryID == "" {
+		return generator, nil
+	}
+
+	schedule, err := s.schedule(ctx, series.FactoryID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule != nil {
+		generator = generators.WithSchedule(generator, series.Measurement, schedule)
+	}
+	return generator, nil
+}
+
+// Run generates readings for every series at its measurement's frequency
+// until ctx is done. Timestamps are aligned to the sampling interval so live
+// readings line up with backfilled ones. Series without a live generator are
+// skipped.
+func (s *Simulator) Run(ctx context.Context, series []catalog.Series, emit Emit) error {
+	var wg sync.WaitGroup
+	for _, one := range series {
+		generator, err := s.Generator(ctx, one)
+		if errors.Is(err, generators.ErrUnsupportedGenerator) {
+			continue
+		}
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		wg.Add(1)
+		go func(one catalog.Series, generator generators.Generator) {
+			defer wg.Done()
+			tick(ctx, one, generator, generators.Interval(one.Measurement), emit)
+		}(one, generator)
+	}
+	wg.Wait()
+	return nil
+}
+
+func tick(ctx context.Context, series catalog.Series, generator generators.Generator, interval time.Duration, emit Emit) {
+	next := Align(time.Now(), interval).Add(interval)
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		emit(series, types.Reading{
+			PropertyID: series.Property.PropertyID,
+			Timestamp:  next.UnixMilli(),
+			Value:      generator.Value(next),
+		})
+
+		// Skip ticks that were missed rather than bursting to catch up.
+		next = Align(time.Now(), interval).Add(interval)
+		timer.Reset(time.Until(next))
+	}
+}
+
+func (s *Simulator) schedule(ctx context.Context, factoryID string) (*calendar.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if schedule, ok := s.schedules[factoryID]; ok {
+		return schedule, nil
+	}
+
+	cal, err := s.Catalog.Calendar(ctx, factoryID)
+	if errors.Is(err, catalog.ErrNotFound) {
+		s.schedules[factoryID] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Shifts without a zone of their own run in the factory's.
+	if err := timezone.LocateCalendar(ctx, s.Catalog, &cal); err != nil {
+		return nil, err
+	}
+
+	schedule, err := calendar.Compile(cal)
+	if err != nil {
+		return nil, err
+	}
+	s.schedules[factoryID] = schedule
+	return schedule, nil
+}
+
+// Align rounds t down to a multiple of interval since the Unix epoch, the
+// instants both live and backfilled readings are generated at.
+func Align(t time.Time, interval time.Duration) time.Time {
+	ms, width := t.UnixMilli(), interval.Milliseconds()
+	return time.UnixMilli(ms - ms%width)
+}
@@ -0,0 +1,247 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+	"wdd/api/internal/zone"
+)
+
+// CONCURRENCY bounds the parallel model and latest reading queries.
+const CONCURRENCY = 16
+
+// Property is one property of an asset's model with its measurement and
+// latest reading, whose quality is assessed when the snapshot is taken.
+type Property struct {
+	types.Property
+	Measurement *types.Measurement `json:"measurement,omitempty"`
+	Latest      *types.Reading     `json:"latest,omitempty"`
+}
+
+type Asset struct {
+	types.Asset
+	Model      *types.Model `json:"model,omitempty"`
+	Properties []Property   `json:"properties"`
+}
+
+type Factory struct {
+	types.Factory
+	Assets []Asset `json:"assets"`
+}
+
+type Snapshotter struct {
+	Catalog *catalog.Catalog
+	Store   *timeseries.Store
+	Now     func() time.Time
+}
+
+func New(db types.DynamoDBClient) *Snapshotter {
+	return &Snapshotter{
+		Catalog: catalog.New(db),
+		Store:   timeseries.NewStore(db),
+		Now:     time.Now,
+	}
+}
+
+// Asset resolves one asset with its model, properties, measurements and
+// latest readings.
+func (s Snapshotter) Asset(ctx context.Context, assetID string) (*Asset, error) {
+	asset, err := s.Catalog.Asset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.assets(ctx, []types.Asset{asset})
+	if err != nil {
+		return nil, err
+	}
+	return &snapshots[0], nil
+}
+
+// Factory resolves a factory and every one of its assets.
+func (s Snapshotter) Factory(ctx context.Context, factoryID string) (*Factory, error) {
+	factory, err := s.Catalog.Factory(ctx, factoryID)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := s.Catalog.FactoryAssets(ctx, factoryID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.assets(ctx, assets)
+	if err != nil {
+		return nil, err
+	}
+	return &Factory{Factory: factory, Assets: snapshots}, nil
+}
+
+// assets resolves the assets level by level so that each distinct model,
+// property and measurement is read once however many assets share it:
+// models and latest readings with parallel queries, properties and
+// measurements with batched reads. References to deleted models,
+// properties or measurements are left out rather than failing the snapshot.
+func (s Snapshotter) assets(ctx context.Context, assets []types.Asset) ([]Asset, error) {
+	if err := calibration.Locate(ctx, s.Catalog, assets); err != nil {
+		return nil, err
+	}
+	if err := zone.Locate(ctx, s.Catalog, assets); err != nil {
+		return nil, err
+	}
+
+	var modelIDs []string
+	for _, a := range assets {
+		if a.ModelID != nil && *a.ModelID != "" {
+			modelIDs = append(modelIDs, *a.ModelID)
+		}
+	}
+	modelIDs = unique(modelIDs)
+	models := make([]*types.Model, len(modelIDs))
+	err := parallel(ctx, len(modelIDs), func(ctx context.Context, i int) error {
+		model, err := s.Catalog.Model(ctx, modelIDs[i])
+		if errors.Is(err, catalog.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		models[i] = &model
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modelsByID := map[string]*types.Model{}
+	var propertyIDs []string
+	for i, model := range models {
+		if model == nil {
+			continue
+		}
+		modelsByID[modelIDs[i]] = model
+		if model.Properties != nil {
+			propertyIDs = append(propertyIDs, *model.Properties...)
+		}
+	}
+	propertyIDs = unique(propertyIDs)
+
+	properties, err := s.Catalog.Properties(ctx, propertyIDs)
+	if err != nil {
+		return nil, err
+	}
+	measurementIDs := make([]string, 0, len(properties))
+	for _, p := range properties {
+		measurementIDs = append(measurementIDs, p.MeasurementID)
+	}
+	measurements, err := s.Catalog.Measurements(ctx, measurementIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make([]*types.Reading, len(propertyIDs))
+	err = parallel(ctx, len(propertyIDs), func(ctx context.Context, i int) error {
+		if _, ok := properties[propertyIDs[i]]; !ok {
+			return nil
+		}
+		reading, err := s.Store.Latest(ctx, propertyIDs[i])
+		latest[i] = reading
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	latestByID := make(map[string]*types.Reading, len(propertyIDs))
+	for i, reading := range latest {
+		if reading != nil {
+			current := quality.Current(*reading, measurements[properties[propertyIDs[i]].MeasurementID], now)
+			latestByID[propertyIDs[i]] = &current
+		}
+	}
+
+	snapshots := make([]Asset, len(assets))
+	for i, a := range assets {
+		snapshots[i] = Asset{Asset: a, Properties: []Property{}}
+		if a.ModelID == nil {
+			continue
+		}
+		model := modelsByID[*a.ModelID]
+		if model == nil {
+			continue
+		}
+		snapshots[i].Model = model
+		if model.Properties == nil {
+			continue
+		}
+		for _, id := range *model.Properties {
+			p, ok := properties[id]
+			if !ok {
+				continue
+			}
+			property := Property{Property: p, Latest: latestByID[id]}
+			if m, ok := measurements[p.MeasurementID]; ok {
+				property.Measurement = &m
+			}
+			snapshots[i].Properties = append(snapshots[i].Properties, property)
+		}
+	}
+	return snapshots, nil
+}
+
+// parallel runs do for every index in [0, n) with CONCURRENCY workers,
+// stopping at the first error.
+func parallel(ctx context.Context, n int, do func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < CONCURRENCY && i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := do(ctx, j); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/quality"
+	"wdd/api/internal/timeseries"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var now = time.UnixMilli(1700000000000)
+
+func s(v string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: v}
+}
+
+func n(v string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: v}
+}
+
+type calls struct {
+	mu      sync.Mutex
+	byTable map[string]int
+}
+
+func (c *calls) add(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byTable[table]++
+}
+
+// mockClient has factory f1 with assets a1 and a2 of model m1, and a3 of a
+// deleted model. Model m1 lists p1, p2 and the deleted p9; only p1 has a
+// reading, and the first property batch leaves p2 unprocessed.
+func mockClient(c *calls) *mocks.DynamoDBClient {
+	var retried bool
+	return &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			c.add(aws.ToString(params.TableName))
+			switch aws.ToString(params.TableName) {
+			case "Factory":
+				if params.Key["factoryId"].(*types.AttributeValueMemberS).Value == "f1" {
+					return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"factoryId": s("f1")}}, nil
+				}
+			case "Asset":
+				if params.Key["assetId"].(*types.AttributeValueMemberS).Value == "a1" {
+					return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{"assetId": s("a1"), "modelId": s("m1")}}, nil
+				}
+			}
+			return &dynamodb.GetItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			c.add(aws.ToString(params.TableName))
+			switch aws.ToString(params.TableName) {
+			case "Asset":
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+					{"assetId": s("a1"), "factoryId": s("f1"), "modelId": s("m1")},
+					{"assetId": s("a2"), "factoryId": s("f1"), "modelId": s("m1")},
+					{"assetId": s("a3"), "factoryId": s("f1"), "modelId": s("gone")},
+				}}, nil
+			case "Model":
+				if params.ExpressionAttributeValues[":modelId"].(*types.AttributeValueMemberS).Value != "m1" {
+					return &dynamodb.QueryOutput{}, nil
+				}
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"modelId":    s("m1"),
+					"properties": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("p1"), s("p2"), s("p9")}},
+				}}}, nil
+			case timeseries.TABLENAME:
+				if params.ExpressionAttributeValues[":propertyId"].(*types.AttributeValueMemberS).Value != "p1" {
+					return &dynamodb.QueryOutput{}, nil
+				}
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+					"propertyId": s("p1"), "timestamp": n("1699999999000"), "value": n("42"),
+				}}}, nil
+			}
+			return &dynamodb.QueryOutput{}, nil
+		},
+		BatchGetItemFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{}}
+			for table, request := range params.RequestItems {
+				c.add(table)
+				for _, key := range request.Keys {
+					switch table {
+					case "Property":
+						id := key["propertyId"].(*types.AttributeValueMemberS).Value
+						if id == "p2" && !retried {
+							retried = true
+							output.UnprocessedKeys = map[string]types.KeysAndAttributes{table: {Keys: []map[string]types.AttributeValue{key}}}
+							continue
+						}
+						if id != "p9" {
+							output.Responses[table] = append(output.Responses[table], map[string]types.AttributeValue{"propertyId": s(id), "measurementId": s("m-" + id)})
+						}
+					case "Measurement":
+						id := key["measurementId"].(*types.AttributeValueMemberS).Value
+						output.Responses[table] = append(output.Responses[table], map[string]types.AttributeValue{"measurementId": s(id), "upperBound": n("40")})
+					}
+				}
+			}
+			return output, nil
+		},
+	}
+}
+
+func newSnapshotter(c *calls) *Snapshotter {
+	snapshotter := New(mockClient(c))
+	snapshotter.Now = func() time.Time { return now }
+	return snapshotter
+}
+
+func TestFactory(t *testing.T) {
+	c := &calls{byTable: map[string]int{}}
+	factory, err := newSnapshotter(c).Factory(context.Background(), "f1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if factory.FactoryID != "f1" || len(factory.Assets) != 3 {
+		t.Fatalf("Expected factory f1 with 3 assets, got %+v", factory)
+	}
+	for _, asset := range factory.Assets[:2] {
+		if asset.Model == nil || len(asset.Properties) != 2 {
+			t.Fatalf("Expected %s to have model m1 and 2 properties, got %+v", asset.AssetID, asset)
+		}
+		p1, p2 := asset.Properties[0], asset.Properties[1]
+		if p1.PropertyID != "p1" || p1.Measurement == nil || p1.Measurement.MeasurementID != "m-p1" {
+			t.Errorf("Expected p1 with its measurement, got %+v", p1)
+		}
+		if p1.Latest == nil || p1.Latest.Value != 42 || p1.Latest.Quality != quality.OUTOFBOUNDS {
+			t.Errorf("Expected the latest p1 reading flagged out of bounds, got %+v", p1.Latest)
+		}
+		if p2.PropertyID != "p2" || p2.Latest != nil {
+			t.Errorf("Expected p2 without a reading, got %+v", p2)
+		}
+	}
+	if a3 := factory.Assets[2]; a3.Model != nil || len(a3.Properties) != 0 {
+		t.Errorf("Expected a3 without a model, got %+v", a3)
+	}
+
+	// Shared models, properties and measurements are read once.
+	expected := map[string]int{"Factory": 1, "Asset": 1, "Model": 2, "Property": 2, "Measurement": 1, timeseries.TABLENAME: 2}
+	for table, count := range expected {
+		if c.byTable[table] != count {
+			t.Errorf("Expected %d %s reads, got %d", count, table, c.byTable[table])
+		}
+	}
+}
+
+func TestAsset(t *testing.T) {
+	c := &calls{byTable: map[string]int{}}
+	asset, err := newSnapshotter(c).Asset(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if asset.AssetID != "a1" || len(asset.Properties) != 2 {
+		t.Errorf("Expected a1 with 2 properties, got %+v", asset)
+	}
+
+	if _, err = newSnapshotter(c).Asset(context.Background(), "missing"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err = newSnapshotter(c).Factory(context.Background(), "missing"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFactory_QueryError(t *testing.T) {
+	c := &calls{byTable: map[string]int{}}
+	client := mockClient(c)
+	query := client.QueryFunc
+	client.QueryFunc = func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+		if aws.ToString(params.TableName) == timeseries.TABLENAME {
+			return nil, errors.New("mock query error")
+		}
+		return query(ctx, params, optFns...)
+	}
+
+	if _, err := New(client).Factory(context.Background(), "f1"); err == nil {
+		t.Errorf("Expected the query error")
+	}
+}
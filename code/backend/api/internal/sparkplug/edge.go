@@ -0,0 +1,553 @@
+package sparkplug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mqtt"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/telemetry"
+	"wdd/api/internal/types"
+)
+
+const (
+	NAMESPACE       = "spBv1.0"
+	DEFAULTEDGENODE = "wdd"
+	ASSETSASDEVICES = "devices"
+	ASSETSASNODES   = "nodes"
+	BDSEQ           = "bdSeq"
+	REBIRTH         = "Node Control/Rebirth"
+	ATTRIBUTEPREFIX = "Attributes/"
+	SHUTDOWNTIMEOUT = 5 * time.Second
+)
+
+// Sparkplug B message types.
+const (
+	NBIRTH = "NBIRTH"
+	NDATA  = "NDATA"
+	NDEATH = "NDEATH"
+	NCMD   = "NCMD"
+	DBIRTH = "DBIRTH"
+	DDATA  = "DDATA"
+	DDEATH = "DDEATH"
+)
+
+var ErrInvalidOptions = errors.New("invalid sparkplug options")
+
+// Client is the part of an MQTT client an edge node needs.
+type Client interface {
+	Publish(ctx context.Context, m mqtt.Message) error
+	Subscribe(ctx context.Context, filter string, qos byte, fn func(mqtt.Message)) error
+	Disconnect() error
+}
+
+type Dialer func(ctx context.Context, opts mqtt.Options) (Client, error)
+
+type Options struct {
+	// GroupID overrides the group, which is otherwise the factory.
+	GroupID string
+	// EdgeNodeID names the single edge node when assets are devices.
+	EdgeNodeID string
+	// AssetsAs publishes assets as devices of one edge node ("devices") or
+	// as edge nodes of their own ("nodes").
+	AssetsAs string
+	// IDs names groups, nodes, devices and metrics after names ("names") or
+	// after IDs ("ids"), as for the plain MQTT output.
+	IDs string
+}
+
+// Publisher publishes simulated readings as a Sparkplug B edge: factories
+// are groups, assets are devices or edge nodes, properties are metrics.
+type Publisher struct {
+	Catalog   *catalog.Catalog
+	Simulator *simulator.Simulator
+	Dial      Dialer
+	// Connection holds the broker address and credentials; every edge node
+	// connects with its own client ID and death certificate.
+	Connection mqtt.Options
+	Options    Options
+
+	mu    sync.Mutex
+	bdSeq uint64
+}
+
+// Session is a set of born edge nodes publishing a fixed list of series.
+// Assets of the same model share property IDs, so a property has a metric on
+// every device or node of those assets.
+type Session struct {
+	nodes   []*node
+	metrics map[string][]*metric
+}
+
+type node struct {
+	group, id string
+	client    Client
+	bdSeq     uint64
+	metrics   []*metric
+	devices   []*device
+	aliases   uint64
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+type device struct {
+	id      string
+	metrics []*metric
+}
+
+type metric struct {
+	node   *node
+	device *device
+	def    Metric
+}
+
+func New(c *catalog.Catalog, sim *simulator.Simulator, conn mqtt.Options, opts Options) (*Publisher, error) {
+	if opts.EdgeNodeID == "" {
+		opts.EdgeNodeID = DEFAULTEDGENODE
+	}
+	if opts.AssetsAs == "" {
+		opts.AssetsAs = ASSETSASDEVICES
+	}
+	if opts.IDs == "" {
+		opts.IDs = telemetry.TOPICSNAMES
+	}
+	if opts.AssetsAs != ASSETSASDEVICES && opts.AssetsAs != ASSETSASNODES {
+		return nil, fmt.Errorf("%w: assets must be published as %q or %q", ErrInvalidOptions, ASSETSASDEVICES, ASSETSASNODES)
+	}
+	if opts.IDs != telemetry.TOPICSNAMES && opts.IDs != telemetry.TOPICSIDS {
+		return nil, fmt.Errorf("%w: ids must be %q or %q", ErrInvalidOptions, telemetry.TOPICSNAMES, telemetry.TOPICSIDS)
+	}
+	for _, id := range []string{opts.GroupID, opts.EdgeNodeID} {
+		if telemetry.Segment(id) != id {
+			return nil, fmt.Errorf("%w: %q is not a valid sparkplug id", ErrInvalidOptions, id)
+		}
+	}
+	return &Publisher{
+		Catalog:    c,
+		Simulator:  sim,
+		Dial:       func(ctx context.Context, opts mqtt.Options) (Client, error) { return mqtt.Dial(ctx, opts) },
+		Connection: conn,
+		Options:    opts,
+	}, nil
+}
+
+// Run births the edge for exactly one of a factory, asset or property,
+// publishes every simulated reading until ctx is done, then publishes the
+// deaths.
+func (p *Publisher) Run(ctx context.Context, factoryID, assetID, propertyID string) error {
+	series, err := p.Catalog.Series(ctx, factoryID, assetID, propertyID)
+	if err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		return catalog.ErrNotFound
+	}
+
+	session, err := p.Start(ctx, series)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWNTIMEOUT)
+		defer cancel()
+		session.Close(closeCtx)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Readings are per property, and every metric of a property publishes
+	// each, so a property is simulated once however many assets share it.
+	simulated := make([]catalog.Series, 0, len(series))
+	seen := make(map[string]bool, len(series))
+	for _, s := range series {
+		if !seen[s.Property.PropertyID] {
+			seen[s.Property.PropertyID] = true
+			simulated = append(simulated, s)
+		}
+	}
+
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	err = p.Simulator.Run(ctx, simulated, func(s catalog.Series, reading types.Reading) {
+		if err := session.Publish(ctx, reading); err != nil && ctx.Err() == nil {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// Start connects an edge node per node ID, with NDEATH as its will, and
+// publishes NBIRTH and a DBIRTH per device.
+func (p *Publisher) Start(ctx context.Context, series []catalog.Series) (*Session, error) {
+	session, err := p.plan(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range session.nodes {
+		if err = p.connect(ctx, n); err != nil {
+			session.Close(ctx)
+			return nil, err
+		}
+		if err = n.birth(ctx); err != nil {
+			session.Close(ctx)
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func (p *Publisher) connect(ctx context.Context, n *node) error {
+	p.mu.Lock()
+	n.bdSeq = p.bdSeq
+	p.bdSeq = (p.bdSeq + 1) % 256
+	p.mu.Unlock()
+
+	death, err := n.death()
+	if err != nil {
+		return err
+	}
+
+	opts := p.Connection
+	opts.ClientID = strings.Trim(opts.ClientID+"-"+n.id, "-")
+	opts.Will = &death
+	client, err := p.Dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	// Host applications ask for a rebirth when they have lost track of the
+	// node's metrics; births take the node lock, so they cannot run on the
+	// client's read loop.
+	err = client.Subscribe(ctx, n.topic(NCMD, ""), 1, func(m mqtt.Message) {
+		if rebirthRequested(m.Payload) {
+			go n.birth(context.Background())
+		}
+	})
+	if err != nil {
+		client.Disconnect()
+		return err
+	}
+
+	n.mu.Lock()
+	n.client = client
+	n.mu.Unlock()
+	return nil
+}
+
+// plan groups the series into edge nodes and devices and assigns every
+// property of every asset a metric alias.
+func (p *Publisher) plan(ctx context.Context, series []catalog.Series) (*Session, error) {
+	session := &Session{metrics: map[string][]*metric{}}
+	nodes := map[string]*node{}
+	devices := map[string]*device{}
+	assets := map[string]bool{}
+	planned := map[string]bool{}
+
+	for _, s := range series {
+		group, err := p.groupID(ctx, s.FactoryID)
+		if err != nil {
+			return nil, err
+		}
+		asset := p.name(s.AssetName, s.AssetID)
+
+		nodeID := p.Options.EdgeNodeID
+		if p.Options.AssetsAs == ASSETSASNODES {
+			nodeID = asset
+		}
+		n, ok := nodes[group+"/"+nodeID]
+		if !ok {
+			n = &node{group: group, id: nodeID}
+			nodes[group+"/"+nodeID] = n
+			session.nodes = append(session.nodes, n)
+		}
+
+		var d *device
+		if p.Options.AssetsAs == ASSETSASDEVICES {
+			if d, ok = devices[n.group+"/"+asset]; !ok {
+				d = &device{id: asset}
+				devices[n.group+"/"+asset] = d
+				n.devices = append(n.devices, d)
+			}
+		}
+
+		if s.AssetID != "" && !assets[s.AssetID] {
+			assets[s.AssetID] = true
+			if err = p.addAttributes(ctx, n, d, s.AssetID); err != nil {
+				return nil, err
+			}
+		}
+
+		key := s.AssetID + "/" + s.Property.PropertyID
+		if planned[key] {
+			continue
+		}
+		planned[key] = true
+		// Metric names may contain spaces and "/" folders, so they are not sanitized.
+		name := s.Property.PropertyID
+		if p.Options.IDs == telemetry.TOPICSNAMES && s.Property.Name != "" {
+			name = s.Property.Name
+		}
+		m := &metric{node: n, device: d, def: propertyMetric(s, name)}
+		n.add(d, m)
+		session.metrics[s.Property.PropertyID] = append(session.metrics[s.Property.PropertyID], m)
+	}
+	return session, nil
+}
+
+// addAttributes adds the asset's model attributes as string metrics, valued
+// from the asset.
+func (p *Publisher) addAttributes(ctx context.Context, n *node, d *device, assetID string) error {
+	asset, err := p.Catalog.Asset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset.ModelID == nil {
+		return nil
+	}
+	model, err := p.Catalog.Model(ctx, *asset.ModelID)
+	if err != nil {
+		return err
+	}
+	if model.Attributes == nil {
+		return nil
+	}
+
+	names := append([]string(nil), *model.Attributes...)
+	sort.Strings(names)
+	for _, name := range names {
+		def := Metric{Name: ATTRIBUTEPREFIX + name, DataType: STRING}
+		if attr, ok := asset.Attributes[name]; ok {
+			def.Value = attr.Value
+			if attr.Unit != "" {
+				def.Properties = []Property{{Key: "engUnit", DataType: STRING, Value: attr.Unit}}
+			}
+		}
+		n.add(d, &metric{node: n, device: d, def: def})
+	}
+	return nil
+}
+
+func (p *Publisher) groupID(ctx context.Context, factoryID string) (string, error) {
+	if p.Options.GroupID != "" {
+		return p.Options.GroupID, nil
+	}
+	if factoryID == "" {
+		return telemetry.UNASSIGNED, nil
+	}
+	if p.Options.IDs == telemetry.TOPICSIDS {
+		return telemetry.Segment(factoryID), nil
+	}
+
+	factory, err := p.Catalog.Factory(ctx, factoryID)
+	if err != nil {
+		return "", err
+	}
+	var name string
+	if factory.Name != nil {
+		name = *factory.Name
+	}
+	return p.name(name, factoryID), nil
+}
+
+func (p *Publisher) name(name, id string) string {
+	if p.Options.IDs == telemetry.TOPICSNAMES && name != "" {
+		return telemetry.Segment(name)
+	}
+	if id == "" {
+		return telemetry.UNASSIGNED
+	}
+	return telemetry.Segment(id)
+}
+
+func propertyMetric(s catalog.Series, name string) Metric {
+	def := Metric{
+		Name:       name,
+		DataType:   DOUBLE,
+		Properties: []Property{{Key: "engUnit", DataType: STRING, Value: s.Property.Unit}},
+	}
+	if s.Measurement.LowerBound != nil {
+		def.Properties = append(def.Properties, Property{Key: "engLow", DataType: DOUBLE, Value: *s.Measurement.LowerBound})
+	}
+	if s.Measurement.UpperBound != nil {
+		def.Properties = append(def.Properties, Property{Key: "engHigh", DataType: DOUBLE, Value: *s.Measurement.UpperBound})
+	}
+	if s.Property.Value != nil {
+		def.Value = *s.Property.Value
+	}
+	return def
+}
+
+// Publish sends a reading as NDATA or DDATA, addressed by alias, to every
+// device or node with the property.
+func (s *Session) Publish(ctx context.Context, reading types.Reading) error {
+	metrics, ok := s.metrics[reading.PropertyID]
+	if !ok {
+		return fmt.Errorf("property %s: %w", reading.PropertyID, catalog.ErrNotFound)
+	}
+	for _, m := range metrics {
+		if err := m.node.data(ctx, m, reading); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close publishes DDEATH for every device and NDEATH for every node, then
+// disconnects, so the brokers discard the wills.
+func (s *Session) Close(ctx context.Context) error {
+	var errs []error
+	for _, n := range s.nodes {
+		errs = append(errs, n.close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// add assigns the metric the next alias; aliases are unique per edge node,
+// across its devices.
+func (n *node) add(d *device, m *metric) {
+	n.aliases++
+	m.def.Alias = n.aliases
+	if d != nil {
+		d.metrics = append(d.metrics, m)
+	} else {
+		n.metrics = append(n.metrics, m)
+	}
+}
+
+func (n *node) topic(kind, deviceID string) string {
+	topic := strings.Join([]string{NAMESPACE, n.group, kind, n.id}, "/")
+	if deviceID != "" {
+		topic += "/" + deviceID
+	}
+	return topic
+}
+
+// birth publishes NBIRTH and every DBIRTH, restarting the sequence at zero.
+func (n *node) birth(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client == nil {
+		return mqtt.ErrClosed
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	metrics := []Metric{
+		{Name: BDSEQ, Timestamp: now, DataType: INT64, Value: int64(n.bdSeq)},
+		{Name: REBIRTH, Timestamp: now, DataType: BOOLEAN, Value: false},
+	}
+	n.seq = 0
+	if err := n.publish(ctx, NBIRTH, "", now, append(metrics, definitions(n.metrics, now)...)); err != nil {
+		return err
+	}
+	for _, d := range n.devices {
+		if err := n.publish(ctx, DBIRTH, d.id, now, definitions(d.metrics, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) data(ctx context.Context, m *metric, reading types.Reading) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client == nil {
+		return mqtt.ErrClosed
+	}
+
+	m.def.Value = reading.Value
+	ts := uint64(reading.Timestamp)
+	value := Metric{Alias: m.def.Alias, Timestamp: ts, DataType: DOUBLE, Value: reading.Value}
+	if m.device != nil {
+		return n.publish(ctx, DDATA, m.device.id, ts, []Metric{value})
+	}
+	return n.publish(ctx, NDATA, "", ts, []Metric{value})
+}
+
+func (n *node) close(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.client == nil {
+		return nil
+	}
+	client := n.client
+	n.client = nil
+
+	now := uint64(time.Now().UnixMilli())
+	var errs []error
+	for _, d := range n.devices {
+		errs = append(errs, n.publishWith(ctx, client, DDEATH, d.id, now, nil))
+	}
+	death, err := n.death()
+	if err == nil {
+		err = client.Publish(ctx, death)
+	}
+	errs = append(errs, err, client.Disconnect())
+	return errors.Join(errs...)
+}
+
+// death is the NDEATH message, which carries the connection's bdSeq and no
+// sequence number.
+func (n *node) death() (mqtt.Message, error) {
+	payload, err := Payload{
+		Timestamp: uint64(time.Now().UnixMilli()),
+		Metrics:   []Metric{{Name: BDSEQ, DataType: INT64, Value: int64(n.bdSeq)}},
+	}.Marshal()
+	if err != nil {
+		return mqtt.Message{}, err
+	}
+	return mqtt.Message{Topic: n.topic(NDEATH, ""), Payload: payload, QoS: 1}, nil
+}
+
+// publish must be called with n.mu held.
+func (n *node) publish(ctx context.Context, kind, deviceID string, timestamp uint64, metrics []Metric) error {
+	return n.publishWith(ctx, n.client, kind, deviceID, timestamp, metrics)
+}
+
+func (n *node) publishWith(ctx context.Context, client Client, kind, deviceID string, timestamp uint64, metrics []Metric) error {
+	seq := n.seq
+	n.seq = (n.seq + 1) % 256
+
+	payload, err := Payload{Timestamp: timestamp, Metrics: metrics, Seq: &seq}.Marshal()
+	if err != nil {
+		return err
+	}
+	return client.Publish(ctx, mqtt.Message{Topic: n.topic(kind, deviceID), Payload: payload})
+}
+
+func definitions(metrics []*metric, timestamp uint64) []Metric {
+	defs := make([]Metric, len(metrics))
+	for i, m := range metrics {
+		defs[i] = m.def
+		defs[i].Timestamp = timestamp
+	}
+	return defs
+}
+
+func rebirthRequested(data []byte) bool {
+	payload, err := Unmarshal(data)
+	if err != nil {
+		return false
+	}
+	for _, m := range payload.Metrics {
+		if rebirth, ok := m.Value.(bool); m.Name == REBIRTH && ok && rebirth {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package sparkplug
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/mqtt"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func newCatalog() *catalog.Catalog {
+	return catalog.New(&mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			switch aws.ToString(params.TableName) {
+			case catalog.FACTORYTABLE:
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"factoryId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+					"name":      &ddbtypes.AttributeValueMemberS{Value: "North Plant"},
+				}}, nil
+			default:
+				return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+					"assetId": &ddbtypes.AttributeValueMemberS{Value: "a1"},
+					"modelId": &ddbtypes.AttributeValueMemberS{Value: "m1"},
+					"attributes": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+						"serial": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+							"value": &ddbtypes.AttributeValueMemberS{Value: "SN-1"},
+						}},
+					}},
+				}}, nil
+			}
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{{
+				"modelId":    &ddbtypes.AttributeValueMemberS{Value: "m1"},
+				"attributes": &ddbtypes.AttributeValueMemberL{Value: []ddbtypes.AttributeValue{&ddbtypes.AttributeValueMemberS{Value: "serial"}}},
+			}}}, nil
+		},
+	})
+}
+
+func testSeries() []catalog.Series {
+	lower, upper := 0.0, 120.0
+	return []catalog.Series{
+		{FactoryID: "f1", AssetID: "a1", AssetName: "Press", Property: types.Property{PropertyID: "p1", Name: "Oil temp", Unit: "°C"}, Measurement: types.Measurement{LowerBound: &lower, UpperBound: &upper}},
+		{FactoryID: "f1", AssetID: "a1", AssetName: "Press", Property: types.Property{PropertyID: "p2", Name: "Speed", Unit: "rpm"}},
+	}
+}
+
+type received struct {
+	topic   string
+	payload Payload
+}
+
+func startEdge(t *testing.T, opts Options) (*Publisher, *mqtt.Client, <-chan received) {
+	t.Helper()
+	broker := mqtt.NewBroker()
+	addr, err := broker.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error starting broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	host, err := mqtt.Dial(context.Background(), mqtt.Options{Broker: addr.String(), ClientID: "host"})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	t.Cleanup(func() { host.Disconnect() })
+
+	messages := make(chan received, 20)
+	err = host.Subscribe(context.Background(), NAMESPACE+"/#", 0, func(m mqtt.Message) {
+		payload, err := Unmarshal(m.Payload)
+		if err != nil {
+			t.Errorf("Unexpected error decoding %s: %v", m.Topic, err)
+		}
+		messages <- received{topic: m.Topic, payload: payload}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	p, err := New(newCatalog(), nil, mqtt.Options{Broker: addr.String(), ClientID: "edge"}, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return p, host, messages
+}
+
+func next(t *testing.T, messages <-chan received, topic string) Payload {
+	t.Helper()
+	select {
+	case m := <-messages:
+		if m.topic != topic {
+			t.Fatalf("Expected a message on %s, got %s", topic, m.topic)
+		}
+		return m.payload
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", topic)
+		return Payload{}
+	}
+}
+
+func metricNamed(payload Payload, name string) (Metric, bool) {
+	for _, m := range payload.Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+func TestPublisher_Lifecycle(t *testing.T) {
+	p, _, messages := startEdge(t, Options{})
+	ctx := context.Background()
+
+	session, err := p.Start(ctx, testSeries())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	nbirth := next(t, messages, "spBv1.0/North-Plant/NBIRTH/wdd")
+	if *nbirth.Seq != 0 {
+		t.Errorf("Expected NBIRTH seq 0, got %d", *nbirth.Seq)
+	}
+	if m, ok := metricNamed(nbirth, BDSEQ); !ok || m.Value != int64(0) {
+		t.Errorf("Expected bdSeq 0 in NBIRTH, got %+v", m)
+	}
+
+	dbirth := next(t, messages, "spBv1.0/North-Plant/DBIRTH/wdd/Press")
+	if *dbirth.Seq != 1 {
+		t.Errorf("Expected DBIRTH seq 1, got %d", *dbirth.Seq)
+	}
+	if m, ok := metricNamed(dbirth, "Attributes/serial"); !ok || m.Value != "SN-1" {
+		t.Errorf("Expected the serial attribute, got %+v", m)
+	}
+	oil, ok := metricNamed(dbirth, "Oil temp")
+	if !ok || !oil.IsNull || oil.DataType != DOUBLE {
+		t.Fatalf("Expected a null double Oil temp metric, got %+v", oil)
+	}
+	wantProperties := []Property{
+		{Key: "engUnit", DataType: STRING, Value: "°C"},
+		{Key: "engLow", DataType: DOUBLE, Value: 0.0},
+		{Key: "engHigh", DataType: DOUBLE, Value: 120.0},
+	}
+	if len(oil.Properties) != len(wantProperties) {
+		t.Fatalf("Expected properties %+v, got %+v", wantProperties, oil.Properties)
+	}
+	for i, want := range wantProperties {
+		if oil.Properties[i] != want {
+			t.Errorf("Expected property %+v, got %+v", want, oil.Properties[i])
+		}
+	}
+	speed, _ := metricNamed(dbirth, "Speed")
+
+	if err = session.Publish(ctx, types.Reading{PropertyID: "p2", Timestamp: 1000, Value: 42}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ddata := next(t, messages, "spBv1.0/North-Plant/DDATA/wdd/Press")
+	if *ddata.Seq != 2 || len(ddata.Metrics) != 1 {
+		t.Fatalf("Expected one metric at seq 2, got %+v", ddata)
+	}
+	if m := ddata.Metrics[0]; m.Alias != speed.Alias || m.Name != "" || m.Value != 42.0 || m.Timestamp != 1000 {
+		t.Errorf("Expected Speed by alias %d, got %+v", speed.Alias, m)
+	}
+
+	if err = session.Close(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ddeath := next(t, messages, "spBv1.0/North-Plant/DDEATH/wdd/Press"); *ddeath.Seq != 3 {
+		t.Errorf("Expected DDEATH seq 3, got %d", *ddeath.Seq)
+	}
+	ndeath := next(t, messages, "spBv1.0/North-Plant/NDEATH/wdd")
+	if m, ok := metricNamed(ndeath, BDSEQ); ndeath.Seq != nil || !ok || m.Value != int64(0) {
+		t.Errorf("Expected NDEATH with bdSeq 0 and no seq, got %+v", ndeath)
+	}
+	if err = session.Publish(ctx, types.Reading{PropertyID: "p2"}); err == nil {
+		t.Error("Expected publishing after close to fail")
+	}
+}
+
+func TestPublisher_Rebirth(t *testing.T) {
+	p, host, messages := startEdge(t, Options{AssetsAs: ASSETSASNODES, GroupID: "line-1", IDs: "ids"})
+	ctx := context.Background()
+
+	session, err := p.Start(ctx, testSeries())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer session.Close(ctx)
+
+	nbirth := next(t, messages, "spBv1.0/line-1/NBIRTH/a1")
+	if _, ok := metricNamed(nbirth, "p1"); !ok {
+		t.Errorf("Expected the properties as node metrics, got %+v", nbirth.Metrics)
+	}
+	if err = session.Publish(ctx, types.Reading{PropertyID: "p1", Timestamp: 1, Value: 3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next(t, messages, "spBv1.0/line-1/NDATA/a1")
+
+	command, _ := Payload{Metrics: []Metric{{Name: REBIRTH, DataType: BOOLEAN, Value: true}}}.Marshal()
+	if err = host.Publish(ctx, mqtt.Message{Topic: "spBv1.0/line-1/NCMD/a1", Payload: command}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next(t, messages, "spBv1.0/line-1/NCMD/a1")
+
+	rebirth := next(t, messages, "spBv1.0/line-1/NBIRTH/a1")
+	if *rebirth.Seq != 0 {
+		t.Errorf("Expected the rebirth to restart at seq 0, got %d", *rebirth.Seq)
+	}
+	if m, _ := metricNamed(rebirth, "p1"); m.Value != 3.0 {
+		t.Errorf("Expected the rebirth to carry the last value, got %+v", m)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	for _, opts := range []Options{{AssetsAs: "machines"}, {IDs: "slugs"}, {GroupID: "a/b"}, {EdgeNodeID: "node+1"}} {
+		if _, err := New(nil, nil, mqtt.Options{}, opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestPublisher_SharedModel(t *testing.T) {
+	p, _, messages := startEdge(t, Options{IDs: "ids"})
+	ctx := context.Background()
+
+	// Both presses are of one model, so they share its property.
+	series := []catalog.Series{
+		{FactoryID: "f1", AssetID: "a1", Property: types.Property{PropertyID: "p1", Name: "Oil temp"}},
+		{FactoryID: "f1", AssetID: "a2", Property: types.Property{PropertyID: "p1", Name: "Oil temp"}},
+	}
+	session, err := p.Start(ctx, series)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer session.Close(ctx)
+
+	next(t, messages, "spBv1.0/f1/NBIRTH/wdd")
+	aliases := map[string]uint64{}
+	for _, device := range []string{"a1", "a2"} {
+		dbirth := next(t, messages, "spBv1.0/f1/DBIRTH/wdd/"+device)
+		m, ok := metricNamed(dbirth, "p1")
+		if !ok {
+			t.Fatalf("Expected p1 in the DBIRTH of %s, got %+v", device, dbirth.Metrics)
+		}
+		aliases[device] = m.Alias
+	}
+	if aliases["a1"] == aliases["a2"] {
+		t.Errorf("Expected an alias per device, got %v", aliases)
+	}
+
+	if err = session.Publish(ctx, types.Reading{PropertyID: "p1", Timestamp: 1000, Value: 42}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, device := range []string{"a1", "a2"} {
+		ddata := next(t, messages, "spBv1.0/f1/DDATA/wdd/"+device)
+		if len(ddata.Metrics) != 1 || ddata.Metrics[0].Alias != aliases[device] || ddata.Metrics[0].Value != 42.0 {
+			t.Errorf("Expected 42 by alias %d for %s, got %+v", aliases[device], device, ddata.Metrics)
+		}
+	}
+}
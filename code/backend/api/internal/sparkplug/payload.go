@@ -0,0 +1,311 @@
+package sparkplug
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DataType is a Sparkplug B metric or property data type.
+type DataType uint32
+
+const (
+	INT32   DataType = 3
+	INT64   DataType = 4
+	UINT64  DataType = 8
+	DOUBLE  DataType = 10
+	BOOLEAN DataType = 11
+	STRING  DataType = 12
+)
+
+var ErrMalformedPayload = errors.New("malformed sparkplug payload")
+
+// Payload is the Sparkplug B protobuf payload, limited to the fields and
+// value types this simulator publishes.
+type Payload struct {
+	// Timestamp is in milliseconds since the epoch.
+	Timestamp uint64
+	Metrics   []Metric
+	// Seq is nil for NDEATH, which carries no sequence number.
+	Seq *uint64
+}
+
+type Metric struct {
+	Name string
+	// Alias is assigned at birth; zero means the metric has none.
+	Alias      uint64
+	Timestamp  uint64
+	DataType   DataType
+	IsNull     bool
+	Properties []Property
+	// Value is an int32, int64, uint64, float64, bool or string matching DataType.
+	Value any
+}
+
+type Property struct {
+	Key      string
+	DataType DataType
+	Value    any
+}
+
+// Marshal encodes the payload in protobuf wire format.
+func (p Payload) Marshal() ([]byte, error) {
+	var buf []byte
+	buf = appendVarintField(buf, 1, p.Timestamp)
+	for _, m := range p.Metrics {
+		metric, err := m.marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = appendBytesField(buf, 2, metric)
+	}
+	if p.Seq != nil {
+		buf = appendVarintField(buf, 3, *p.Seq)
+	}
+	return buf, nil
+}
+
+func (m Metric) marshal() ([]byte, error) {
+	var buf []byte
+	if m.Name != "" {
+		buf = appendBytesField(buf, 1, []byte(m.Name))
+	}
+	if m.Alias != 0 {
+		buf = appendVarintField(buf, 2, m.Alias)
+	}
+	if m.Timestamp != 0 {
+		buf = appendVarintField(buf, 3, m.Timestamp)
+	}
+	buf = appendVarintField(buf, 4, uint64(m.DataType))
+	if len(m.Properties) > 0 {
+		set, err := marshalProperties(m.Properties)
+		if err != nil {
+			return nil, err
+		}
+		buf = appendBytesField(buf, 9, set)
+	}
+	if m.IsNull || m.Value == nil {
+		return appendVarintField(buf, 7, 1), nil
+	}
+	// Metric values start at field 10, property values at field 3.
+	return appendValue(buf, 10, m.DataType, m.Value)
+}
+
+func marshalProperties(properties []Property) ([]byte, error) {
+	var keys, values []byte
+	for _, p := range properties {
+		keys = appendBytesField(keys, 1, []byte(p.Key))
+
+		value := appendVarintField(nil, 1, uint64(p.DataType))
+		var err error
+		if p.Value == nil {
+			value = appendVarintField(value, 2, 1)
+		} else if value, err = appendValue(value, 3, p.DataType, p.Value); err != nil {
+			return nil, fmt.Errorf("property %s: %w", p.Key, err)
+		}
+		values = appendBytesField(values, 2, value)
+	}
+	return append(keys, values...), nil
+}
+
+// appendValue writes a value into the oneof whose int_value field is base;
+// the long, float, double, boolean and string fields follow it in order.
+func appendValue(buf []byte, base int, dataType DataType, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case int32:
+		if dataType == INT32 {
+			return appendVarintField(buf, base, uint64(uint32(v))), nil
+		}
+	case int64:
+		if dataType == INT64 {
+			return appendVarintField(buf, base+1, uint64(v)), nil
+		}
+	case uint64:
+		if dataType == UINT64 {
+			return appendVarintField(buf, base+1, v), nil
+		}
+	case float64:
+		if dataType == DOUBLE {
+			buf = appendTag(buf, base+3, 1)
+			return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), nil
+		}
+	case bool:
+		if dataType == BOOLEAN {
+			var b uint64
+			if v {
+				b = 1
+			}
+			return appendVarintField(buf, base+4, b), nil
+		}
+	case string:
+		if dataType == STRING {
+			return appendBytesField(buf, base+5, []byte(v)), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T value for data type %d", ErrMalformedPayload, value, dataType)
+}
+
+// Unmarshal decodes a payload, skipping fields it does not know.
+func Unmarshal(data []byte) (Payload, error) {
+	var p Payload
+	err := walk(data, func(field int, varint uint64, raw []byte) error {
+		switch field {
+		case 1:
+			p.Timestamp = varint
+		case 2:
+			m, err := unmarshalMetric(raw)
+			if err != nil {
+				return err
+			}
+			p.Metrics = append(p.Metrics, m)
+		case 3:
+			seq := varint
+			p.Seq = &seq
+		}
+		return nil
+	})
+	return p, err
+}
+
+func unmarshalMetric(data []byte) (Metric, error) {
+	var m Metric
+	var keys []string
+	var values []Property
+	err := walk(data, func(field int, varint uint64, raw []byte) error {
+		switch {
+		case field == 1:
+			m.Name = string(raw)
+		case field == 2:
+			m.Alias = varint
+		case field == 3:
+			m.Timestamp = varint
+		case field == 4:
+			m.DataType = DataType(varint)
+		case field == 7:
+			m.IsNull = varint != 0
+		case field == 9:
+			return walk(raw, func(field int, _ uint64, raw []byte) error {
+				switch field {
+				case 1:
+					keys = append(keys, string(raw))
+				case 2:
+					p, err := unmarshalProperty(raw)
+					if err != nil {
+						return err
+					}
+					values = append(values, p)
+				}
+				return nil
+			})
+		case field >= 10 && field <= 15:
+			m.Value = decodeValue(field-10, m.DataType, varint, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return m, err
+	}
+	if len(keys) != len(values) {
+		return m, fmt.Errorf("%w: property set has %d keys and %d values", ErrMalformedPayload, len(keys), len(values))
+	}
+	for i := range keys {
+		values[i].Key = keys[i]
+	}
+	m.Properties = values
+	return m, nil
+}
+
+func unmarshalProperty(data []byte) (Property, error) {
+	var p Property
+	err := walk(data, func(field int, varint uint64, raw []byte) error {
+		switch {
+		case field == 1:
+			p.DataType = DataType(varint)
+		case field >= 3 && field <= 8:
+			p.Value = decodeValue(field-3, p.DataType, varint, raw)
+		}
+		return nil
+	})
+	return p, err
+}
+
+// decodeValue decodes the offset-th field of a value oneof: int, long,
+// float, double, boolean, string.
+func decodeValue(offset int, dataType DataType, varint uint64, raw []byte) any {
+	switch offset {
+	case 0:
+		return int32(varint)
+	case 1:
+		if dataType == INT64 {
+			return int64(varint)
+		}
+		return varint
+	case 2:
+		return float64(math.Float32frombits(uint32(varint)))
+	case 3:
+		return math.Float64frombits(varint)
+	case 4:
+		return varint != 0
+	default:
+		return string(raw)
+	}
+}
+
+// walk calls fn for every field of a protobuf message. Varint and fixed
+// fields arrive in varint, length-delimited fields in raw.
+func walk(data []byte, fn func(field int, varint uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrMalformedPayload
+		}
+		data = data[n:]
+
+		var varint uint64
+		var raw []byte
+		switch tag & 0x07 {
+		case 0:
+			if varint, n = binary.Uvarint(data); n <= 0 {
+				return ErrMalformedPayload
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return ErrMalformedPayload
+			}
+			varint, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return ErrMalformedPayload
+			}
+			raw, data = data[n:n+int(length)], data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return ErrMalformedPayload
+			}
+			varint, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("%w: unsupported wire type %d", ErrMalformedPayload, tag&0x07)
+		}
+
+		if err := fn(int(tag>>3), varint, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendTag(buf []byte, field int, wireType byte) []byte {
+	return binary.AppendUvarint(buf, uint64(field)<<3|uint64(wireType))
+}
+
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(buf, field, 0), v)
+}
+
+func appendBytesField(buf []byte, field int, b []byte) []byte {
+	buf = binary.AppendUvarint(appendTag(buf, field, 2), uint64(len(b)))
+	return append(buf, b...)
+}
@@ -0,0 +1,79 @@
+package sparkplug
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPayload_RoundTrip(t *testing.T) {
+	seq := uint64(7)
+	payload := Payload{
+		Timestamp: 1700000000000,
+		Seq:       &seq,
+		Metrics: []Metric{
+			{Name: BDSEQ, DataType: INT64, Value: int64(3)},
+			{Name: REBIRTH, DataType: BOOLEAN, Value: false},
+			{
+				Name:      "Oil temp",
+				Alias:     1,
+				Timestamp: 1700000000001,
+				DataType:  DOUBLE,
+				Properties: []Property{
+					{Key: "engUnit", DataType: STRING, Value: "°C"},
+					{Key: "engLow", DataType: DOUBLE, Value: -20.5},
+				},
+				Value: 21.25,
+			},
+			{Name: "Attributes/serial", DataType: STRING, Value: "SN-1"},
+			{Name: "Speed", Alias: 2, DataType: DOUBLE, IsNull: true},
+			{Name: "Count", DataType: INT32, Value: int32(-4)},
+		},
+	}
+
+	data, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, payload) {
+		t.Errorf("Round trip mismatch:\nwant %+v\ngot  %+v", payload, decoded)
+	}
+}
+
+func TestPayload_WireFormat(t *testing.T) {
+	// A DDATA payload assembled by hand from sparkplug_b.proto field numbers.
+	want := []byte{
+		0x08, 0xe8, 0x07, // timestamp 1000
+		0x12, 0x10, // metric, 16 bytes
+		0x10, 0x01, // alias 1
+		0x18, 0xe9, 0x07, // timestamp 1001
+		0x20, 0x0a, // datatype Double
+		0x69, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f, // double_value 1.5
+		0x18, 0x05, // seq 5
+	}
+	seq := uint64(5)
+	got, err := Payload{
+		Timestamp: 1000,
+		Seq:       &seq,
+		Metrics:   []Metric{{Alias: 1, Timestamp: 1001, DataType: DOUBLE, Value: 1.5}},
+	}.Marshal()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % x, got % x", want, got)
+	}
+}
+
+func TestPayload_Errors(t *testing.T) {
+	if _, err := (Payload{Metrics: []Metric{{DataType: DOUBLE, Value: "x"}}}).Marshal(); err == nil {
+		t.Error("Expected a value not matching its data type to fail")
+	}
+	if _, err := Unmarshal([]byte{0x12, 0x05, 0x01}); err == nil {
+		t.Error("Expected a truncated payload to fail")
+	}
+}
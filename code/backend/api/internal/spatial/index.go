@@ -0,0 +1,132 @@
+package spatial
+
+import (
+	"math"
+	"sort"
+	"wdd/api/internal/types"
+)
+
+// Index is a static k-d tree over the positions of one floorplan. The tree
+// is implicit in the order of its items: each range is split at its median
+// along x at even depths and along y at odd ones.
+type Index struct {
+	points []types.Position
+	order  []int
+}
+
+// New indexes points, which are referred to by their index in results.
+func New(points []types.Position) *Index {
+	ix := &Index{points: points, order: make([]int, len(points))}
+	for i := range ix.order {
+		ix.order[i] = i
+	}
+	ix.build(0, len(points), 0)
+	return ix
+}
+
+func (ix *Index) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	items := ix.order[lo:hi]
+	sort.Slice(items, func(i, j int) bool {
+		return ix.coordinate(items[i], depth) < ix.coordinate(items[j], depth)
+	})
+	mid := (lo + hi) / 2
+	ix.build(lo, mid, depth+1)
+	ix.build(mid+1, hi, depth+1)
+}
+
+func (ix *Index) coordinate(i, depth int) float64 {
+	if depth%2 == 0 {
+		return ix.points[i].X
+	}
+	return ix.points[i].Y
+}
+
+// Within returns the points at most radius from center, edge included.
+func (ix *Index) Within(center types.Position, radius float64) []int {
+	var found []int
+	min := types.Position{X: center.X - radius, Y: center.Y - radius}
+	max := types.Position{X: center.X + radius, Y: center.Y + radius}
+	ix.rect(0, len(ix.order), 0, min, max, func(i int) {
+		if Distance(ix.points[i], center) <= radius {
+			found = append(found, i)
+		}
+	})
+	return found
+}
+
+// InRect returns the points in the axis-aligned rectangle from min to max,
+// edges included.
+func (ix *Index) InRect(min, max types.Position) []int {
+	var found []int
+	ix.rect(0, len(ix.order), 0, min, max, func(i int) {
+		found = append(found, i)
+	})
+	return found
+}
+
+func (ix *Index) rect(lo, hi, depth int, min, max types.Position, visit func(int)) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	i := ix.order[mid]
+	p := ix.points[i]
+	if p.X >= min.X && p.X <= max.X && p.Y >= min.Y && p.Y <= max.Y {
+		visit(i)
+	}
+
+	low, high := min.X, max.X
+	if depth%2 == 1 {
+		low, high = min.Y, max.Y
+	}
+	split := ix.coordinate(i, depth)
+	if low <= split {
+		ix.rect(lo, mid, depth+1, min, max, visit)
+	}
+	if high >= split {
+		ix.rect(mid+1, hi, depth+1, min, max, visit)
+	}
+}
+
+// Nearest returns the point closest to p other than skip, which may be -1
+// to skip none, and its distance. It returns false when there is no such
+// point.
+func (ix *Index) Nearest(p types.Position, skip int) (int, float64, bool) {
+	best, bestDistance := -1, math.Inf(1)
+	var search func(lo, hi, depth int)
+	search = func(lo, hi, depth int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		i := ix.order[mid]
+		if d := Distance(ix.points[i], p); i != skip && (d < bestDistance || d == bestDistance && i < best) {
+			best, bestDistance = i, d
+		}
+
+		offset := p.X - ix.points[i].X
+		if depth%2 == 1 {
+			offset = p.Y - ix.points[i].Y
+		}
+		near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+		if offset > 0 {
+			near, far = far, near
+		}
+		search(near[0], near[1], depth+1)
+		// The far side can only hold a closer point within the splitting
+		// line's distance.
+		if math.Abs(offset) <= bestDistance {
+			search(far[0], far[1], depth+1)
+		}
+	}
+	search(0, len(ix.order), 0)
+	return best, bestDistance, best >= 0
+}
+
+// Distance is the straight-line distance between two positions.
+func Distance(a, b types.Position) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
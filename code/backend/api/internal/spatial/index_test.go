@@ -0,0 +1,92 @@
+package spatial
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func TestIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := make([]types.Position, 500)
+	for i := range points {
+		// Coarse coordinates, so that some points share them.
+		points[i] = types.Position{X: float64(r.Intn(100)), Y: float64(r.Intn(100))}
+	}
+	ix := New(points)
+
+	for q := 0; q < 50; q++ {
+		center := types.Position{X: r.Float64() * 100, Y: r.Float64() * 100}
+		radius := r.Float64() * 20
+		min := types.Position{X: center.X - radius, Y: center.Y - 2*radius}
+		max := types.Position{X: center.X + 2*radius, Y: center.Y + radius}
+
+		var within, inRect []int
+		nearest := -1
+		for i, p := range points {
+			if Distance(p, center) <= radius {
+				within = append(within, i)
+			}
+			if p.X >= min.X && p.X <= max.X && p.Y >= min.Y && p.Y <= max.Y {
+				inRect = append(inRect, i)
+			}
+			if nearest < 0 || Distance(p, center) < Distance(points[nearest], center) {
+				nearest = i
+			}
+		}
+
+		if got := sorted(ix.Within(center, radius)); !equal(got, within) {
+			t.Errorf("Expected %v within %g of %+v, got %v", within, radius, center, got)
+		}
+		if got := sorted(ix.InRect(min, max)); !equal(got, inRect) {
+			t.Errorf("Expected %v in %+v-%+v, got %v", inRect, min, max, got)
+		}
+		if got, d, ok := ix.Nearest(center, -1); !ok || got != nearest {
+			t.Errorf("Expected %d nearest to %+v, got %d at %g", nearest, center, got, d)
+		}
+	}
+
+	// Every point's nearest other point is at most as far as any other.
+	for i, p := range points {
+		j, d, ok := ix.Nearest(p, i)
+		if !ok || j == i {
+			t.Fatalf("Expected a neighbour of %d, got %d", i, j)
+		}
+		for k, other := range points {
+			if k != i && Distance(p, other) < d {
+				t.Fatalf("Expected %d at %g to be nearest to %d, but %d is at %g", j, d, i, k, Distance(p, other))
+			}
+		}
+	}
+}
+
+func TestIndex_Empty(t *testing.T) {
+	ix := New(nil)
+	if found := ix.Within(types.Position{}, 10); len(found) != 0 {
+		t.Errorf("Expected nothing in an empty index, got %v", found)
+	}
+	if _, _, ok := ix.Nearest(types.Position{}, -1); ok {
+		t.Errorf("Expected no nearest point in an empty index")
+	}
+	if _, _, ok := New([]types.Position{{X: 1, Y: 1}}).Nearest(types.Position{X: 1, Y: 1}, 0); ok {
+		t.Errorf("Expected a lone point to have no neighbour")
+	}
+}
+
+func sorted(values []int) []int {
+	sort.Ints(values)
+	return values
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
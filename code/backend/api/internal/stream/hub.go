@@ -0,0 +1,119 @@
+package stream
+
+import (
+	"sync"
+	"wdd/api/internal/types"
+)
+
+const (
+	DEFAULTBUFFER = 256
+	MAXBUFFER     = 10000
+)
+
+// Hub fans readings out to the subscriptions interested in their property.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription buffers the readings of a set of properties for one client.
+// When the client falls behind and the buffer is full, the oldest reading is
+// dropped so a slow client always sees the most recent values.
+type Subscription struct {
+	properties map[string]bool
+	notify     chan struct{}
+
+	mu      sync.Mutex
+	ring    []types.Reading
+	head    int
+	size    int
+	dropped int64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: map[*Subscription]struct{}{},
+	}
+}
+
+func (h *Hub) Subscribe(propertyIDs []string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DEFAULTBUFFER
+	}
+	sub := &Subscription{
+		properties: make(map[string]bool, len(propertyIDs)),
+		notify:     make(chan struct{}, 1),
+		ring:       make([]types.Reading, buffer),
+	}
+	for _, id := range propertyIDs {
+		sub.properties[id] = true
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// Publish never blocks on slow subscribers.
+func (h *Hub) Publish(readings ...types.Reading) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		for _, reading := range readings {
+			if sub.properties[reading.PropertyID] {
+				sub.push(reading)
+			}
+		}
+	}
+}
+
+// Ready is signalled whenever readings are waiting to be drained.
+func (s *Subscription) Ready() <-chan struct{} {
+	return s.notify
+}
+
+// Drain returns the buffered readings, oldest first, and empties the buffer.
+func (s *Subscription) Drain() []types.Reading {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readings := make([]types.Reading, s.size)
+	for i := range readings {
+		readings[i] = s.ring[(s.head+i)%len(s.ring)]
+	}
+	s.head, s.size = 0, 0
+	return readings
+}
+
+// Dropped is the number of readings dropped so far because the buffer was full.
+func (s *Subscription) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) push(reading types.Reading) {
+	s.mu.Lock()
+	if s.size == len(s.ring) {
+		s.ring[s.head] = reading
+		s.head = (s.head + 1) % len(s.ring)
+		s.dropped++
+	} else {
+		s.ring[(s.head+s.size)%len(s.ring)] = reading
+		s.size++
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
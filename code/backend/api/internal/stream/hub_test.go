@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHub_PublishFiltersByProperty(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe([]string{"p1"}, 10)
+
+	hub.Publish(types.Reading{PropertyID: "p1", Timestamp: 1}, types.Reading{PropertyID: "p2", Timestamp: 2})
+
+	select {
+	case <-sub.Ready():
+	default:
+		t.Fatal("Expected the subscription to be ready")
+	}
+	if readings := sub.Drain(); len(readings) != 1 || readings[0].PropertyID != "p1" {
+		t.Errorf("Expected only the p1 reading, got %+v", readings)
+	}
+
+	hub.Unsubscribe(sub)
+	hub.Publish(types.Reading{PropertyID: "p1", Timestamp: 3})
+	if readings := sub.Drain(); len(readings) != 0 {
+		t.Errorf("Expected no readings after unsubscribing, got %+v", readings)
+	}
+}
+
+func TestHub_DropsOldest(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe([]string{"p1"}, 3)
+
+	for ts := int64(1); ts <= 5; ts++ {
+		hub.Publish(types.Reading{PropertyID: "p1", Timestamp: ts})
+	}
+
+	readings := sub.Drain()
+	if len(readings) != 3 || readings[0].Timestamp != 3 || readings[2].Timestamp != 5 {
+		t.Errorf("Expected the three newest readings in order, got %+v", readings)
+	}
+	if sub.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped readings, got %d", sub.Dropped())
+	}
+}
+
+func TestTap_PublishesWrittenReadings(t *testing.T) {
+	item := func(ts string) map[string]ddbtypes.AttributeValue {
+		return map[string]ddbtypes.AttributeValue{
+			"propertyId": &ddbtypes.AttributeValueMemberS{Value: "p1"},
+			"timestamp":  &ddbtypes.AttributeValueMemberN{Value: ts},
+			"value":      &ddbtypes.AttributeValueMemberN{Value: "1.5"},
+		}
+	}
+	mockDDBClient := &mocks.DynamoDBClient{
+		BatchWriteItemFunc: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]ddbtypes.WriteRequest{
+				"Reading": {{PutRequest: &ddbtypes.PutRequest{Item: item("2")}}},
+			}}, nil
+		},
+	}
+
+	hub := NewHub()
+	sub := hub.Subscribe([]string{"p1"}, 10)
+	db := Tap(mockDDBClient, hub)
+
+	_, err := db.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{RequestItems: map[string][]ddbtypes.WriteRequest{
+		"Reading": {
+			{PutRequest: &ddbtypes.PutRequest{Item: item("1")}},
+			{PutRequest: &ddbtypes.PutRequest{Item: item("2")}},
+		},
+		"ReadingRollup": {{PutRequest: &ddbtypes.PutRequest{Item: item("3")}}},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if readings := sub.Drain(); len(readings) != 1 || readings[0].Timestamp != 1 || readings[0].Value != 1.5 {
+		t.Errorf("Expected only the processed reading to be published, got %+v", readings)
+	}
+
+	_, _ = db.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{RequestItems: map[string][]ddbtypes.WriteRequest{
+		"Property": {{PutRequest: &ddbtypes.PutRequest{Item: item("4")}}},
+	}})
+	if readings := sub.Drain(); len(readings) != 0 {
+		t.Errorf("Expected writes to other tables to be ignored, got %+v", readings)
+	}
+}
+
+func TestLive_KeysByFactory(t *testing.T) {
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	live := NewLive(ctx, NewHub(), simulator.New(catalog.New(mockDDBClient), 1))
+
+	series := func(factoryID string) catalog.Series {
+		return catalog.Series{
+			FactoryID:   factoryID,
+			Property:    types.Property{PropertyID: "p1"},
+			Measurement: types.Measurement{GeneratorFunction: generators.SINEWAVE},
+		}
+	}
+	withoutFactory, err := live.Acquire(ctx, []catalog.Series{series("")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	withFactory, err := live.Acquire(ctx, []catalog.Series{series("f1"), series("f1")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	live.mu.Lock()
+	running, shared := len(live.running), live.running["f1/p1"]
+	live.mu.Unlock()
+	if running != 2 || shared == nil || shared.refs != 2 {
+		t.Fatalf("Expected one generator per factory, got %d running", running)
+	}
+
+	withoutFactory()
+	withFactory()
+	live.mu.Lock()
+	running = len(live.running)
+	live.mu.Unlock()
+	if running != 0 {
+		t.Errorf("Expected every generator to stop once released, got %d running", running)
+	}
+}
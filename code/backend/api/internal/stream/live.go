@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/generators"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/types"
+)
+
+// Live runs the simulator for the properties that have subscribers and
+// publishes what it generates. Each property is simulated once per factory,
+// whose shift calendar shapes it, no matter how many clients or assets share
+// it.
+type Live struct {
+	Hub       *Hub
+	Simulator *simulator.Simulator
+
+	ctx     context.Context
+	mu      sync.Mutex
+	running map[string]*liveSeries
+}
+
+type liveSeries struct {
+	refs   int
+	cancel context.CancelFunc
+}
+
+// NewLive stops every simulation when ctx is done.
+func NewLive(ctx context.Context, hub *Hub, sim *simulator.Simulator) *Live {
+	return &Live{
+		Hub:       hub,
+		Simulator: sim,
+		ctx:       ctx,
+		running:   map[string]*liveSeries{},
+	}
+}
+
+// Acquire starts simulating the series that are not running yet. The returned
+// release function stops those no longer needed by anyone.
+func (l *Live) Acquire(ctx context.Context, series []catalog.Series) (func(), error) {
+	var acquired []string
+	release := func() { l.release(acquired) }
+
+	for _, s := range series {
+		id := liveKey(s)
+		l.mu.Lock()
+		if running, ok := l.running[id]; ok {
+			running.refs++
+			l.mu.Unlock()
+			acquired = append(acquired, id)
+			continue
+		}
+		l.mu.Unlock()
+
+		// Surface calendar errors now rather than inside the goroutine.
+		if _, err := l.Simulator.Generator(ctx, s); errors.Is(err, generators.ErrUnsupportedGenerator) {
+			continue
+		} else if err != nil {
+			release()
+			return nil, err
+		}
+		l.start(s)
+		acquired = append(acquired, id)
+	}
+	return release, nil
+}
+
+func (l *Live) start(s catalog.Series) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := liveKey(s)
+	if running, ok := l.running[id]; ok {
+		running.refs++
+		return
+	}
+
+	ctx, cancel := context.WithCancel(l.ctx)
+	l.running[id] = &liveSeries{refs: 1, cancel: cancel}
+	go func() {
+		_ = l.Simulator.Run(ctx, []catalog.Series{s}, func(_ catalog.Series, reading types.Reading) {
+			l.Hub.Publish(reading)
+		})
+	}()
+}
+
+func (l *Live) release(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range keys {
+		running, ok := l.running[id]
+		if !ok {
+			continue
+		}
+		if running.refs--; running.refs == 0 {
+			running.cancel()
+			delete(l.running, id)
+		}
+	}
+}
+
+// liveKey tells apart series of the same property resolved with and without
+// their factory, so a generator started without the factory's calendar is
+// never shared with subscribers that expect it.
+func liveKey(s catalog.Series) string {
+	return s.FactoryID + "/" + s.Property.PropertyID
+}
@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"context"
+	"wdd/api/internal/timeseries"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// tap publishes every reading successfully written to the readings table, so
+// ingested readings reach live subscribers too. Backfills must write around
+// it: their readings are historical, not live.
+type tap struct {
+	types.DynamoDBClient
+	hub *Hub
+}
+
+func Tap(db types.DynamoDBClient, hub *Hub) types.DynamoDBClient {
+	return &tap{DynamoDBClient: db, hub: hub}
+}
+
+func (t *tap) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	output, err := t.DynamoDBClient.PutItem(ctx, params, optFns...)
+	if err == nil && aws.ToString(params.TableName) == timeseries.TABLENAME {
+		t.publish([]ddbtypes.WriteRequest{{PutRequest: &ddbtypes.PutRequest{Item: params.Item}}}, nil)
+	}
+	return output, err
+}
+
+func (t *tap) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output, err := t.DynamoDBClient.BatchWriteItem(ctx, params, optFns...)
+	if err == nil {
+		if requests, ok := params.RequestItems[timeseries.TABLENAME]; ok {
+			t.publish(requests, output.UnprocessedItems[timeseries.TABLENAME])
+		}
+	}
+	return output, err
+}
+
+func (t *tap) publish(requests, unprocessed []ddbtypes.WriteRequest) {
+	skip := map[types.Reading]bool{}
+	for _, request := range unprocessed {
+		if reading, ok := putReading(request); ok {
+			skip[reading] = true
+		}
+	}
+
+	readings := make([]types.Reading, 0, len(requests))
+	for _, request := range requests {
+		if reading, ok := putReading(request); ok && !skip[reading] {
+			readings = append(readings, reading)
+		}
+	}
+	if len(readings) > 0 {
+		t.hub.Publish(readings...)
+	}
+}
+
+func putReading(request ddbtypes.WriteRequest) (types.Reading, bool) {
+	var reading types.Reading
+	if request.PutRequest == nil {
+		return reading, false
+	}
+	err := wrappers.UnmarshalMap(request.PutRequest.Item, &reading)
+	return reading, err == nil
+}
@@ -0,0 +1,202 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mqtt"
+	"wdd/api/internal/simulator"
+	"wdd/api/internal/types"
+)
+
+const (
+	DEFAULTPREFIX = "wdd"
+	TOPICSNAMES   = "names"
+	TOPICSIDS     = "ids"
+	// UNASSIGNED stands in for the factory and asset levels of a property
+	// that is published on its own.
+	UNASSIGNED = "unassigned"
+)
+
+var ErrInvalidOptions = errors.New("invalid mqtt publisher options")
+
+// Client is the part of an MQTT client the publisher needs.
+type Client interface {
+	Publish(ctx context.Context, m mqtt.Message) error
+}
+
+type Options struct {
+	// TopicPrefix is the first level of every topic.
+	TopicPrefix string
+	// Topics names topic levels after factory, asset and property names
+	// ("names") or after their IDs ("ids").
+	Topics string
+	QoS    byte
+	// Retain keeps the last value of every topic on the broker for new subscribers.
+	Retain bool
+}
+
+// Payload is the JSON body of every published reading.
+type Payload struct {
+	Value     float64 `json:"value"`
+	Unit      string  `json:"unit"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// Publisher publishes simulated readings to an MQTT broker, one topic per
+// property: <prefix>/<factory>/<asset>/<property>.
+type Publisher struct {
+	Catalog   *catalog.Catalog
+	Simulator *simulator.Simulator
+	Client    Client
+	Options   Options
+
+	mu        sync.Mutex
+	factories map[string]string
+}
+
+func New(c *catalog.Catalog, sim *simulator.Simulator, client Client, opts Options) (*Publisher, error) {
+	if opts.TopicPrefix == "" {
+		opts.TopicPrefix = DEFAULTPREFIX
+	}
+	if opts.Topics == "" {
+		opts.Topics = TOPICSNAMES
+	}
+	if opts.Topics != TOPICSNAMES && opts.Topics != TOPICSIDS {
+		return nil, fmt.Errorf("%w: topics must be %q or %q", ErrInvalidOptions, TOPICSNAMES, TOPICSIDS)
+	}
+	if opts.QoS > 2 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, mqtt.ErrInvalidQoS)
+	}
+	return &Publisher{
+		Catalog:   c,
+		Simulator: sim,
+		Client:    client,
+		Options:   opts,
+		factories: map[string]string{},
+	}, nil
+}
+
+// Run simulates exactly one of a factory, asset or property and publishes
+// every reading until ctx is done or a publish fails.
+func (p *Publisher) Run(ctx context.Context, factoryID, assetID, propertyID string) error {
+	series, err := p.Catalog.Series(ctx, factoryID, assetID, propertyID)
+	if err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		return catalog.ErrNotFound
+	}
+	// Resolve factory names up front so the first readings are not delayed.
+	for _, s := range series {
+		if _, err = p.Topic(ctx, s); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	err = p.Simulator.Run(ctx, series, func(s catalog.Series, reading types.Reading) {
+		if err := p.Publish(ctx, s, reading); err != nil && ctx.Err() == nil {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// Publish sends one reading to its property's topic.
+func (p *Publisher) Publish(ctx context.Context, s catalog.Series, reading types.Reading) error {
+	topic, err := p.Topic(ctx, s)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Payload{Value: reading.Value, Unit: s.Property.Unit, Timestamp: reading.Timestamp})
+	if err != nil {
+		return err
+	}
+	return p.Client.Publish(ctx, mqtt.Message{Topic: topic, Payload: payload, QoS: p.Options.QoS, Retain: p.Options.Retain})
+}
+
+// Topic returns the topic a series is published on.
+func (p *Publisher) Topic(ctx context.Context, s catalog.Series) (string, error) {
+	factory, asset, property := s.FactoryID, s.AssetID, s.Property.PropertyID
+	if p.Options.Topics == TOPICSNAMES {
+		name, err := p.factoryName(ctx, s.FactoryID)
+		if err != nil {
+			return "", err
+		}
+		factory = or(name, factory)
+		asset = or(s.AssetName, asset)
+		property = or(s.Property.Name, property)
+	}
+	levels := []string{p.Options.TopicPrefix, Segment(or(factory, UNASSIGNED)), Segment(or(asset, UNASSIGNED)), Segment(property)}
+	return strings.Join(levels, "/"), nil
+}
+
+func (p *Publisher) factoryName(ctx context.Context, factoryID string) (string, error) {
+	if factoryID == "" {
+		return "", nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name, ok := p.factories[factoryID]; ok {
+		return name, nil
+	}
+
+	factory, err := p.Catalog.Factory(ctx, factoryID)
+	if err != nil {
+		return "", err
+	}
+	var name string
+	if factory.Name != nil {
+		name = *factory.Name
+	}
+	p.factories[factoryID] = name
+	return name, nil
+}
+
+// Segment makes a name safe to use as one topic level: separators and
+// wildcards become underscores and runs of whitespace become a hyphen.
+func Segment(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == '/' || r == '+' || r == '#' || r == 0:
+			r = '_'
+		}
+		if space {
+			b.WriteByte('-')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func or(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
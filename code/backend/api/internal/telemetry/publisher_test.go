@@ -0,0 +1,157 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/mqtt"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type recorder struct {
+	messages []mqtt.Message
+}
+
+func (r *recorder) Publish(ctx context.Context, m mqtt.Message) error {
+	r.messages = append(r.messages, m)
+	return nil
+}
+
+func newPublisher(t *testing.T, opts Options) (*Publisher, *recorder, *int) {
+	t.Helper()
+	lookups := 0
+	mockDDBClient := &mocks.DynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			lookups++
+			return &dynamodb.GetItemOutput{Item: map[string]ddbtypes.AttributeValue{
+				"factoryId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+				"name":      &ddbtypes.AttributeValueMemberS{Value: "North Plant"},
+			}}, nil
+		},
+	}
+
+	client := &recorder{}
+	p, err := New(catalog.New(mockDDBClient), nil, client, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return p, client, &lookups
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	p, client, lookups := newPublisher(t, Options{QoS: 1, Retain: true})
+	s := catalog.Series{
+		FactoryID: "f1",
+		AssetID:   "a1",
+		AssetName: "Press #2",
+		Property:  types.Property{PropertyID: "p1", Name: "Oil temp", Unit: "°C"},
+	}
+
+	for ts := int64(1); ts <= 2; ts++ {
+		if err := p.Publish(context.Background(), s, types.Reading{PropertyID: "p1", Timestamp: ts, Value: 21.5}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if len(client.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(client.messages))
+	}
+	m := client.messages[1]
+	if m.Topic != "wdd/North-Plant/Press-_2/Oil-temp" {
+		t.Errorf("Unexpected topic %q", m.Topic)
+	}
+	if m.QoS != 1 || !m.Retain {
+		t.Errorf("Expected qos 1 and retain, got qos %d retain %v", m.QoS, m.Retain)
+	}
+	var payload Payload
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		t.Fatalf("Unexpected error decoding payload: %v", err)
+	}
+	if payload != (Payload{Value: 21.5, Unit: "°C", Timestamp: 2}) {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+	if *lookups != 1 {
+		t.Errorf("Expected the factory name to be looked up once, got %d lookups", *lookups)
+	}
+}
+
+func TestPublisher_Topic(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		series catalog.Series
+		want   string
+	}{
+		{
+			name:   "ids",
+			opts:   Options{Topics: TOPICSIDS, TopicPrefix: "plant"},
+			series: catalog.Series{FactoryID: "f1", AssetID: "a1", AssetName: "Press", Property: types.Property{PropertyID: "p1", Name: "Speed"}},
+			want:   "plant/f1/a1/p1",
+		},
+		{
+			name:   "property on its own",
+			series: catalog.Series{Property: types.Property{PropertyID: "p1", Name: "Speed"}},
+			want:   "wdd/unassigned/unassigned/Speed",
+		},
+		{
+			name:   "missing names fall back to ids",
+			series: catalog.Series{FactoryID: "f1", AssetID: "a1", Property: types.Property{PropertyID: "p1"}},
+			want:   "wdd/North-Plant/a1/p1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, _ := newPublisher(t, tt.opts)
+			got, err := p.Topic(context.Background(), tt.series)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	for _, opts := range []Options{{Topics: "slugs"}, {QoS: 3}} {
+		if _, err := New(nil, nil, &recorder{}, opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestPublisher_EmbeddedBroker(t *testing.T) {
+	broker := mqtt.NewBroker()
+	addr, err := broker.ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error starting broker: %v", err)
+	}
+	defer broker.Close()
+
+	client, err := mqtt.Dial(context.Background(), mqtt.Options{Broker: addr.String()})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	defer client.Disconnect()
+
+	p, _, _ := newPublisher(t, Options{Topics: TOPICSIDS, QoS: 2, Retain: true})
+	p.Client = client
+	s := catalog.Series{FactoryID: "f1", AssetID: "a1", Property: types.Property{PropertyID: "p1", Unit: "rpm"}}
+	if err = p.Publish(context.Background(), s, types.Reading{Timestamp: 5, Value: 3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m, ok := broker.Retained("wdd/f1/a1/p1")
+	if !ok {
+		t.Fatal("Expected the last value to be retained")
+	}
+	if string(m.Payload) != `{"value":3,"unit":"rpm","timestamp":5}` {
+		t.Errorf("Unexpected payload %s", m.Payload)
+	}
+}
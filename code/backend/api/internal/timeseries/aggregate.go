@@ -0,0 +1,260 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"wdd/api/internal/types"
+)
+
+const (
+	MIN    = "min"
+	MAX    = "max"
+	AVG    = "avg"
+	FIRST  = "first"
+	LAST   = "last"
+	COUNT  = "count"
+	STDDEV = "stddev"
+)
+
+var ErrInvalidAggregate = errors.New("invalid aggregate")
+
+var aggregates = map[string]bool{MIN: true, MAX: true, AVG: true, FIRST: true, LAST: true, COUNT: true, STDDEV: true}
+
+// Bucket holds the requested aggregates of the readings in
+// [Timestamp, Timestamp+bucket). Aggregates that were not requested are nil.
+type Bucket struct {
+	Timestamp int64    `json:"timestamp"`
+	Count     *int64   `json:"count,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Avg       *float64 `json:"avg,omitempty"`
+	First     *float64 `json:"first,omitempty"`
+	Last      *float64 `json:"last,omitempty"`
+	Stddev    *float64 `json:"stddev,omitempty"`
+}
+
+type bucketState struct {
+	start int64
+	count int64
+	min   float64
+	max   float64
+	first float64
+	last  float64
+	mean  float64
+	m2    float64
+}
+
+// ParseAggregates parses a comma separated list such as "min,max,avg".
+func ParseAggregates(list string) ([]string, error) {
+	if list == "" {
+		return []string{AVG}, nil
+	}
+
+	var parsed []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !aggregates[name] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAggregate, name)
+		}
+		parsed = append(parsed, name)
+	}
+	return parsed, nil
+}
+
+// Aggregator folds readings, or rollups of a finer resolution, into buckets
+// aligned to the Unix epoch so the same instant always falls into the same
+// bucket regardless of the query window. Inputs must arrive in timestamp order.
+type Aggregator struct {
+	width  int64
+	funcs  []string
+	states []bucketState
+}
+
+func NewAggregator(bucket time.Duration, funcs []string) (*Aggregator, error) {
+	if bucket < time.Millisecond {
+		return nil, fmt.Errorf("%w: bucket must be at least 1ms", ErrInvalidAggregate)
+	}
+	return &Aggregator{width: bucket.Milliseconds(), funcs: funcs}, nil
+}
+
+// Aggregate consumes an iterator and returns one bucket per interval that has
+// readings.
+func Aggregate(ctx context.Context, it *Iterator, bucket time.Duration, funcs []string) ([]Bucket, error) {
+	agg, err := NewAggregator(bucket, funcs)
+	if err != nil {
+		return nil, err
+	}
+	for it.Next(ctx) {
+		agg.Add(it.Reading())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return agg.Buckets(), nil
+}
+
+func (a *Aggregator) Add(reading types.Reading) {
+	a.state(reading.Timestamp).add(reading.Value)
+}
+
+func (a *Aggregator) AddRollup(rollup types.Rollup) {
+	if rollup.Count == 0 {
+		return
+	}
+	a.state(rollup.Timestamp).merge(rollup)
+}
+
+func (a *Aggregator) Buckets() []Bucket {
+	buckets := make([]Bucket, len(a.states))
+	for i := range a.states {
+		buckets[i] = a.states[i].bucket(a.funcs)
+	}
+	return buckets
+}
+
+// Rollups returns the buckets as rollups of the given property and resolution.
+func (a *Aggregator) Rollups(propertyID, resolution string) []types.Rollup {
+	rollups := make([]types.Rollup, len(a.states))
+	for i, s := range a.states {
+		rollups[i] = types.Rollup{
+			SeriesID:   SeriesID(propertyID, resolution),
+			PropertyID: propertyID,
+			Resolution: resolution,
+			Timestamp:  s.start,
+			Count:      s.count,
+			Min:        s.min,
+			Max:        s.max,
+			Avg:        s.mean,
+			First:      s.first,
+			Last:       s.last,
+			M2:         s.m2,
+		}
+	}
+	return rollups
+}
+
+func (a *Aggregator) state(timestamp int64) *bucketState {
+	start := timestamp - mod(timestamp, a.width)
+	if n := len(a.states); n > 0 && a.states[n-1].start == start {
+		return &a.states[n-1]
+	}
+	a.states = append(a.states, bucketState{start: start, min: math.Inf(1), max: math.Inf(-1)})
+	return &a.states[len(a.states)-1]
+}
+
+// Downsample reduces readings to at most threshold points with the
+// Largest-Triangle-Three-Buckets algorithm, which keeps the visual shape of a
+// series for charting.
+func Downsample(readings []types.Reading, threshold int) []types.Reading {
+	if threshold >= len(readings) || threshold <= 0 {
+		return readings
+	}
+	if threshold < 3 {
+		return []types.Reading{readings[0], readings[len(readings)-1]}[:threshold]
+	}
+
+	sampled := make([]types.Reading, 0, threshold)
+	sampled = append(sampled, readings[0])
+
+	every := float64(len(readings)-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		avgStart := int(math.Floor(float64(i+1)*every)) + 1
+		avgEnd := int(math.Floor(float64(i+2)*every)) + 1
+		if avgEnd > len(readings) {
+			avgEnd = len(readings)
+		}
+		var avgX, avgY float64
+		for _, r := range readings[avgStart:avgEnd] {
+			avgX += float64(r.Timestamp)
+			avgY += r.Value
+		}
+		avgX /= float64(avgEnd - avgStart)
+		avgY /= float64(avgEnd - avgStart)
+
+		rangeStart := int(math.Floor(float64(i)*every)) + 1
+		rangeEnd := int(math.Floor(float64(i+1)*every)) + 1
+		pointX, pointY := float64(readings[a].Timestamp), readings[a].Value
+
+		maxArea, next := -1.0, rangeStart
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((pointX-avgX)*(readings[j].Value-pointY) - (pointX-float64(readings[j].Timestamp))*(avgY-pointY))
+			if area > maxArea {
+				maxArea, next = area, j
+			}
+		}
+		sampled = append(sampled, readings[next])
+		a = next
+	}
+
+	return append(sampled, readings[len(readings)-1])
+}
+
+func (s *bucketState) add(value float64) {
+	if s.count == 0 {
+		s.first = value
+	}
+	s.count++
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+	s.last = value
+	delta := value - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (value - s.mean)
+}
+
+// merge combines a rollup into the state with Chan et al.'s parallel variance
+// update, so stddev over merged rollups matches stddev over the raw readings.
+func (s *bucketState) merge(r types.Rollup) {
+	if s.count == 0 {
+		s.first = r.First
+	}
+	n := s.count + r.Count
+	delta := r.Avg - s.mean
+	s.mean += delta * float64(r.Count) / float64(n)
+	s.m2 += r.M2 + delta*delta*float64(s.count)*float64(r.Count)/float64(n)
+	s.count = n
+	s.min = math.Min(s.min, r.Min)
+	s.max = math.Max(s.max, r.Max)
+	s.last = r.Last
+}
+
+func (s *bucketState) bucket(funcs []string) Bucket {
+	b := Bucket{Timestamp: s.start}
+	for _, name := range funcs {
+		switch name {
+		case COUNT:
+			count := s.count
+			b.Count = &count
+		case MIN:
+			b.Min = float(s.min)
+		case MAX:
+			b.Max = float(s.max)
+		case AVG:
+			b.Avg = float(s.mean)
+		case FIRST:
+			b.First = float(s.first)
+		case LAST:
+			b.Last = float(s.last)
+		case STDDEV:
+			b.Stddev = float(math.Sqrt(s.m2 / float64(s.count)))
+		}
+	}
+	return b
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func mod(a, b int64) int64 {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
@@ -0,0 +1,142 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func mockReadings(values map[int64]float64, order []int64) *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			items := make([]map[string]ddbtypes.AttributeValue, 0, len(order))
+			for _, ts := range order {
+				items = append(items, map[string]ddbtypes.AttributeValue{
+					"propertyId": &ddbtypes.AttributeValueMemberS{Value: "p1"},
+					"timestamp":  &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(ts, 10)},
+					"value":      &ddbtypes.AttributeValueMemberN{Value: strconv.FormatFloat(values[ts], 'f', -1, 64)},
+				})
+			}
+			return &dynamodb.QueryOutput{Items: items}, nil
+		},
+	}
+}
+
+func TestParseAggregates(t *testing.T) {
+	funcs, err := ParseAggregates("min, MAX,stddev")
+	if err != nil || len(funcs) != 3 || funcs[1] != MAX {
+		t.Errorf("Expected min, max and stddev, got %v %v", funcs, err)
+	}
+	if _, err = ParseAggregates("median"); !errors.Is(err, ErrInvalidAggregate) {
+		t.Errorf("Expected ErrInvalidAggregate for median, got %v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	values := map[int64]float64{0: 1, 30000: 3, 59999: 2, 60000: 10, 180000: 4}
+	order := []int64{0, 30000, 59999, 60000, 180000}
+	store := NewStore(mockReadings(values, order))
+
+	it := store.Iterate("p1", time.UnixMilli(0), time.UnixMilli(240000))
+	buckets, err := Aggregate(context.Background(), it, time.Minute, []string{MIN, MAX, AVG, FIRST, LAST, COUNT, STDDEV})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(buckets) != 3 {
+		t.Fatalf("Expected 3 non-empty buckets, got %d", len(buckets))
+	}
+	first := buckets[0]
+	if first.Timestamp != 0 || *first.Count != 3 || *first.Min != 1 || *first.Max != 3 || *first.Avg != 2 || *first.First != 1 || *first.Last != 2 {
+		t.Errorf("Unexpected first bucket %+v", first)
+	}
+	if math.Abs(*first.Stddev-math.Sqrt(2.0/3.0)) > 1e-9 {
+		t.Errorf("Expected population stddev of 1,3,2, got %f", *first.Stddev)
+	}
+	if buckets[1].Timestamp != 60000 || buckets[2].Timestamp != 180000 {
+		t.Errorf("Expected epoch aligned buckets at 60000 and 180000, got %d and %d", buckets[1].Timestamp, buckets[2].Timestamp)
+	}
+}
+
+func TestAggregate_OnlyRequested(t *testing.T) {
+	store := NewStore(mockReadings(map[int64]float64{0: 1}, []int64{0}))
+
+	buckets, err := Aggregate(context.Background(), store.Iterate("p1", time.UnixMilli(0), time.UnixMilli(1000)), time.Second, []string{MAX})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buckets[0].Max == nil || buckets[0].Min != nil || buckets[0].Count != nil {
+		t.Errorf("Expected only max to be set, got %+v", buckets[0])
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	readings := make([]types.Reading, 1000)
+	for i := range readings {
+		readings[i] = types.Reading{Timestamp: int64(i), Value: math.Sin(float64(i) / 50)}
+	}
+	readings[500].Value = 100
+
+	sampled := Downsample(readings, 50)
+	if len(sampled) != 50 {
+		t.Fatalf("Expected 50 points, got %d", len(sampled))
+	}
+	if sampled[0] != readings[0] || sampled[49] != readings[999] {
+		t.Errorf("Expected the first and last readings to be kept")
+	}
+
+	spike := false
+	for i, r := range sampled {
+		if i > 0 && r.Timestamp <= sampled[i-1].Timestamp {
+			t.Fatalf("Expected increasing timestamps")
+		}
+		spike = spike || r.Value == 100
+	}
+	if !spike {
+		t.Errorf("Expected the spike to survive downsampling")
+	}
+
+	if len(Downsample(readings[:10], 50)) != 10 {
+		t.Errorf("Expected short series to be returned unchanged")
+	}
+}
+
+func TestAggregator_AddRollup(t *testing.T) {
+	values := []float64{4, 8, 15, 16, 23, 42}
+
+	minutes, _ := NewAggregator(time.Minute, nil)
+	for i, v := range values {
+		minutes.Add(types.Reading{Timestamp: int64(i) * 20000, Value: v})
+	}
+	rollups := minutes.Rollups("p1", "1m")
+	if len(rollups) != 2 || rollups[0].SeriesID != "p1#1m" || rollups[1].Timestamp != 60000 {
+		t.Fatalf("Expected two minute rollups, got %+v", rollups)
+	}
+
+	hours, _ := NewAggregator(time.Hour, []string{COUNT, MIN, MAX, AVG, FIRST, LAST, STDDEV})
+	for _, r := range rollups {
+		hours.AddRollup(r)
+	}
+	merged := hours.Buckets()[0]
+
+	raw, _ := NewAggregator(time.Hour, []string{COUNT, MIN, MAX, AVG, FIRST, LAST, STDDEV})
+	for i, v := range values {
+		raw.Add(types.Reading{Timestamp: int64(i) * 20000, Value: v})
+	}
+	expected := raw.Buckets()[0]
+
+	if *merged.Count != 6 || *merged.Min != 4 || *merged.Max != 42 || *merged.First != 4 || *merged.Last != 42 {
+		t.Errorf("Unexpected merged bucket %+v", merged)
+	}
+	if math.Abs(*merged.Avg-*expected.Avg) > 1e-9 || math.Abs(*merged.Stddev-*expected.Stddev) > 1e-9 {
+		t.Errorf("Expected merged avg %f and stddev %f, got %f and %f", *expected.Avg, *expected.Stddev, *merged.Avg, *merged.Stddev)
+	}
+}
@@ -0,0 +1,137 @@
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// pager pages through the items of a DynamoDB query.
+type pager[T any] struct {
+	db      types.DynamoDBClient
+	input   *dynamodb.QueryInput
+	page    []T
+	pos     int
+	current T
+	done    bool
+	err     error
+}
+
+// Iterator pages through the readings of one property in timestamp order.
+type Iterator struct {
+	pager[types.Reading]
+}
+
+// Iterate returns an iterator over the readings of a property in [start, end).
+func (s Store) Iterate(propertyID string, start, end time.Time) *Iterator {
+	return &Iterator{pager[types.Reading]{
+		db:    s.DynamoDB,
+		input: rangeQuery(TABLENAME, "propertyId", propertyID, start, end),
+	}}
+}
+
+// Query collects every reading of a property in [start, end).
+func (s Store) Query(ctx context.Context, propertyID string, start, end time.Time) ([]types.Reading, error) {
+	var readings []types.Reading
+	it := s.Iterate(propertyID, start, end)
+	for it.Next(ctx) {
+		readings = append(readings, it.Reading())
+	}
+	return readings, it.Err()
+}
+
+// Latest returns the most recent reading of a property, or nil when it has
+// none.
+func (s Store) Latest(ctx context.Context, propertyID string) (*types.Reading, error) {
+	return s.first(ctx, propertyID, false)
+}
+
+// Earliest returns the oldest reading of a property, or nil when it has none.
+func (s Store) Earliest(ctx context.Context, propertyID string) (*types.Reading, error) {
+	return s.first(ctx, propertyID, true)
+}
+
+func (s Store) first(ctx context.Context, propertyID string, forward bool) (*types.Reading, error) {
+	result, err := s.DynamoDB.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(TABLENAME),
+		KeyConditionExpression: aws.String("propertyId = :propertyId"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":propertyId": &ddbtypes.AttributeValueMemberS{Value: propertyID},
+		},
+		ScanIndexForward: aws.Bool(forward),
+		Limit:            aws.Int32(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", TABLENAME, err)
+	}
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+
+	var reading types.Reading
+	if err = wrappers.UnmarshalMap(result.Items[0], &reading); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", TABLENAME, err)
+	}
+	return &reading, nil
+}
+
+func (it *Iterator) Reading() types.Reading {
+	return it.current
+}
+
+func (p *pager[T]) Next(ctx context.Context) bool {
+	for p.pos >= len(p.page) {
+		if p.done || p.err != nil {
+			return false
+		}
+		p.fetch(ctx)
+	}
+	p.current = p.page[p.pos]
+	p.pos++
+	return true
+}
+
+func (p *pager[T]) Err() error {
+	return p.err
+}
+
+func (p *pager[T]) fetch(ctx context.Context) {
+	table := aws.ToString(p.input.TableName)
+	result, err := p.db.Query(ctx, p.input)
+	if err != nil {
+		p.err = fmt.Errorf("error querying %s: %w", table, err)
+		return
+	}
+
+	p.page = p.page[:0]
+	p.pos = 0
+	if err = wrappers.UnmarshalListOfMaps(result.Items, &p.page); err != nil {
+		p.err = fmt.Errorf("failed to unmarshal %s: %w", table, err)
+		return
+	}
+
+	p.input.ExclusiveStartKey = result.LastEvaluatedKey
+	p.done = len(result.LastEvaluatedKey) == 0
+}
+
+func rangeQuery(table, keyName, keyValue string, start, end time.Time) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName:              aws.String(table),
+		KeyConditionExpression: aws.String(keyName + " = :" + keyName + " AND #timestamp BETWEEN :start AND :end"),
+		ExpressionAttributeNames: map[string]string{
+			"#timestamp": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":" + keyName: &ddbtypes.AttributeValueMemberS{Value: keyValue},
+			":start":      &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(start.UnixMilli(), 10)},
+			":end":        &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(end.UnixMilli()-1, 10)},
+		},
+	}
+}
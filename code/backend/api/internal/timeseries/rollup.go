@@ -0,0 +1,68 @@
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const ROLLUPTABLE = "ReadingRollup"
+
+// RollupIterator pages through the rollups of one property and resolution in
+// timestamp order.
+type RollupIterator struct {
+	pager[types.Rollup]
+}
+
+// SeriesID is the partition key of the rollups of a property at a resolution.
+func SeriesID(propertyID, resolution string) string {
+	return propertyID + "#" + resolution
+}
+
+// IterateRollups returns an iterator over the rollups in [start, end).
+func (s Store) IterateRollups(propertyID, resolution string, start, end time.Time) *RollupIterator {
+	return &RollupIterator{pager[types.Rollup]{
+		db:    s.DynamoDB,
+		input: rangeQuery(ROLLUPTABLE, "seriesId", SeriesID(propertyID, resolution), start, end),
+	}}
+}
+
+func (it *RollupIterator) Rollup() types.Rollup {
+	return it.current
+}
+
+func (s Store) WriteRollups(ctx context.Context, rollups []types.Rollup) error {
+	return putItems(ctx, s, ROLLUPTABLE, rollups)
+}
+
+// LatestRollup returns the most recent rollup of a property at a resolution,
+// or nil when none has been written yet.
+func (s Store) LatestRollup(ctx context.Context, propertyID, resolution string) (*types.Rollup, error) {
+	result, err := s.DynamoDB.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(ROLLUPTABLE),
+		KeyConditionExpression: aws.String("seriesId = :seriesId"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":seriesId": &ddbtypes.AttributeValueMemberS{Value: SeriesID(propertyID, resolution)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", ROLLUPTABLE, err)
+	}
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+
+	var rollup types.Rollup
+	if err = wrappers.UnmarshalMap(result.Items[0], &rollup); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", ROLLUPTABLE, err)
+	}
+	return &rollup, nil
+}
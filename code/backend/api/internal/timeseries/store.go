@@ -0,0 +1,118 @@
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	TABLENAME  = "Reading"
+	BATCHSIZE  = 25
+	MAXRETRIES = 5
+)
+
+type Store struct {
+	DynamoDB types.DynamoDBClient
+}
+
+func NewStore(db types.DynamoDBClient) *Store {
+	return &Store{
+		DynamoDB: db,
+	}
+}
+
+// Write stores readings with BatchWriteItem, splitting them into requests of at
+// most BATCHSIZE items and retrying whatever DynamoDB reports as unprocessed.
+func (s Store) Write(ctx context.Context, readings []types.Reading) error {
+	return putItems(ctx, s, TABLENAME, readings)
+}
+
+// Delete removes the readings of a property in [start, end) and returns how
+// many were deleted.
+func (s Store) Delete(ctx context.Context, propertyID string, start, end time.Time) (int64, error) {
+	it := s.Iterate(propertyID, start, end)
+	it.input.ProjectionExpression = aws.String("propertyId, #timestamp")
+
+	var deleted int64
+	requests := make([]ddbtypes.WriteRequest, 0, BATCHSIZE)
+	for it.Next(ctx) {
+		key, err := wrappers.MarshalMap(it.Reading())
+		if err != nil {
+			return deleted, fmt.Errorf("error marshalling reading key: %w", err)
+		}
+		delete(key, "value")
+		requests = append(requests, ddbtypes.WriteRequest{DeleteRequest: &ddbtypes.DeleteRequest{Key: key}})
+
+		if len(requests) == BATCHSIZE {
+			if err = s.writeBatch(ctx, TABLENAME, requests); err != nil {
+				return deleted, err
+			}
+			deleted += int64(len(requests))
+			requests = requests[:0]
+		}
+	}
+	if err := it.Err(); err != nil {
+		return deleted, err
+	}
+	if len(requests) > 0 {
+		if err := s.writeBatch(ctx, TABLENAME, requests); err != nil {
+			return deleted, err
+		}
+		deleted += int64(len(requests))
+	}
+	return deleted, nil
+}
+
+func putItems[T any](ctx context.Context, s Store, table string, items []T) error {
+	for start := 0; start < len(items); start += BATCHSIZE {
+		end := start + BATCHSIZE
+		if end > len(items) {
+			end = len(items)
+		}
+
+		requests := make([]ddbtypes.WriteRequest, 0, end-start)
+		for _, item := range items[start:end] {
+			av, err := wrappers.MarshalMap(item)
+			if err != nil {
+				return fmt.Errorf("error marshalling %s item: %w", table, err)
+			}
+			requests = append(requests, ddbtypes.WriteRequest{PutRequest: &ddbtypes.PutRequest{Item: av}})
+		}
+
+		if err := s.writeBatch(ctx, table, requests); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Store) writeBatch(ctx context.Context, table string, requests []ddbtypes.WriteRequest) error {
+	pending := map[string][]ddbtypes.WriteRequest{table: requests}
+
+	for attempt := 0; len(pending[table]) > 0; attempt++ {
+		if attempt > MAXRETRIES {
+			return fmt.Errorf("%d %s items left unprocessed after %d retries", len(pending[table]), table, MAXRETRIES)
+		}
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<attempt) * 25 * time.Millisecond):
+			}
+		}
+
+		output, err := s.DynamoDB.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("error writing %s batch: %w", table, err)
+		}
+		pending = output.UnprocessedItems
+	}
+	return nil
+}
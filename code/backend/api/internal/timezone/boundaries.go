@@ -0,0 +1,137 @@
+package timezone
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"wdd/api/internal/geo"
+)
+
+//go:generate go run gen.go combined-with-oceans.reduce.bin
+
+// boundaries.bin holds the land time zones of timezone-boundary-builder
+// (ODbL, derived from OpenStreetMap), rasterized by gen.go: the zone names,
+// then for every cell of a degree, row by row from the north west, a
+// quadtree in preorder. A node is SPLIT, followed by its north west, north
+// east, south west and south east quarters, or the index of its zone from 1,
+// with 0 for the sea.
+//
+//go:embed boundaries.bin
+var bundledBoundaries []byte
+
+const (
+	// MAXDEPTH is how many times a cell of a degree is split at most, so
+	// borders are accurate to 1/2^MAXDEPTH degree.
+	MAXDEPTH = 7
+	SPLIT    = 0xFFFF
+)
+
+// boundaries is the decoded grid; roots holds the offset in nodes of the
+// quadtree of every cell of a degree.
+type boundaries struct {
+	zones []string
+	nodes []uint16
+	roots []uint32
+}
+
+var (
+	boundariesOnce sync.Once
+	defaultGrid    *boundaries
+	boundariesErr  error
+)
+
+func defaultBoundaries() (*boundaries, error) {
+	boundariesOnce.Do(func() {
+		defaultGrid, boundariesErr = decodeBoundaries(bundledBoundaries)
+	})
+	return defaultGrid, boundariesErr
+}
+
+func decodeBoundaries(data []byte) (*boundaries, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) < 2 {
+		return nil, errors.New("truncated time zone boundaries")
+	}
+	b := &boundaries{zones: make([]string, binary.LittleEndian.Uint16(raw))}
+	raw = raw[2:]
+	for i := range b.zones {
+		if len(raw) < 1 || len(raw) < 1+int(raw[0]) {
+			return nil, errors.New("truncated time zone names")
+		}
+		b.zones[i], raw = string(raw[1:1+raw[0]]), raw[1+raw[0]:]
+	}
+	if len(raw)%2 != 0 {
+		return nil, errors.New("truncated time zone grid")
+	}
+	b.nodes = make([]uint16, len(raw)/2)
+	for i := range b.nodes {
+		b.nodes[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+
+	b.roots = make([]uint32, 360*180)
+	offset := 0
+	for i := range b.roots {
+		if offset >= len(b.nodes) {
+			return nil, errors.New("truncated time zone grid")
+		}
+		b.roots[i] = uint32(offset)
+		offset = b.skip(offset)
+	}
+	if offset != len(b.nodes) {
+		return nil, errors.New("invalid time zone grid")
+	}
+	return b, nil
+}
+
+// skip returns the offset of the node after the subtree at offset.
+func (b *boundaries) skip(offset int) int {
+	for pending := 1; pending > 0 && offset < len(b.nodes); pending-- {
+		if b.nodes[offset] == SPLIT {
+			pending += 4
+		}
+		offset++
+	}
+	return offset
+}
+
+// zone returns the land zone a point is in, or "" at sea.
+func (b *boundaries) zone(p geo.Point) string {
+	// Distances from the north west corner of the world in degrees.
+	x := math.Max(0, math.Min(p.Lon+180, 360-1e-9))
+	y := math.Max(0, math.Min(90-p.Lat, 180-1e-9))
+	col, row := int(x), int(y)
+	x, y = x-float64(col), y-float64(row)
+
+	offset := int(b.roots[row*360+col])
+	for b.nodes[offset] == SPLIT {
+		quarter := 0
+		if x >= 0.5 {
+			quarter, x = quarter+1, x-0.5
+		}
+		if y >= 0.5 {
+			quarter, y = quarter+2, y-0.5
+		}
+		x, y = 2*x, 2*y
+		offset++
+		for i := 0; i < quarter; i++ {
+			offset = b.skip(offset)
+		}
+	}
+	if zone := b.nodes[offset]; zone > 0 && int(zone) <= len(b.zones) {
+		return b.zones[zone-1]
+	}
+	return ""
+}
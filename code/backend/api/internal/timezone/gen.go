@@ -0,0 +1,312 @@
+//go:build ignore
+
+// gen rasterizes the time zone polygons of timezone-boundary-builder into
+// boundaries.bin, the grid Of looks points up in. It reads the polygons as
+// published, simplified, by github.com/ringsaturn/tzf-rel-lite; copy its
+// combined-with-oceans.reduce.bin here and run go generate, or:
+//
+//	go run gen.go <path to combined-with-oceans.reduce.bin>
+//
+// Ocean zones are left out; Of gives the sea the nautical zone of its
+// longitude. Every cell of a degree is split in four until it holds at most
+// one land zone, down to cells of 1/2^MAXDEPTH degree, which take the zone
+// of their centre. A cell with one land zone and sea is wholly in that zone,
+// so coastlines need no splitting.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	MAXDEPTH = 7
+	SPLIT    = 0xFFFF
+)
+
+type segment struct {
+	x1, y1, x2, y2 float64
+	polygon        int
+}
+
+type polygon struct {
+	zone uint16
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: go run gen.go <combined-with-oceans.reduce.bin>")
+	}
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names, polygons, segments, version, err := decode(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Segments by the band of a degree of latitude they cross, from the
+	// north.
+	bands := make([][]segment, 180)
+	for _, s := range segments {
+		top := int(math.Floor(90 - math.Max(s.y1, s.y2)))
+		bottom := int(math.Floor(90 - math.Min(s.y1, s.y2)))
+		for b := clamp(top, 0, 179); b <= clamp(bottom, 0, 179); b++ {
+			bands[b] = append(bands[b], s)
+		}
+	}
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.LittleEndian, uint16(len(names)))
+	for _, name := range names {
+		out.WriteByte(byte(len(name)))
+		out.WriteString(name)
+	}
+
+	side := 1 << MAXDEPTH
+	step := 1 / float64(side)
+	raster := make([][]uint16, side)
+	for r := range raster {
+		raster[r] = make([]uint16, 360*side)
+	}
+	nodes := 0
+	for band := 0; band < 180; band++ {
+		for r := 0; r < side; r++ {
+			lat := 90 - float64(band) - (float64(r)+0.5)*step
+			fill(raster[r], bands[band], polygons, lat, step)
+		}
+		for col := 0; col < 360; col++ {
+			tree := build(raster, 0, col*side, side)
+			nodes += write(&out, tree)
+		}
+	}
+
+	var compressed bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	w.Comment = "timezone-boundary-builder " + version
+	if _, err := w.Write(out.Bytes()); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("boundaries.bin", compressed.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d zones of %s in %d nodes, %d bytes", len(names), version, nodes, compressed.Len())
+}
+
+// fill sets the cells of a row whose centres lie inside a polygon to its
+// zone, counting crossings even-odd so holes stay out.
+func fill(row []uint16, segments []segment, polygons []polygon, lat, step float64) {
+	for i := range row {
+		row[i] = 0
+	}
+	crossings := map[int][]float64{}
+	for _, s := range segments {
+		if (s.y1 > lat) == (s.y2 > lat) {
+			continue
+		}
+		x := s.x1 + (lat-s.y1)*(s.x2-s.x1)/(s.y2-s.y1)
+		crossings[s.polygon] = append(crossings[s.polygon], x)
+	}
+	for p, xs := range crossings {
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			first := int(math.Ceil((xs[i]+180)/step - 0.5))
+			last := int(math.Ceil((xs[i+1]+180)/step-0.5)) - 1
+			for c := clamp(first, 0, len(row)-1); c <= clamp(last, 0, len(row)-1) && c >= first; c++ {
+				row[c] = polygons[p].zone
+			}
+		}
+	}
+}
+
+// node is a cell of the grid: a zone, or four quarters when it is split.
+type node struct {
+	zone     uint16
+	quarters []node
+}
+
+// build merges the cells of the raster from row, col of size cells a side.
+func build(raster [][]uint16, row, col, size int) node {
+	if size == 1 {
+		return node{zone: raster[row][col]}
+	}
+	half := size / 2
+	quarters := []node{
+		build(raster, row, col, half),
+		build(raster, row, col+half, half),
+		build(raster, row+half, col, half),
+		build(raster, row+half, col+half, half),
+	}
+	zone := uint16(0)
+	for _, q := range quarters {
+		if q.quarters != nil || q.zone != 0 && zone != 0 && q.zone != zone {
+			return node{quarters: quarters}
+		}
+		if q.zone != 0 {
+			zone = q.zone
+		}
+	}
+	return node{zone: zone}
+}
+
+func write(out *bytes.Buffer, n node) int {
+	if n.quarters == nil {
+		binary.Write(out, binary.LittleEndian, n.zone)
+		return 1
+	}
+	binary.Write(out, binary.LittleEndian, uint16(SPLIT))
+	count := 1
+	for _, q := range n.quarters {
+		count += write(out, q)
+	}
+	return count
+}
+
+// decode reads the Timezones protocol buffer of tzf:
+//
+//	message Point { float lng = 1; float lat = 2; }
+//	message Polygon { repeated Point points = 1; repeated Polygon holes = 2; }
+//	message Timezone { repeated Polygon polygons = 1; string name = 2; }
+//	message Timezones { repeated Timezone timezones = 1; bool reduced = 2; string version = 3; }
+func decode(data []byte) ([]string, []polygon, []segment, string, error) {
+	var (
+		names    []string
+		polygons []polygon
+		segments []segment
+		version  string
+	)
+	err := fields(data, func(field int, value []byte) error {
+		switch field {
+		case 3:
+			version = string(value)
+		case 1:
+			var name string
+			var all [][][]float64
+			err := fields(value, func(field int, value []byte) error {
+				switch field {
+				case 2:
+					name = string(value)
+				case 1:
+					polygon, err := rings(value)
+					if err != nil {
+						return err
+					}
+					all = append(all, polygon...)
+					all = append(all, nil)
+				}
+				return nil
+			})
+			if err != nil || strings.HasPrefix(name, "Etc/") {
+				return err
+			}
+			names = append(names, name)
+			zone := uint16(len(names))
+			polygons = append(polygons, polygon{zone: zone})
+			for _, ring := range all {
+				if ring == nil {
+					// The end of a polygon and its holes.
+					polygons = append(polygons, polygon{zone: zone})
+					continue
+				}
+				for i := range ring {
+					a, b := ring[i], ring[(i+1)%len(ring)]
+					segments = append(segments, segment{a[0], a[1], b[0], b[1], len(polygons) - 1})
+				}
+			}
+			polygons = polygons[:len(polygons)-1]
+		}
+		return nil
+	})
+	return names, polygons, segments, version, err
+}
+
+// rings returns the outer ring of a polygon and its holes.
+func rings(data []byte) ([][][]float64, error) {
+	var outer [][]float64
+	var holes [][][]float64
+	err := fields(data, func(field int, value []byte) error {
+		switch field {
+		case 1:
+			point := make([]float64, 2)
+			err := fields(value, func(field int, value []byte) error {
+				if len(value) != 4 || field < 1 || field > 2 {
+					return errors.New("invalid point")
+				}
+				point[field-1] = float64(math.Float32frombits(binary.LittleEndian.Uint32(value)))
+				return nil
+			})
+			outer = append(outer, point)
+			return err
+		case 2:
+			hole, err := rings(value)
+			if err != nil {
+				return err
+			}
+			holes = append(holes, hole...)
+		}
+		return nil
+	})
+	return append([][][]float64{outer}, holes...), err
+}
+
+// fields calls fn with every length-delimited or 32-bit field of a message.
+func fields(data []byte, fn func(field int, value []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("invalid varint")
+			}
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return errors.New("invalid length")
+			}
+			if err := fn(field, data[n:n+int(length)]); err != nil {
+				return err
+			}
+			data = data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return errors.New("invalid fixed32")
+			}
+			if err := fn(field, data[:4]); err != nil {
+				return err
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&7)
+		}
+	}
+	return nil
+}
+
+func clamp(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
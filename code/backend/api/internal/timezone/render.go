@@ -0,0 +1,57 @@
+package timezone
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+	"wdd/api/internal/wrappers"
+)
+
+// LOCALFORMAT renders local times with milliseconds and the zone offset.
+const LOCALFORMAT = "2006-01-02T15:04:05.000Z07:00"
+
+// timestampFields hold Unix millisecond timestamps in API responses.
+var timestampFields = []string{"timestamp", "start", "end"}
+
+// Render rewrites a JSON response body for a location. Every Unix
+// millisecond field in timestampFields keeps its value and gains a
+// "<field>Local" sibling with the local time, and RFC 3339 dateCreated
+// fields are given in local time.
+func Render(body []byte, location *time.Location) ([]byte, error) {
+	if location == nil {
+		return body, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return wrappers.JSONMarshal(render(value, location))
+}
+
+func render(value interface{}, location *time.Location) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = render(field, location)
+		}
+		for _, key := range timestampFields {
+			if number, ok := v[key].(json.Number); ok {
+				if ms, err := number.Int64(); err == nil {
+					v[key+"Local"] = time.UnixMilli(ms).In(location).Format(LOCALFORMAT)
+				}
+			}
+		}
+		if created, ok := v["dateCreated"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, created); err == nil {
+				v["dateCreated"] = t.In(location).Format(time.RFC3339)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = render(item, location)
+		}
+	}
+	return value
+}
@@ -0,0 +1,119 @@
+package timezone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+
+	// Lambda images do not ship a zoneinfo database, so embed one.
+	_ "time/tzdata"
+)
+
+// FACTORY names the zone of the factory a request is about.
+const FACTORY = "factory"
+
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// Of returns the IANA time zone of a point: the land zone whose boundary
+// contains it, or else, at sea, the nautical zone of its longitude.
+func Of(p geo.Point) string {
+	if b, err := defaultBoundaries(); err == nil {
+		if zone := b.zone(p); zone != "" {
+			if _, err := time.LoadLocation(zone); err == nil {
+				return zone
+			}
+		}
+	}
+	return nautical(p.Lon)
+}
+
+// nautical returns the Etc zone 15 degrees of longitude wide around lon,
+// whose POSIX-style name inverts the sign of the offset.
+func nautical(lon float64) string {
+	offset := int(math.Round(lon / 15))
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("Etc/GMT-%d", offset)
+	case offset < 0:
+		return fmt.Sprintf("Etc/GMT+%d", -offset)
+	}
+	return "Etc/GMT"
+}
+
+// Validate checks that a zone is in the embedded zoneinfo database.
+func Validate(zone string) error {
+	if zone == "" || zone == "Local" {
+		return fmt.Errorf("%w: %q is not an IANA time zone", ErrInvalidTimezone, zone)
+	}
+	if _, err := time.LoadLocation(zone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidTimezone, zone)
+	}
+	return nil
+}
+
+// Locate sets the timezone of a factory from its location, unless it
+// already has one. A factory without a location is left as it is.
+func Locate(factory *types.Factory) {
+	if factory.Timezone != nil && *factory.Timezone != "" {
+		return
+	}
+	if p, ok := geo.LocationPoint(factory.Location); ok {
+		zone := Of(p)
+		factory.Timezone = &zone
+	}
+}
+
+// LocateCalendar sets the timezone of a calendar without one of its own to
+// that of its factory, whose shifts then run in the factory's zone.
+func LocateCalendar(ctx context.Context, c *catalog.Catalog, cal *types.Calendar) error {
+	if cal.Timezone != nil && *cal.Timezone != "" {
+		return nil
+	}
+	factory, err := c.Factory(ctx, cal.FactoryID)
+	if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+		return err
+	}
+	Locate(&factory)
+	cal.Timezone = factory.Timezone
+	return nil
+}
+
+// Load returns the location a response asks to be rendered in: an IANA
+// zone, or FACTORY for the zone of factoryID. It returns nil when no zone is
+// asked for.
+func Load(ctx context.Context, c *catalog.Catalog, zone, factoryID string) (*time.Location, error) {
+	if zone != FACTORY {
+		return ForFactory(zone, types.Factory{})
+	}
+	if factoryID == "" {
+		return nil, fmt.Errorf("%w: %s needs a factoryId", ErrInvalidTimezone, FACTORY)
+	}
+	factory, err := c.Factory(ctx, factoryID)
+	if err != nil {
+		return nil, err
+	}
+	return ForFactory(zone, factory)
+}
+
+// ForFactory is Load for a factory already read.
+func ForFactory(zone string, factory types.Factory) (*time.Location, error) {
+	switch zone {
+	case "":
+		return nil, nil
+	case FACTORY:
+		Locate(&factory)
+		if factory.Timezone == nil {
+			return nil, fmt.Errorf("%w: factory %s has no location", ErrInvalidTimezone, factory.FactoryID)
+		}
+		zone = *factory.Timezone
+	}
+	if err := Validate(zone); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(zone)
+}
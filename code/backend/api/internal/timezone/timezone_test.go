@@ -0,0 +1,127 @@
+package timezone
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"wdd/api/internal/geo"
+	"wdd/api/internal/types"
+)
+
+func TestOf(t *testing.T) {
+	cases := map[string]geo.Point{
+		"Europe/Berlin":   {Lat: 48.8, Lon: 9.2},
+		"America/Detroit": {Lat: 42.35, Lon: -83.1},
+		"Asia/Tokyo":      {Lat: 35.7, Lon: 139.7},
+		"Etc/GMT+2":       {Lat: 0, Lon: -30},
+		"Etc/GMT-12":      {Lat: -50, Lon: 179.9},
+		"Etc/GMT":         {Lat: -60, Lon: 5},
+	}
+	for expected, p := range cases {
+		if zone := Of(p); zone != expected {
+			t.Errorf("Expected %s for %+v, got %s", expected, p, zone)
+		}
+	}
+}
+
+func TestOf_Borders(t *testing.T) {
+	cases := []struct {
+		place    string
+		point    geo.Point
+		expected string
+	}{
+		// Capitals nearer another zone's city than any of their own.
+		{"Helsinki", geo.Point{Lat: 60.17, Lon: 24.94}, "Europe/Helsinki"},
+		{"Lisbon", geo.Point{Lat: 38.72, Lon: -9.14}, "Europe/Lisbon"},
+		{"Athens", geo.Point{Lat: 37.98, Lon: 23.73}, "Europe/Athens"},
+		{"Kyiv", geo.Point{Lat: 50.45, Lon: 30.52}, "Europe/Kyiv"},
+		{"Indianapolis", geo.Point{Lat: 39.77, Lon: -86.16}, "America/Indiana/Indianapolis"},
+		{"Gary", geo.Point{Lat: 41.59, Lon: -87.35}, "America/Chicago"},
+		// Towns a few kilometres apart across a border.
+		{"Strasbourg", geo.Point{Lat: 48.5734, Lon: 7.7521}, "Europe/Paris"},
+		{"Kehl", geo.Point{Lat: 48.5727, Lon: 7.8155}, "Europe/Berlin"},
+		{"Tornio", geo.Point{Lat: 65.8481, Lon: 24.1466}, "Europe/Helsinki"},
+		{"Haparanda", geo.Point{Lat: 65.8355, Lon: 24.1365}, "Europe/Stockholm"},
+		{"Detroit", geo.Point{Lat: 42.35, Lon: -83.1}, "America/Detroit"},
+		{"Windsor", geo.Point{Lat: 42.3, Lon: -83.0}, "America/Toronto"},
+		{"El Paso", geo.Point{Lat: 31.76, Lon: -106.49}, "America/Denver"},
+		{"Ciudad Juárez", geo.Point{Lat: 31.69, Lon: -106.42}, "America/Ciudad_Juarez"},
+		{"San Diego", geo.Point{Lat: 32.72, Lon: -117.16}, "America/Los_Angeles"},
+		{"Tijuana", geo.Point{Lat: 32.53, Lon: -117.04}, "America/Tijuana"},
+		{"Kaliningrad", geo.Point{Lat: 54.71, Lon: 20.51}, "Europe/Kaliningrad"},
+	}
+	for _, c := range cases {
+		if zone := Of(c.point); zone != c.expected {
+			t.Errorf("Expected %s for %s, got %s", c.expected, c.place, zone)
+		}
+	}
+}
+
+func TestDecodeBoundaries(t *testing.T) {
+	b, err := defaultBoundaries()
+	if err != nil {
+		t.Fatalf("Expected the bundled boundaries to decode, got %v", err)
+	}
+	if len(b.zones) < 400 || len(b.roots) != 360*180 {
+		t.Errorf("Expected over 400 zones in cells of a degree, got %d zones in %d cells", len(b.zones), len(b.roots))
+	}
+	for _, p := range []geo.Point{{Lat: 90, Lon: 180}, {Lat: -90, Lon: -180}, {Lat: 91, Lon: 200}} {
+		b.zone(p)
+	}
+
+	if _, err := decodeBoundaries([]byte("not gzip")); err == nil {
+		t.Errorf("Expected an error for invalid boundaries")
+	}
+}
+
+func TestLocate(t *testing.T) {
+	lat, lon := 48.8, 9.2
+	factory := types.Factory{Location: &types.Location{Latitude: &lat, Longitude: &lon}}
+	Locate(&factory)
+	if factory.Timezone == nil || *factory.Timezone != "Europe/Berlin" {
+		t.Errorf("Expected Europe/Berlin, got %v", factory.Timezone)
+	}
+
+	given := "Europe/Paris"
+	factory.Timezone = &given
+	Locate(&factory)
+	if *factory.Timezone != given {
+		t.Errorf("Expected a given timezone to be kept, got %s", *factory.Timezone)
+	}
+
+	if location, err := ForFactory(FACTORY, types.Factory{FactoryID: "f1"}); !errors.Is(err, ErrInvalidTimezone) {
+		t.Errorf("Expected ErrInvalidTimezone for a factory without a location, got %v %v", location, err)
+	}
+	for _, zone := range []string{"Local", "Mars/Olympus_Mons"} {
+		if err := Validate(zone); !errors.Is(err, ErrInvalidTimezone) {
+			t.Errorf("Expected ErrInvalidTimezone for %s, got %v", zone, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	location, err := ForFactory("Asia/Tokyo", types.Factory{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body := `{"start":1704067200000,"readings":[{"timestamp":1704070800123,"value":1.5}],"dateCreated":"2024-01-01T00:00:00Z","name":"end"}`
+	rendered, err := Render([]byte(body), location)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{
+		`"startLocal":"2024-01-01T09:00:00.000+09:00"`,
+		`"timestampLocal":"2024-01-01T10:00:00.123+09:00"`,
+		`"timestamp":1704070800123`,
+		`"dateCreated":"2024-01-01T09:00:00+09:00"`,
+		`"value":1.5`,
+	} {
+		if !strings.Contains(string(rendered), expected) {
+			t.Errorf("Expected %s in %s", expected, rendered)
+		}
+	}
+
+	if same, _ := Render([]byte(body), nil); string(same) != body {
+		t.Errorf("Expected no zone to leave the body as it is, got %s", same)
+	}
+}
@@ -9,10 +9,20 @@ type Factory struct {
 	FactoryID   string    `json:"factoryId" dynamodbav:"factoryId"`
 	Name        *string   `json:"name,omitempty" dynamodbav:"name"`
 	Location    *Location `json:"location,omitempty" dynamodbav:"location"`
+	Address     *string   `json:"address,omitempty" dynamodbav:"address"`
 	Description *string   `json:"description,omitempty" dynamodbav:"description"`
 	DateCreated string    `json:"dateCreated" dynamodbav:"Date Created"`
+	// Timezone is the IANA zone of the factory, derived from Location
+	// unless given.
+	Timezone *string `json:"timezone,omitempty" dynamodbav:"timezone"`
+	// Geohash and GeohashKey index the location for spatial queries; both
+	// are derived from Location and left out when it is incomplete.
+	Geohash    string `json:"geohash,omitempty" dynamodbav:"geohash,omitempty"`
+	GeohashKey string `json:"-" dynamodbav:"geohashKey,omitempty"`
 }
 
+// FloorplanCoords is a pixel position on a floorplan image: Longitude is
+// the x offset from the left edge and Latitude the y offset from the top.
 type FloorplanCoords struct {
 	Longitude *float64 `json:"longitude,omitempty" dynamodbav:"longitude"`
 	Latitude  *float64 `json:"latitude,omitempty" dynamodbav:"latitude"`
@@ -31,6 +41,17 @@ type Asset struct {
 	Type            *string              `json:"type,omitempty" dynamodbav:"type"`
 	Description     *string              `json:"description,omitempty" dynamodbav:"description"`
 	Attributes      map[string]Attribute `json:"attributes,omitempty" dynamodbav:"attributes"`
+	// PositionMeters is FloorplanCoords on the calibrated shop floor. It is
+	// derived when the asset is read and never stored.
+	PositionMeters *Position `json:"positionMeters,omitempty" dynamodbav:"-"`
+	// Zones are the IDs of the zones of its floorplan the asset sits in,
+	// also derived when it is read.
+	Zones []string `json:"zones,omitempty" dynamodbav:"-"`
+	// FloorplanVersion is the version of the floorplan image FloorplanCoords
+	// refer to. Publishing a new image moves the asset onto it. Moving the
+	// asset clears it, and then the position refers to the floorplan's
+	// current version.
+	FloorplanVersion *int `json:"floorplanVersion,omitempty" dynamodbav:"floorplanVersion,omitempty"`
 }
 
 type Attribute struct {
@@ -43,6 +64,60 @@ type Floorplan struct {
 	FactoryID   string `json:"factoryId" dynamodbav:"factoryId"`
 	DateCreated string `json:"dateCreated" dynamodbav:"dateCreated"`
 	ImageData   string `json:"imageData" dynamodbav:"imageData"`
+	// Level is the floor of the factory the plan shows, 0 for the ground
+	// floor.
+	Level *int    `json:"level,omitempty" dynamodbav:"level,omitempty"`
+	Name  *string `json:"name,omitempty" dynamodbav:"name,omitempty"`
+	// Version counts the images published for the floorplan, from 1.
+	// Replacing the image publishes a new version and keeps the old ones.
+	Version int `json:"version" dynamodbav:"version"`
+	// Width and Height are the pixel dimensions of the image, which bound the
+	// FloorplanCoords of its assets.
+	Width       *int         `json:"width,omitempty" dynamodbav:"width,omitempty"`
+	Height      *int         `json:"height,omitempty" dynamodbav:"height,omitempty"`
+	Calibration *Calibration `json:"calibration,omitempty" dynamodbav:"calibration,omitempty"`
+}
+
+// Position is a point in a plane: pixels of a floorplan image, with y
+// growing down, or meters on the shop floor, with y growing up.
+type Position struct {
+	X float64 `json:"x" dynamodbav:"x"`
+	Y float64 `json:"y" dynamodbav:"y"`
+}
+
+// Calibration maps the pixels of a floorplan image to meters on the shop
+// floor, either by two reference points whose floor positions are known or
+// by a scale, a rotation and the pixel at the origin. GeoOrigin and
+// BearingDegrees optionally place the floor on the globe.
+type Calibration struct {
+	References      []CalibrationReference `json:"references,omitempty" dynamodbav:"references,omitempty"`
+	MetersPerPixel  *float64               `json:"metersPerPixel,omitempty" dynamodbav:"metersPerPixel,omitempty"`
+	RotationDegrees *float64               `json:"rotationDegrees,omitempty" dynamodbav:"rotationDegrees,omitempty"`
+	Origin          *Position              `json:"origin,omitempty" dynamodbav:"origin,omitempty"`
+	GeoOrigin       *Location              `json:"geoOrigin,omitempty" dynamodbav:"geoOrigin,omitempty"`
+	BearingDegrees  *float64               `json:"bearingDegrees,omitempty" dynamodbav:"bearingDegrees,omitempty"`
+}
+
+// CalibrationReference is a pixel of a floorplan image and the position in
+// meters of the spot it shows.
+type CalibrationReference struct {
+	Pixel  Position `json:"pixel" dynamodbav:"pixel"`
+	Meters Position `json:"meters" dynamodbav:"meters"`
+}
+
+// Zone is a named region of a floorplan, such as a production line, a
+// work cell or a hazard area, outlined by a polygon in the pixels of the
+// floorplan image.
+type Zone struct {
+	ZoneID      string `json:"zoneId" dynamodbav:"zoneId"`
+	FloorplanID string `json:"floorplanId" dynamodbav:"floorplanId"`
+	Name        string `json:"name" dynamodbav:"name"`
+	// Type is free-form, for example "area", "line" or "cell".
+	Type    *string    `json:"type,omitempty" dynamodbav:"type,omitempty"`
+	Polygon []Position `json:"polygon" dynamodbav:"polygon"`
+	// FloorplanVersion is the version of the floorplan image Polygon refers
+	// to, like the FloorplanVersion of an asset.
+	FloorplanVersion int `json:"floorplanVersion,omitempty" dynamodbav:"floorplanVersion,omitempty"`
 }
 
 type Model struct {
@@ -68,6 +143,93 @@ type Measurement struct {
 	LowerBound        *float64 `json:"lowerBound,omitempty" dynamodbav:"lowerBound"`
 	UpperBound        *float64 `json:"upperBound,omitempty" dynamodbav:"upperBound"`
 	Precision         *float64 `json:"precision,omitempty" dynamodbav:"precision"`
+	AngularFrequency  *float64 `json:"angularFrequency,omitempty" dynamodbav:"angularFrequency"`
+	Amplitude         *float64 `json:"amplitude,omitempty" dynamodbav:"amplitude"`
+	Phase             *float64 `json:"phase,omitempty" dynamodbav:"phase"`
+}
+
+type Reading struct {
+	PropertyID string  `json:"propertyId" dynamodbav:"propertyId"`
+	Timestamp  int64   `json:"timestamp" dynamodbav:"timestamp"`
+	Value      float64 `json:"value" dynamodbav:"value"`
+	// Quality is an OPC-style code, a severity ("good", "uncertain" or
+	// "bad") optionally followed by a subcode, as in "bad/sensorFailure".
+	// Empty means good.
+	Quality string `json:"quality,omitempty" dynamodbav:"quality,omitempty"`
+}
+
+// Rollup summarises the readings of a property in [Timestamp, Timestamp+Resolution).
+// SeriesID is "<propertyId>#<resolution>"; ExpiresAt is the DynamoDB TTL in
+// Unix seconds and is unset for rollups that are kept forever.
+type Rollup struct {
+	SeriesID   string  `json:"-" dynamodbav:"seriesId"`
+	PropertyID string  `json:"propertyId" dynamodbav:"propertyId"`
+	Resolution string  `json:"resolution" dynamodbav:"resolution"`
+	Timestamp  int64   `json:"timestamp" dynamodbav:"timestamp"`
+	Count      int64   `json:"count" dynamodbav:"count"`
+	Min        float64 `json:"min" dynamodbav:"min"`
+	Max        float64 `json:"max" dynamodbav:"max"`
+	Avg        float64 `json:"avg" dynamodbav:"avg"`
+	First      float64 `json:"first" dynamodbav:"first"`
+	Last       float64 `json:"last" dynamodbav:"last"`
+	M2         float64 `json:"-" dynamodbav:"m2"`
+	ExpiresAt  *int64  `json:"-" dynamodbav:"expiresAt,omitempty"`
+}
+
+type RetentionPolicy struct {
+	FactoryID   string         `json:"factoryId" dynamodbav:"factoryId"`
+	RawDays     *int           `json:"rawDays,omitempty" dynamodbav:"rawDays"`
+	Rollups     []RollupPolicy `json:"rollups,omitempty" dynamodbav:"rollups"`
+	DateCreated string         `json:"dateCreated,omitempty" dynamodbav:"dateCreated"`
+}
+
+// RollupPolicy keeps rollups of the given resolution (a duration such as "1m")
+// for RetentionDays, or forever when RetentionDays is unset.
+type RollupPolicy struct {
+	Resolution    string `json:"resolution" dynamodbav:"resolution"`
+	RetentionDays *int   `json:"retentionDays,omitempty" dynamodbav:"retentionDays"`
+}
+
+// LineProtocolMapping tells line protocol ingestion which asset and property
+// a point's fields belong to. The asset is named by the AssetTag tag, or by
+// the measurement when the tag is missing, and matched against Assets, then
+// asset IDs, then asset names. Fields are looked up in Fields as
+// "<measurement>.<field>", then "<field>", and the result (or the field
+// name itself) is matched against the asset's property IDs, then names.
+type LineProtocolMapping struct {
+	FactoryID   string            `json:"factoryId" dynamodbav:"factoryId"`
+	AssetTag    string            `json:"assetTag,omitempty" dynamodbav:"assetTag,omitempty"`
+	Assets      map[string]string `json:"assets,omitempty" dynamodbav:"assets,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty" dynamodbav:"fields,omitempty"`
+	DateCreated string            `json:"dateCreated,omitempty" dynamodbav:"dateCreated"`
+}
+
+type Calendar struct {
+	FactoryID   string    `json:"factoryId" dynamodbav:"factoryId"`
+	Timezone    *string   `json:"timezone,omitempty" dynamodbav:"timezone"`
+	Shifts      []Shift   `json:"shifts,omitempty" dynamodbav:"shifts"`
+	Holidays    []Holiday `json:"holidays,omitempty" dynamodbav:"holidays"`
+	IdleLevel   *float64  `json:"idleLevel,omitempty" dynamodbav:"idleLevel"`
+	DateCreated string    `json:"dateCreated" dynamodbav:"dateCreated"`
+}
+
+type Shift struct {
+	Name   string       `json:"name" dynamodbav:"name"`
+	Days   []string     `json:"days" dynamodbav:"days"`
+	Start  string       `json:"start" dynamodbav:"start"`
+	End    string       `json:"end" dynamodbav:"end"`
+	Breaks []ShiftBreak `json:"breaks,omitempty" dynamodbav:"breaks"`
+}
+
+type ShiftBreak struct {
+	Name  string `json:"name,omitempty" dynamodbav:"name"`
+	Start string `json:"start" dynamodbav:"start"`
+	End   string `json:"end" dynamodbav:"end"`
+}
+
+type Holiday struct {
+	Date string `json:"date" dynamodbav:"date"`
+	Name string `json:"name,omitempty" dynamodbav:"name"`
 }
 
 type User struct {
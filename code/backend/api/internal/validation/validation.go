@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"wdd/api/internal/types"
+	"wdd/api/internal/wrappers"
+	"wdd/api/internal/zone"
+)
+
+// COORDINATEDECIMALS is the precision locations are rounded to, about 1cm.
+const COORDINATEDECIMALS = 7
+
+var ErrInvalid = errors.New("validation failed")
+
+// Violation is a field that failed validation.
+type Violation struct {
+	Field  string   `json:"field"`
+	Value  *float64 `json:"value,omitempty"`
+	Reason string   `json:"reason"`
+}
+
+// Error lists every violation of a request, so clients can fix them all at
+// once.
+type Error struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *Error) Error() string {
+	first := e.Violations[0]
+	if len(e.Violations) == 1 {
+		return fmt.Sprintf("%s: %s %s", ErrInvalid, first.Field, first.Reason)
+	}
+	return fmt.Sprintf("%s: %s %s, and %d more", ErrInvalid, first.Field, first.Reason, len(e.Violations)-1)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+func (e *Error) add(field string, value *float64, format string, args ...interface{}) {
+	e.Violations = append(e.Violations, Violation{Field: field, Value: value, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Invalid is a validation error of a single field.
+func Invalid(field, reason string) error {
+	return &Error{Violations: []Violation{{Field: field, Reason: reason}}}
+}
+
+// err returns e, or nil when nothing was violated.
+func (e *Error) err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Body renders a validation error as a JSON response body:
+// {"error": "...", "violations": [{"field": "...", "value": 91, "reason": "..."}]}.
+// Other errors are rendered as their message.
+func Body(err error) string {
+	var invalid *Error
+	if !errors.As(err, &invalid) {
+		return err.Error()
+	}
+	body, marshalErr := wrappers.JSONMarshal(struct {
+		Message    string      `json:"error"`
+		Violations []Violation `json:"violations"`
+	}{invalid.Error(), invalid.Violations})
+	if marshalErr != nil {
+		return err.Error()
+	}
+	return string(body)
+}
+
+// Location checks that the coordinates of a location are finite and in
+// range, and rounds them to COORDINATEDECIMALS. A complete location must
+// have both; updates may give one.
+func Location(location *types.Location, complete bool) error {
+	invalid := &Error{}
+	if location == nil {
+		return nil
+	}
+	if complete && (location.Latitude == nil) != (location.Longitude == nil) {
+		invalid.add("location", nil, "must have both latitude and longitude")
+	}
+	coordinate(invalid, "location.latitude", location.Latitude, -90, 90)
+	coordinate(invalid, "location.longitude", location.Longitude, -180, 180)
+	if err := invalid.err(); err != nil {
+		return err
+	}
+
+	round(location.Latitude)
+	round(location.Longitude)
+	return nil
+}
+
+// FloorplanCoords checks that a position is a finite pixel of its floorplan:
+// longitude is the x offset from the left edge of the image and latitude the
+// y offset from its top, both between 0 and the image's width and height.
+// Floorplans uploaded without known dimensions only bound the position to
+// non-negative pixels.
+func FloorplanCoords(coords *types.FloorplanCoords, floorplan *types.Floorplan) error {
+	invalid := &Error{}
+	if coords == nil {
+		return nil
+	}
+	width, height := math.Inf(1), math.Inf(1)
+	if floorplan != nil && floorplan.Width != nil && floorplan.Height != nil {
+		width, height = float64(*floorplan.Width), float64(*floorplan.Height)
+	}
+	coordinate(invalid, "floorplanCoords.longitude", coords.Longitude, 0, width)
+	coordinate(invalid, "floorplanCoords.latitude", coords.Latitude, 0, height)
+	return invalid.err()
+}
+
+// Polygon checks that a zone outline is a simple polygon of at least three
+// finite pixels of its floorplan, bounded like FloorplanCoords, that
+// encloses some area.
+func Polygon(polygon []types.Position, floorplan *types.Floorplan) error {
+	invalid := &Error{}
+	if len(polygon) < 3 || len(polygon) > zone.MAXVERTICES {
+		invalid.add("polygon", nil, "must have between 3 and %d vertices", zone.MAXVERTICES)
+		return invalid
+	}
+	width, height := math.Inf(1), math.Inf(1)
+	if floorplan != nil && floorplan.Width != nil && floorplan.Height != nil {
+		width, height = float64(*floorplan.Width), float64(*floorplan.Height)
+	}
+	for i := range polygon {
+		coordinate(invalid, fmt.Sprintf("polygon[%d].x", i), &polygon[i].X, 0, width)
+		coordinate(invalid, fmt.Sprintf("polygon[%d].y", i), &polygon[i].Y, 0, height)
+	}
+	if err := invalid.err(); err != nil {
+		return err
+	}
+
+	if zone.Area(polygon) == 0 {
+		invalid.add("polygon", nil, "must enclose an area")
+	} else if !zone.Simple(polygon) {
+		invalid.add("polygon", nil, "must not cross itself")
+	}
+	return invalid.err()
+}
+
+func coordinate(invalid *Error, field string, value *float64, min, max float64) {
+	switch {
+	case value == nil:
+	case math.IsNaN(*value) || math.IsInf(*value, 0):
+		invalid.add(field, nil, "must be a finite number")
+	case *value < min || *value > max:
+		if math.IsInf(max, 1) {
+			invalid.add(field, value, "must be at least %g", min)
+		} else {
+			invalid.add(field, value, "must be between %g and %g", min, max)
+		}
+	}
+}
+
+func round(value *float64) {
+	if value == nil {
+		return
+	}
+	scale := math.Pow10(COORDINATEDECIMALS)
+	// Adding 0 turns -0 into 0.
+	*value = math.Round(*value*scale)/scale + 0
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"wdd/api/internal/types"
+)
+
+func TestLocation(t *testing.T) {
+	lat, lon := 57.649111111, -0.00000001
+	location := &types.Location{Latitude: &lat, Longitude: &lon}
+	if err := Location(location, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *location.Latitude != 57.6491111 || *location.Longitude != 0 || math.Signbit(*location.Longitude) {
+		t.Errorf("Expected coordinates rounded to 7 decimals, got %v %v", *location.Latitude, *location.Longitude)
+	}
+
+	if err := Location(&types.Location{Latitude: &lat}, false); err != nil {
+		t.Errorf("Expected a partial location to be valid for updates, got %v", err)
+	}
+
+	high, nan := 91.0, math.NaN()
+	err := Location(&types.Location{Latitude: &high, Longitude: &nan}, true)
+	var invalid *Error
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalid) || len(invalid.Violations) != 2 {
+		t.Fatalf("Expected two violations, got %v", err)
+	}
+	if v := invalid.Violations[0]; v.Field != "location.latitude" || v.Value == nil || *v.Value != 91 {
+		t.Errorf("Expected the latitude violation first, got %+v", v)
+	}
+
+	if err := Location(&types.Location{Latitude: &lat}, true); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a complete location to need both coordinates, got %v", err)
+	}
+}
+
+func TestFloorplanCoords(t *testing.T) {
+	width, height := 100, 50
+	floorplan := &types.Floorplan{Width: &width, Height: &height}
+	x, y := 100.0, 50.0
+	if err := FloorplanCoords(&types.FloorplanCoords{Longitude: &x, Latitude: &y}, floorplan); err != nil {
+		t.Errorf("Expected the corner of the image to be valid, got %v", err)
+	}
+	y = 50.5
+	if err := FloorplanCoords(&types.FloorplanCoords{Longitude: &x, Latitude: &y}, floorplan); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a position below the image to be invalid, got %v", err)
+	}
+	if err := FloorplanCoords(&types.FloorplanCoords{Latitude: &y}, &types.Floorplan{}); err != nil {
+		t.Errorf("Expected any non-negative position on a floorplan of unknown size, got %v", err)
+	}
+}
+
+func TestPolygon(t *testing.T) {
+	width, height := 100, 50
+	floorplan := &types.Floorplan{Width: &width, Height: &height}
+	if err := Polygon([]types.Position{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 100, Y: 50}}, floorplan); err != nil {
+		t.Errorf("Expected a triangle within the image to be valid, got %v", err)
+	}
+
+	cases := map[string][]types.Position{
+		"too few vertices": {{X: 0, Y: 0}, {X: 1, Y: 1}},
+		"outside":          {{X: 0, Y: 0}, {X: 101, Y: 0}, {X: 0, Y: 10}},
+		"no area":          {{X: 0, Y: 0}, {X: 5, Y: 5}, {X: 10, Y: 10}},
+		"crossing":         {{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 10}},
+	}
+	for name, polygon := range cases {
+		if err := Polygon(polygon, floorplan); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected %s to be invalid, got %v", name, err)
+		}
+	}
+
+	err := Polygon([]types.Position{{X: -1, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 60}}, floorplan)
+	var invalid *Error
+	if !errors.As(err, &invalid) || len(invalid.Violations) != 2 || invalid.Violations[1].Field != "polygon[2].y" {
+		t.Errorf("Expected a violation for each vertex outside the image, got %v", err)
+	}
+}
+
+func TestBody(t *testing.T) {
+	var body struct {
+		Error      string      `json:"error"`
+		Violations []Violation `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(Body(Invalid("floorplanId", "is unknown"))), &body); err != nil {
+		t.Fatalf("Expected a JSON body, got %v", err)
+	}
+	if body.Error != "validation failed: floorplanId is unknown" || len(body.Violations) != 1 {
+		t.Errorf("Expected one violation, got %+v", body)
+	}
+	if Body(errors.New("plain")) != "plain" {
+		t.Errorf("Expected other errors as their message")
+	}
+}
@@ -0,0 +1,56 @@
+package zone
+
+import (
+	"context"
+	"sort"
+	"wdd/api/internal/calibration"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/types"
+)
+
+// Locate sets the Zones of every asset placed on a floorplan, reading the
+// zones of each floorplan once.
+func Locate(ctx context.Context, c *catalog.Catalog, assets []types.Asset) error {
+	zones := map[string][]types.Zone{}
+	for i := range assets {
+		pixel, ok := calibration.Pixel(assets[i].FloorplanCoords)
+		if !ok || assets[i].FloorplanID == nil || *assets[i].FloorplanID == "" {
+			continue
+		}
+
+		floorplanID := *assets[i].FloorplanID
+		floorplanZones, read := zones[floorplanID]
+		if !read {
+			var err error
+			if floorplanZones, err = c.FloorplanZones(ctx, floorplanID); err != nil {
+				return err
+			}
+			sort.Slice(floorplanZones, func(i, j int) bool { return floorplanZones[i].ZoneID < floorplanZones[j].ZoneID })
+			zones[floorplanID] = floorplanZones
+		}
+
+		assets[i].Zones = nil
+		for _, z := range floorplanZones {
+			if Contains(z.Polygon, pixel) {
+				assets[i].Zones = append(assets[i].Zones, z.ZoneID)
+			}
+		}
+	}
+	return nil
+}
+
+// Assets returns the assets of a zone's floorplan that sit in it.
+func Assets(ctx context.Context, c *catalog.Catalog, z types.Zone) ([]types.Asset, error) {
+	candidates, err := c.FloorplanAssets(ctx, z.FloorplanID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := []types.Asset{}
+	for _, asset := range candidates {
+		if pixel, ok := calibration.Pixel(asset.FloorplanCoords); ok && Contains(z.Polygon, pixel) {
+			assets = append(assets, asset)
+		}
+	}
+	return assets, nil
+}
@@ -0,0 +1,106 @@
+package zone
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"wdd/api/internal/catalog"
+	"wdd/api/internal/mocks"
+	"wdd/api/internal/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func positioned(id, floorplanID string, x, y float64) types.Asset {
+	return types.Asset{AssetID: id, FloorplanID: &floorplanID, FloorplanCoords: &types.FloorplanCoords{Longitude: &x, Latitude: &y}}
+}
+
+func polygon(points ...float64) ddbtypes.AttributeValue {
+	var vertices []ddbtypes.AttributeValue
+	for i := 0; i < len(points); i += 2 {
+		vertices = append(vertices, &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+			"x": &ddbtypes.AttributeValueMemberN{Value: fmt.Sprint(points[i])},
+			"y": &ddbtypes.AttributeValueMemberN{Value: fmt.Sprint(points[i+1])},
+		}})
+	}
+	return &ddbtypes.AttributeValueMemberL{Value: vertices}
+}
+
+// mockClient has zones z1, the L, and z2, its bottom half, on floorplan f1,
+// whose assets a1 at (2,2), a2 at (8,8) and a3 at (2,8) are listed over
+// two pages.
+func mockClient(queries map[string]int) *mocks.DynamoDBClient {
+	return &mocks.DynamoDBClient{
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			table := aws.ToString(params.TableName)
+			queries[table]++
+			if params.ExpressionAttributeValues[":key"].(*ddbtypes.AttributeValueMemberS).Value != "f1" {
+				return &dynamodb.QueryOutput{}, nil
+			}
+			switch table {
+			case catalog.ZONETABLE:
+				return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{
+					{"zoneId": &ddbtypes.AttributeValueMemberS{Value: "z2"}, "floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"}, "polygon": polygon(0, 0, 10, 0, 10, 5, 0, 5)},
+					{"zoneId": &ddbtypes.AttributeValueMemberS{Value: "z1"}, "floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"}, "polygon": polygon(0, 0, 10, 0, 10, 5, 5, 5, 5, 10, 0, 10)},
+				}}, nil
+			case catalog.ASSETTABLE:
+				item := func(id, x, y string) map[string]ddbtypes.AttributeValue {
+					return map[string]ddbtypes.AttributeValue{
+						"assetId":     &ddbtypes.AttributeValueMemberS{Value: id},
+						"floorplanId": &ddbtypes.AttributeValueMemberS{Value: "f1"},
+						"floorplanCoords": &ddbtypes.AttributeValueMemberM{Value: map[string]ddbtypes.AttributeValue{
+							"longitude": &ddbtypes.AttributeValueMemberN{Value: x},
+							"latitude":  &ddbtypes.AttributeValueMemberN{Value: y},
+						}},
+					}
+				}
+				if params.ExclusiveStartKey == nil {
+					return &dynamodb.QueryOutput{
+						Items:            []map[string]ddbtypes.AttributeValue{item("a1", "2", "2"), item("a2", "8", "8")},
+						LastEvaluatedKey: map[string]ddbtypes.AttributeValue{"assetId": &ddbtypes.AttributeValueMemberS{Value: "a2"}},
+					}, nil
+				}
+				return &dynamodb.QueryOutput{Items: []map[string]ddbtypes.AttributeValue{item("a3", "2", "8")}}, nil
+			}
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}
+}
+
+func TestLocate(t *testing.T) {
+	queries := map[string]int{}
+	assets := []types.Asset{positioned("a1", "f1", 2, 2), positioned("a2", "f1", 8, 8), positioned("a3", "f1", 2, 8), {AssetID: "a4"}}
+	if err := Locate(context.Background(), catalog.New(mockClient(queries)), assets); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := [][]string{{"z1", "z2"}, nil, {"z1"}, nil}
+	for i, zones := range expected {
+		if !reflect.DeepEqual(assets[i].Zones, zones) {
+			t.Errorf("Expected %s in zones %v, got %v", assets[i].AssetID, zones, assets[i].Zones)
+		}
+	}
+	if queries[catalog.ZONETABLE] != 1 {
+		t.Errorf("Expected the zones of f1 to be read once, got %d reads", queries[catalog.ZONETABLE])
+	}
+}
+
+func TestAssets(t *testing.T) {
+	queries := map[string]int{}
+	c := catalog.New(mockClient(queries))
+	l := types.Zone{ZoneID: "z1", FloorplanID: "f1", Polygon: l}
+	assets, err := Assets(context.Background(), c, l)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(assets) != 2 || assets[0].AssetID != "a1" || assets[1].AssetID != "a3" {
+		t.Errorf("Expected a1 and a3 in the L, got %+v", assets)
+	}
+
+	if assets, err := Assets(context.Background(), c, types.Zone{FloorplanID: "f2", Polygon: l.Polygon}); err != nil || assets == nil || len(assets) != 0 {
+		t.Errorf("Expected no assets on an empty floorplan, got %v %v", assets, err)
+	}
+}
@@ -0,0 +1,76 @@
+package zone
+
+import (
+	"math"
+	"wdd/api/internal/types"
+)
+
+// MAXVERTICES caps the polygon of a zone, which is checked for crossing
+// edges pair by pair.
+const MAXVERTICES = 1000
+
+// Contains reports whether a point lies inside a polygon or on its edge.
+// The polygon is closed implicitly from its last vertex to its first.
+func Contains(polygon []types.Position, p types.Position) bool {
+	inside := false
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		if onSegment(a, b, p) {
+			return true
+		}
+		// Crossings of the ray from p toward +x, counting each vertex on
+		// the ray for the edge above it only.
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Area is the area enclosed by a polygon, positive when its vertices run
+// counterclockwise in a y-up plane.
+func Area(polygon []types.Position) float64 {
+	var sum float64
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		sum += a.X*b.Y - b.X*a.Y
+	}
+	return sum / 2
+}
+
+// Simple reports whether no two edges of a polygon cross or touch other
+// than adjacent edges at their shared vertex.
+func Simple(polygon []types.Position) bool {
+	n := len(polygon)
+	for i := 0; i < n; i++ {
+		a, b := polygon[i], polygon[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			if intersects(a, b, polygon[j], polygon[(j+1)%n]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func cross(o, a, b types.Position) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+func onSegment(a, b, p types.Position) bool {
+	return cross(a, b, p) == 0 &&
+		p.X >= math.Min(a.X, b.X) && p.X <= math.Max(a.X, b.X) &&
+		p.Y >= math.Min(a.Y, b.Y) && p.Y <= math.Max(a.Y, b.Y)
+}
+
+func intersects(a, b, c, d types.Position) bool {
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+	if (d1 > 0 && d2 < 0 || d1 < 0 && d2 > 0) && (d3 > 0 && d4 < 0 || d3 < 0 && d4 > 0) {
+		return true
+	}
+	return onSegment(c, d, a) || onSegment(c, d, b) || onSegment(a, b, c) || onSegment(a, b, d)
+}
@@ -0,0 +1,57 @@
+package zone
+
+import (
+	"testing"
+	"wdd/api/internal/types"
+)
+
+// l is an L-shaped zone: a 10x10 square without the quarter beyond (5, 5).
+var l = []types.Position{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 10}, {X: 0, Y: 10}}
+
+func TestContains(t *testing.T) {
+	cases := map[types.Position]bool{
+		{X: 2, Y: 2}:   true,
+		{X: 8, Y: 2}:   true,
+		{X: 2, Y: 8}:   true,
+		{X: 8, Y: 8}:   false,
+		{X: 0, Y: 0}:   true,
+		{X: 10, Y: 3}:  true,
+		{X: 7, Y: 5}:   true,
+		{X: 11, Y: 5}:  false,
+		{X: -1, Y: 0}:  false,
+		{X: 5, Y: 10}:  true,
+		{X: 12, Y: 10}: false,
+	}
+	for p, expected := range cases {
+		if got := Contains(l, p); got != expected {
+			t.Errorf("Expected Contains(%+v) to be %v, got %v", p, expected, got)
+		}
+	}
+	if Contains(nil, types.Position{}) {
+		t.Errorf("Expected an empty polygon to contain nothing")
+	}
+}
+
+func TestArea(t *testing.T) {
+	if a := Area(l); a != 75 {
+		t.Errorf("Expected an area of 75, got %g", a)
+	}
+	if a := Area([]types.Position{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 0}}); a != -0.5 {
+		t.Errorf("Expected a clockwise triangle to have a negative area, got %g", a)
+	}
+}
+
+func TestSimple(t *testing.T) {
+	if !Simple(l) {
+		t.Errorf("Expected the L to be simple")
+	}
+	bowtie := []types.Position{{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 10}}
+	if Simple(bowtie) {
+		t.Errorf("Expected a bowtie not to be simple")
+	}
+	// An edge doubling back over its neighbour's vertex touches it.
+	spike := []types.Position{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 5}, {X: 0, Y: 10}}
+	if Simple(spike) {
+		t.Errorf("Expected a spike along an edge not to be simple")
+	}
+}
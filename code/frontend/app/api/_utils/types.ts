@@ -20,6 +20,9 @@ export interface Floorplan {
     dateCreated: string;
     imageData: string;
     factoryId: string;
+    level?: number;
+    name?: string;
+    version?: number;
 }
 
 export interface Asset {
@@ -29,17 +29,23 @@ export const createFloorplan = async (
     }
 };
 
-export const getFloorplan = async (id: string): Promise<Floorplan | null> => {
+export const getFloorplan = async (
+    factoryId: string,
+): Promise<Floorplan | null> => {
     try {
-        const response = await fetch(`${BASE_URL}/floorplan/?id=${id}`);
+        const response = await fetch(
+            `${BASE_URL}/floorplan?factoryId=${factoryId}`,
+        );
         if (!response.ok) {
-            if (response.status === 404) {
-                console.log(`No floorplan found for factory ID ${id}`);
-                return null;
-            }
             throw new Error(`Failed to get floorplan: ${response.statusText}`);
         }
-        return (await response.json()) as Floorplan;
+        // Floorplans come from the lowest level up.
+        const floorplans = (await response.json()) as Floorplan[];
+        if (floorplans.length === 0) {
+            console.log(`No floorplan found for factory ID ${factoryId}`);
+            return null;
+        }
+        return floorplans[0];
     } catch (error) {
         console.error("Failed to get floorplan", error);
         throw new Error("Failed to get floorplan");
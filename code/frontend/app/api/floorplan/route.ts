@@ -2,6 +2,7 @@ import {
     BackendConnector,
     GetConfig,
     PostConfig,
+    PutConfig,
 } from "@/app/api/_utils/connector";
 import { Floorplan } from "@/app/api/_utils/types";
 
@@ -27,14 +28,34 @@ export async function POST(request: Request) {
     }
 }
 
+export async function PUT(request: Request) {
+    try {
+        const payload = (await request.json()) as Partial<Floorplan>;
+        const config: PutConfig<Partial<Floorplan>> = {
+            resource: "floorplan",
+            payload,
+        };
+        const data = await BackendConnector.put(config);
+        return new Response(JSON.stringify(data));
+    } catch (error) {
+        console.error(error);
+        return new Response(JSON.stringify({ success: false }));
+    }
+}
+
 export async function GET(request: Request) {
     const { searchParams } = new URL(request.url);
 
     const floorplanId = searchParams.get("id");
+    const factoryId = searchParams.get("factoryId");
 
     const config: GetConfig = {
         resource: "floorplan",
-        params: floorplanId ? { id: floorplanId } : undefined,
+        params: floorplanId
+            ? { id: floorplanId }
+            : factoryId
+              ? { factoryId }
+              : undefined,
     };
 
     try {
@@ -16,6 +16,7 @@ export default function FactoryDashboard({
     params: { factoryId: string };
 }) {
     const [floorPlanFile, setFloorPlanFile] = useState<File | null>(null);
+    const [floorplanId, setFloorplanId] = useState<string | undefined>();
     const [assetMarkers, setAssetMarkers] = useState<JSX.Element[]>([]);
     const { factoryId } = params;
 
@@ -26,13 +27,17 @@ export default function FactoryDashboard({
                     const config: GetConfig = {
                         resource: "floorplan",
                         params: {
-                            id: factoryId,
+                            factoryId,
                         },
                     };
 
+                    // Floorplans come from the lowest level up.
                     const data = await NextServerConnector.get(config);
-                    const floorplan = data as Floorplan;
+                    const floorplan = Array.isArray(data)
+                        ? (data[0] as Floorplan | undefined)
+                        : undefined;
 
+                    setFloorplanId(floorplan?.floorplanId);
                     if (floorplan && floorplan.imageData) {
                         const blob = await fetch(floorplan.imageData).then(
                             (res) => res.blob(),
@@ -70,6 +75,7 @@ export default function FactoryDashboard({
                             ) : (
                                 <FileUploadContainer
                                     setFloorPlanFile={setFloorPlanFile}
+                                    floorplanId={floorplanId}
                                 />
                             )}
                             <FloorManager
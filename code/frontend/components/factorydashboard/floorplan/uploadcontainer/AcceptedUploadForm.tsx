@@ -3,7 +3,11 @@
 import React, { useState } from "react";
 import Image from "next/image";
 import { usePathname } from "next/navigation";
-import { PostConfig, NextServerConnector } from "@/app/api/_utils/connector";
+import {
+    PostConfig,
+    PutConfig,
+    NextServerConnector,
+} from "@/app/api/_utils/connector";
 import { Floorplan } from "@/app/api/_utils/types";
 import UploadResultTray from "./UploadResultTray";
 
@@ -13,6 +17,7 @@ const AcceptedUploadForm = (props: {
     acceptedFileItems: React.JSX.Element[];
     fileRejectionItems: React.JSX.Element[];
     setFloorPlanFile: React.Dispatch<React.SetStateAction<File | null>>;
+    floorplanId?: string;
 }) => {
     const {
         uploadedFile,
@@ -20,6 +25,7 @@ const AcceptedUploadForm = (props: {
         acceptedFileItems,
         fileRejectionItems,
         setFloorPlanFile,
+        floorplanId,
     } = props;
     const [isVisible, setVisibility] = useState(true);
     const navigation = usePathname();
@@ -40,19 +46,27 @@ const AcceptedUploadForm = (props: {
             const base64Image = reader.result?.toString().split(",")[1];
             if (base64Image) {
                 try {
-                    const config: PostConfig<Floorplan> = {
-                        resource: "floorplan",
-                        payload: {
-                            factoryId,
-                            dateCreated: new Date().toISOString(),
-                            floorplanId: factoryId, // change it later
-                            imageData: base64Image,
-                        },
-                    };
-
-                    // eslint-disable-next-line @typescript-eslint/no-unused-vars
-                    const data =
-                        await NextServerConnector.post<Floorplan>(config);
+                    if (floorplanId) {
+                        // A new image of an existing floorplan is published
+                        // as its next version.
+                        const config: PutConfig<Partial<Floorplan>> = {
+                            resource: "floorplan",
+                            payload: {
+                                floorplanId,
+                                imageData: base64Image,
+                            },
+                        };
+                        await NextServerConnector.put(config);
+                    } else {
+                        const config: PostConfig<Partial<Floorplan>> = {
+                            resource: "floorplan",
+                            payload: {
+                                factoryId,
+                                imageData: base64Image,
+                            },
+                        };
+                        await NextServerConnector.post(config);
+                    }
                 } catch (error) {
                     console.error("Error uploading floor plan:", error);
                 }
@@ -9,10 +9,11 @@ interface DropFile extends File {
 
 const FileUploadContainer = (props: {
     setFloorPlanFile: React.Dispatch<React.SetStateAction<File | null>>;
+    floorplanId?: string;
 }) => {
     const [uploadedFile, setUploadedFile] = useState<File | null>(null);
 
-    const { setFloorPlanFile } = props;
+    const { setFloorPlanFile, floorplanId } = props;
 
     const onDrop = useCallback((acceptedFiles: File[]) => {
         // call API endpoint that sends the floor-plan to the backend
@@ -82,6 +83,7 @@ const FileUploadContainer = (props: {
                         acceptedFileItems={acceptedFileItems}
                         fileRejectionItems={fileRejectionItems}
                         setFloorPlanFile={setFloorPlanFile}
+                        floorplanId={floorplanId}
                     />
                 )}
             </div>
@@ -4,7 +4,11 @@
 import React from "react";
 import { render, waitFor, act } from "@testing-library/react";
 import "@testing-library/jest-dom";
-import { NextServerConnector, PostConfig } from "@/app/api/_utils/connector";
+import {
+    NextServerConnector,
+    PostConfig,
+    PutConfig,
+} from "@/app/api/_utils/connector";
 import { Floorplan } from "@/app/api/_utils/types";
 import AcceptedUploadForm from "../components/factorydashboard/floorplan/uploadcontainer/AcceptedUploadForm";
 
@@ -17,6 +21,7 @@ jest.mock("next/navigation", () => ({
 jest.mock("@/app/api/_utils/connector", () => ({
     NextServerConnector: {
         post: jest.fn(),
+        put: jest.fn(),
     },
 }));
 
@@ -101,12 +106,10 @@ describe("AcceptedUploadForm", () => {
             NextServerConnector.post as jest.Mock
         ).mockResolvedValue({});
 
-        const mockConfig: PostConfig<Floorplan> = {
+        const mockConfig: PostConfig<Partial<Floorplan>> = {
             resource: "floorplan",
             payload: {
                 factoryId: "1",
-                dateCreated: "2024-01-01T00:00:00.100Z",
-                floorplanId: "1",
                 imageData: "base64Image",
             },
         };
@@ -124,6 +127,36 @@ describe("AcceptedUploadForm", () => {
         });
     });
 
+    test("should publish a new version of an existing floorplan when Accept button is clicked", async () => {
+        mockUsePathname.mockReturnValueOnce("some-path/some-path/1");
+        mockReaderResult.mockImplementationOnce(() => "some-data,base64Image");
+        const mockPost = NextServerConnector.post as jest.Mock;
+        const mockPut = (
+            NextServerConnector.put as jest.Mock
+        ).mockResolvedValue({});
+
+        const mockConfig: PutConfig<Partial<Floorplan>> = {
+            resource: "floorplan",
+            payload: {
+                floorplanId: "f1",
+                imageData: "base64Image",
+            },
+        };
+
+        const { getByText } = render(
+            <AcceptedUploadForm {...props} floorplanId="f1" />,
+        );
+
+        act(() => {
+            getByText("Accept").click();
+        });
+
+        await waitFor(() => {
+            expect(mockPut).toHaveBeenCalledWith(mockConfig);
+        });
+        expect(mockPost).not.toHaveBeenCalled();
+    });
+
     test("should not call createFloorplan when Deny button is clicked", () => {
         const mockPost = (
             NextServerConnector.post as jest.Mock